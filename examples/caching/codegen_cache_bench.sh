@@ -0,0 +1,10 @@
+#!/bin/bash
+# Builds catalog_go, rebuilds it expecting zero protoc invocations, then edits
+# stock.proto and reports which files were regenerated.
+set -euo pipefail
+
+cd "$(git rev-parse --show-toplevel)"
+python3 tools/codegen_cache_bench.py //examples/caching:catalog_go \
+    --touch examples/caching/catalog/stock.proto \
+    --expect-warm-runs 0 \
+    "$@"
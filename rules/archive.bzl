@@ -0,0 +1,141 @@
+"""Distribution archives of generated protobuf code.
+
+This module provides a rule that packages the outputs of one or more language
+rules into a .tar.gz or .zip archive for external distribution (e.g. SDK
+releases), with a configurable directory layout and an optional README and
+license. Archives are deterministic so that release artifacts can be verified
+by rebuilding them.
+"""
+
+load("//rules/private:providers.bzl", "LanguageProtoInfo")
+
+_ARCHIVE_FORMATS = ["tar.gz", "zip"]
+
+def _validate_archive_dir(path, what):
+    """Fails if path would escape the archive root."""
+    if path.startswith("/") or ".." in path.split("/"):
+        fail("{} must be a relative path inside the archive, got '{}'".format(what, path))
+
+def _target_files(target):
+    """Returns the generated files of a language rule, or its default outputs."""
+    language_info = target.get(LanguageProtoInfo)
+    if language_info:
+        return language_info.generated_files
+    return target[DefaultInfo].default_outputs
+
+def _archive_path(directory, basename):
+    directory = directory.strip("/")
+    if directory in ["", "."]:
+        return basename
+    return "{}/{}".format(directory, basename)
+
+def _proto_archive_impl(ctx):
+    """Implementation of the proto_archive rule."""
+    archive = ctx.actions.declare_output("{}.{}".format(ctx.label.name, ctx.attrs.format))
+
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._proto_archive[DefaultInfo].default_outputs[0],
+        "--format", ctx.attrs.format,
+        "--output", archive.as_output(),
+        "--prefix", ctx.attrs.prefix,
+    ])
+
+    for directory, target in sorted(ctx.attrs.srcs.items()):
+        for generated_file in _target_files(target):
+            cmd.add("--entry", _archive_path(directory, generated_file.basename), generated_file)
+
+    if ctx.attrs.readme:
+        cmd.add("--entry", ctx.attrs.readme_name, ctx.attrs.readme)
+    if ctx.attrs.license:
+        cmd.add("--entry", ctx.attrs.license_name, ctx.attrs.license)
+
+    ctx.actions.run(
+        cmd,
+        category = "proto_archive",
+        identifier = ctx.label.name,
+    )
+
+    return [DefaultInfo(default_outputs = [archive])]
+
+proto_archive_rule = rule(
+    impl = _proto_archive_impl,
+    attrs = {
+        "srcs": attrs.dict(attrs.string(), attrs.dep(), doc = "Archive directory to language rule whose outputs it holds"),
+        "format": attrs.enum(_ARCHIVE_FORMATS, default = "tar.gz", doc = "Archive format"),
+        "prefix": attrs.string(default = "", doc = "Top-level directory of every entry"),
+        "readme": attrs.option(attrs.source(), default = None, doc = "README placed at the archive root"),
+        "readme_name": attrs.string(default = "README.md", doc = "Name of the README inside the archive"),
+        "license": attrs.option(attrs.source(), default = None, doc = "License placed at the archive root"),
+        "license_name": attrs.string(default = "LICENSE", doc = "Name of the license inside the archive"),
+        "_proto_archive": attrs.exec_dep(default = "//tools:proto_archive.py"),
+    },
+)
+
+def proto_archive(
+    name,
+    srcs,
+    format = "tar.gz",
+    prefix = "",
+    readme = None,
+    readme_name = "README.md",
+    license = None,
+    license_name = "LICENSE",
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Packages generated code into a deterministic .tar.gz or .zip archive.
+
+    `srcs` maps a directory inside the archive to a language rule (such as
+    go_proto_library or python_proto_library); all files the rule generates
+    are placed in that directory. Use "" for the archive root. The README and
+    license are placed at the archive root. Every entry is nested under
+    `prefix` when it is set.
+
+    Archives are reproducible: entries are sorted by path, every entry has the
+    same fixed timestamp (1980-01-01), owners are cleared and modes normalized.
+    Two files mapped to the same path fail the build.
+
+    Args:
+        name: Target name; the archive is written to <name>.tar.gz or <name>.zip
+        srcs: Dict of archive directory to language rule target
+        format: "tar.gz" or "zip"
+        prefix: Top-level directory of every entry (e.g. "user-sdk-1.2.0")
+        readme: Optional README file placed at the archive root
+        readme_name: Name of the README inside the archive
+        license: Optional license file placed at the archive root
+        license_name: Name of the license inside the archive
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_archive(
+            name = "user_sdk",
+            srcs = {
+                "go/userv1": ":user_go",
+                "python/user": ":user_py",
+            },
+            format = "zip",
+            prefix = "user-sdk",
+            readme = "SDK_README.md",
+            license = "//:LICENSE",
+        )
+    """
+    if format not in _ARCHIVE_FORMATS:
+        fail("format must be one of {}, got '{}'".format(_ARCHIVE_FORMATS, format))
+    _validate_archive_dir(prefix, "prefix")
+    for directory in srcs:
+        _validate_archive_dir(directory, "srcs directory")
+
+    proto_archive_rule(
+        name = name,
+        srcs = srcs,
+        format = format,
+        prefix = prefix,
+        readme = readme,
+        readme_name = readme_name,
+        license = license,
+        license_name = license_name,
+        visibility = visibility,
+        **kwargs
+    )
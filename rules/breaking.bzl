@@ -0,0 +1,111 @@
+"""Breaking change checks against a committed baseline descriptor set.
+
+This module provides a rule that compiles a proto_library to a
+FileDescriptorSet and fails the build if buf's breaking change rules find
+incompatibilities with a baseline .binpb checked into the repository.
+"""
+
+load("//rules/private:providers.bzl", "BufToolchainInfo", "ProtoInfo")
+load("//rules:tools.bzl", "TOOL_ATTRS", "get_protoc_binary")
+
+# buf breaking rule sets that can be selected with `use`
+BREAKING_RULE_SETS = ["WIRE", "WIRE_JSON", "FILE"]
+
+def _proto_breaking_check_impl(ctx):
+    """Implementation of the proto_breaking_check rule."""
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    buf_cli = ctx.attrs._buf_toolchain[BufToolchainInfo].buf_cli
+
+    report = ctx.actions.declare_output("{}.json".format(ctx.label.name))
+
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._proto_breaking[DefaultInfo].default_outputs[0],
+        "--protoc", get_protoc_binary(ctx),
+        "--buf", buf_cli,
+        "--baseline", ctx.attrs.baseline,
+        "--output", report.as_output(),
+    ])
+
+    for rule_set in ctx.attrs.use:
+        cmd.add("--use", rule_set)
+
+    for import_path in proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+
+    cmd.add(proto_info.proto_files)
+
+    ctx.actions.run(
+        cmd,
+        category = "proto_breaking",
+        identifier = ctx.label.name,
+        inputs = proto_info.transitive_proto_files,
+        env = {"BUF_CACHE_DIR": "buck-out/buf-cache"},
+    )
+
+    return [DefaultInfo(default_outputs = [report])]
+
+proto_breaking_check_rule = rule(
+    impl = _proto_breaking_check_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library to check"),
+        "baseline": attrs.source(doc = "Baseline FileDescriptorSet (.binpb) to compare against"),
+        "use": attrs.list(attrs.string(), default = ["WIRE_JSON"], doc = "buf breaking rule sets to apply"),
+        "_proto_breaking": attrs.exec_dep(default = "//tools:proto_breaking.py"),
+        "_buf_toolchain": attrs.toolchain_dep(
+            default = "//tools:buf_toolchain",
+            providers = ["BufToolchainInfo"],
+        ),
+    }),
+)
+
+def proto_breaking_check(
+    name,
+    proto,
+    baseline,
+    use = ["WIRE_JSON"],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a proto_library breaks compatibility with a baseline descriptor set.
+
+    The library is compiled with imports and source info and compared with
+    `buf breaking` against the baseline, a FileDescriptorSet (.binpb) committed
+    to the repository. Each violation is listed with its file, line and buf
+    rule (e.g. FIELD_SAME_TYPE); the listing is sorted so that the output and
+    exit status are deterministic. The JSON report is written to <name>.json.
+
+    Release branches can point the check at a different baseline without
+    editing the BUCK file by setting `proto_breaking.<name>` in the buckconfig,
+    e.g. `-c proto_breaking.user_breaking=//baselines/v1.4:user.binpb`.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        baseline: Baseline .binpb file or target
+        use: buf breaking rule sets: "WIRE", "WIRE_JSON" and/or "FILE"
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_breaking_check(
+            name = "user_breaking",
+            proto = ":user_proto",
+            baseline = "baselines/user.binpb",
+            use = ["WIRE"],
+        )
+    """
+    if not use:
+        fail("use must list at least one rule set")
+    for rule_set in use:
+        if rule_set not in BREAKING_RULE_SETS:
+            fail("Unsupported breaking rule set: {}. Available rule sets: {}".format(rule_set, BREAKING_RULE_SETS))
+
+    proto_breaking_check_rule(
+        name = name,
+        proto = proto,
+        baseline = read_root_config("proto_breaking", name, baseline),
+        use = use,
+        visibility = visibility,
+        **kwargs
+    )
@@ -6,8 +6,9 @@ public repositories.
 """
 
 load("//rules/private:bsr_impl.bzl", "resolve_bsr_dependencies", "validate_bsr_dependencies")
-load("//rules/private:providers.bzl", "BSRRepositoryInfo")
+load("//rules/private:providers.bzl", "BSRRepositoryInfo", "BufToolchainInfo", "ProtoInfo")
 load("//rules/private:utils.bzl", "get_toolchain_info")
+load("//rules:tools.bzl", "TOOL_ATTRS", "get_offline_args", "get_protoc_binary")
 
 def bsr_repository(
     name,
@@ -397,3 +398,126 @@ def bsr_team_config(
         cmd = "echo '{}' > $OUT".format(json.encode(team_config_data)),
         visibility = visibility,
     )
+
+def bsr_module(
+    name,
+    module,
+    lockfile = "buf.lock",
+    commit = "",
+    visibility = None,
+    **kwargs):
+    """
+    Exposes a Buf Schema Registry module as a proto dependency.
+    
+    The module is pinned by a committed buf.lock file (v1 or v2): its commit
+    is exported with `buf export` into a local cache, the digest of the
+    exported files is verified against the lockfile, and the modules it
+    imports from are resolved the same way, recursively. Other
+    proto_library targets list the bsr_module in `deps` and import its files
+    by their path in the module.
+    
+    Args:
+        name: Target name
+        module: Module name, e.g. "buf.build/acme/payments"
+        lockfile: buf.lock pinning the module and its transitive dependencies
+                  (written by `buf dep update`)
+        commit: Expected commit; fails when the lockfile pins another one.
+                Defaults to the lockfile pin
+        visibility: Visibility list
+        **kwargs: Additional rule attributes (offline, offline_cache_dir, ...)
+        
+    Example:
+        ```python
+        bsr_module(
+            name = "payments_proto",
+            module = "buf.build/acme/payments",
+            lockfile = "buf.lock",
+        )
+        
+        proto_library(
+            name = "checkout_proto",
+            srcs = ["checkout.proto"],  # import "acme/payments/v1/payment.proto";
+            deps = [":payments_proto"],
+        )
+        ```
+    """
+    if module.count("/") != 2 or ":" in module:
+        fail("bsr_module '{}': module '{}' must be a module name like buf.build/owner/module; pin the commit in buf.lock".format(name, module))
+    
+    bsr_module_rule(
+        name = name,
+        module = module,
+        lockfile = lockfile,
+        commit = commit,
+        visibility = visibility,
+        **kwargs
+    )
+
+def _bsr_module_impl(ctx):
+    """Implementation for bsr_module."""
+    files_dir = ctx.actions.declare_output("module", dir = True)
+    descriptor_set = ctx.actions.declare_output("{}.binpb".format(ctx.label.name))
+    manifest = ctx.actions.declare_output("{}_modules.json".format(ctx.label.name))
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._bsr_module[DefaultInfo].default_outputs[0],
+        "--module", ctx.attrs.module,
+        "--lockfile", ctx.attrs.lockfile,
+        "--output-dir", files_dir.as_output(),
+        "--descriptor-set", descriptor_set.as_output(),
+        "--manifest", manifest.as_output(),
+        "--buf", ctx.attrs._buf_toolchain[BufToolchainInfo].buf_cli,
+        "--protoc", get_protoc_binary(ctx),
+    ])
+    if ctx.attrs.commit:
+        cmd.add("--commit", ctx.attrs.commit)
+    cmd.add(get_offline_args(ctx))
+    
+    ctx.actions.run(
+        cmd,
+        category = "bsr_module",
+        identifier = ctx.attrs.module,
+        local_only = True,  # Modules are fetched into a shared cache outside the action sandbox
+    )
+    
+    return [
+        DefaultInfo(
+            default_outputs = [descriptor_set],
+            sub_targets = {
+                "files": [DefaultInfo(default_outputs = [files_dir])],
+                "manifest": [DefaultInfo(default_outputs = [manifest])],
+            },
+        ),
+        ProtoInfo(
+            descriptor_set = descriptor_set,
+            proto_files = [],
+            import_paths = [],
+            transitive_descriptor_sets = [descriptor_set],
+            transitive_proto_files = [],
+            transitive_import_paths = [],
+            go_package = "",
+            python_package = "",
+            java_package = "",
+            lint_report = None,
+            breaking_report = None,
+            direct_dep_proto_files = [],
+            proto_file_owners = {},
+            file_import_paths = {},
+            external_descriptor_sets = [descriptor_set],
+        ),
+    ]
+
+bsr_module_rule = rule(
+    impl = _bsr_module_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "module": attrs.string(doc = "Module name, e.g. buf.build/acme/payments"),
+        "lockfile": attrs.source(doc = "buf.lock pinning the module and its dependencies"),
+        "commit": attrs.string(default = "", doc = "Expected commit (defaults to the lockfile pin)"),
+        "_bsr_module": attrs.exec_dep(default = "//tools:bsr_module.py"),
+        "_buf_toolchain": attrs.toolchain_dep(
+            default = "//tools:buf_toolchain",
+            providers = ["BufToolchainInfo"],
+        ),
+    }),
+)
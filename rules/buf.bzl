@@ -5,8 +5,9 @@ and breaking change detection. These rules integrate buf CLI operations
 directly into the Buck2 build system with proper caching and error handling.
 """
 
-load("//rules/private:buf_impl.bzl", "buf_lint_impl", "buf_format_impl", "buf_breaking_impl")
-load("//rules/private:providers.bzl", "BufLintInfo", "BufFormatInfo", "BufBreakingInfo")
+load("//rules/private:buf_impl.bzl", "buf_lint_impl", "buf_proto_lint_impl", "buf_format_impl", "buf_format_check_impl", "buf_format_fix_impl", "buf_breaking_impl")
+load("//rules/private:providers.bzl", "BufLintInfo", "BufFormatInfo", "BufBreakingInfo", "ProtoInfo")
+load("//rules:tools.bzl", "TOOL_ATTRS")
 
 # Re-export providers for external use
 BufLintInfo = BufLintInfo
@@ -15,9 +16,14 @@ BufBreakingInfo = BufBreakingInfo
 
 def buf_lint(
     name,
-    srcs,
+    srcs = [],
+    proto = None,
     buf_yaml = None,
     config = {},
+    use = ["DEFAULT"],
+    except_rules = [],
+    ignore = [],
+    ignore_only = {},
     fail_on_error = True,
     visibility = ["//visibility:private"],
     **kwargs
@@ -29,11 +35,23 @@ def buf_lint(
     It integrates with Buck2's caching system to prevent redundant validation
     and provides clear, actionable error messages.
     
+    Given a proto_library instead of srcs, the rule lints the descriptor set
+    the library compiles to, so the schema is parsed once by protoc for both
+    codegen and lint. The configuration is then taken from use, except_rules,
+    ignore and ignore_only, and violations are reported as sorted
+    file:line:column diagnostics in <name>.txt.
+    
     Args:
         name: Unique name for this lint target
         srcs: List of .proto files to lint
-        buf_yaml: Optional buf.yaml configuration file path
-        config: Dictionary of inline lint configuration options
+        proto: proto_library target to lint instead of srcs
+        buf_yaml: Optional buf.yaml configuration file path (srcs only)
+        config: Dictionary of inline lint configuration options (srcs only)
+        use: Lint categories or rule IDs, e.g. ["DEFAULT"] or ["MINIMAL", "COMMENTS"] (proto only)
+        except_rules: Rule IDs to skip (proto only)
+        ignore: Files or directories to skip, relative to the import root (proto only)
+        ignore_only: Map of rule ID to files or directories it skips, for
+                     grandfathering existing violations (proto only)
         fail_on_error: Whether to fail the build on lint violations (default: True)
         visibility: Buck2 visibility specification
         **kwargs: Additional arguments passed to underlying rule
@@ -52,7 +70,38 @@ def buf_lint(
             },
             visibility = ["PUBLIC"],
         )
+        
+        buf_lint(
+            name = "lint_user",
+            proto = ":user_proto",
+            use = ["DEFAULT"],
+            ignore_only = {"FIELD_LOWER_SNAKE_CASE": ["acme/legacy/v1/user.proto"]},
+        )
     """
+    if proto:
+        if srcs or buf_yaml or config:
+            fail("buf_lint: use either srcs (with buf_yaml/config) or proto (with use/except_rules/ignore/ignore_only)")
+        if not use:
+            fail("buf_lint: use must list at least one category or rule")
+        for rule in use + except_rules + list(ignore_only.keys()):
+            _validate_lint_rule(rule)
+        buf_proto_lint_rule(
+            name = name,
+            proto = proto,
+            use = use,
+            except_rules = except_rules,
+            ignore = ignore,
+            ignore_only = ignore_only,
+            fail_on_error = fail_on_error,
+            visibility = visibility,
+            **kwargs
+        )
+        return
+    if not srcs:
+        fail("buf_lint: srcs or proto is required")
+    if use != ["DEFAULT"] or except_rules or ignore or ignore_only:
+        fail("buf_lint: use, except_rules, ignore and ignore_only require proto; configure srcs with config or buf_yaml")
+    
     buf_lint_rule(
         name = name,
         srcs = srcs,
@@ -115,6 +164,61 @@ def buf_format(
         **kwargs
     )
 
+def buf_format_check(
+    name,
+    proto,
+    exclude = [],
+    visibility = ["//visibility:private"],
+    **kwargs
+):
+    """
+    Fail the build if the sources of a proto_library are not buf formatted.
+    
+    Each checked-in source of the library is run through buf format; files
+    that would change are printed as a unified diff and the build fails.
+    The diff is sorted by path and free of timestamps, so <name>.diff can be
+    attached to a PR comment. Generated files (non-source artifacts, or files
+    starting with a "Code generated ... DO NOT EDIT." comment) are skipped.
+    
+    A companion `<name>_format` target rewrites the files in place for
+    fixing them locally: `buck2 run //pkg:<name>_format`.
+    
+    Args:
+        name: Unique name for this check target
+        proto: proto_library target whose sources are checked
+        exclude: Globs or directories to skip, e.g. ["acme/legacy", "*_test.proto"]
+        visibility: Buck2 visibility specification
+        **kwargs: Additional arguments passed to underlying rule
+    
+    Provides:
+        BufFormatInfo: Information about the checked files and the diff report
+    
+    Example:
+        buf_format_check(
+            name = "user_style",
+            proto = ":user_proto",
+            exclude = ["acme/legacy"],
+        )
+    """
+    for pattern in exclude:
+        if not pattern or pattern.startswith("/"):
+            fail("buf_format_check: exclude entries must be relative globs or directories, got '{}'".format(pattern))
+    
+    buf_format_check_rule(
+        name = name,
+        proto = proto,
+        exclude = exclude,
+        visibility = visibility,
+        **kwargs
+    )
+    
+    buf_format_fix_rule(
+        name = "{}_format".format(name),
+        proto = proto,
+        exclude = exclude,
+        visibility = visibility,
+    )
+
 def buf_breaking(
     name,
     srcs,
@@ -222,6 +326,15 @@ def buf_breaking(
         **kwargs
     )
 
+def _validate_lint_rule(rule):
+    """Fails unless rule looks like a buf lint category or rule ID (e.g. DEFAULT, ENUM_ZERO_VALUE_SUFFIX)."""
+    valid = rule != "" and rule[0].isalpha()
+    for char in rule.elems():
+        if not (char.isupper() or char.isdigit() or char == "_"):
+            valid = False
+    if not valid:
+        fail("buf_lint: '{}' is not a lint category or rule ID (upper snake case, e.g. DEFAULT)".format(rule))
+
 # Rule definitions with proper attributes
 buf_lint_rule = rule(
     impl = buf_lint_impl,
@@ -246,6 +359,32 @@ buf_lint_rule = rule(
     toolchains = ["//tools:buf_toolchain"],
 )
 
+buf_proto_lint_rule = rule(
+    impl = buf_proto_lint_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library to lint"),
+        "use": attrs.list(attrs.string(), default = ["DEFAULT"], doc = "Lint categories or rule IDs"),
+        "except_rules": attrs.list(attrs.string(), default = [], doc = "Rule IDs to skip"),
+        "ignore": attrs.list(attrs.string(), default = [], doc = "Files or directories to skip"),
+        "ignore_only": attrs.dict(
+            attrs.string(),
+            attrs.list(attrs.string()),
+            default = {},
+            doc = "Rule ID to the files or directories it skips",
+        ),
+        "fail_on_error": attrs.bool(
+            default = True, 
+            doc = "Whether to fail build on lint violations"
+        ),
+        "_buf_lint_check": attrs.exec_dep(default = "//tools:buf_lint_check.py"),
+        "_buf_toolchain": attrs.toolchain_dep(
+            default = "//tools:buf_toolchain",
+            providers = ["BufToolchainInfo"],
+        ),
+    }),
+    toolchains = ["//tools:buf_toolchain"],
+)
+
 buf_format_rule = rule(
     impl = buf_format_impl,
     attrs = {
@@ -261,6 +400,35 @@ buf_format_rule = rule(
     toolchains = ["//tools:buf_toolchain"],
 )
 
+buf_format_check_rule = rule(
+    impl = buf_format_check_impl,
+    attrs = {
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library whose sources are checked"),
+        "exclude": attrs.list(attrs.string(), default = [], doc = "Globs or directories to skip"),
+        "_buf_format_check": attrs.exec_dep(default = "//tools:buf_format_check.py"),
+        "_buf_toolchain": attrs.toolchain_dep(
+            default = "//tools:buf_toolchain",
+            providers = ["BufToolchainInfo"],
+        ),
+    },
+    toolchains = ["//tools:buf_toolchain"],
+)
+
+# Runnable companion of buf_format_check_rule that formats the sources in place
+buf_format_fix_rule = rule(
+    impl = buf_format_fix_impl,
+    attrs = {
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library whose sources are rewritten"),
+        "exclude": attrs.list(attrs.string(), default = [], doc = "Globs or directories to skip"),
+        "_buf_format_check": attrs.exec_dep(default = "//tools:buf_format_check.py"),
+        "_buf_toolchain": attrs.toolchain_dep(
+            default = "//tools:buf_toolchain",
+            providers = ["BufToolchainInfo"],
+        ),
+    },
+    toolchains = ["//tools:buf_toolchain"],
+)
+
 buf_breaking_rule = rule(
     impl = buf_breaking_impl,
     attrs = {
@@ -0,0 +1,101 @@
+"""Generated code size budgets.
+
+This module provides a rule that sums the size of the code generated by
+language rules, per language, and fails the build when a language exceeds its
+budget. It guards against schema changes that balloon generated code and slow
+down compilation.
+"""
+
+load("//rules/private:providers.bzl", "LanguageProtoInfo")
+
+def _proto_codegen_budget_check_impl(ctx):
+    """Implementation of the proto_codegen_budget_check rule."""
+    report = ctx.actions.declare_output("{}.json".format(ctx.label.name))
+
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._codegen_budget[DefaultInfo].default_outputs[0],
+        "--budget-bytes", str(ctx.attrs.budget_bytes),
+        "--top", str(ctx.attrs.top),
+        "--output", report.as_output(),
+    ])
+
+    for language, budget in sorted(ctx.attrs.language_budgets.items()):
+        cmd.add("--language-budget", "{}={}".format(language, budget))
+
+    for target in ctx.attrs.srcs:
+        language_info = target[LanguageProtoInfo]
+        for generated_file in language_info.generated_files:
+            cmd.add("--file", language_info.language, generated_file.short_path, generated_file)
+
+    ctx.actions.run(
+        cmd,
+        category = "codegen_budget",
+        identifier = ctx.label.name,
+    )
+
+    return [DefaultInfo(default_outputs = [report])]
+
+proto_codegen_budget_check_rule = rule(
+    impl = _proto_codegen_budget_check_impl,
+    attrs = {
+        "srcs": attrs.list(attrs.dep(providers = [LanguageProtoInfo]), doc = "Language rules whose generated code is measured"),
+        "budget_bytes": attrs.int(default = 0, doc = "Budget per language in bytes (0 for unlimited)"),
+        "language_budgets": attrs.dict(attrs.string(), attrs.int(), default = {}, doc = "Budget of individual languages, overriding budget_bytes"),
+        "top": attrs.int(default = 10, doc = "Number of largest files to report"),
+        "_codegen_budget": attrs.exec_dep(default = "//tools:codegen_budget.py"),
+    },
+)
+
+def proto_codegen_budget_check(
+    name,
+    srcs,
+    budget_bytes = 0,
+    language_budgets = {},
+    top = 10,
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if the generated code of a language exceeds a size budget.
+
+    The files generated by each language rule in `srcs` (go_proto_library,
+    python_proto_library, ...) are summed per language, as reported by the
+    rule's LanguageProtoInfo. A language whose total exceeds its budget fails
+    the build, listing the total and the `top` largest files. The JSON report
+    with every language's total is written to <name>.json.
+
+    Args:
+        name: Target name
+        srcs: Language rule targets to measure
+        budget_bytes: Budget per language in bytes; 0 (the default) is unlimited
+        language_budgets: Dict of language ("go", "python", ...) to its budget in
+                          bytes, overriding budget_bytes; 0 is unlimited
+        top: Number of largest files to list per language
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_codegen_budget_check(
+            name = "api_codegen_budget",
+            srcs = [":api_go", ":api_py", ":api_ts"],
+            budget_bytes = 2 * 1024 * 1024,
+            language_budgets = {"typescript": 4 * 1024 * 1024},
+        )
+    """
+    if budget_bytes < 0:
+        fail("budget_bytes must not be negative, got {}".format(budget_bytes))
+    for language, budget in language_budgets.items():
+        if budget < 0:
+            fail("language_budgets[\"{}\"] must not be negative, got {}".format(language, budget))
+    if top < 1:
+        fail("top must be at least 1, got {}".format(top))
+
+    proto_codegen_budget_check_rule(
+        name = name,
+        srcs = srcs,
+        budget_bytes = budget_bytes,
+        language_budgets = language_budgets,
+        top = top,
+        visibility = visibility,
+        **kwargs
+    )
@@ -6,7 +6,7 @@ namespace support and modern C++17+ features.
 """
 
 load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo")
-load("//rules/private:utils.bzl", "get_proto_import_path")
+load("//rules/private:utils.bzl", "get_proto_import_path", "protoc_source_args", "sort_by_path")
 load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS", "get_protoc_command")
 
 def cpp_proto_library(
@@ -331,10 +331,8 @@ def _generate_cpp_code(ctx, proto_info, tools, output_files, namespace: str):
     # Build protoc command arguments
     protoc_cmd = cmd_args([tools["protoc"]])
     
-    # Add import paths (current + transitive)
-    all_import_paths = proto_info.import_paths + proto_info.transitive_import_paths
-    for import_path in all_import_paths:
-        protoc_cmd.add("--proto_path={}".format(import_path))
+    # Import paths and proto files, or the shared descriptor set of a proto_bundle
+    source_args, source_inputs = protoc_source_args(proto_info)
     
     # Configure C++ code generation
     if "cpp" in ctx.attrs.plugins:
@@ -370,10 +368,10 @@ def _generate_cpp_code(ctx, proto_info, tools, output_files, namespace: str):
             protoc_cmd.add("--grpc_opt={}".format(",".join(grpc_options)))
     
     # Add proto files
-    protoc_cmd.add(proto_info.proto_files)
+    protoc_cmd.add(source_args)
     
     # Collect all inputs
-    inputs = [tools["protoc"]] + proto_info.proto_files + proto_info.transitive_descriptor_sets
+    inputs = [tools["protoc"]] + source_inputs
     
     # Add plugin binaries if available
     if "grpc-cpp" in ctx.attrs.plugins and "protoc-gen-grpc-cpp" in tools:
@@ -461,7 +459,7 @@ def _cpp_proto_library_impl(ctx):
     
     # Create build configuration files
     config_files = _create_build_config_files(ctx, namespace)
-    output_files.extend(config_files)
+    output_files = sort_by_path(output_files + config_files)
     
     # Determine dependencies based on plugins used
     dependencies = ["protobuf"]
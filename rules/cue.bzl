@@ -0,0 +1,109 @@
+"""CUE schema generation rules for Buck2.
+
+This module provides rules for translating protobuf messages into CUE
+definitions, so configuration files can be validated with `cue vet` against
+the same schema as the protobuf API.
+"""
+
+load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo")
+
+def cue_proto_schema(
+    name: str,
+    proto: str,
+    cue_package: str = "",
+    field_names: str = "json",
+    visibility: list[str] = ["//visibility:private"],
+    **kwargs
+):
+    """
+    Generates a CUE schema from a proto_library target.
+    
+    Messages become CUE definitions (nested messages become nested
+    definitions), repeated fields become lists, maps become string-keyed
+    structs, and enums become disjunctions of value names. A subset of
+    protovalidate rules is translated into CUE constraints (string length,
+    pattern, prefix/suffix, numeric bounds, `in`, `required`, repeated
+    min/max items). Constructs without a CUE equivalent, such as oneof
+    exclusivity, extensions and unsupported protovalidate rules, are emitted
+    as `// unmappable:` comments.
+    
+    Args:
+        name: Unique name for this CUE schema target
+        proto: proto_library target to translate
+        cue_package: CUE package name (default: last component of the proto package)
+        field_names: Field label style, "json" (lowerCamelCase, as protojson) or "proto"
+        visibility: Buck2 visibility specification
+        **kwargs: Additional arguments passed to underlying rule
+    
+    Example:
+        cue_proto_schema(
+            name = "server_config_cue",
+            proto = ":server_config_proto",
+            cue_package = "config",
+        )
+        
+    Generated Files:
+        - <name>.cue: CUE definitions for all messages and enums in the target
+    """
+    if field_names not in ["json", "proto"]:
+        fail("field_names must be 'json' or 'proto', got '{}'".format(field_names))
+
+    cue_proto_schema_rule(
+        name = name,
+        proto = proto,
+        cue_package = cue_package,
+        field_names = field_names,
+        visibility = visibility,
+        **kwargs
+    )
+
+def _cue_proto_schema_impl(ctx):
+    """
+    Implementation function for cue_proto_schema rule.
+    
+    Runs tools/proto_to_cue.py over the proto sources of the target, using
+    transitive dependencies for type resolution only.
+    """
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    output = ctx.actions.declare_output("{}.cue".format(ctx.label.name))
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._proto_to_cue[DefaultInfo].default_outputs[0],
+        "--field-names", ctx.attrs.field_names,
+        "--output", output.as_output(),
+    ])
+    if ctx.attrs.cue_package:
+        cmd.add("--package", ctx.attrs.cue_package)
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files:
+            cmd.add("--dep", dep_file)
+    cmd.add(proto_info.proto_files)
+    
+    ctx.actions.run(
+        cmd,
+        category = "cue_schema",
+        identifier = ctx.label.name,
+    )
+    
+    return [
+        DefaultInfo(default_outputs = [output]),
+        LanguageProtoInfo(
+            language = "cue",
+            generated_files = [output],
+            package_name = ctx.attrs.cue_package,
+            dependencies = [],
+            compiler_flags = [],
+        ),
+    ]
+
+# CUE schema rule definition
+cue_proto_schema_rule = rule(
+    impl = _cue_proto_schema_impl,
+    attrs = {
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
+        "cue_package": attrs.string(default = "", doc = "CUE package name override"),
+        "field_names": attrs.string(default = "json", doc = "Field label style: json or proto"),
+        "_proto_to_cue": attrs.exec_dep(default = "//tools:proto_to_cue.py"),
+    },
+)
@@ -6,20 +6,69 @@ Go module integration.
 """
 
 load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo", "PerformanceInfo", "PerformanceMetricsInfo")
-load("//rules/private:utils.bzl", "get_proto_import_path")
+load("//rules/private:utils.bzl", "compile_descriptor_set", "external_descriptor_set_args", "generate_package_doc_index", "get_proto_import_path", "proto_import_path", "protoc_source_args", "sort_by_path")
 load("//rules/private:performance_impl.bzl", "create_performance_optimized_action", "get_default_performance_config")
 load("//rules/private:cache_impl.bzl", "get_default_cache_config")
-load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS", "get_protoc_command")
+load("//rules/private:go_helpers_impl.bzl", "generate_go_helpers")
+load("//rules/private:go_mappings.bzl", "go_output_path", "import_mapping_error", "output_extension_renames", "parse_import_mappings", "plugin_option_args")
+load("//rules:tools.bzl", "ensure_tools_available", "get_plugin_binary", "TOOL_ATTRS", "get_protoc_command")
 
 def go_proto_library(
     name: str,
     proto: str,
     go_package: str = "",
+    import_mappings: list[str] = [],
     visibility: list[str] = ["//visibility:private"],
     plugins: list[str] = ["go", "go-grpc"],
     options: dict[str, str] = {},
+    custom_plugins: dict[str, str] = {},
+    plugin_options: dict[str, str] = {},
+    plugin_order: list[str] = [],
+    plugin_prefix: str = "",
+    plugin_memory_limit: str = "",
+    openapi_v3_title: str = "",
+    openapi_v3_version: str = "",
+    openapi_v3_server_url: str = "",
+    openapi_v3_merge: bool = False,
+    per_file_actions: bool = True,
     go_module: str = "",
     embed: list[str] = [],
+    json_casing: str = "",
+    json_wkt_formats: dict[str, str] = {},
+    grpc_observability: str = "",
+    grpc_message_limits: bool = False,
+    grpc_server_interceptors: list[str] = [],
+    grpc_client_timeouts: bool = False,
+    grpc_keepalive: bool = False,
+    grpc_status_codes: bool = False,
+    grpc_status_code_option: str = "",
+    grpc_retry_codes: bool = False,
+    build_info: bool = False,
+    build_stamp = None,
+    build_time: str = "",
+    redaction: bool = False,
+    span_attributes: bool = False,
+    enum_strings: bool = False,
+    enum_string_option: str = "",
+    recursion_guard_depth: int = 0,
+    arena_constructors: bool = False,
+    field_index: bool = False,
+    init_hook: str = "",
+    descriptor_embed: str = "",
+    descriptor_embed_imports: bool = False,
+    grpc_service_names: dict[str, str] = {},
+    grpc_service_prefix: str = "",
+    grpc_split_services: list[str] = [],
+    check_custom_options: bool = False,
+    strict_deps: bool = False,
+    validate_tags: bool = False,
+    validate_tag_key: str = "validate",
+    validate_tag_rules: dict[str, str] = {},
+    oneof_wrapper_baseline = None,
+    oneof_wrapper_aliases: bool = False,
+    output_extension_map: dict[str, str] = {},
+    coverage_marker: str = "",
+    doc_index: bool = False,
     **kwargs
 ):
     """
@@ -29,11 +78,150 @@ def go_proto_library(
         name: Unique name for this Go protobuf library target
         proto: proto_library target to generate Go code from
         go_package: Go package path override (e.g., "github.com/org/pkg/v1")
+        import_mappings: protoc M flags for imported files whose go_package cannot be edited,
+                         e.g. ["Mgoogle/type/money.proto=github.com/us/gen/money"]; passed to
+                         every Go plugin, so imports of the mapped files use the new path.
+                         Each file must be in the transitive deps of proto and mapped once
         visibility: Buck2 visibility specification
-        plugins: List of protoc plugins to use ["go", "go-grpc", "grpc-gateway", "validate"]
-        options: Additional protoc options for Go generation
+        plugins: List of protoc plugins to use ["go", "go-grpc", "connect-go", "grpc-gateway", "openapiv2",
+                 "openapi_v3"].
+                 "connect-go" writes Connect handlers and clients to the sibling
+                 <package>connect package, so it can be combined with "go-grpc".
+                 "grpc-gateway" writes reverse-proxy handlers for methods with
+                 google.api.http annotations; "openapiv2" (requires "grpc-gateway")
+                 adds the matching swagger JSON; "openapi_v3" writes one merged OpenAPI v3
+                 openapi.yaml (protoc-gen-openapi)
+        options: Additional protoc options for Go generation; keys prefixed go_, go_grpc_,
+                 connect_go_, grpc_gateway_, openapiv2_ or openapi_v3_ are passed to the
+                 matching plugin
+        custom_plugins: Map of plugin name to an additional protoc plugin executable
+                        (protoc-gen-<name>), run after the built-in plugins unless
+                        plugin_order says otherwise
+        plugin_options: Map of custom or prefixed plugin name to its parameter (--<name>_opt);
+                        "{out_dir}" is replaced by the staging directory
+        plugin_order: Execution order of all enabled plugins, e.g. ["go", "gotag"]. Each
+                      plugin then runs in its own protoc invocation over a shared staging
+                      directory, so later plugins see earlier outputs (slower; see
+                      docs/rules-reference.md)
+        plugin_prefix: Directory of protoc-gen-<name> binaries; every name in plugins that is
+                       not built in resolves to <plugin_prefix>/protoc-gen-<name> and runs like
+                       a custom plugin. Defaults to the protobuf.plugin_prefix buckconfig
+        plugin_memory_limit: Memory cap of each plugin process, e.g. "512M" or "2G"; a plugin
+                             exceeding it fails the build with an error naming it. Applied
+                             with rlimits where available (not on Windows). Plugins then run
+                             through the sequential pipeline, so per_file_actions is ignored.
+                             Defaults to the protobuf.plugin_memory_limit buckconfig
+        openapi_v3_title: API title of openapi.yaml (protoc-gen-openapi's title option)
+        openapi_v3_version: API version of openapi.yaml (protoc-gen-openapi's version option)
+        openapi_v3_server_url: URL of the server entry added to openapi.yaml
+        openapi_v3_merge: Allow protos declaring several services, which are merged into one
+                          openapi.yaml; without it more than one service fails the build
+        per_file_actions: Run one protoc action per proto file, depending only on the
+                          files it imports, so editing one file does not regenerate the
+                          rest of the target. Ignored with plugin_order, custom plugins,
+                          grpc_service_names, grpc_service_prefix, grpc_split_services
+                          and validate_tags
         go_module: Go module name for generated go.mod file
         embed: Additional files to include in the Go package
+        json_casing: Generate MarshalJSON/UnmarshalJSON using this field name casing
+                     ("proto", "snake", "kebab", "camel" or "pascal") instead of protojson defaults
+        json_wkt_formats: Generate MarshalJSON/UnmarshalJSON rendering well-known types in legacy
+                          formats, e.g. {"timestamp": "epoch_millis", "duration": "seconds"};
+                          cannot be combined with json_casing
+        grpc_observability: Generate server constructors with channelz and a metrics interceptor
+                            ("prometheus" or "otel"); requires the "go-grpc" plugin
+        grpc_message_limits: Generate <Service>ServerOptions() applying message size limits
+                             from (buck2protobuf.grpc.v1.message_limits); see
+                             //proto:grpc_limits_proto. Requires the "go-grpc" plugin
+        grpc_server_interceptors: Generate DefaultServerOptions() chaining these standard
+                                  interceptors ("metrics", "logging", "recovery",
+                                  "validation"); requires the "go-grpc" plugin
+        grpc_client_timeouts: Generate New<Service>TimeoutClient() applying method timeouts
+                              from (buck2protobuf.deadline.v1.timeout_ms) when the caller's
+                              context has no deadline; see //proto:deadline_proto. Requires
+                              the "go-grpc" plugin
+        grpc_keepalive: Generate <Service>KeepaliveServerOptions() and
+                        <Service>KeepaliveDialOptions() applying the settings of
+                        (buck2protobuf.keepalive.v1.keepalive); see //proto:keepalive_proto.
+                        Requires the "go-grpc" plugin
+        grpc_status_codes: Make messages annotated with (buck2protobuf.errors.v1.grpc_code)
+                           errors that convert to a gRPC status with that code, and
+                           generate StatusFromErr(err); see //proto:errors_proto. Requires
+                           the "go-grpc" plugin
+        grpc_status_code_option: Fully-qualified message option read by grpc_status_codes
+                                 instead of buck2protobuf.errors.v1.grpc_code
+        grpc_retry_codes: Generate <Service>RetryableCodes and IsRetryable(method, err)
+                          classifying failed calls by the status codes listed in
+                          (buck2protobuf.retry.v1.retry); see //proto:retry_proto.
+                          Requires the "go-grpc" plugin
+        build_info: Generate a BuildInfo variable with the commit and build time
+        build_stamp: Stamp file with workspace status lines ("STABLE_GIT_COMMIT <sha>",
+                     "BUILD_TIMESTAMP <unix seconds>"); BuildInfo stays empty without it
+        build_time: Fixed build time for BuildInfo, overriding the stamp's timestamp
+        redaction: Generate a Redact() method per message that clears or masks fields
+                   annotated with (buck2protobuf.redact.v1.pii); see //proto:redact_proto
+        span_attributes: Generate a SpanAttributes() method per message returning fields annotated
+                         with (buck2protobuf.otel.v1.span_attribute) as OpenTelemetry attributes;
+                         see //proto:otel_proto
+        enum_strings: Generate MarshalText/UnmarshalText on enums whose values are annotated
+                      with (buck2protobuf.enums.v1.string_value), marshaling to those
+                      external strings; see //proto:enums_proto
+        enum_string_option: Fully-qualified enum value option read by enum_strings
+                            instead of buck2protobuf.enums.v1.string_value
+        recursion_guard_depth: Generate UnmarshalSafe methods on recursive messages that
+                               reject input nested deeper than this (0 disables)
+        arena_constructors: Generate New<Message>Arena constructors for Go's experimental
+                            arena package; only compiled with GOEXPERIMENT=arenas
+        field_index: Generate a <Message>FieldIndex map from field name to number, wire type
+                     and struct offset for reflection-free codecs; offsets are only valid
+                     within the binary they were compiled into
+        init_hook: Registration function called from a generated init() with the
+                   protoreflect.FileDescriptor of each proto file, either
+                   "import/path.Func" or a function of this package ("registerFile")
+        descriptor_embed: Generate <File>ProtoCompactDescriptor() returning the file's
+                          descriptor without source info, "raw" (serialized
+                          FileDescriptorProto) or "gzip"; see docs/go-helpers.md
+        descriptor_embed_imports: Embed a FileDescriptorSet with the file and its
+                                  transitive imports instead of the file alone
+        grpc_service_names: Map of fully-qualified proto service name to the wire-level
+                            name registered with gRPC (/<name>/Method); Go types keep
+                            the proto name. Requires the "go-grpc" plugin
+        grpc_service_prefix: Prefix prepended to the wire-level name of every service
+                             (after grpc_service_names), e.g. "tenant_a" registers and
+                             calls /tenant_a.acme.user.v1.UserService/Method; Go types are
+                             unchanged. Requires the "go-grpc" plugin
+        grpc_split_services: Fully-qualified names of every service of the proto files;
+                             instead of one <file>_grpc.pb.go per proto file, the stubs
+                             of each service are written to <service>_grpc.pb.go (e.g.
+                             user_service_grpc.pb.go) with the imports it uses. Buck2
+                             declares outputs before protoc runs, so the services are
+                             listed explicitly. Requires the "go-grpc" plugin
+        check_custom_options: Before running protoc, verify that every custom option used
+                              by the proto files is defined by an extension in the
+                              target, its transitive deps or the implicitly provided
+                              googleapis protos, naming the missing extension
+        strict_deps: Before running protoc, fail if a proto file imports a file that is not
+                     provided by a direct deps entry of the proto_library, even when it is
+                     available transitively; the error names the target to add to deps
+        validate_tags: Add struct tags derived from (buf.validate.field) rules to the
+                       generated message structs, for ORM validation (gorm, ent)
+        validate_tag_key: Struct tag key for validate_tags (default "validate")
+        validate_tag_rules: Overrides of the protovalidate rule to tag mapping, e.g.
+                            {"string.pattern": "regexp={}"}; "" disables a rule
+        oneof_wrapper_baseline: JSON file mapping fully-qualified oneof field names to their
+                                expected wrapper type names; the build fails if protoc-gen-go
+                                output drifts from it (see docs/go-helpers.md)
+        oneof_wrapper_aliases: Instead of failing on drift, generate type aliases that keep
+                               the baseline wrapper names compiling
+        output_extension_map: Map of output file suffix to replacement suffix, applied to all
+                              outputs after generation (e.g. {".pb.go": ".pb.go.txt"});
+                              the longest matching suffix wins
+        coverage_marker: Comment lines inserted at the top of every generated Go file so
+                         coverage tools skip it, e.g. "//coverage:ignore file" or
+                         "//go:build !coverage" (combined with an existing build constraint)
+        doc_index: Write a markdown index (README.md) per proto package listing its
+                   services, messages and enums with their doc comment summaries,
+                   available as the [doc_index] sub-target
         **kwargs: Additional arguments passed to underlying rule
     
     Example:
@@ -48,20 +236,302 @@ def go_proto_library(
     Generated Files:
         - *.pb.go: Basic protobuf message code (protoc-gen-go)
         - *_grpc.pb.go: gRPC service stubs (protoc-gen-go-grpc)
+        - <package>connect/*.connect.go: Connect handlers and clients (protoc-gen-connect-go)
+        - [gateway] *.pb.gw.go: gRPC-Gateway reverse-proxy handlers (protoc-gen-grpc-gateway)
+        - [openapiv2] *.swagger.json: OpenAPI v2 definitions (protoc-gen-openapiv2)
+        - [openapi_v3] openapi.yaml: Merged OpenAPI v3 document (protoc-gen-openapi)
         - go.mod: Go module definition (if go_module specified)
+        - *_json.pb.go: JSON marshalers with custom field casing (if json_casing specified)
+        - *_wkt_json.pb.go: JSON marshalers with legacy well-known type formats (if json_wkt_formats specified)
+        - *_observability.pb.go: Observable gRPC server constructors (if grpc_observability,
+          or observability of go_grpc_library, specified)
+        - *_grpc_limits.pb.go: Server options with per-service message size limits (if grpc_message_limits specified)
+        - *_interceptors.pb.go: DefaultServerOptions() with chained interceptors, in the first
+          service file's helper (if grpc_server_interceptors specified)
+        - *_client_timeouts.pb.go: Client wrappers applying annotated method timeouts (if grpc_client_timeouts specified)
+        - *_keepalive.pb.go: Server and dial options applying annotated keepalive settings (if grpc_keepalive specified)
+        - *_status.pb.go: Error methods and StatusFromErr() for annotated error messages (if grpc_status_codes specified)
+        - *_retry.pb.go: Retryable status codes per method and IsRetryable() (if grpc_retry_codes specified)
+        - *_build_info.pb.go: BuildInfo variable, in the first proto file's helper (if build_info specified)
+        - *_redact.pb.go: Redact() methods that clear or mask PII fields (if redaction specified)
+        - *_otel.pb.go: SpanAttributes() methods for annotated fields (if span_attributes specified)
+        - *_enum_strings.pb.go: Text marshaling of enums with external strings (if enum_strings specified)
+        - *_depth.pb.go: UnmarshalSafe methods for recursive messages (if recursion_guard_depth specified)
+        - *_arena.pb.go: Arena constructors, behind goexperiment.arenas (if arena_constructors specified)
+        - *_field_index.pb.go: Field name to number, wire type and offset maps (if field_index specified)
+        - *_init_hook.pb.go: init() passing the file descriptor to the hook (if init_hook specified)
+        - *_desc.pb.go: Compact embedded file descriptor accessor (if descriptor_embed specified)
+        - oneof_wrapper_aliases.pb.go: Aliases for drifted oneof wrapper names (if oneof_wrapper_aliases specified)
     """
+    plugin_prefix = (plugin_prefix or read_root_config("protobuf", "plugin_prefix", "")).rstrip("/")
+    prefixed_plugins = _prefixed_plugins(plugins, plugin_prefix)
+    for plugin_name in prefixed_plugins:
+        _validate_plugin_name(plugin_name)
+    for plugin_name in custom_plugins:
+        _validate_plugin_name(plugin_name)
+        if plugin_name in plugins:
+            fail("custom plugin '{}' clashes with a built-in plugin".format(plugin_name))
+    for plugin_name in plugin_options:
+        if plugin_name not in custom_plugins and plugin_name not in prefixed_plugins:
+            fail("plugin_options given for '{}', which is not in custom_plugins or resolved from plugin_prefix".format(plugin_name))
+    if "openapiv2" in plugins and "grpc-gateway" not in plugins:
+        fail("the 'openapiv2' plugin requires the 'grpc-gateway' plugin")
+    if (openapi_v3_title or openapi_v3_version or openapi_v3_server_url or openapi_v3_merge) and "openapi_v3" not in plugins:
+        fail("openapi_v3_title, openapi_v3_version, openapi_v3_server_url and openapi_v3_merge require the 'openapi_v3' plugin")
+    for opt_key in ["openapi_v3_title", "openapi_v3_version", "openapi_v3_output_mode"]:
+        if opt_key in options:
+            fail("options '{}' is not supported: set title and version with the rule attributes; the document is always merged".format(opt_key))
+    if plugin_order:
+        enabled = [p for p in plugins if p not in _GATEWAY_PLUGINS] + list(custom_plugins.keys())
+        if sorted(plugin_order) != sorted(enabled):
+            fail("plugin_order must list every enabled plugin exactly once ({}), got {}".format(enabled, plugin_order))
+    if recursion_guard_depth < 0:
+        fail("recursion_guard_depth must not be negative, got {}".format(recursion_guard_depth))
+    for proto_name, wire_name in grpc_service_names.items():
+        _validate_grpc_service_name(proto_name)
+        _validate_grpc_service_name(wire_name)
+    if grpc_service_prefix:
+        # The prefixed path /<prefix>.<package>.<Service>/ is valid iff the prefix is
+        _validate_grpc_service_name(grpc_service_prefix)
+    split_files = {}
+    for service_name in grpc_split_services:
+        _validate_grpc_service_name(service_name)
+        file_name = _grpc_service_file_name(service_name)
+        if file_name in split_files:
+            fail("grpc_split_services {} and {} are both written to {}".format(split_files[file_name], service_name, file_name))
+        split_files[file_name] = service_name
+    _parse_import_mappings(import_mappings)
+    if (validate_tag_rules or validate_tag_key != "validate") and not validate_tags:
+        fail("validate_tag_key and validate_tag_rules require validate_tags = True")
+    for suffix, replacement in output_extension_map.items():
+        if not suffix or not replacement or "/" in suffix or "/" in replacement:
+            fail("output_extension_map entries must be non-empty file suffixes without '/', got '{}': '{}'".format(suffix, replacement))
+    if coverage_marker:
+        for marker_line in coverage_marker.strip("\n").split("\n"):
+            if not marker_line.startswith("//"):
+                fail("coverage_marker lines must be Go line comments starting with '//', got '{}'".format(marker_line))
+    if oneof_wrapper_aliases and not oneof_wrapper_baseline:
+        fail("oneof_wrapper_aliases requires oneof_wrapper_baseline")
+    if init_hook:
+        _validate_init_hook(init_hook)
+    if descriptor_embed and descriptor_embed not in ["raw", "gzip"]:
+        fail("descriptor_embed must be 'raw' or 'gzip', got '{}'".format(descriptor_embed))
+    if descriptor_embed_imports and not descriptor_embed:
+        fail("descriptor_embed_imports requires descriptor_embed")
+    if enum_string_option and not enum_strings:
+        fail("enum_string_option requires enum_strings = True")
+    if grpc_status_code_option and not grpc_status_codes:
+        fail("grpc_status_code_option requires grpc_status_codes = True")
+    if (build_stamp or build_time) and not build_info:
+        fail("build_stamp and build_time require build_info = True")
+    if json_casing and json_casing not in _JSON_CASINGS:
+        fail("json_casing must be one of {}, got '{}'".format(_JSON_CASINGS, json_casing))
+    if json_casing and json_wkt_formats:
+        fail("json_casing and json_wkt_formats both generate MarshalJSON; enable only one")
+    for wkt, wkt_format in json_wkt_formats.items():
+        if wkt not in _JSON_WKT_FORMATS or wkt_format not in _JSON_WKT_FORMATS[wkt]:
+            fail("json_wkt_formats supports {}, got {} = '{}'".format(_JSON_WKT_FORMATS, wkt, wkt_format))
+    if grpc_observability and grpc_observability not in _GRPC_METRICS_BACKENDS:
+        fail("grpc_observability must be one of {}, got '{}'".format(_GRPC_METRICS_BACKENDS, grpc_observability))
+    for interceptor in grpc_server_interceptors:
+        if interceptor not in _GRPC_SERVER_INTERCEPTORS:
+            fail("grpc_server_interceptors supports {}, got '{}'".format(_GRPC_SERVER_INTERCEPTORS, interceptor))
+
     go_proto_library_rule(
         name = name,
         proto = proto,
         go_package = go_package,
+        import_mappings = import_mappings,
         visibility = visibility,
         plugins = plugins,
         options = options,
+        custom_plugins = custom_plugins,
+        plugin_options = plugin_options,
+        plugin_order = plugin_order,
+        plugin_prefix = plugin_prefix,
+        plugin_memory_limit = plugin_memory_limit,
+        openapi_v3_title = openapi_v3_title,
+        openapi_v3_version = openapi_v3_version,
+        openapi_v3_server_url = openapi_v3_server_url,
+        openapi_v3_merge = openapi_v3_merge,
+        per_file_actions = per_file_actions,
         go_module = go_module,
         embed = embed,
+        json_casing = json_casing,
+        json_wkt_formats = json_wkt_formats,
+        grpc_observability = grpc_observability,
+        grpc_message_limits = grpc_message_limits,
+        grpc_server_interceptors = grpc_server_interceptors,
+        grpc_client_timeouts = grpc_client_timeouts,
+        grpc_keepalive = grpc_keepalive,
+        grpc_status_codes = grpc_status_codes,
+        grpc_status_code_option = grpc_status_code_option,
+        grpc_retry_codes = grpc_retry_codes,
+        build_info = build_info,
+        build_stamp = build_stamp,
+        build_time = build_time,
+        redaction = redaction,
+        span_attributes = span_attributes,
+        enum_strings = enum_strings,
+        enum_string_option = enum_string_option,
+        recursion_guard_depth = recursion_guard_depth,
+        arena_constructors = arena_constructors,
+        field_index = field_index,
+        init_hook = init_hook,
+        descriptor_embed = descriptor_embed,
+        descriptor_embed_imports = descriptor_embed_imports,
+        grpc_service_names = grpc_service_names,
+        grpc_service_prefix = grpc_service_prefix,
+        grpc_split_services = grpc_split_services,
+        check_custom_options = check_custom_options,
+        strict_deps = strict_deps,
+        validate_tags = validate_tags,
+        validate_tag_key = validate_tag_key,
+        validate_tag_rules = validate_tag_rules,
+        oneof_wrapper_baseline = oneof_wrapper_baseline,
+        oneof_wrapper_aliases = oneof_wrapper_aliases,
+        output_extension_map = output_extension_map,
+        coverage_marker = coverage_marker,
+        doc_index = doc_index,
         **kwargs
     )
 
+def _validate_grpc_service_name(service_name: str):
+    """Fails unless service_name is a valid fully-qualified gRPC service name (package.Service)."""
+    for part in service_name.split("."):
+        valid = part != "" and not part[0].isdigit()
+        for char in part.elems():
+            if not (char.isalnum() or char == "_"):
+                valid = False
+        if not valid:
+            fail("'{}' is not a valid gRPC service name (expected package.Service, used as /package.Service/Method)".format(service_name))
+
+def _grpc_service_file_name(service_name: str) -> str:
+    """
+    Returns the file grpc_split_services writes a service to, e.g. user_service_grpc.pb.go.
+
+    Mirrors service_file_name in tools/go_grpc_split.py.
+    """
+    name = service_name.split(".")[-1]
+    chars = name.elems()
+    result = ""
+    for i, char in enumerate(chars):
+        if char.isupper() and i > 0:
+            previous = chars[i - 1]
+            following = chars[i + 1] if i + 1 < len(chars) else ""
+            if previous.islower() or previous.isdigit() or (previous.isupper() and following.islower()):
+                result += "_"
+        result += char.lower()
+    return result + "_grpc.pb.go"
+
+def _parse_import_mappings(import_mappings: list[str]) -> dict[str, str]:
+    """Parses M<file>=<import path> entries, failing on malformed or colliding ones."""
+    mappings, error = parse_import_mappings(import_mappings)
+    if error:
+        fail(error)
+    return mappings
+
+def _go_package_mappings(ctx, proto_info, go_package: str) -> list[str]:
+    """
+    Returns the M<file>=<import path> options passed to every Go plugin.
+    
+    The target's own files are mapped to the resolved go_package, and the
+    import_mappings entries relocate imported files.
+    
+    Args:
+        ctx: Buck2 rule context
+        proto_info: ProtoInfo provider from proto dependency
+        go_package: Resolved Go package path
+        
+    Returns:
+        List of plugin option values
+    """
+    mappings = []
+    if go_package:
+        for proto_file in proto_info.proto_files:
+            mappings.append("M{}={}".format(proto_file.short_path, go_package))
+    
+    import_mappings = _parse_import_mappings(ctx.attrs.import_mappings)
+    if not import_mappings:
+        return mappings
+    own_files = {proto_import_path(proto_info, f): True for f in proto_info.proto_files}
+    graph = dict(own_files)
+    for proto_file in proto_info.transitive_proto_files:
+        graph[proto_import_path(proto_info, proto_file)] = True
+    for import_path in (proto_info.proto_file_owners or {}).keys():
+        graph[import_path] = True
+    for proto_path, go_import_path in import_mappings.items():
+        error = import_mapping_error(proto_path, own_files, graph, str(ctx.attrs.proto.label.raw_target()))
+        if error:
+            fail(error)
+        mappings.append("M{}={}".format(proto_path, go_import_path))
+    return mappings
+
+def _validate_init_hook(init_hook: str):
+    """Fails unless init_hook is "import/path.Func" or a bare function name."""
+    func = init_hook.split("/")[-1].split(".")[-1]
+    valid = func != "" and not func[0].isdigit() and init_hook.split("/")[-1].count(".") <= 1
+    for char in init_hook.elems():
+        if char.isspace() or char == "\"":
+            valid = False
+    for char in func.elems():
+        if not (char.isalnum() or char == "_"):
+            valid = False
+    if not valid:
+        fail("init_hook must be \"import/path.Func\" or a function name, got '{}'".format(init_hook))
+
+def _validate_plugin_name(plugin_name: str):
+    """Fails unless plugin_name can be used in protoc flags (--<name>_out)."""
+    valid = plugin_name != ""
+    for char in plugin_name.elems():
+        if not (char.isalnum() or char in "-_"):
+            valid = False
+    if not valid:
+        fail("'{}' is not a valid plugin name (letters, digits, '-' and '_' only)".format(plugin_name))
+
+# Field name casings supported by the json_casing helper
+_JSON_CASINGS = ["proto", "snake", "kebab", "camel", "pascal"]
+
+# JSON formats per well-known type supported by the wkt_json helper
+_JSON_WKT_FORMATS = {
+    "timestamp": ["rfc3339", "epoch_millis", "epoch_seconds"],
+    "duration": ["string", "seconds", "millis"],
+}
+
+# Metrics backends supported by the grpc_observability helper
+_GRPC_METRICS_BACKENDS = ["prometheus", "otel"]
+
+# Interceptors supported by the grpc_server_interceptors helper, outermost first
+_GRPC_SERVER_INTERCEPTORS = ["metrics", "logging", "recovery", "validation"]
+
+# Plugins run in their own protoc invocation with the googleapis HTTP annotations
+# on the include path; their output depends on which methods are annotated
+_GATEWAY_PLUGINS = ["grpc-gateway", "openapiv2", "openapi_v3"]
+
+# Files of the googleapis directory added by _implicit_proto_dirs
+_IMPLICIT_PROTO_FILES = ["google/api/annotations.proto", "google/api/http.proto"]
+
+# Plugins downloaded by the rule; other names in plugins resolve against plugin_prefix
+_BUILTIN_PLUGINS = ["go", "go-grpc", "connect-go"] + _GATEWAY_PLUGINS
+
+def _implicit_proto_dirs(ctx) -> list:
+    """
+    Returns the directories of protos provided without a proto_library dependency.
+    
+    With a gateway plugin enabled, google/api/annotations.proto and
+    google/api/http.proto are on the include path of every action compiling
+    the library, so protos can import them without vendoring googleapis.
+    """
+    if not [p for p in ctx.attrs.plugins if p in _GATEWAY_PLUGINS]:
+        return []
+    return [ctx.attrs._googleapis_protos[DefaultInfo].default_outputs[0]]
+
+def _prefixed_plugins(plugins: list[str], plugin_prefix: str) -> list[str]:
+    """Returns the plugins resolved to <plugin_prefix>/protoc-gen-<name>."""
+    if not plugin_prefix:
+        return []
+    return [p for p in plugins if p not in _BUILTIN_PLUGINS]
+
 def _resolve_go_package(ctx, proto_info):
     """
     Resolves the Go package path for generated code.
@@ -140,9 +610,18 @@ def _get_go_output_files(ctx, proto_info, go_package: str):
             output_files.append(pb_go_file)
         
         # gRPC service stubs (if "go-grpc" plugin enabled and proto has services)
-        if "go-grpc" in ctx.attrs.plugins:
+        if "go-grpc" in ctx.attrs.plugins and not ctx.attrs.grpc_split_services:
             grpc_pb_go_file = ctx.actions.declare_output("go", base_name + "_grpc.pb.go")
             output_files.append(grpc_pb_go_file)
+        
+        # Connect handlers and clients, in the sibling <package>connect package
+        if "connect-go" in ctx.attrs.plugins:
+            connect_go_file = ctx.actions.declare_output("go", _connect_go_package_name(go_package), base_name + ".connect.go")
+            output_files.append(connect_go_file)
+    
+    # gRPC service stubs split into one file per service
+    for service_name in ctx.attrs.grpc_split_services:
+        output_files.append(ctx.actions.declare_output("go", _grpc_service_file_name(service_name)))
     
     # go.mod file (if go_module specified)
     if ctx.attrs.go_module:
@@ -151,6 +630,27 @@ def _get_go_output_files(ctx, proto_info, go_package: str):
     
     return output_files
 
+def _connect_go_package_name(go_package: str) -> str:
+    """
+    Returns the Go package name protoc-gen-connect-go generates into.
+    
+    Connect code lives in a separate package named after the message package
+    with a "connect" suffix (e.g. userv1connect), so its handler and client
+    types never collide with the protoc-gen-go-grpc stubs of the same services.
+    
+    Args:
+        go_package: Resolved Go package path, optionally with an explicit ";name"
+        
+    Returns:
+        Package name, e.g. "userv1connect"
+    """
+    if ";" in go_package:
+        name = go_package.split(";")[-1]
+    else:
+        name = go_package.split("/")[-1]
+    name = "".join([c if c.isalnum() or c == "_" else "_" for c in name.elems()])
+    return name + "connect"
+
 def _create_go_mod_content(go_module: str) -> str:
     """
     Creates go.mod file content for generated Go code.
@@ -171,7 +671,7 @@ require (
 )
 """.format(module = go_module)
 
-def _generate_go_code(ctx, proto_info, tools, output_files, go_package: str):
+def _generate_go_code(ctx, proto_info, tools, output_files, go_package: str, grpc_out_dir = None, go_out_dir = None, validation_reports = []):
     """
     Executes protoc with Go plugins to generate Go code.
     
@@ -181,6 +681,9 @@ def _generate_go_code(ctx, proto_info, tools, output_files, go_package: str):
         tools: Dictionary of tool file objects
         output_files: List of expected output files
         go_package: Resolved Go package path
+        grpc_out_dir: Separate directory for protoc-gen-go-grpc output that is post-processed
+        go_out_dir: Separate directory for protoc-gen-go output that is post-processed
+        validation_reports: Reports of checks that must pass before protoc runs
     """
     # Create output directory
     output_dir = ctx.actions.declare_output("go")
@@ -188,55 +691,59 @@ def _generate_go_code(ctx, proto_info, tools, output_files, go_package: str):
     # Build protoc command arguments
     protoc_cmd = cmd_args([tools["protoc"]])
     
-    # Add import paths (current + transitive)
-    all_import_paths = proto_info.import_paths + proto_info.transitive_import_paths
-    for import_path in all_import_paths:
-        protoc_cmd.add("--proto_path={}".format(import_path))
+    # Import paths and proto files, or the shared descriptor set of a proto_bundle
+    source_args, source_inputs = protoc_source_args(proto_info)
+    package_mappings = _go_package_mappings(ctx, proto_info, go_package)
     
     # Configure Go code generation
     if "go" in ctx.attrs.plugins:
         protoc_cmd.add("--plugin=protoc-gen-go={}".format(tools["protoc-gen-go"]))
-        protoc_cmd.add("--go_out={}".format(output_dir.as_output()))
+        protoc_cmd.add("--go_out={}".format((go_out_dir or output_dir).as_output()))
         protoc_cmd.add("--go_opt=paths=source_relative")
         
         # Add custom Go package mapping if specified
-        if go_package:
-            for proto_file in proto_info.proto_files:
-                protoc_cmd.add("--go_opt=M{}={}".format(
-                    proto_file.short_path, 
-                    go_package
-                ))
+        for mapping in package_mappings:
+            protoc_cmd.add("--go_opt={}".format(mapping))
     
     # Configure gRPC service generation
     if "go-grpc" in ctx.attrs.plugins:
         protoc_cmd.add("--plugin=protoc-gen-go-grpc={}".format(tools["protoc-gen-go-grpc"]))
-        protoc_cmd.add("--go-grpc_out={}".format(output_dir.as_output()))
+        protoc_cmd.add("--go-grpc_out={}".format((grpc_out_dir or output_dir).as_output()))
         protoc_cmd.add("--go-grpc_opt=paths=source_relative")
         
         # Add custom gRPC package mapping if specified
-        if go_package:
-            for proto_file in proto_info.proto_files:
-                protoc_cmd.add("--go-grpc_opt=M{}={}".format(
-                    proto_file.short_path, 
-                    go_package
-                ))
+        for mapping in package_mappings:
+            protoc_cmd.add("--go-grpc_opt={}".format(mapping))
+    
+    # Configure Connect generation
+    if "connect-go" in ctx.attrs.plugins:
+        protoc_cmd.add("--plugin=protoc-gen-connect-go={}".format(tools["protoc-gen-connect-go"]))
+        protoc_cmd.add("--connect-go_out={}".format(output_dir.as_output()))
+        protoc_cmd.add("--connect-go_opt=paths=source_relative")
+        
+        # Same package mapping as protoc-gen-go, so the generated imports resolve
+        for mapping in package_mappings:
+            protoc_cmd.add("--connect-go_opt={}".format(mapping))
     
     # Add any additional options
-    for opt_key, opt_value in ctx.attrs.options.items():
-        if opt_key.startswith("go_"):
-            protoc_cmd.add("--go_opt={}={}".format(opt_key[3:], opt_value))
-        elif opt_key.startswith("go_grpc_"):
-            protoc_cmd.add("--go-grpc_opt={}={}".format(opt_key[8:], opt_value))
+    for plugin in ["go", "go-grpc", "connect-go"]:
+        if plugin in ctx.attrs.plugins:
+            protoc_cmd.add(plugin_option_args(plugin, ctx.attrs.options))
     
     # Add proto files
-    protoc_cmd.add(proto_info.proto_files)
+    for proto_dir in _implicit_proto_dirs(ctx):
+        protoc_cmd.add(cmd_args(proto_dir, format = "--proto_path={}"))
+    protoc_cmd.add(source_args)
     
     # Collect all inputs
-    inputs = [tools["protoc"]] + proto_info.proto_files + proto_info.transitive_descriptor_sets
+    inputs = [tools["protoc"]] + source_inputs + _implicit_proto_dirs(ctx)
     if "protoc-gen-go" in tools:
         inputs.append(tools["protoc-gen-go"])
     if "protoc-gen-go-grpc" in tools:
         inputs.append(tools["protoc-gen-go-grpc"])
+    if "protoc-gen-connect-go" in tools:
+        inputs.append(tools["protoc-gen-connect-go"])
+    inputs.extend(validation_reports)
     
     # Run protoc to generate Go code
     ctx.actions.run(
@@ -244,13 +751,654 @@ def _generate_go_code(ctx, proto_info, tools, output_files, go_package: str):
         category = "go_protoc",
         identifier = "{}_go_generation".format(ctx.label.name),
         inputs = inputs,
-        outputs = [output_dir] + output_files,
+        outputs = [output_dir] + [d for d in [go_out_dir, grpc_out_dir] if d] + output_files,
         env = {
             "PATH": "/usr/bin:/bin:/usr/local/bin",
         },
         local_only = False,
     )
 
+def _go_outputs_by_proto(proto_info, output_files):
+    """
+    Groups declared outputs by the proto file they are generated from.
+    
+    Returns:
+        Dictionary of proto file to its outputs, or None if some output does
+        not belong to a single proto file (e.g. go.mod)
+    """
+    outputs_by_proto = {}
+    claimed = []
+    for proto_file in proto_info.proto_files:
+        base_name = proto_file.basename[:-len(".proto")] if proto_file.basename.endswith(".proto") else proto_file.basename
+        names = [base_name + ".pb.go", base_name + "_grpc.pb.go", base_name + ".connect.go"]
+        outputs_by_proto[proto_file] = [f for f in output_files if f.basename in names]
+        claimed.extend(outputs_by_proto[proto_file])
+    if len(claimed) != len(output_files):
+        return None
+    return outputs_by_proto
+
+def _generate_go_code_per_file(ctx, proto_info, tools, outputs_by_proto, go_package: str, validation_reports = []):
+    """
+    Runs one protoc invocation per proto file.
+    
+    Each action only depends on its proto file, the files of the target it
+    imports (read from the import graph at build time), the dependencies'
+    descriptor sets and the exact protoc and plugin binaries. Editing one
+    file therefore only regenerates it and the files importing it; every
+    other action stays an action cache hit. The graph is keyed by import
+    path, so imports match files moved by strip_import_prefix, import_prefix
+    or import roots.
+    
+    Args:
+        ctx: Buck2 rule context
+        proto_info: ProtoInfo provider from proto dependency
+        tools: Dictionary of tool file objects
+        outputs_by_proto: Declared outputs of each proto file
+        go_package: Resolved Go package path
+        validation_reports: Reports of checks that must pass before protoc runs
+    
+    Returns:
+        Import graph of the proto files
+    """
+    import_graph = ctx.actions.declare_output("go_import_graph.json")
+    graph_cmd = cmd_args([
+        "python3",
+        ctx.attrs._proto_import_graph[DefaultInfo].default_outputs[0],
+        "--output", import_graph.as_output(),
+    ])
+    for proto_file in proto_info.proto_files:
+        graph_cmd.add(cmd_args(proto_import_path(proto_info, proto_file), "=", proto_file, delimiter = ""))
+    ctx.actions.run(
+        graph_cmd,
+        category = "go_import_graph",
+        identifier = ctx.label.name,
+    )
+    
+    # Plugin arguments are shared by every file; they run in a single protoc invocation
+    plugin_args = []
+    shared_inputs = [tools["protoc"]] + proto_info.transitive_descriptor_sets + validation_reports + _implicit_proto_dirs(ctx)
+    for plugin in ctx.attrs.plugins:
+        if plugin not in ["go", "go-grpc", "connect-go"]:
+            continue  # Not run by the single-invocation path either
+        args, executable = _go_plugin_stage_args(ctx, tools, plugin, proto_info, go_package)
+        plugin_args.extend(args)
+        shared_inputs.append(executable)
+    protos_by_path = {proto_import_path(proto_info, proto_file): proto_file for proto_file in proto_info.proto_files}
+    all_outputs = [f for outputs in outputs_by_proto.values() for f in outputs]
+    
+    def generate(ctx, artifacts, outputs):
+        graph = artifacts[import_graph].read_json()["files"]
+        for proto_file, proto_outputs in outputs_by_proto.items():
+            imported = [protos_by_path[path] for path in graph[proto_import_path(proto_info, proto_file)]]
+            staged_dir = ctx.actions.declare_output("go_per_file", proto_file.short_path, dir = True)
+            cmd = cmd_args([
+                "python3",
+                ctx.attrs._protoc_pipeline[DefaultInfo].default_outputs[0],
+                "--protoc", tools["protoc"],
+                "--out-dir", staged_dir.as_output(),
+                "--profile-name", "{}_{}".format(ctx.label.raw_target(), proto_file.short_path),
+            ])
+            for output_file in proto_outputs:
+                cmd.add("--output", outputs[output_file].as_output())
+            cmd.add("--")
+            for import_path in proto_info.import_paths + proto_info.transitive_import_paths:
+                cmd.add("--proto_path={}".format(import_path))
+            for proto_dir in _implicit_proto_dirs(ctx):
+                cmd.add(cmd_args(proto_dir, format = "--proto_path={}"))
+            cmd.add(external_descriptor_set_args(proto_info))
+            cmd.add(proto_file)
+            cmd.add("--stage", "go", plugin_args)
+            ctx.actions.run(
+                cmd,
+                category = "go_protoc",
+                identifier = proto_file.short_path,
+                inputs = shared_inputs + [proto_file] + imported,
+                env = {
+                    "PATH": "/usr/bin:/bin:/usr/local/bin",
+                },
+            )
+    
+    ctx.actions.dynamic_output(
+        dynamic = [import_graph],
+        inputs = [],
+        outputs = [f.as_output() for f in all_outputs],
+        f = generate,
+    )
+    return import_graph
+
+def _plugin_memory_limit(ctx) -> str:
+    """Returns the plugin memory cap of the target, or an empty string for none."""
+    return ctx.attrs.plugin_memory_limit or read_root_config("protobuf", "plugin_memory_limit", "")
+
+def _go_plugin_order(ctx) -> list[str]:
+    """Returns the plugins to run sequentially, or an empty list to run them in one protoc invocation."""
+    if ctx.attrs.plugin_order:
+        return ctx.attrs.plugin_order
+    # Memory-capped plugins are started by the pipeline wrapper
+    if ctx.attrs.custom_plugins or _prefixed_plugins(ctx.attrs.plugins, ctx.attrs.plugin_prefix) or _plugin_memory_limit(ctx):
+        return [p for p in ctx.attrs.plugins if p not in _GATEWAY_PLUGINS] + list(ctx.attrs.custom_plugins.keys())
+    return []
+
+def _go_plugin_stage_args(ctx, tools, plugin: str, proto_info, go_package: str):
+    """
+    Returns the protoc arguments of one pipeline stage.
+    
+    Args:
+        ctx: Buck2 rule context
+        tools: Dictionary of tool file objects
+        plugin: Built-in ("go", "go-grpc", "connect-go"), custom or prefixed plugin name
+        proto_info: ProtoInfo provider from proto dependency
+        go_package: Resolved Go package path
+        
+    Returns:
+        Tuple of (arguments, plugin executable); the executable is None for
+        prefixed plugins, which are not build artifacts
+    """
+    prefixed = plugin in _prefixed_plugins(ctx.attrs.plugins, ctx.attrs.plugin_prefix)
+    if plugin in ctx.attrs.custom_plugins or prefixed:
+        if prefixed:
+            # protoc_pipeline verifies the path is executable before running protoc
+            path, executable = "{}/protoc-gen-{}".format(ctx.attrs.plugin_prefix, plugin), None
+        else:
+            executable = ctx.attrs.custom_plugins[plugin][DefaultInfo].default_outputs[0]
+            path = executable
+        args = ["--{}_out={{out_dir}}".format(plugin)]
+        if plugin in ctx.attrs.plugin_options:
+            args.append("--{}_opt={}".format(plugin, ctx.attrs.plugin_options[plugin]))
+        return ["--plugin=protoc-gen-{}={}".format(plugin, path)] + args, executable
+    
+    if plugin == "go":
+        executable = tools["protoc-gen-go"]
+    elif plugin == "go-grpc":
+        executable = tools["protoc-gen-go-grpc"]
+    elif plugin == "connect-go":
+        executable = tools["protoc-gen-connect-go"]
+    else:
+        fail("plugin_order contains unsupported plugin '{}'".format(plugin))
+    args = [
+        "--plugin=protoc-gen-{}={}".format(plugin, executable),
+        "--{}_out={{out_dir}}".format(plugin),
+        "--{}_opt=paths=source_relative".format(plugin),
+    ]
+    for mapping in _go_package_mappings(ctx, proto_info, go_package):
+        args.append("--{}_opt={}".format(plugin, mapping))
+    args.extend(plugin_option_args(plugin, ctx.attrs.options))
+    return args, executable
+
+def _run_go_plugin_pipeline(ctx, proto_info, tools, output_files, go_package: str, plugin_order, staged_dir, validation_reports = []):
+    """
+    Runs the Go plugins as sequential protoc invocations.
+    
+    Every plugin writes into staged_dir, which already holds the output of
+    the plugins before it, so plugins can post-process earlier output.
+    
+    Args:
+        ctx: Buck2 rule context
+        proto_info: ProtoInfo provider from proto dependency
+        tools: Dictionary of tool file objects
+        output_files: Declared outputs to copy from the staging directory
+        go_package: Resolved Go package path
+        plugin_order: Plugin names in execution order
+        staged_dir: Declared staging directory shared by all stages
+        validation_reports: Reports of checks that must pass before protoc runs
+    """
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._protoc_pipeline[DefaultInfo].default_outputs[0],
+        "--protoc", tools["protoc"],
+        "--out-dir", staged_dir.as_output(),
+        "--profile-name", str(ctx.label.raw_target()),
+    ])
+    if _plugin_memory_limit(ctx):
+        cmd.add("--plugin-memory-limit", _plugin_memory_limit(ctx))
+    for output_file in sort_by_path(output_files):
+        cmd.add("--output", output_file.as_output())
+    
+    cmd.add("--")
+    for import_path in proto_info.import_paths + proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+    for proto_dir in _implicit_proto_dirs(ctx):
+        cmd.add(cmd_args(proto_dir, format = "--proto_path={}"))
+    cmd.add(external_descriptor_set_args(proto_info))
+    cmd.add(proto_info.proto_files)
+    
+    inputs = [tools["protoc"]] + proto_info.proto_files + proto_info.transitive_descriptor_sets + _implicit_proto_dirs(ctx)
+    for plugin in plugin_order:
+        args, executable = _go_plugin_stage_args(ctx, tools, plugin, proto_info, go_package)
+        cmd.add("--stage", plugin)
+        cmd.add(args)
+        if executable:
+            inputs.append(executable)
+    inputs.extend(validation_reports)
+    
+    env = {
+        "PATH": "/usr/bin:/bin:/usr/local/bin",
+    }
+    # Opt-in per-phase timing; the trace is written outside the action outputs
+    profile_dir = read_root_config("protobuf", "profile_dir", "")
+    if profile_dir:
+        env["BUCK2_PROTOBUF_PROFILE_DIR"] = profile_dir
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_protoc_pipeline",
+        identifier = "{}_go_generation".format(ctx.label.name),
+        inputs = inputs,
+        env = env,
+    )
+
+def _compile_descriptor_set(ctx, proto_info, tools):
+    """
+    Compiles the proto files and their imports to a FileDescriptorSet.
+    
+    protoc omits source code info unless asked for it, so the set only holds
+    what a runtime needs for reflection.
+    
+    Returns:
+        Descriptor set file
+    """
+    return compile_descriptor_set(
+        ctx, proto_info, tools["protoc"], "{}_embed.descriptorset".format(ctx.label.name),
+        category = "go_descriptor_set",
+        proto_path_dirs = _implicit_proto_dirs(ctx),
+    )
+
+def _generate_gateway_code(ctx, proto_info, tools, go_package: str):
+    """
+    Runs protoc-gen-grpc-gateway, and protoc-gen-openapiv2 if enabled.
+    
+    google/api/annotations.proto and google/api/http.proto are added to the
+    include path, so protos can use google.api.http without vendoring them.
+    Methods without an HTTP rule are skipped by the plugins, and files without
+    any produce no output, so both plugins write to directory outputs.
+    
+    Returns:
+        Tuple of (gateway directory, OpenAPI directory or None)
+    """
+    gateway_dir = ctx.actions.declare_output("go_gateway", dir = True)
+    openapi_dir = ctx.actions.declare_output("go_openapiv2", dir = True) if "openapiv2" in ctx.attrs.plugins else None
+    
+    cmd = cmd_args([tools["protoc"]])
+    for import_path in proto_info.import_paths + proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+    for proto_dir in _implicit_proto_dirs(ctx):
+        cmd.add(cmd_args(proto_dir, format = "--proto_path={}"))
+    cmd.add(external_descriptor_set_args(proto_info))
+    
+    cmd.add("--plugin=protoc-gen-grpc-gateway={}".format(tools["protoc-gen-grpc-gateway"]))
+    cmd.add(cmd_args(gateway_dir.as_output(), format = "--grpc-gateway_out={}"))
+    cmd.add("--grpc-gateway_opt=paths=source_relative")
+    cmd.add("--grpc-gateway_opt=generate_unbound_methods=false")
+    for mapping in _go_package_mappings(ctx, proto_info, go_package):
+        cmd.add("--grpc-gateway_opt={}".format(mapping))
+    if openapi_dir:
+        cmd.add("--plugin=protoc-gen-openapiv2={}".format(tools["protoc-gen-openapiv2"]))
+        cmd.add(cmd_args(openapi_dir.as_output(), format = "--openapiv2_out={}"))
+        cmd.add("--openapiv2_opt=generate_unbound_methods=false")
+    for opt_key, opt_value in ctx.attrs.options.items():
+        if opt_key.startswith("grpc_gateway_"):
+            cmd.add("--grpc-gateway_opt={}={}".format(opt_key[len("grpc_gateway_"):], opt_value))
+        elif opt_key.startswith("openapiv2_") and openapi_dir:
+            cmd.add("--openapiv2_opt={}={}".format(opt_key[len("openapiv2_"):], opt_value))
+    cmd.add(proto_info.proto_files)
+    
+    inputs = [tools["protoc"], tools["protoc-gen-grpc-gateway"]] + _implicit_proto_dirs(ctx) + proto_info.proto_files + proto_info.transitive_descriptor_sets
+    if openapi_dir:
+        inputs.append(tools["protoc-gen-openapiv2"])
+    ctx.actions.run(
+        cmd,
+        category = "go_gateway",
+        identifier = ctx.label.name,
+        inputs = inputs,
+        env = {
+            "PATH": "/usr/bin:/bin:/usr/local/bin",
+        },
+    )
+    return gateway_dir, openapi_dir
+
+def _generate_openapi_v3(ctx, proto_info, tools):
+    """
+    Runs protoc-gen-openapi and finalizes the merged openapi.yaml.
+    
+    protoc-gen-openapi writes one document for all proto files, with a schema
+    for every message the operations reference. openapi_v3_doc.py then
+    enforces openapi_v3_merge, adds the server URL and verifies that every
+    schema reference resolves.
+    
+    Returns:
+        openapi.yaml file
+    """
+    raw_dir = ctx.actions.declare_output("go_openapi_v3_raw", dir = True)
+    openapi_yaml = ctx.actions.declare_output("openapi.yaml")
+    
+    cmd = cmd_args([tools["protoc"]])
+    for import_path in proto_info.import_paths + proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+    for proto_dir in _implicit_proto_dirs(ctx):
+        cmd.add(cmd_args(proto_dir, format = "--proto_path={}"))
+    cmd.add(external_descriptor_set_args(proto_info))
+    cmd.add("--plugin=protoc-gen-openapi={}".format(tools["protoc-gen-openapi"]))
+    cmd.add(cmd_args(raw_dir.as_output(), format = "--openapi_out={}"))
+    if ctx.attrs.openapi_v3_title:
+        cmd.add("--openapi_opt=title={}".format(ctx.attrs.openapi_v3_title))
+    if ctx.attrs.openapi_v3_version:
+        cmd.add("--openapi_opt=version={}".format(ctx.attrs.openapi_v3_version))
+    for opt_key, opt_value in ctx.attrs.options.items():
+        if opt_key.startswith("openapi_v3_"):
+            cmd.add("--openapi_opt={}={}".format(opt_key[len("openapi_v3_"):], opt_value))
+    cmd.add(proto_info.proto_files)
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_openapi_v3",
+        identifier = ctx.label.name,
+        inputs = [tools["protoc"], tools["protoc-gen-openapi"]] + _implicit_proto_dirs(ctx) + proto_info.proto_files + proto_info.transitive_descriptor_sets,
+        env = {
+            "PATH": "/usr/bin:/bin:/usr/local/bin",
+        },
+    )
+    
+    finalize_cmd = cmd_args([
+        "python3",
+        ctx.attrs._openapi_v3_doc[DefaultInfo].default_outputs[0],
+        "--input", cmd_args(raw_dir, format = "{}/openapi.yaml"),
+        "--output", openapi_yaml.as_output(),
+    ])
+    if ctx.attrs.openapi_v3_server_url:
+        finalize_cmd.add("--server-url", ctx.attrs.openapi_v3_server_url)
+    if ctx.attrs.openapi_v3_merge:
+        finalize_cmd.add("--merge")
+    finalize_cmd.add(proto_info.proto_files)
+    
+    ctx.actions.run(
+        finalize_cmd,
+        category = "go_openapi_v3_doc",
+        identifier = ctx.label.name,
+    )
+    return openapi_yaml
+
+def _rename_grpc_services(ctx, grpc_raw_dir, grpc_files):
+    """
+    Rewrites wire-level gRPC service names in protoc-gen-go-grpc output.
+    
+    Applies grpc_service_names, then prepends grpc_service_prefix to every service.
+    
+    Args:
+        ctx: Buck2 rule context
+        grpc_raw_dir: Directory with the unmodified protoc-gen-go-grpc output
+        grpc_files: Declared *_grpc.pb.go outputs to write
+    """
+    mapping_file = ctx.actions.write(
+        "{}_grpc_service_names.json".format(ctx.label.name),
+        json.encode(ctx.attrs.grpc_service_names),
+    )
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._grpc_service_rename[DefaultInfo].default_outputs[0],
+        "--mapping", mapping_file,
+        "--input-dir", grpc_raw_dir,
+    ])
+    for grpc_file in grpc_files:
+        cmd.add("--output", grpc_file.as_output())
+    if ctx.attrs.grpc_service_prefix:
+        cmd.add("--prefix", ctx.attrs.grpc_service_prefix)
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_grpc_service_rename",
+        identifier = ctx.label.name,
+    )
+
+def _split_grpc_services(ctx, grpc_raw_dir, grpc_inputs, grpc_files):
+    """
+    Splits protoc-gen-go-grpc output into one file per service.
+    
+    Args:
+        ctx: Buck2 rule context
+        grpc_raw_dir: Directory with protoc-gen-go-grpc output, or None to read grpc_inputs
+        grpc_inputs: Generated *_grpc.pb.go files to split when grpc_raw_dir is None
+        grpc_files: Declared <service>_grpc.pb.go outputs to write
+    """
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._go_grpc_split[DefaultInfo].default_outputs[0],
+    ])
+    if grpc_raw_dir:
+        cmd.add("--input-dir", grpc_raw_dir)
+    for grpc_file in grpc_files:
+        cmd.add("--output", grpc_file.as_output())
+    cmd.add(grpc_inputs)
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_grpc_split",
+        identifier = ctx.label.name,
+    )
+
+def _check_custom_options(ctx, proto_info):
+    """
+    Verifies that every custom option is defined by an extension in the transitive deps.
+    
+    protoc reports an undefined option only as "Option ... unknown"; this check
+    runs first and names the missing extension and the options message it must
+    extend.
+    
+    Args:
+        ctx: Buck2 rule context
+        proto_info: ProtoInfo provider from proto dependency
+        
+    Returns:
+        JSON report of the check, to be consumed by the protoc action
+    """
+    report = ctx.actions.declare_output("{}_custom_options.json".format(ctx.label.name))
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._schema_lint[DefaultInfo].default_outputs[0],
+        "--check", "registered_options",
+        "--output", report.as_output(),
+    ])
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files:
+            cmd.add("--dep", dep_file)
+    # Files on the include path without a proto_library dependency define options too
+    for proto_dir in _implicit_proto_dirs(ctx):
+        for proto_path in _IMPLICIT_PROTO_FILES:
+            cmd.add("--dep", cmd_args(proto_dir, format = "{}/" + proto_path))
+    cmd.add(proto_info.proto_files)
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_custom_option_check",
+        identifier = ctx.label.name,
+    )
+    
+    return report
+
+def _check_strict_deps(ctx, proto_info):
+    """
+    Verifies that every import is provided by a direct dep of the proto_library.
+    
+    protoc accepts imports of files that are only reachable through a dep of a
+    dep; this check rejects them and names the target to add to deps.
+    
+    Args:
+        ctx: Buck2 rule context
+        proto_info: ProtoInfo provider from proto dependency
+        
+    Returns:
+        JSON report of the check, to be consumed by the protoc action
+    """
+    report = ctx.actions.declare_output("{}_strict_deps.json".format(ctx.label.name))
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._strict_deps[DefaultInfo].default_outputs[0],
+        "--target", str(ctx.attrs.proto.label.raw_target()),
+        "--output", report.as_output(),
+    ])
+    provided = {}
+    for proto_file in proto_info.proto_files:
+        import_path = proto_import_path(proto_info, proto_file)
+        provided[import_path] = True
+        cmd.add(cmd_args(import_path, "=", proto_file, delimiter = ""))
+    for dep_file in proto_info.direct_dep_proto_files or []:
+        import_path = proto_import_path(proto_info, dep_file)
+        provided[import_path] = True
+        cmd.add("--direct", import_path)
+    for import_path, owner in (proto_info.proto_file_owners or {}).items():
+        if import_path not in provided:
+            cmd.add("--transitive", "{}={}".format(import_path, owner))
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_strict_deps_check",
+        identifier = ctx.label.name,
+    )
+    
+    return report
+
+def _add_validate_tags(ctx, proto_info, go_raw_dir, go_files):
+    """
+    Adds ORM validation struct tags to protoc-gen-go output.
+    
+    Args:
+        ctx: Buck2 rule context
+        proto_info: ProtoInfo provider from proto dependency
+        go_raw_dir: Directory with the unmodified protoc-gen-go output
+        go_files: Declared *.pb.go outputs to write
+    """
+    config_file = ctx.actions.write(
+        "{}_validate_tags.json".format(ctx.label.name),
+        json.encode({
+            "tag": ctx.attrs.validate_tag_key,
+            "rules": ctx.attrs.validate_tag_rules,
+        }),
+    )
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._go_validate_tags[DefaultInfo].default_outputs[0],
+        "--config", config_file,
+        "--input-dir", go_raw_dir,
+    ])
+    for go_file in go_files:
+        cmd.add("--output", go_file.as_output())
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files:
+            cmd.add("--dep", dep_file)
+    cmd.add(proto_info.proto_files)
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_validate_tags",
+        identifier = ctx.label.name,
+    )
+
+def _check_oneof_wrapper_names(ctx, proto_info, go_package: str, pb_go_files):
+    """
+    Compares oneof wrapper type names in protoc-gen-go output with a baseline.
+    
+    Args:
+        ctx: Buck2 rule context
+        proto_info: ProtoInfo provider from proto dependency
+        go_package: Resolved Go package path
+        pb_go_files: protoc-gen-go outputs to read wrapper names from
+        
+    Returns:
+        Tuple of (current name mapping JSON, alias file or None)
+    """
+    names_file = ctx.actions.declare_output("{}_oneof_wrapper_names.json".format(ctx.label.name))
+    aliases_file = None
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._go_oneof_names[DefaultInfo].default_outputs[0],
+        "--go-package", go_package,
+        "--baseline", ctx.attrs.oneof_wrapper_baseline,
+        "--names-output", names_file.as_output(),
+    ])
+    if ctx.attrs.oneof_wrapper_aliases:
+        aliases_file = ctx.actions.declare_output("go", "oneof_wrapper_aliases.pb.go")
+        cmd.add("--aliases-output", aliases_file.as_output())
+    for pb_go_file in pb_go_files:
+        cmd.add("--generated", pb_go_file)
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files:
+            cmd.add("--dep", dep_file)
+    cmd.add(proto_info.proto_files)
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_oneof_wrapper_names",
+        identifier = ctx.label.name,
+    )
+    
+    return names_file, aliases_file
+
+def _apply_output_extension_map(ctx, output_files):
+    """
+    Renames generated files according to the output_extension_map attribute.
+    
+    Each output whose file name ends with a mapped suffix (longest match) is
+    copied to a file with the replacement suffix in the same directory of the
+    Go output tree (e.g. <pkg>connect/); other outputs are kept.
+    
+    Args:
+        ctx: Buck2 rule context
+        output_files: Generated files
+        
+    Returns:
+        List of output files with renamed files in place of the originals
+    """
+    output_paths = [go_output_path(output_file) for output_file in output_files]
+    renames, error = output_extension_renames(output_paths, ctx.attrs.output_extension_map)
+    if error:
+        fail(error)
+    
+    result = []
+    for index, output_file in enumerate(output_files):
+        if output_paths[index] not in renames:
+            result.append(output_file)
+            continue
+        renamed = ctx.actions.declare_output("go_renamed", renames[output_paths[index]])
+        ctx.actions.copy_file(renamed.as_output(), output_file)
+        result.append(renamed)
+    return result
+
+def _add_coverage_marker(ctx, output_files):
+    """
+    Adds the coverage_marker attribute to the top of every generated Go file.
+    
+    Args:
+        ctx: Buck2 rule context
+        output_files: Generated files
+        
+    Returns:
+        List of output files with marked copies in place of the Go files
+    """
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._go_coverage_marker[DefaultInfo].default_outputs[0],
+        "--marker", ctx.attrs.coverage_marker,
+    ])
+    result = []
+    for output_file in output_files:
+        if not output_file.basename.endswith(".go"):
+            result.append(output_file)
+            continue
+        marked = ctx.actions.declare_output("go_coverage", go_output_path(output_file))
+        cmd.add("--file", output_file, marked.as_output())
+        result.append(marked)
+    
+    ctx.actions.run(
+        cmd,
+        category = "go_coverage_marker",
+        identifier = ctx.label.name,
+    )
+    return result
+
 def _create_go_mod_file(ctx, go_module: str):
     """
     Creates a go.mod file for the generated Go code.
@@ -291,12 +1439,87 @@ def _go_proto_library_impl(ctx):
     
     # Ensure required tools are available
     tools = ensure_tools_available(ctx, "go")
+    if "connect-go" in ctx.attrs.plugins:
+        tools["protoc-gen-connect-go"] = get_plugin_binary(ctx, "protoc-gen-connect-go")
+    if "grpc-gateway" in ctx.attrs.plugins:
+        tools["protoc-gen-grpc-gateway"] = get_plugin_binary(ctx, "protoc-gen-grpc-gateway")
+    if "openapiv2" in ctx.attrs.plugins:
+        if "grpc-gateway" not in ctx.attrs.plugins:
+            fail("the 'openapiv2' plugin requires the 'grpc-gateway' plugin")
+        tools["protoc-gen-openapiv2"] = get_plugin_binary(ctx, "protoc-gen-openapiv2")
+    if "openapi_v3" in ctx.attrs.plugins:
+        tools["protoc-gen-openapi"] = get_plugin_binary(ctx, "protoc-gen-openapi")
     
     # Get expected output files
     output_files = _get_go_output_files(ctx, proto_info, go_package)
+    protoc_go_files = [
+        f for f in output_files
+        if f.basename.endswith(".pb.go") and not f.basename.endswith("_grpc.pb.go")
+    ]
     
-    # Generate Go code using protoc
-    _generate_go_code(ctx, proto_info, tools, output_files, go_package)
+    # Generate Go code using protoc; renamed gRPC services and validate tags are post-processed.
+    # With an explicit plugin order, all plugins write to one staging directory that also
+    # serves as the input of the post-processing steps.
+    plugin_order = _go_plugin_order(ctx)
+    staged_dir = ctx.actions.declare_output("go_staged", dir = True) if plugin_order else None
+    protoc_outputs = output_files
+    import_graph = None
+    grpc_raw_dir = None
+    go_raw_dir = None
+    if ctx.attrs.grpc_service_names or ctx.attrs.grpc_service_prefix or ctx.attrs.grpc_split_services:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_service_names, grpc_service_prefix and grpc_split_services require the 'go-grpc' plugin")
+        grpc_raw_dir = staged_dir or ctx.actions.declare_output("go_grpc_raw", dir = True)
+        grpc_files = [f for f in output_files if f.basename.endswith("_grpc.pb.go")]
+        protoc_outputs = [f for f in protoc_outputs if f not in grpc_files]
+    if ctx.attrs.validate_tags:
+        if "go" not in ctx.attrs.plugins:
+            fail("validate_tags requires the 'go' plugin")
+        go_raw_dir = staged_dir or ctx.actions.declare_output("go_raw", dir = True)
+        protoc_outputs = [f for f in protoc_outputs if f not in protoc_go_files]
+    validation_reports = [_check_custom_options(ctx, proto_info)] if ctx.attrs.check_custom_options else []
+    if ctx.attrs.strict_deps:
+        validation_reports.append(_check_strict_deps(ctx, proto_info))
+    if proto_info.well_known_types_report:
+        # Per-file and pipeline actions list their inputs instead of using protoc_source_args
+        validation_reports.append(proto_info.well_known_types_report)
+    if staged_dir:
+        _run_go_plugin_pipeline(
+            ctx, proto_info, tools, protoc_outputs, go_package, plugin_order, staged_dir,
+            validation_reports = validation_reports,
+        )
+    else:
+        # Per-file actions need every output to belong to one proto file, and
+        # post-processing steps read the single protoc output directory
+        outputs_by_proto = None
+        if ctx.attrs.per_file_actions and not grpc_raw_dir and not go_raw_dir and len(proto_info.proto_files) > 1:
+            outputs_by_proto = _go_outputs_by_proto(proto_info, protoc_outputs)
+        if outputs_by_proto:
+            import_graph = _generate_go_code_per_file(
+                ctx, proto_info, tools, outputs_by_proto, go_package,
+                validation_reports = validation_reports,
+            )
+        else:
+            _generate_go_code(
+                ctx, proto_info, tools, protoc_outputs, go_package,
+                grpc_out_dir = grpc_raw_dir,
+                go_out_dir = go_raw_dir,
+                validation_reports = validation_reports,
+            )
+    if grpc_raw_dir and ctx.attrs.grpc_split_services:
+        renamed_files = []
+        if ctx.attrs.grpc_service_names or ctx.attrs.grpc_service_prefix:
+            # Services are renamed per proto file before the files are split
+            renamed_files = [
+                ctx.actions.declare_output("go_grpc_renamed", proto_file.basename.removesuffix(".proto") + "_grpc.pb.go")
+                for proto_file in proto_info.proto_files
+            ]
+            _rename_grpc_services(ctx, grpc_raw_dir, renamed_files)
+        _split_grpc_services(ctx, None if renamed_files else grpc_raw_dir, renamed_files, grpc_files)
+    elif grpc_raw_dir:
+        _rename_grpc_services(ctx, grpc_raw_dir, grpc_files)
+    if go_raw_dir:
+        _add_validate_tags(ctx, proto_info, go_raw_dir, protoc_go_files)
     
     # Create go.mod file if requested
     go_mod_file = None
@@ -304,21 +1527,228 @@ def _go_proto_library_impl(ctx):
         go_mod_file = _create_go_mod_file(ctx, ctx.attrs.go_module)
         output_files.append(go_mod_file)
     
+    # Generate optional helper files alongside protoc-gen-go output
+    if ctx.attrs.json_casing:
+        if "go" not in ctx.attrs.plugins:
+            fail("json_casing requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "json_casing", "json",
+            {"casing": ctx.attrs.json_casing},
+        ))
+    if ctx.attrs.json_wkt_formats:
+        if "go" not in ctx.attrs.plugins:
+            fail("json_wkt_formats requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "wkt_json", "wkt_json",
+            {"formats": ctx.attrs.json_wkt_formats},
+        ))
+    if ctx.attrs.grpc_observability:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_observability requires the 'go-grpc' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "grpc_observability", "observability",
+            {"metrics": ctx.attrs.grpc_observability},
+        ))
+    if ctx.attrs.grpc_message_limits:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_message_limits requires the 'go-grpc' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "grpc_message_limits", "grpc_limits", {},
+        ))
+    if ctx.attrs.grpc_server_interceptors:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_server_interceptors requires the 'go-grpc' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "grpc_server_interceptors", "interceptors",
+            {"interceptors": ctx.attrs.grpc_server_interceptors},
+        ))
+    if ctx.attrs.grpc_client_timeouts:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_client_timeouts requires the 'go-grpc' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "grpc_client_timeouts", "client_timeouts", {},
+        ))
+    if ctx.attrs.grpc_keepalive:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_keepalive requires the 'go-grpc' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "grpc_keepalive", "keepalive", {},
+        ))
+    if ctx.attrs.grpc_status_codes:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_status_codes requires the 'go-grpc' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "grpc_status_codes", "status",
+            {"option": ctx.attrs.grpc_status_code_option},
+        ))
+    if ctx.attrs.grpc_retry_codes:
+        if "go-grpc" not in ctx.attrs.plugins:
+            fail("grpc_retry_codes requires the 'go-grpc' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "grpc_retry_codes", "retry", {},
+        ))
+    if ctx.attrs.build_info:
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "build_info", "build_info",
+            {"build_time": ctx.attrs.build_time},
+            stamp = ctx.attrs.build_stamp,
+        ))
+    if ctx.attrs.redaction:
+        if "go" not in ctx.attrs.plugins:
+            fail("redaction requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "redaction", "redact", {},
+        ))
+    if ctx.attrs.span_attributes:
+        if "go" not in ctx.attrs.plugins:
+            fail("span_attributes requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "span_attributes", "otel", {},
+        ))
+    if ctx.attrs.enum_strings:
+        if "go" not in ctx.attrs.plugins:
+            fail("enum_strings requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "enum_strings", "enum_strings",
+            {"option": ctx.attrs.enum_string_option},
+        ))
+    if ctx.attrs.recursion_guard_depth:
+        if "go" not in ctx.attrs.plugins:
+            fail("recursion_guard_depth requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "recursion_guard", "depth",
+            {"max_depth": ctx.attrs.recursion_guard_depth},
+        ))
+    if ctx.attrs.arena_constructors:
+        if "go" not in ctx.attrs.plugins:
+            fail("arena_constructors requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "arena", "arena", {},
+        ))
+    if ctx.attrs.field_index:
+        if "go" not in ctx.attrs.plugins:
+            fail("field_index requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "field_index", "field_index", {},
+        ))
+    if ctx.attrs.init_hook:
+        if "go" not in ctx.attrs.plugins:
+            fail("init_hook requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "init_hook", "init_hook",
+            {"hook": ctx.attrs.init_hook},
+        ))
+    if ctx.attrs.descriptor_embed:
+        if "go" not in ctx.attrs.plugins:
+            fail("descriptor_embed requires the 'go' plugin")
+        output_files.extend(generate_go_helpers(
+            ctx, proto_info, go_package, "descriptor_embed", "desc",
+            {"format": ctx.attrs.descriptor_embed, "include_imports": ctx.attrs.descriptor_embed_imports},
+            descriptor_set = _compile_descriptor_set(ctx, proto_info, tools),
+        ))
+    
+    sub_targets = {}
+    if import_graph:
+        # Imports each per-file protoc action depends on
+        sub_targets["import_graph"] = [DefaultInfo(default_outputs = [import_graph])]
+    if staged_dir:
+        # Custom plugins may write files that are not declared outputs
+        sub_targets["plugin_outputs"] = [DefaultInfo(default_outputs = [staged_dir])]
+    
+    # Reverse-proxy handlers and OpenAPI definitions, in directories mirroring the proto layout
+    if "grpc-gateway" in ctx.attrs.plugins:
+        gateway_dir, openapi_dir = _generate_gateway_code(ctx, proto_info, tools, go_package)
+        output_files.append(gateway_dir)
+        sub_targets["gateway"] = [DefaultInfo(default_outputs = [gateway_dir])]
+        if openapi_dir:
+            output_files.append(openapi_dir)
+            sub_targets["openapiv2"] = [DefaultInfo(default_outputs = [openapi_dir])]
+    if "openapi_v3" in ctx.attrs.plugins:
+        openapi_yaml = _generate_openapi_v3(ctx, proto_info, tools)
+        output_files.append(openapi_yaml)
+        sub_targets["openapi_v3"] = [DefaultInfo(default_outputs = [openapi_yaml])]
+    
+    # Check oneof wrapper names in protoc-gen-go output against the baseline
+    oneof_names_file = None
+    if ctx.attrs.oneof_wrapper_baseline:
+        if "go" not in ctx.attrs.plugins:
+            fail("oneof_wrapper_baseline requires the 'go' plugin")
+        oneof_names_file, aliases_file = _check_oneof_wrapper_names(ctx, proto_info, go_package, protoc_go_files)
+        sub_targets["oneof_wrapper_names"] = [DefaultInfo(default_outputs = [oneof_names_file])]
+        if aliases_file:
+            output_files.append(aliases_file)
+    
+    # Keep generated code out of coverage reports; before renaming, so only Go files are marked
+    if ctx.attrs.coverage_marker:
+        output_files = _add_coverage_marker(ctx, output_files)
+    
+    # Rename outputs for packaging pipelines that need non-default extensions
+    if ctx.attrs.output_extension_map:
+        output_files = _apply_output_extension_map(ctx, output_files)
+    
+    # Package documentation is built alongside, but is not Go source
+    other_outputs = [oneof_names_file] if oneof_names_file else []
+    if ctx.attrs.doc_index:
+        index_dir = generate_package_doc_index(
+            ctx, proto_info, tools["protoc"], ctx.attrs._package_doc_index[DefaultInfo].default_outputs[0],
+            proto_path_dirs = _implicit_proto_dirs(ctx),
+        )
+        other_outputs.append(index_dir)
+        sub_targets["doc_index"] = [DefaultInfo(default_outputs = [index_dir])]
+    
+    dependencies = [
+        "google.golang.org/protobuf",
+        "google.golang.org/grpc",
+    ] if "go-grpc" in ctx.attrs.plugins else ["google.golang.org/protobuf"]
+    if ctx.attrs.grpc_observability == "prometheus":
+        dependencies += [
+            "github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus",
+            "github.com/prometheus/client_golang",
+        ]
+    elif ctx.attrs.grpc_observability == "otel":
+        dependencies += [
+            "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc",
+            "go.opentelemetry.io/otel/metric",
+        ]
+    interceptors = ctx.attrs.grpc_server_interceptors
+    if "metrics" in interceptors and ctx.attrs.grpc_observability != "prometheus":
+        dependencies += [
+            "github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus",
+            "github.com/prometheus/client_golang",
+        ]
+    if "logging" in interceptors or "recovery" in interceptors or "validation" in interceptors:
+        dependencies.append("github.com/grpc-ecosystem/go-grpc-middleware/v2")
+    if "validation" in interceptors:
+        dependencies.append("github.com/bufbuild/protovalidate-go")
+    if ctx.attrs.redaction:
+        dependencies.append("github.com/birbparty/buck2-protobuf/proto/buck2protobuf/redact/v1")
+    if ctx.attrs.span_attributes:
+        dependencies.append("go.opentelemetry.io/otel")
+    if "grpc-gateway" in ctx.attrs.plugins:
+        dependencies += [
+            "github.com/grpc-ecosystem/grpc-gateway/v2",
+            "google.golang.org/genproto/googleapis/api",
+        ]
+    
+    # Declared in lexical order so that the output manifest is stable
+    output_files = sort_by_path(output_files)
+    
     # Create LanguageProtoInfo provider
     language_proto_info = LanguageProtoInfo(
         language = "go",
         generated_files = output_files,
         package_name = go_package,
-        dependencies = [
-            "google.golang.org/protobuf",
-            "google.golang.org/grpc",
-        ] if "go-grpc" in ctx.attrs.plugins else ["google.golang.org/protobuf"],
+        dependencies = dependencies,
         compiler_flags = [],
     )
     
     # Return providers
     return [
-        DefaultInfo(default_outputs = output_files),
+        DefaultInfo(
+            default_outputs = output_files,
+            other_outputs = other_outputs,
+            sub_targets = sub_targets,
+        ),
         language_proto_info,
     ]
 
@@ -328,10 +1758,70 @@ go_proto_library_rule = rule(
     attrs = {
         "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
         "go_package": attrs.string(default = "", doc = "Go package path override"),
+        "import_mappings": attrs.list(attrs.string(), default = [], doc = "protoc M<file>=<Go import path> mappings of imported files"),
         "plugins": attrs.list(attrs.string(), default = ["go", "go-grpc"], doc = "Protoc plugins to use"),
         "options": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Additional protoc options"),
+        "custom_plugins": attrs.dict(attrs.string(), attrs.exec_dep(), default = {}, doc = "Additional protoc plugins by name"),
+        "plugin_options": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Parameter of each custom plugin"),
+        "plugin_order": attrs.list(attrs.string(), default = [], doc = "Sequential plugin execution order"),
+        "plugin_prefix": attrs.string(default = "", doc = "Directory of protoc-gen-<name> binaries for plugins that are not built in"),
+        "plugin_memory_limit": attrs.string(default = "", doc = "Memory cap of each plugin process, e.g. 512M"),
+        "openapi_v3_title": attrs.string(default = "", doc = "API title of the OpenAPI v3 document"),
+        "openapi_v3_version": attrs.string(default = "", doc = "API version of the OpenAPI v3 document"),
+        "openapi_v3_server_url": attrs.string(default = "", doc = "Server URL added to the OpenAPI v3 document"),
+        "openapi_v3_merge": attrs.bool(default = False, doc = "Merge several services into one OpenAPI v3 document"),
+        "_openapi_v3_doc": attrs.exec_dep(default = "//tools:openapi_v3_doc.py"),
+        "_googleapis_protos": attrs.dep(default = "//proto:googleapis_http_protos", doc = "google/api HTTP annotation protos"),
+        "_protoc_pipeline": attrs.exec_dep(default = "//tools:protoc_pipeline.py"),
+        "per_file_actions": attrs.bool(default = True, doc = "Run one protoc action per proto file"),
+        "_proto_import_graph": attrs.exec_dep(default = "//tools:proto_import_graph.py"),
         "go_module": attrs.string(default = "", doc = "Go module name for go.mod file"),
         "embed": attrs.list(attrs.source(), default = [], doc = "Additional files to embed"),
+        "json_casing": attrs.string(default = "", doc = "Field name casing for generated JSON marshalers"),
+        "json_wkt_formats": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "JSON formats for well-known types in generated marshalers"),
+        "grpc_observability": attrs.string(default = "", doc = "Metrics backend for generated observable gRPC servers"),
+        "grpc_message_limits": attrs.bool(default = False, doc = "Generate server options applying annotated message size limits"),
+        "grpc_server_interceptors": attrs.list(attrs.string(), default = [], doc = "Standard interceptors chained by the generated DefaultServerOptions()"),
+        "grpc_client_timeouts": attrs.bool(default = False, doc = "Generate client wrappers applying annotated method timeouts"),
+        "grpc_keepalive": attrs.bool(default = False, doc = "Generate server and dial options applying annotated keepalive settings"),
+        "grpc_status_codes": attrs.bool(default = False, doc = "Generate gRPC status conversions for annotated error messages"),
+        "grpc_status_code_option": attrs.string(default = "", doc = "Message option holding the gRPC code name"),
+        "grpc_retry_codes": attrs.bool(default = False, doc = "Generate IsRetryable() classifying failures by annotated retryable codes"),
+        "build_info": attrs.bool(default = False, doc = "Generate a BuildInfo variable from the build stamp"),
+        "build_stamp": attrs.option(attrs.source(), default = None, doc = "Build stamp file (workspace status lines)"),
+        "build_time": attrs.string(default = "", doc = "Fixed build time for BuildInfo"),
+        "redaction": attrs.bool(default = False, doc = "Generate Redact() methods for PII-annotated fields"),
+        "span_attributes": attrs.bool(default = False, doc = "Generate SpanAttributes() methods for fields annotated as span attributes"),
+        "enum_strings": attrs.bool(default = False, doc = "Generate text marshaling of enums with external strings"),
+        "enum_string_option": attrs.string(default = "", doc = "Enum value option holding the external string"),
+        "recursion_guard_depth": attrs.int(default = 0, doc = "Max nesting depth enforced by generated UnmarshalSafe methods"),
+        "arena_constructors": attrs.bool(default = False, doc = "Generate arena constructors (GOEXPERIMENT=arenas)"),
+        "field_index": attrs.bool(default = False, doc = "Generate field name to number, wire type and offset maps"),
+        "init_hook": attrs.string(default = "", doc = "Function called from a generated init() with each file descriptor"),
+        "descriptor_embed": attrs.string(default = "", doc = "Format of the embedded compact file descriptor (raw or gzip)"),
+        "descriptor_embed_imports": attrs.bool(default = False, doc = "Embed a descriptor set including transitive imports"),
+        "grpc_service_names": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Proto service name to wire-level gRPC service name"),
+        "grpc_service_prefix": attrs.string(default = "", doc = "Prefix prepended to every wire-level gRPC service name"),
+        "_grpc_service_rename": attrs.exec_dep(default = "//tools:grpc_service_rename.py"),
+        "grpc_split_services": attrs.list(attrs.string(), default = [], doc = "Services whose gRPC stubs are written to their own <service>_grpc.pb.go"),
+        "_go_grpc_split": attrs.exec_dep(default = "//tools:go_grpc_split.py"),
+        "check_custom_options": attrs.bool(default = False, doc = "Verify custom options are defined before running protoc"),
+        "_schema_lint": attrs.exec_dep(default = "//tools:schema_lint.py"),
+        "strict_deps": attrs.bool(default = False, doc = "Fail on imports not provided by a direct proto dep"),
+        "_strict_deps": attrs.exec_dep(default = "//tools:strict_deps.py"),
+        "validate_tags": attrs.bool(default = False, doc = "Add ORM validation struct tags from protovalidate rules"),
+        "validate_tag_key": attrs.string(default = "validate", doc = "Struct tag key for validate_tags"),
+        "validate_tag_rules": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Protovalidate rule to tag template overrides"),
+        "_go_validate_tags": attrs.exec_dep(default = "//tools:go_validate_tags.py"),
+        "_go_coverage_marker": attrs.exec_dep(default = "//tools:go_coverage_marker.py"),
+        "oneof_wrapper_baseline": attrs.option(attrs.source(), default = None, doc = "Expected oneof wrapper type names (JSON)"),
+        "oneof_wrapper_aliases": attrs.bool(default = False, doc = "Generate aliases for drifted oneof wrapper names instead of failing"),
+        "_go_oneof_names": attrs.exec_dep(default = "//tools:go_oneof_names.py"),
+        "output_extension_map": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Output file suffix to replacement suffix"),
+        "coverage_marker": attrs.string(default = "", doc = "Coverage exclusion comment added to every generated Go file"),
+        "doc_index": attrs.bool(default = False, doc = "Write a markdown index per proto package"),
+        "_package_doc_index": attrs.exec_dep(default = "//tools:package_doc_index.py"),
+        "_go_helper_gen": attrs.exec_dep(default = "//tools:go_helper_gen.py"),
         "_protoc": attrs.exec_dep(default = "//tools:protoc"),
         "_protoc_gen_go": attrs.exec_dep(default = "//tools:protoc-gen-go", doc = "Go protoc plugin"),
         "_protoc_gen_go_grpc": attrs.exec_dep(default = "//tools:protoc-gen-go-grpc", doc = "Go gRPC protoc plugin"),
@@ -374,6 +1864,16 @@ def go_grpc_library(
     proto: str,
     go_package: str = "",
     visibility: list[str] = ["//visibility:private"],
+    observability: str = "",
+    service_names: dict[str, str] = {},
+    service_prefix: str = "",
+    split_services: list[str] = [],
+    server_interceptors: list[str] = [],
+    client_timeouts: bool = False,
+    keepalive: bool = False,
+    status_codes: bool = False,
+    status_code_option: str = "",
+    retry_codes: bool = False,
     **kwargs
 ):
     """
@@ -387,7 +1887,47 @@ def go_grpc_library(
         proto: proto_library target (must contain service definitions)
         go_package: Go package path override
         visibility: Buck2 visibility specification
+        observability: Opt-in metrics backend ("prometheus" or "otel") for generated
+                       New<Service>ObservableServer constructors that also enable channelz,
+                       in a separate *_observability.pb.go file
+        service_names: Map of fully-qualified proto service name to the wire-level service
+                       name registered with gRPC, for zero-downtime renames; Go type names
+                       are unchanged
+        service_prefix: Prefix prepended to the wire-level name of every service, for
+                        tenant-scoped routing: servers register and clients call
+                        /<prefix>.<package>.<Service>/<Method>; Go type names are unchanged
+        split_services: Fully-qualified names of every service; each service's stubs are
+                        written to its own <service>_grpc.pb.go (user_service_grpc.pb.go)
+                        instead of one <file>_grpc.pb.go per proto file
+        server_interceptors: Opt-in standard interceptors ("metrics", "logging", "recovery",
+                             "validation") chained by a generated DefaultServerOptions()
+                             in a separate *_interceptors.pb.go file
+        client_timeouts: Opt-in New<Service>TimeoutClient() wrappers applying per-method
+                         timeouts from (buck2protobuf.deadline.v1.timeout_ms) to calls
+                         without a deadline, in a separate *_client_timeouts.pb.go file
+        keepalive: Opt-in <Service>KeepaliveServerOptions() and <Service>KeepaliveDialOptions()
+                   applying the settings of (buck2protobuf.keepalive.v1.keepalive), in a
+                   separate *_keepalive.pb.go file
+        status_codes: Opt-in error and GRPCStatus() methods on messages annotated with
+                      (buck2protobuf.errors.v1.grpc_code), plus StatusFromErr(err)
+                      converting domain errors to statuses, in *_status.pb.go files
+        status_code_option: Fully-qualified message option to read the code name from
+                            instead of buck2protobuf.errors.v1.grpc_code
+        retry_codes: Opt-in IsRetryable(method, err) for retry interceptors, classifying
+                     failed calls by the codes listed in the method's
+                     (buck2protobuf.retry.v1.retry) annotation, in *_retry.pb.go files
         **kwargs: Additional arguments
+    
+    Example:
+        go_grpc_library(
+            name = "user_go_grpc",
+            proto = ":user_proto",
+            service_names = {"acme.user.v2.UserService": "acme.user.v1.UserService"},
+            server_interceptors = ["recovery", "logging", "validation"],
+            client_timeouts = True,
+            status_codes = True,
+            retry_codes = True,
+        )
     """
     go_proto_library(
         name = name,
@@ -395,5 +1935,15 @@ def go_grpc_library(
         go_package = go_package,
         visibility = visibility,
         plugins = ["go", "go-grpc"],  # Both messages and gRPC services
+        grpc_observability = observability,
+        grpc_service_names = service_names,
+        grpc_service_prefix = service_prefix,
+        grpc_split_services = split_services,
+        grpc_server_interceptors = server_interceptors,
+        grpc_client_timeouts = client_timeouts,
+        grpc_keepalive = keepalive,
+        grpc_status_codes = status_codes,
+        grpc_status_code_option = status_code_option,
+        grpc_retry_codes = retry_codes,
         **kwargs
     )
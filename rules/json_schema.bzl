@@ -0,0 +1,143 @@
+"""JSON Schema generation rules for Buck2.
+
+This module provides a rule that generates JSON Schema documents from
+protobuf messages with protoc-gen-jsonschema, so frontends and API docs can
+validate JSON payloads against the same schema as the protobuf API.
+"""
+
+load("//rules/private:providers.bzl", "LanguageProtoInfo", "ProtoInfo")
+load("//rules/private:utils.bzl", "protoc_source_args")
+load("//rules:tools.bzl", "TOOL_ATTRS", "get_plugin_binary", "get_protoc_binary")
+
+# JSON Schema drafts the schemas can be written for
+JSON_SCHEMA_DRAFTS = ["draft-07", "2020-12"]
+
+def proto_json_schema(
+    name: str,
+    proto: str,
+    draft: str = "2020-12",
+    roots: list[str] = [],
+    visibility: list[str] = ["//visibility:private"],
+    **kwargs
+):
+    """
+    Generates a JSON Schema per top-level message of a proto_library target.
+
+    protoc-gen-jsonschema writes the schemas, using protojson field names
+    (lowerCamelCase or json_name); each schema holds the messages it
+    references as definitions. Protovalidate rules with a JSON Schema
+    equivalent are then added as keywords: string min_len/max_len/len,
+    pattern and formats (email, hostname, ipv4, ipv6, uri, uuid), numeric
+    gt/gte/lt/lte, const and in, repeated min_items/max_items/unique, map
+    min_pairs/max_pairs, and required. Other rules are listed in the
+    property's $comment.
+
+    Args:
+        name: Unique name for this JSON Schema target
+        proto: proto_library target to generate schemas from
+        draft: JSON Schema draft of the output, "draft-07" or "2020-12"
+        roots: Fully-qualified top-level messages to generate schemas for; other
+               messages are only written if a root references them, directly
+               or transitively (default: every top-level message)
+        visibility: Buck2 visibility specification
+        **kwargs: Additional arguments passed to underlying rule
+
+    Example:
+        proto_json_schema(
+            name = "user_json_schema",
+            proto = ":user_proto",
+            draft = "draft-07",
+            roots = ["acme.user.v1.CreateUserRequest"],
+        )
+
+    Generated Files:
+        - <name>/<package>.<Message>.json: JSON Schema of each selected message
+    """
+    if draft not in JSON_SCHEMA_DRAFTS:
+        fail("draft must be one of {}, got '{}'".format(JSON_SCHEMA_DRAFTS, draft))
+    for root in roots:
+        if not root or root.startswith(".") or " " in root:
+            fail("roots must be fully-qualified message names such as acme.user.v1.User, got '{}'".format(root))
+
+    proto_json_schema_rule(
+        name = name,
+        proto = proto,
+        draft = draft,
+        roots = roots,
+        visibility = visibility,
+        **kwargs
+    )
+
+def _proto_json_schema_impl(ctx):
+    """
+    Implementation function for proto_json_schema rule.
+
+    Runs protoc-gen-jsonschema into a staging directory, then
+    tools/proto_json_schema.py selects the messages, translates protovalidate
+    rules and converts the schemas to the requested draft.
+    """
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    protoc = get_protoc_binary(ctx)
+    plugin = get_plugin_binary(ctx, "protoc-gen-jsonschema")
+    raw_dir = ctx.actions.declare_output("{}_raw".format(ctx.label.name), dir = True)
+    output_dir = ctx.actions.declare_output(ctx.label.name, dir = True)
+
+    source_args, source_inputs = protoc_source_args(proto_info)
+    cmd = cmd_args([protoc])
+    cmd.add("--plugin=protoc-gen-jsonschema={}".format(plugin))
+    cmd.add(cmd_args(raw_dir.as_output(), format = "--jsonschema_out={}"))
+    cmd.add("--jsonschema_opt=json_fieldnames")
+    cmd.add("--jsonschema_opt=prefix_schema_files_with_package")
+    cmd.add(source_args)
+
+    ctx.actions.run(
+        cmd,
+        category = "json_schema_protoc",
+        identifier = ctx.label.name,
+        inputs = [protoc, plugin] + source_inputs,
+        env = {
+            "PATH": "/usr/bin:/bin:/usr/local/bin",
+        },
+    )
+
+    finalize_cmd = cmd_args([
+        "python3",
+        ctx.attrs._proto_json_schema[DefaultInfo].default_outputs[0],
+        "--input-dir", raw_dir,
+        "--output-dir", output_dir.as_output(),
+        "--draft", ctx.attrs.draft,
+    ])
+    for root in ctx.attrs.roots:
+        finalize_cmd.add("--root", root)
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files:
+            finalize_cmd.add("--dep", dep_file)
+    finalize_cmd.add(proto_info.proto_files)
+
+    ctx.actions.run(
+        finalize_cmd,
+        category = "json_schema",
+        identifier = ctx.label.name,
+    )
+
+    return [
+        DefaultInfo(default_outputs = [output_dir]),
+        LanguageProtoInfo(
+            language = "jsonschema",
+            generated_files = [output_dir],
+            package_name = "",
+            dependencies = [],
+            compiler_flags = [],
+        ),
+    ]
+
+# JSON Schema rule definition
+proto_json_schema_rule = rule(
+    impl = _proto_json_schema_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
+        "draft": attrs.string(default = "2020-12", doc = "JSON Schema draft: draft-07 or 2020-12"),
+        "roots": attrs.list(attrs.string(), default = [], doc = "Root messages; unreferenced messages are skipped"),
+        "_proto_json_schema": attrs.exec_dep(default = "//tools:proto_json_schema.py"),
+    }),
+)
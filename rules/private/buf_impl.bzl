@@ -7,7 +7,9 @@ configuration discovery, and error reporting.
 
 load("//rules/private:providers.bzl", "BufLintInfo", "BufFormatInfo", "BufBreakingInfo", "BufToolchainInfo")
 load("//rules/private:buf_config.bzl", "discover_comprehensive_buf_config", "create_buf_config", "validate_buf_config", "create_effective_buf_config")
-load("//rules/private:utils.bzl", "get_short_path", "create_cache_key")
+load("//rules/private:utils.bzl", "get_short_path", "create_cache_key", "compile_descriptor_set")
+load("//rules/private:providers.bzl", "ProtoInfo")
+load("//rules:tools.bzl", "get_protoc_binary")
 
 def buf_lint_impl(ctx):
     """
@@ -139,6 +141,84 @@ def buf_lint_impl(ctx):
         buf_lint_info,
     ]
 
+def buf_proto_lint_impl(ctx):
+    """
+    Implementation function for buf_lint with a proto_library.
+    
+    Lints the descriptor set the library is compiled to, shared with code
+    generation, instead of re-parsing the sources with buf. Diagnostics are
+    limited to the library's own files and written as a sorted text report.
+    
+    Args:
+        ctx: Buck2 rule context
+        
+    Returns:
+        List of providers including BufLintInfo and DefaultInfo
+    """
+    buf_cli = ctx.attrs._buf_toolchain[BufToolchainInfo].buf_cli
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    
+    image = compile_descriptor_set(
+        ctx, proto_info, get_protoc_binary(ctx), "{}.binpb".format(ctx.label.name),
+        include_source_info = True,
+        category = "buf_lint_descriptor_set",
+    )
+    
+    lint_report_text = ctx.actions.declare_output("{}.txt".format(ctx.label.name))
+    lint_report_json = ctx.actions.declare_output("{}.json".format(ctx.label.name))
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._buf_lint_check[DefaultInfo].default_outputs[0],
+        "--buf", buf_cli,
+        "--image", image,
+        "--output", lint_report_text.as_output(),
+        "--json-output", lint_report_json.as_output(),
+    ])
+    for rule in ctx.attrs.use:
+        cmd.add("--use", rule)
+    for rule in ctx.attrs.except_rules:
+        cmd.add("--except", rule)
+    for path in ctx.attrs.ignore:
+        cmd.add("--ignore", path)
+    for rule, paths in ctx.attrs.ignore_only.items():
+        for path in paths:
+            cmd.add("--ignore-only", "{}={}".format(rule, path))
+    for proto_file in proto_info.proto_files:
+        cmd.add("--target", proto_file.short_path)
+    if ctx.attrs.fail_on_error:
+        cmd.add("--exit-code")
+    
+    ctx.actions.run(
+        cmd,
+        category = "buf_lint",
+        identifier = ctx.label.name,
+        env = {"BUF_CACHE_DIR": "buck-out/buf-cache"},
+    )
+    
+    buf_lint_info = BufLintInfo(
+        lint_report = lint_report_json,
+        violations = [],  # Listed in the JSON report
+        passed = True,  # The action fails on violations when fail_on_error is set
+        config_used = None,
+        files_linted = proto_info.proto_files,
+        lint_time_ms = 0,
+        rules_applied = ctx.attrs.use,
+        error_count = 0,
+        warning_count = 0,
+    )
+    
+    return [
+        DefaultInfo(
+            default_outputs = [lint_report_text],
+            sub_targets = {
+                "json": [DefaultInfo(default_outputs = [lint_report_json])],
+                "descriptor_set": [DefaultInfo(default_outputs = [image])],
+            },
+        ),
+        buf_lint_info,
+    ]
+
 def buf_format_impl(ctx):
     """
     Implementation function for buf_format rule.
@@ -304,6 +384,94 @@ def buf_format_impl(ctx):
         buf_format_info,
     ]
 
+def _buf_format_sources(ctx):
+    """Returns the checked-in sources of the proto library; generated files are never formatted."""
+    sources = [f for f in ctx.attrs.proto[ProtoInfo].proto_files if f.is_source]
+    if not sources:
+        fail("buf_format_check: {} has no checked-in proto sources".format(ctx.attrs.proto.label))
+    return sources
+
+def buf_format_check_impl(ctx):
+    """
+    Implementation function for buf_format_check.
+    
+    Runs buf format on each source of the proto library and writes the
+    changes it would make as a unified diff to <name>.diff. The action fails
+    when a file is not canonically formatted, printing the same diff.
+    
+    Args:
+        ctx: Buck2 rule context
+        
+    Returns:
+        List of providers including BufFormatInfo and DefaultInfo
+    """
+    buf_cli = ctx.attrs._buf_toolchain[BufToolchainInfo].buf_cli
+    sources = _buf_format_sources(ctx)
+    diff_report = ctx.actions.declare_output("{}.diff".format(ctx.label.name))
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._buf_format_check[DefaultInfo].default_outputs[0],
+        "--buf", buf_cli,
+        "--output", diff_report.as_output(),
+        "--fix-hint", "buck2 run //{}:{}_format".format(ctx.label.package, ctx.label.name),
+        "--exit-code",
+    ])
+    for pattern in ctx.attrs.exclude:
+        cmd.add("--exclude", pattern)
+    cmd.add(sources)
+    
+    ctx.actions.run(
+        cmd,
+        category = "buf_format_check",
+        identifier = ctx.label.name,
+        env = {"BUF_CACHE_DIR": "buck-out/buf-cache"},
+    )
+    
+    buf_format_info = BufFormatInfo(
+        formatted_files = [],
+        diff_report = diff_report,
+        needs_formatting = False,  # The action fails when files need formatting
+        files_processed = sources,
+        format_time_ms = 0,
+        changes_made = 0,
+        diff_output = diff_report,
+    )
+    
+    return [
+        DefaultInfo(default_outputs = [diff_report]),
+        buf_format_info,
+    ]
+
+def buf_format_fix_impl(ctx):
+    """
+    Implementation function for the runnable companion of buf_format_check.
+    
+    Args:
+        ctx: Buck2 rule context
+        
+    Returns:
+        List of providers including RunInfo
+    """
+    buf_cli = ctx.attrs._buf_toolchain[BufToolchainInfo].buf_cli
+    
+    # Source artifacts resolve to their paths in the repository under
+    # `buck2 run`, so the files are rewritten in place
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._buf_format_check[DefaultInfo].default_outputs[0],
+        "--buf", buf_cli,
+        "--write",
+    ])
+    for pattern in ctx.attrs.exclude:
+        cmd.add("--exclude", pattern)
+    cmd.add(_buf_format_sources(ctx))
+    
+    return [
+        DefaultInfo(),
+        RunInfo(args = cmd),
+    ]
+
 def buf_breaking_impl(ctx):
     """
     Implementation function for buf_breaking rule.
@@ -2,7 +2,12 @@
 
 This module provides the core logic for coordinating code generation across
 multiple languages from a single proto_library target. It handles language
-configuration, parallel execution, and output organization.
+configuration, per-language target creation, and output organization.
+
+The proto_bundle macro compiles the library once into a shared descriptor
+set and instantiates one target per language that reads it, so each
+language's protoc invocation is a separate action: they run in parallel,
+are cached independently, and a failure names the language's target.
 """
 
 load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo", "ProtoBundleInfo", "ConsistencyReport")
@@ -114,12 +119,13 @@ def generate_language_target_name(base_name, language):
     """
     return "{}_{}".format(base_name, language)
 
-def create_language_target(ctx, language, config, proto_target, target_name):
+def create_language_target(language, config, proto_target, target_name):
     """
     Creates a language-specific generation target within the bundle.
     
+    Must be called from a macro, since it instantiates the language's rule.
+    
     Args:
-        ctx: Buck2 rule context
         language: Target language name
         config: Language-specific configuration
         proto_target: Proto library target
@@ -249,6 +255,7 @@ def _validate_api_surface_consistency(language_targets, proto_info):
     # Note: In a production implementation, this would parse the actual proto files
     # For now, we'll do basic validation based on configuration
     
+    patterns = {}
     for language, target_info in language_targets.items():
         config = target_info["config"]
         plugins = config["plugins"]
@@ -261,16 +268,12 @@ def _validate_api_surface_consistency(language_targets, proto_info):
         
         # Store for cross-language comparison
         # This is simplified - full implementation would parse proto definitions
-        if not hasattr(_validate_api_surface_consistency, "_service_patterns"):
-            _validate_api_surface_consistency._service_patterns = {}
-        
-        _validate_api_surface_consistency._service_patterns[language] = {
+        patterns[language] = {
             "has_grpc": has_grpc,
             "plugins": plugins,
         }
     
     # Compare patterns across languages
-    patterns = getattr(_validate_api_surface_consistency, "_service_patterns", {})
     if len(patterns) > 1:
         grpc_patterns = [p["has_grpc"] for p in patterns.values()]
         if not all(grpc_patterns) and any(grpc_patterns):
@@ -311,9 +311,6 @@ def _hash_consistency_checks(ctx):
     if hasattr(ctx.attrs, 'consistency_checks'):
         checks.append("consistency_checks={}".format(ctx.attrs.consistency_checks))
     
-    if hasattr(ctx.attrs, 'parallel_generation'):
-        checks.append("parallel_generation={}".format(ctx.attrs.parallel_generation))
-    
     if not checks:
         return "default-checks"
     
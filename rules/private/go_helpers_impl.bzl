@@ -0,0 +1,72 @@
+"""Implementation of optional Go helper generation.
+
+This module runs generators from tools/go_helper_gen.py alongside protoc-gen-go.
+Each enabled helper writes one additional file per proto source
+(`<base>_<suffix>.pb.go`) into the same Go package, leaving the canonical
+protoc-gen-go output untouched.
+"""
+
+def _proto_base_name(proto_file):
+    """Returns the proto file basename without the .proto extension."""
+    base_name = proto_file.basename
+    if base_name.endswith(".proto"):
+        base_name = base_name[:-6]
+    return base_name
+
+def generate_go_helpers(ctx, proto_info, go_package: str, generator: str, suffix: str, config: dict, stamp = None, descriptor_set = None):
+    """
+    Generates a Go helper file for each proto source of a go_proto_library.
+
+    Args:
+        ctx: Buck2 rule context (must provide the _go_helper_gen attribute)
+        proto_info: ProtoInfo provider from proto dependency
+        go_package: Resolved Go package path
+        generator: Name of the generator in tools/go_helper_gen.py
+        suffix: Output file suffix; files are named <base>_<suffix>.pb.go
+        config: Generator configuration, passed to the tool as JSON
+        stamp: Optional build stamp file (workspace status "KEY value" lines)
+        descriptor_set: Optional FileDescriptorSet of the proto files and their imports
+
+    Returns:
+        List of generated Go files
+    """
+    outputs = [
+        ctx.actions.declare_output("go", "{}_{}.pb.go".format(_proto_base_name(proto_file), suffix))
+        for proto_file in proto_info.proto_files
+    ]
+
+    config_file = ctx.actions.write(
+        "{}_{}_config.json".format(ctx.label.name, generator),
+        json.encode(config),
+    )
+
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._go_helper_gen[DefaultInfo].default_outputs[0],
+        "--generator", generator,
+        "--config", config_file,
+        "--go-package", go_package,
+    ])
+
+    if stamp:
+        cmd.add("--stamp", stamp)
+
+    if descriptor_set:
+        cmd.add("--descriptor-set", descriptor_set)
+
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files:
+            cmd.add("--dep", dep_file)
+
+    for output in outputs:
+        cmd.add("--output", output.as_output())
+
+    cmd.add(proto_info.proto_files)
+
+    ctx.actions.run(
+        cmd,
+        category = "go_helper_gen",
+        identifier = "{}_{}".format(ctx.label.name, generator),
+    )
+
+    return outputs
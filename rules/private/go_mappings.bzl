@@ -0,0 +1,123 @@
+"""Path mappings of go_proto_library.
+
+These helpers compute how go_proto_library attributes map files and return
+errors as strings instead of failing, so the rule decides when to fail and
+tests can check the messages.
+"""
+
+def parse_import_mappings(import_mappings: list[str]):
+    """
+    Parses protoc M<file>.proto=<Go import path> entries.
+    
+    Args:
+        import_mappings: Entries of the import_mappings attribute
+        
+    Returns:
+        Tuple of (dict of proto import path to Go import path, error message
+        or "" if an entry is malformed or a file is mapped twice)
+    """
+    mappings = {}
+    for mapping in import_mappings:
+        proto_path, sep, import_path = mapping.removeprefix("M").partition("=")
+        if not mapping.startswith("M") or not sep or not proto_path.endswith(".proto") or not import_path:
+            return {}, "import_mappings entries must be M<file>.proto=<Go import path>, got '{}'".format(mapping)
+        if proto_path.startswith("/") or " " in import_path:
+            return {}, "import_mappings entry '{}' must map an import path such as foo/bar.proto to a Go import path".format(mapping)
+        if proto_path in mappings:
+            return {}, "import_mappings maps {} to both {} and {}".format(proto_path, mappings[proto_path], import_path)
+        mappings[proto_path] = import_path
+    return mappings, ""
+
+def import_mapping_error(proto_path: str, own_files: dict, graph: dict, target: str) -> str:
+    """
+    Checks that an import mapping relocates a file imported by target.
+    
+    Args:
+        proto_path: Mapped proto import path
+        own_files: Import paths of the files of target
+        graph: Import paths of every file in the dependency graph of target
+        target: proto_library target of the go_proto_library
+        
+    Returns:
+        Error message, or "" if the mapping applies
+    """
+    if proto_path in own_files:
+        return "import_mappings maps {}, a file of {}; set go_package instead".format(proto_path, target)
+    if proto_path not in graph:
+        return "import_mappings maps {}, which is not in the dependency graph of {}".format(proto_path, target)
+    return ""
+
+# Option key prefix of each built-in plugin; go_grpc_ keys also start with go_
+_PLUGIN_OPTION_PREFIXES = {
+    "go": "go_",
+    "go-grpc": "go_grpc_",
+    "connect-go": "connect_go_",
+}
+
+def plugin_option_args(plugin: str, options: dict[str, str]) -> list[str]:
+    """
+    Returns the --<plugin>_opt arguments of the prefixed options of a plugin.
+    
+    Args:
+        plugin: Built-in plugin name ("go", "go-grpc" or "connect-go")
+        options: options attribute of go_proto_library, e.g. {"go_grpc_require_unimplemented_servers": "false"}
+        
+    Returns:
+        Arguments such as --go-grpc_opt=require_unimplemented_servers=false
+    """
+    prefix = _PLUGIN_OPTION_PREFIXES[plugin]
+    args = []
+    for key, value in options.items():
+        if not key.startswith(prefix):
+            continue
+        # go_grpc_* options belong to go-grpc, not to go
+        if plugin == "go" and key.startswith("go_grpc_"):
+            continue
+        args.append("--{}_opt={}={}".format(plugin, key[len(prefix):], value))
+    return args
+
+def go_output_path(output_file) -> str:
+    """
+    Returns an output path relative to its output root.
+    
+    Outputs are declared under a root directory per step ("go", "go_coverage",
+    ...), followed by the path in the Go output tree, e.g.
+    "go/userv1connect/user.connect.go" -> "userv1connect/user.connect.go".
+    Outputs declared without a root (e.g. directories) keep their path.
+    """
+    root, sep, path = output_file.short_path.partition("/")
+    return path if sep else root
+
+def output_extension_renames(output_paths: list[str], extension_map: dict[str, str]):
+    """
+    Computes the renamed path of each output matched by output_extension_map.
+    
+    Renaming keeps the directory of the output, and the longest matching
+    suffix of its file name applies.
+    
+    Args:
+        output_paths: Output paths relative to the Go output root
+        extension_map: Output file suffix to replacement suffix
+        
+    Returns:
+        Tuple of (dict of output path to renamed path, error message or "" if
+        two outputs of the same directory would end up with the same name)
+    """
+    suffixes = sorted(extension_map.keys(), key = lambda suffix: -len(suffix))
+    
+    renames = {}
+    for path in output_paths:
+        file_name = path.split("/")[-1]
+        for suffix in suffixes:
+            if file_name.endswith(suffix):
+                renames[path] = path[:-len(suffix)] + extension_map[suffix]
+                break
+    
+    # Renamed files must not collide with each other or with kept files
+    sources = {}
+    for path in output_paths:
+        renamed = renames.get(path, path)
+        if renamed in sources:
+            return {}, "output_extension_map maps {} and {} to the same file {}".format(sources[renamed], path, renamed)
+        sources[renamed] = path
+    return renames, ""
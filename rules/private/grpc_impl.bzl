@@ -7,6 +7,7 @@ features like gRPC-Gateway, OpenAPI documentation, validation, and mocking.
 load("//rules/private:providers.bzl", "ProtoInfo", "GrpcServiceInfo", "PluginInfo")
 load("//rules/private:bundle_impl.bzl", "SUPPORTED_LANGUAGES")
 load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS")
+load("//rules/private:utils.bzl", "external_descriptor_set_args")
 
 # Advanced plugin configurations
 ADVANCED_PLUGINS = {
@@ -180,6 +181,7 @@ def generate_grpc_gateway_code(ctx, proto_info, plugin_config, output_dir):
     all_import_paths = proto_info.import_paths + proto_info.transitive_import_paths
     for import_path in all_import_paths:
         protoc_cmd.add("--proto_path={}".format(import_path))
+    protoc_cmd.add(external_descriptor_set_args(proto_info))
     
     # Configure gRPC-Gateway plugin
     protoc_cmd.add("--plugin=protoc-gen-grpc-gateway={}".format(tools["protoc-gen-grpc-gateway"]))
@@ -276,6 +278,7 @@ def generate_validation_code(ctx, proto_info, plugin_config, languages, output_d
     all_import_paths = proto_info.import_paths + proto_info.transitive_import_paths
     for import_path in all_import_paths:
         protoc_cmd.add("--proto_path={}".format(import_path))
+    protoc_cmd.add(external_descriptor_set_args(proto_info))
     
     # Configure validation plugin for each language
     for lang in languages:
@@ -45,6 +45,20 @@ ProtoInfo = provider(fields = [
     "java_package",         # Java package path (if specified)
     "lint_report",          # Lint validation report
     "breaking_report",      # Breaking change report
+    "shared_descriptor_set", # Descriptor set code generation reads instead of the sources (proto_bundle)
+    "direct_dep_proto_files", # Proto files of the direct deps and bsr_deps (strict dependency checks)
+    "proto_file_owners",     # Import path of each file of this library and its deps to its target label
+    "file_import_paths",     # Short path of each file of this library and its deps to its import path
+    "external_descriptor_sets", # Descriptor sets of bsr_module deps, read by protoc with --descriptor_set_in
+    "well_known_types_report", # Report of the check against the declared well-known types subset (or None)
+])
+
+# ProtoDescriptorSetInfo provider - a library compiled to a FileDescriptorSet (proto_descriptor_set)
+ProtoDescriptorSetInfo = provider(fields = [
+    "descriptor_set",       # Serialized FileDescriptorSet (.binpb)
+    "proto_files",          # Source .proto files of the compiled library
+    "include_imports",      # Whether the set contains the transitive imports
+    "include_source_info",  # Whether the set contains comments and source locations
 ])
 
 # LanguageProtoInfo provider - will be implemented across language tasks
@@ -56,6 +70,17 @@ LanguageProtoInfo = provider(fields = [
     "compiler_flags",       # Language-specific compiler flags
 ])
 
+# PyInfo provider - generated Python sources, field-compatible with rules_python's PyInfo
+PyInfo = provider(fields = [
+    "transitive_sources",   # Generated source trees of this library and its deps
+    "direct_pyi_files",     # Type stub trees generated by this library
+    "transitive_pyi_files", # Type stub trees of this library and its deps
+    "imports",              # Import roots to put on sys.path (the source trees)
+    "has_py3_only_sources", # Always True, generated code targets Python 3
+    "uses_shared_libraries", # Always False, generated code is pure Python
+    "package_mapping",      # Proto directory to Python package mapping of this library and its deps
+])
+
 # ProtoBundleInfo provider - information about multi-language bundles
 ProtoBundleInfo = provider(fields = [
     "bundle_name",          # Name of the bundle
@@ -321,6 +346,14 @@ BufToolchainInfo = provider(fields = [
     "checksum_verified",   # Whether the CLI checksum was verified
 ])
 
+# ProtocToolchainInfo provider - checksum-pinned protoc toolchain information
+ProtocToolchainInfo = provider(fields = [
+    "protoc",              # Verified protoc binary
+    "version",             # Version of protoc
+    "platform",            # Platform (e.g., "linux-x86_64")
+    "sha256",              # Archive checksum override ("" when the lockfile pin is used)
+])
+
 # Governance and review workflow providers
 
 # SchemaReviewInfo provider - schema review workflow information
@@ -0,0 +1,102 @@
+"""Implementation of schema lint checks.
+
+This module runs checks from tools/schema_lint.py over the sources of one or
+more proto_library targets. Each check is a single build action that writes a
+JSON report and fails the build when the check reports errors.
+"""
+
+load("//rules/private:providers.bzl", "ProtoInfo", "ValidationInfo")
+
+def _collect_proto_files(targets):
+    """
+    Collects direct and transitive proto files from proto_library targets.
+
+    Args:
+        targets: List of dependencies providing ProtoInfo
+
+    Returns:
+        Tuple of (direct proto files, transitive-only proto files)
+    """
+    direct = []
+    for target in targets:
+        for proto_file in target[ProtoInfo].proto_files:
+            if proto_file not in direct:
+                direct.append(proto_file)
+
+    transitive = []
+    for target in targets:
+        for proto_file in target[ProtoInfo].transitive_proto_files:
+            if proto_file not in direct and proto_file not in transitive:
+                transitive.append(proto_file)
+
+    return direct, transitive
+
+def schema_lint_impl(ctx):
+    """Implementation of the schema_lint rule."""
+    proto_files, dep_files = _collect_proto_files(ctx.attrs.protos)
+
+    config_file = ctx.actions.write(
+        "{}_config.json".format(ctx.label.name),
+        ctx.attrs.config,
+    )
+    report = ctx.actions.declare_output("{}_report.json".format(ctx.label.name))
+
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._schema_lint[DefaultInfo].default_outputs[0],
+        "--check", ctx.attrs.check,
+        "--config", config_file,
+        "--output", report.as_output(),
+    ])
+
+    for key, src in ctx.attrs.inputs.items():
+        cmd.add("--input", cmd_args(key, "=", src, delimiter = ""))
+
+    for dep_file in dep_files:
+        cmd.add("--dep", dep_file)
+
+    if ctx.attrs.baseline:
+        for baseline_file in ctx.attrs.baseline[ProtoInfo].proto_files:
+            cmd.add("--baseline", baseline_file)
+
+    cmd.add(proto_files)
+
+    ctx.actions.run(
+        cmd,
+        category = "schema_lint",
+        identifier = "{}_{}".format(ctx.label.name, ctx.attrs.check),
+    )
+
+    return [
+        DefaultInfo(default_outputs = [report]),
+        ValidationInfo(
+            passed = True,  # The action fails the build when errors are reported
+            report = report,
+            linter_used = "schema_lint:{}".format(ctx.attrs.check),
+        ),
+    ]
+
+def schema_lint_fix_impl(ctx):
+    """Implementation of the schema_lint_fix rule."""
+    proto_files, _ = _collect_proto_files(ctx.attrs.protos)
+
+    config_file = ctx.actions.write(
+        "{}_config.json".format(ctx.label.name),
+        ctx.attrs.config,
+    )
+
+    # Source artifacts resolve to their paths in the repository under
+    # `buck2 run`, so the fixer rewrites the checked-in files in place
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._schema_lint[DefaultInfo].default_outputs[0],
+        "--check", ctx.attrs.check,
+        "--config", config_file,
+        "--fix",
+    ])
+    cmd.add(proto_files)
+
+    return [
+        DefaultInfo(),
+        RunInfo(args = cmd),
+    ]
@@ -83,6 +83,80 @@ def get_proto_import_path(src, import_prefix = "", strip_import_prefix = ""):
     
     return path
 
+def resolve_import_root(src, package, import_roots):
+    """
+    Returns the import root a source file belongs to.
+    
+    Roots follow Bazel's strip_import_prefix semantics: a root is relative to
+    the package, or to the repository root when it starts with "/". When
+    several roots contain the file, the longest one wins.
+    
+    Args:
+        src: Source proto file
+        package: Package of the proto_library
+        import_roots: Map of root to the import_prefix of its files
+    
+    Returns:
+        Tuple of (root directory relative to the repository root, import prefix), or None
+    """
+    best = None
+    for root, import_prefix in import_roots.items():
+        if root.startswith("/"):
+            root_dir = root[1:]
+        else:
+            root_dir = "/".join([part for part in [package, root] if part])
+        root_dir = root_dir.rstrip("/")
+        if root_dir and not src.short_path.startswith(root_dir + "/"):
+            continue
+        if best == None or len(root_dir) > len(best[0]):
+            best = (root_dir, import_prefix)
+    return best
+
+def import_root_proto_path(root_dir, import_prefix):
+    """
+    Returns the --proto_path entry of an import root.
+    
+    A root with an import prefix uses protoc's VIRTUAL=DISK form, so that
+    third_party/proto/common/types.proto is imported as vendor/common/types.proto.
+    """
+    proto_path = root_dir or "."
+    import_prefix = import_prefix.strip("/")
+    return "{}={}".format(import_prefix, proto_path) if import_prefix else proto_path
+
+def merge_file_import_paths(label, file_import_paths_list):
+    """
+    Merges maps of proto file short paths to import paths.
+    
+    protoc registers a file once per import path: a file reachable under two
+    import paths (e.g. from two roots) defines its symbols twice, and two files
+    with one import path shadow each other. Both fail the build here, naming
+    the files.
+    
+    Args:
+        label: Label of the proto_library, for error messages
+        file_import_paths_list: Maps of short path to import path
+    
+    Returns:
+        Merged map of short path to import path
+    """
+    merged = {}
+    files_by_import_path = {}
+    for file_import_paths in file_import_paths_list:
+        for short_path, import_path in file_import_paths.items():
+            if merged.get(short_path, import_path) != import_path:
+                fail("proto_library '{}': {} is registered under two import paths, '{}' and '{}'; it must belong to one import root".format(
+                    label, short_path, merged[short_path], import_path))
+            if files_by_import_path.get(import_path, short_path) != short_path:
+                fail("proto_library '{}': import path '{}' is provided by both {} and {}".format(
+                    label, import_path, files_by_import_path[import_path], short_path))
+            merged[short_path] = import_path
+            files_by_import_path[import_path] = short_path
+    return merged
+
+def proto_import_path(proto_info, proto_file):
+    """Returns the path a proto file is imported as, e.g. common/types.proto."""
+    return (getattr(proto_info, "file_import_paths", None) or {}).get(proto_file.short_path, proto_file.short_path)
+
 def get_proto_package_option(proto_content, option_name):
     """
     Extracts a package option from proto file content.
@@ -130,6 +204,8 @@ def validate_proto_library_inputs(ctx):
                 ctx.label, src.short_path))
     
     # Check import prefix/strip prefix validity
+    if ctx.attrs.import_roots and (ctx.attrs.import_prefix or ctx.attrs.strip_import_prefix):
+        fail("proto_library '{}': import_roots replaces import_prefix and strip_import_prefix; set the prefixes per root".format(ctx.label))
     if ctx.attrs.strip_import_prefix and ctx.attrs.import_prefix:
         if ctx.attrs.strip_import_prefix.startswith(ctx.attrs.import_prefix):
             fail("proto_library '{}': strip_import_prefix '{}' cannot start with import_prefix '{}'".format(
@@ -166,6 +242,164 @@ def create_descriptor_set_action(ctx, proto_files, deps_descriptor_sets, import_
     
     return descriptor_set
 
+def compile_descriptor_set(ctx, proto_info, protoc, output_name: str, include_source_info: bool = False, category: str = "protoc_descriptor_set", include_imports: bool = True, proto_path_dirs = []):
+    """
+    Compiles a proto library and its imports to a FileDescriptorSet with protoc.
+    
+    Code generation and checks that need a parsed schema (descriptor
+    embedding, buf lint, ...) share this action, so protoc parses the
+    library the same way for all of them.
+    
+    Args:
+        ctx: Rule context
+        proto_info: ProtoInfo provider of the library
+        protoc: protoc binary
+        output_name: Name of the declared descriptor set output
+        include_source_info: Keep comments and source locations (needed for file:line diagnostics)
+        category: Action category
+        include_imports: Also include the files the library imports, transitively
+        proto_path_dirs: Directories of implicitly provided protos (e.g. the googleapis
+                         HTTP annotations) to add to the include path
+    
+    Returns:
+        Descriptor set file
+    """
+    descriptor_set = ctx.actions.declare_output(output_name)
+    cmd = cmd_args([
+        protoc,
+        cmd_args(descriptor_set.as_output(), format = "--descriptor_set_out={}"),
+    ])
+    if include_imports:
+        cmd.add("--include_imports")
+    if include_source_info:
+        cmd.add("--include_source_info")
+    for import_path in proto_info.import_paths + proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+    for proto_path_dir in proto_path_dirs:
+        cmd.add(cmd_args(proto_path_dir, format = "--proto_path={}"))
+    cmd.add(external_descriptor_set_args(proto_info))
+    cmd.add(sort_by_path(proto_info.proto_files))
+    
+    ctx.actions.run(
+        cmd,
+        category = category,
+        identifier = ctx.label.name,
+        inputs = [protoc] + proto_info.proto_files + proto_info.transitive_proto_files + proto_info.transitive_descriptor_sets + _check_reports(proto_info) + proto_path_dirs,
+    )
+    return descriptor_set
+
+def protoc_source_args(proto_info):
+    """
+    Returns the protoc arguments and inputs selecting a library's proto files.
+
+    Libraries re-exported by a proto_bundle carry the bundle's shared
+    descriptor set; protoc then reads the parsed files from it with
+    --descriptor_set_in, so each language's action only depends on that set
+    instead of re-parsing the sources.
+
+    Args:
+        proto_info: ProtoInfo provider of the library
+
+    Returns:
+        Tuple of (protoc arguments, action inputs)
+    """
+    proto_files = sort_by_path(proto_info.proto_files)
+    shared_descriptor_set = getattr(proto_info, "shared_descriptor_set", None)
+    if shared_descriptor_set:
+        args = [cmd_args(shared_descriptor_set, format = "--descriptor_set_in={}")]
+        args.extend([proto_import_path(proto_info, proto_file) for proto_file in proto_files])
+        return args, [shared_descriptor_set] + _check_reports(proto_info)
+
+    args = ["--proto_path={}".format(import_path) for import_path in proto_info.import_paths + proto_info.transitive_import_paths]
+    args.extend(external_descriptor_set_args(proto_info))
+    args.extend(proto_files)
+    return args, proto_files + proto_info.transitive_descriptor_sets + _check_reports(proto_info)
+
+def _check_reports(proto_info):
+    """
+    Returns the reports of checks proto_library runs on its sources.
+
+    Actions compiling the library depend on them, so a failing check (e.g.
+    an import outside the declared well-known types) fails code generation.
+    """
+    report = getattr(proto_info, "well_known_types_report", None)
+    return [report] if report else []
+
+def external_descriptor_set_args(proto_info):
+    """
+    Returns the protoc arguments reading the descriptor sets of external modules.
+
+    bsr_module targets provide their files as a compiled descriptor set
+    rather than sources on an import path; protoc resolves imports of those
+    files from the sets given to --descriptor_set_in.
+
+    Args:
+        proto_info: ProtoInfo provider of the library
+
+    Returns:
+        List with a single --descriptor_set_in argument, or empty
+    """
+    descriptor_sets = getattr(proto_info, "external_descriptor_sets", None)
+    if not descriptor_sets:
+        return []
+    return [cmd_args(cmd_args(descriptor_sets, delimiter = ":"), format = "--descriptor_set_in={}")]
+
+def generate_package_doc_index(ctx, proto_info, protoc, tool, proto_path_dirs = []):
+    """
+    Writes a markdown index (README.md) per proto package of a library.
+
+    The index is derived from a descriptor set with source info, so it
+    lists each service, message and enum with its doc comment summary.
+
+    Args:
+        ctx: Rule context
+        proto_info: ProtoInfo provider of the library
+        protoc: protoc binary
+        tool: tools/package_doc_index.py
+        proto_path_dirs: Directories of implicitly provided protos to add to the include path
+
+    Returns:
+        Directory with one <package path>/README.md per package
+    """
+    descriptor_set = compile_descriptor_set(
+        ctx, proto_info, protoc, "{}_doc_index.binpb".format(ctx.label.name),
+        include_source_info = True, category = "doc_index_descriptor_set",
+        proto_path_dirs = proto_path_dirs,
+    )
+    index_dir = ctx.actions.declare_output("doc_index", dir = True)
+    cmd = cmd_args([
+        "python3",
+        tool,
+        "--descriptor-set", descriptor_set,
+        "--output-dir", index_dir.as_output(),
+    ])
+    for proto_file in sort_by_path(proto_info.proto_files):
+        cmd.add("--target", proto_file.short_path)
+
+    ctx.actions.run(
+        cmd,
+        category = "package_doc_index",
+        identifier = ctx.label.name,
+    )
+    return index_dir
+
+def sort_by_path(files):
+    """
+    Sorts files or declared outputs lexically by short path.
+
+    Codegen rules pass proto files to protoc and return their outputs in
+    this order, so command lines and output manifests don't depend on the
+    order of srcs, dict iteration or dependency traversal, and identical
+    inputs produce identical action keys on every machine.
+
+    Args:
+        files: Source files or artifacts
+
+    Returns:
+        New list sorted by short_path
+    """
+    return sorted(files, key = lambda f: f.short_path)
+
 def get_short_path(file):
     """
     Get the short path of a file for use in commands.
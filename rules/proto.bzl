@@ -8,16 +8,34 @@ integration.
 The rules defined here follow the API specification and are implemented in Task 002.
 """
 
-load("//rules/private:utils.bzl", "merge_proto_infos", "get_proto_import_path", "validate_proto_library_inputs", "create_descriptor_set_action", "get_proto_package_option")
-load("//rules/private:providers.bzl", "ProtoInfo", "ProtoBundleInfo", "GrpcServiceInfo", "CacheKeyInfo", "CacheConfigInfo")
-load("//rules/private:bundle_impl.bzl", "validate_bundle_config", "create_language_target", "generate_language_target_name", "validate_cross_language_consistency", "create_bundle_info")
+load("//rules/private:utils.bzl", "merge_proto_infos", "get_proto_import_path", "validate_proto_library_inputs", "create_descriptor_set_action", "get_proto_package_option", "compile_descriptor_set", "sort_by_path", "resolve_import_root", "import_root_proto_path", "merge_file_import_paths")
+load("//rules/private:providers.bzl", "ProtoInfo", "ProtoDescriptorSetInfo", "ProtoBundleInfo", "GrpcServiceInfo", "CacheKeyInfo", "CacheConfigInfo")
+load("//rules/private:bundle_impl.bzl", "SUPPORTED_LANGUAGES", "validate_bundle_config", "create_language_target", "generate_language_target_name", "validate_cross_language_consistency", "create_bundle_info")
 load("//rules/private:grpc_impl.bzl", "validate_grpc_service_config", "generate_grpc_gateway_code", "generate_validation_code", "generate_mock_code", "create_grpc_service_info")
 load("//rules/private:cache_impl.bzl", "get_default_cache_config", "create_cache_key_info", "try_cache_lookup", "store_in_cache")
 load("//rules/private:cache_keys.bzl", "generate_cache_key_for_bundle", "generate_cache_key_for_grpc_service")
 load("//rules/private:bsr_impl.bzl", "resolve_bsr_dependencies", "validate_bsr_dependencies")
+load("//rules:tools.bzl", "TOOL_ATTRS", "get_protoc_binary")
 
-# Re-export ProtoInfo for external use
+# Well-known types bundled with protoc, selectable with proto_library(well_known_types = [...])
+WELL_KNOWN_TYPES = [
+    "any",
+    "api",
+    "compiler/plugin",
+    "descriptor",
+    "duration",
+    "empty",
+    "field_mask",
+    "source_context",
+    "struct",
+    "timestamp",
+    "type",
+    "wrappers",
+]
+
+# Re-export providers for external use
 ProtoInfo = ProtoInfo
+ProtoDescriptorSetInfo = ProtoDescriptorSetInfo
 
 def proto_library(
     name,
@@ -27,6 +45,7 @@ def proto_library(
     visibility = ["//visibility:private"],
     import_prefix = "",
     strip_import_prefix = "",
+    import_roots = {},
     options = {},
     validation = {},
     well_known_types = True,
@@ -48,9 +67,19 @@ def proto_library(
         visibility: Buck2 visibility specification controlling who can depend on this
         import_prefix: Prefix to add to all import paths for this library
         strip_import_prefix: Prefix to strip from import paths when resolving
+        import_roots: Source roots of a library whose files live under several
+                      roots, mapping each strip_import_prefix to the
+                      import_prefix of its files (Bazel semantics: relative to
+                      the package, or to the repository root with a leading
+                      "/"); e.g. {"proto": "", "third_party/proto": ""}. Each
+                      file uses the longest root containing it. Replaces
+                      import_prefix and strip_import_prefix
         options: Protobuf options to apply (go_package, java_package, etc.)
         validation: Validation configuration (see ValidationConfig below)
-        well_known_types: Whether to include Google's well-known types
+        well_known_types: Well-known types the library may import: True for
+                          all (default), False for none, or a list of names
+                          from WELL_KNOWN_TYPES (e.g. ["timestamp"]); importing
+                          any other google/protobuf file fails the build
         protoc_version: Specific protoc version to use (defaults to global config)
         **kwargs: Additional arguments passed to underlying rule
     
@@ -70,6 +99,15 @@ def proto_library(
             visibility = ["PUBLIC"],
         )
     """
+    if well_known_types == True:
+        well_known_types = None
+    elif well_known_types == False:
+        well_known_types = []
+    else:
+        for wkt in well_known_types:
+            if wkt not in WELL_KNOWN_TYPES:
+                fail("proto_library '{}': unknown well-known type '{}'. Available: {}".format(name, wkt, WELL_KNOWN_TYPES))
+    
     # Call the actual Buck2 rule
     proto_library_rule(
         name = name,
@@ -79,6 +117,7 @@ def proto_library(
         visibility = visibility,
         import_prefix = import_prefix,
         strip_import_prefix = strip_import_prefix,
+        import_roots = import_roots,
         options = options,
         validation = validation,
         well_known_types = well_known_types,
@@ -100,8 +139,9 @@ def _proto_library_impl(ctx):
     # Validate inputs
     validate_proto_library_inputs(ctx)
     
-    # Get proto source files
-    proto_files = ctx.attrs.srcs
+    # Get proto source files, in lexical order so that every downstream
+    # protoc invocation and declared output list is independent of srcs order
+    proto_files = sort_by_path(ctx.attrs.srcs)
     
     # Collect dependency ProtoInfo providers
     dep_proto_infos = []
@@ -126,26 +166,68 @@ def _proto_library_impl(ctx):
     # Merge transitive dependency information
     transitive_info = merge_proto_infos(dep_proto_infos)
     
+    # Files of the direct deps, and the target declaring each reachable file,
+    # for checks that reject imports only available transitively
+    direct_dep_proto_files = list(bsr_proto_files)
+    proto_file_owners = {}
+    external_descriptor_sets = []
+    dep_file_import_paths = []
+    for dep in ctx.attrs.deps:
+        direct_dep_proto_files.extend(dep[ProtoInfo].proto_files)
+        proto_file_owners.update(dep[ProtoInfo].proto_file_owners or {})
+        dep_file_import_paths.append(dep[ProtoInfo].file_import_paths or {})
+        for descriptor_set in dep[ProtoInfo].external_descriptor_sets or []:
+            if descriptor_set not in external_descriptor_sets:
+                external_descriptor_sets.append(descriptor_set)
+    
     # Compute import paths for this library
     import_paths = []
-    for src in proto_files:
-        import_path = get_proto_import_path(
-            src,
-            ctx.attrs.import_prefix,
-            ctx.attrs.strip_import_prefix
-        )
-        # Get the directory of the import path
-        import_dir = "/".join(import_path.split("/")[:-1]) if "/" in import_path else "."
-        if import_dir not in import_paths:
-            import_paths.append(import_dir)
+    own_import_paths = {}
+    if ctx.attrs.import_roots:
+        roots = {}
+        for src in proto_files:
+            root = resolve_import_root(src, ctx.label.package, ctx.attrs.import_roots)
+            if root == None:
+                fail("proto_library '{}': {} is not under any of the import_roots {}".format(
+                    ctx.label, src.short_path, ctx.attrs.import_roots.keys()))
+            own_import_paths[src.short_path] = get_proto_import_path(src, root[1], root[0])
+            roots[root[0]] = root[1]
+        
+        # Longer roots first: protoc maps each source to the first --proto_path
+        # containing it, so a file under third_party/proto is registered
+        # relative to that root rather than to an enclosing one
+        for root_dir in sorted(roots.keys(), key = lambda root_dir: -len(root_dir)):
+            import_paths.append(import_root_proto_path(root_dir, roots[root_dir]))
+    else:
+        for src in proto_files:
+            import_path = get_proto_import_path(
+                src,
+                ctx.attrs.import_prefix,
+                ctx.attrs.strip_import_prefix
+            )
+            own_import_paths[src.short_path] = import_path
+            # Get the directory of the import path
+            import_dir = "/".join(import_path.split("/")[:-1]) if "/" in import_path else "."
+            if import_dir not in import_paths:
+                import_paths.append(import_dir)
+        
+        # Add current directory as import path if not already present
+        if "." not in import_paths:
+            import_paths.append(".")
     
-    # Add current directory as import path if not already present
-    if "." not in import_paths:
-        import_paths.append(".")
+    # Fails if a file is reachable under two import paths, or two files under one
+    file_import_paths = merge_file_import_paths(ctx.label, dep_file_import_paths + [own_import_paths])
+    for proto_file in proto_files + bsr_proto_files:
+        proto_file_owners[file_import_paths.get(proto_file.short_path, proto_file.short_path)] = str(ctx.label.raw_target())
     
     # Combine all import paths (local + BSR + transitive)
     all_import_paths = import_paths + bsr_import_paths + transitive_info["transitive_import_paths"]
     
+    # Restrict imports of well-known types to the declared subset
+    well_known_types_report = None
+    if ctx.attrs.well_known_types != None:
+        well_known_types_report = _check_well_known_types(ctx, proto_files)
+    
     # Create descriptor set via protoc compilation
     descriptor_set = create_descriptor_set_action(
         ctx,
@@ -176,14 +258,54 @@ def _proto_library_impl(ctx):
         java_package = java_package,
         lint_report = None,  # Will be implemented in validation tasks
         breaking_report = None,  # Will be implemented in validation tasks
+        direct_dep_proto_files = direct_dep_proto_files,
+        proto_file_owners = proto_file_owners,
+        file_import_paths = file_import_paths,
+        external_descriptor_sets = external_descriptor_sets,
+        well_known_types_report = well_known_types_report,
     )
     
     # Return providers
     return [
-        DefaultInfo(default_outputs = [descriptor_set]),
+        DefaultInfo(
+            default_outputs = [descriptor_set],
+            other_outputs = [well_known_types_report] if well_known_types_report else [],
+        ),
         proto_info,
     ]
 
+def _check_well_known_types(ctx, proto_files):
+    """
+    Fails the build if a source imports a well-known type outside the declared subset.
+    
+    Args:
+        ctx: Rule context
+        proto_files: Source files of the library
+    
+    Returns:
+        JSON report of the check
+    """
+    config = ctx.actions.write(
+        "{}_well_known_types.json".format(ctx.label.name),
+        json.encode({"allowed": ctx.attrs.well_known_types}),
+    )
+    report = ctx.actions.declare_output("{}_well_known_types_report.json".format(ctx.label.name))
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._schema_lint[DefaultInfo].default_outputs[0],
+        "--check", "well_known_type_subset",
+        "--config", config,
+        "--output", report.as_output(),
+    ])
+    cmd.add(proto_files)
+    
+    ctx.actions.run(
+        cmd,
+        category = "well_known_types",
+        identifier = ctx.label.name,
+    )
+    return report
+
 # Proto library rule definition
 proto_library_rule = rule(
     impl = _proto_library_impl,
@@ -193,13 +315,57 @@ proto_library_rule = rule(
         "bsr_deps": attrs.list(attrs.string(), default = [], doc = "BSR dependencies"),
         "import_prefix": attrs.string(default = "", doc = "Import prefix"),
         "strip_import_prefix": attrs.string(default = "", doc = "Strip import prefix"),
+        "import_roots": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Source root (strip_import_prefix) to import_prefix of its files"),
         "options": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Protobuf options"),
         "validation": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Validation config"),
-        "well_known_types": attrs.bool(default = True, doc = "Include well-known types"),
+        "well_known_types": attrs.option(attrs.list(attrs.string()), default = None, doc = "Well-known types the sources may import (None for all)"),
         "protoc_version": attrs.string(default = "", doc = "Protoc version"),
+        "_schema_lint": attrs.exec_dep(default = "//tools:schema_lint.py"),
     },
 )
 
+def proto_descriptor_set(
+    name,
+    proto,
+    include_imports = True,
+    include_source_info = True,
+    visibility = ["//visibility:private"],
+    **kwargs
+):
+    """
+    Compiles a proto_library to a single FileDescriptorSet (<name>.binpb).
+    
+    The set is the raw input of tools that work on descriptors rather than
+    generated code, such as custom code generators or a runtime schema
+    registry. Other rules read its path from ProtoDescriptorSetInfo instead
+    of compiling the library again.
+    
+    Args:
+        name: Target name; the output is <name>.binpb
+        proto: proto_library target to compile
+        include_imports: Also include every file the library imports, transitively
+        include_source_info: Keep comments and source locations; disable to
+                             shrink the set when only the schema is needed
+        visibility: Buck2 visibility specification
+        **kwargs: Additional arguments
+    
+    Example:
+        proto_descriptor_set(
+            name = "user_descriptors",
+            proto = ":user_proto",
+            include_source_info = False,
+            visibility = ["PUBLIC"],
+        )
+    """
+    proto_descriptor_set_rule(
+        name = name,
+        proto = proto,
+        include_imports = include_imports,
+        include_source_info = include_source_info,
+        visibility = visibility,
+        **kwargs
+    )
+
 # Multi-language bundle implementation
 def proto_bundle(
     name,
@@ -207,26 +373,34 @@ def proto_bundle(
     languages,
     visibility = ["//visibility:private"],
     consistency_checks = True,
-    parallel_generation = True,
+    parallel_generation = None,
     **kwargs
 ):
     """
     Generates code for multiple languages from a single proto_library.
     
-    This is a convenience rule that creates multiple language-specific
+    This is a convenience macro that creates multiple language-specific
     generation targets from a single proto library with consistent
     configuration and cross-language validation.
     
+    The library is compiled once into a shared descriptor set
+    ({name}_descriptor_set). Each language target runs its own protoc
+    action that only reads that set, so languages generate in parallel,
+    are cached independently (changing the Go options does not rerun the
+    Python generation), and a failing language is reported by its target,
+    e.g. //pkg:user_bundle_python.
+    
     Args:
         name: Base name for the bundle (individual targets will be suffixed)
         proto: proto_library target to generate code from
         languages: Dictionary mapping language names to their configurations
         visibility: Buck2 visibility specification applied to all targets
         consistency_checks: Whether to perform cross-language consistency validation
-        parallel_generation: Whether to generate language targets in parallel
+        parallel_generation: Deprecated and ignored; languages always generate in parallel
         **kwargs: Additional arguments
     
     Generated Targets:
+        - {name}_descriptor_set: shared descriptor set of the proto library
         - {name}_go (if "go" language specified)
         - {name}_python (if "python" language specified)
         - {name}_typescript (if "typescript" language specified)
@@ -245,13 +419,33 @@ def proto_bundle(
             visibility = ["PUBLIC"],
         )
     """
+    if parallel_generation != None:
+        print("WARNING: proto_bundle({}): parallel_generation is deprecated and ignored, each language is generated by its own action; remove the argument".format(name))
+    
+    validated_languages = validate_bundle_config(languages)
+    
+    descriptor_set_name = "{}_descriptor_set".format(name)
+    proto_bundle_descriptor_set_rule(
+        name = descriptor_set_name,
+        proto = proto,
+        visibility = visibility,
+    )
+    
+    language_targets = {}
+    for language, config in validated_languages.items():
+        if "visibility" not in languages[language]:
+            config["visibility"] = visibility
+        target_name = generate_language_target_name(name, language)
+        create_language_target(language, config, ":" + descriptor_set_name, target_name)
+        language_targets[language] = ":" + target_name
+    
     proto_bundle_rule(
         name = name,
         proto = proto,
-        languages = languages,
+        languages = validated_languages,
+        language_targets = language_targets,
         visibility = visibility,
         consistency_checks = consistency_checks,
-        parallel_generation = parallel_generation,
         **kwargs
     )
 
@@ -311,33 +505,85 @@ def grpc_service(
         **kwargs
     )
 
+def _proto_descriptor_set_impl(ctx):
+    """Implementation function for proto_descriptor_set rule."""
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    descriptor_set = compile_descriptor_set(
+        ctx, proto_info, get_protoc_binary(ctx), "{}.binpb".format(ctx.label.name),
+        include_source_info = ctx.attrs.include_source_info,
+        include_imports = ctx.attrs.include_imports,
+        category = "proto_descriptor_set",
+    )
+    
+    return [
+        DefaultInfo(default_outputs = [descriptor_set]),
+        ProtoDescriptorSetInfo(
+            descriptor_set = descriptor_set,
+            proto_files = proto_info.proto_files,
+            include_imports = ctx.attrs.include_imports,
+            include_source_info = ctx.attrs.include_source_info,
+        ),
+    ]
+
+def _proto_bundle_descriptor_set_impl(ctx):
+    """Implementation function for proto_bundle_descriptor_set rule.
+    
+    Compiles the bundled proto_library once and re-exports its ProtoInfo
+    with the descriptor set attached, so language rules depending on this
+    target read it instead of parsing the proto files again.
+    """
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    descriptor_set = compile_descriptor_set(
+        ctx, proto_info, get_protoc_binary(ctx), "{}.binpb".format(ctx.label.name),
+        include_source_info = True, category = "bundle_descriptor_set",
+    )
+    
+    return [
+        DefaultInfo(default_outputs = [descriptor_set]),
+        ProtoInfo(
+            descriptor_set = proto_info.descriptor_set,
+            proto_files = proto_info.proto_files,
+            import_paths = proto_info.import_paths,
+            transitive_descriptor_sets = proto_info.transitive_descriptor_sets,
+            transitive_proto_files = proto_info.transitive_proto_files,
+            transitive_import_paths = proto_info.transitive_import_paths,
+            go_package = proto_info.go_package,
+            python_package = proto_info.python_package,
+            java_package = proto_info.java_package,
+            lint_report = proto_info.lint_report,
+            breaking_report = proto_info.breaking_report,
+            shared_descriptor_set = descriptor_set,
+            direct_dep_proto_files = proto_info.direct_dep_proto_files,
+            proto_file_owners = proto_info.proto_file_owners,
+            file_import_paths = proto_info.file_import_paths,
+            external_descriptor_sets = proto_info.external_descriptor_sets,
+            well_known_types_report = proto_info.well_known_types_report,
+        ),
+    ]
+
 def _proto_bundle_impl(ctx):
     """Implementation function for proto_bundle rule.
     
     Handles:
-    - Multi-language code generation coordination
-    - Language-specific target creation
+    - Aggregation of the per-language targets created by the macro
     - Cross-language consistency validation
     - Bundle information management
     """
     # Get ProtoInfo from proto dependency
     proto_info = ctx.attrs.proto[ProtoInfo]
     
-    # Validate and normalize language configurations
-    validated_languages = validate_bundle_config(ctx.attrs.languages)
-    
-    # Create language-specific targets
     language_targets = {}
-    for language, config in validated_languages.items():
-        target_name = generate_language_target_name(ctx.label.name, language)
-        language_target = create_language_target(
-            ctx, 
-            language, 
-            config, 
-            ctx.attrs.proto, 
-            target_name
-        )
-        language_targets[language] = language_target
+    all_outputs = []
+    sub_targets = {}
+    for language, target in sorted(ctx.attrs.language_targets.items()):
+        language_targets[language] = {
+            "name": target.label.name,
+            "language": language,
+            "config": ctx.attrs.languages[language],
+            "expected_extensions": SUPPORTED_LANGUAGES[language]["file_extensions"],
+        }
+        all_outputs.extend(target[DefaultInfo].default_outputs)
+        sub_targets[language] = [target[DefaultInfo]]
     
     # Perform cross-language consistency validation if enabled
     consistency_report = None
@@ -370,15 +616,8 @@ def _proto_bundle_impl(ctx):
         ctx.attrs.languages
     )
     
-    # Collect all generated outputs for default target
-    all_outputs = []
-    for target_info in language_targets.values():
-        # In a real implementation, we would collect actual generated files
-        # For now, we use a placeholder approach
-        pass
-    
     return [
-        DefaultInfo(default_outputs = all_outputs),
+        DefaultInfo(default_outputs = sort_by_path(all_outputs), sub_targets = sub_targets),
         bundle_info,
     ]
 
@@ -461,7 +700,7 @@ def _grpc_service_impl(ctx):
     )
     
     # Collect all generated outputs
-    all_outputs = gateway_files + openapi_files + validation_files + mock_files
+    all_outputs = sort_by_path(gateway_files + openapi_files + validation_files + mock_files)
     
     return [
         DefaultInfo(default_outputs = all_outputs),
@@ -475,14 +714,32 @@ proto_bundle_rule = rule(
         "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
         "languages": attrs.dict(
             attrs.string(), 
-            attrs.dict(attrs.string(), attrs.string()), 
-            doc = "Language configurations"
+            attrs.dict(attrs.string(), attrs.any()), 
+            doc = "Validated language configurations"
         ),
+        "language_targets": attrs.dict(attrs.string(), attrs.dep(), doc = "Generated target of each language"),
         "consistency_checks": attrs.bool(default = True, doc = "Enable consistency validation"),
-        "parallel_generation": attrs.bool(default = True, doc = "Enable parallel generation"),
     },
 )
 
+# FileDescriptorSet of a proto_library for descriptor-based tools
+proto_descriptor_set_rule = rule(
+    impl = _proto_descriptor_set_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
+        "include_imports": attrs.bool(default = True, doc = "Include transitively imported files"),
+        "include_source_info": attrs.bool(default = True, doc = "Include comments and source locations"),
+    }),
+)
+
+# Shared descriptor set of a proto_bundle, read by every language target
+proto_bundle_descriptor_set_rule = rule(
+    impl = _proto_bundle_descriptor_set_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
+    }),
+)
+
 # gRPC service rule definition
 grpc_service_rule = rule(
     impl = _grpc_service_impl,
@@ -0,0 +1,107 @@
+"""Descriptor set reproducibility checks for protoc upgrades.
+
+This module provides a rule that compiles a proto_library with two protoc
+versions, typically the pinned one and an upgrade candidate, and fails if the
+descriptor sets differ after protoc-version-specific metadata is normalized
+away.
+"""
+
+load("//rules/private:providers.bzl", "ProtoInfo")
+load("//rules:tools.bzl", "TOOL_ATTRS", "get_protoc_binary")
+load("//tools/platforms:common.bzl", "get_default_versions", "get_protoc_info")
+
+def _proto_protoc_upgrade_check_impl(ctx):
+    """Implementation of the proto_protoc_upgrade_check rule."""
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    baseline_version = ctx.attrs.baseline_version or get_default_versions()["protoc"]
+
+    report = ctx.actions.declare_output("{}.json".format(ctx.label.name))
+
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._descriptor_repro[DefaultInfo].default_outputs[0],
+        "--protoc", cmd_args(baseline_version, "=", get_protoc_binary(ctx, baseline_version), delimiter = ""),
+        "--protoc", cmd_args(ctx.attrs.candidate_version, "=", get_protoc_binary(ctx, ctx.attrs.candidate_version), delimiter = ""),
+        "--output", report.as_output(),
+    ])
+
+    if ctx.attrs.include_well_known_types:
+        cmd.add("--include-well-known-types")
+
+    for import_path in proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+
+    cmd.add(proto_info.proto_files)
+
+    ctx.actions.run(
+        cmd,
+        category = "descriptor_repro",
+        identifier = ctx.label.name,
+        inputs = proto_info.transitive_proto_files,
+    )
+
+    return [DefaultInfo(default_outputs = [report])]
+
+proto_protoc_upgrade_check_rule = rule(
+    impl = _proto_protoc_upgrade_check_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library to compile with both versions"),
+        "baseline_version": attrs.string(default = "", doc = "Pinned protoc version (default: the global default)"),
+        "candidate_version": attrs.string(doc = "protoc version to compare against the baseline"),
+        "include_well_known_types": attrs.bool(default = False, doc = "Also compare the google/protobuf files bundled with protoc"),
+        "_descriptor_repro": attrs.exec_dep(default = "//tools:descriptor_repro.py"),
+    }),
+)
+
+def proto_protoc_upgrade_check(
+    name,
+    proto,
+    candidate_version,
+    baseline_version = "",
+    include_well_known_types = False,
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if two protoc versions produce semantically different descriptor sets.
+
+    Both versions compile the proto_library with imports and source info.
+    Before comparing, version-specific metadata is normalized away: source
+    info, resolved edition features, default json_name values, legacy syntax
+    and edition markers, and field order. The well-known types bundled with
+    protoc are skipped unless include_well_known_types is set. Each remaining
+    difference is reported with the path of the element, and the JSON report
+    is written to <name>.json.
+
+    Args:
+        name: Target name
+        proto: proto_library target to compile
+        candidate_version: protoc version being evaluated, e.g. "25.1"
+        baseline_version: Pinned protoc version; defaults to the global default
+        include_well_known_types: Also compare google/protobuf/* files
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_protoc_upgrade_check(
+            name = "user_protoc_upgrade",
+            proto = ":user_proto",
+            candidate_version = "25.1",
+        )
+    """
+    for version in [baseline_version, candidate_version]:
+        if version and version not in get_protoc_info():
+            fail("Unsupported protoc version: {}. Available versions: {}".format(version, get_protoc_info().keys()))
+    if not candidate_version:
+        fail("candidate_version is required")
+    if (baseline_version or get_default_versions()["protoc"]) == candidate_version:
+        fail("candidate_version must differ from the baseline version {}".format(candidate_version))
+
+    proto_protoc_upgrade_check_rule(
+        name = name,
+        proto = proto,
+        baseline_version = baseline_version,
+        candidate_version = candidate_version,
+        include_well_known_types = include_well_known_types,
+        visibility = visibility,
+        **kwargs
+    )
@@ -5,19 +5,24 @@ Supports both basic protobuf messages and gRPC service stubs with comprehensive
 mypy type checking support and proper Python package structure.
 """
 
-load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo")
-load("//rules/private:utils.bzl", "get_proto_import_path")
-load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS", "get_protoc_command")
+load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo", "PyInfo")
+load("//rules/private:utils.bzl", "generate_package_doc_index", "get_proto_import_path", "protoc_source_args")
+load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS", "get_plugin_binary", "get_protoc_command")
 
 def python_proto_library(
     name: str,
     proto: str,
+    deps: list[str] = [],
     python_package: str = "",
     visibility: list[str] = ["//visibility:private"],
     plugins: list[str] = ["python", "grpc-python"],
     generate_stubs: bool = True,
     mypy_support: bool = True,
     options: dict[str, str] = {},
+    package_mapping: dict[str, str] = {},
+    relative_imports: bool = False,
+    import_cycle_strategy: str = "fail",
+    doc_index: bool = False,
     **kwargs
 ):
     """
@@ -26,43 +31,92 @@ def python_proto_library(
     Args:
         name: Unique name for this Python protobuf library target
         proto: proto_library target to generate Python code from
+        deps: python_proto_library targets of the protos imported by proto; their
+              modules are imported under the packages they were generated into
         python_package: Python package path override (e.g., "myapp.protos.v1")
         visibility: Buck2 visibility specification
         plugins: List of protoc plugins to use ["python", "grpc-python", "mypy"]
+                 ("grpc" is accepted for "grpc-python"); "mypy" generates the
+                 stubs with protoc-gen-mypy, plus protoc-gen-mypy_grpc with gRPC
         generate_stubs: Whether to generate .pyi type stub files
         mypy_support: Whether to enable mypy compatibility features
         options: Additional protoc options for Python generation
+        package_mapping: Maps proto directories to Python packages (e.g. {"acme/user": "myapp.protos.user"});
+                         generated modules are relocated and their imports rewritten to match
+        relative_imports: Rewrite imports between generated modules as relative imports
+                          (requires package_mapping)
+        import_cycle_strategy: How import cycles between generated packages are handled:
+                               "fail" rejects cycles introduced by package_mapping;
+                               "deferred" breaks them by having package __init__.py files
+                               import generated modules lazily on first access
+        doc_index: Write a markdown index (README.md) per proto package listing its
+                   services, messages and enums with their doc comment summaries,
+                   available as the [doc_index] sub-target
         **kwargs: Additional arguments passed to underlying rule
     
     Example:
         python_proto_library(
             name = "user_py_proto",
             proto = ":user_proto",
+            deps = ["//proto/common:common_py_proto"],
             python_package = "myapp.protos.user.v1",
-            plugins = ["python", "grpc-python"],
+            plugins = ["python", "grpc", "mypy"],
             visibility = ["PUBLIC"],
         )
         
-    Generated Files:
+    Generated Files (in a python/ directory laid out by package, e.g.
+    python/myapp/protos/user/v1/):
         - *_pb2.py: Basic protobuf message code (protoc --python_out)
         - *_pb2_grpc.py: gRPC service stubs (protoc --grpc_python_out)
-        - *_pb2.pyi: Type stubs for basic protobuf code
-        - *_pb2_grpc.pyi: Type stubs for gRPC service code
-        - __init__.py: Python package initialization
+        - *_pb2.pyi: Type stubs for basic protobuf code (protoc --pyi_out or protoc-gen-mypy)
+        - *_pb2_grpc.pyi: Type stubs for gRPC service code (protoc-gen-mypy_grpc)
+        - __init__.py: Python package initialization, in every package directory
         - py.typed: PEP 561 typed package marker
+
+    Providers:
+        - PyInfo: The package tree and those of deps, for Python targets
     """
+    plugins = _normalize_python_plugins(plugins)
+    if relative_imports and not package_mapping:
+        fail("relative_imports requires package_mapping")
+    if import_cycle_strategy not in _IMPORT_CYCLE_STRATEGIES:
+        fail("import_cycle_strategy must be one of {}, got '{}'".format(_IMPORT_CYCLE_STRATEGIES, import_cycle_strategy))
+
     python_proto_library_rule(
         name = name,
         proto = proto,
+        deps = deps,
         python_package = python_package,
         visibility = visibility,
         plugins = plugins,
         generate_stubs = generate_stubs,
         mypy_support = mypy_support,
         options = options,
+        package_mapping = package_mapping,
+        relative_imports = relative_imports,
+        import_cycle_strategy = import_cycle_strategy,
+        doc_index = doc_index,
         **kwargs
     )
 
+# Strategies of tools/python_package_mapper.py for import cycles between generated packages
+_IMPORT_CYCLE_STRATEGIES = ["fail", "deferred"]
+
+# Plugins python_proto_library runs, and the names accepted for them
+_PYTHON_PLUGINS = ["python", "grpc-python", "mypy"]
+_PYTHON_PLUGIN_ALIASES = {"grpc": "grpc-python"}
+
+def _normalize_python_plugins(plugins: list[str]) -> list[str]:
+    """Resolves plugin aliases, drops duplicates and rejects unknown plugins."""
+    normalized = []
+    for plugin in plugins:
+        plugin = _PYTHON_PLUGIN_ALIASES.get(plugin, plugin)
+        if plugin not in _PYTHON_PLUGINS:
+            fail("Unknown Python plugin '{}', expected one of {}".format(plugin, _PYTHON_PLUGINS + _PYTHON_PLUGIN_ALIASES.keys()))
+        if plugin not in normalized:
+            normalized.append(plugin)
+    return normalized
+
 def _resolve_python_package(ctx, proto_info):
     """
     Resolves the Python package path for generated code.
@@ -115,9 +169,14 @@ def _generate_python_package_from_path(proto_path: str) -> str:
     else:
         return ""
 
-def _get_python_output_files(ctx, proto_info, python_package: str):
+def _python_package_mapping(ctx, proto_info, python_package: str):
     """
-    Determines the expected Python output files based on proto content and plugins.
+    Resolves the proto directory to Python package mapping for generated code.
+    
+    Mappings of python_proto_library deps come first, so imports of their
+    modules resolve to the packages they were generated into. An explicit
+    python_package (attribute or proto option) maps each of this library's
+    proto files, and package_mapping entries take precedence.
     
     Args:
         ctx: Buck2 rule context
@@ -125,244 +184,66 @@ def _get_python_output_files(ctx, proto_info, python_package: str):
         python_package: Resolved Python package path
         
     Returns:
-        List of expected output file objects
-    """
-    output_files = []
-    
-    # Base name from proto files
-    for proto_file in proto_info.proto_files:
-        base_name = proto_file.basename
-        if base_name.endswith(".proto"):
-            base_name = base_name[:-6]
-        
-        # Basic protobuf messages (always generated if "python" plugin enabled)
-        if "python" in ctx.attrs.plugins:
-            pb2_file = ctx.actions.declare_output("python", base_name + "_pb2.py")
-            output_files.append(pb2_file)
-            
-            # Generate type stubs if requested
-            if ctx.attrs.generate_stubs:
-                pb2_stub_file = ctx.actions.declare_output("python", base_name + "_pb2.pyi")
-                output_files.append(pb2_stub_file)
-        
-        # gRPC service stubs (if "grpc-python" plugin enabled)
-        if "grpc-python" in ctx.attrs.plugins:
-            grpc_file = ctx.actions.declare_output("python", base_name + "_pb2_grpc.py")
-            output_files.append(grpc_file)
-            
-            # Generate gRPC type stubs if requested
-            if ctx.attrs.generate_stubs:
-                grpc_stub_file = ctx.actions.declare_output("python", base_name + "_pb2_grpc.pyi")
-                output_files.append(grpc_stub_file)
-    
-    # Package structure files
-    init_file = ctx.actions.declare_output("python", "__init__.py")
-    output_files.append(init_file)
-    
-    # PEP 561 marker file for type checking support
-    if ctx.attrs.mypy_support:
-        py_typed_file = ctx.actions.declare_output("python", "py.typed")
-        output_files.append(py_typed_file)
-    
-    return output_files
-
-def _create_init_py_content(ctx, python_package: str, proto_info) -> str:
+        Dictionary mapping proto directories and files to Python packages
     """
-    Creates __init__.py file content for the generated Python package.
+    mapping = {}
+    for dep in ctx.attrs.deps:
+        mapping.update(dep[PyInfo].package_mapping)
     
-    Args:
-        ctx: Buck2 rule context
-        python_package: Python package path
-        proto_info: ProtoInfo provider
-        
-    Returns:
-        String content for __init__.py file
-    """
-    # Collect all module names that will be generated
-    module_names = []
-    for proto_file in proto_info.proto_files:
-        base_name = proto_file.basename
-        if base_name.endswith(".proto"):
-            base_name = base_name[:-6]
-        
-        if "python" in ctx.attrs.plugins:
-            module_names.append(base_name + "_pb2")
-        if "grpc-python" in ctx.attrs.plugins:
-            module_names.append(base_name + "_pb2_grpc")
+    if ctx.attrs.python_package or proto_info.python_package:
+        for proto_file in proto_info.proto_files:
+            mapping[proto_file.short_path] = python_package
     
-    # Create __init__.py content
-    content = '''"""Generated Python protobuf package.
-
-This package contains protobuf message definitions and gRPC service stubs
-automatically generated from .proto files using Buck2.
-"""
-
-'''
-    
-    if python_package:
-        content += '__package__ = "{}"\n'.format(python_package)
-    
-    content += '__version__ = "1.0.0"\n\n'
-    
-    if module_names:
-        content += '__all__ = [\n'
-        for module_name in sorted(module_names):
-            content += '    "{}",\n'.format(module_name)
-        content += ']\n\n'
-        
-        # Add convenience imports
-        content += '# Convenience imports\n'
-        for module_name in sorted(module_names):
-            content += 'from . import {}\n'.format(module_name)
-    
-    return content
-
-def _create_py_typed_content() -> str:
-    """
-    Creates py.typed file content for PEP 561 compliance.
-    
-    Returns:
-        String content for py.typed file
-    """
-    return """# PEP 561 stub package marker file
-# This file indicates that this package supports type checking
-# and provides type information for mypy and other type checkers.
-"""
-
-def _generate_type_stubs(ctx, generated_files):
-    """
-    Generates .pyi type stub files for mypy support.
-    
-    Args:
-        ctx: Buck2 rule context
-        generated_files: List of generated .py files
-        
-    Returns:
-        List of generated .pyi stub files
-    """
-    stub_files = []
-    
-    for py_file in generated_files:
-        if py_file.short_path.endswith(".py") and not py_file.short_path.endswith("__init__.py"):
-            # Create corresponding .pyi file
-            stub_path = py_file.short_path.replace(".py", ".pyi")
-            stub_file = ctx.actions.declare_output("python", stub_path.split("/")[-1])
-            
-            # Generate basic type stub content
-            stub_content = _create_stub_content(py_file.basename)
-            
-            ctx.actions.write(
-                stub_file,
-                stub_content,
-            )
-            
-            stub_files.append(stub_file)
-    
-    return stub_files
+    mapping.update(ctx.attrs.package_mapping)
+    return mapping
 
-def _create_stub_content(py_filename: str) -> str:
-    """
-    Creates basic type stub content for a Python protobuf file.
-    
-    Args:
-        py_filename: Name of the Python file (e.g., "user_pb2.py")
-        
-    Returns:
-        String content for the .pyi stub file
-    """
-    if py_filename.endswith("_pb2.py"):
-        return '''"""Type stubs for protobuf message definitions."""
-
-from typing import Any, Dict, List, Optional, Union
-import google.protobuf.message
-import google.protobuf.descriptor
-
-# Placeholder type stubs - will be enhanced with actual type information
-# Generated protobuf classes will have proper type annotations
-
-__all__: List[str]
-
-class Message(google.protobuf.message.Message):
-    def __init__(self, **kwargs: Any) -> None: ...
-    def SerializeToString(self) -> bytes: ...
-    def ParseFromString(self, data: bytes) -> None: ...
-    def CopyFrom(self, other: Message) -> None: ...
-'''
-    elif py_filename.endswith("_pb2_grpc.py"):
-        return '''"""Type stubs for gRPC service definitions."""
-
-from typing import Any, Callable, Iterator, Optional, Union
-import grpc
-import grpc.aio
-
-# Placeholder type stubs for gRPC services
-# Generated service classes will have proper type annotations
-
-__all__: List[str]
-
-class ServicerContext:
-    def __init__(self) -> None: ...
-
-class Servicer:
-    def __init__(self) -> None: ...
-
-class Stub:
-    def __init__(self, channel: Union[grpc.Channel, grpc.aio.Channel]) -> None: ...
-'''
-    else:
-        return '''"""Type stubs for generated Python code."""
-
-from typing import Any
-
-__all__: List[str]
-'''
-
-def _generate_python_code(ctx, proto_info, tools, output_files, python_package: str):
+def _generate_python_code(ctx, proto_info, tools, output_name: str = "python_protoc"):
     """
     Executes protoc with Python plugins to generate Python code.
     
+    Modules are laid out by .proto path (package acme.user.v1 ends up in
+    acme/user/v1/) with absolute imports that mirror that layout. Type stubs
+    come from protoc's built-in pyi generator, or from protoc-gen-mypy and
+    protoc-gen-mypy_grpc when the "mypy" plugin is enabled.
+    
     Args:
         ctx: Buck2 rule context
         proto_info: ProtoInfo provider from proto dependency
         tools: Dictionary of tool file objects
-        output_files: List of expected output files
-        python_package: Resolved Python package path
+        output_name: Name of the protoc output directory
+
+    Returns:
+        The protoc output directory
     """
     # Create output directory
-    output_dir = ctx.actions.declare_output("python")
+    output_dir = ctx.actions.declare_output(output_name, dir = True)
     
     # Build protoc command arguments
     protoc_cmd = cmd_args([tools["protoc"]])
     
-    # Add import paths (current + transitive)
-    all_import_paths = proto_info.import_paths + proto_info.transitive_import_paths
-    for import_path in all_import_paths:
-        protoc_cmd.add("--proto_path={}".format(import_path))
+    # Import paths and proto files, or the shared descriptor set of a proto_bundle
+    source_args, source_inputs = protoc_source_args(proto_info)
+    
+    inputs = [tools["protoc"]] + source_inputs
     
     # Configure Python code generation
     if "python" in ctx.attrs.plugins:
         protoc_cmd.add("--python_out={}".format(output_dir.as_output()))
-        
-        # Add custom Python package mapping if specified
-        if python_package:
-            for proto_file in proto_info.proto_files:
-                protoc_cmd.add("--python_opt=M{}={}".format(
-                    proto_file.short_path, 
-                    python_package
-                ))
+        if ctx.attrs.generate_stubs and "mypy" not in ctx.attrs.plugins:
+            protoc_cmd.add("--pyi_out={}".format(output_dir.as_output()))
     
     # Configure gRPC Python generation
     if "grpc-python" in ctx.attrs.plugins:
         # Note: gRPC Python plugin is typically provided by grpcio-tools
         protoc_cmd.add("--grpc_python_out={}".format(output_dir.as_output()))
-        
-        # Add custom gRPC package mapping if specified
-        if python_package:
-            for proto_file in proto_info.proto_files:
-                protoc_cmd.add("--grpc_python_opt=M{}={}".format(
-                    proto_file.short_path, 
-                    python_package
-                ))
+    
+    # Configure mypy-protobuf stub generation
+    if "mypy" in ctx.attrs.plugins:
+        stub_plugins = ["mypy", "mypy_grpc"] if "grpc-python" in ctx.attrs.plugins else ["mypy"]
+        for plugin in stub_plugins:
+            protoc_cmd.add(cmd_args("--plugin=protoc-gen-", plugin, "=", tools["protoc-gen-" + plugin], delimiter = ""))
+            protoc_cmd.add("--{}_out={}".format(plugin, output_dir.as_output()))
+            inputs.append(tools["protoc-gen-" + plugin])
     
     # Add any additional options
     for opt_key, opt_value in ctx.attrs.options.items():
@@ -370,12 +251,13 @@ def _generate_python_code(ctx, proto_info, tools, output_files, python_package:
             protoc_cmd.add("--python_opt={}={}".format(opt_key[7:], opt_value))
         elif opt_key.startswith("grpc_python_"):
             protoc_cmd.add("--grpc_python_opt={}={}".format(opt_key[12:], opt_value))
+        elif opt_key.startswith("mypy_grpc_"):
+            protoc_cmd.add("--mypy_grpc_opt={}={}".format(opt_key[10:], opt_value))
+        elif opt_key.startswith("mypy_"):
+            protoc_cmd.add("--mypy_opt={}={}".format(opt_key[5:], opt_value))
     
     # Add proto files
-    protoc_cmd.add(proto_info.proto_files)
-    
-    # Collect all inputs
-    inputs = [tools["protoc"]] + proto_info.proto_files + proto_info.transitive_descriptor_sets
+    protoc_cmd.add(source_args)
     
     # Run protoc to generate Python code
     ctx.actions.run(
@@ -383,7 +265,6 @@ def _generate_python_code(ctx, proto_info, tools, output_files, python_package:
         category = "python_protoc",
         identifier = "{}_python_generation".format(ctx.label.name),
         inputs = inputs,
-        outputs = [output_dir] + [f for f in output_files if f.short_path.endswith((".py", ".pyi"))],
         env = {
             "PATH": "/usr/bin:/bin:/usr/local/bin",
             "PYTHONPATH": "/usr/lib/python3/dist-packages:/usr/local/lib/python3/dist-packages",
@@ -391,42 +272,52 @@ def _generate_python_code(ctx, proto_info, tools, output_files, python_package:
         local_only = False,
     )
 
-def _create_package_files(ctx, python_package: str, proto_info):
+    return output_dir
+
+def _layout_python_package(ctx, protoc_dir, mapping: dict[str, str]):
     """
-    Creates Python package structure files (__init__.py, py.typed).
+    Lays out the generated modules as an importable package tree.
+    
+    tools/python_package_mapper.py moves each module into its mapped package,
+    rewrites imports in the generated files (including imports of modules
+    generated by deps) to absolute module paths of the mapped packages, writes
+    an __init__.py into every package directory and fails on introduced import
+    cycles. Package __init__.py files extend their __path__, so packages
+    shared with deps (e.g. acme for acme.user and acme.common) are merged
+    when both trees are on sys.path.
     
     Args:
         ctx: Buck2 rule context
-        python_package: Python package path
-        proto_info: ProtoInfo provider
+        protoc_dir: protoc output directory
+        mapping: Proto directory to Python package mapping
         
     Returns:
-        List of created package files
+        The package tree directory
     """
-    package_files = []
-    
-    # Create __init__.py file
-    init_file = ctx.actions.declare_output("python", "__init__.py")
-    init_content = _create_init_py_content(ctx, python_package, proto_info)
+    package_dir = ctx.actions.declare_output("python", dir = True)
+    
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._python_package_mapper[DefaultInfo].default_outputs[0],
+        "--input-dir", protoc_dir,
+        "--output-dir", package_dir.as_output(),
+        "--extend-path",
+    ])
+    for proto_dir, package in sorted(mapping.items()):
+        cmd.add("--map", "{}={}".format(proto_dir, package))
+    if ctx.attrs.relative_imports:
+        cmd.add("--relative-imports")
+    if ctx.attrs.mypy_support:
+        cmd.add("--py-typed")
+    cmd.add("--cycle-strategy", ctx.attrs.import_cycle_strategy)
     
-    ctx.actions.write(
-        init_file,
-        init_content,
+    ctx.actions.run(
+        cmd,
+        category = "python_package_mapping",
+        identifier = "{}_package_mapping".format(ctx.label.name),
     )
-    package_files.append(init_file)
-    
-    # Create py.typed file if mypy support is enabled
-    if ctx.attrs.mypy_support:
-        py_typed_file = ctx.actions.declare_output("python", "py.typed")
-        py_typed_content = _create_py_typed_content()
-        
-        ctx.actions.write(
-            py_typed_file,
-            py_typed_content,
-        )
-        package_files.append(py_typed_file)
     
-    return package_files
+    return package_dir
 
 def _python_proto_library_impl(ctx):
     """
@@ -435,10 +326,9 @@ def _python_proto_library_impl(ctx):
     Handles:
     - Python package path resolution
     - Tool downloading and caching
-    - protoc execution with Python plugins
-    - Type stub generation for mypy support
-    - Python package structure creation
-    - Output file management
+    - protoc execution with Python plugins and type stub generation
+    - Python package structure creation and import resolution
+    - PyInfo for downstream Python targets
     """
     # Get ProtoInfo from proto dependency
     proto_info = ctx.attrs.proto[ProtoInfo]
@@ -448,26 +338,27 @@ def _python_proto_library_impl(ctx):
     
     # Ensure required tools are available
     tools = ensure_tools_available(ctx, "python")
+    if "mypy" in ctx.attrs.plugins:
+        tools["protoc-gen-mypy"] = get_plugin_binary(ctx, "protoc-gen-mypy")
+        if "grpc-python" in ctx.attrs.plugins:
+            tools["protoc-gen-mypy_grpc"] = get_plugin_binary(ctx, "protoc-gen-mypy_grpc")
     
-    # Get expected output files
-    output_files = _get_python_output_files(ctx, proto_info, python_package)
-    
-    # Generate Python code using protoc
-    _generate_python_code(ctx, proto_info, tools, output_files, python_package)
-    
-    # Create package structure files
-    package_files = _create_package_files(ctx, python_package, proto_info)
-    output_files.extend(package_files)
+    # Generate Python code and lay it out as importable packages
+    protoc_dir = _generate_python_code(ctx, proto_info, tools)
+    mapping = _python_package_mapping(ctx, proto_info, python_package)
+    package_dir = _layout_python_package(ctx, protoc_dir, mapping)
     
-    # Generate type stubs if requested (and not already generated by protoc)
-    if ctx.attrs.generate_stubs and "mypy" not in ctx.attrs.plugins:
-        stub_files = _generate_type_stubs(ctx, [f for f in output_files if f.short_path.endswith(".py")])
-        output_files.extend(stub_files)
+    dep_infos = [dep[PyInfo] for dep in ctx.attrs.deps]
+    transitive_sources = [package_dir]
+    transitive_pyi_files = [package_dir] if ctx.attrs.generate_stubs else []
+    for dep_info in dep_infos:
+        transitive_sources.extend([s for s in dep_info.transitive_sources if s not in transitive_sources])
+        transitive_pyi_files.extend([s for s in dep_info.transitive_pyi_files if s not in transitive_pyi_files])
     
     # Create LanguageProtoInfo provider
     language_proto_info = LanguageProtoInfo(
         language = "python",
-        generated_files = output_files,
+        generated_files = [package_dir],
         package_name = python_package,
         dependencies = [
             "protobuf",
@@ -476,26 +367,53 @@ def _python_proto_library_impl(ctx):
         compiler_flags = [],
     )
     
+    sub_targets = {"protoc": [DefaultInfo(default_outputs = [protoc_dir])]}
+    other_outputs = []
+    if ctx.attrs.doc_index:
+        index_dir = generate_package_doc_index(ctx, proto_info, tools["protoc"], ctx.attrs._package_doc_index[DefaultInfo].default_outputs[0])
+        other_outputs.append(index_dir)
+        sub_targets["doc_index"] = [DefaultInfo(default_outputs = [index_dir])]
+    
     # Return providers
     return [
-        DefaultInfo(default_outputs = output_files),
+        DefaultInfo(
+            default_outputs = [package_dir],
+            other_outputs = other_outputs,
+            sub_targets = sub_targets,
+        ),
         language_proto_info,
+        PyInfo(
+            transitive_sources = transitive_sources,
+            direct_pyi_files = [package_dir] if ctx.attrs.generate_stubs else [],
+            transitive_pyi_files = transitive_pyi_files,
+            imports = transitive_sources,
+            has_py3_only_sources = True,
+            uses_shared_libraries = False,
+            package_mapping = mapping,
+        ),
     ]
 
 # Python protobuf library rule definition
 python_proto_library_rule = rule(
     impl = _python_proto_library_impl,
-    attrs = {
+    attrs = dict(TOOL_ATTRS, **{
         "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
+        "deps": attrs.list(attrs.dep(providers = [PyInfo]), default = [], doc = "python_proto_library targets of imported protos"),
         "python_package": attrs.string(default = "", doc = "Python package path override"),
         "plugins": attrs.list(attrs.string(), default = ["python", "grpc-python"], doc = "Protoc plugins to use"),
         "generate_stubs": attrs.bool(default = True, doc = "Generate .pyi type stub files"),
         "mypy_support": attrs.bool(default = True, doc = "Enable mypy compatibility features"),
         "options": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Additional protoc options"),
+        "package_mapping": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Proto directory to Python package mapping"),
+        "relative_imports": attrs.bool(default = False, doc = "Use relative imports between generated modules"),
+        "import_cycle_strategy": attrs.string(default = "fail", doc = "How import cycles between generated packages are handled"),
+        "doc_index": attrs.bool(default = False, doc = "Write a markdown index per proto package"),
+        "_package_doc_index": attrs.exec_dep(default = "//tools:package_doc_index.py"),
         "_protoc": attrs.exec_dep(default = "//tools:protoc"),
+        "_python_package_mapper": attrs.exec_dep(default = "//tools:python_package_mapper.py"),
         "_protoc_gen_python": attrs.exec_dep(default = "//tools:protoc-gen-python", doc = "Python protoc plugin"),
         "_protoc_gen_grpc_python": attrs.exec_dep(default = "//tools:protoc-gen-grpc-python", doc = "Python gRPC protoc plugin"),
-    },
+    }),
 )
 
 # Convenience function for basic Python protobuf generation (messages only)
@@ -569,8 +487,8 @@ def python_proto_mypy(
     """
     Generates Python protobuf code with enhanced mypy support.
     
-    This uses protoc-gen-mypy for more accurate type stub generation
-    when available, falling back to built-in stub generation.
+    This uses protoc-gen-mypy (and protoc-gen-mypy_grpc for services) instead
+    of protoc's built-in .pyi generator for more precise type stubs.
     
     Args:
         name: Target name
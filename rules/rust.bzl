@@ -6,7 +6,7 @@ proper Cargo integration, async/await support, and modern Rust features.
 """
 
 load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo")
-load("//rules/private:utils.bzl", "get_proto_import_path")
+load("//rules/private:utils.bzl", "get_proto_import_path", "protoc_source_args", "sort_by_path")
 load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS", "get_protoc_command")
 
 def rust_proto_library(
@@ -21,6 +21,7 @@ def rust_proto_library(
     use_grpc: bool = False,
     edition: str = "2021",
     serde: bool = False,
+    prost_config: dict[str, list[str]] = {},
     **kwargs
 ):
     """
@@ -38,6 +39,10 @@ def rust_proto_library(
         use_grpc: Generate tonic gRPC service code (adds tonic plugin)
         edition: Rust edition to use (2018, 2021)
         serde: Enable serde serialization support
+        prost_config: protoc-gen-prost options, each a list of values passed as
+                      --prost_opt=<option>=<value>, e.g. {"btree_map": ["."],
+                      "type_attribute": [".acme.v1.User=#[derive(Eq)]"]};
+                      an empty list passes the bare option
         **kwargs: Additional arguments passed to underlying rule
     
     Example:
@@ -54,8 +59,13 @@ def rust_proto_library(
         
     Generated Files:
         - Cargo.toml: Rust package configuration
-        - src/lib.rs: Library root with module exports
-        - src/*.rs: Generated Rust code for protobuf messages and services
+        - src/lib.rs: Crate root including src/mod.rs
+        - src/mod.rs: Module tree following the proto package hierarchy
+        - src/<package>.rs, src/<package>.tonic.rs: prost messages and tonic services
+    
+    Code is generated for the target's files and the files of its proto_library
+    deps, so the crate compiles on its own; well-known types refer to the
+    prost-types crate.
     """
     # Add tonic plugin if requested
     effective_plugins = list(plugins)
@@ -79,6 +89,7 @@ def rust_proto_library(
         use_grpc = use_grpc,
         edition = edition,
         serde = serde,
+        prost_config = prost_config,
         **kwargs
     )
 
@@ -132,54 +143,6 @@ def _generate_package_from_path(proto_path: str) -> str:
     
     return package_name
 
-def _get_rust_output_files(ctx, proto_info):
-    """
-    Determines the expected Rust output files based on proto content and plugins.
-    
-    Args:
-        ctx: Buck2 rule context
-        proto_info: ProtoInfo provider from proto dependency
-        
-    Returns:
-        List of expected output file objects
-    """
-    output_files = []
-    
-    # Base name from proto files
-    for proto_file in proto_info.proto_files:
-        base_name = proto_file.basename
-        if base_name.endswith(".proto"):
-            base_name = base_name[:-6]
-        
-        # Convert to snake_case for Rust modules
-        rust_module = base_name.replace("-", "_").lower()
-        
-        # Rust protobuf files (generated by prost plugin)
-        if "prost" in ctx.attrs.plugins:
-            rust_file = ctx.actions.declare_output("rust", "src", rust_module + ".rs")
-            output_files.append(rust_file)
-        
-        # tonic gRPC service files (if tonic plugin enabled)
-        if "tonic" in ctx.attrs.plugins:
-            # Tonic generates services in the same files as messages
-            # but we may need separate service-specific files for complex cases
-            if base_name.endswith("_service") or "service" in base_name.lower():
-                service_file = ctx.actions.declare_output("rust", "src", rust_module + "_service.rs")
-                output_files.append(service_file)
-    
-    # Library configuration files
-    cargo_toml = ctx.actions.declare_output("rust", "Cargo.toml")
-    output_files.append(cargo_toml)
-    
-    lib_rs = ctx.actions.declare_output("rust", "src", "lib.rs")
-    output_files.append(lib_rs)
-    
-    # Build script (optional, for additional codegen)
-    build_rs = ctx.actions.declare_output("rust", "build.rs")
-    output_files.append(build_rs)
-    
-    return output_files
-
 def _create_cargo_toml_content(ctx, rust_package: str) -> str:
     """
     Creates Cargo.toml content for generated Rust code.
@@ -191,9 +154,10 @@ def _create_cargo_toml_content(ctx, rust_package: str) -> str:
     Returns:
         String content for Cargo.toml file
     """
-    # Base dependencies
+    # Base dependencies; prost-types provides the well-known types
     dependencies = {
         "prost": "0.12",
+        "prost-types": "0.12",
     }
     
     # Add tonic dependencies if needed
@@ -223,16 +187,9 @@ def _create_cargo_toml_content(ctx, rust_package: str) -> str:
             # Simple version string
             deps_lines.append('{} = "{}"'.format(dep, version))
     
-    # Optional dependencies (for features)
-    optional_deps = []
-    if ctx.attrs.serde:
-        # serde is already added above, but mark it as optional if it's a feature
-        pass
-    
-    # Features section
+    # Features section; serde derives are generated unconditionally, so serde
+    # is a regular dependency rather than an optional feature
     features_lines = ["default = []"]
-    if ctx.attrs.serde:
-        features_lines.append('serde = ["dep:serde"]')
     
     # Add custom features
     for feature in ctx.attrs.features:
@@ -254,56 +211,6 @@ edition = "{}"
 # This file can be customized as needed for your project
 '''.format(rust_package, ctx.attrs.edition, chr(10).join(deps_lines), chr(10).join(features_lines))
 
-def _create_lib_rs_content(ctx, proto_info, rust_package: str) -> str:
-    """
-    Creates lib.rs content for generated Rust library.
-    
-    Args:
-        ctx: Buck2 rule context
-        proto_info: ProtoInfo provider from proto dependency
-        rust_package: Rust package name
-        
-    Returns:
-        String content for lib.rs file
-    """
-    # Collect module names from proto files
-    modules = []
-    for proto_file in proto_info.proto_files:
-        base_name = proto_file.basename
-        if base_name.endswith(".proto"):
-            base_name = base_name[:-6]
-        
-        rust_module = base_name.replace("-", "_").lower()
-        modules.append(rust_module)
-    
-    # Generate module declarations and re-exports
-    module_lines = []
-    export_lines = []
-    
-    for module in modules:
-        module_lines.append("pub mod {};".format(module))
-        export_lines.append("pub use {}::*;".format(module))
-    
-    # Add tonic imports if gRPC is enabled
-    tonic_imports = ""
-    if "tonic" in ctx.attrs.plugins:
-        tonic_imports = """
-// Re-export tonic for convenient access
-pub use tonic;
-"""
-    
-    return '''//! Generated protobuf library: {}
-//! 
-//! This library contains generated Rust code from protobuf definitions.
-//! Generated by Buck2 protobuf rules.
-
-{}
-
-// Re-export all generated types for convenient access
-{}
-{}
-'''.format(rust_package, chr(10).join(module_lines), chr(10).join(export_lines), tonic_imports)
-
 def _create_build_rs_content(ctx) -> str:
     """
     Creates build.rs content for additional build-time codegen.
@@ -328,91 +235,120 @@ fn main() {
 }
 '''
 
-def _generate_rust_code(ctx, proto_info, tools, output_files, rust_package: str):
+def _prost_options(ctx):
+    """
+    Returns the protoc-gen-prost options of the target.
+    
+    Well-known types always map to the prost-types crate. derive and serde
+    become type attributes on every message and enum; prost_config and
+    prost_-prefixed options are passed through.
+    
+    Args:
+        ctx: Buck2 rule context
+        
+    Returns:
+        List of option strings, one --prost_opt each
+    """
+    options = ["extern_path=.google.protobuf=::prost_types"]
+    for derive in ctx.attrs.derive:
+        options.append("type_attribute=.=#[derive({})]".format(derive))
+    if ctx.attrs.serde:
+        options.append("type_attribute=.=#[derive(serde::Serialize)]")
+        options.append("type_attribute=.=#[derive(serde::Deserialize)]")
+    for opt_key, opt_value in ctx.attrs.options.items():
+        if opt_key.startswith("prost_"):
+            options.append("{}={}".format(opt_key[6:], opt_value))
+    for option, values in ctx.attrs.prost_config.items():
+        if not values:
+            options.append(option)
+        for value in values:
+            options.append("{}={}".format(option, value))
+    return options
+
+def _generate_rust_code(ctx, proto_info, tools, raw_dir):
     """
     Executes protoc with Rust plugins to generate Rust code.
     
+    Code is generated for the library's files and the files of its
+    proto_library deps, so that every package the generated code refers to
+    is part of the crate.
+    
     Args:
         ctx: Buck2 rule context
         proto_info: ProtoInfo provider from proto dependency
         tools: Dictionary of tool file objects
-        output_files: List of expected output files
-        rust_package: Resolved Rust package name
+        raw_dir: Declared directory for the plugin output, one file per package
     """
-    # Create output directory
-    output_dir = ctx.actions.declare_output("rust")
-    src_dir = ctx.actions.declare_output("rust", "src")
-    
-    # Build protoc command arguments
     protoc_cmd = cmd_args([tools["protoc"]])
     
-    # Add import paths (current + transitive)
-    all_import_paths = proto_info.import_paths + proto_info.transitive_import_paths
-    for import_path in all_import_paths:
-        protoc_cmd.add("--proto_path={}".format(import_path))
+    # Import paths and proto files, or the shared descriptor set of a proto_bundle
+    source_args, source_inputs = protoc_source_args(proto_info)
+    shared = getattr(proto_info, "shared_descriptor_set", None)
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files and not dep_file.short_path.startswith("google/protobuf/"):
+            source_args.append(dep_file.short_path if shared else dep_file)
+            if not shared:
+                source_inputs.append(dep_file)
     
-    # Configure prost code generation
     if "prost" in ctx.attrs.plugins:
-        protoc_cmd.add("--prost_out={}".format(src_dir.as_output()))
-        
-        # Add prost-specific options
-        prost_options = []
-        
-        # Add derive macros
-        if ctx.attrs.derive:
-            derive_list = ",".join(ctx.attrs.derive)
-            prost_options.append("derive={}".format(derive_list))
-        
-        # Add serde support
-        if ctx.attrs.serde:
-            prost_options.append("serde")
-        
-        # Add custom options
-        for opt_key, opt_value in ctx.attrs.options.items():
-            if opt_key.startswith("prost_"):
-                prost_options.append("{}={}".format(opt_key[6:], opt_value))
-        
-        if prost_options:
-            protoc_cmd.add("--prost_opt={}".format(",".join(prost_options)))
+        protoc_cmd.add("--plugin=protoc-gen-prost={}".format(tools["protoc-gen-prost"]))
+        protoc_cmd.add(cmd_args(raw_dir.as_output(), format = "--prost_out={}"))
+        for option in _prost_options(ctx):
+            protoc_cmd.add("--prost_opt={}".format(option))
     
-    # Configure tonic gRPC generation
     if "tonic" in ctx.attrs.plugins:
         protoc_cmd.add("--plugin=protoc-gen-tonic={}".format(tools["protoc-gen-tonic"]))
-        protoc_cmd.add("--tonic_out={}".format(src_dir.as_output()))
-        
-        # Add tonic-specific options
-        tonic_options = ["compile_well_known_types"]  # Always enable well-known types
-        
+        protoc_cmd.add(cmd_args(raw_dir.as_output(), format = "--tonic_out={}"))
+        tonic_options = ["extern_path=.google.protobuf=::prost_types"]
         for opt_key, opt_value in ctx.attrs.options.items():
             if opt_key.startswith("tonic_"):
                 tonic_options.append("{}={}".format(opt_key[6:], opt_value))
-        
-        if tonic_options:
-            protoc_cmd.add("--tonic_opt={}".format(",".join(tonic_options)))
+        protoc_cmd.add("--tonic_opt={}".format(",".join(tonic_options)))
     
-    # Add proto files
-    protoc_cmd.add(proto_info.proto_files)
+    protoc_cmd.add(source_args)
     
-    # Collect all inputs
-    inputs = [tools["protoc"]] + proto_info.proto_files + proto_info.transitive_descriptor_sets
+    inputs = [tools["protoc"]] + source_inputs
+    for plugin in ["protoc-gen-prost", "protoc-gen-tonic"]:
+        if plugin in tools:
+            inputs.append(tools[plugin])
     
-    # Add plugin binaries if available
-    if "tonic" in ctx.attrs.plugins and "protoc-gen-tonic" in tools:
-        inputs.append(tools["protoc-gen-tonic"])
-    
-    # Run protoc to generate Rust code
     ctx.actions.run(
         protoc_cmd,
         category = "rust_protoc",
         identifier = "{}_rust_generation".format(ctx.label.name),
         inputs = inputs,
-        outputs = [output_dir, src_dir] + [f for f in output_files if f.short_path.startswith("rust/src/") and f.short_path.endswith(".rs")],
         env = {
             "PATH": "/usr/bin:/bin:/usr/local/bin",
         },
         local_only = False,
     )
 
+def _assemble_crate_sources(ctx, raw_dir, rust_package: str):
+    """
+    Writes the crate's src/ tree: the generated files plus mod.rs and lib.rs.
+    
+    Args:
+        ctx: Buck2 rule context
+        raw_dir: protoc output directory
+        rust_package: Crate name
+        
+    Returns:
+        The src/ directory
+    """
+    src_dir = ctx.actions.declare_output("rust", "src", dir = True)
+    ctx.actions.run(
+        cmd_args([
+            "python3",
+            ctx.attrs._rust_crate_gen[DefaultInfo].default_outputs[0],
+            "--input-dir", raw_dir,
+            "--output-dir", src_dir.as_output(),
+            "--crate-name", rust_package,
+        ]),
+        category = "rust_crate_sources",
+        identifier = ctx.label.name,
+    )
+    return src_dir
+
 def _create_package_config_files(ctx, rust_package: str):
     """
     Creates Rust package configuration files.
@@ -436,17 +372,6 @@ def _create_package_config_files(ctx, rust_package: str):
     )
     files.append(cargo_toml)
     
-    # Create lib.rs
-    lib_rs = ctx.actions.declare_output("rust", "src", "lib.rs")
-    proto_info = ctx.attrs.proto[ProtoInfo]
-    lib_content = _create_lib_rs_content(ctx, proto_info, rust_package)
-    
-    ctx.actions.write(
-        lib_rs,
-        lib_content,
-    )
-    files.append(lib_rs)
-    
     # Create build.rs
     build_rs = ctx.actions.declare_output("rust", "build.rs")
     build_content = _create_build_rs_content(ctx)
@@ -467,7 +392,7 @@ def _rust_proto_library_impl(ctx):
     - Rust package name resolution
     - Tool downloading and caching
     - protoc execution with Rust plugins (prost/tonic)
-    - Cargo.toml and lib.rs generation
+    - Crate source tree (mod.rs, lib.rs) and Cargo.toml generation
     - Output file management
     """
     # Get ProtoInfo from proto dependency
@@ -479,18 +404,17 @@ def _rust_proto_library_impl(ctx):
     # Ensure required tools are available
     tools = ensure_tools_available(ctx, "rust")
     
-    # Get expected output files
-    output_files = _get_rust_output_files(ctx, proto_info)
-    
-    # Generate Rust code using protoc
-    _generate_rust_code(ctx, proto_info, tools, output_files, rust_package)
+    # Generate Rust code using protoc, then lay it out as a crate
+    raw_dir = ctx.actions.declare_output("rust_raw", dir = True)
+    _generate_rust_code(ctx, proto_info, tools, raw_dir)
+    src_dir = _assemble_crate_sources(ctx, raw_dir, rust_package)
     
     # Create package configuration files
     config_files = _create_package_config_files(ctx, rust_package)
-    output_files.extend(config_files)
+    output_files = sort_by_path([src_dir] + config_files)
     
     # Determine dependencies based on plugins used
-    dependencies = ["prost"]
+    dependencies = ["prost", "prost-types"]
     if "tonic" in ctx.attrs.plugins:
         dependencies.extend(["tonic", "tokio"])
     if ctx.attrs.serde:
@@ -507,7 +431,13 @@ def _rust_proto_library_impl(ctx):
     
     # Return providers
     return [
-        DefaultInfo(default_outputs = output_files),
+        DefaultInfo(
+            default_outputs = output_files,
+            sub_targets = {
+                # For a downstream rust_library: mapped_srcs = {"<target>[src]": "src"}, crate_root = "src/lib.rs"
+                "src": [DefaultInfo(default_outputs = [src_dir])],
+            },
+        ),
         language_proto_info,
     ]
 
@@ -524,6 +454,8 @@ rust_proto_library_rule = rule(
         "use_grpc": attrs.bool(default = False, doc = "Generate tonic gRPC service code"),
         "edition": attrs.string(default = "2021", doc = "Rust edition to use"),
         "serde": attrs.bool(default = False, doc = "Enable serde serialization support"),
+        "prost_config": attrs.dict(attrs.string(), attrs.list(attrs.string()), default = {}, doc = "protoc-gen-prost option to its values"),
+        "_rust_crate_gen": attrs.exec_dep(default = "//tools:rust_crate_gen.py"),
         "_protoc": attrs.exec_dep(default = "//tools:protoc"),
         "_protoc_gen_prost": attrs.exec_dep(default = "//tools:protoc-gen-prost", doc = "Prost protoc plugin"),
         "_protoc_gen_tonic": attrs.exec_dep(default = "//tools:protoc-gen-tonic", doc = "Tonic protoc plugin"),
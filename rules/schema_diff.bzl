@@ -0,0 +1,103 @@
+"""Schema diff summaries for protobuf pull requests.
+
+This module provides a rule that compares a proto_library against a baseline
+(usually the same library built from the base branch) and writes a compact
+markdown summary of the changes for PR bots, together with a JSON report.
+"""
+
+load("//rules/private:providers.bzl", "ProtoInfo")
+
+def _proto_schema_diff_impl(ctx):
+    """Implementation of the proto_schema_diff rule."""
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    baseline_info = ctx.attrs.baseline[ProtoInfo]
+
+    summary = ctx.actions.declare_output("{}.md".format(ctx.label.name))
+    report = ctx.actions.declare_output("{}.json".format(ctx.label.name))
+
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._schema_diff[DefaultInfo].default_outputs[0],
+        "--output", summary.as_output(),
+        "--json-output", report.as_output(),
+        "--title", ctx.attrs.title,
+    ])
+
+    if ctx.attrs.fail_on_breaking:
+        cmd.add("--exit-code")
+
+    for baseline_file in baseline_info.proto_files:
+        cmd.add("--baseline", baseline_file)
+
+    for dep_file in proto_info.transitive_proto_files:
+        if dep_file not in proto_info.proto_files:
+            cmd.add("--dep", dep_file)
+
+    cmd.add(proto_info.proto_files)
+
+    ctx.actions.run(
+        cmd,
+        category = "schema_diff",
+        identifier = ctx.label.name,
+    )
+
+    return [
+        DefaultInfo(
+            default_outputs = [summary],
+            sub_targets = {"json": [DefaultInfo(default_outputs = [report])]},
+        ),
+    ]
+
+proto_schema_diff_rule = rule(
+    impl = _proto_schema_diff_impl,
+    attrs = {
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library with the proposed schema"),
+        "baseline": attrs.dep(providers = [ProtoInfo], doc = "Proto library with the base branch schema"),
+        "title": attrs.string(default = "Schema changes", doc = "Heading of the markdown summary"),
+        "fail_on_breaking": attrs.bool(default = False, doc = "Fail the build when breaking changes are found"),
+        "_schema_diff": attrs.exec_dep(default = "//tools:schema_diff.py"),
+    },
+)
+
+def proto_schema_diff(
+    name,
+    proto,
+    baseline,
+    title = "Schema changes",
+    fail_on_breaking = False,
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Summarizes schema changes against a baseline as markdown for PR comments.
+
+    The summary starts with the breaking status and counts of added, removed
+    and changed elements (e.g. "3 added fields · 1 removed rpc"), followed by
+    collapsible lists of breaking and other changes. Fields and enum values
+    are matched by number, so renames are reported as breaking changes. The
+    `[json]` sub-target holds the same changes as JSON.
+
+    Args:
+        name: Target name; the summary is written to <name>.md
+        proto: proto_library target with the proposed schema
+        baseline: proto_library target with the base branch schema
+        title: Heading of the markdown summary
+        fail_on_breaking: Fail the build when breaking changes are found
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_schema_diff(
+            name = "user_schema_diff",
+            proto = ":user_proto",
+            baseline = "//baseline:user_proto",
+        )
+    """
+    proto_schema_diff_rule(
+        name = name,
+        proto = proto,
+        baseline = baseline,
+        title = title,
+        fail_on_breaking = fail_on_breaking,
+        visibility = visibility,
+        **kwargs
+    )
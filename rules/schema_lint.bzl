@@ -0,0 +1,1626 @@
+"""Schema lint rules for protobuf APIs.
+
+This module provides build-time checks that enforce organization-specific API
+conventions which buf lint does not cover. Each public macro configures one
+check from tools/schema_lint.py; the check runs as a build action over the
+sources of the given proto_library targets and fails the build on violations.
+
+Every check writes a JSON report (same format as custom validation rules) and
+accepts `severity = "warning"` to report without failing, and `exemptions`
+to skip specific fully-qualified elements (glob patterns are supported).
+"""
+
+load("//rules/private:schema_lint_impl.bzl", "schema_lint_fix_impl", "schema_lint_impl")
+load("//rules/private:providers.bzl", "ProtoInfo")
+
+# Generic rule shared by all schema lint macros
+schema_lint_rule = rule(
+    impl = schema_lint_impl,
+    attrs = {
+        "protos": attrs.list(attrs.dep(providers = [ProtoInfo]), doc = "Proto library targets to check"),
+        "check": attrs.string(doc = "Name of the check in tools/schema_lint.py"),
+        "config": attrs.string(default = "{}", doc = "JSON-encoded check configuration"),
+        "inputs": attrs.dict(attrs.string(), attrs.source(), default = {}, doc = "Named input files for the check"),
+        "baseline": attrs.option(attrs.dep(providers = [ProtoInfo]), default = None, doc = "Baseline proto_library for version comparisons"),
+        "_schema_lint": attrs.exec_dep(default = "//tools:schema_lint.py"),
+    },
+)
+
+# Runnable companion of schema_lint_rule for checks that can fix their violations
+schema_lint_fix_rule = rule(
+    impl = schema_lint_fix_impl,
+    attrs = {
+        "protos": attrs.list(attrs.dep(providers = [ProtoInfo]), doc = "Proto library targets whose sources are rewritten"),
+        "check": attrs.string(doc = "Name of a check with a fixer in tools/schema_lint.py"),
+        "config": attrs.string(default = "{}", doc = "JSON-encoded check configuration"),
+        "_schema_lint": attrs.exec_dep(default = "//tools:schema_lint.py"),
+    },
+)
+
+def _schema_lint(name, protos, check, config, severity, exemptions, visibility, inputs = {}, baseline = None, **kwargs):
+    """Instantiates schema_lint_rule with the common configuration keys."""
+    if severity not in ["error", "warning"]:
+        fail("severity must be 'error' or 'warning', got '{}'".format(severity))
+
+    full_config = dict(config)
+    full_config["severity"] = severity
+    full_config["exemptions"] = exemptions
+
+    schema_lint_rule(
+        name = name,
+        protos = protos,
+        check = check,
+        config = json.encode(full_config),
+        inputs = inputs,
+        baseline = baseline,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_api_allowlist_check(
+    name,
+    proto,
+    allowlist,
+    public_option = "",
+    public_name_patterns = [],
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Validates that public messages and the API allowlist agree.
+
+    A message is public when it sets `public_option` to true or its fully
+    qualified name matches one of `public_name_patterns` (regular expressions).
+    The check fails in both directions: a public message missing from the
+    allowlist, and an allowlisted message that no longer exists.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        allowlist: Text file listing fully-qualified public message names, one per line
+        public_option: Message option marking public API (e.g. "(acme.api.v1.public)")
+        public_name_patterns: Regular expressions over full names that mark a message public
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_api_allowlist_check(
+            name = "user_api_surface",
+            proto = ":user_proto",
+            allowlist = "api_allowlist.txt",
+            public_option = "(acme.api.v1.public)",
+        )
+    """
+    if not public_option and not public_name_patterns:
+        fail("proto_api_allowlist_check requires public_option or public_name_patterns")
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "api_allowlist",
+        config = {
+            "public_option": public_option,
+            "public_name_patterns": public_name_patterns,
+        },
+        inputs = {"allowlist": allowlist},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_large_option_check(
+    name,
+    proto,
+    max_bytes = 1024,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Flags options and field defaults whose values exceed a byte threshold.
+
+    Large literals embedded in options or defaults are copied into every
+    descriptor set that includes the file. The check reports each offending
+    element together with the option name and its encoded size.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        max_bytes: Largest allowed option or default value, in bytes
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_large_option_check(
+            name = "user_option_size",
+            proto = ":user_proto",
+            max_bytes = 512,
+        )
+    """
+    if max_bytes <= 0:
+        fail("max_bytes must be positive, got {}".format(max_bytes))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "large_option_values",
+        config = {"max_bytes": max_bytes},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_comment_marker_check(
+    name,
+    proto,
+    markers = ["TODO", "FIXME", "XXX"],
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if doc comments contain release-blocking markers.
+
+    Intended for release builds: every message, field, enum, service and RPC
+    comment (leading and trailing) is searched for the given markers as whole
+    words, and each offending element is reported.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        markers: Words that must not appear in comments
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_comment_marker_check(
+            name = "user_release_hygiene",
+            proto = ":user_proto",
+            markers = ["TODO", "FIXME"],
+        )
+    """
+    if not markers:
+        fail("proto_comment_marker_check requires at least one marker")
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "comment_markers",
+        config = {"markers": markers},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_http_route_conflict_check(
+    name,
+    protos,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if two RPCs map to the same HTTP verb and path.
+
+    Collects every google.api.http rule (including additional_bindings) from
+    all given targets. Path templates are compared after normalizing
+    variables, so "/v1/users/{id}" and "/v1/users/{name}" conflict. Each
+    conflict reports both methods.
+
+    Args:
+        name: Target name
+        protos: proto_library targets whose services are served together
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified method names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_http_route_conflict_check(
+            name = "gateway_routes",
+            protos = [":user_proto", ":admin_proto"],
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = protos,
+        check = "http_route_conflicts",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_enum_stability_check(
+    name,
+    proto,
+    baseline,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if enum value numbers change relative to a baseline.
+
+    For enums persisted as integers, the name to number mapping must be
+    stable. The check compares every enum present in both versions and
+    reports values whose number changed, and new values that reuse a
+    number previously assigned to a different name. This is independent of
+    field-level breaking change detection.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        baseline: proto_library target with the previously released schema
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified enum value names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_enum_stability_check(
+            name = "status_enum_stability",
+            proto = ":status_proto",
+            baseline = "//baseline:status_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "enum_number_stability",
+        config = {},
+        baseline = baseline,
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_field_cardinality_check(
+    name,
+    proto,
+    baseline,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a field changes between singular, repeated and map relative to a baseline.
+
+    Fields are matched by message and field number, so renamed fields are
+    still compared. Such changes keep the number but break the wire format
+    for some types (e.g. message fields, or packed and unpacked scalars).
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        baseline: proto_library target with the previously released schema
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_field_cardinality_check(
+            name = "order_field_cardinality",
+            proto = ":order_proto",
+            baseline = "//baseline:order_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "field_cardinality_stability",
+        config = {},
+        baseline = baseline,
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_field_layout_check(
+    name,
+    proto,
+    baseline,
+    allow_inserted_fields = False,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if fields of a message change declaration order relative to a baseline.
+
+    Opt-in protection for codecs that depend on the descriptor's field order
+    rather than on field numbers. Fields are matched by number; each message
+    whose kept fields appear in a different order is reported with both
+    layouts. New fields must be declared after the existing ones unless
+    allow_inserted_fields is set.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        baseline: proto_library target with the previously released schema
+        allow_inserted_fields: Accept new fields declared between existing ones
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified message names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_field_layout_check(
+            name = "frame_field_layout",
+            proto = ":frame_proto",
+            baseline = "//baseline:frame_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "field_layout_stability",
+        config = {"allow_inserted_fields": allow_inserted_fields},
+        baseline = baseline,
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_unit_suffix_check(
+    name,
+    proto,
+    unit_rules = {},
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Requires numeric fields that carry a unit to name it with a suffix.
+
+    Each unit rule has a `pattern` (regular expression searched in the field
+    name) and the `suffixes` a matching field must end with. Only numeric
+    scalar fields are checked; message-typed fields such as
+    google.protobuf.Duration carry their own units. Without `unit_rules` the
+    built-in time (_seconds, _millis, _micros, _nanos), size (_bytes) and
+    money (_micros, _cents) rules apply.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        unit_rules: Dict of category name to {"pattern": regex, "suffixes": [...]}
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_unit_suffix_check(
+            name = "billing_units",
+            proto = ":billing_proto",
+            unit_rules = {
+                "time": {"pattern": "(^|_)(timeout|ttl)(_|$)", "suffixes": ["_seconds", "_millis"]},
+                "money": {"pattern": "(^|_)(price|total)(_|$)", "suffixes": ["_micros"]},
+            },
+        )
+    """
+    for category, rule in unit_rules.items():
+        if not rule.get("pattern") or not rule.get("suffixes"):
+            fail("unit rule '{}' requires a pattern and at least one suffix".format(category))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "unit_suffixes",
+        config = {"unit_rules": unit_rules},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_field_presence_check(
+    name,
+    proto,
+    prefer = "",
+    severity = "error",
+    exemptions = {},
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Flags proto3 messages that mix implicit-presence and optional scalars.
+
+    Within one message, either every singular scalar field should be
+    `optional` (explicit presence) or none should be, so consumers can tell
+    which fields are nullable. Repeated, map, oneof and message-typed fields
+    are not considered, and proto2/editions files are skipped.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        prefer: "explicit" or "implicit" to report the fields that should change
+                to the preferred style; empty to list both groups
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Dict of fully-qualified message names (or globs) to the
+                    documented reason for mixing styles
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_field_presence_check(
+            name = "user_field_presence",
+            proto = ":user_proto",
+            prefer = "explicit",
+            exemptions = {"acme.user.v1.User": "id is always set by the server"},
+        )
+    """
+    if prefer and prefer not in ["explicit", "implicit"]:
+        fail("prefer must be 'explicit' or 'implicit', got '{}'".format(prefer))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "field_presence_consistency",
+        config = {"prefer": prefer},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_service_casing_check(
+    name,
+    proto,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if any service or RPC name is not PascalCase.
+
+    Mirrors buf's SERVICE_PASCAL_CASE and RPC_PASCAL_CASE rules: a name must
+    start with an uppercase letter and contain only letters and digits.
+    Exempting a service also exempts all of its methods.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified service or method names (or globs) to skip,
+                    e.g. legacy services that cannot be renamed
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_service_casing_check(
+            name = "user_service_casing",
+            proto = ":user_service_proto",
+            exemptions = ["acme.user.v1.legacy_user_service"],
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "service_pascal_case",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_import_allowlist_check(
+    name,
+    proto,
+    allowed_modules,
+    module_prefixes = {},
+    allow_well_known_types = True,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a proto_library imports a file from a module outside an allowlist.
+
+    Each import is attributed to a source module by import path prefix. Common
+    BSR modules are built in (e.g. google/api/ belongs to
+    buf.build/googleapis/googleapis); `module_prefixes` adds or overrides
+    modules, and any other path belongs to the module named after its first
+    directory. Imports between the target's own files are always allowed.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        allowed_modules: Module names the target may import from
+        module_prefixes: Dict of module name to import path prefixes it provides
+        allow_well_known_types: Allow google/protobuf/ imports without listing
+                                buf.build/protocolbuffers/wellknowntypes
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Import paths (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_import_allowlist_check(
+            name = "payments_import_allowlist",
+            proto = ":payments_proto",
+            allowed_modules = [
+                "buf.build/googleapis/googleapis",
+                "//third_party/money:money_proto",
+            ],
+            module_prefixes = {"//third_party/money:money_proto": ["money/v1/"]},
+        )
+    """
+    allowed = list(allowed_modules)
+    if allow_well_known_types:
+        allowed.append("buf.build/protocolbuffers/wellknowntypes")
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "import_allowlist",
+        config = {
+            "allowed_modules": allowed,
+            "module_prefixes": module_prefixes,
+        },
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_rpc_top_level_check(
+    name,
+    proto,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if an RPC request or response type is a nested message.
+
+    Nested request and response types complicate imports and tooling in most
+    languages. Violations name the RPC and the offending type.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified RPC names or nested message names (or globs)
+                    to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_rpc_top_level_check(
+            name = "user_service_rpc_types",
+            proto = ":user_service_proto",
+            exemptions = ["acme.user.v1.UserService.LegacyLookup"],
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "rpc_top_level_messages",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_file_size_check(
+    name,
+    proto,
+    max_lines = 0,
+    max_definitions = 0,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a proto file exceeds a number of lines or top-level definitions.
+
+    Top-level definitions are messages, enums, services and extensions
+    declared directly in the file; nested types are not counted. Violations report the file and
+    its count, suggesting a split.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        max_lines: Largest allowed number of lines per file (0 for unlimited)
+        max_definitions: Largest allowed number of top-level definitions per
+                         file (0 for unlimited)
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: File paths (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_file_size_check(
+            name = "orders_file_size",
+            proto = ":orders_proto",
+            max_lines = 1000,
+            max_definitions = 30,
+        )
+    """
+    if max_lines < 0:
+        fail("max_lines must not be negative, got {}".format(max_lines))
+    if max_definitions < 0:
+        fail("max_definitions must not be negative, got {}".format(max_definitions))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "file_size",
+        config = {
+            "max_lines": max_lines,
+            "max_definitions": max_definitions,
+        },
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_custom_option_check(
+    name,
+    proto,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a custom option is not defined by an extension in the transitive deps.
+
+    Options are resolved with protobuf scoping rules against the extensions
+    declared in the target and its dependencies, and must extend the options
+    message of the element they are set on (e.g. google.protobuf.FieldOptions
+    for field options). go_proto_library runs this check before protoc by
+    default.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified element names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_custom_option_check(
+            name = "user_custom_options",
+            proto = ":user_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "registered_options",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_type_name_case_check(
+    name,
+    proto,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if two message or enum names in a package differ only in case.
+
+    Names such as FooBar and Foobar produce colliding files on case-insensitive
+    filesystems and colliding identifiers in some languages. Nested types are
+    compared by their fully-qualified names, and types from dependencies in the
+    same package are included. Violations name both types.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified type names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_type_name_case_check(
+            name = "user_type_name_case",
+            proto = ":user_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "case_insensitive_type_names",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_package_depth_check(
+    name,
+    proto,
+    min_depth = 3,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a package has fewer than min_depth dot-separated components.
+
+    With the default of 3, packages must look like org.domain.v1; files
+    without a package always fail. Violations report the file and package.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        min_depth: Minimum number of package components
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Package names (or globs) to skip, e.g. a shared root package
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_package_depth_check(
+            name = "billing_package_depth",
+            proto = ":billing_proto",
+            exemptions = ["acme.common"],
+        )
+    """
+    if min_depth <= 0:
+        fail("min_depth must be positive, got {}".format(min_depth))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "package_depth",
+        config = {"min_depth": min_depth},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_cardinality_check(
+    name,
+    proto,
+    option = "buck2protobuf.sizing.v1.max_count",
+    include_scalars = False,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a repeated message or map field has no expected maximum count.
+
+    Fields are annotated with a positive value of the given field option,
+    by default (buck2protobuf.sizing.v1.max_count) from
+    //proto:sizing_proto. Violations report each unannotated field.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        option: Fully-qualified custom field option holding the count
+        include_scalars: Also require the annotation on repeated scalar fields
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_cardinality_check(
+            name = "order_cardinality",
+            proto = ":order_proto",
+            exemptions = ["acme.order.v1.Export.*"],
+        )
+    """
+    if not option:
+        fail("option must name a custom field option")
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "repeated_cardinality",
+        config = {"option": option, "include_scalars": include_scalars},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_service_exposure_check(
+    name,
+    proto,
+    internal_option = "buck2protobuf.api.v1.internal",
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a service has no HTTP routes and is not marked internal.
+
+    A service passes when at least one method has a (google.api.http)
+    annotation or the service sets the internal marker option to true, by
+    default (buck2protobuf.api.v1.internal) from //proto:api_exposure_proto.
+    Violations report the service.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        internal_option: Fully-qualified bool service option marking internal services
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified service names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_service_exposure_check(
+            name = "api_exposure",
+            proto = ":api_proto",
+        )
+    """
+    if not internal_option:
+        fail("internal_option must name a custom service option")
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "service_exposure",
+        config = {"internal_option": internal_option},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_enum_negative_value_check(
+    name,
+    proto,
+    min_value = 0,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if an enum value is negative (below min_value).
+
+    Negative values are encoded as 10-byte varints and are rejected or
+    mishandled by some languages. Violations report each offending value.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        min_value: Smallest allowed enum value
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified enum or enum value names (or globs) to skip,
+                    e.g. enums that need a -1 sentinel
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_enum_negative_value_check(
+            name = "status_enum_values",
+            proto = ":status_proto",
+            exemptions = {"acme.v1.LegacyStatus": "-1 sentinel used by v0 clients"},
+        )
+    """
+    if min_value < 0:
+        fail("min_value must not be negative, got {}".format(min_value))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "negative_enum_values",
+        config = {"min_value": min_value},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_map_key_docs_check(
+    name,
+    proto,
+    key_pattern = "",
+    message_values_only = False,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a map field's leading comment does not describe the key.
+
+    A comment describes the key when it matches key_pattern, which by
+    default accepts phrases such as "keyed by", "key is", "indexed by" and
+    "map from". Violations report each map field that lacks such a comment.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        key_pattern: Case-insensitive regular expression a describing comment must match
+        message_values_only: Only check maps whose values are messages
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_map_key_docs_check(
+            name = "catalog_map_docs",
+            proto = ":catalog_proto",
+            message_values_only = True,
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "map_key_docs",
+        config = {"key_pattern": key_pattern, "message_values_only": message_values_only},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_reserved_field_number_check(
+    name,
+    proto,
+    ranges = [(50000, 536870911)],
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a non-extension field uses a number in a reserved internal range.
+
+    Field numbers from 50000 up are reserved for internal extensions by
+    default, so a regular field there is usually a mistake. Extension fields
+    are allowed in the ranges. Violations report each offending field.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        ranges: Inclusive (start, end) field number ranges; 536870911 is max
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_reserved_field_number_check(
+            name = "user_field_numbers",
+            proto = ":user_proto",
+            ranges = [(19000, 19999), (50000, 536870911)],
+        )
+    """
+    if not ranges:
+        fail("ranges must not be empty")
+    for start, end in ranges:
+        if start < 1 or start > end or end > 536870911:
+            fail("range ({}, {}) must satisfy 1 <= start <= end <= 536870911".format(start, end))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "reserved_field_numbers",
+        config = {"ranges": [[start, end] for start, end in ranges]},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_oneof_docs_check(
+    name,
+    proto,
+    keywords = [],
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a oneof's leading comment does not say its members are exclusive.
+
+    A comment passes when it contains one of the keywords (case-insensitive),
+    by default "exclusive", "only one", "at most one", "exactly one" or
+    "one of". Violations report each oneof without such a comment.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        keywords: Words or phrases that state exclusivity; empty for the defaults
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified oneof names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_oneof_docs_check(
+            name = "payment_oneof_docs",
+            proto = ":payment_proto",
+            keywords = ["exactly one", "at most one"],
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "oneof_exclusivity_docs",
+        config = {"keywords": keywords},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_proto2_required_check(
+    name,
+    proto,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a proto3 file uses a proto2 message that has required fields.
+
+    proto3 has no required fields, so proto3 code and tooling (JSON, partial
+    merges, default construction) readily produce messages that fail to
+    serialize or parse once a nested proto2 required field is unset. Every
+    proto3 field, map value and RPC request or response whose type is a proto2
+    message with a required field (directly or in a message it contains) is
+    reported with the import that brings it in and the required field. Types
+    are resolved across the transitive dependencies.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field or RPC names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_proto2_required_check(
+            name = "order_proto2_required",
+            proto = ":order_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "proto2_required_in_proto3",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_proto2_defaults_check(
+    name,
+    proto,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a proto3 file uses a proto2 message whose fields have explicit defaults.
+
+    Explicit defaults (`[default = 3]`) are a proto2 feature: proto3 has no
+    field presence defaults, and proto3 code, JSON mappings and schemas ported
+    to proto3 read unset fields as zero values. Every proto3 field, map value
+    and RPC request or response whose type is a proto2 message with defaulted
+    fields (directly or in proto2 messages it contains) is reported with the
+    import that brings it in and each defaulted field and its default. Types are
+    resolved across the transitive dependencies.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field or RPC names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_proto2_defaults_check(
+            name = "job_proto2_defaults",
+            proto = ":job_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "proto2_defaults_in_proto3",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_crud_annotation_check(
+    name,
+    proto,
+    create_option = "buck2protobuf.crud.v1.required_on_create",
+    update_option = "buck2protobuf.crud.v1.required_on_update",
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if create/update requirement annotations on fields conflict.
+
+    Fields are annotated with the given boolean field options, by default
+    (buck2protobuf.crud.v1.required_on_create) and
+    (buck2protobuf.crud.v1.required_on_update) from //proto:crud_proto.
+    Violations report fields marked with both, and fields required on create
+    that are nullable: proto3 optional fields, wrapper types and
+    google.protobuf.Value.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        create_option: Fully-qualified field option marking create-required fields
+        update_option: Fully-qualified field option marking update-required fields
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_crud_annotation_check(
+            name = "user_crud_annotations",
+            proto = ":user_proto",
+        )
+    """
+    if not create_option or not update_option:
+        fail("create_option and update_option must name custom field options")
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "crud_required_annotations",
+        config = {"create_option": create_option, "update_option": update_option},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_line_endings_check(
+    name,
+    proto,
+    line_ending = "lf",
+    final_newline = True,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a proto file lacks a trailing newline or uses other line endings.
+
+    Violations report the file, how many lines use the wrong line ending and
+    the first of them. A companion `<name>_fix` target rewrites the sources of
+    `proto` in place to satisfy the check: `buck2 run //pkg:<name>_fix`.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        line_ending: "lf", "crlf", or "consistent" to accept either as long
+                     as a file does not mix them
+        final_newline: Whether every non-empty file must end with a newline
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: File paths (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_line_endings_check(
+            name = "user_line_endings",
+            proto = ":user_proto",
+        )
+    """
+    if line_ending not in ["lf", "crlf", "consistent"]:
+        fail("line_ending must be 'lf', 'crlf' or 'consistent', got '{}'".format(line_ending))
+
+    config = {"line_ending": line_ending, "final_newline": final_newline}
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "line_endings",
+        config = config,
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+    fix_config = dict(config)
+    fix_config["exemptions"] = exemptions
+    schema_lint_fix_rule(
+        name = "{}_fix".format(name),
+        protos = [proto],
+        check = "line_endings",
+        config = json.encode(fix_config),
+        visibility = visibility,
+    )
+
+def proto_json_name_collision_check(
+    name,
+    proto,
+    proto_names = True,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if two fields of a message serialize to the same JSON key.
+
+    The JSON key of a field is its json_name option or protoc's lowerCamelCase
+    form of its name, so user_id and userId collide. Violations name both
+    fields and the shared key.
+
+    Args:
+    name: Target name
+        proto: proto_library target to check
+        proto_names: Whether a field name that equals another field's JSON
+                     name also collides, since JSON parsers accept both
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_json_name_collision_check(
+            name = "user_json_names",
+            proto = ":user_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "json_name_collisions",
+        config = {"proto_names": proto_names},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_idempotent_mutation_check(
+    name,
+    proto,
+    mutation_prefixes = ["Create", "Delete", "Update"],
+    justification = "Idempotent:",
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a mutating RPC is marked idempotent without a justification.
+
+    Clients and proxies retry methods with idempotency_level = IDEMPOTENT or
+    NO_SIDE_EFFECTS automatically, which is unsafe for most mutations. A
+    method whose name starts with one of mutation_prefixes (as a whole word:
+    CreateUser, not Createur) and carries either level is reported unless its
+    leading comment contains the justification marker.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        mutation_prefixes: Method name prefixes that denote a mutation
+        justification: Text that, found in a method's leading comment, accepts
+                       the level (case-insensitive); empty to require an exemption
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified method names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_idempotent_mutation_check(
+            name = "user_idempotency",
+            proto = ":user_service_proto",
+            mutation_prefixes = ["Create", "Delete", "Update", "Cancel"],
+        )
+    """
+    if not mutation_prefixes:
+        fail("mutation_prefixes must not be empty")
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "idempotent_mutations",
+        config = {"mutation_prefixes": mutation_prefixes, "justification": justification},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_group_check(
+    name,
+    proto,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a message or extension uses proto2 group syntax.
+
+    Groups are deprecated: they are not supported in proto3, most non-C++
+    tooling handles them poorly and their field name is derived from the type
+    name. Each group field and group extension is reported with its file, the
+    group type and field number; declare a nested message and a message field
+    instead.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified group field names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_group_check(
+            name = "search_groups",
+            proto = ":search_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "group_fields",
+        config = {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_bare_wkt_response_check(
+    name,
+    proto,
+    allowed = ["google.protobuf.Empty"],
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if an RPC returns a bare well-known type instead of its own message.
+
+    A response such as google.protobuf.StringValue cannot gain fields later
+    without changing the RPC signature, so each method should return a
+    dedicated response message. Violations name the RPC and the type it
+    returns.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        allowed: Fully-qualified well-known types that may be returned as is
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified RPC names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_bare_wkt_response_check(
+            name = "user_service_responses",
+            proto = ":user_service_proto",
+            exemptions = ["acme.user.v1.UserService.LegacyName"],
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "bare_wkt_responses",
+        config = {"allowed": allowed},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_comment_type_reference_check(
+    name,
+    proto,
+    pattern = "",
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a doc comment references a type that does not exist.
+
+    Doc generators turn [TypeName] references in comments into links. Each
+    reference is resolved like a type name written where the commented
+    element is declared, against the messages, enums and services of the
+    target and its dependencies. Violations report the comment line and the
+    missing type.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        pattern: Regular expression with one group capturing the referenced
+                 type name (default: [TypeName] references that are not
+                 markdown links)
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Fully-qualified names of commented elements (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_comment_type_reference_check(
+            name = "user_doc_links",
+            proto = ":user_proto",
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "comment_type_references",
+        config = {"pattern": pattern} if pattern else {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_enum_openness_check(
+    name,
+    proto,
+    policy = "closed",
+    severity = "error",
+    exemptions = {},
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if an enum is open when the policy requires closed enums, or vice versa.
+
+    Openness is resolved as protoc resolves it for the descriptor set: proto2
+    enums are closed, proto3 enums are open, and editions enums take
+    features.enum_type from the enum, its enclosing messages or the file,
+    defaulting to open. Violations report the enum and what made it open or
+    closed.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        policy: "closed" or "open"
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Dict of fully-qualified enum names (or globs) to the
+                    documented reason for deviating from the policy
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_enum_openness_check(
+            name = "user_enum_openness",
+            proto = ":user_proto",
+            policy = "closed",
+            exemptions = {"acme.user.v1.Color": "clients may send new colors"},
+        )
+    """
+    if policy not in ["closed", "open"]:
+        fail("policy must be 'closed' or 'open', got '{}'".format(policy))
+
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "enum_openness",
+        config = {"policy": policy},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_wkt_name_shadowing_check(
+    name,
+    proto,
+    names = [],
+    severity = "error",
+    exemptions = {},
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a message or enum has the name of a well-known type.
+
+    A local acme.v1.Timestamp is easily mistaken for google.protobuf.Timestamp
+    by consumers. Nested types are checked too. Violations name the local
+    type and the well-known type it shadows.
+
+    Args:
+        name: Target name
+        proto: proto_library target to check
+        names: Fully-qualified well-known types whose names are reserved
+               (default: all well-known types, the generic ones such as
+               google.protobuf.Field only in files importing their file)
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: Dict of fully-qualified type names (or globs) to the
+                    documented reason for keeping the name
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_wkt_name_shadowing_check(
+            name = "user_wkt_names",
+            proto = ":user_proto",
+            exemptions = {"acme.user.v1.Timestamp": "wire format predates the well-known type"},
+        )
+    """
+    _schema_lint(
+        name = name,
+        protos = [proto],
+        check = "wkt_name_shadowing",
+        config = {"names": names} if names else {},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
+
+def proto_package_owner_check(
+    name,
+    protos,
+    owner_option = "buck2protobuf.owner.v1.owner",
+    require_consistent = True,
+    severity = "error",
+    exemptions = [],
+    visibility = ["//visibility:private"],
+    **kwargs):
+    """
+    Fails if a file does not declare its owning team.
+
+    Every file must set the owner file option, by default
+    (buck2protobuf.owner.v1.owner) from //proto:owner_proto, to a non-empty
+    string. With require_consistent, all files of a package must also name
+    the same owner; pass every target of a package in protos so the check
+    sees all of its files. Violations report the file or the package.
+
+    Args:
+        name: Target name
+        protos: proto_library targets to check
+        owner_option: Fully-qualified string file option naming the owner
+        require_consistent: Whether files of the same package must agree on the owner
+        severity: "error" to fail the build, "warning" to only report
+        exemptions: File paths or package names (or globs) to skip
+        visibility: Target visibility
+        **kwargs: Additional arguments
+
+    Example:
+        proto_package_owner_check(
+            name = "billing_owners",
+            protos = [":billing_proto", ":invoice_proto"],
+        )
+    """
+    if not owner_option:
+        fail("owner_option must name a custom file option")
+
+    _schema_lint(
+        name = name,
+        protos = protos,
+        check = "package_owners",
+        config = {"owner_option": owner_option, "require_consistent": require_consistent},
+        severity = severity,
+        exemptions = exemptions,
+        visibility = visibility,
+        **kwargs
+    )
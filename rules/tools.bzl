@@ -24,14 +24,52 @@ def get_target_platform(ctx = None):
     return platform_info["platform_string"]
 
 
-def get_protoc_binary(ctx, version: str = "", platform: str = ""):
+def get_offline_args(ctx):
+    """
+    Returns the download script arguments of offline (air-gapped) mode.
+    
+    Offline mode is enabled by the rule's `offline` attribute or
+    `[protobuf] offline = true`. Tools are then only resolved from the
+    pre-populated cache at `offline_cache_dir` (attribute, then
+    `[protobuf] offline_cache_dir`, then $BUCK2_PROTOBUF_OFFLINE_CACHE
+    in the action environment) and a missing tool fails without any
+    download attempt.
+    
+    Args:
+        ctx: Buck2 rule context
+    
+    Returns:
+        List of arguments, empty when downloads are allowed
+    """
+    offline = getattr(ctx.attrs, "offline", None)
+    if offline == None:
+        offline = read_root_config("protobuf", "offline", "false").lower() in ["true", "1", "yes"]
+    if not offline:
+        return []
+    
+    args = ["--offline"]
+    cache_dir = getattr(ctx.attrs, "offline_cache_dir", "") or read_root_config("protobuf", "offline_cache_dir", "")
+    if cache_dir:
+        args.extend(["--offline-cache-dir", cache_dir])
+    return args
+
+
+def get_protoc_binary(ctx, version: str = "", platform: str = "", sha256: str = "", url: str = "", lockfile = None):
     """
     Downloads and caches the protoc binary for the specified version and platform.
     
+    The release archive is verified against its SHA256 from the lockfile
+    (`lockfile`, or the rule's `_protoc_lockfile` attribute); `sha256`
+    overrides the pin, e.g. for archives repackaged by an internal mirror.
+    Archives without a pin are used with a warning listing their digest.
+    
     Args:
         ctx: Buck2 rule context
         version: Protoc version (e.g., "24.4"). Uses default if empty.
         platform: Target platform (e.g., "linux-x86_64"). Auto-detected if empty.
+        sha256: SHA256 of the release archive, overriding the lockfile
+        url: Download URL override (e.g. an air-gapped mirror)
+        lockfile: Lockfile artifact with pinned checksums
     
     Returns:
         File object pointing to the cached protoc binary
@@ -47,26 +85,29 @@ def get_protoc_binary(ctx, version: str = "", platform: str = ""):
     
     # Get protoc configuration
     protoc_info = get_protoc_info()
-    if version not in protoc_info:
-        fail("Unsupported protoc version: {}. Available versions: {}".format(
-            version, protoc_info.keys()))
-    
-    if platform not in protoc_info[version]:
-        fail("Unsupported platform for protoc {}: {}. Available platforms: {}".format(
-            version, platform, protoc_info[version].keys()))
+    if not url:
+        if version not in protoc_info:
+            fail("Unsupported protoc version: {}. Available versions: {}".format(
+                version, protoc_info.keys()))
+        
+        if platform not in protoc_info[version]:
+            fail("Unsupported platform for protoc {}: {}. Available platforms: {}".format(
+                version, platform, protoc_info[version].keys()))
     
-    config = protoc_info[version][platform]
-    expected_checksum = config["sha256"]
+    binary_path = "bin/protoc.exe" if platform.startswith("windows") else "bin/protoc"
     
     # Create cache directory name
     cache_key = "protoc-{}-{}".format(version, platform)
     
     # Create the download action
     download_script = ctx.attrs._download_protoc_script[DefaultInfo].default_outputs[0]
-    output_file = ctx.actions.declare_output("tools", cache_key, config["binary_path"])
+    output_file = ctx.actions.declare_output("tools", cache_key, binary_path)
     
-    # Create cache directory for the action
-    cache_dir = ctx.actions.declare_output("tools", "cache")
+    # Create cache directory for the action (per version, so a rule can fetch several)
+    cache_dir = ctx.actions.declare_output("tools", "cache", cache_key)
+    
+    if lockfile == None:
+        lockfile = getattr(ctx.attrs, "_protoc_lockfile", None)
     
     cmd = cmd_args([
         "python3",
@@ -74,15 +115,24 @@ def get_protoc_binary(ctx, version: str = "", platform: str = ""):
         "--version", version,
         "--platform", platform,
         "--cache-dir", cache_dir.as_output(),
-        "--checksum", expected_checksum,
+        "--output", output_file.as_output(),
     ])
+    if lockfile:
+        cmd.add("--lockfile", lockfile)
+    if sha256:
+        cmd.add("--checksum", sha256)
+    offline_args = get_offline_args(ctx)
+    if offline_args:
+        cmd.add(offline_args)
+    elif url:
+        cmd.add("--url", url)
     
     # Run the download script
     ctx.actions.run(
         cmd,
         category = "protoc_download",
         identifier = cache_key,
-        inputs = [download_script],
+        inputs = [download_script] + _offline_tools_inputs(ctx, offline_args),
         outputs = [output_file, cache_dir],
         env = {
             "PYTHONPATH": ".",
@@ -151,18 +201,30 @@ def get_plugin_binary(ctx, plugin: str, version: str = "", platform: str = ""):
         "--version", version,
         "--platform", platform,
         "--cache-dir", cache_dir.as_output(),
+        "--output", output_file.as_output(),
     ])
     
     # Add checksum validation for binary plugins
     if "sha256" in config:
         cmd.add("--checksum", config["sha256"])
     
+    # Shared content-addressed cache, so plugin versions fetched by earlier
+    # builds are not downloaded again
+    plugin_cache_dir = read_root_config("protobuf", "plugin_cache_dir", "")
+    if plugin_cache_dir:
+        cmd.add("--plugin-cache-dir", plugin_cache_dir)
+    
+    offline_args = get_offline_args(ctx)
+    if offline_args:
+        cmd.add(offline_args)
+        cmd.add("--binary-path", config["binary_path"])
+    
     # Run the download script
     ctx.actions.run(
         cmd,
         category = "plugin_download",
         identifier = cache_key,
-        inputs = [download_script],
+        inputs = [download_script] + _plugin_cache_inputs(ctx) + _offline_tools_inputs(ctx, offline_args),
         outputs = [output_file, cache_dir],
         env = {
             "PYTHONPATH": ".",
@@ -173,6 +235,20 @@ def get_plugin_binary(ctx, plugin: str, version: str = "", platform: str = ""):
     return output_file
 
 
+def _plugin_cache_inputs(ctx):
+    """Returns the plugin cache module the plugin download script imports."""
+    plugin_cache = getattr(ctx.attrs, "_plugin_cache_script", None)
+    return [plugin_cache] if plugin_cache else []
+
+
+def _offline_tools_inputs(ctx, offline_args):
+    """Returns the offline resolution module the download scripts import in offline mode."""
+    offline_tools = getattr(ctx.attrs, "_offline_tools_script", None)
+    if offline_args and offline_tools:
+        return [offline_tools]
+    return []
+
+
 def validate_tool_checksum(ctx, file, expected_checksum: str, tool_type: str = "protoc"):
     """
     Validates that a downloaded tool matches its expected SHA256 checksum.
@@ -328,6 +404,10 @@ TOOL_ATTRS = {
         default = "//tools:download_protoc.py",
         doc = "Python script for downloading protoc binaries",
     ),
+    "_protoc_lockfile": attrs.source(
+        default = "//tools:protoc.lock.json",
+        doc = "Pinned SHA256 checksums of protoc release archives",
+    ),
     "_download_plugins_script": attrs.source(
         default = "//tools:download_plugins.py", 
         doc = "Python script for downloading protoc plugins",
@@ -336,6 +416,16 @@ TOOL_ATTRS = {
         default = "//tools:validate_tools.py",
         doc = "Python script for validating tool integrity",
     ),
+    "_offline_tools_script": attrs.source(
+        default = "//tools:offline_tools.py",
+        doc = "Offline tool cache resolution used by the download scripts",
+    ),
+    "_plugin_cache_script": attrs.source(
+        default = "//tools:plugin_cache.py",
+        doc = "Content-addressed plugin cache used by the plugin download script",
+    ),
+    "offline": attrs.option(attrs.bool(), default = None, doc = "Resolve tools from the offline cache only (default: [protobuf] offline)"),
+    "offline_cache_dir": attrs.string(default = "", doc = "Pre-populated tool cache for offline mode (default: [protobuf] offline_cache_dir)"),
 }
 
 
@@ -411,3 +501,67 @@ def ensure_tools_available(ctx, language: str):
             tools[tool_name] = get_plugin_binary(ctx, tool_name, version)
     
     return tools
+
+
+def _tool_manifest_impl(ctx):
+    """Implementation of the tool_manifest rule."""
+    versions = ctx.attrs.tools or get_default_versions()
+    platforms = ctx.attrs.platforms or [get_target_platform(ctx)]
+    protoc_info = get_protoc_info()
+    plugin_info = get_plugin_info()
+    
+    specs = []
+    for tool in sorted(versions.keys()):
+        version = versions[tool]
+        for platform in platforms:
+            if tool == "protoc":
+                config = protoc_info.get(version, {}).get(platform, {})
+                binary_path = "bin/protoc.exe" if platform.startswith("windows") else "bin/protoc"
+            else:
+                config = plugin_info.get(tool, {}).get(version, {}).get(platform, {})
+                binary_path = tool
+            specs.append({
+                "tool": tool,
+                "version": version,
+                "platform": platform,
+                "binary_path": config.get("binary_path", binary_path),
+                "sha256": config.get("sha256"),
+                "url": config.get("url"),
+            })
+    
+    specs_file = ctx.actions.write_json("{}_specs.json".format(ctx.label.name), specs)
+    manifest = ctx.actions.declare_output("{}.txt".format(ctx.label.name))
+    cmd = cmd_args([
+        "python3",
+        ctx.attrs._offline_tools_script,
+        "manifest",
+        "--specs", specs_file,
+        "--lockfile", ctx.attrs._protoc_lockfile,
+        "--output", manifest.as_output(),
+    ])
+    cache_dir = ctx.attrs.cache_dir or read_root_config("protobuf", "offline_cache_dir", "")
+    if cache_dir:
+        cmd.add("--cache-dir", cache_dir)
+    
+    ctx.actions.run(
+        cmd,
+        category = "tool_manifest",
+        identifier = ctx.label.name,
+        local_only = True,  # Checks the local offline cache
+    )
+    
+    return [DefaultInfo(default_outputs = [manifest])]
+
+
+# Lists the tools (and versions, checksums, URLs) a build needs, to seed an offline cache:
+#   buck2 build //tools:tool_manifest --out -
+tool_manifest = rule(
+    impl = _tool_manifest_impl,
+    attrs = {
+        "tools": attrs.dict(attrs.string(), attrs.string(), default = {}, doc = "Tool versions to list (default: all default versions)"),
+        "platforms": attrs.list(attrs.string(), default = [], doc = "Platforms to list (default: the target platform)"),
+        "cache_dir": attrs.string(default = "", doc = "Offline cache to mark tools present or missing in (default: [protobuf] offline_cache_dir)"),
+        "_offline_tools_script": attrs.source(default = "//tools:offline_tools.py"),
+        "_protoc_lockfile": attrs.source(default = "//tools:protoc.lock.json"),
+    },
+)
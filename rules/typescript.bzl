@@ -2,12 +2,14 @@
 
 This module provides rules for generating TypeScript code from protobuf definitions.
 Supports both basic protobuf messages and gRPC-Web client generation with proper
-NPM package integration and TypeScript configuration.
+NPM package integration and TypeScript configuration, protobuf-es code
+generation (with optional Connect-ES clients) through ts_proto_library, and
+google-protobuf based gRPC-Web clients through grpc_web_library.
 """
 
 load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo")
-load("//rules/private:utils.bzl", "get_proto_import_path")
-load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS", "get_protoc_command")
+load("//rules/private:utils.bzl", "external_descriptor_set_args", "get_proto_import_path", "protoc_source_args", "sort_by_path")
+load("//rules:tools.bzl", "ensure_tools_available", "TOOL_ATTRS", "get_plugin_binary", "get_protoc_binary", "get_protoc_command")
 
 def typescript_proto_library(
     name: str,
@@ -332,10 +334,8 @@ def _generate_typescript_code(ctx, proto_info, tools, output_files, npm_package:
     # Build protoc command arguments
     protoc_cmd = cmd_args([tools["protoc"]])
     
-    # Add import paths (current + transitive)
-    all_import_paths = proto_info.import_paths + proto_info.transitive_import_paths
-    for import_path in all_import_paths:
-        protoc_cmd.add("--proto_path={}".format(import_path))
+    # Import paths and proto files, or the shared descriptor set of a proto_bundle
+    source_args, source_inputs = protoc_source_args(proto_info)
     
     # Configure TypeScript code generation based on plugins
     if "ts" in ctx.attrs.plugins:
@@ -377,10 +377,10 @@ def _generate_typescript_code(ctx, proto_info, tools, output_files, npm_package:
         protoc_cmd.add("--grpc-web_out=import_style=typescript,mode=grpcwebtext:{}".format(src_dir.as_output()))
     
     # Add proto files
-    protoc_cmd.add(proto_info.proto_files)
+    protoc_cmd.add(source_args)
     
     # Collect all inputs
-    inputs = [tools["protoc"]] + proto_info.proto_files + proto_info.transitive_descriptor_sets
+    inputs = [tools["protoc"]] + source_inputs
     
     for plugin_name in ctx.attrs.plugins:
         if plugin_name == "ts" and "protoc-gen-ts" in tools:
@@ -492,7 +492,7 @@ def _typescript_proto_library_impl(ctx):
     
     # Create index.ts file
     index_file = _create_index_file(ctx, proto_info)
-    output_files.append(index_file)
+    output_files = sort_by_path(output_files + [index_file])
     
     # Determine dependencies based on plugins used
     dependencies = ["google-protobuf"]
@@ -594,3 +594,379 @@ def typescript_grpc_web_library(
         use_grpc_web = True,
         **kwargs
     )
+
+# protobuf-es runtime versions matching the default protoc-gen-es and
+# protoc-gen-connect-es plugins
+ES_RUNTIME_VERSIONS = {
+    "@bufbuild/protobuf": "^1.10.0",
+    "@connectrpc/connect": "^1.6.1",
+}
+
+ES_TARGETS = ["ts", "js+dts"]
+
+def _package_tree_proto_files(proto_info):
+    """
+    Returns the proto files generated into a ts_proto_library or
+    grpc_web_library tree.
+
+    protoc-gen-es and protoc-gen-js import the code of other proto files
+    through relative paths, so the files of proto_library dependencies are
+    generated into the same tree. Well-known types are imported from the
+    runtime package (@bufbuild/protobuf or google-protobuf) instead.
+    """
+    files = list(proto_info.proto_files)
+    for proto_file in proto_info.transitive_proto_files:
+        if proto_file in files:
+            continue
+        if get_proto_import_path(proto_file).startswith("google/protobuf/"):
+            continue
+        files.append(proto_file)
+    return files
+
+def _create_es_package_json(npm_package: str, connect: bool):
+    """
+    Returns the package.json stub of a ts_proto_library tree.
+
+    Generated files sit under src/ at their proto import paths and are exported
+    by path, e.g. "@acme/user-es/acme/user/v1/user_pb.js".
+    """
+    peer_dependencies = {"@bufbuild/protobuf": ES_RUNTIME_VERSIONS["@bufbuild/protobuf"]}
+    if connect:
+        peer_dependencies["@connectrpc/connect"] = ES_RUNTIME_VERSIONS["@connectrpc/connect"]
+    return {
+        "name": npm_package,
+        "version": "0.0.0",
+        "private": True,
+        "type": "module",
+        "sideEffects": False,
+        "exports": {
+            "./*": "./src/*",
+        },
+        "peerDependencies": peer_dependencies,
+    }
+
+def _ts_proto_library_impl(ctx):
+    """Implementation of the ts_proto_library rule."""
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    npm_package = _resolve_npm_package(ctx, proto_info)
+    protoc = get_protoc_binary(ctx)
+    protoc_gen_es = get_plugin_binary(ctx, "protoc-gen-es")
+
+    src_dir = ctx.actions.declare_output("{}_src".format(ctx.label.name), dir = True)
+    es_opts = ["target={}".format(ctx.attrs.target)] + ctx.attrs.options
+    cmd = cmd_args([
+        protoc,
+        cmd_args(protoc_gen_es, format = "--plugin=protoc-gen-es={}"),
+        cmd_args(src_dir.as_output(), format = "--es_out={}"),
+        "--es_opt={}".format(",".join(es_opts)),
+    ])
+    inputs = [protoc, protoc_gen_es]
+    if ctx.attrs.connect:
+        protoc_gen_connect_es = get_plugin_binary(ctx, "protoc-gen-connect-es")
+        cmd.add(cmd_args(protoc_gen_connect_es, format = "--plugin=protoc-gen-connect-es={}"))
+        cmd.add(cmd_args(src_dir.as_output(), format = "--connect-es_out={}"))
+        cmd.add("--connect-es_opt={}".format(",".join(es_opts)))
+        inputs.append(protoc_gen_connect_es)
+
+    for import_path in proto_info.import_paths + proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+    cmd.add(external_descriptor_set_args(proto_info))
+    cmd.add(_package_tree_proto_files(proto_info))
+
+    ctx.actions.run(
+        cmd,
+        category = "protoc_gen_es",
+        identifier = ctx.label.name,
+        inputs = inputs + proto_info.transitive_descriptor_sets,
+    )
+
+    package_config = _create_es_package_json(npm_package, ctx.attrs.connect)
+    package_json = ctx.actions.write_json(
+        "{}_package.json".format(ctx.label.name),
+        package_config,
+        pretty = True,
+    )
+    package_dir = ctx.actions.copied_dir(ctx.label.name, {
+        "package.json": package_json,
+        "src": src_dir,
+    })
+
+    dependencies = sorted(package_config["peerDependencies"].keys())
+    return [
+        DefaultInfo(
+            default_outputs = [package_dir],
+            sub_targets = {
+                "src": [DefaultInfo(default_outputs = [src_dir])],
+                "package.json": [DefaultInfo(default_outputs = [package_json])],
+            },
+        ),
+        LanguageProtoInfo(
+            language = "typescript",
+            generated_files = [package_dir],
+            package_name = npm_package,
+            dependencies = dependencies,
+            compiler_flags = [],
+        ),
+    ]
+
+ts_proto_library_rule = rule(
+    impl = _ts_proto_library_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
+        "npm_package": attrs.string(default = "", doc = "NPM package name override"),
+        "target": attrs.string(default = "ts", doc = "protobuf-es output: ts or js+dts"),
+        "connect": attrs.bool(default = False, doc = "Also run protoc-gen-connect-es for services"),
+        "options": attrs.list(attrs.string(), default = [], doc = "Additional KEY=VALUE plugin options"),
+    }),
+)
+
+def ts_proto_library(
+    name: str,
+    proto: str,
+    target: str = "ts",
+    connect: bool = False,
+    npm_package: str = "",
+    options: list[str] = [],
+    visibility: list[str] = ["//visibility:private"],
+    **kwargs
+):
+    """
+    Generates protobuf-es TypeScript code from a proto_library target.
+
+    Runs protoc-gen-es, and protoc-gen-connect-es when connect is set, and
+    produces an npm package tree: a package.json stub declaring the
+    @bufbuild/protobuf peer dependency, and the generated files under src/ at
+    their proto import paths. Files of proto_library dependencies are
+    generated into the same tree so that their relative imports resolve;
+    well-known types come from @bufbuild/protobuf.
+
+    Args:
+        name: Unique name for this target
+        proto: proto_library target to generate code from
+        target: "ts" for .ts files, or "js+dts" for .js files with .d.ts
+                declarations, as the buf-es target option
+        connect: Generate Connect-ES service clients (*_connect files) and
+                 declare the @connectrpc/connect peer dependency
+        npm_package: NPM package name (defaults to one derived from the proto path)
+        options: Additional KEY=VALUE options for both plugins, e.g.
+                 ["import_extension=none"]
+        visibility: Buck2 visibility specification
+        **kwargs: Additional arguments passed to the underlying rule
+
+    Example:
+        ts_proto_library(
+            name = "user_es",
+            proto = ":user_proto",
+            target = "js+dts",
+            connect = True,
+            npm_package = "@myorg/user-es",
+        )
+
+    Generated Files:
+        - <name>/package.json: NPM package stub
+        - <name>/src/**/*_pb.ts (or *_pb.js and *_pb.d.ts): Messages and enums
+        - <name>/src/**/*_connect.ts (or .js and .d.ts): Service clients (if connect=True)
+    """
+    if target not in ES_TARGETS:
+        fail("target must be one of {}, got '{}'".format(ES_TARGETS, target))
+    for option in options:
+        if "=" not in option or option.startswith("target="):
+            fail("options must be KEY=VALUE plugin options other than target, got '{}'".format(option))
+
+    ts_proto_library_rule(
+        name = name,
+        proto = proto,
+        target = target,
+        connect = connect,
+        npm_package = npm_package,
+        options = options,
+        visibility = visibility,
+        **kwargs
+    )
+
+# Runtime versions matching the default protoc-gen-js and protoc-gen-grpc-web
+# plugins. google-protobuf also provides the well-known type modules.
+GRPC_WEB_RUNTIME_VERSIONS = {
+    "google-protobuf": "^3.21.2",
+    "grpc-web": "^1.4.2",
+}
+
+# Supported (import_style, mode) combinations of grpc_web_library.
+GRPC_WEB_COMBINATIONS = [
+    ("commonjs", "grpcwebtext"),
+    ("commonjs", "grpcweb"),
+    ("typescript", "grpcwebtext"),
+    ("typescript", "grpcweb"),
+]
+
+# protoc-gen-grpc-web import_style passed for each grpc_web_library
+# import_style; commonjs output always comes with .d.ts declarations.
+_GRPC_WEB_PLUGIN_IMPORT_STYLES = {
+    "commonjs": "commonjs+dts",
+    "typescript": "typescript",
+}
+
+def _create_grpc_web_package_json(npm_package: str):
+    """
+    Returns the package.json stub of a grpc_web_library tree.
+
+    Generated files are CommonJS modules under src/ at their proto import
+    paths and are exported by path, e.g.
+    "@acme/user-web/acme/user/v1/user_grpc_web_pb.js".
+    """
+    return {
+        "name": npm_package,
+        "version": "0.0.0",
+        "private": True,
+        "exports": {
+            "./*": "./src/*",
+        },
+        "peerDependencies": dict(GRPC_WEB_RUNTIME_VERSIONS),
+    }
+
+def _grpc_web_library_impl(ctx):
+    """Implementation of the grpc_web_library rule."""
+    proto_info = ctx.attrs.proto[ProtoInfo]
+    npm_package = _resolve_npm_package(ctx, proto_info)
+    protoc = get_protoc_binary(ctx)
+    protoc_gen_js = get_plugin_binary(ctx, "protoc-gen-js")
+    protoc_gen_grpc_web = get_plugin_binary(ctx, "protoc-gen-grpc-web")
+
+    # protoc-gen-js writes the *_pb.js messages; protoc-gen-grpc-web writes
+    # the *_pb.d.ts declarations and the service clients
+    src_dir = ctx.actions.declare_output("{}_src".format(ctx.label.name), dir = True)
+    grpc_web_opts = [
+        "import_style={}".format(_GRPC_WEB_PLUGIN_IMPORT_STYLES[ctx.attrs.import_style]),
+        "mode={}".format(ctx.attrs.mode),
+    ] + ctx.attrs.options
+    cmd = cmd_args([
+        protoc,
+        cmd_args(protoc_gen_js, format = "--plugin=protoc-gen-js={}"),
+        cmd_args(src_dir.as_output(), format = "--js_out=import_style=commonjs,binary:{}"),
+        cmd_args(protoc_gen_grpc_web, format = "--plugin=protoc-gen-grpc-web={}"),
+        cmd_args(src_dir.as_output(), format = "--grpc-web_out={}"),
+        "--grpc-web_opt={}".format(",".join(grpc_web_opts)),
+    ])
+
+    for import_path in proto_info.import_paths + proto_info.transitive_import_paths:
+        cmd.add("--proto_path={}".format(import_path))
+    cmd.add(external_descriptor_set_args(proto_info))
+    cmd.add(_package_tree_proto_files(proto_info))
+
+    ctx.actions.run(
+        cmd,
+        category = "protoc_gen_grpc_web",
+        identifier = ctx.label.name,
+        inputs = [protoc, protoc_gen_js, protoc_gen_grpc_web] + proto_info.transitive_descriptor_sets,
+    )
+
+    package_config = _create_grpc_web_package_json(npm_package)
+    package_json = ctx.actions.write_json(
+        "{}_package.json".format(ctx.label.name),
+        package_config,
+        pretty = True,
+    )
+    package_dir = ctx.actions.copied_dir(ctx.label.name, {
+        "package.json": package_json,
+        "src": src_dir,
+    })
+
+    return [
+        DefaultInfo(
+            default_outputs = [package_dir],
+            sub_targets = {
+                "src": [DefaultInfo(default_outputs = [src_dir])],
+                "package.json": [DefaultInfo(default_outputs = [package_json])],
+            },
+        ),
+        LanguageProtoInfo(
+            language = "typescript",
+            generated_files = [package_dir],
+            package_name = npm_package,
+            dependencies = sorted(package_config["peerDependencies"].keys()),
+            compiler_flags = [],
+        ),
+    ]
+
+grpc_web_library_rule = rule(
+    impl = _grpc_web_library_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "proto": attrs.dep(providers = [ProtoInfo], doc = "Proto library target"),
+        "npm_package": attrs.string(default = "", doc = "NPM package name override"),
+        "import_style": attrs.string(default = "commonjs", doc = "Service client style: commonjs or typescript"),
+        "mode": attrs.string(default = "grpcwebtext", doc = "Wire format: grpcwebtext or grpcweb"),
+        "options": attrs.list(attrs.string(), default = [], doc = "Additional KEY=VALUE protoc-gen-grpc-web options"),
+    }),
+)
+
+def grpc_web_library(
+    name: str,
+    proto: str,
+    import_style: str = "commonjs",
+    mode: str = "grpcwebtext",
+    npm_package: str = "",
+    options: list[str] = [],
+    visibility: list[str] = ["//visibility:private"],
+    **kwargs
+):
+    """
+    Generates gRPC-Web browser clients from a proto_library target.
+
+    Runs protoc-gen-js for CommonJS messages and protoc-gen-grpc-web for
+    service clients and declarations, and produces an npm package tree: a
+    package.json stub declaring the google-protobuf and grpc-web peer
+    dependencies, and the generated files under src/ at their proto import
+    paths. Files of proto_library dependencies are generated into the same
+    tree; well-known types are required from the google-protobuf package,
+    which ships their generated code.
+
+    Args:
+        name: Unique name for this target
+        proto: proto_library target to generate code from
+        import_style: "commonjs" for *_grpc_web_pb.js clients with .d.ts
+                      declarations, or "typescript" for <File>ServiceClientPb.ts
+                      clients
+        mode: "grpcwebtext" (base64 payloads, supports server streaming) or
+              "grpcweb" (binary payloads, unary calls only)
+        npm_package: NPM package name (defaults to one derived from the proto path)
+        options: Additional KEY=VALUE protoc-gen-grpc-web options
+        visibility: Buck2 visibility specification
+        **kwargs: Additional arguments passed to the underlying rule
+
+    Example:
+        grpc_web_library(
+            name = "user_web",
+            proto = ":user_proto",
+            import_style = "typescript",
+            mode = "grpcwebtext",
+            npm_package = "@myorg/user-web",
+        )
+
+    Generated Files:
+        - <name>/package.json: NPM package stub
+        - <name>/src/**/*_pb.js and *_pb.d.ts: Messages and enums
+        - <name>/src/**/*_grpc_web_pb.js and *_grpc_web_pb.d.ts: Service
+          clients (import_style = "commonjs")
+        - <name>/src/**/<File>ServiceClientPb.ts: Service clients
+          (import_style = "typescript")
+    """
+    if (import_style, mode) not in GRPC_WEB_COMBINATIONS:
+        fail("grpc_web_library does not support import_style = '{}' with mode = '{}'; supported combinations: {}".format(
+            import_style,
+            mode,
+            ", ".join(["{}/{}".format(style, m) for style, m in GRPC_WEB_COMBINATIONS]),
+        ))
+    for option in options:
+        if "=" not in option or option.startswith("import_style=") or option.startswith("mode="):
+            fail("options must be KEY=VALUE plugin options other than import_style and mode, got '{}'".format(option))
+
+    grpc_web_library_rule(
+        name = name,
+        proto = proto,
+        import_style = import_style,
+        mode = mode,
+        npm_package = npm_package,
+        options = options,
+        visibility = visibility,
+        **kwargs
+    )
@@ -3,8 +3,129 @@
 load("@bazel_skylib//lib:unittest.bzl", "asserts", "unittest")
 load("//rules:go.bzl", "go_proto_library", "go_proto_messages", "go_grpc_library")
 load("//rules:proto.bzl", "proto_library")
+load("//rules/private:go_mappings.bzl", "go_output_path", "import_mapping_error", "output_extension_renames", "parse_import_mappings", "plugin_option_args")
 load("//rules/private:providers.bzl", "ProtoInfo", "LanguageProtoInfo")
 
+def go_proto_library_test(name, proto, expected_outputs = [], **kwargs):
+    """
+    Test framework for go_proto_library output validation.
+    
+    Creates a go_proto_library target and checks that its default outputs
+    include every expected path. Paths are relative to the Go output root,
+    e.g. "userv1connect/user.connect.go". Building the test runs every
+    protoc action of the library.
+    
+    Args:
+        name: Unique name for this test target
+        proto: proto_library target to generate Go code from
+        expected_outputs: Paths that must be among the generated outputs
+        **kwargs: Additional go_proto_library arguments
+    """
+    go_proto_library(
+        name = name + "_go",
+        proto = proto,
+        visibility = ["//test:__subpackages__"],
+        **kwargs
+    )
+    
+    _go_output_validation_test(
+        name = name,
+        go_target = ":" + name + "_go",
+        expected_outputs = expected_outputs,
+    )
+
+def _go_output_validation_test_impl(ctx):
+    """Implementation for go output validation test rule."""
+    outputs = ctx.attrs.go_target[DefaultInfo].default_outputs
+    actual_paths = [go_output_path(output) for output in outputs]
+    for expected in ctx.attrs.expected_outputs:
+        if expected not in actual_paths:
+            fail("Expected output '{}' not found in {}".format(expected, actual_paths))
+    
+    test_script = ctx.actions.write(
+        "test_script.sh",
+        [
+            "#!/bin/bash",
+            "echo 'Go proto library test passed: {}'".format(ctx.label),
+            "exit 0",
+        ],
+        is_executable = True,
+    )
+    
+    return [
+        DefaultInfo(default_output = test_script, other_outputs = outputs),
+        RunInfo(args = cmd_args(test_script, hidden = outputs)),
+    ]
+
+_go_output_validation_test = rule(
+    impl = _go_output_validation_test_impl,
+    attrs = {
+        "go_target": attrs.dep(providers = [LanguageProtoInfo]),
+        "expected_outputs": attrs.list(attrs.string(), default = []),
+    },
+)
+
+def go_import_graph_test(name, proto, expected_graph, **kwargs):
+    """
+    Test framework for the import graph of per-file Go protoc actions.
+    
+    Creates a go_proto_library target and compares the import graph its
+    per-file protoc actions depend on with expected_graph. A sibling file
+    missing from the graph is also missing from the inputs of the action
+    importing it, which only fails in sandboxed or remote builds.
+    
+    Args:
+        name: Unique name for this test target
+        proto: proto_library target with more than one proto file
+        expected_graph: Import path of each file to the import paths of the
+                        files of the target it imports
+        **kwargs: Additional go_proto_library arguments
+    """
+    go_proto_library(
+        name = name + "_go",
+        proto = proto,
+        visibility = ["//test:__subpackages__"],
+        **kwargs
+    )
+    
+    _go_import_graph_validation_test(
+        name = name,
+        import_graph = ":" + name + "_go[import_graph]",
+        expected_graph = expected_graph,
+    )
+
+def _go_import_graph_validation_test_impl(ctx):
+    """Implementation for go import graph validation test rule."""
+    import_graph = ctx.attrs.import_graph[DefaultInfo].default_outputs[0]
+    expected = ctx.actions.write_json("expected_import_graph.json", {"files": ctx.attrs.expected_graph})
+    
+    test_script = ctx.actions.write(
+        "test_script.sh",
+        [
+            "#!/bin/bash",
+            "set -euo pipefail",
+            "if ! diff <(python3 -m json.tool --sort-keys \"$2\") <(python3 -m json.tool --sort-keys \"$1\"); then",
+            "  echo 'Unexpected import graph of {}'".format(ctx.label),
+            "  exit 1",
+            "fi",
+            "echo 'Go import graph test passed: {}'".format(ctx.label),
+        ],
+        is_executable = True,
+    )
+    
+    return [
+        DefaultInfo(default_output = test_script, other_outputs = [import_graph, expected]),
+        RunInfo(args = cmd_args(test_script, import_graph, expected)),
+    ]
+
+_go_import_graph_validation_test = rule(
+    impl = _go_import_graph_validation_test_impl,
+    attrs = {
+        "import_graph": attrs.dep(),
+        "expected_graph": attrs.dict(attrs.string(), attrs.list(attrs.string())),
+    },
+)
+
 def _test_go_package_resolution_impl(ctx):
     """Test Go package path resolution logic."""
     env = unittest.begin(ctx)
@@ -143,6 +264,108 @@ def _test_error_handling_impl(ctx):
     
     return unittest.end(env)
 
+def _test_output_extension_renames_impl(ctx):
+    """Test output_extension_map renames keep subdirectories and reject collisions."""
+    env = unittest.begin(ctx)
+    
+    # The longest suffix wins and the <pkg>connect/ subdirectory is kept
+    renames, error = output_extension_renames(
+        ["user.pb.go", "user_grpc.pb.go", "userv1connect/user.connect.go", "go.mod"],
+        {".pb.go": ".pb.go.txt", "_grpc.pb.go": "_grpc.go.txt", ".go": ".go.txt"},
+    )
+    asserts.equals(env, "", error)
+    asserts.equals(env, {
+        "user.pb.go": "user.pb.go.txt",
+        "user_grpc.pb.go": "user_grpc.go.txt",
+        "userv1connect/user.connect.go": "userv1connect/user.connect.go.txt",
+    }, renames)
+    
+    # Same-named files in different directories do not collide
+    _, error = output_extension_renames(
+        ["user.connect.go", "userv1connect/user.connect.go"],
+        {".connect.go": ".connect.go.txt"},
+    )
+    asserts.equals(env, "", error)
+    
+    # Two renamed files collide
+    _, error = output_extension_renames(
+        ["user.pb.go", "user_grpc.pb.go"],
+        {".pb.go": ".txt", "_grpc.pb.go": ".txt"},
+    )
+    asserts.equals(env, "output_extension_map maps user.pb.go and user_grpc.pb.go to the same file user.txt", error)
+    
+    # A renamed file collides with a kept file
+    _, error = output_extension_renames(
+        ["user.pb.go", "user.go"],
+        {".pb.go": ".go"},
+    )
+    asserts.equals(env, "output_extension_map maps user.pb.go and user.go to the same file user.go", error)
+    
+    return unittest.end(env)
+
+def _test_import_mappings_impl(ctx):
+    """Test import_mappings parsing and its collision and dependency graph errors."""
+    env = unittest.begin(ctx)
+    
+    mappings, error = parse_import_mappings([
+        "Mgoogle/type/money.proto=github.com/us/gen/googleapis/money",
+        "Mvendor/acme/ledger.proto=github.com/us/gen/acme/ledger",
+    ])
+    asserts.equals(env, "", error)
+    asserts.equals(env, {
+        "google/type/money.proto": "github.com/us/gen/googleapis/money",
+        "vendor/acme/ledger.proto": "github.com/us/gen/acme/ledger",
+    }, mappings)
+    
+    # The same file mapped twice
+    _, error = parse_import_mappings([
+        "Mvendor/acme/ledger.proto=github.com/us/gen/acme/ledger",
+        "Mvendor/acme/ledger.proto=github.com/us/gen/ledger",
+    ])
+    asserts.equals(env, "import_mappings maps vendor/acme/ledger.proto to both github.com/us/gen/acme/ledger and github.com/us/gen/ledger", error)
+    
+    # Malformed entries
+    _, error = parse_import_mappings(["vendor/acme/ledger.proto=github.com/us/gen/acme/ledger"])
+    asserts.equals(env, "import_mappings entries must be M<file>.proto=<Go import path>, got 'vendor/acme/ledger.proto=github.com/us/gen/acme/ledger'", error)
+    _, error = parse_import_mappings(["M/vendor/acme/ledger.proto=github.com/us/gen/acme/ledger"])
+    asserts.equals(env, "import_mappings entry 'M/vendor/acme/ledger.proto=github.com/us/gen/acme/ledger' must map an import path such as foo/bar.proto to a Go import path", error)
+    
+    own_files = {"billing/v1/billing.proto": True}
+    graph = {"billing/v1/billing.proto": True, "vendor/acme/ledger.proto": True}
+    asserts.equals(env, "", import_mapping_error("vendor/acme/ledger.proto", own_files, graph, "//billing:billing_proto"))
+    
+    # A file of the target conflicts with its go_package
+    asserts.equals(
+        env,
+        "import_mappings maps billing/v1/billing.proto, a file of //billing:billing_proto; set go_package instead",
+        import_mapping_error("billing/v1/billing.proto", own_files, graph, "//billing:billing_proto"),
+    )
+    
+    # A file outside the dependency graph
+    asserts.equals(
+        env,
+        "import_mappings maps google/type/money.proto, which is not in the dependency graph of //billing:billing_proto",
+        import_mapping_error("google/type/money.proto", own_files, graph, "//billing:billing_proto"),
+    )
+    
+    return unittest.end(env)
+
+def _test_plugin_option_args_impl(ctx):
+    """Test that prefixed options reach the matching plugin only."""
+    env = unittest.begin(ctx)
+    
+    options = {
+        "go_module": "github.com/org/greeter",
+        "go_grpc_require_unimplemented_servers": "false",
+        "connect_go_simple": "true",
+        "grpc_gateway_logtostderr": "true",
+    }
+    asserts.equals(env, ["--go_opt=module=github.com/org/greeter"], plugin_option_args("go", options))
+    asserts.equals(env, ["--go-grpc_opt=require_unimplemented_servers=false"], plugin_option_args("go-grpc", options))
+    asserts.equals(env, ["--connect-go_opt=simple=true"], plugin_option_args("connect-go", options))
+    
+    return unittest.end(env)
+
 def _test_performance_requirements_impl(ctx):
     """Test performance requirements compliance."""
     env = unittest.begin(ctx)
@@ -166,6 +389,9 @@ _test_go_proto_messages_wrapper = unittest.make(_test_go_proto_messages_wrapper_
 _test_go_grpc_library_wrapper = unittest.make(_test_go_grpc_library_wrapper_impl)
 _test_tool_integration = unittest.make(_test_tool_integration_impl)
 _test_error_handling = unittest.make(_test_error_handling_impl)
+_test_output_extension_renames = unittest.make(_test_output_extension_renames_impl)
+_test_import_mappings = unittest.make(_test_import_mappings_impl)
+_test_plugin_option_args = unittest.make(_test_plugin_option_args_impl)
 _test_performance_requirements = unittest.make(_test_performance_requirements_impl)
 
 def go_proto_test_suite(name):
@@ -181,5 +407,8 @@ def go_proto_test_suite(name):
         _test_go_grpc_library_wrapper,
         _test_tool_integration,
         _test_error_handling,
+        _test_output_extension_renames,
+        _test_import_mappings,
+        _test_plugin_option_args,
         _test_performance_requirements,
     )
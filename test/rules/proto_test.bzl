@@ -5,8 +5,9 @@ Buck2 protobuf rules. It includes helpers for testing rule compilation,
 output validation, and error handling.
 """
 
-load("//rules:proto.bzl", "proto_library")
-load("//rules/private:providers.bzl", "ProtoInfo")
+load("//rules:proto.bzl", "proto_descriptor_set", "proto_library")
+load("//rules:tools.bzl", "TOOL_ATTRS", "get_protoc_binary")
+load("//rules/private:providers.bzl", "ProtoDescriptorSetInfo", "ProtoInfo")
 
 def proto_library_test(
     name,
@@ -310,6 +311,109 @@ _proto_failure_validation_test = rule(
     },
 )
 
+def proto_descriptor_set_test(
+    name,
+    proto,
+    expected_files,
+    include_imports = True,
+    include_source_info = True,
+    **kwargs
+):
+    """
+    Test framework for proto_descriptor_set rule validation.
+    
+    Builds a proto_descriptor_set of proto and checks, by decoding it with
+    protoc, that it contains exactly expected_files and carries source
+    info only when include_source_info is set.
+    
+    Args:
+        name: Unique name for this test target
+        proto: proto_library target to compile
+        expected_files: Import paths of the files the set must contain
+        include_imports: Passed to proto_descriptor_set
+        include_source_info: Passed to proto_descriptor_set
+        **kwargs: Additional arguments
+    
+    Example:
+        proto_descriptor_set_test(
+            name = "descriptor_set_imports_test",
+            proto = "//test/fixtures/dependencies:derived_proto",
+            expected_files = [
+                "test/fixtures/dependencies/base.proto",
+                "test/fixtures/dependencies/derived.proto",
+            ],
+        )
+    """
+    proto_descriptor_set(
+        name = name + "_descriptor_set",
+        proto = proto,
+        include_imports = include_imports,
+        include_source_info = include_source_info,
+        visibility = ["//test:__subpackages__"],
+    )
+    
+    _proto_descriptor_set_validation_test(
+        name = name,
+        descriptor_set_target = ":" + name + "_descriptor_set",
+        expected_files = expected_files,
+        include_imports = include_imports,
+        include_source_info = include_source_info,
+        **kwargs
+    )
+
+def _proto_descriptor_set_validation_test_impl(ctx):
+    """Implementation for proto_descriptor_set validation test rule."""
+    info = ctx.attrs.descriptor_set_target[ProtoDescriptorSetInfo]
+    
+    # The provider must describe the set that was requested
+    if info.include_imports != ctx.attrs.include_imports:
+        fail("ProtoDescriptorSetInfo.include_imports = {}, expected {}".format(info.include_imports, ctx.attrs.include_imports))
+    if info.include_source_info != ctx.attrs.include_source_info:
+        fail("ProtoDescriptorSetInfo.include_source_info = {}, expected {}".format(info.include_source_info, ctx.attrs.include_source_info))
+    if info.descriptor_set.basename != "{}.binpb".format(ctx.attrs.descriptor_set_target.label.name):
+        fail("Expected descriptor set {}.binpb, got {}".format(ctx.attrs.descriptor_set_target.label.name, info.descriptor_set.basename))
+    
+    # protoc --decode_raw prints each FileDescriptorProto as "1 {", its name
+    # as field 1 and its SourceCodeInfo as field 9
+    expected_files = "\n".join(sorted(ctx.attrs.expected_files))
+    test_script = ctx.actions.write(
+        "test_script.sh",
+        [
+            "#!/bin/bash",
+            "set -euo pipefail",
+            "decoded=$(\"$1\" --decode_raw < \"$2\")",
+            "files=$(echo \"$decoded\" | sed -n 's/^  1: \"\\(.*\\)\"$/\\1/p' | sort)",
+            "expected=$(printf '%s' '{}')".format(expected_files),
+            "if [ \"$files\" != \"$expected\" ]; then",
+            "  echo \"Descriptor set contains:\"; echo \"$files\"; echo \"expected:\"; echo \"$expected\"",
+            "  exit 1",
+            "fi",
+            "if echo \"$decoded\" | grep -q '^  9 {'; then has_source_info=true; else has_source_info=false; fi",
+            "if [ \"$has_source_info\" != \"{}\" ]; then".format("true" if ctx.attrs.include_source_info else "false"),
+            "  echo \"Source info present: $has_source_info, expected {}\"".format("true" if ctx.attrs.include_source_info else "false"),
+            "  exit 1",
+            "fi",
+            "echo 'Proto descriptor set test passed: {}'".format(ctx.label),
+        ],
+        is_executable = True,
+    )
+    
+    protoc = get_protoc_binary(ctx)
+    return [
+        DefaultInfo(default_output = test_script, other_outputs = [info.descriptor_set]),
+        RunInfo(args = cmd_args(test_script, protoc, info.descriptor_set)),
+    ]
+
+_proto_descriptor_set_validation_test = rule(
+    impl = _proto_descriptor_set_validation_test_impl,
+    attrs = dict(TOOL_ATTRS, **{
+        "descriptor_set_target": attrs.dep(providers = [ProtoDescriptorSetInfo]),
+        "expected_files": attrs.list(attrs.string()),
+        "include_imports": attrs.bool(),
+        "include_source_info": attrs.bool(),
+    }),
+)
+
 # Performance testing utilities
 def proto_performance_test(
     name,
@@ -153,7 +153,7 @@ def test_python_dependencies():
     assert_file_exists("test_derived_proto", "derived_pb2.py")
 
 def test_python_init_py_content():
-    """Test __init__.py content generation."""
+    """Test __init__.py files of the generated package tree."""
     python_proto_library(
         name = "test_init_content",
         proto = "//test/fixtures:complex_proto",
@@ -161,16 +161,55 @@ def test_python_init_py_content():
         plugins = ["python", "grpc-python"],
     )
     
-    # Read and validate __init__.py content
-    init_content = read_file("test_init_content/__init__.py")
+    # Every package directory is importable and merges with other generated trees
+    for package_dir in ["test", "test/init", "test/init/v1"]:
+        init_content = read_file("test_init_content/python/{}/__init__.py".format(package_dir))
+        assert_contains(init_content, '"""Generated Python protobuf package."""')
+        assert_contains(init_content, '__path__ = __import__("pkgutil").extend_path(__path__, __name__)')
     
-    assert_contains(init_content, '__package__ = "test.init.v1"')
-    assert_contains(init_content, '__version__ = "1.0.0"')
-    assert_contains(init_content, '__all__ = [')
-    assert_contains(init_content, '"complex_pb2"')
-    assert_contains(init_content, '"complex_pb2_grpc"')
-    assert_contains(init_content, 'from . import complex_pb2')
-    assert_contains(init_content, 'from . import complex_pb2_grpc')
+    assert_file_exists("test_init_content", "python/test/init/v1/complex_pb2.py")
+    assert_file_exists("test_init_content", "python/test/init/v1/complex_pb2_grpc.py")
+
+def test_python_nested_packages():
+    """Test that dotted proto packages are laid out as nested directories."""
+    python_proto_library(
+        name = "test_nested_packages",
+        proto = "//test/fixtures/basic:minimal_proto",
+    )
+    
+    assert_file_exists("test_nested_packages", "python/test/fixtures/basic/__init__.py")
+    assert_file_exists("test_nested_packages", "python/test/fixtures/basic/minimal_pb2.py")
+    assert_file_exists("test_nested_packages", "python/test/fixtures/basic/minimal_pb2.pyi")
+
+def test_python_mypy_stubs():
+    """Test type stubs generated by protoc-gen-mypy and protoc-gen-mypy_grpc."""
+    python_proto_library(
+        name = "test_mypy_stubs",
+        proto = "//test/fixtures:service_proto",
+        python_package = "test.mypy.v1",
+        plugins = ["python", "grpc", "mypy"],
+    )
+    
+    assert_file_exists("test_mypy_stubs", "python/test/mypy/v1/service_pb2.pyi")
+    assert_file_exists("test_mypy_stubs", "python/test/mypy/v1/service_pb2_grpc.pyi")
+
+def test_python_dependency_imports():
+    """Test that imports of dep modules resolve to the dep's Python package."""
+    python_proto_library(
+        name = "test_imported_base",
+        proto = "//test/fixtures/dependencies:base_proto",
+        python_package = "test.imports.base",
+    )
+    
+    python_proto_library(
+        name = "test_importing_derived",
+        proto = "//test/fixtures/dependencies:derived_proto",
+        deps = [":test_imported_base"],
+        python_package = "test.imports.derived",
+    )
+    
+    derived_content = read_file("test_importing_derived/python/test/imports/derived/derived_pb2.py")
+    assert_contains(derived_content, "from test.imports.base import base_pb2")
 
 def test_python_py_typed_marker():
     """Test py.typed marker file generation.""" 
@@ -241,6 +280,9 @@ python_proto_test_suite = test_suite(
         test_case("python_plugin_configuration", test_python_plugin_configuration),
         test_case("python_dependencies", test_python_dependencies),
         test_case("python_init_py_content", test_python_init_py_content),
+        test_case("python_nested_packages", test_python_nested_packages),
+        test_case("python_mypy_stubs", test_python_mypy_stubs),
+        test_case("python_dependency_imports", test_python_dependency_imports),
         test_case("python_py_typed_marker", test_python_py_typed_marker),
         test_case("python_custom_options", test_python_custom_options),
         test_case("python_error_handling", test_python_error_handling),
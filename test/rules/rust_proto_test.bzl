@@ -27,7 +27,8 @@ def test_basic_rust_proto_generation():
     expected_files = [
         "rust/Cargo.toml",
         "rust/src/lib.rs",
-        "rust/src/simple.rs",
+        "rust/src/mod.rs",
+        "rust/src/test.simple.rs",
         "rust/build.rs",
     ]
     
@@ -52,7 +53,9 @@ def test_rust_grpc_generation():
     expected_files = [
         "rust/Cargo.toml",
         "rust/src/lib.rs", 
-        "rust/src/service.rs",
+        "rust/src/mod.rs",
+        "rust/src/test.service.rs",
+        "rust/src/test.service.tonic.rs",
         "rust/build.rs",
     ]
     
@@ -191,7 +194,7 @@ def test_rust_edition_support():
     )
 
 def test_rust_lib_rs_generation():
-    """Test that lib.rs is properly generated with module exports."""
+    """Test that lib.rs includes mod.rs, which follows the package hierarchy."""
     proto_library(
         name = "test_lib_proto",
         srcs = [
@@ -207,16 +210,23 @@ def test_rust_lib_rs_generation():
         use_grpc = True,
     )
     
-    # Verify lib.rs contains proper module declarations
     assert_content_contains(
         "test_lib_rust",
         "rust/src/lib.rs",
+        ['include!("mod.rs");'],
+    )
+    
+    # Verify mod.rs nests one module per package segment
+    assert_content_contains(
+        "test_lib_rust",
+        "rust/src/mod.rs",
         [
-            "pub mod minimal;",
-            "pub mod types;", 
-            "pub use minimal::*;",
-            "pub use types::*;",
-            "pub use tonic;",  # Should include tonic re-export
+            "pub mod test {",
+            "    pub mod basic {",
+            "        pub mod minimal {",
+            '            include!("test.basic.minimal.rs");',
+            "        pub mod types {",
+            '            include!("test.basic.types.rs");',
         ]
     )
 
@@ -0,0 +1,326 @@
+#!/usr/bin/env python3
+"""
+Resolution of a Buf Schema Registry module pinned by a buf.lock file.
+
+The bsr_module rule runs this tool to turn a module reference such as
+`buf.build/acme/payments` into the module's .proto files:
+
+1. The commit and digest of the module are read from the committed buf.lock
+   (v1 or v2). A commit requested by the rule must match the lockfile pin.
+2. Each pinned commit is exported with `buf export --exclude-imports` into a
+   cache directory, once; later builds reuse the cached copy.
+3. The digest of the exported files is computed and compared with the pin on
+   every run, so a tampered cache or a moved commit fails the build.
+4. Imports of files outside the module are resolved against the other
+   modules of the lockfile, recursively, until every import is satisfied.
+
+The files of the module and of every module it needs are written to one
+output directory, together with a descriptor set compiled from them and a
+manifest of the resolved modules:
+
+    {"modules": [{"name": "buf.build/acme/payments", "commit": "...",
+                  "digest": "b5:...", "deps": ["buf.build/acme/money"],
+                  "files": ["acme/payments/v1/payment.proto"]}]}
+
+Digests are computed like buf computes them: a `shake256` manifest digest
+(buf.lock v1) hashes the sorted lines `shake256:<file hex>  <path>`; a `b5`
+digest (buf.lock v2) hashes the sorted digest strings of that manifest and
+of the module's dependencies, joined by newlines.
+
+Usage:
+    bsr_module.py --module buf.build/acme/payments --lockfile buf.lock \\
+        --output-dir out --descriptor-set out.binpb --manifest out.json \\
+        --buf buf --protoc protoc
+"""
+
+import argparse
+import hashlib
+import json
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Callable, Dict, List, Optional
+
+import yaml
+
+try:
+    from offline_tools import OfflineToolError, offline_cache_dir
+    from proto_schema import ProtoParseError, parse_proto_file
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from offline_tools import OfflineToolError, offline_cache_dir
+    from proto_schema import ProtoParseError, parse_proto_file
+
+DEFAULT_CACHE_DIR = Path.home() / ".cache" / "buck2-protobuf" / "bsr" / "modules"
+
+# Shipped with protoc, never provided by a module
+WELL_KNOWN_PREFIX = "google/protobuf/"
+
+# Marks a completely exported commit in the cache
+COMPLETE_MARKER = ".complete"
+
+
+class BSRModuleError(RuntimeError):
+    """Raised when a module cannot be resolved, fetched or verified."""
+
+
+@dataclass
+class LockedModule:
+    """A module pinned by the lockfile."""
+    name: str
+    commit: str
+    digest: str
+
+
+@dataclass
+class ResolvedModule:
+    """A fetched and verified module."""
+    name: str
+    commit: str
+    digest: str
+    directory: Path
+    files: List[str]
+    deps: List[str] = field(default_factory=list)
+
+
+def load_lockfile(path: str) -> Dict[str, LockedModule]:
+    """
+    Loads the pinned modules of a buf.lock file (v1 or v2).
+
+    Returns:
+        Module name (e.g. buf.build/acme/payments) to its pin
+    """
+    with open(path, "r", encoding="utf-8") as f:
+        lock = yaml.safe_load(f) or {}
+    version = lock.get("version", "v1")
+    if version not in ("v1", "v2"):
+        raise BSRModuleError(f"{path}: unsupported buf.lock version '{version}'")
+
+    modules = {}
+    for entry in lock.get("deps") or []:
+        if version == "v2":
+            name = entry.get("name", "")
+        else:
+            name = "/".join(entry.get(key, "") for key in ("remote", "owner", "repository"))
+        if name.count("/") != 2 or not entry.get("commit") or not entry.get("digest"):
+            raise BSRModuleError(f"{path}: invalid dependency entry {entry}")
+        modules[name] = LockedModule(name, entry["commit"], entry["digest"])
+    return modules
+
+
+def _shake256(content: bytes) -> str:
+    return hashlib.shake_256(content).hexdigest(64)
+
+
+def files_digest(directory: Path, files: List[str]) -> str:
+    """Returns the shake256 manifest digest of a module's files."""
+    lines = []
+    for path in sorted(files):
+        lines.append(f"shake256:{_shake256((directory / path).read_bytes())}  {path}\n")
+    return "shake256:" + _shake256("".join(lines).encode("utf-8"))
+
+
+def module_digest(digest_type: str, directory: Path, files: List[str], dep_digests: List[str]) -> str:
+    """Computes a module digest of the given type (shake256 or b5)."""
+    manifest = files_digest(directory, files)
+    if digest_type == "shake256":
+        return manifest
+    if digest_type == "b5":
+        return "b5:" + _shake256("\n".join(sorted([manifest] + dep_digests)).encode("utf-8"))
+    raise BSRModuleError(f"unsupported digest type '{digest_type}'")
+
+
+def list_proto_files(directory: Path) -> List[str]:
+    """Lists the .proto files of an exported module, relative to its root."""
+    return sorted(p.relative_to(directory).as_posix() for p in directory.rglob("*.proto"))
+
+
+class BufExporter:
+    """Fetches pinned commits with `buf export` into a cache directory."""
+
+    def __init__(self, buf: str, cache_dir: Path, offline: bool = False):
+        self.buf = buf
+        self.cache_dir = cache_dir
+        self.offline = offline
+
+    def __call__(self, module: LockedModule) -> Path:
+        target = self.cache_dir / module.name / module.commit
+        if (target / COMPLETE_MARKER).is_file():
+            return target
+        if self.offline:
+            raise BSRModuleError(
+                f"offline mode: {module.name} at commit {module.commit} is not in the module cache "
+                f"{self.cache_dir}; expected {target}, no download was attempted"
+            )
+
+        target.parent.mkdir(parents=True, exist_ok=True)
+        staging = Path(tempfile.mkdtemp(dir=target.parent, prefix=f".{module.commit}-"))
+        try:
+            result = subprocess.run(
+                [self.buf, "export", f"{module.name}:{module.commit}", "--exclude-imports", "--output", str(staging)],
+                capture_output=True, text=True, timeout=300,
+            )
+            if result.returncode != 0:
+                raise BSRModuleError(f"buf export {module.name}:{module.commit} failed: {result.stderr.strip()}")
+            (staging / COMPLETE_MARKER).write_text(module.digest + "\n")
+            if target.exists():
+                shutil.rmtree(target)
+            staging.rename(target)
+        finally:
+            if staging.exists():
+                shutil.rmtree(staging)
+        return target
+
+
+class ModuleResolver:
+    """Resolves a module and, recursively, the lockfile modules its imports need."""
+
+    def __init__(self, lock: Dict[str, LockedModule], fetch: Callable[[LockedModule], Path]):
+        self.lock = lock
+        self.fetch = fetch
+        self._fetched: Dict[str, tuple] = {}
+
+    def _files(self, name: str) -> tuple:
+        if name not in self._fetched:
+            directory = self.fetch(self.lock[name])
+            self._fetched[name] = (directory, list_proto_files(directory))
+        return self._fetched[name]
+
+    def _provider(self, importer: str, import_path: str) -> str:
+        """Returns the lockfile module providing a file, fetching modules as needed."""
+        # Modules whose name appears in the import path are the likely providers; try them first
+        candidates = sorted(self.lock, key=lambda name: name.split("/")[-1] not in import_path)
+        for name in candidates:
+            if name == importer:
+                continue
+            if import_path in self._files(name)[1]:
+                return name
+        raise BSRModuleError(
+            f"{importer} imports \"{import_path}\", which no module pinned in buf.lock provides; "
+            f"run `buf dep update` to pin its dependencies"
+        )
+
+    def resolve(self, name: str, commit: Optional[str] = None) -> List[ResolvedModule]:
+        """
+        Resolves a module and its transitive dependencies.
+
+        Args:
+            name: Module name, e.g. buf.build/acme/payments
+            commit: Commit requested by the rule; must match the lockfile pin
+
+        Returns:
+            The module followed by its dependencies, each fetched and verified
+        """
+        if name not in self.lock:
+            raise BSRModuleError(f"{name} is not pinned in buf.lock; add it to buf.yaml and run `buf dep update`")
+        if commit and commit != self.lock[name].commit:
+            raise BSRModuleError(
+                f"{name}: requested commit {commit} does not match the buf.lock pin {self.lock[name].commit}; "
+                f"run `buf dep update` or change the commit"
+            )
+
+        deps: Dict[str, List[str]] = {}
+        order = []
+        pending = [name]
+        while pending:
+            current = pending.pop(0)
+            if current in deps:
+                continue
+            order.append(current)
+            directory, files = self._files(current)
+            own = set(files)
+            needed = set()
+            for path in files:
+                for import_path in parse_proto_file(str(directory / path)).imports:
+                    if import_path in own or import_path.startswith(WELL_KNOWN_PREFIX):
+                        continue
+                    needed.add(self._provider(current, import_path))
+            deps[current] = sorted(needed)
+            pending.extend(deps[current])
+
+        return [self._verify(current, deps[current]) for current in order]
+
+    def _verify(self, name: str, deps: List[str]) -> ResolvedModule:
+        pin = self.lock[name]
+        directory, files = self._files(name)
+        digest_type = pin.digest.split(":", 1)[0]
+        actual = module_digest(digest_type, directory, files, [self.lock[dep].digest for dep in deps])
+        if actual != pin.digest:
+            raise BSRModuleError(
+                f"{name} at commit {pin.commit} has digest {actual}, but buf.lock pins {pin.digest}; "
+                f"the cached copy in {directory} or the commit was modified"
+            )
+        return ResolvedModule(name, pin.commit, pin.digest, directory, files, deps)
+
+
+def write_outputs(modules: List[ResolvedModule], output_dir: Path, manifest: Optional[Path]) -> List[str]:
+    """Copies the files of every module into one include root and writes the manifest."""
+    owners: Dict[str, str] = {}
+    for module in modules:
+        for path in module.files:
+            if path in owners:
+                raise BSRModuleError(f"{path} is provided by both {owners[path]} and {module.name}")
+            owners[path] = module.name
+            destination = output_dir / path
+            destination.parent.mkdir(parents=True, exist_ok=True)
+            shutil.copyfile(module.directory / path, destination)
+
+    if manifest:
+        entries = [{"name": m.name, "commit": m.commit, "digest": m.digest, "deps": m.deps, "files": m.files}
+                   for m in modules]
+        manifest.write_text(json.dumps({"modules": entries}, indent=2) + "\n", encoding="utf-8")
+    return sorted(owners)
+
+
+def compile_descriptor_set(protoc: str, include_root: Path, files: List[str], output: Path) -> None:
+    """Compiles the resolved files to a FileDescriptorSet with their imports."""
+    result = subprocess.run(
+        [protoc, f"--proto_path={include_root}", f"--descriptor_set_out={output}", "--include_imports"] + files,
+        capture_output=True, text=True,
+    )
+    if result.returncode != 0:
+        raise BSRModuleError(f"protoc failed to compile the module: {result.stderr.strip()}")
+
+
+def main():
+    """Main entry point for BSR module resolution."""
+    parser = argparse.ArgumentParser(description="Resolve a BSR module pinned by buf.lock")
+    parser.add_argument("--module", required=True, help="Module name, e.g. buf.build/acme/payments")
+    parser.add_argument("--commit", help="Commit to resolve; must match the lockfile pin")
+    parser.add_argument("--lockfile", required=True, help="buf.lock file pinning the module and its deps")
+    parser.add_argument("--output-dir", required=True, help="Directory to write the .proto files to")
+    parser.add_argument("--descriptor-set", help="FileDescriptorSet to compile the files to")
+    parser.add_argument("--manifest", help="JSON manifest of the resolved modules")
+    parser.add_argument("--buf", default="buf", help="buf executable")
+    parser.add_argument("--protoc", default="protoc", help="protoc executable")
+    parser.add_argument("--cache-dir", help="Module cache directory")
+    parser.add_argument("--offline", action="store_true", help="Only use modules already in the cache")
+    parser.add_argument("--offline-cache-dir", help="Pre-populated tool cache holding a bsr/ module cache")
+    args = parser.parse_args()
+
+    try:
+        if args.offline:
+            cache_dir = offline_cache_dir(args.offline_cache_dir) / "bsr"
+        else:
+            cache_dir = Path(args.cache_dir or os.environ.get("BUCK2_PROTOBUF_BSR_CACHE") or DEFAULT_CACHE_DIR)
+        resolver = ModuleResolver(load_lockfile(args.lockfile), BufExporter(args.buf, cache_dir, args.offline))
+        modules = resolver.resolve(args.module, args.commit)
+        output_dir = Path(args.output_dir)
+        output_dir.mkdir(parents=True, exist_ok=True)
+        files = write_outputs(modules, output_dir, Path(args.manifest) if args.manifest else None)
+        if args.descriptor_set:
+            compile_descriptor_set(args.protoc, output_dir, files, Path(args.descriptor_set))
+    except (BSRModuleError, OfflineToolError, ProtoParseError, OSError, yaml.YAMLError) as e:
+        print(f"ERROR: bsr_module: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    for module in modules:
+        print(f"{module.name} {module.commit} ({len(module.files)} files, {module.digest})", file=sys.stderr)
+
+
+if __name__ == "__main__":
+    main()
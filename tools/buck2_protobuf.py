@@ -0,0 +1,126 @@
+#!/usr/bin/env python3
+"""
+Command line interface for buck2-protobuf maintenance tasks.
+
+Commands:
+    check-resolution    Verify that every referenced tool resolves without downloading
+    schema-diff         Summarize schema changes against a base revision as markdown
+
+Usage:
+    buck2-protobuf check-resolution [PATH ...] [--lockfile FILE] [--platform P] [--no-network]
+    buck2-protobuf schema-diff --base BASE_DIR [--output FILE] HEAD_DIR
+"""
+
+import argparse
+import json
+import sys
+from pathlib import Path
+from typing import List
+
+try:
+    from download_protoc import PlatformDetector
+    from proto_schema import ProtoParseError
+    from schema_diff import render_markdown, run_diff
+    from tool_resolution import (
+        ToolDatabase, check_resolution, collect_references, format_problems, problems_to_json,
+    )
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from download_protoc import PlatformDetector
+    from proto_schema import ProtoParseError
+    from schema_diff import render_markdown, run_diff
+    from tool_resolution import (
+        ToolDatabase, check_resolution, collect_references, format_problems, problems_to_json,
+    )
+
+
+def _current_platform() -> str:
+    os_name, arch = PlatformDetector.detect()
+    return f"{os_name}-{arch}"
+
+
+def cmd_check_resolution(args: argparse.Namespace) -> int:
+    """Runs the check-resolution command and returns the exit code."""
+    database = ToolDatabase.load()
+    roots = [Path(p) for p in args.paths] or [Path.cwd()]
+    lockfile = Path(args.lockfile) if args.lockfile else None
+    references = collect_references(roots, database, lockfile)
+    platforms = args.platform or [_current_platform()]
+
+    problems = check_resolution(references, database, platforms, network=not args.no_network)
+
+    if args.json:
+        print(json.dumps(problems_to_json(problems, references), indent=2))
+    else:
+        for reference in references:
+            print(f"checked {reference.tool} {reference.version or '<none>'} ({len(reference.sources)} references)")
+        if problems:
+            print(f"\n{len(problems)} unresolvable tool(s):", file=sys.stderr)
+            print(format_problems(problems), file=sys.stderr)
+        else:
+            print(f"\nAll {len(references)} tool(s) resolve on {', '.join(platforms)}")
+
+    return 1 if problems else 0
+
+
+def _proto_files(paths: List[str]) -> List[str]:
+    """Expands directories to the .proto files they contain."""
+    files = []
+    for path in map(Path, paths):
+        if path.is_dir():
+            files.extend(str(p) for p in sorted(path.rglob("*.proto")))
+        else:
+            files.append(str(path))
+    return files
+
+
+def cmd_schema_diff(args: argparse.Namespace) -> int:
+    """Runs the schema-diff command; exits 1 when breaking changes are found."""
+    try:
+        changes = run_diff(_proto_files(args.head), _proto_files(args.base), _proto_files(args.dep))
+    except (ProtoParseError, OSError) as e:
+        print(f"ERROR: {e}", file=sys.stderr)
+        return 2
+
+    markdown = render_markdown(changes, args.title)
+    if args.output:
+        Path(args.output).write_text(markdown, encoding="utf-8")
+    else:
+        sys.stdout.write(markdown)
+    return 1 if any(c.breaking for c in changes) else 0
+
+
+def main() -> int:
+    """Main entry point for the buck2-protobuf CLI."""
+    parser = argparse.ArgumentParser(prog="buck2-protobuf", description="buck2-protobuf maintenance commands")
+    subparsers = parser.add_subparsers(dest="command", required=True)
+
+    check = subparsers.add_parser(
+        "check-resolution",
+        help="Verify tool resolution (version, checksum, URL) without downloading",
+    )
+    check.add_argument("paths", nargs="*", help="Directories or BUCK files to scan (default: current directory)")
+    check.add_argument("--lockfile", help="JSON lockfile with pinned tool versions")
+    check.add_argument("--platform", action="append", default=[],
+                       help="Platform to check, e.g. linux-x86_64 (repeatable; default: current platform)")
+    check.add_argument("--no-network", action="store_true", help="Skip HEAD requests to download URLs")
+    check.add_argument("--json", action="store_true", help="Print a JSON report")
+    check.set_defaults(func=cmd_check_resolution)
+
+    diff = subparsers.add_parser(
+        "schema-diff",
+        help="Summarize schema changes against a base revision as markdown (exit 1 on breaking changes)",
+    )
+    diff.add_argument("head", nargs="+", help="Current .proto files or directories")
+    diff.add_argument("--base", action="append", required=True, help="Base revision .proto files or directories")
+    diff.add_argument("--dep", action="append", default=[], help="Dependency .proto files or directories used for resolution")
+    diff.add_argument("--output", help="Markdown output file (default: stdout)")
+    diff.add_argument("--title", default="Schema changes", help="Heading of the summary")
+    diff.set_defaults(func=cmd_schema_diff)
+
+    args = parser.parse_args()
+    return args.func(args)
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,134 @@
+#!/usr/bin/env python3
+"""
+Build-time buf format check of proto sources.
+
+Runs `buf format` on each source file and compares the result with the file
+on disk. Files that are not canonically formatted are reported as a unified
+diff (a/<path> -> b/<path>, sorted by path, without timestamps), so the
+report can be attached to a PR comment as is. With --write the formatted
+content is written back to the files instead, for fixing them locally.
+
+Generated files (a "Code generated ... DO NOT EDIT." comment before the
+first statement) and files matching an --exclude pattern are skipped.
+Patterns are globs or directories, matched against the path or any of its
+trailing segments, so "acme/legacy" skips every file under that directory.
+
+Usage:
+    buf_format_check.py --buf bin/buf --exclude acme/legacy \\
+        --output format.diff --exit-code acme/user.proto acme/order.proto
+    buf_format_check.py --buf bin/buf --write acme/user.proto
+"""
+
+import argparse
+import difflib
+import fnmatch
+import re
+import subprocess
+import sys
+from pathlib import Path
+from typing import List, Tuple
+
+# Go's convention for generated files, also used by protoc plugins emitting protos
+GENERATED_PATTERN = re.compile(r"^// Code generated .* DO NOT EDIT\.$")
+
+
+class FormatCheckError(Exception):
+    """Raised when the format check cannot run."""
+
+
+def is_generated(content: str) -> bool:
+    """Returns whether a generated-file comment precedes the first statement."""
+    for line in content.splitlines():
+        stripped = line.strip()
+        if GENERATED_PATTERN.match(stripped):
+            return True
+        if stripped and not stripped.startswith("//"):
+            return False
+    return False
+
+
+def is_excluded(path: str, patterns: List[str]) -> bool:
+    """Returns whether a path matches an exclude glob or lies under an excluded directory."""
+    parts = path.split("/")
+    for pattern in patterns:
+        pattern = pattern.rstrip("/")
+        for i in range(len(parts)):
+            suffix = "/".join(parts[i:])
+            if fnmatch.fnmatchcase(suffix, pattern) or suffix.startswith(pattern + "/"):
+                return True
+    return False
+
+
+def format_file(buf: str, path: str) -> str:
+    """Returns the canonically formatted content of a proto file."""
+    result = subprocess.run([buf, "format", path], capture_output=True, text=True)
+    if result.returncode != 0:
+        raise FormatCheckError(f"buf format {path} failed with exit code {result.returncode}:\n"
+                               f"{result.stderr.strip()}")
+    return result.stdout
+
+
+def file_diff(path: str, original: str, formatted: str) -> str:
+    """Renders the changes formatting makes to a file as a unified diff."""
+    lines = difflib.unified_diff(
+        original.splitlines(keepends=True),
+        formatted.splitlines(keepends=True),
+        fromfile=f"a/{path}",
+        tofile=f"b/{path}",
+    )
+    # Keep the diff applicable when a side lacks a trailing newline
+    return "".join(line if line.endswith("\n") else line + "\n\\ No newline at end of file\n" for line in lines)
+
+
+def check_files(buf: str, files: List[str], exclude: List[str]) -> List[Tuple[str, str, str]]:
+    """Returns (path, original, formatted) for every checked file that is not canonically formatted."""
+    unformatted = []
+    for path in sorted(set(files)):
+        if is_excluded(path, exclude):
+            continue
+        original = Path(path).read_text(encoding="utf-8")
+        if is_generated(original):
+            continue
+        formatted = format_file(buf, path)
+        if formatted != original:
+            unformatted.append((path, original, formatted))
+    return unformatted
+
+
+def main():
+    """Main entry point for the buf format check."""
+    parser = argparse.ArgumentParser(description="Check that proto files are formatted with buf format")
+    parser.add_argument("--buf", required=True, help="buf binary")
+    parser.add_argument("--exclude", action="append", default=[], help="Glob or directory to skip (repeatable)")
+    parser.add_argument("--output", help="Unified diff report to write")
+    parser.add_argument("--exit-code", action="store_true", help="Exit with status 1 when files are not formatted")
+    parser.add_argument("--fix-hint", default="", help="Command printed on failure to fix the files")
+    parser.add_argument("--write", action="store_true", help="Rewrite the files in place instead of reporting")
+    parser.add_argument("files", nargs="+", help="Proto files to check")
+    args = parser.parse_args()
+
+    try:
+        unformatted = check_files(args.buf, args.files, args.exclude)
+        if args.write:
+            for path, _, formatted in unformatted:
+                Path(path).write_text(formatted, encoding="utf-8")
+                print(f"formatted {path}", file=sys.stderr)
+            return
+    except (FormatCheckError, OSError, UnicodeDecodeError) as e:
+        print(f"ERROR: buf_format_check: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    report = "".join(file_diff(path, original, formatted) for path, original, formatted in unformatted)
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(report)
+    if unformatted and args.exit_code:
+        print(f"{len(unformatted)} file(s) not formatted with buf format:", file=sys.stderr)
+        sys.stderr.write(report)
+        if args.fix_hint:
+            print(f"Fix with: {args.fix_hint}", file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,136 @@
+#!/usr/bin/env python3
+"""
+Build-time buf lint of a compiled proto library.
+
+Runs `buf lint` on the FileDescriptorSet a proto_library is compiled to (the
+same descriptor set codegen uses), with the lint configuration passed inline:
+rule categories or IDs to use, rules to except, paths to ignore and per-rule
+ignore_only paths for grandfathered violations.
+
+The descriptor set includes every import, so violations are limited to the
+library's own files. The report is sorted and contains no timestamps or
+absolute paths, so it can be snapshotted; it is also printed to stderr when
+the check fails.
+
+Usage:
+    buf_lint_check.py --buf bin/buf --image user.binpb --use DEFAULT \\
+        --ignore-only FIELD_LOWER_SNAKE_CASE=acme/legacy.proto \\
+        --target acme/user.proto --output lint.txt --exit-code
+"""
+
+import argparse
+import json
+import re
+import subprocess
+import sys
+from dataclasses import asdict
+from pathlib import Path
+from typing import Dict, List
+
+try:
+    from proto_breaking import BreakingCheckError, Violation, parse_violations
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_breaking import BreakingCheckError, Violation, parse_violations
+
+# buf exits with this code when it found violations, as opposed to failing
+BUF_VIOLATIONS_EXIT_CODE = 100
+
+# Lint categories and rule IDs are upper snake case, e.g. DEFAULT or ENUM_ZERO_VALUE_SUFFIX
+RULE_PATTERN = re.compile(r"^[A-Z][A-Z0-9_]*$")
+
+
+class LintCheckError(Exception):
+    """Raised when the lint check cannot run."""
+
+
+def lint_config(use: List[str], except_rules: List[str], ignore: List[str],
+                ignore_only: Dict[str, List[str]]) -> str:
+    """Returns the inline buf configuration for the lint section."""
+    for rule in use + except_rules + list(ignore_only):
+        if not RULE_PATTERN.match(rule):
+            raise LintCheckError(f"invalid lint rule or category {rule!r}")
+    lint = {"use": use or ["DEFAULT"]}
+    if except_rules:
+        lint["except"] = sorted(set(except_rules))
+    if ignore:
+        lint["ignore"] = sorted(set(ignore))
+    if ignore_only:
+        lint["ignore_only"] = {rule: sorted(set(paths)) for rule, paths in sorted(ignore_only.items())}
+    return json.dumps({"version": "v1", "lint": lint}, separators=(",", ":"), sort_keys=True)
+
+
+def parse_ignore_only(values: List[str]) -> Dict[str, List[str]]:
+    """Parses repeated RULE=PATH arguments into the ignore_only map."""
+    ignore_only: Dict[str, List[str]] = {}
+    for value in values:
+        rule, sep, path = value.partition("=")
+        if not sep or not rule or not path:
+            raise LintCheckError(f"--ignore-only expects RULE=PATH, got {value!r}")
+        ignore_only.setdefault(rule, []).append(path)
+    return ignore_only
+
+
+def is_target(path: str, targets: List[str]) -> bool:
+    """Returns whether a descriptor file name belongs to one of the linted files."""
+    return any(target == path or target.endswith("/" + path) for target in targets)
+
+
+def run_buf_lint(buf: str, image: str, config: str, targets: List[str]) -> List[Violation]:
+    """Runs buf lint on a descriptor set and returns the violations in the target files."""
+    cmd = [buf, "lint", image, "--config", config, "--error-format", "json"]
+    result = subprocess.run(cmd, capture_output=True, text=True)
+    if result.returncode not in (0, BUF_VIOLATIONS_EXIT_CODE):
+        raise LintCheckError(f"{buf} failed with exit code {result.returncode}:\n{result.stderr.strip()}")
+    try:
+        violations = parse_violations(result.stdout)
+    except BreakingCheckError as e:
+        raise LintCheckError(str(e)) from e
+    return [v for v in violations if is_target(v.path, targets)]
+
+
+def render_report(violations: List[Violation]) -> str:
+    """Renders violations as sorted file:line:column diagnostics."""
+    return "".join(f"{violation}\n" for violation in violations)
+
+
+def main():
+    """Main entry point for the buf lint check."""
+    parser = argparse.ArgumentParser(description="Lint a compiled proto library with buf")
+    parser.add_argument("--buf", required=True, help="buf binary")
+    parser.add_argument("--image", required=True, help="FileDescriptorSet with imports and source info")
+    parser.add_argument("--use", action="append", default=[], help="Lint category or rule ID (repeatable, default: DEFAULT)")
+    parser.add_argument("--except", dest="except_rules", action="append", default=[], help="Rule ID to skip (repeatable)")
+    parser.add_argument("--ignore", action="append", default=[], help="File or directory to skip (repeatable)")
+    parser.add_argument("--ignore-only", action="append", default=[], metavar="RULE=PATH",
+                        help="Skip one rule for a file or directory (repeatable)")
+    parser.add_argument("--target", action="append", required=True, help="Proto file of the library (repeatable)")
+    parser.add_argument("--output", help="Text report to write")
+    parser.add_argument("--json-output", help="JSON report to write")
+    parser.add_argument("--exit-code", action="store_true", help="Exit with status 1 when there are violations")
+    args = parser.parse_args()
+
+    try:
+        config = lint_config(args.use, args.except_rules, args.ignore, parse_ignore_only(args.ignore_only))
+        violations = run_buf_lint(args.buf, args.image, config, args.target)
+    except (LintCheckError, OSError) as e:
+        print(f"ERROR: buf_lint_check: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    report = render_report(violations)
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(report)
+    if args.json_output:
+        with open(args.json_output, "w", encoding="utf-8") as f:
+            json.dump({"config": json.loads(config), "violations": [asdict(v) for v in violations]},
+                      f, indent=2, sort_keys=True)
+            f.write("\n")
+    if violations and args.exit_code:
+        print(f"{len(violations)} lint violation(s):", file=sys.stderr)
+        sys.stderr.write(report)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,179 @@
+#!/usr/bin/env python3
+"""
+Parallel multi-language generation benchmark for proto_bundle.
+
+Writes a synthetic schema (50 files by default, each importing its
+predecessor) and a BUCK file bundling it for several languages, then builds
+the bundle twice: once with `-j 1`, which runs the per-language protoc
+actions one after the other, and once with the default job count, where
+they run concurrently. Every build first rewrites a salt comment in each
+file, so neither build is served from the action cache.
+
+The report lists the wall-clock time of both builds, the speedup and the
+protoc actions each language target ran.
+
+Usage:
+    bundle_parallel_bench.py --package bench/bundle_parallel \\
+        --languages go,python,cpp,typescript --output bench.json
+"""
+
+import argparse
+import json
+import subprocess
+import sys
+import time
+from pathlib import Path
+from typing import Dict, List
+
+try:
+    from codegen_cache_bench import BenchError, count_protoc_runs
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from codegen_cache_bench import BenchError, count_protoc_runs
+
+BUNDLE_NAME = "bench_bundle"
+
+# protoc action category of each bundle language
+LANGUAGE_CATEGORIES = {
+    "go": "go_protoc",
+    "python": "python_protoc",
+    "typescript": "typescript_protoc",
+    "cpp": "cpp_protoc",
+    "rust": "rust_protoc",
+}
+
+LANGUAGE_CONFIGS = {
+    "go": '{"go_package": "example.com/bench/v1;benchv1"}',
+    "python": '{"python_package": "bench.v1"}',
+    "typescript": '{"npm_package": "@bench/v1"}',
+    "cpp": '{"namespace": "bench::v1"}',
+    "rust": '{"rust_package": "bench_v1"}',
+}
+
+
+def schema_file(index: int, salt: str) -> str:
+    """Returns the content of schema file `index`, importing file `index - 1`."""
+    lines = [
+        f"// bundle_parallel_bench salt: {salt}",
+        'syntax = "proto3";',
+        "",
+        "package bench.v1;",
+        "",
+    ]
+    if index > 0:
+        lines += [f'import "{schema_path(index - 1)}";', ""]
+    lines.append(f"message Entity{index} {{")
+    for field in range(1, 21):
+        lines.append(f"  string field_{field} = {field};")
+    if index > 0:
+        lines.append(f"  Entity{index - 1} parent = 21;")
+    lines.append(f"  repeated Entity{index}Item items = 22;")
+    lines += ["}", "", f"message Entity{index}Item {{", "  int64 id = 1;", "  bytes payload = 2;", "}", ""]
+    if index % 10 == 0:
+        lines += [
+            f"service Entity{index}Service {{",
+            f"  rpc Get(Entity{index}) returns (Entity{index});",
+            f"  rpc List(Entity{index}Item) returns (stream Entity{index});",
+            "}",
+            "",
+        ]
+    return "\n".join(lines)
+
+
+def schema_path(index: int) -> str:
+    return f"bench/v1/entity_{index:03d}.proto"
+
+
+def buck_file(file_count: int, languages: List[str]) -> str:
+    """Returns the BUCK file declaring the schema library and its bundle."""
+    srcs = "".join(f'        "{schema_path(i)}",\n' for i in range(file_count))
+    configs = "".join(f'        "{language}": {LANGUAGE_CONFIGS[language]},\n' for language in languages)
+    return (
+        '# Generated by tools/bundle_parallel_bench.py\n'
+        'load("//rules:proto.bzl", "proto_bundle", "proto_library")\n\n'
+        'proto_library(\n'
+        '    name = "bench_proto",\n'
+        f'    srcs = [\n{srcs}    ],\n'
+        ')\n\n'
+        'proto_bundle(\n'
+        f'    name = "{BUNDLE_NAME}",\n'
+        '    proto = ":bench_proto",\n'
+        f'    languages = {{\n{configs}    }},\n'
+        '    consistency_checks = False,\n'
+        ')\n'
+    )
+
+
+def write_schema(package_dir: Path, file_count: int, languages: List[str], salt: str) -> None:
+    """Writes (or re-salts) the schema files and the BUCK file."""
+    unknown = [language for language in languages if language not in LANGUAGE_CATEGORIES]
+    if unknown:
+        raise BenchError(f"unsupported languages: {', '.join(unknown)}")
+    for index in range(file_count):
+        path = package_dir / schema_path(index)
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text(schema_file(index, salt), encoding="utf-8")
+    (package_dir / "BUCK").write_text(buck_file(file_count, languages), encoding="utf-8")
+
+
+def build(buck2: str, target: str, jobs: int, languages: List[str], extra_args: List[str]) -> Dict:
+    """Builds the bundle and returns its duration and the protoc actions per language."""
+    args = [buck2, "build", target] + (["-j", str(jobs)] if jobs else []) + extra_args
+    start = time.perf_counter()
+    result = subprocess.run(args, capture_output=True, text=True)
+    duration = time.perf_counter() - start
+    if result.returncode != 0:
+        # buck2 names the failing language target, e.g. //bench:bench_bundle_python (python_protoc ...)
+        raise BenchError(f"buck2 build {target} failed:\n{result.stderr.strip()}")
+    log = subprocess.run([buck2, "log", "what-ran", "--format", "json"], capture_output=True, text=True, check=True)
+    what_ran = log.stdout.splitlines()
+    actions = {language: len(count_protoc_runs(what_ran, (LANGUAGE_CATEGORIES[language],))) for language in languages}
+    return {"seconds": round(duration, 3), "protoc_actions": actions}
+
+
+def run_benchmark(buck2: str, package: str, file_count: int, languages: List[str], extra_args: List[str]) -> Dict:
+    """Builds the bundle serially and in parallel, each from a freshly salted schema."""
+    package_dir = Path(package)
+    target = f"//{package}:{BUNDLE_NAME}"
+    report = {"target": target, "files": file_count, "languages": languages}
+    for phase, jobs in (("serial", 1), ("parallel", 0)):
+        write_schema(package_dir, file_count, languages, f"{phase}-{time.time_ns()}")
+        report[phase] = build(buck2, target, jobs, languages, extra_args)
+    report["speedup"] = round(report["serial"]["seconds"] / max(report["parallel"]["seconds"], 0.001), 2)
+    return report
+
+
+def main():
+    """Main entry point for the parallel bundle benchmark."""
+    parser = argparse.ArgumentParser(description="Compare serial and parallel proto_bundle generation")
+    parser.add_argument("--package", default="bench/bundle_parallel", help="Package directory to write the schema to")
+    parser.add_argument("--files", type=int, default=50, help="Number of proto files in the schema")
+    parser.add_argument("--languages", default="go,python,cpp,typescript", help="Comma-separated bundle languages")
+    parser.add_argument("--min-speedup", type=float, help="Fail if the parallel build is not this many times faster")
+    parser.add_argument("--buck2", default="buck2", help="buck2 executable")
+    parser.add_argument("--output", help="Write the JSON report to this file")
+    parser.add_argument("build_args", nargs=argparse.REMAINDER, help="-- extra arguments for buck2 build")
+    args = parser.parse_args()
+
+    extra_args = args.build_args[1:] if args.build_args[:1] == ["--"] else args.build_args
+    languages = [language.strip() for language in args.languages.split(",") if language.strip()]
+    try:
+        report = run_benchmark(args.buck2, args.package.strip("/"), args.files, languages, extra_args)
+    except (BenchError, OSError, subprocess.CalledProcessError, ValueError) as e:
+        print(f"ERROR: bundle_parallel_bench: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    output = json.dumps(report, indent=2)
+    if args.output:
+        Path(args.output).write_text(output + "\n", encoding="utf-8")
+    print(output)
+
+    print(f"serial: {report['serial']['seconds']}s, parallel: {report['parallel']['seconds']}s, "
+          f"speedup {report['speedup']}x", file=sys.stderr)
+    if args.min_speedup is not None and report["speedup"] < args.min_speedup:
+        print(f"ERROR: speedup {report['speedup']}x is below {args.min_speedup}x", file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
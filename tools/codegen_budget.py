@@ -0,0 +1,158 @@
+#!/usr/bin/env python3
+"""
+Generated code size budgets.
+
+Sums the size of the files generated by language rules, per language, and
+fails when a language exceeds its byte budget. The report lists the total per
+language and the largest files, so that a schema change that balloons
+generated code is caught at review time rather than by slow compiles.
+
+Usage:
+    codegen_budget.py --budget-bytes 2000000 --language-budget go=500000 \\
+        --file go user/v1/user.pb.go buck-out/.../user.pb.go \\
+        --output report.json
+"""
+
+import argparse
+import json
+import os
+import sys
+from dataclasses import dataclass, field
+from typing import Dict, List, Optional, Tuple
+
+DEFAULT_TOP = 10
+
+
+class BudgetError(Exception):
+    """Raised when the budget configuration or an input is invalid."""
+
+
+@dataclass
+class LanguageUsage:
+    """Generated code size of one language."""
+    language: str
+    total_bytes: int = 0
+    budget_bytes: int = 0  # 0 means unlimited
+    files: List[Tuple[str, int]] = field(default_factory=list)
+
+    @property
+    def over_budget(self) -> bool:
+        return self.budget_bytes > 0 and self.total_bytes > self.budget_bytes
+
+    def largest(self, top: int) -> List[Tuple[str, int]]:
+        return sorted(self.files, key=lambda f: (-f[1], f[0]))[:top]
+
+
+def file_sizes(name: str, path: str) -> List[Tuple[str, int]]:
+    """Returns (name, size) for a file, or for every file below a directory."""
+    if not os.path.isdir(path):
+        return [(name, os.path.getsize(path))]
+    sizes = []
+    for root, dirs, files in os.walk(path):
+        dirs.sort()
+        for file_name in sorted(files):
+            full_path = os.path.join(root, file_name)
+            relative = os.path.relpath(full_path, path).replace(os.sep, "/")
+            sizes.append((f"{name}/{relative}", os.path.getsize(full_path)))
+    return sizes
+
+
+def parse_language_budgets(values: List[str]) -> Dict[str, int]:
+    """Parses LANGUAGE=BYTES pairs."""
+    budgets = {}
+    for value in values:
+        language, _, size = value.partition("=")
+        if not language or not size.isdigit():
+            raise BudgetError(f"language budget must be LANGUAGE=BYTES, got '{value}'")
+        budgets[language] = int(size)
+    return budgets
+
+
+def measure(
+    files: List[Tuple[str, str, str]],
+    budget_bytes: int = 0,
+    language_budgets: Optional[Dict[str, int]] = None,
+) -> List[LanguageUsage]:
+    """
+    Sums generated file sizes per language.
+
+    Args:
+        files: (language, display name, path) triples; a file listed twice counts once
+        budget_bytes: Budget of every language without its own budget (0 for unlimited)
+        language_budgets: Per-language budgets overriding budget_bytes
+
+    Returns:
+        Usage per language, sorted by language
+    """
+    language_budgets = language_budgets or {}
+    usage: Dict[str, LanguageUsage] = {}
+    seen = set()
+    for language, name, path in files:
+        if (language, path) in seen:
+            continue
+        seen.add((language, path))
+        entry = usage.setdefault(language, LanguageUsage(language, budget_bytes=language_budgets.get(language, budget_bytes)))
+        for file_name, size in file_sizes(name, path):
+            entry.files.append((file_name, size))
+            entry.total_bytes += size
+    return [usage[language] for language in sorted(usage)]
+
+
+def format_bytes(size: int) -> str:
+    for unit, scale in (("MiB", 1 << 20), ("KiB", 1 << 10)):
+        if size >= scale:
+            return f"{size / scale:.1f} {unit}"
+    return f"{size} B"
+
+
+def render_report(usage: List[LanguageUsage], top: int) -> Dict:
+    return {
+        "languages": [
+            {
+                "language": entry.language,
+                "total_bytes": entry.total_bytes,
+                "budget_bytes": entry.budget_bytes,
+                "over_budget": entry.over_budget,
+                "largest": [{"file": name, "bytes": size} for name, size in entry.largest(top)],
+            }
+            for entry in usage
+        ],
+    }
+
+
+def main():
+    """Main entry point for the budget check."""
+    parser = argparse.ArgumentParser(description="Check generated code size against a budget")
+    parser.add_argument("--file", nargs=3, action="append", default=[], metavar=("LANGUAGE", "NAME", "PATH"),
+                        help="Generated file or directory: language, display name and path")
+    parser.add_argument("--budget-bytes", type=int, default=0, help="Budget per language in bytes (0 for unlimited)")
+    parser.add_argument("--language-budget", action="append", default=[], metavar="LANGUAGE=BYTES",
+                        help="Budget of one language, overriding --budget-bytes")
+    parser.add_argument("--top", type=int, default=DEFAULT_TOP, help="Number of largest files to report")
+    parser.add_argument("--output", required=True, help="JSON report to write")
+    args = parser.parse_args()
+
+    try:
+        if args.budget_bytes < 0:
+            raise BudgetError("budget must not be negative")
+        usage = measure([tuple(f) for f in args.file], args.budget_bytes, parse_language_budgets(args.language_budget))
+    except (BudgetError, OSError) as e:
+        print(f"ERROR: codegen_budget: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    with open(args.output, "w", encoding="utf-8") as f:
+        json.dump(render_report(usage, args.top), f, indent=2)
+
+    over = [entry for entry in usage if entry.over_budget]
+    for entry in over:
+        print(f"ERROR: generated {entry.language} code is {format_bytes(entry.total_bytes)} "
+              f"({entry.total_bytes} bytes), over the budget of {format_bytes(entry.budget_bytes)} "
+              f"({entry.budget_bytes} bytes). Largest files:", file=sys.stderr)
+        for name, size in entry.largest(args.top):
+            print(f"  {format_bytes(size):>10}  {name}", file=sys.stderr)
+    if over:
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,118 @@
+#!/usr/bin/env python3
+"""
+Warm-cache codegen benchmark for protobuf Buck2 integration.
+
+Builds a target twice and counts the protoc actions each build executed,
+using `buck2 log what-ran`. With per-file codegen actions the rebuild must
+not run protoc at all, and after editing one proto file (--touch) only that
+file and the files importing it are regenerated. Action cache hits are not
+counted as invocations.
+
+Usage:
+    codegen_cache_bench.py //examples/caching:catalog_go \\
+        --touch examples/caching/catalog/price.proto --expect-warm-runs 0
+"""
+
+import argparse
+import json
+import re
+import subprocess
+import sys
+import time
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional
+
+# Action categories that invoke protoc
+PROTOC_CATEGORIES = ("go_protoc", "go_protoc_pipeline", "protoc")
+
+TOUCH_COMMENT = b"\n// codegen_cache_bench: touched\n"
+
+
+class BenchError(Exception):
+    """Raised when a build of the benchmark fails."""
+
+
+def count_protoc_runs(what_ran: Iterable[str], categories=PROTOC_CATEGORIES) -> List[str]:
+    """
+    Returns the protoc actions executed according to `buck2 log what-ran --format json`.
+
+    Each line is a JSON object whose identity reads "<target> (<category> <identifier>)";
+    actions served from the action cache are skipped.
+    """
+    pattern = re.compile(r"\((" + "|".join(re.escape(c) for c in categories) + r")(\s[^)]*)?\)\s*$")
+    runs = []
+    for line in what_ran:
+        line = line.strip()
+        if not line:
+            continue
+        entry = json.loads(line)
+        if str(entry.get("executor", "")).lower() == "cache":
+            continue
+        identity = entry.get("identity", "")
+        if pattern.search(identity):
+            runs.append(identity)
+    return runs
+
+
+def build(buck2: str, target: str, extra_args: List[str]) -> Dict:
+    """Builds the target and returns its duration and executed protoc actions."""
+    start = time.perf_counter()
+    result = subprocess.run([buck2, "build", target] + extra_args, capture_output=True, text=True)
+    duration = time.perf_counter() - start
+    if result.returncode != 0:
+        raise BenchError(f"buck2 build {target} failed:\n{result.stderr.strip()}")
+    log = subprocess.run([buck2, "log", "what-ran", "--format", "json"], capture_output=True, text=True, check=True)
+    runs = count_protoc_runs(log.stdout.splitlines())
+    return {"seconds": round(duration, 3), "protoc_runs": len(runs), "actions": runs}
+
+
+def run_benchmark(buck2: str, target: str, touch: Optional[str], extra_args: List[str]) -> Dict:
+    """Runs the initial build, the warm rebuild and the optional single-file edit."""
+    report = {"target": target}
+    report["initial"] = build(buck2, target, extra_args)
+    report["warm"] = build(buck2, target, extra_args)
+    if touch:
+        path = Path(touch)
+        original = path.read_bytes()
+        try:
+            path.write_bytes(original + TOUCH_COMMENT)
+            report["touched"] = dict(build(buck2, target, extra_args), file=touch)
+        finally:
+            path.write_bytes(original)
+    return report
+
+
+def main():
+    """Main entry point for the codegen cache benchmark."""
+    parser = argparse.ArgumentParser(description="Count protoc invocations of a warm rebuild")
+    parser.add_argument("target", help="Target to build, e.g. //examples/caching:catalog_go")
+    parser.add_argument("--touch", help="Proto file to edit (a trailing comment) before a third build; restored afterwards")
+    parser.add_argument("--expect-warm-runs", type=int, help="Fail unless the warm rebuild ran exactly this many protoc actions")
+    parser.add_argument("--buck2", default="buck2", help="buck2 executable")
+    parser.add_argument("--output", help="Write the JSON report to this file")
+    parser.add_argument("build_args", nargs=argparse.REMAINDER, help="-- extra arguments for buck2 build")
+    args = parser.parse_args()
+
+    extra_args = args.build_args[1:] if args.build_args[:1] == ["--"] else args.build_args
+    try:
+        report = run_benchmark(args.buck2, args.target, args.touch, extra_args)
+    except (BenchError, OSError, subprocess.CalledProcessError, ValueError) as e:
+        print(f"ERROR: codegen_cache_bench: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    output = json.dumps(report, indent=2)
+    if args.output:
+        Path(args.output).write_text(output + "\n", encoding="utf-8")
+    print(output)
+
+    for phase in ("initial", "warm", "touched"):
+        if phase in report:
+            print(f"{phase}: {report[phase]['protoc_runs']} protoc runs in {report[phase]['seconds']}s", file=sys.stderr)
+    if args.expect_warm_runs is not None and report["warm"]["protoc_runs"] != args.expect_warm_runs:
+        print(f"ERROR: warm rebuild ran {report['warm']['protoc_runs']} protoc actions, "
+              f"expected {args.expect_warm_runs}", file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
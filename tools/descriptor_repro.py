@@ -0,0 +1,349 @@
+#!/usr/bin/env python3
+"""
+Descriptor set reproducibility check across protoc versions.
+
+Compiles the same proto files with two protoc binaries (usually the pinned
+version and an upgrade candidate), normalizes both descriptor sets and reports
+every semantic difference. Normalization removes data that depends on the
+protoc version rather than on the schema:
+
+- source_code_info (comment and span locations)
+- resolved edition features (the `features` field of every options message)
+- json_name when it equals the default protoc derives from the field name
+- syntax "proto2" (older protoc versions omit it) and the legacy edition
+  markers EDITION_PROTO2 / EDITION_PROTO3
+- options messages that are empty after the above
+- the order of differently numbered fields within a message
+
+Well-known types shipped with protoc (google/protobuf/*) differ between
+versions by design and are skipped unless --include-well-known-types is set.
+
+Descriptor sets are decoded from the protobuf wire format directly, against
+the subset of descriptor.proto needed to name elements in the report; all
+other fields are compared as raw values.
+
+Usage:
+    descriptor_repro.py --protoc 24.4=bin/protoc-24.4 --protoc 25.1=bin/protoc-25.1 \\
+        --proto_path=. --output report.json acme/user/v1/user.proto
+"""
+
+import argparse
+import json
+import os
+import subprocess
+import sys
+import tempfile
+from typing import Any, Dict, List, Optional, Tuple
+
+# Wire types
+VARINT, FIXED64, LENGTH_DELIMITED, START_GROUP, END_GROUP, FIXED32 = 0, 1, 2, 3, 4, 5
+
+# Field number of the resolved edition features in every *Options message
+FEATURES_FIELD = 50
+
+# descriptor.proto subset: message type -> field number -> (name, kind). A kind
+# is "string", "int", "bool", "options" or the name of another message type.
+DESCRIPTOR_SCHEMA: Dict[str, Dict[int, Tuple[str, str]]] = {
+    "FileDescriptorSet": {1: ("file", "FileDescriptorProto")},
+    "FileDescriptorProto": {
+        1: ("name", "string"), 2: ("package", "string"), 3: ("dependency", "string"),
+        10: ("public_dependency", "int"), 11: ("weak_dependency", "int"),
+        4: ("message_type", "DescriptorProto"), 5: ("enum_type", "EnumDescriptorProto"),
+        6: ("service", "ServiceDescriptorProto"), 7: ("extension", "FieldDescriptorProto"),
+        8: ("options", "options"), 9: ("source_code_info", "SourceCodeInfo"),
+        12: ("syntax", "string"), 14: ("edition", "int"),
+    },
+    "DescriptorProto": {
+        1: ("name", "string"), 2: ("field", "FieldDescriptorProto"),
+        6: ("extension", "FieldDescriptorProto"), 3: ("nested_type", "DescriptorProto"),
+        4: ("enum_type", "EnumDescriptorProto"), 5: ("extension_range", "Range"),
+        8: ("oneof_decl", "OneofDescriptorProto"), 7: ("options", "options"),
+        9: ("reserved_range", "Range"), 10: ("reserved_name", "string"),
+    },
+    "Range": {1: ("start", "int"), 2: ("end", "int"), 3: ("options", "options")},
+    "FieldDescriptorProto": {
+        1: ("name", "string"), 3: ("number", "int"), 4: ("label", "int"), 5: ("type", "int"),
+        6: ("type_name", "string"), 2: ("extendee", "string"), 7: ("default_value", "string"),
+        9: ("oneof_index", "int"), 10: ("json_name", "string"), 8: ("options", "options"),
+        17: ("proto3_optional", "bool"),
+    },
+    "OneofDescriptorProto": {1: ("name", "string"), 2: ("options", "options")},
+    "EnumDescriptorProto": {
+        1: ("name", "string"), 2: ("value", "EnumValueDescriptorProto"), 3: ("options", "options"),
+        4: ("reserved_range", "Range"), 5: ("reserved_name", "string"),
+    },
+    "EnumValueDescriptorProto": {1: ("name", "string"), 2: ("number", "int"), 3: ("options", "options")},
+    "ServiceDescriptorProto": {
+        1: ("name", "string"), 2: ("method", "MethodDescriptorProto"), 3: ("options", "options"),
+    },
+    "MethodDescriptorProto": {
+        1: ("name", "string"), 2: ("input_type", "string"), 3: ("output_type", "string"),
+        4: ("options", "options"), 5: ("client_streaming", "bool"), 6: ("server_streaming", "bool"),
+    },
+    "SourceCodeInfo": {1: ("location", "Location")},
+    "Location": {
+        1: ("path", "int"), 2: ("span", "int"), 3: ("leading_comments", "string"),
+        4: ("trailing_comments", "string"), 6: ("leading_detached_comments", "string"),
+    },
+}
+
+# Repeated elements matched by name when diffing, with their report labels
+NAMED_ELEMENTS = {
+    "file": "file", "message_type": "message", "nested_type": "message", "enum_type": "enum",
+    "field": "field", "extension": "extension", "oneof_decl": "oneof", "value": "value",
+    "service": "service", "method": "method",
+}
+
+# FileDescriptorProto.edition values of files using `syntax = "proto2"` / "proto3"
+LEGACY_EDITIONS = {998, 999}
+
+
+class DescriptorError(Exception):
+    """Raised when a descriptor set cannot be built or decoded."""
+
+
+def _read_varint(data: bytes, pos: int) -> Tuple[int, int]:
+    result = shift = 0
+    while True:
+        if pos >= len(data):
+            raise DescriptorError("truncated varint")
+        byte = data[pos]
+        pos += 1
+        result |= (byte & 0x7F) << shift
+        if not byte & 0x80:
+            return result, pos
+        shift += 7
+
+
+def _read_fields(data: bytes) -> List[Tuple[int, int, Any]]:
+    """Splits a serialized message into (field number, wire type, raw value) triples."""
+    fields = []
+    pos = 0
+    while pos < len(data):
+        key, pos = _read_varint(data, pos)
+        number, wire_type = key >> 3, key & 7
+        if wire_type == VARINT:
+            value, pos = _read_varint(data, pos)
+        elif wire_type == FIXED64:
+            value, pos = int.from_bytes(data[pos:pos + 8], "little"), pos + 8
+        elif wire_type == FIXED32:
+            value, pos = int.from_bytes(data[pos:pos + 4], "little"), pos + 4
+        elif wire_type == LENGTH_DELIMITED:
+            length, pos = _read_varint(data, pos)
+            value, pos = data[pos:pos + length], pos + length
+        elif wire_type == START_GROUP:
+            start = pos
+            depth = 1
+            while depth:
+                inner_key, pos = _read_varint(data, pos)
+                inner_type = inner_key & 7
+                if inner_type == START_GROUP:
+                    depth += 1
+                elif inner_type == END_GROUP:
+                    depth -= 1
+                elif inner_type == VARINT:
+                    _, pos = _read_varint(data, pos)
+                elif inner_type == FIXED64:
+                    pos += 8
+                elif inner_type == FIXED32:
+                    pos += 4
+                elif inner_type == LENGTH_DELIMITED:
+                    length, pos = _read_varint(data, pos)
+                    pos += length
+            value = data[start:pos]
+        else:
+            raise DescriptorError(f"unsupported wire type {wire_type} for field {number}")
+        if pos > len(data):
+            raise DescriptorError(f"truncated field {number}")
+        fields.append((number, wire_type, value))
+    return fields
+
+
+def _raw_value(value: Any) -> Any:
+    return value.hex() if isinstance(value, bytes) else value
+
+
+def decode(data: bytes, type_name: str = "FileDescriptorSet") -> Dict[str, List[Any]]:
+    """
+    Decodes a serialized descriptor message.
+
+    Returns:
+        Dict of field name (or "#<number>" for fields outside the schema) to
+        the list of its values in wire order. Options messages keep every
+        field by number.
+    """
+    schema = DESCRIPTOR_SCHEMA.get(type_name, {})
+    decoded: Dict[str, List[Any]] = {}
+    for number, wire_type, value in _read_fields(data):
+        name, kind = schema.get(number, (f"#{number}", "raw"))
+        if kind == "string" and wire_type == LENGTH_DELIMITED:
+            values = [value.decode("utf-8")]
+        elif kind in ("int", "bool") and wire_type == LENGTH_DELIMITED:
+            values = []  # packed
+            pos = 0
+            while pos < len(value):
+                item, pos = _read_varint(value, pos)
+                values.append(item)
+        elif kind == "bool":
+            values = [bool(value)]
+        elif kind == "options" and wire_type == LENGTH_DELIMITED:
+            values = [decode(value, "options")]
+        elif kind in DESCRIPTOR_SCHEMA and wire_type == LENGTH_DELIMITED:
+            values = [decode(value, kind)]
+        else:
+            values = [_raw_value(value)]
+        decoded.setdefault(name, []).extend(values)
+    return decoded
+
+
+def default_json_name(name: str) -> str:
+    """Returns the json_name protoc derives from a field name."""
+    result = []
+    capitalize = False
+    for c in name:
+        if c == "_":
+            capitalize = True
+        elif capitalize:
+            result.append(c.upper())
+            capitalize = False
+        else:
+            result.append(c)
+    return "".join(result)
+
+
+def normalize(message: Dict[str, List[Any]], type_name: str = "FileDescriptorSet") -> Dict[str, List[Any]]:
+    """Removes protoc-version-specific data from a decoded descriptor message."""
+    schema = {name: kind for name, kind in DESCRIPTOR_SCHEMA.get(type_name, {}).values()}
+    normalized: Dict[str, List[Any]] = {}
+    for key, values in message.items():
+        kind = schema.get(key)
+        if kind == "options":
+            values = [
+                {k: v for k, v in options.items() if k != f"#{FEATURES_FIELD}"}
+                for options in values
+            ]
+            values = [options for options in values if options]
+        elif kind in DESCRIPTOR_SCHEMA:
+            values = [normalize(value, kind) for value in values]
+        if values:
+            normalized[key] = values
+
+    if type_name == "FileDescriptorProto":
+        normalized.pop("source_code_info", None)
+        if normalized.get("syntax") == ["proto2"]:
+            del normalized["syntax"]
+        if normalized.get("edition", [None])[0] in LEGACY_EDITIONS:
+            del normalized["edition"]
+    elif type_name == "FieldDescriptorProto":
+        if normalized.get("json_name") == [default_json_name(normalized.get("name", [""])[0])]:
+            del normalized["json_name"]
+    return normalized
+
+
+def _format(values: Optional[List[Any]]) -> str:
+    if values is None:
+        return "(unset)"
+    return json.dumps(values[0] if len(values) == 1 else values, sort_keys=True)
+
+
+def diff(baseline: Dict[str, List[Any]], candidate: Dict[str, List[Any]], type_name: str = "FileDescriptorSet",
+         path: str = "") -> List[str]:
+    """
+    Compares two normalized descriptor messages.
+
+    Returns:
+        One line per difference, e.g.
+        "acme/user.proto > message User > field email: json_name: "email" -> "eMail""
+    """
+    schema = {name: kind for name, kind in DESCRIPTOR_SCHEMA.get(type_name, {}).values()}
+    differences = []
+    for key in sorted(set(baseline) | set(candidate)):
+        old, new = baseline.get(key), candidate.get(key)
+        kind = schema.get(key)
+        if key in NAMED_ELEMENTS and kind in DESCRIPTOR_SCHEMA:
+            label = NAMED_ELEMENTS[key]
+            old_by_name = {element.get("name", [""])[0]: element for element in old or []}
+            new_by_name = {element.get("name", [""])[0]: element for element in new or []}
+            for name in sorted(set(old_by_name) | set(new_by_name)):
+                element_path = name if label == "file" else f"{path} > {label} {name}"
+                if name not in new_by_name:
+                    differences.append(f"{element_path}: missing from candidate")
+                elif name not in old_by_name:
+                    differences.append(f"{element_path}: only in candidate")
+                else:
+                    differences.extend(diff(old_by_name[name], new_by_name[name], kind, element_path))
+            if [e.get("name") for e in old or []] != [e.get("name") for e in new or []] \
+                    and set(old_by_name) == set(new_by_name):
+                differences.append(f"{path}: {key} order changed")
+        elif old != new:
+            differences.append(f"{path}: {key}: {_format(old)} -> {_format(new)}")
+    return differences
+
+
+def build_descriptor_set(protoc: str, proto_paths: List[str], protos: List[str], output: str) -> bytes:
+    """Runs protoc to build a descriptor set with imports and source info."""
+    cmd = [protoc] + [f"--proto_path={path}" for path in proto_paths] + [
+        f"--descriptor_set_out={output}", "--include_imports", "--include_source_info",
+    ] + protos
+    result = subprocess.run(cmd, capture_output=True, text=True)
+    if result.returncode != 0:
+        raise DescriptorError(f"{protoc} failed with exit code {result.returncode}:\n{result.stderr.strip()}")
+    with open(output, "rb") as f:
+        return f.read()
+
+
+def compare_descriptor_sets(baseline: bytes, candidate: bytes, include_well_known_types: bool = False) -> List[str]:
+    """Normalizes two serialized descriptor sets and returns their differences."""
+    sets = []
+    for data in (baseline, candidate):
+        descriptor_set = normalize(decode(data))
+        if not include_well_known_types:
+            descriptor_set["file"] = [
+                f for f in descriptor_set.get("file", [])
+                if not f.get("name", [""])[0].startswith("google/protobuf/")
+            ]
+        sets.append(descriptor_set)
+    return diff(sets[0], sets[1])
+
+
+def main():
+    """Main entry point for the reproducibility check."""
+    parser = argparse.ArgumentParser(description="Compare descriptor sets built by two protoc versions")
+    parser.add_argument("--protoc", action="append", required=True, metavar="VERSION=PATH",
+                        help="protoc binary and its version label; give exactly two, baseline first")
+    parser.add_argument("--proto_path", action="append", default=[], help="Import path")
+    parser.add_argument("--output", help="JSON report to write")
+    parser.add_argument("--include-well-known-types", action="store_true",
+                        help="Also compare the google/protobuf files bundled with protoc")
+    parser.add_argument("protos", nargs="+", help="Proto files to compile")
+    args = parser.parse_args()
+
+    try:
+        if len(args.protoc) != 2 or not all("=" in p for p in args.protoc):
+            raise DescriptorError("--protoc must be given exactly twice as VERSION=PATH")
+        versions = [p.split("=", 1) for p in args.protoc]
+        with tempfile.TemporaryDirectory() as temp_dir:
+            data = [
+                build_descriptor_set(path, args.proto_path, args.protos, os.path.join(temp_dir, f"{i}.pb"))
+                for i, (_, path) in enumerate(versions)
+            ]
+        differences = compare_descriptor_sets(data[0], data[1], args.include_well_known_types)
+    except (DescriptorError, OSError, UnicodeDecodeError) as e:
+        print(f"ERROR: descriptor_repro: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    baseline, candidate = versions[0][0], versions[1][0]
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            json.dump({"baseline": baseline, "candidate": candidate, "differences": differences}, f, indent=2)
+            f.write("\n")
+    if differences:
+        print(f"Descriptor sets built by protoc {baseline} and {candidate} differ:", file=sys.stderr)
+        for difference in differences:
+            print(f"  {difference}", file=sys.stderr)
+        sys.exit(1)
+    print(f"Descriptor sets built by protoc {baseline} and {candidate} are equivalent")
+
+
+if __name__ == "__main__":
+    main()
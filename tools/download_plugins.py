@@ -4,6 +4,8 @@ Download protoc plugins for protobuf Buck2 integration.
 
 This script handles downloading and caching protoc plugins for different
 languages, platforms, and versions with comprehensive security features.
+Downloaded plugins are kept in the content-addressed plugin cache (see
+plugin_cache.py), so switching back to a version fetched before is instant.
 
 Enhanced with ORAS distribution support for improved performance and bandwidth efficiency.
 """
@@ -31,21 +33,32 @@ try:
 except ImportError:
     ORAS_AVAILABLE = False
 
+try:
+    from offline_tools import OfflineToolError, ToolSpec, offline_cache_dir, resolve as resolve_offline_tool
+    from plugin_cache import PluginCache, plugin_cache_root
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from offline_tools import OfflineToolError, ToolSpec, offline_cache_dir, resolve as resolve_offline_tool
+    from plugin_cache import PluginCache, plugin_cache_root
+
 
 class PluginDownloader:
     """Handles downloading, caching, and validation of protoc plugins."""
     
-    def __init__(self, cache_dir: str, verbose: bool = False):
+    def __init__(self, cache_dir: str, verbose: bool = False, plugin_cache_dir: Optional[str] = None):
         """
         Initialize the plugin downloader.
         
         Args:
             cache_dir: Directory to store cached downloads
             verbose: Enable verbose logging
+            plugin_cache_dir: Root of the content-addressed plugin cache
+                              (default: $BUCK2_PROTOBUF_PLUGIN_CACHE)
         """
         self.cache_dir = Path(cache_dir)
         self.cache_dir.mkdir(parents=True, exist_ok=True)
         self.verbose = verbose
+        self.plugin_cache = PluginCache(plugin_cache_root(plugin_cache_dir))
         
         # Plugin configuration database
         self.plugin_config = {
@@ -325,7 +338,8 @@ class PluginDownloader:
         
         return wrapper_path
     
-    def get_cached_plugin_path(self, plugin: str, version: str, platform: str) -> Optional[Path]:
+    def get_cached_plugin_path(self, plugin: str, version: str, platform: str,
+                               checksum: Optional[str] = None) -> Optional[Path]:
         """Check if plugin binary is already cached and valid."""
         if plugin not in self.plugin_config:
             return None
@@ -337,9 +351,14 @@ class PluginDownloader:
             return None
         
         config = self.plugin_config[plugin][version][platform]
-        cache_key = f"{plugin}-{version}-{platform}"
-        cached_dir = self.cache_dir / cache_key
-        binary_path = cached_dir / config["binary_path"]
+        if config.get("type") == "python_package":
+            cache_key = f"{plugin}-{version}-{platform}"
+            binary_path = self.cache_dir / cache_key / config["binary_path"]
+        else:
+            binary_path = self.plugin_cache.lookup(plugin, version, platform, config["binary_path"],
+                                                   checksum or config.get("sha256"))
+            if binary_path is None:
+                return None
         
         if not binary_path.exists():
             return None
@@ -358,14 +377,45 @@ class PluginDownloader:
         self.log(f"Using cached plugin at {binary_path}")
         return binary_path
     
-    def download_plugin(self, plugin: str, version: str, platform: str) -> str:
+    def resolve_offline(self, plugin: str, version: str, platform: str, cache_dir: Path,
+                        binary_path: Optional[str] = None, checksum: Optional[str] = None) -> str:
+        """
+        Returns a plugin from a pre-populated offline cache, never downloading.
+        
+        Args:
+            binary_path: Binary path inside the cache entry; defaults to the built-in configuration
+            checksum: Expected archive sha256; defaults to the built-in configuration
+        
+        Raises:
+            OfflineToolError: If the binary is not in the cache (the message
+                              names the version, expected path and checksum)
+        """
+        config = self.plugin_config.get(plugin, {}).get(version, {}).get(platform, {})
+        spec = ToolSpec(
+            tool=plugin,
+            version=version,
+            platform=platform,
+            binary_path=binary_path or config.get("binary_path") or plugin,
+            sha256=checksum or config.get("sha256"),
+            url=config.get("url"),
+        )
+        binary = resolve_offline_tool(spec, cache_dir)
+        self.log(f"Using offline plugin at {binary}")
+        return str(binary)
+    
+    def download_plugin(self, plugin: str, version: str, platform: str, checksum: Optional[str] = None) -> str:
         """
         Download and cache a protoc plugin binary.
         
+        Binary plugins are stored in the content-addressed plugin cache under
+        their archive checksum; concurrent downloads of the same plugin are
+        serialized there and never expose a partial download.
+        
         Args:
             plugin: Plugin name (e.g., "protoc-gen-go")
             version: Plugin version
             platform: Target platform (e.g., "linux-x86_64")
+            checksum: Expected archive SHA256 (default: the built-in configuration)
             
         Returns:
             Path to the downloaded plugin binary
@@ -375,7 +425,7 @@ class PluginDownloader:
             RuntimeError: If download or validation fails
         """
         # Check cache first
-        cached_path = self.get_cached_plugin_path(plugin, version, platform)
+        cached_path = self.get_cached_plugin_path(plugin, version, platform, checksum)
         if cached_path:
             return str(cached_path)
         
@@ -420,36 +470,28 @@ class PluginDownloader:
             else:
                 # Handle binary downloads
                 url = config["url"]
-                expected_checksum = config["sha256"]
+                expected_checksum = checksum or config.get("sha256")
                 binary_path = config["binary_path"]
                 archive_type = config.get("archive_type", "tar.gz")
                 
-                # Set up paths
-                archive_path = self.cache_dir / f"{cache_key}.{archive_type.replace('.', '_')}"
-                final_binary = cached_dir / binary_path
-                
-                # Download archive
-                if not self.download_with_retry(url, archive_path):
-                    raise RuntimeError(f"Failed to download {url}")
-                
-                # Validate checksum
-                if not self.validate_checksum(archive_path, expected_checksum):
-                    archive_path.unlink(missing_ok=True)
-                    raise RuntimeError(f"Checksum validation failed for {archive_path}")
-                
-                # Extract archive
-                if not self.extract_archive(archive_path, cached_dir, archive_type):
-                    raise RuntimeError(f"Failed to extract {archive_path}")
-                
-                # Verify binary exists and make executable
-                if not final_binary.exists():
-                    raise RuntimeError(f"Binary not found at expected path: {final_binary}")
-                
-                final_binary.chmod(0o755)
-                
-                # Clean up archive
-                archive_path.unlink(missing_ok=True)
+                def populate(staging_dir: Path) -> str:
+                    archive_path = staging_dir.parent / f"{staging_dir.name}.{archive_type.replace('.', '_')}"
+                    try:
+                        if not self.download_with_retry(url, archive_path):
+                            raise RuntimeError(f"Failed to download {url}")
+                        digest = self.calculate_sha256(archive_path)
+                        if expected_checksum and not self.validate_checksum(archive_path, expected_checksum):
+                            raise RuntimeError(f"Checksum validation failed for {url}")
+                        if not self.extract_archive(archive_path, staging_dir, archive_type):
+                            raise RuntimeError(f"Failed to extract {archive_path}")
+                        if (staging_dir / binary_path).exists():
+                            (staging_dir / binary_path).chmod(0o755)
+                        return digest
+                    finally:
+                        archive_path.unlink(missing_ok=True)
                 
+                final_binary = self.plugin_cache.fetch(plugin, version, platform, binary_path, populate,
+                                                       expected_checksum)
                 self.log(f"Successfully installed plugin {plugin} {version} for {platform} at {final_binary}")
                 return str(final_binary)
                 
@@ -489,7 +531,8 @@ def detect_platform_string() -> str:
 
 def download_plugin_enhanced(plugin: str, version: str, platform: str = None, 
                             cache_dir: str = None, registry: str = "oras.birb.homes", 
-                            verbose: bool = False, use_oras: bool = True) -> str:
+                            verbose: bool = False, use_oras: bool = True,
+                            checksum: Optional[str] = None, plugin_cache_dir: Optional[str] = None) -> str:
     """
     Enhanced plugin download with ORAS support and HTTP fallback.
     
@@ -504,6 +547,9 @@ def download_plugin_enhanced(plugin: str, version: str, platform: str = None,
         registry: ORAS registry URL
         verbose: Enable verbose logging
         use_oras: Enable ORAS distribution (falls back to HTTP if unavailable)
+        checksum: Expected archive SHA256
+        plugin_cache_dir: Root of the content-addressed plugin cache, checked
+                          before ORAS and filled by HTTP downloads
         
     Returns:
         Path to the plugin binary
@@ -518,6 +564,11 @@ def download_plugin_enhanced(plugin: str, version: str, platform: str = None,
     if cache_dir is None:
         cache_dir = os.path.expanduser("~/.cache/buck2-protobuf")
     
+    downloader = PluginDownloader(cache_dir, verbose=verbose, plugin_cache_dir=plugin_cache_dir)
+    cached_path = downloader.get_cached_plugin_path(plugin, version, platform, checksum)
+    if cached_path:
+        return str(cached_path)
+    
     # Try ORAS distribution first if available and enabled
     if use_oras and ORAS_AVAILABLE:
         try:
@@ -532,8 +583,7 @@ def download_plugin_enhanced(plugin: str, version: str, platform: str = None,
                 print(f"[enhanced-downloader] ORAS failed: {e}, falling back to HTTP", file=sys.stderr)
     
     # Fallback to traditional HTTP download
-    downloader = PluginDownloader(cache_dir, verbose=verbose)
-    return downloader.download_plugin(plugin, version, platform)
+    return downloader.download_plugin(plugin, version, platform, checksum)
 
 
 def download_plugin_bundle(bundle_name: str, bundle_version: str = "latest",
@@ -578,6 +628,16 @@ def download_plugin_bundle(bundle_name: str, bundle_version: str = "latest",
     return distributor.get_bundle(bundle_name, bundle_version, platform)
 
 
+def copy_to_output(binary_path: str, output: Optional[str]) -> str:
+    """Copies the binary to output (if given) and returns the path to use."""
+    if not output:
+        return binary_path
+    Path(output).parent.mkdir(parents=True, exist_ok=True)
+    shutil.copyfile(binary_path, output)
+    Path(output).chmod(0o755)
+    return output
+
+
 def main():
     """Main entry point for plugin download script."""
     parser = argparse.ArgumentParser(description="Download protoc plugins")
@@ -593,6 +653,12 @@ def main():
     parser.add_argument("--verbose", "-v", action="store_true", help="Enable verbose output")
     parser.add_argument("--list-plugins", action="store_true", help="List supported plugins")
     parser.add_argument("--list-bundles", action="store_true", help="List supported bundles")
+    parser.add_argument("--output", help="Copy the binary to this path")
+    parser.add_argument("--offline", action="store_true", help="Resolve from the offline cache only, never download")
+    parser.add_argument("--offline-cache-dir", help="Pre-populated tool cache (default: $BUCK2_PROTOBUF_OFFLINE_CACHE)")
+    parser.add_argument("--binary-path", help="Binary path inside an offline cache entry")
+    parser.add_argument("--plugin-cache-dir",
+                        help="Content-addressed plugin cache (default: $BUCK2_PROTOBUF_PLUGIN_CACHE)")
     
     args = parser.parse_args()
     
@@ -664,6 +730,18 @@ def main():
             parser.print_help()
             return
         
+        if args.offline:
+            downloader = PluginDownloader(
+                args.cache_dir or os.path.expanduser("~/.cache/buck2-protobuf"),
+                verbose=args.verbose
+            )
+            binary_path = downloader.resolve_offline(
+                args.plugin, args.version, platform, offline_cache_dir(args.offline_cache_dir),
+                binary_path=args.binary_path, checksum=args.checksum,
+            )
+            print(copy_to_output(binary_path, args.output))
+            return
+        
         # Download plugin using enhanced interface
         binary_path = download_plugin_enhanced(
             plugin=args.plugin,
@@ -672,7 +750,9 @@ def main():
             cache_dir=args.cache_dir,
             registry=args.registry,
             verbose=args.verbose,
-            use_oras=not args.no_oras
+            use_oras=not args.no_oras,
+            checksum=args.checksum,
+            plugin_cache_dir=args.plugin_cache_dir,
         )
         
         # Additional checksum validation if requested (for binary plugins only)
@@ -686,8 +766,11 @@ def main():
                 sys.exit(1)
         
         # Output binary path
-        print(binary_path)
+        print(copy_to_output(binary_path, args.output))
         
+    except OfflineToolError as e:
+        print(f"ERROR: {e}", file=sys.stderr)
+        sys.exit(1)
     except Exception as e:
         print(f"ERROR: Failed to download plugin: {e}", file=sys.stderr)
         sys.exit(1)
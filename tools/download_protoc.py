@@ -21,6 +21,37 @@ import zipfile
 from pathlib import Path
 from typing import Dict, Optional, Tuple
 
+try:
+    from offline_tools import OfflineToolError, ToolSpec, offline_cache_dir, resolve as resolve_offline_tool
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from offline_tools import OfflineToolError, ToolSpec, offline_cache_dir, resolve as resolve_offline_tool
+
+# Marker written next to a cached binary recording the digest of its archive
+CHECKSUM_MARKER = ".sha256"
+
+
+class ChecksumMismatchError(RuntimeError):
+    """Raised when a downloaded archive does not match its pinned SHA256."""
+
+
+def load_lockfile(path: str) -> Dict[str, Dict[str, str]]:
+    """
+    Load pinned protoc checksums from a lockfile.
+
+    The lockfile maps versions to platforms to the SHA256 of the release
+    archive: {"protoc": {"25.1": {"linux-x86_64": "<sha256>"}}}.
+
+    Returns:
+        Dictionary mapping versions to platform checksums
+    """
+    with open(path, "r", encoding="utf-8") as f:
+        data = json.load(f)
+    pins = data.get("protoc") if isinstance(data, dict) else None
+    if not isinstance(pins, dict):
+        raise ValueError(f"{path}: expected a top-level \"protoc\" object")
+    return pins
+
 
 class PlatformDetector:
     """Handles robust platform detection across different environments."""
@@ -64,17 +95,21 @@ class PlatformDetector:
 class ProtocDownloader:
     """Handles downloading, caching, and validation of protoc binaries."""
     
-    def __init__(self, cache_dir: str, verbose: bool = False):
+    def __init__(self, cache_dir: str, verbose: bool = False, lockfile: Optional[str] = None):
         """
         Initialize the downloader.
         
         Args:
             cache_dir: Directory to store cached downloads
             verbose: Enable verbose logging
+            lockfile: Lockfile with pinned checksums; when given, it replaces
+                      the built-in checksums
         """
         self.cache_dir = Path(cache_dir)
         self.cache_dir.mkdir(parents=True, exist_ok=True)
         self.verbose = verbose
+        self.lockfile = lockfile
+        self.pins = load_lockfile(lockfile) if lockfile else None
         
         # Tool configuration database
         self.protoc_config = {
@@ -350,27 +385,62 @@ class ProtocDownloader:
             self.log(f"Extraction error: {e}")
             return False
     
-    def get_cached_binary_path(self, version: str, platform: str) -> Optional[Path]:
+    def resolve_checksum(self, version: str, platform: str, override: Optional[str] = None) -> Optional[str]:
+        """
+        Returns the SHA256 the protoc archive must match, or None if it is not pinned.
+
+        An explicit override wins over the lockfile; the built-in checksums are
+        only used when no lockfile is given.
+        """
+        if override:
+            return override.lower()
+        if self.pins is not None:
+            checksum = self.pins.get(version, {}).get(platform)
+        else:
+            checksum = self.protoc_config.get(version, {}).get(platform, {}).get("sha256")
+        return checksum.lower() if checksum else None
+
+    def get_release_config(self, version: str, platform: str, url: Optional[str] = None) -> Dict[str, str]:
+        """
+        Returns the URL and binary path of a protoc release.
+
+        A URL override (e.g. an air-gapped mirror) also allows versions
+        missing from the built-in configuration.
+        """
+        config = self.protoc_config.get(version, {}).get(platform)
+        if config is None and not url:
+            if version not in self.protoc_config:
+                available_versions = list(self.protoc_config.keys())
+                raise ValueError(f"Unsupported protoc version: {version}. "
+                               f"Available versions: {available_versions}")
+            available_platforms = list(self.protoc_config[version].keys())
+            raise ValueError(f"Unsupported platform: {platform}. "
+                           f"Available platforms: {available_platforms}")
+        binary_path = "bin/protoc.exe" if platform.startswith("windows") else "bin/protoc"
+        return {
+            "url": url or config["url"],
+            "binary_path": config["binary_path"] if config else binary_path,
+        }
+
+    def get_cached_binary_path(self, version: str, platform: str, expected_checksum: Optional[str] = None) -> Optional[Path]:
         """
         Check if protoc binary is already cached and valid.
         
         Args:
             version: Protoc version
             platform: Platform string
+            expected_checksum: Pinned archive SHA256; a cached binary extracted
+                               from a different archive is discarded
             
         Returns:
             Path to cached binary if valid, None otherwise
         """
-        if version not in self.protoc_config:
-            return None
-        
-        if platform not in self.protoc_config[version]:
-            return None
-        
-        config = self.protoc_config[version][platform]
+        # Versions missing from the built-in configuration are cached when installed from a URL override
+        config = self.protoc_config.get(version, {}).get(platform)
+        default_binary_path = "bin/protoc.exe" if platform.startswith("windows") else "bin/protoc"
         cache_key = f"protoc-{version}-{platform}"
         cached_dir = self.cache_dir / cache_key
-        binary_path = cached_dir / config["binary_path"]
+        binary_path = cached_dir / (config["binary_path"] if config else default_binary_path)
         
         if not binary_path.exists():
             return None
@@ -386,45 +456,70 @@ class ProtocDownloader:
             except Exception:
                 return None
         
-        # TODO: In a production system, we might want to validate
-        # the binary checksum or version to ensure cache integrity
+        if expected_checksum:
+            marker = cached_dir / CHECKSUM_MARKER
+            recorded = marker.read_text().strip() if marker.exists() else ""
+            if recorded != expected_checksum:
+                self.log(f"Discarding cached protoc at {cached_dir}: archive checksum does not match the pin")
+                shutil.rmtree(cached_dir, ignore_errors=True)
+                return None
+        
         self.log(f"Using cached protoc at {binary_path}")
         return binary_path
     
-    def download_protoc(self, version: str, platform: str) -> str:
+    def resolve_offline(self, version: str, platform: str, cache_dir: Path, checksum: Optional[str] = None) -> str:
+        """
+        Returns protoc from a pre-populated offline cache, never downloading.
+        
+        Raises:
+            OfflineToolError: If the binary is not in the cache (the message
+                              names the version, expected path and checksum)
+        """
+        config = self.protoc_config.get(version, {}).get(platform, {})
+        spec = ToolSpec(
+            tool="protoc",
+            version=version,
+            platform=platform,
+            binary_path=config.get("binary_path") or ("bin/protoc.exe" if platform.startswith("windows") else "bin/protoc"),
+            sha256=self.resolve_checksum(version, platform, checksum),
+            url=config.get("url"),
+        )
+        binary = resolve_offline_tool(spec, cache_dir)
+        self.log(f"Using offline protoc at {binary}")
+        return str(binary)
+    
+    def download_protoc(self, version: str, platform: str, checksum: Optional[str] = None, url: Optional[str] = None) -> str:
         """
         Download and cache protoc binary for the specified version and platform.
         
+        The release archive is verified against its pinned SHA256 (see
+        resolve_checksum). Archives without a pin are accepted with a warning
+        that lists their computed digest, so it can be added to the lockfile.
+        
         Args:
             version: Protoc version (e.g., "24.4")
             platform: Target platform (e.g., "linux-x86_64")
+            checksum: SHA256 override for the archive
+            url: Download URL override (e.g. an internal mirror)
             
         Returns:
             Path to the downloaded protoc binary
             
         Raises:
             ValueError: If version/platform is not supported
-            RuntimeError: If download or validation fails
+            ChecksumMismatchError: If the archive does not match its pin
+            RuntimeError: If download or extraction fails
         """
-        # Check cache first
-        cached_path = self.get_cached_binary_path(version, platform)
+        expected_checksum = self.resolve_checksum(version, platform, checksum)
+        
+        # Check cache first; the cache is keyed on version and archive checksum, so a
+        # mirror serving other bytes for a pinned version is downloaded and rejected
+        cached_path = self.get_cached_binary_path(version, platform, expected_checksum)
         if cached_path:
             return str(cached_path)
         
-        # Validate version and platform
-        if version not in self.protoc_config:
-            available_versions = list(self.protoc_config.keys())
-            raise ValueError(f"Unsupported protoc version: {version}. "
-                           f"Available versions: {available_versions}")
-        
-        if platform not in self.protoc_config[version]:
-            available_platforms = list(self.protoc_config[version].keys())
-            raise ValueError(f"Unsupported platform: {platform}. "
-                           f"Available platforms: {available_platforms}")
-        
-        config = self.protoc_config[version][platform]
+        config = self.get_release_config(version, platform, url)
         url = config["url"]
-        expected_checksum = config["sha256"]
         binary_path = config["binary_path"]
         
         # Set up paths
@@ -439,9 +534,19 @@ class ProtocDownloader:
                 raise RuntimeError(f"Failed to download {url}")
             
             # Validate checksum
-            if not self.validate_checksum(archive_path, expected_checksum):
+            actual_checksum = self.calculate_sha256(archive_path)
+            if expected_checksum is None:
+                print(f"WARNING: no pinned sha256 for protoc {version} on {platform}; "
+                      f"{url} has sha256 {actual_checksum}. "
+                      f"Add it to {self.lockfile or 'the protoc lockfile'} after verifying the release.",
+                      file=sys.stderr)
+            elif actual_checksum != expected_checksum:
                 archive_path.unlink(missing_ok=True)
-                raise RuntimeError(f"Checksum validation failed for {archive_path}")
+                raise ChecksumMismatchError(
+                    f"sha256 mismatch for protoc {version} on {platform} from {url}: "
+                    f"expected {expected_checksum}, got {actual_checksum}. "
+                    f"The archive may have been tampered with; it was not installed."
+                )
             
             # Extract archive
             if not self.extract_archive(archive_path, cached_dir):
@@ -452,6 +557,7 @@ class ProtocDownloader:
                 raise RuntimeError(f"Binary not found at expected path: {final_binary}")
             
             final_binary.chmod(0o755)
+            (cached_dir / CHECKSUM_MARKER).write_text(actual_checksum + "\n")
             
             # Clean up archive
             archive_path.unlink(missing_ok=True)
@@ -484,7 +590,12 @@ def main():
     parser.add_argument("--version", required=True, help="Protoc version")
     parser.add_argument("--platform", help="Target platform (auto-detected if not specified)")
     parser.add_argument("--cache-dir", required=True, help="Cache directory")
-    parser.add_argument("--checksum", help="Expected SHA256 checksum (for verification)")
+    parser.add_argument("--checksum", help="Expected SHA256 of the release archive (overrides the lockfile)")
+    parser.add_argument("--lockfile", help="JSON lockfile with pinned archive checksums per version and platform")
+    parser.add_argument("--url", help="Download URL override, e.g. an internal mirror")
+    parser.add_argument("--output", help="Copy the binary to this path")
+    parser.add_argument("--offline", action="store_true", help="Resolve from the offline cache only, never download")
+    parser.add_argument("--offline-cache-dir", help="Pre-populated tool cache (default: $BUCK2_PROTOBUF_OFFLINE_CACHE)")
     parser.add_argument("--verbose", "-v", action="store_true", help="Enable verbose output")
     
     args = parser.parse_args()
@@ -498,18 +609,25 @@ def main():
                 print(f"Auto-detected platform: {platform}", file=sys.stderr)
         
         # Create downloader and get binary
-        downloader = ProtocDownloader(args.cache_dir, verbose=args.verbose)
-        binary_path = downloader.download_protoc(args.version, platform)
+        downloader = ProtocDownloader(args.cache_dir, verbose=args.verbose, lockfile=args.lockfile)
+        if args.offline:
+            binary_path = downloader.resolve_offline(
+                args.version, platform, offline_cache_dir(args.offline_cache_dir), checksum=args.checksum)
+        else:
+            binary_path = downloader.download_protoc(args.version, platform, checksum=args.checksum, url=args.url)
         
-        # Additional checksum validation if requested
-        if args.checksum:
-            if not downloader.validate_checksum(Path(binary_path), args.checksum):
-                print(f"ERROR: Checksum validation failed for {binary_path}", file=sys.stderr)
-                sys.exit(1)
+        if args.output:
+            Path(args.output).parent.mkdir(parents=True, exist_ok=True)
+            shutil.copyfile(binary_path, args.output)
+            Path(args.output).chmod(0o755)
+            binary_path = args.output
         
         # Output binary path
         print(binary_path)
         
+    except OfflineToolError as e:
+        print(f"ERROR: {e}", file=sys.stderr)
+        sys.exit(1)
     except Exception as e:
         print(f"ERROR: Failed to download protoc: {e}", file=sys.stderr)
         sys.exit(1)
@@ -0,0 +1,134 @@
+#!/usr/bin/env python3
+"""
+Per-service splitting of protoc-gen-go-grpc output for protobuf Buck2 integration.
+
+protoc-gen-go-grpc writes the stubs of every service of a proto file into one
+<file>_grpc.pb.go. For protos with many services this tool moves each service
+into its own <service>_grpc.pb.go, where <service> is the snake_case service
+name (UserService -> user_service_grpc.pb.go).
+
+protoc-gen-go-grpc emits services one after another, each ending with its
+<Service>_ServiceDesc variable, so a service's section runs from the end of
+the previous one to the end of its ServiceDesc. Every output keeps the file
+header, package clause and version assertion, and only the imports its
+section uses, so the package still compiles.
+
+Usage:
+    go_grpc_split.py --input-dir raw/ --output user_service_grpc.pb.go \\
+        --output admin_service_grpc.pb.go
+    go_grpc_split.py --output user_service_grpc.pb.go renamed/user_grpc.pb.go
+"""
+
+import argparse
+import re
+import sys
+from pathlib import Path
+from typing import Dict, List, Tuple
+
+GRPC_SUFFIX = "_grpc.pb.go"
+
+_SERVICE_DESC = re.compile(r"^var (\w+)_ServiceDesc = grpc\.ServiceDesc\{\n.*?^\}\n", re.M | re.S)
+_VERSION_ASSERTION = re.compile(r"^const _ = grpc\.SupportPackageIsVersion\w+\n", re.M)
+_IMPORT_BLOCK = re.compile(r"^import \(\n(.*?)^\)\n", re.M | re.S)
+_IMPORT_LINE = re.compile(r'^\s*(?:(\w+)\s+)?"([^"]+)"\s*$')
+
+
+class SplitError(Exception):
+    """Raised when generated code cannot be split."""
+
+
+def service_file_name(service: str) -> str:
+    """
+    Returns the file a service is written to.
+
+    Mirrors _grpc_service_file_name in rules/go.bzl, which declares the outputs.
+    """
+    name = service.rsplit(".", 1)[-1]
+    result = ""
+    for i, char in enumerate(name):
+        if char.isupper() and i > 0:
+            previous = name[i - 1]
+            following = name[i + 1] if i + 1 < len(name) else ""
+            if previous.islower() or previous.isdigit() or (previous.isupper() and following.islower()):
+                result += "_"
+        result += char.lower()
+    return result + GRPC_SUFFIX
+
+
+def parse_imports(block: str) -> List[Tuple[str, str]]:
+    """Returns (name, line) of each import, name being the identifier it is used by."""
+    imports = []
+    for line in block.splitlines():
+        match = _IMPORT_LINE.match(line)
+        if match:
+            imports.append((match.group(1) or match.group(2).rsplit("/", 1)[-1], line))
+    return imports
+
+
+def split_services(content: str) -> Dict[str, str]:
+    """
+    Splits one protoc-gen-go-grpc file into one file per service.
+
+    Returns:
+        Map of Go service name to the content of its file
+    """
+    assertion = _VERSION_ASSERTION.search(content)
+    imports_match = _IMPORT_BLOCK.search(content)
+    if not assertion or not imports_match:
+        raise SplitError("not protoc-gen-go-grpc output: missing import block or grpc version assertion")
+    header = content[:imports_match.start()]
+    imports = parse_imports(imports_match.group(1))
+    preamble = content[imports_match.end():assertion.end()]
+
+    files = {}
+    start = assertion.end()
+    for desc in _SERVICE_DESC.finditer(content):
+        section = content[start:desc.end()]
+        start = desc.end()
+        used = [line for name, line in imports
+                if re.search(rf"\b{re.escape(name)}\.", preamble + section)]
+        import_block = "import (\n" + "".join(f"{line}\n" for line in used) + ")\n"
+        files[desc.group(1)] = header + import_block + preamble + section
+    trailer = content[start:].strip()
+    if trailer:
+        raise SplitError(f"unexpected code after the last ServiceDesc: {trailer.splitlines()[0]}")
+    return files
+
+
+def main():
+    """Main entry point for gRPC service splitting."""
+    parser = argparse.ArgumentParser(description="Split protoc-gen-go-grpc output into one file per service")
+    parser.add_argument("--input-dir", help="Directory with protoc-gen-go-grpc output (*_grpc.pb.go)")
+    parser.add_argument("--output", action="append", default=[],
+                        help="Output file; its basename selects the service, e.g. user_service_grpc.pb.go")
+    parser.add_argument("inputs", nargs="*", help="Generated *_grpc.pb.go files")
+    args = parser.parse_args()
+
+    try:
+        inputs = [Path(path) for path in args.inputs]
+        if args.input_dir:
+            inputs += sorted(Path(args.input_dir).rglob("*" + GRPC_SUFFIX))
+        services = {}
+        for path in inputs:
+            for name, content in split_services(path.read_text(encoding="utf-8")).items():
+                file_name = service_file_name(name)
+                if file_name in services:
+                    raise SplitError(f"services {services[file_name][0]} and {name} are both written to {file_name}")
+                services[file_name] = (name, content)
+
+        outputs = {Path(output).name: output for output in args.output}
+        unlisted = sorted(name for file_name, (name, _) in services.items() if file_name not in outputs)
+        if unlisted:
+            raise SplitError(f"services {', '.join(unlisted)} are not listed in grpc_split_services")
+        missing = sorted(set(outputs) - set(services))
+        if missing:
+            raise SplitError(f"protoc-gen-go-grpc did not produce services for {', '.join(missing)}")
+        for file_name, output in outputs.items():
+            Path(output).write_text(services[file_name][1], encoding="utf-8")
+    except (SplitError, OSError) as e:
+        print(f"ERROR: go_grpc_split: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,2098 @@
+#!/usr/bin/env python3
+"""
+Go helper code generation for protobuf Buck2 integration.
+
+This tool generates optional Go helper files that sit next to the output of
+protoc-gen-go in the same package. Each helper is produced by a registered
+generator and written to its own file (`<base>_<suffix>.pb.go`), so opting in
+to a helper never changes the canonical generated code.
+
+Generators receive the parsed schema (see proto_schema.py), the resolved Go
+package and a generator-specific configuration supplied by go_proto_library.
+
+Usage:
+    go_helper_gen.py --generator json_casing --config config.json \\
+        --go-package github.com/org/user/v1 --output user_json.pb.go user.proto
+"""
+
+import argparse
+import gzip
+import json
+import re
+import sys
+from dataclasses import dataclass, field
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional, Set, Tuple
+
+try:
+    from proto_schema import Enum, EnumValue, Message, Method, ProtoFile, ProtoParseError, SchemaSet, Service, find_option, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import Enum, EnumValue, Message, Method, ProtoFile, ProtoParseError, SchemaSet, Service, find_option, load_schema_set
+
+
+class GeneratorConfigError(Exception):
+    """Raised when a generator is misconfigured."""
+
+
+@dataclass
+class GoFile:
+    """A generated Go source file."""
+    package: str
+    source: str
+    generator: str
+    imports: Dict[str, str] = field(default_factory=dict)  # import path -> alias
+    header: List[str] = field(default_factory=list)
+    body: List[str] = field(default_factory=list)
+    build_constraint: str = ""  # //go:build expression, if the file is conditional
+
+    def add_import(self, path: str, alias: str = "") -> None:
+        self.imports[path] = alias
+
+    def add(self, code: str) -> None:
+        self.body.append(code.strip("\n"))
+
+    def render(self) -> str:
+        lines = [f"//go:build {self.build_constraint}", ""] if self.build_constraint else []
+        lines += [
+            f"// Code generated by buck2-protobuf go_helper_gen ({self.generator}). DO NOT EDIT.",
+            f"// source: {self.source}",
+        ]
+        if self.header:
+            lines.append("//")
+            lines.extend(f"// {line}".rstrip() for line in self.header)
+        lines.extend(["", f"package {self.package}", ""])
+
+        if self.imports:
+            std = sorted(i for i in self.imports if "." not in i.split("/")[0])
+            external = sorted(i for i in self.imports if "." in i.split("/")[0])
+            lines.append("import (")
+            lines.extend(self._import_line(i) for i in std)
+            if std and external:
+                lines.append("")
+            lines.extend(self._import_line(i) for i in external)
+            lines.extend([")", ""])
+
+        lines.append("\n\n".join(self.body))
+        return "\n".join(lines).rstrip() + "\n"
+
+    def _import_line(self, path: str) -> str:
+        alias = self.imports[path]
+        return f'\t{alias} "{path}"' if alias else f'\t"{path}"'
+
+
+@dataclass
+class GeneratorContext:
+    """Everything a generator needs to produce one Go file."""
+    schema: SchemaSet
+    proto_file: ProtoFile
+    go_package: str
+    config: Dict[str, Any]
+
+    @property
+    def package_name(self) -> str:
+        return go_package_name(self.go_package, self.proto_file)
+
+    @property
+    def file_ident(self) -> str:
+        """Unexported identifier prefix unique to the source file within the package."""
+        return lower_first(go_camel_case(Path(self.proto_file.path).stem))
+
+    def new_file(self, generator: str) -> GoFile:
+        return GoFile(package=self.package_name, source=self.proto_file.path, generator=generator)
+
+    def messages(self) -> List[Message]:
+        """Returns messages that have a generated Go struct (map entries excluded)."""
+        return [m for m in self.proto_file.all_messages() if not m.is_map_entry]
+
+    def go_type_name(self, message: Message) -> str:
+        return go_type_name(message.full_name, self.proto_file.package)
+
+
+GeneratorFunction = Callable[[GeneratorContext], Optional[GoFile]]
+
+
+@dataclass
+class Generator:
+    """A registered helper generator."""
+    name: str
+    suffix: str
+    description: str
+    func: GeneratorFunction
+
+
+GENERATORS: Dict[str, Generator] = {}
+
+
+def register_generator(name: str, suffix: str, description: str) -> Callable[[GeneratorFunction], GeneratorFunction]:
+    """Registers a generator that writes `<base>_<suffix>.pb.go` for each proto file."""
+    def decorator(func: GeneratorFunction) -> GeneratorFunction:
+        GENERATORS[name] = Generator(name=name, suffix=suffix, description=description, func=func)
+        return func
+    return decorator
+
+
+# Go naming helpers (mirroring protoc-gen-go)
+
+
+def go_camel_case(name: str) -> str:
+    """Converts a proto identifier to a Go identifier the way protoc-gen-go does."""
+    result = []
+    i = 0
+    while i < len(name):
+        c = name[i]
+        if c == "." and i + 1 < len(name) and name[i + 1].islower():
+            pass
+        elif c == ".":
+            result.append("_")
+        elif c == "_" and (i == 0 or name[i - 1] == "."):
+            result.append("X")
+        elif c == "_" and i + 1 < len(name) and name[i + 1].islower():
+            pass
+        elif c.isdigit():
+            result.append(c)
+        else:
+            result.append(c.upper() if c.islower() else c)
+            while i + 1 < len(name) and name[i + 1].islower():
+                i += 1
+                result.append(name[i])
+        i += 1
+    return "".join(result)
+
+
+def lower_first(name: str) -> str:
+    return name[:1].lower() + name[1:]
+
+
+def go_type_name(full_name: str, package: str) -> str:
+    """Returns the Go type name for a message or enum relative to its proto package."""
+    relative = full_name[len(package) + 1:] if package and full_name.startswith(package + ".") else full_name
+    return go_camel_case(relative.replace(".", "_")) if "." in relative else go_camel_case(relative)
+
+
+# Method names of generated messages that struct fields must not shadow
+RESERVED_METHOD_NAMES = [
+    "Reset", "String", "ProtoMessage", "Marshal", "Unmarshal",
+    "ExtensionRangeArray", "ExtensionMap", "Descriptor",
+]
+
+
+def go_field_names(message: Message) -> Tuple[Dict[str, str], Dict[str, str]]:
+    """
+    Returns the Go struct field names protoc-gen-go derives for a message.
+
+    In declaration order, a field name is made unique by appending "_" while it
+    (or "Get" + it) is already used; a oneof name is made unique right after
+    its first member, ignoring getters.
+
+    Returns:
+        Tuple of ({proto field name: Go name}, {oneof name: Go name})
+    """
+    used: Dict[str, bool] = {name: True for name in RESERVED_METHOD_NAMES}
+
+    def unique(name: str, has_getter: bool) -> str:
+        while used.get(name) or (has_getter and used.get("Get" + name)):
+            name += "_"
+        used[name] = True
+        if has_getter:
+            used["Get" + name] = True
+        return name
+
+    fields: Dict[str, str] = {}
+    oneofs: Dict[str, str] = {}
+    for message_field in message.fields:
+        fields[message_field.name] = unique(go_camel_case(message_field.name), True)
+        if message_field.oneof and message_field.oneof not in oneofs:
+            oneofs[message_field.oneof] = unique(go_camel_case(message_field.oneof), False)
+    return fields, oneofs
+
+
+def go_package_name(go_package: str, proto_file: ProtoFile) -> str:
+    """Derives the Go package name from an import path (honouring "path;name")."""
+    import_path = go_package or proto_file.options.get("go_package", "") or proto_file.package
+    if ";" in import_path:
+        name = import_path.split(";", 1)[1]
+    else:
+        name = import_path.rstrip("/").split("/")[-1]
+    name = re.sub(r"[^A-Za-z0-9_]", "_", name) or "proto"
+    return "_" + name if name[0].isdigit() else name
+
+
+# Generators
+
+
+def _json_casing_name_func(ident: str, casing: str) -> str:
+    """Returns the Go function converting a proto field name to the configured casing."""
+    separator = {"snake": "_", "kebab": "-"}.get(casing, "")
+    first = "strings.ToUpper(word[:1]) + word[1:]" if casing == "pascal" else "strings.ToLower(word)"
+    rest = "strings.ToLower(word)" if separator else "strings.ToUpper(word[:1]) + word[1:]"
+    return f"""// {ident}JSONName converts a proto field name to its JSON key.
+func {ident}JSONName(name string) string {{
+\twords := strings.FieldsFunc(name, func(r rune) bool {{ return r == '_' }})
+\tfor i, word := range words {{
+\t\tif i == 0 {{
+\t\t\twords[i] = {first}
+\t\t}} else {{
+\t\t\twords[i] = {rest}
+\t\t}}
+\t}}
+\treturn strings.Join(words, "{separator}")
+}}"""
+
+
+JSON_CASINGS = ["proto", "snake", "kebab", "camel", "pascal"]
+
+
+@register_generator("json_casing", "json", "MarshalJSON/UnmarshalJSON with configurable field name casing")
+def generate_json_casing(ctx: GeneratorContext) -> Optional[GoFile]:
+    casing = ctx.config.get("casing", "proto")
+    if casing not in JSON_CASINGS:
+        raise GeneratorConfigError(f"unsupported JSON casing '{casing}' (expected one of: {', '.join(JSON_CASINGS)})")
+
+    messages = ctx.messages()
+    if not messages:
+        return None
+
+    ident = ctx.file_ident
+    out = ctx.new_file("json_casing")
+    if casing == "proto":
+        out.header = [
+            "JSON field names are the proto field names instead of protojson's lowerCamelCase",
+            "(protojson with UseProtoNames): json_name options are ignored when encoding, and",
+            "decoding accepts proto field names and JSON names alike. Keys keep protojson's order.",
+        ]
+    else:
+        out.header = [
+            f"JSON field names use {casing} casing instead of protojson's lowerCamelCase.",
+            "This diverges from canonical protojson: json_name options are ignored,",
+            "object keys are emitted in sorted order, and only the configured casing",
+            "(or the original proto field name) is accepted when decoding.",
+            "Use protojson directly where canonical proto3 JSON is required.",
+        ]
+    out.add_import("google.golang.org/protobuf/encoding/protojson")
+    out.add_import("google.golang.org/protobuf/proto")
+
+    if casing == "proto":
+        out.add(f"""
+// {ident}MarshalJSON encodes m with proto field names as JSON keys.
+func {ident}MarshalJSON(m proto.Message) ([]byte, error) {{
+\treturn protojson.MarshalOptions{{UseProtoNames: true}}.Marshal(m)
+}}
+
+// {ident}UnmarshalJSON decodes data keyed by proto field names into m.
+func {ident}UnmarshalJSON(data []byte, m proto.Message) error {{
+\treturn protojson.Unmarshal(data, m)
+}}""")
+    else:
+        for path in ("bytes", "encoding/json", "strings", "google.golang.org/protobuf/reflect/protoreflect"):
+            out.add_import(path)
+        out.add(_json_casing_name_func(ident, casing))
+        out.add(f"""
+// {ident}RenameJSON rewrites the keys of a protojson object tree for md. When
+// encode is true proto field names become cased names; otherwise the reverse.
+// Well-known types keep their special JSON forms and are left untouched.
+func {ident}RenameJSON(md protoreflect.MessageDescriptor, tree map[string]any, encode bool) map[string]any {{
+\tif md.ParentFile().Package() == "google.protobuf" {{
+\t\treturn tree
+\t}}
+\tout := make(map[string]any, len(tree))
+\tfor key, value := range tree {{
+\t\tout[key] = value
+\t}}
+\tfields := md.Fields()
+\tfor i := 0; i < fields.Len(); i++ {{
+\t\tfd := fields.Get(i)
+\t\tfrom, to := string(fd.TextName()), {ident}JSONName(string(fd.TextName()))
+\t\tif !encode {{
+\t\t\tfrom, to = to, from
+\t\t}}
+\t\tvalue, ok := out[from]
+\t\tif !ok {{
+\t\t\tcontinue
+\t\t}}
+\t\tdelete(out, from)
+\t\tout[to] = {ident}RenameJSONValue(fd, value, encode)
+\t}}
+\treturn out
+}}
+
+// {ident}RenameJSONValue renames keys inside message-typed field values.
+func {ident}RenameJSONValue(fd protoreflect.FieldDescriptor, value any, encode bool) any {{
+\tswitch {{
+\tcase fd.IsMap():
+\t\tentries, ok := value.(map[string]any)
+\t\tif !ok || fd.MapValue().Message() == nil {{
+\t\t\treturn value
+\t\t}}
+\t\tfor key, entry := range entries {{
+\t\t\tif object, ok := entry.(map[string]any); ok {{
+\t\t\t\tentries[key] = {ident}RenameJSON(fd.MapValue().Message(), object, encode)
+\t\t\t}}
+\t\t}}
+\tcase fd.Message() == nil:
+\t\treturn value
+\tcase fd.IsList():
+\t\telements, ok := value.([]any)
+\t\tif !ok {{
+\t\t\treturn value
+\t\t}}
+\t\tfor i, element := range elements {{
+\t\t\tif object, ok := element.(map[string]any); ok {{
+\t\t\t\telements[i] = {ident}RenameJSON(fd.Message(), object, encode)
+\t\t\t}}
+\t\t}}
+\tdefault:
+\t\tif object, ok := value.(map[string]any); ok {{
+\t\t\treturn {ident}RenameJSON(fd.Message(), object, encode)
+\t\t}}
+\t}}
+\treturn value
+}}
+
+// {ident}DecodeTree decodes a JSON object, preserving number precision.
+func {ident}DecodeTree(data []byte) (map[string]any, error) {{
+\tdecoder := json.NewDecoder(bytes.NewReader(data))
+\tdecoder.UseNumber()
+\tvar tree map[string]any
+\tif err := decoder.Decode(&tree); err != nil {{
+\t\treturn nil, err
+\t}}
+\treturn tree, nil
+}}
+
+// {ident}MarshalJSON encodes m with {casing}-cased JSON keys.
+func {ident}MarshalJSON(m proto.Message) ([]byte, error) {{
+\tdata, err := protojson.MarshalOptions{{UseProtoNames: true}}.Marshal(m)
+\tif err != nil {{
+\t\treturn nil, err
+\t}}
+\ttree, err := {ident}DecodeTree(data)
+\tif err != nil {{
+\t\treturn nil, err
+\t}}
+\treturn json.Marshal({ident}RenameJSON(m.ProtoReflect().Descriptor(), tree, true))
+}}
+
+// {ident}UnmarshalJSON decodes {casing}-cased JSON into m.
+func {ident}UnmarshalJSON(data []byte, m proto.Message) error {{
+\ttree, err := {ident}DecodeTree(data)
+\tif err != nil {{
+\t\treturn err
+\t}}
+\tdata, err = json.Marshal({ident}RenameJSON(m.ProtoReflect().Descriptor(), tree, false))
+\tif err != nil {{
+\t\treturn err
+\t}}
+\treturn protojson.Unmarshal(data, m)
+}}""")
+
+    for message in messages:
+        type_name = ctx.go_type_name(message)
+        out.add(f"""
+// MarshalJSON implements json.Marshaler using {casing} field name casing.
+func (x *{type_name}) MarshalJSON() ([]byte, error) {{
+\treturn {ident}MarshalJSON(x)
+}}
+
+// UnmarshalJSON implements json.Unmarshaler using {casing} field name casing.
+func (x *{type_name}) UnmarshalJSON(data []byte) error {{
+\treturn {ident}UnmarshalJSON(data, x)
+}}""")
+
+    return out
+
+
+# Supported JSON formats per well-known type; the first entry is protojson's default
+WKT_JSON_FORMATS = {
+    "timestamp": ["rfc3339", "epoch_millis", "epoch_seconds"],
+    "duration": ["string", "seconds", "millis"],
+}
+
+# Go expressions converting seconds/nanos (int64) to the JSON value
+_WKT_ENCODE = {
+    ("timestamp", "epoch_millis"): "seconds*1000 + nanos/1000000",
+    ("timestamp", "epoch_seconds"): "seconds",
+    ("duration", "seconds"): "float64(seconds) + float64(nanos)/1e9",
+    ("duration", "millis"): "seconds*1000 + nanos/1000000",
+}
+
+# Go statements converting n (json.Number) back to the protojson string form
+_WKT_DECODE = {
+    ("timestamp", "epoch_millis"): ("Int64", "time.UnixMilli(v).UTC().Format(time.RFC3339Nano)"),
+    ("timestamp", "epoch_seconds"): ("Int64", "time.Unix(v, 0).UTC().Format(time.RFC3339Nano)"),
+    ("duration", "seconds"): ("Float64", 'strconv.FormatFloat(v, \'f\', -1, 64) + "s"'),
+    ("duration", "millis"): ("Int64", 'strconv.FormatFloat(float64(v)/1000, \'f\', -1, 64) + "s"'),
+}
+
+_WKT_FULL_NAMES = {"timestamp": "google.protobuf.Timestamp", "duration": "google.protobuf.Duration"}
+
+
+@register_generator("wkt_json", "wkt_json", "MarshalJSON/UnmarshalJSON rendering Timestamp and Duration in legacy formats")
+def generate_wkt_json(ctx: GeneratorContext) -> Optional[GoFile]:
+    formats = {}
+    for wkt, value in ctx.config.get("formats", {}).items():
+        if wkt not in WKT_JSON_FORMATS:
+            raise GeneratorConfigError(f"unsupported well-known type '{wkt}' (expected one of: {', '.join(WKT_JSON_FORMATS)})")
+        if value not in WKT_JSON_FORMATS[wkt]:
+            raise GeneratorConfigError(f"unsupported {wkt} format '{value}' (expected one of: {', '.join(WKT_JSON_FORMATS[wkt])})")
+        if value != WKT_JSON_FORMATS[wkt][0]:
+            formats[wkt] = value
+    if not formats:
+        raise GeneratorConfigError("wkt_json requires at least one non-default format")
+
+    messages = ctx.messages()
+    if not messages:
+        return None
+
+    ident = ctx.file_ident
+    out = ctx.new_file("wkt_json")
+    out.header = [
+        "JSON encoding follows protojson except for these well-known types:",
+    ] + [f"  {_WKT_FULL_NAMES[wkt]}: {value}" for wkt, value in sorted(formats.items())] + [
+        "Object keys are emitted in sorted order. Decoding also accepts the",
+        "protojson forms. Use protojson directly where canonical JSON is required.",
+    ]
+    for path in ("bytes", "encoding/json", "google.golang.org/protobuf/encoding/protojson",
+                 "google.golang.org/protobuf/proto", "google.golang.org/protobuf/reflect/protoreflect"):
+        out.add_import(path)
+    if "timestamp" in formats:
+        out.add_import("time")
+    if "duration" in formats:
+        out.add_import("strconv")
+
+    encode_cases, decode_cases = [], []
+    for wkt, value in sorted(formats.items(), reverse=True):
+        encode_cases.append(f"""\tcase "{_WKT_FULL_NAMES[wkt]}":
+\t\tfields := m.Descriptor().Fields()
+\t\tseconds, nanos := m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int()
+\t\treturn {_WKT_ENCODE[(wkt, value)]}""")
+        parse, expr = _WKT_DECODE[(wkt, value)]
+        decode_cases.append(f"""\tcase "{_WKT_FULL_NAMES[wkt]}":
+\t\tif n, ok := value.(json.Number); ok {{
+\t\t\tif v, err := n.{parse}(); err == nil {{
+\t\t\t\treturn {expr}
+\t\t\t}}
+\t\t}}
+\t\treturn value""")
+    encode_switch = "\n".join(encode_cases)
+    decode_switch = "\n".join(decode_cases)
+
+    out.add(f"""
+// {ident}WKTEncode rewrites well-known type values in the protojson object tree of m.
+func {ident}WKTEncode(m protoreflect.Message, tree map[string]any) {{
+\tm.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {{
+\t\tvalue, ok := tree[fd.JSONName()]
+\t\tif !ok {{
+\t\t\treturn true
+\t\t}}
+\t\tswitch {{
+\t\tcase fd.IsMap():
+\t\t\tentries, ok := value.(map[string]any)
+\t\t\tif !ok || fd.MapValue().Message() == nil {{
+\t\t\t\treturn true
+\t\t\t}}
+\t\t\tv.Map().Range(func(key protoreflect.MapKey, entry protoreflect.Value) bool {{
+\t\t\t\tif element, ok := entries[key.String()]; ok {{
+\t\t\t\t\tentries[key.String()] = {ident}WKTEncodeValue(entry.Message(), element)
+\t\t\t\t}}
+\t\t\t\treturn true
+\t\t\t}})
+\t\tcase fd.Message() == nil:
+\t\tcase fd.IsList():
+\t\t\telements, _ := value.([]any)
+\t\t\tlist := v.List()
+\t\t\tfor i := 0; i < list.Len() && i < len(elements); i++ {{
+\t\t\t\telements[i] = {ident}WKTEncodeValue(list.Get(i).Message(), elements[i])
+\t\t\t}}
+\t\tdefault:
+\t\t\ttree[fd.JSONName()] = {ident}WKTEncodeValue(v.Message(), value)
+\t\t}}
+\t\treturn true
+\t}})
+}}
+
+// {ident}WKTEncodeValue converts a well-known type value, or recurses into a message.
+func {ident}WKTEncodeValue(m protoreflect.Message, value any) any {{
+\tswitch m.Descriptor().FullName() {{
+{encode_switch}
+\t}}
+\tif object, ok := value.(map[string]any); ok {{
+\t\t{ident}WKTEncode(m, object)
+\t}}
+\treturn value
+}}
+
+// {ident}WKTDecode rewrites well-known type values in tree back to their protojson forms.
+func {ident}WKTDecode(md protoreflect.MessageDescriptor, tree map[string]any) {{
+\tfields := md.Fields()
+\tfor i := 0; i < fields.Len(); i++ {{
+\t\tfd := fields.Get(i)
+\t\tfor _, key := range []string{{fd.JSONName(), string(fd.Name())}} {{
+\t\t\tvalue, ok := tree[key]
+\t\t\tif !ok {{
+\t\t\t\tcontinue
+\t\t\t}}
+\t\t\tswitch {{
+\t\t\tcase fd.IsMap():
+\t\t\t\tentries, ok := value.(map[string]any)
+\t\t\t\tif !ok || fd.MapValue().Message() == nil {{
+\t\t\t\t\tcontinue
+\t\t\t\t}}
+\t\t\t\tfor entryKey, entry := range entries {{
+\t\t\t\t\tentries[entryKey] = {ident}WKTDecodeValue(fd.MapValue().Message(), entry)
+\t\t\t\t}}
+\t\t\tcase fd.Message() == nil:
+\t\t\tcase fd.IsList():
+\t\t\t\telements, _ := value.([]any)
+\t\t\t\tfor j, element := range elements {{
+\t\t\t\t\telements[j] = {ident}WKTDecodeValue(fd.Message(), element)
+\t\t\t\t}}
+\t\t\tdefault:
+\t\t\t\ttree[key] = {ident}WKTDecodeValue(fd.Message(), value)
+\t\t\t}}
+\t\t}}
+\t}}
+}}
+
+// {ident}WKTDecodeValue converts a legacy well-known type value, or recurses into a message.
+func {ident}WKTDecodeValue(md protoreflect.MessageDescriptor, value any) any {{
+\tswitch md.FullName() {{
+{decode_switch}
+\t}}
+\tif object, ok := value.(map[string]any); ok {{
+\t\t{ident}WKTDecode(md, object)
+\t}}
+\treturn value
+}}
+
+// {ident}WKTTree decodes a JSON object, preserving number precision.
+func {ident}WKTTree(data []byte) (map[string]any, error) {{
+\tdecoder := json.NewDecoder(bytes.NewReader(data))
+\tdecoder.UseNumber()
+\tvar tree map[string]any
+\tif err := decoder.Decode(&tree); err != nil {{
+\t\treturn nil, err
+\t}}
+\treturn tree, nil
+}}
+
+// {ident}WKTMarshalJSON encodes m as protojson with legacy well-known type formats.
+func {ident}WKTMarshalJSON(m proto.Message) ([]byte, error) {{
+\tdata, err := protojson.Marshal(m)
+\tif err != nil {{
+\t\treturn nil, err
+\t}}
+\ttree, err := {ident}WKTTree(data)
+\tif err != nil {{
+\t\treturn nil, err
+\t}}
+\t{ident}WKTEncode(m.ProtoReflect(), tree)
+\treturn json.Marshal(tree)
+}}
+
+// {ident}WKTUnmarshalJSON decodes JSON with legacy or protojson well-known type formats into m.
+func {ident}WKTUnmarshalJSON(data []byte, m proto.Message) error {{
+\ttree, err := {ident}WKTTree(data)
+\tif err != nil {{
+\t\treturn err
+\t}}
+\t{ident}WKTDecode(m.ProtoReflect().Descriptor(), tree)
+\tdata, err = json.Marshal(tree)
+\tif err != nil {{
+\t\treturn err
+\t}}
+\treturn protojson.Unmarshal(data, m)
+}}""")
+
+    for message in messages:
+        type_name = ctx.go_type_name(message)
+        out.add(f"""
+// MarshalJSON implements json.Marshaler with legacy well-known type formats.
+func (x *{type_name}) MarshalJSON() ([]byte, error) {{
+\treturn {ident}WKTMarshalJSON(x)
+}}
+
+// UnmarshalJSON implements json.Unmarshaler with legacy well-known type formats.
+func (x *{type_name}) UnmarshalJSON(data []byte) error {{
+\treturn {ident}WKTUnmarshalJSON(data, x)
+}}""")
+
+    return out
+
+
+GRPC_METRICS_BACKENDS = ["prometheus", "otel"]
+
+
+@register_generator("grpc_observability", "observability", "gRPC server constructors with channelz and metrics interceptors")
+def generate_grpc_observability(ctx: GeneratorContext) -> Optional[GoFile]:
+    backend = ctx.config.get("metrics", "prometheus")
+    if backend not in GRPC_METRICS_BACKENDS:
+        raise GeneratorConfigError(f"unsupported metrics backend '{backend}' (expected one of: {', '.join(GRPC_METRICS_BACKENDS)})")
+
+    services = ctx.proto_file.services
+    if not services:
+        return None
+
+    out = ctx.new_file("grpc_observability")
+    out.add_import("google.golang.org/grpc")
+    out.add_import("google.golang.org/grpc/channelz/service", "channelzsvc")
+    if backend == "prometheus":
+        out.add_import("errors")
+        out.add_import("github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus", "grpcprom")
+        out.add_import("github.com/prometheus/client_golang/prometheus")
+    else:
+        out.add_import("go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc")
+        out.add_import("go.opentelemetry.io/otel/metric")
+
+    for service in services:
+        go_name = go_camel_case(service.name)
+        # Services without streaming methods never reach a stream interceptor
+        stream_option = "\n\t\tgrpc.ChainStreamInterceptor(metrics.StreamServerInterceptor())," if service.has_streaming else ""
+        if backend == "prometheus":
+            out.add(f"""
+// New{go_name}ObservableServer creates a gRPC server serving srv with channelz
+// enabled and Prometheus request metrics registered in registry. Server
+// metrics already present in registry are reused, so several services can
+// share one registry.
+func New{go_name}ObservableServer(srv {go_name}Server, registry prometheus.Registerer, opts ...grpc.ServerOption) (*grpc.Server, error) {{
+\tmetrics := grpcprom.NewServerMetrics()
+\tif err := registry.Register(metrics); err != nil {{
+\t\tvar registered prometheus.AlreadyRegisteredError
+\t\tif !errors.As(err, &registered) {{
+\t\t\treturn nil, err
+\t\t}}
+\t\texisting, ok := registered.ExistingCollector.(*grpcprom.ServerMetrics)
+\t\tif !ok {{
+\t\t\treturn nil, err
+\t\t}}
+\t\tmetrics = existing
+\t}}
+\topts = append(opts,
+\t\tgrpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor()),{stream_option}
+\t)
+\tserver := grpc.NewServer(opts...)
+\tRegister{go_name}Server(server, srv)
+\tchannelzsvc.RegisterChannelzServiceToServer(server)
+\tmetrics.InitializeMetrics(server)
+\treturn server, nil
+}}""")
+        else:
+            out.add(f"""
+// New{go_name}ObservableServer creates a gRPC server serving srv with channelz
+// enabled and OpenTelemetry RPC metrics recorded through provider.
+func New{go_name}ObservableServer(srv {go_name}Server, provider metric.MeterProvider, opts ...grpc.ServerOption) (*grpc.Server, error) {{
+\topts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithMeterProvider(provider))))
+\tserver := grpc.NewServer(opts...)
+\tRegister{go_name}Server(server, srv)
+\tchannelzsvc.RegisterChannelzServiceToServer(server)
+\treturn server, nil
+}}""")
+
+    return out
+
+
+# Interceptors available to grpc_server_interceptors, outermost first
+GRPC_SERVER_INTERCEPTORS = ["metrics", "logging", "recovery", "validation"]
+
+
+@register_generator("grpc_server_interceptors", "interceptors", "DefaultServerOptions() chaining the standard gRPC server interceptors")
+def generate_grpc_server_interceptors(ctx: GeneratorContext) -> Optional[GoFile]:
+    selected = ctx.config.get("interceptors", GRPC_SERVER_INTERCEPTORS)
+    unknown = [name for name in selected if name not in GRPC_SERVER_INTERCEPTORS]
+    if unknown:
+        raise GeneratorConfigError(f"unsupported interceptors: {', '.join(unknown)} (expected any of: {', '.join(GRPC_SERVER_INTERCEPTORS)})")
+    if not selected:
+        raise GeneratorConfigError("at least one interceptor must be selected")
+
+    # DefaultServerOptions is package-level; emit it once, next to the first file with services
+    with_services = [f for f in ctx.schema.files if f.services]
+    if not with_services or ctx.proto_file is not with_services[0]:
+        return None
+
+    interceptors = [name for name in GRPC_SERVER_INTERCEPTORS if name in selected]
+    # The options are shared by all services of the package; stream interceptors
+    # are only needed if one of them streams
+    streaming = any(service.has_streaming for f in ctx.schema.files for service in f.services)
+    out = ctx.new_file("grpc_server_interceptors")
+    out.add_import("google.golang.org/grpc")
+    params, setup, unary, stream = [], [], [], []
+    if "metrics" in interceptors:
+        out.add_import("errors")
+        out.add_import("github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus", "grpcprom")
+        out.add_import("github.com/prometheus/client_golang/prometheus")
+        params.append("registry prometheus.Registerer")
+        setup.append("""
+\tmetrics := grpcprom.NewServerMetrics()
+\tif err := registry.Register(metrics); err != nil {
+\t\tvar registered prometheus.AlreadyRegisteredError
+\t\tif !errors.As(err, &registered) {
+\t\t\treturn nil, err
+\t\t}
+\t\texisting, ok := registered.ExistingCollector.(*grpcprom.ServerMetrics)
+\t\tif !ok {
+\t\t\treturn nil, err
+\t\t}
+\t\tmetrics = existing
+\t}""")
+        unary.append("metrics.UnaryServerInterceptor()")
+        stream.append("metrics.StreamServerInterceptor()")
+    if "logging" in interceptors:
+        out.add_import("context")
+        out.add_import("log/slog")
+        out.add_import("github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging")
+        params.append("logger *slog.Logger")
+        setup.append("""
+\tlog := logging.LoggerFunc(func(ctx context.Context, level logging.Level, msg string, fields ...any) {
+\t\tlogger.Log(ctx, slog.Level(level), msg, fields...)
+\t})""")
+        unary.append("logging.UnaryServerInterceptor(log)")
+        stream.append("logging.StreamServerInterceptor(log)")
+    if "recovery" in interceptors:
+        out.add_import("github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery")
+        unary.append("recovery.UnaryServerInterceptor()")
+        stream.append("recovery.StreamServerInterceptor()")
+    if "validation" in interceptors:
+        out.add_import("github.com/bufbuild/protovalidate-go")
+        out.add_import("github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/protovalidate", "protovalidatemw")
+        setup.append("""
+\tvalidator, err := protovalidate.New()
+\tif err != nil {
+\t\treturn nil, err
+\t}""")
+        unary.append("protovalidatemw.UnaryServerInterceptor(validator)")
+        stream.append("protovalidatemw.StreamServerInterceptor(validator)")
+
+    notes = {
+        "metrics": "Prometheus request metrics are registered in registry; server metrics already\n// present in registry are reused, so several servers can share one registry.",
+        "logging": "Requests are logged to logger.",
+        "recovery": "Panics in handlers are converted to codes.Internal errors.",
+        "validation": "Requests failing their (buf.validate) rules are rejected with\n// codes.InvalidArgument.",
+    }
+    doc = "\n// ".join(notes[name] for name in interceptors)
+    unary_lines = "".join(f"\n\t\t\t{i}," for i in unary)
+    stream_option = ""
+    if streaming:
+        stream_lines = "".join(f"\n\t\t\t{i}," for i in stream)
+        stream_option = f"\n\t\tgrpc.ChainStreamInterceptor({stream_lines}\n\t\t),"
+    out.add(f"""
+// DefaultServerOptions returns server options chaining the standard
+// interceptors, outermost first: {", ".join(interceptors)}.
+//
+// {doc}
+func DefaultServerOptions({", ".join(params)}) ([]grpc.ServerOption, error) {{{"".join(setup)}
+\treturn []grpc.ServerOption{{
+\t\tgrpc.ChainUnaryInterceptor({unary_lines}
+\t\t),{stream_option}
+\t}}, nil
+}}""")
+    return out
+
+
+REDACT_GO_PACKAGE = "github.com/birbparty/buck2-protobuf/proto/buck2protobuf/redact/v1"
+REDACT_MASK = "[REDACTED]"
+
+
+@register_generator("redaction", "redact", "Redact() methods that clear or mask fields annotated as PII")
+def generate_redaction(ctx: GeneratorContext) -> Optional[GoFile]:
+    first = ctx.proto_file is ctx.schema.files[0]
+    messages = ctx.messages()
+    if not messages and not first:
+        return None
+
+    out = ctx.new_file("redaction")
+    out.add_import("google.golang.org/protobuf/proto")
+
+    for message in messages:
+        go_name = ctx.go_type_name(message)
+        out.add(f"""
+// Redact returns a copy of x in which fields annotated with
+// (buck2protobuf.redact.v1.pii) are cleared or masked, recursing into nested
+// messages. The copy is safe to log; x is not modified.
+func (x *{go_name}) Redact() *{go_name} {{
+\tif x == nil {{
+\t\treturn nil
+\t}}
+\tc := proto.Clone(x).(*{go_name})
+\tredactPIIMessage(c.ProtoReflect())
+\treturn c
+}}""")
+
+    # The reflection-based walker is package-level; emit it once, next to the first proto file
+    if first:
+        out.add_import("google.golang.org/protobuf/reflect/protoreflect")
+        out.add_import(REDACT_GO_PACKAGE, "redactv1")
+        out.add(f"""
+// redactPIIMask replaces string values of fields annotated with REDACTION_MASK.
+const redactPIIMask = {json.dumps(REDACT_MASK)}
+
+// redactPIIMessage redacts the PII fields of m in place. Annotations are read
+// from the field descriptors, so messages from other packages are handled too.
+func redactPIIMessage(m protoreflect.Message) {{
+\tfields := m.Descriptor().Fields()
+\tfor i := 0; i < fields.Len(); i++ {{
+\t\tfd := fields.Get(i)
+\t\tif !m.Has(fd) {{
+\t\t\tcontinue
+\t\t}}
+\t\tswitch proto.GetExtension(fd.Options(), redactv1.E_Pii).(redactv1.Redaction) {{
+\t\tcase redactv1.Redaction_REDACTION_CLEAR:
+\t\t\tm.Clear(fd)
+\t\tcase redactv1.Redaction_REDACTION_MASK:
+\t\t\tredactPIIMaskField(m, fd)
+\t\tdefault:
+\t\t\tredactPIINested(m, fd)
+\t\t}}
+\t}}
+}}
+
+// redactPIIMaskField masks string values of fd and clears any other kind.
+func redactPIIMaskField(m protoreflect.Message, fd protoreflect.FieldDescriptor) {{
+\tmask := protoreflect.ValueOfString(redactPIIMask)
+\tswitch {{
+\tcase fd.IsMap() && fd.MapValue().Kind() == protoreflect.StringKind:
+\t\tentries := m.Mutable(fd).Map()
+\t\tvar keys []protoreflect.MapKey
+\t\tentries.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {{
+\t\t\tkeys = append(keys, k)
+\t\t\treturn true
+\t\t}})
+\t\tfor _, k := range keys {{
+\t\t\tentries.Set(k, mask)
+\t\t}}
+\tcase fd.IsList() && fd.Kind() == protoreflect.StringKind:
+\t\titems := m.Mutable(fd).List()
+\t\tfor i := 0; i < items.Len(); i++ {{
+\t\t\titems.Set(i, mask)
+\t\t}}
+\tcase !fd.IsMap() && !fd.IsList() && fd.Kind() == protoreflect.StringKind:
+\t\tm.Set(fd, mask)
+\tdefault:
+\t\tm.Clear(fd)
+\t}}
+}}
+
+// redactPIINested redacts messages held by a field that is not itself PII.
+func redactPIINested(m protoreflect.Message, fd protoreflect.FieldDescriptor) {{
+\tswitch {{
+\tcase fd.IsMap():
+\t\tif fd.MapValue().Message() == nil {{
+\t\t\treturn
+\t\t}}
+\t\tm.Get(fd).Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {{
+\t\t\tredactPIIMessage(v.Message())
+\t\t\treturn true
+\t\t}})
+\tcase fd.IsList():
+\t\tif fd.Message() == nil {{
+\t\t\treturn
+\t\t}}
+\t\titems := m.Get(fd).List()
+\t\tfor i := 0; i < items.Len(); i++ {{
+\t\t\tredactPIIMessage(items.Get(i).Message())
+\t\t}}
+\tcase fd.Message() != nil:
+\t\tredactPIIMessage(m.Get(fd).Message())
+\t}}
+}}""")
+
+    return out
+
+
+def recursive_messages(schema: SchemaSet) -> Set[str]:
+    """Returns the full names of messages that can (transitively) contain themselves."""
+    edges: Dict[str, Set[str]] = {}
+    for proto_file in schema.all_files:
+        for message in proto_file.all_messages():
+            targets = set()
+            for message_field in message.fields:
+                type_name = message_field.map_value_type if message_field.is_map else message_field.type_name
+                resolved = schema.resolve_type(type_name, message.full_name)
+                if isinstance(resolved, Message):
+                    targets.add(resolved.full_name)
+            edges[message.full_name] = targets
+
+    recursive = set()
+    for start in edges:
+        stack, seen = list(edges[start]), set()
+        while stack:
+            name = stack.pop()
+            if name == start:
+                recursive.add(start)
+                break
+            if name not in seen:
+                seen.add(name)
+                stack.extend(edges.get(name, ()))
+    return recursive
+
+
+@register_generator("recursion_guard", "depth", "UnmarshalSafe methods enforcing a max nesting depth on recursive messages")
+def generate_recursion_guard(ctx: GeneratorContext) -> Optional[GoFile]:
+    max_depth = ctx.config.get("max_depth", 100)
+    if not isinstance(max_depth, int) or max_depth <= 0:
+        raise GeneratorConfigError("recursion_guard requires a positive integer max_depth")
+
+    recursive = recursive_messages(ctx.schema)
+    messages = [m for m in ctx.messages() if m.full_name in recursive]
+    if not messages:
+        return None
+
+    out = ctx.new_file("recursion_guard")
+    out.add_import("google.golang.org/protobuf/proto")
+    for message in messages:
+        go_name = ctx.go_type_name(message)
+        out.add(f"""
+// UnmarshalSafe parses the wire-format message in b into x like
+// proto.Unmarshal, but returns an error if messages are nested more than
+// {max_depth} levels deep. {go_name} is recursive, so adversarial input could
+// otherwise nest it deeply enough to exhaust the stack.
+func (x *{go_name}) UnmarshalSafe(b []byte) error {{
+\treturn proto.UnmarshalOptions{{RecursionLimit: {max_depth}}}.Unmarshal(b, x)
+}}""")
+    return out
+
+
+GRPC_LIMITS_OPTION = "buck2protobuf.grpc.v1.message_limits"
+
+
+def _message_limit(service: Service, limits: Dict[str, Any], key: str) -> int:
+    value = limits.get(key, 0)
+    if not isinstance(value, int) or isinstance(value, bool) or value < 0:
+        raise GeneratorConfigError(f"({GRPC_LIMITS_OPTION}).{key} on {service.full_name} must be a non-negative integer")
+    return value
+
+
+@register_generator("grpc_message_limits", "grpc_limits", "Server options applying message size limits from (buck2protobuf.grpc.v1.message_limits)")
+def generate_grpc_message_limits(ctx: GeneratorContext) -> Optional[GoFile]:
+    annotated = []
+    for service in ctx.proto_file.services:
+        limits = find_option(service.options, GRPC_LIMITS_OPTION)
+        if isinstance(limits, dict):
+            recv = _message_limit(service, limits, "max_recv_bytes")
+            send = _message_limit(service, limits, "max_send_bytes")
+            if recv or send:
+                annotated.append((service, recv, send))
+    if not annotated:
+        return None
+
+    out = ctx.new_file("grpc_message_limits")
+    out.add_import("google.golang.org/grpc")
+    for service, recv, send in annotated:
+        go_name = go_camel_case(service.name)
+        constants, options = [], []
+        if recv:
+            constants.append(f"""
+// {go_name}MaxRecvMsgSize is the largest request {go_name} accepts, in bytes.
+const {go_name}MaxRecvMsgSize = {recv}""")
+            options.append(f"grpc.MaxRecvMsgSize({go_name}MaxRecvMsgSize)")
+        if send:
+            constants.append(f"""
+// {go_name}MaxSendMsgSize is the largest response {go_name} sends, in bytes.
+const {go_name}MaxSendMsgSize = {send}""")
+            options.append(f"grpc.MaxSendMsgSize({go_name}MaxSendMsgSize)")
+        for constant in constants:
+            out.add(constant)
+        option_lines = "".join(f"\n\t\t{option}," for option in options)
+        out.add(f"""
+// {go_name}ServerOptions returns server options applying the message size
+// limits declared with ({GRPC_LIMITS_OPTION}) on {service.full_name}.
+// The limits apply to every service registered on the server.
+func {go_name}ServerOptions() []grpc.ServerOption {{
+\treturn []grpc.ServerOption{{{option_lines}
+\t}}
+}}""")
+    return out
+
+
+CLIENT_TIMEOUT_OPTION = "buck2protobuf.deadline.v1.timeout_ms"
+
+
+def _method_timeouts(proto_file: ProtoFile) -> List[Tuple[Service, List[Tuple[Method, int]]]]:
+    """Returns the services of a file with their methods annotated with a non-zero timeout."""
+    annotated = []
+    for service in proto_file.services:
+        methods = []
+        for method in service.methods:
+            timeout = find_option(method.options, CLIENT_TIMEOUT_OPTION)
+            if timeout is None:
+                continue
+            if not isinstance(timeout, int) or isinstance(timeout, bool) or timeout < 0:
+                raise GeneratorConfigError(f"({CLIENT_TIMEOUT_OPTION}) on {method.full_name} must be a non-negative integer")
+            if timeout:
+                methods.append((method, timeout))
+        if methods:
+            annotated.append((service, methods))
+    return annotated
+
+
+def _go_duration(milliseconds: int) -> str:
+    if milliseconds % 1000 == 0:
+        return f"{milliseconds // 1000} * time.Second"
+    return f"{milliseconds} * time.Millisecond"
+
+
+@register_generator("grpc_client_timeouts", "client_timeouts", "Client wrappers applying method timeouts from (buck2protobuf.deadline.v1.timeout_ms)")
+def generate_grpc_client_timeouts(ctx: GeneratorContext) -> Optional[GoFile]:
+    annotated = _method_timeouts(ctx.proto_file)
+    if not annotated:
+        return None
+
+    out = ctx.new_file("grpc_client_timeouts")
+    out.add_import("time")
+    out.add_import("google.golang.org/grpc")
+
+    # The connection wrapper is package-level; emit it once, next to the first annotated file
+    first = next(f for f in ctx.schema.files if f is ctx.proto_file or _method_timeouts(f))
+    if first is ctx.proto_file:
+        out.add_import("context")
+        out.add("""
+// timeoutClientConn applies per-method timeouts, keyed by full method name,
+// to calls whose context has no deadline.
+type timeoutClientConn struct {
+\tcc       grpc.ClientConnInterface
+\ttimeouts map[string]time.Duration
+}
+
+func (c timeoutClientConn) timeout(ctx context.Context, method string) (time.Duration, bool) {
+\tif _, ok := ctx.Deadline(); ok {
+\t\treturn 0, false
+\t}
+\ttimeout, ok := c.timeouts[method]
+\treturn timeout, ok
+}
+
+func (c timeoutClientConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+\tif timeout, ok := c.timeout(ctx, method); ok {
+\t\tvar cancel context.CancelFunc
+\t\tctx, cancel = context.WithTimeout(ctx, timeout)
+\t\tdefer cancel()
+\t}
+\treturn c.cc.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream bounds the whole stream by the method timeout.
+func (c timeoutClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+\ttimeout, ok := c.timeout(ctx, method)
+\tif !ok {
+\t\treturn c.cc.NewStream(ctx, desc, method, opts...)
+\t}
+\tctx, cancel := context.WithTimeout(ctx, timeout)
+\tstream, err := c.cc.NewStream(ctx, desc, method, opts...)
+\tif err != nil {
+\t\tcancel()
+\t\treturn nil, err
+\t}
+\t// Release the timer as soon as the stream finishes
+\tgo func() {
+\t\t<-stream.Context().Done()
+\t\tcancel()
+\t}()
+\treturn stream, nil
+}""")
+
+    for service, methods in annotated:
+        go_name = go_camel_case(service.name)
+        keys = [f"{go_name}_{go_camel_case(method.name)}_FullMethodName:" for method, _ in methods]
+        # Align values the way gofmt does
+        width = max(len(key) for key in keys)
+        entries = "".join(
+            f"\n\t{key.ljust(width)} {_go_duration(timeout)},"
+            for key, (_, timeout) in zip(keys, methods)
+        )
+        out.add(f"""
+// {go_name}MethodTimeouts maps each {go_name} method annotated with
+// ({CLIENT_TIMEOUT_OPTION}) to its timeout.
+var {go_name}MethodTimeouts = map[string]time.Duration{{{entries}
+}}
+
+// New{go_name}TimeoutClient returns a {go_name}Client that applies
+// {go_name}MethodTimeouts as the deadline of calls whose context has none.
+// Calls that already carry a deadline keep it.
+func New{go_name}TimeoutClient(cc grpc.ClientConnInterface) {go_name}Client {{
+\treturn New{go_name}Client(timeoutClientConn{{cc: cc, timeouts: {go_name}MethodTimeouts}})
+}}""")
+    return out
+
+
+KEEPALIVE_OPTION = "buck2protobuf.keepalive.v1.keepalive"
+
+# Keepalive settings to the fields of the grpc-go keepalive structs they set
+KEEPALIVE_SERVER_PARAMETERS = [
+    ("max_connection_idle_ms", "MaxConnectionIdle"),
+    ("max_connection_age_ms", "MaxConnectionAge"),
+    ("max_connection_age_grace_ms", "MaxConnectionAgeGrace"),
+    ("time_ms", "Time"),
+    ("timeout_ms", "Timeout"),
+]
+KEEPALIVE_CLIENT_PARAMETERS = [("time_ms", "Time"), ("timeout_ms", "Timeout"), ("permit_without_stream", "PermitWithoutStream")]
+KEEPALIVE_DURATIONS = {"time_ms", "timeout_ms", "min_time_ms", "max_connection_idle_ms", "max_connection_age_ms", "max_connection_age_grace_ms"}
+
+
+def _keepalive_settings(service: Service) -> Dict[str, Any]:
+    """Returns the non-zero keepalive settings annotated on a service."""
+    settings = find_option(service.options, KEEPALIVE_OPTION)
+    if not isinstance(settings, dict):
+        return {}
+    for key, value in settings.items():
+        if key == "permit_without_stream":
+            if not isinstance(value, bool):
+                raise GeneratorConfigError(f"({KEEPALIVE_OPTION}).{key} on {service.full_name} must be a bool")
+        elif key in KEEPALIVE_DURATIONS:
+            if not isinstance(value, int) or isinstance(value, bool) or value < 0:
+                raise GeneratorConfigError(f"({KEEPALIVE_OPTION}).{key} on {service.full_name} must be a non-negative integer")
+        else:
+            raise GeneratorConfigError(f"({KEEPALIVE_OPTION}) on {service.full_name} has unknown field '{key}'")
+    return {key: value for key, value in settings.items() if value}
+
+
+def _go_struct_literal(type_name: str, fields: List[Tuple[str, str]], indent: str) -> str:
+    """Renders a multi-line composite literal with values aligned the way gofmt does."""
+    width = max(len(name) for name, _ in fields) + 1
+    lines = "".join(f"\n{indent}\t{(name + ':').ljust(width)} {value}," for name, value in fields)
+    return f"{type_name}{{{lines}\n{indent}}}"
+
+
+@register_generator("grpc_keepalive", "keepalive", "Server and dial options applying keepalive settings from (buck2protobuf.keepalive.v1.keepalive)")
+def generate_grpc_keepalive(ctx: GeneratorContext) -> Optional[GoFile]:
+    annotated = []
+    for service in ctx.proto_file.services:
+        settings = _keepalive_settings(service)
+        if settings:
+            annotated.append((service, settings))
+    if not annotated:
+        return None
+
+    out = ctx.new_file("grpc_keepalive")
+    out.add_import("google.golang.org/grpc")
+    out.add_import("google.golang.org/grpc/keepalive")
+
+    def value(settings: Dict[str, Any], key: str) -> str:
+        if key == "permit_without_stream":
+            return "true"
+        out.add_import("time")
+        return _go_duration(settings[key])
+
+    indent = "\t\t"
+    for service, settings in annotated:
+        go_name = go_camel_case(service.name)
+        server_options = []
+        server_fields = [(field, value(settings, key)) for key, field in KEEPALIVE_SERVER_PARAMETERS if key in settings]
+        if server_fields:
+            server_options.append(f"grpc.KeepaliveParams({_go_struct_literal('keepalive.ServerParameters', server_fields, indent)})")
+        # Without an explicit minimum, allow clients to ping as often as the
+        # annotation tells them to; the gRPC default of 5 minutes would make the
+        # server drop clients configured from the same annotation
+        min_time_key = "min_time_ms" if "min_time_ms" in settings else "time_ms"
+        policy_fields = [(field, value(settings, key)) for key, field in [(min_time_key, "MinTime"), ("permit_without_stream", "PermitWithoutStream")]
+                         if key in settings]
+        if policy_fields:
+            server_options.append(f"grpc.KeepaliveEnforcementPolicy({_go_struct_literal('keepalive.EnforcementPolicy', policy_fields, indent)})")
+        if server_options:
+            option_lines = "".join(f"\n\t\t{option}," for option in server_options)
+            out.add(f"""
+// {go_name}KeepaliveServerOptions returns server options applying the
+// keepalive settings declared with ({KEEPALIVE_OPTION}) on
+// {service.full_name}.
+//
+// Keepalive is configured per server, so the settings apply to every service
+// registered on it.
+func {go_name}KeepaliveServerOptions() []grpc.ServerOption {{
+\treturn []grpc.ServerOption{{{option_lines}
+\t}}
+}}""")
+
+        client_fields = [(field, value(settings, key)) for key, field in KEEPALIVE_CLIENT_PARAMETERS if key in settings]
+        if client_fields:
+            out.add(f"""
+// {go_name}KeepaliveDialOptions returns dial options applying the client
+// side of the keepalive settings declared with
+// ({KEEPALIVE_OPTION}) on {service.full_name}.
+func {go_name}KeepaliveDialOptions() []grpc.DialOption {{
+\treturn []grpc.DialOption{{
+\t\tgrpc.WithKeepaliveParams({_go_struct_literal('keepalive.ClientParameters', client_fields, indent)}),
+\t}}
+}}""")
+    return out
+
+
+STATUS_CODE_OPTION = "buck2protobuf.errors.v1.grpc_code"
+
+# Canonical gRPC code names (google.rpc.Code) to google.golang.org/grpc/codes constants
+GRPC_CODES = {
+    "CANCELLED": "Canceled", "UNKNOWN": "Unknown", "INVALID_ARGUMENT": "InvalidArgument",
+    "DEADLINE_EXCEEDED": "DeadlineExceeded", "NOT_FOUND": "NotFound", "ALREADY_EXISTS": "AlreadyExists",
+    "PERMISSION_DENIED": "PermissionDenied", "RESOURCE_EXHAUSTED": "ResourceExhausted",
+    "FAILED_PRECONDITION": "FailedPrecondition", "ABORTED": "Aborted", "OUT_OF_RANGE": "OutOfRange",
+    "UNIMPLEMENTED": "Unimplemented", "INTERNAL": "Internal", "UNAVAILABLE": "Unavailable",
+    "DATA_LOSS": "DataLoss", "UNAUTHENTICATED": "Unauthenticated",
+}
+
+# Methods added to annotated messages; fields with these Go names would clash
+STATUS_ERROR_METHODS = ["Error", "GRPCStatus"]
+
+
+def _error_messages(proto_file: ProtoFile, option: str) -> List[Tuple[Message, str]]:
+    """Returns the messages of a file annotated with a status code, with their codes constant."""
+    annotated = []
+    for message in proto_file.all_messages():
+        code = find_option(message.options, option)
+        if code is None:
+            continue
+        if code not in GRPC_CODES:
+            raise GeneratorConfigError(
+                f"({option}) on {message.full_name} must be one of {', '.join(GRPC_CODES)}, got {code!r}")
+        field_names, oneof_names = go_field_names(message)
+        clashes = [n for n in STATUS_ERROR_METHODS if n in field_names.values() or n in oneof_names.values()]
+        if clashes:
+            raise GeneratorConfigError(
+                f"{message.full_name} has a field named {clashes[0]}, which clashes with the generated error method")
+        annotated.append((message, GRPC_CODES[code]))
+    return annotated
+
+
+@register_generator("grpc_status_codes", "status", "error and GRPCStatus methods for messages annotated with (buck2protobuf.errors.v1.grpc_code)")
+def generate_grpc_status_codes(ctx: GeneratorContext) -> Optional[GoFile]:
+    option = (ctx.config.get("option") or STATUS_CODE_OPTION).strip("()")
+    annotated = _error_messages(ctx.proto_file, option)
+    if not annotated:
+        return None
+
+    out = ctx.new_file("grpc_status_codes")
+    out.add_import("google.golang.org/grpc/codes")
+    out.add_import("google.golang.org/grpc/status")
+
+    # StatusFromErr is package-level; emit it once, next to the first annotated file
+    first = next(f for f in ctx.schema.files if f is ctx.proto_file or _error_messages(f, option))
+    if first is ctx.proto_file:
+        out.add_import("context")
+        out.add_import("errors")
+        out.add(f"""
+// StatusFromErr converts err to a gRPC status. The first error in err's chain
+// that carries a status wins, including messages annotated with
+// ({option}).
+// Context cancellation and deadline errors map to their codes and any other
+// error to codes.Unknown. A nil error yields nil.
+func StatusFromErr(err error) *status.Status {{
+\tif err == nil {{
+\t\treturn nil
+\t}}
+\tvar withStatus interface{{ GRPCStatus() *status.Status }}
+\tif errors.As(err, &withStatus) {{
+\t\treturn withStatus.GRPCStatus()
+\t}}
+\tif errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {{
+\t\treturn status.FromContextError(err)
+\t}}
+\treturn status.New(codes.Unknown, err.Error())
+}}""")
+
+    for message, code in annotated:
+        go_name = ctx.go_type_name(message)
+        out.add(f"""
+// Error implements error, so that a {go_name} can be returned as a domain error.
+func (x *{go_name}) Error() string {{
+\treturn "{message.full_name}: " + x.String()
+}}
+
+// GRPCStatus returns a codes.{code} status carrying x as a detail.
+func (x *{go_name}) GRPCStatus() *status.Status {{
+\tst := status.New(codes.{code}, x.Error())
+\tif detailed, err := st.WithDetails(x); err == nil {{
+\t\treturn detailed
+\t}}
+\treturn st
+}}""")
+    return out
+
+
+RETRY_OPTION = "buck2protobuf.retry.v1.retry"
+
+
+def _retry_codes(proto_file: ProtoFile) -> List[Tuple[Service, List[Tuple[Method, List[str]]]]]:
+    """Returns the services of a file with the retryable code constants of their annotated methods."""
+    annotated = []
+    for service in proto_file.services:
+        methods = []
+        for method in service.methods:
+            policy = find_option(method.options, RETRY_OPTION)
+            if policy is None:
+                continue
+            names = policy.get("retryable_codes", []) if isinstance(policy, dict) else policy
+            if isinstance(names, str):
+                names = [names]
+            if not isinstance(names, list) or not all(isinstance(name, str) for name in names):
+                raise GeneratorConfigError(f"({RETRY_OPTION}) on {method.full_name} must set retryable_codes "
+                                           f"to gRPC code names, got {policy!r}")
+            unknown = [name for name in names if name not in GRPC_CODES]
+            if unknown:
+                raise GeneratorConfigError(f"({RETRY_OPTION}) on {method.full_name}: retryable_codes must be "
+                                           f"among {', '.join(GRPC_CODES)}, got {unknown[0]!r}")
+            duplicates = sorted({name for name in names if names.count(name) > 1})
+            if duplicates:
+                raise GeneratorConfigError(f"({RETRY_OPTION}) on {method.full_name} lists {duplicates[0]} twice")
+            if names:
+                methods.append((method, [GRPC_CODES[name] for name in names]))
+        if methods:
+            annotated.append((service, methods))
+    return annotated
+
+
+@register_generator("grpc_retry_codes", "retry", "IsRetryable(method, err) classifying failures by the codes in (buck2protobuf.retry.v1.retry)")
+def generate_grpc_retry_codes(ctx: GeneratorContext) -> Optional[GoFile]:
+    annotated = _retry_codes(ctx.proto_file)
+    if not annotated:
+        return None
+
+    out = ctx.new_file("grpc_retry_codes")
+    out.add_import("google.golang.org/grpc/codes")
+
+    # The classifier is package-level; emit it once, next to the first annotated file
+    package_services = [service for f in ctx.schema.files for service, _ in _retry_codes(f)]
+    first = next(f for f in ctx.schema.files if f is ctx.proto_file or _retry_codes(f))
+    if first is ctx.proto_file:
+        out.add_import("google.golang.org/grpc/status")
+        maps = "".join(f"\n\t{go_camel_case(service.name)}RetryableCodes," for service in package_services)
+        out.add(f"""
+// retryableCodes holds the <Service>RetryableCodes maps of the package.
+var retryableCodes = []map[string][]codes.Code{{{maps}
+}}
+
+// IsRetryable reports whether a failed call may be retried: err must carry a
+// status code listed in the ({RETRY_OPTION}) annotation of method,
+// the full method name a client interceptor receives. Calls of methods
+// without the annotation are never retried.
+func IsRetryable(method string, err error) bool {{
+\tif err == nil {{
+\t\treturn false
+\t}}
+\tcode := status.Code(err)
+\tfor _, methods := range retryableCodes {{
+\t\tfor _, retryable := range methods[method] {{
+\t\t\tif code == retryable {{
+\t\t\t\treturn true
+\t\t\t}}
+\t\t}}
+\t}}
+\treturn false
+}}""")
+
+    for service, methods in annotated:
+        go_name = go_camel_case(service.name)
+        keys = [f"{go_name}_{go_camel_case(method.name)}_FullMethodName:" for method, _ in methods]
+        # Align values the way gofmt does
+        width = max(len(key) for key in keys)
+        entries = "".join(
+            f"\n\t{key.ljust(width)} {{{', '.join('codes.' + code for code in method_codes)}}},"
+            for key, (_, method_codes) in zip(keys, methods)
+        )
+        out.add(f"""
+// {go_name}RetryableCodes maps each {go_name} method annotated with
+// ({RETRY_OPTION}) to the status codes its calls may be retried on.
+var {go_name}RetryableCodes = map[string][]codes.Code{{{entries}
+}}""")
+    return out
+
+
+ENUM_STRING_OPTION = "buck2protobuf.enums.v1.string_value"
+
+
+def _enum_strings(proto_file: ProtoFile, option: str) -> List[Tuple[Enum, List[Tuple[EnumValue, str]]]]:
+    """Returns the enums of a file with annotated values, with each value's external string."""
+    annotated = []
+    for enum in proto_file.all_enums():
+        values = []
+        seen: Dict[str, str] = {}
+        for value in enum.values:
+            external = find_option(value.options, option)
+            if external is None:
+                continue
+            if not isinstance(external, str) or not external:
+                raise GeneratorConfigError(
+                    f"({option}) on {enum.full_name}.{value.name} must be a non-empty string, got {external!r}")
+            if external in seen:
+                raise GeneratorConfigError(
+                    f"{enum.full_name}.{value.name} and {enum.full_name}.{seen[external]} "
+                    f"share the external string {external!r}")
+            seen[external] = value.name
+            values.append((value, external))
+        if values:
+            annotated.append((enum, values))
+    return annotated
+
+
+@register_generator("enum_strings", "enum_strings", "MarshalText/UnmarshalText on enums using external strings from (buck2protobuf.enums.v1.string_value)")
+def generate_enum_strings(ctx: GeneratorContext) -> Optional[GoFile]:
+    option = (ctx.config.get("option") or ENUM_STRING_OPTION).strip("()")
+    annotated = _enum_strings(ctx.proto_file, option)
+    if not annotated:
+        return None
+
+    out = ctx.new_file("enum_strings")
+    out.add_import("fmt")
+    package = ctx.proto_file.package
+    for enum, values in annotated:
+        go_name = go_type_name(enum.full_name, package)
+        # protoc-gen-go prefixes value constants with the enclosing message, or the enum for top-level enums
+        prefix = go_type_name(enum.parent, package) if enum.parent else go_name
+        constants = [(f"{prefix}_{value.name}", value.number, external) for value, external in values]
+
+        # Aliases share a number; the first annotated alias gives the marshaled string
+        to_string, numbers = [], set()
+        for constant, number, external in constants:
+            if number not in numbers:
+                numbers.add(number)
+                to_string.append((constant, json.dumps(external)))
+        from_string = [(json.dumps(external), constant) for constant, _, external in constants]
+        out.add(f"""
+// {go_name}ExternalStrings maps values of {enum.full_name} to their ({option}).
+var {go_name}ExternalStrings = {_go_struct_literal(f"map[{go_name}]string", to_string, "")}
+
+// {go_name}ExternalValues maps the ({option}) strings of {enum.full_name} to their values.
+var {go_name}ExternalValues = {_go_struct_literal(f"map[string]{go_name}", from_string, "")}
+
+// MarshalText implements encoding.TextMarshaler with the external string of x.
+// Values without an external string are an error.
+func (x {go_name}) MarshalText() ([]byte, error) {{
+\tif s, ok := {go_name}ExternalStrings[x]; ok {{
+\t\treturn []byte(s), nil
+\t}}
+\treturn nil, fmt.Errorf("{enum.full_name}: value %d has no external string", int32(x))
+}}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the external
+// strings of {enum.full_name} only.
+func (x *{go_name}) UnmarshalText(text []byte) error {{
+\tv, ok := {go_name}ExternalValues[string(text)]
+\tif !ok {{
+\t\treturn fmt.Errorf("{enum.full_name}: unknown external string %q", text)
+\t}}
+\t*x = v
+\treturn nil
+}}""")
+    return out
+
+
+@register_generator("arena", "arena", "New<Message>Arena constructors allocating messages in a Go arena")
+def generate_arena(ctx: GeneratorContext) -> Optional[GoFile]:
+    messages = ctx.messages()
+    if not messages:
+        return None
+
+    out = ctx.new_file("arena")
+    # The arena package only exists with GOEXPERIMENT=arenas
+    out.build_constraint = "goexperiment.arenas"
+    out.header.extend([
+        "Messages allocated in an arena are freed together by arena.Free. Neither",
+        "the messages nor any pointer, slice or string taken from them may be used",
+        "after the arena is freed.",
+    ])
+    out.add_import("arena")
+    for message in messages:
+        go_name = ctx.go_type_name(message)
+        out.add(f"""
+// New{go_name}Arena returns a zero {go_name} allocated in a. It must not be
+// used or retained after a.Free().
+func New{go_name}Arena(a *arena.Arena) *{go_name} {{
+\treturn arena.New[{go_name}](a)
+}}""")
+    return out
+
+
+# Wire type of each scalar type (google.golang.org/protobuf/encoding/protowire)
+SCALAR_WIRE_TYPES = {
+    "int32": "VarintType", "int64": "VarintType", "uint32": "VarintType", "uint64": "VarintType",
+    "sint32": "VarintType", "sint64": "VarintType", "bool": "VarintType",
+    "fixed64": "Fixed64Type", "sfixed64": "Fixed64Type", "double": "Fixed64Type",
+    "fixed32": "Fixed32Type", "sfixed32": "Fixed32Type", "float": "Fixed32Type",
+    "string": "BytesType", "bytes": "BytesType",
+}
+
+
+def _is_packed(proto_file: ProtoFile, message_field) -> bool:
+    """Returns whether a repeated scalar or enum field uses packed encoding."""
+    packed = find_option(message_field.options, "packed")
+    if packed is not None:
+        return packed is True
+    if proto_file.syntax == "editions":
+        encoding = find_option(message_field.options, "features.repeated_field_encoding")
+        encoding = encoding or find_option(proto_file.options, "features.repeated_field_encoding")
+        return encoding != "EXPANDED"
+    return proto_file.syntax == "proto3"
+
+
+def field_wire_type(ctx: GeneratorContext, message: Message, message_field) -> str:
+    """Returns the protowire type constant a field is encoded with."""
+    if message_field.is_map:
+        return "BytesType"
+    if message_field.is_group:
+        return "StartGroupType"
+    if message_field.is_scalar:
+        wire_type = SCALAR_WIRE_TYPES[message_field.type_name]
+    else:
+        resolved = ctx.schema.resolve_type(message_field.type_name, message.full_name)
+        if resolved is None:
+            raise GeneratorConfigError(f"cannot resolve type {message_field.type_name} of field {message_field.full_name}")
+        wire_type = "BytesType" if isinstance(resolved, Message) else "VarintType"
+    if message_field.label == "repeated" and wire_type != "BytesType" and _is_packed(ctx.proto_file, message_field):
+        return "BytesType"
+    return wire_type
+
+
+@register_generator("field_index", "field_index", "<Message>FieldIndex maps from field name to number, wire type and struct offset")
+def generate_field_index(ctx: GeneratorContext) -> Optional[GoFile]:
+    first = ctx.proto_file is ctx.schema.files[0]
+    messages = ctx.messages()
+    if not messages and not first:
+        return None
+
+    out = ctx.new_file("field_index")
+    out.add_import("google.golang.org/protobuf/encoding/protowire")
+    if messages:
+        out.add_import("unsafe")
+
+    # The entry type is package-level; emit it once, next to the first proto file
+    if first:
+        out.add("""
+// FieldIndex describes how a field is encoded and where it is stored in the
+// generated struct. Number and WireType follow the schema and are as stable
+// as its wire format. Offset is computed by the Go compiler for the current
+// build and must not be persisted or shared between processes: it changes
+// with the architecture, the protoc-gen-go version and the field order.
+type FieldIndex struct {
+\t// Number is the field number.
+\tNumber protowire.Number
+\t// WireType is the wire type of the field's tag; packed repeated fields use BytesType.
+\tWireType protowire.Type
+\t// Offset is the byte offset of the struct field holding the value. For
+\t// oneof members it is the offset of the oneof interface field.
+\tOffset uintptr
+\t// Oneof is the proto name of the oneof containing the field, if any.
+\tOneof string
+}""")
+
+    for message in messages:
+        go_name = ctx.go_type_name(message)
+        field_go_names, oneof_go_names = go_field_names(message)
+        entries = []
+        for message_field in message.fields:
+            wire_type = field_wire_type(ctx, message, message_field)
+            if message_field.oneof:
+                member = oneof_go_names[message_field.oneof]
+                oneof = f", Oneof: {json.dumps(message_field.oneof)}"
+            else:
+                member = field_go_names[message_field.name]
+                oneof = ""
+            # One entry per block keeps gofmt from aligning keys
+            entries.append(
+                f"\n\t{json.dumps(message_field.name)}: {{\n\t\tNumber: {message_field.number}, "
+                f"WireType: protowire.{wire_type}, Offset: unsafe.Offsetof({go_name}{{}}.{member}){oneof},\n\t}},"
+            )
+        closing = "\n}" if entries else "}"
+        out.add(f"""
+// {go_name}FieldIndex maps the proto field names of {message.full_name}
+// to their encoding and struct offset.
+var {go_name}FieldIndex = map[string]FieldIndex{{{"".join(entries)}{closing}""")
+    return out
+
+
+INIT_HOOK_RE = re.compile(r"^(?:(?P<path>[^\s\"]+)\.)?(?P<func>[A-Za-z_][A-Za-z0-9_]*)$")
+
+
+def parse_init_hook(hook: str) -> Tuple[str, str, str]:
+    """
+    Splits an init hook reference into (import path, package alias, function).
+
+    "github.com/acme/registry.Register" refers to an exported function of
+    another package; a bare "register" refers to a function in the generated
+    package itself and has an empty import path.
+    """
+    match = INIT_HOOK_RE.match(hook)
+    if not match or (match.group("path") and "." in match.group("path").rsplit("/", 1)[-1]):
+        raise GeneratorConfigError(f"init hook must be \"import/path.Func\" or \"localFunc\", got '{hook}'")
+    import_path, func = match.group("path") or "", match.group("func")
+    if not import_path:
+        return "", "", func
+    if not func[0].isupper():
+        raise GeneratorConfigError(f"init hook {hook}: {func} must be exported to be called from another package")
+    # Alias the import so the call does not depend on the package clause;
+    # major version suffixes ("/v2") are skipped like the go tool does
+    elements = [e for e in import_path.split("/") if not re.fullmatch(r"v[0-9]+", e)] or ["hook"]
+    alias = re.sub(r"[^A-Za-z0-9_]", "_", elements[-1])
+    return import_path, "_" + alias if alias[0].isdigit() else alias, func
+
+
+@register_generator("init_hook", "init_hook", "init() passing each file descriptor to a configured registration function")
+def generate_init_hook(ctx: GeneratorContext) -> Optional[GoFile]:
+    hook = ctx.config.get("hook", "")
+    if not hook:
+        raise GeneratorConfigError("init_hook requires a 'hook' function")
+    import_path, alias, func = parse_init_hook(hook)
+
+    # The descriptor is reached through a generated type; protoc-gen-go emits
+    # no Go type for files declaring only services or extensions
+    messages = ctx.messages()
+    if messages:
+        descriptor = f"(&{ctx.go_type_name(messages[0])}{{}}).ProtoReflect().Descriptor().ParentFile()"
+    elif ctx.proto_file.enums:
+        descriptor = f"{ctx.go_type_name(ctx.proto_file.enums[0])}(0).Descriptor().ParentFile()"
+    else:
+        return None
+
+    out = ctx.new_file("init_hook")
+    if import_path:
+        out.add_import(import_path, alias)
+        func = f"{alias}.{func}"
+    out.add(f"""
+// init passes the descriptor of {ctx.proto_file.path} to {func}. It runs
+// after the init of the protoc-gen-go output for the same file, whose name
+// sorts first, so the descriptor is fully built.
+func init() {{
+\t{func}({descriptor})
+}}""")
+    return out
+
+
+# Descriptor embedding: a minimal protobuf wire format reader, enough to split
+# a FileDescriptorSet into files without a protobuf runtime
+
+
+def _read_varint(data: bytes, pos: int) -> Tuple[int, int]:
+    value, shift = 0, 0
+    while True:
+        if pos >= len(data):
+            raise ValueError("truncated varint in descriptor set")
+        byte = data[pos]
+        pos += 1
+        value |= (byte & 0x7F) << shift
+        if not byte & 0x80:
+            return value, pos
+        shift += 7
+
+
+def _wire_fields(data: bytes) -> List[Tuple[int, bytes, bytes]]:
+    """Splits a message into (field number, raw field bytes, length-delimited payload) tuples."""
+    fields = []
+    pos = 0
+    while pos < len(data):
+        start = pos
+        key, pos = _read_varint(data, pos)
+        number, wire_type = key >> 3, key & 7
+        payload = b""
+        if wire_type == 0:
+            _, pos = _read_varint(data, pos)
+        elif wire_type == 1:
+            pos += 8
+        elif wire_type == 2:
+            length, pos = _read_varint(data, pos)
+            payload = data[pos:pos + length]
+            pos += length
+        elif wire_type == 5:
+            pos += 4
+        else:
+            raise ValueError(f"unsupported wire type {wire_type} in descriptor set")
+        if pos > len(data):
+            raise ValueError("truncated field in descriptor set")
+        fields.append((number, data[start:pos], payload))
+    return fields
+
+
+def _length_delimited(number: int, payload: bytes) -> bytes:
+    def varint(value: int) -> bytes:
+        out = bytearray()
+        while True:
+            byte = value & 0x7F
+            value >>= 7
+            out.append(byte | (0x80 if value else 0))
+            if not value:
+                return bytes(out)
+    return varint(number << 3 | 2) + varint(len(payload)) + payload
+
+
+# FileDescriptorSet.file, FileDescriptorProto.name, .dependency and .source_code_info
+_SET_FILE, _FILE_NAME, _FILE_DEPENDENCY, _FILE_SOURCE_CODE_INFO = 1, 1, 3, 9
+
+
+@dataclass
+class EmbeddedDescriptor:
+    """One FileDescriptorProto of a descriptor set, without source code info."""
+    name: str
+    dependencies: List[str]
+    data: bytes
+
+
+def parse_descriptor_set(data: bytes) -> Dict[str, EmbeddedDescriptor]:
+    """Parses a serialized FileDescriptorSet into its files, dropping source code info."""
+    files = {}
+    for number, _, payload in _wire_fields(data):
+        if number != _SET_FILE:
+            continue
+        name, dependencies, kept = "", [], []
+        for field_number, raw, value in _wire_fields(payload):
+            if field_number == _FILE_SOURCE_CODE_INFO:
+                continue
+            if field_number == _FILE_NAME:
+                name = value.decode("utf-8")
+            elif field_number == _FILE_DEPENDENCY:
+                dependencies.append(value.decode("utf-8"))
+            kept.append(raw)
+        files[name] = EmbeddedDescriptor(name=name, dependencies=dependencies, data=b"".join(kept))
+    return files
+
+
+DESCRIPTOR_EMBED_FORMATS = ["raw", "gzip"]
+
+
+def _find_descriptor(descriptors: Dict[str, EmbeddedDescriptor], path: str) -> Optional[EmbeddedDescriptor]:
+    for name, descriptor in descriptors.items():
+        if path == name or path.endswith("/" + name):
+            return descriptor
+    return None
+
+
+def _descriptor_with_imports(descriptors: Dict[str, EmbeddedDescriptor], root: EmbeddedDescriptor) -> bytes:
+    """Serializes a FileDescriptorSet of root and its transitive imports, dependencies first."""
+    ordered: List[EmbeddedDescriptor] = []
+    visited: Set[str] = set()
+
+    def visit(descriptor: EmbeddedDescriptor) -> None:
+        if descriptor.name in visited:
+            return
+        visited.add(descriptor.name)
+        for dependency in descriptor.dependencies:
+            if dependency not in descriptors:
+                raise GeneratorConfigError(f"descriptor set lacks {dependency}, imported by {descriptor.name}")
+            visit(descriptors[dependency])
+        ordered.append(descriptor)
+
+    visit(root)
+    return b"".join(_length_delimited(_SET_FILE, d.data) for d in ordered)
+
+
+def _go_byte_slice(data: bytes, indent: str = "\t", per_line: int = 16) -> str:
+    lines = []
+    for start in range(0, len(data), per_line):
+        lines.append(indent + " ".join(f"0x{b:02x}," for b in data[start:start + per_line]))
+    return "[]byte{\n" + "\n".join(lines) + "\n}"
+
+
+@register_generator("descriptor_embed", "desc", "Accessor returning the file descriptor in a compact embedded format")
+def generate_descriptor_embed(ctx: GeneratorContext) -> Optional[GoFile]:
+    embed_format = ctx.config.get("format", "")
+    if embed_format not in DESCRIPTOR_EMBED_FORMATS:
+        raise GeneratorConfigError(f"descriptor_embed format must be one of {DESCRIPTOR_EMBED_FORMATS}, got '{embed_format}'")
+    descriptors = ctx.config.get("descriptors")
+    if descriptors is None:
+        raise GeneratorConfigError("descriptor_embed requires a descriptor set")
+    descriptor = _find_descriptor(descriptors, ctx.proto_file.path)
+    if descriptor is None:
+        raise GeneratorConfigError(f"descriptor set has no file for {ctx.proto_file.path}")
+
+    include_imports = bool(ctx.config.get("include_imports"))
+    if include_imports:
+        data = _descriptor_with_imports(descriptors, descriptor)
+        what = "FileDescriptorSet of the file and its transitive imports,\n// dependencies first"
+    else:
+        data = descriptor.data
+        what = "FileDescriptorProto"
+    if embed_format == "gzip":
+        # mtime=0 keeps the output reproducible
+        data = gzip.compress(data, compresslevel=9, mtime=0)
+        what = "gzip-compressed " + what
+
+    var = f"{ctx.file_ident}CompactDesc"
+    accessor = f"{go_camel_case(Path(ctx.proto_file.path).stem)}ProtoCompactDescriptor"
+    out = ctx.new_file("descriptor_embed")
+    out.add(f"""
+var {var} = {_go_byte_slice(data)}
+
+// {accessor} returns the descriptor of {descriptor.name} ({len(data)} bytes):
+// a {what}, without source code info.
+// The returned slice is shared and must not be modified.
+func {accessor}() []byte {{
+\treturn {var}
+}}""")
+    return out
+
+
+# Stamp keys read for build_info, in order of preference
+STAMP_COMMIT_KEYS = ["STABLE_GIT_COMMIT", "BUILD_SCM_REVISION"]
+STAMP_TIME_KEYS = ["BUILD_TIMESTAMP"]
+
+
+def parse_stamp(content: str) -> Dict[str, str]:
+    """Parses workspace status lines ("KEY value") into a dict."""
+    stamp = {}
+    for line in content.splitlines():
+        key, _, value = line.strip().partition(" ")
+        if key:
+            stamp[key] = value.strip()
+    return stamp
+
+
+def _first_stamp_value(stamp: Dict[str, str], keys: List[str]) -> str:
+    return next((stamp[key] for key in keys if stamp.get(key)), "")
+
+
+@register_generator("build_info", "build_info", "BuildInfo variable with the commit and time from a build stamp")
+def generate_build_info(ctx: GeneratorContext) -> Optional[GoFile]:
+    # BuildInfo is package-level; emit it once, next to the first proto file
+    if ctx.proto_file is not ctx.schema.files[0]:
+        return None
+
+    stamp = ctx.config.get("stamp") or {}
+    commit = _first_stamp_value(stamp, STAMP_COMMIT_KEYS)
+    build_time = ctx.config.get("build_time", "")
+    if not build_time:
+        timestamp = _first_stamp_value(stamp, STAMP_TIME_KEYS)
+        if timestamp:
+            if not timestamp.isdigit():
+                raise GeneratorConfigError(f"stamp value BUILD_TIMESTAMP must be Unix seconds, got '{timestamp}'")
+            build_time = datetime.fromtimestamp(int(timestamp), tz=timezone.utc).strftime("%Y-%m-%dT%H:%M:%SZ")
+
+    out = ctx.new_file("build_info")
+    if stamp:
+        out.header.append("Stamped build: this file changes with every commit and is not cacheable across commits.")
+    out.add(f"""
+// BuildInfo describes the build that produced the generated code in this
+// package. Fields are empty for unstamped (hermetic) builds.
+var BuildInfo = struct {{
+\t// Commit is the source control revision the code was generated from.
+\tCommit string
+\t// BuildTime is the build time in RFC 3339 format (UTC).
+\tBuildTime string
+}}{{
+\tCommit:    {json.dumps(commit)},
+\tBuildTime: {json.dumps(build_time)},
+}}""")
+    return out
+
+
+SPAN_ATTRIBUTE_OPTION = "buck2protobuf.otel.v1.span_attribute"
+
+# attribute constructor and conversion of a field's getter value, by proto type
+SPAN_ATTRIBUTE_SCALARS = {
+    "string": ("String", "{}"),
+    "bool": ("Bool", "{}"),
+    "int32": ("Int64", "int64({})"), "sint32": ("Int64", "int64({})"), "sfixed32": ("Int64", "int64({})"),
+    "uint32": ("Int64", "int64({})"), "fixed32": ("Int64", "int64({})"),
+    "int64": ("Int64", "{}"), "sint64": ("Int64", "{}"), "sfixed64": ("Int64", "{}"),
+    # uint64 does not fit an int64 attribute; the decimal string is lossless
+    "uint64": ("String", "strconv.FormatUint({}, 10)"), "fixed64": ("String", "strconv.FormatUint({}, 10)"),
+    "float": ("Float64", "float64({})"),
+    "double": ("Float64", "{}"),
+}
+
+SPAN_ATTRIBUTE_SLICES = {
+    "string": "StringSlice", "bool": "BoolSlice",
+    "int64": "Int64Slice", "sint64": "Int64Slice", "sfixed64": "Int64Slice",
+    "double": "Float64Slice",
+}
+
+
+def _has_presence(proto_file: ProtoFile, message_field) -> bool:
+    """Returns whether a singular field is generated as a pointer that is nil when unset."""
+    if proto_file.syntax == "proto2":
+        return True
+    if proto_file.syntax == "proto3":
+        return message_field.label == "optional"
+    presence = find_option(message_field.options, "features.field_presence")
+    presence = presence or find_option(proto_file.options, "features.field_presence")
+    return presence != "IMPLICIT"
+
+
+def _span_attributes(ctx: GeneratorContext, message: Message) -> List[Tuple[Any, str, str]]:
+    """Returns the annotated fields of a message with their attribute key and Go expression."""
+    field_go_names, oneof_go_names = go_field_names(message)
+    go_name = ctx.go_type_name(message)
+    attributes = []
+    seen: Dict[str, str] = {}
+    for message_field in message.fields:
+        key = find_option(message_field.options, SPAN_ATTRIBUTE_OPTION)
+        if key is None:
+            continue
+        if not isinstance(key, str) or not key or any(c.isspace() for c in key):
+            raise GeneratorConfigError(f"({SPAN_ATTRIBUTE_OPTION}) on {message_field.full_name} must be a "
+                                       f"non-empty key without whitespace, got {key!r}")
+        if key in seen:
+            raise GeneratorConfigError(f"{message_field.full_name} and {message.full_name}.{seen[key]} "
+                                       f"share the span attribute {key!r}")
+        seen[key] = message_field.name
+
+        getter = f"x.Get{field_go_names[message_field.name]}()"
+        resolved = None if message_field.is_scalar else ctx.schema.resolve_type(message_field.type_name, message.full_name)
+        type_name = "enum" if isinstance(resolved, Enum) else message_field.type_name
+        unsupported = GeneratorConfigError(
+            f"({SPAN_ATTRIBUTE_OPTION}) on {message_field.full_name}: {message_field.cardinality} "
+            f"{message_field.type_name} fields cannot be span attributes")
+        if message_field.is_map:
+            raise unsupported
+        if message_field.label == "repeated":
+            if type_name not in SPAN_ATTRIBUTE_SLICES:
+                raise unsupported
+            value = f"attribute.{SPAN_ATTRIBUTE_SLICES[type_name]}({json.dumps(key)}, {getter})"
+            condition = f"len({getter}) > 0"
+        else:
+            if type_name == "enum":
+                value = f"attribute.String({json.dumps(key)}, {getter}.String())"
+            elif type_name in SPAN_ATTRIBUTE_SCALARS:
+                constructor, conversion = SPAN_ATTRIBUTE_SCALARS[type_name]
+                value = f"attribute.{constructor}({json.dumps(key)}, {conversion.format(getter)})"
+            else:
+                raise unsupported
+            if message_field.oneof:
+                wrapper = f"{go_name}_{go_camel_case(message_field.name)}"
+                condition = f"_, ok := x.{oneof_go_names[message_field.oneof]}.(*{wrapper}); ok"
+            elif _has_presence(ctx.proto_file, message_field):
+                condition = f"x.{field_go_names[message_field.name]} != nil"
+            else:
+                condition = ""
+        attributes.append((message_field, condition, value))
+    return attributes
+
+
+def _span_attribute_messages(ctx: GeneratorContext, proto_file: ProtoFile) -> List[Message]:
+    return [m for m in proto_file.all_messages()
+            if not m.is_map_entry and any(find_option(f.options, SPAN_ATTRIBUTE_OPTION) is not None for f in m.fields)]
+
+
+@register_generator("span_attributes", "otel", "SpanAttributes() returning fields annotated with (buck2protobuf.otel.v1.span_attribute) as OpenTelemetry attributes")
+def generate_span_attributes(ctx: GeneratorContext) -> Optional[GoFile]:
+    messages = _span_attribute_messages(ctx, ctx.proto_file)
+    if not messages:
+        return None
+
+    out = ctx.new_file("span_attributes")
+    out.add_import("go.opentelemetry.io/otel/attribute")
+
+    # The generic accessor is package-level; emit it once, next to the first annotated file
+    first = next(f for f in ctx.schema.files if f is ctx.proto_file or _span_attribute_messages(ctx, f))
+    if first is ctx.proto_file:
+        out.add_import("google.golang.org/protobuf/proto")
+        out.add(f"""
+// SpanAttributes returns the span attributes of m, or nil if its type has no
+// fields annotated with ({SPAN_ATTRIBUTE_OPTION}).
+func SpanAttributes(m proto.Message) []attribute.KeyValue {{
+\tif a, ok := m.(interface{{ SpanAttributes() []attribute.KeyValue }}); ok {{
+\t\treturn a.SpanAttributes()
+\t}}
+\treturn nil
+}}""")
+
+    for message in messages:
+        go_name = ctx.go_type_name(message)
+        statements = []
+        for message_field, condition, value in _span_attributes(ctx, message):
+            if "strconv." in value:
+                out.add_import("strconv")
+            if condition:
+                statements.append(f"\tif {condition} {{\n\t\tattrs = append(attrs, {value})\n\t}}")
+            else:
+                statements.append(f"\tattrs = append(attrs, {value})")
+        out.add(f"""
+// SpanAttributes returns the fields of {message.full_name} annotated with
+// ({SPAN_ATTRIBUTE_OPTION}) as span attributes. Unset fields with
+// presence, empty repeated fields and unset oneof members are omitted.
+func (x *{go_name}) SpanAttributes() []attribute.KeyValue {{
+\tif x == nil {{
+\t\treturn nil
+\t}}
+\tattrs := make([]attribute.KeyValue, 0, {len(statements)})
+{chr(10).join(statements)}
+\treturn attrs
+}}""")
+    return out
+
+
+# Runner
+
+
+def generate(
+    generator: str,
+    files: List[str],
+    config: Dict[str, Any],
+    go_package: str = "",
+    dep_files: Optional[List[str]] = None,
+) -> Dict[str, Optional[str]]:
+    """
+    Runs a registered generator over proto files.
+
+    Args:
+        generator: Registered generator name
+        files: Proto files to generate helpers for
+        config: Generator configuration
+        go_package: Resolved Go import path for the generated package
+        dep_files: Transitive dependency proto files used for resolution only
+
+    Returns:
+        Mapping of proto file path to generated Go source (None if nothing to generate)
+    """
+    if generator not in GENERATORS:
+        raise GeneratorConfigError(f"unknown generator '{generator}' (available: {', '.join(sorted(GENERATORS))})")
+
+    schema = load_schema_set(files, dep_files)
+    results = {}
+    for proto_file in schema.files:
+        ctx = GeneratorContext(schema=schema, proto_file=proto_file, go_package=go_package, config=config)
+        go_file = GENERATORS[generator].func(ctx)
+        results[proto_file.path] = go_file.render() if go_file else None
+    return results
+
+
+def _empty_file(generator: str, source: str, package: str) -> str:
+    """Renders a placeholder so every declared output exists."""
+    return GoFile(package=package, source=source, generator=generator).render()
+
+
+def main():
+    """Main entry point for Go helper generation."""
+    parser = argparse.ArgumentParser(description="Generate Go helper files from proto files")
+    parser.add_argument("--generator", help="Name of the generator to run")
+    parser.add_argument("--config", help="JSON file with generator configuration")
+    parser.add_argument("--go-package", default="", help="Go import path of the generated package")
+    parser.add_argument("--stamp", help="Build stamp file with workspace status lines (KEY value)")
+    parser.add_argument("--descriptor-set", help="Serialized FileDescriptorSet of the proto files (protoc --descriptor_set_out)")
+    parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    parser.add_argument("--output", action="append", default=[], help="Output file, one per proto file in order")
+    parser.add_argument("--list-generators", action="store_true", help="List available generators and exit")
+    parser.add_argument("files", nargs="*", help="Proto files")
+    args = parser.parse_args()
+
+    if args.list_generators:
+        for name in sorted(GENERATORS):
+            print(f"{name} (*_{GENERATORS[name].suffix}.pb.go): {GENERATORS[name].description}")
+        return
+    if not args.generator:
+        parser.error("--generator is required")
+    if args.output and len(args.output) != len(args.files):
+        parser.error("--output must be given once per proto file")
+
+    try:
+        config = {}
+        if args.config:
+            with open(args.config, "r", encoding="utf-8") as f:
+                config = json.load(f)
+        if args.stamp:
+            with open(args.stamp, "r", encoding="utf-8") as f:
+                config["stamp"] = parse_stamp(f.read())
+        if args.descriptor_set:
+            with open(args.descriptor_set, "rb") as f:
+                config["descriptors"] = parse_descriptor_set(f.read())
+        results = generate(args.generator, args.files, config, args.go_package, args.dep)
+    except (GeneratorConfigError, ProtoParseError, OSError, ValueError) as e:
+        print(f"ERROR: {args.generator}: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    for index, path in enumerate(args.files):
+        content = results[path]
+        if content is None:
+            proto_file = load_schema_set([path]).files[0]
+            content = _empty_file(args.generator, path, go_package_name(args.go_package, proto_file))
+        if args.output:
+            with open(args.output[index], "w", encoding="utf-8") as f:
+                f.write(content)
+        else:
+            sys.stdout.write(content)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,187 @@
+#!/usr/bin/env python3
+"""
+Oneof wrapper type name stability for generated Go protobuf code.
+
+protoc-gen-go generates one wrapper struct per oneof member (e.g.
+`*User_Email` for field `email` of a oneof in message `User`). The names
+follow a naming contract with conflict resolution that has shifted between
+protoc-gen-go releases. This tool reads the wrapper names actually present in
+the generated .pb.go files, compares them with a committed baseline, and
+either fails on drift or emits type aliases that keep the baseline names
+compiling.
+
+Naming contract (protoc-gen-go, google.golang.org/protobuf):
+    1. Every field and oneof gets the Go name CamelCase(name). In declaration
+       order, a field name is made unique by appending "_" while it (or
+       "Get" + it) is already used; reserved names are Reset, String,
+       ProtoMessage, Marshal, Unmarshal, ExtensionRangeArray, ExtensionMap
+       and Descriptor. A oneof name is made unique right after its first
+       member, ignoring getters.
+    2. The wrapper type of a oneof member is <Message>_<Field>, where
+       <Message> is the Go name of the containing message
+       (Parent_Child for nested messages).
+    3. While the wrapper name equals a nested message or enum type name of
+       the containing message, "_" is appended.
+
+Usage:
+    go_oneof_names.py --go-package github.com/org/user/v1 \\
+        --baseline oneof_names.json --names-output current.json \\
+        --aliases-output user_oneof_aliases.pb.go \\
+        --generated user.pb.go user.proto
+"""
+
+import argparse
+import json
+import re
+import sys
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+
+try:
+    from go_helper_gen import GoFile, go_field_names, go_package_name, go_type_name
+    from proto_schema import ProtoParseError, SchemaSet, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from go_helper_gen import GoFile, go_field_names, go_package_name, go_type_name
+    from proto_schema import ProtoParseError, SchemaSet, load_schema_set
+
+
+@dataclass
+class OneofMember:
+    """A oneof member with the names protoc-gen-go derives for it."""
+    full_name: str     # Fully-qualified proto field name
+    field_name: str    # Proto field name (as in the struct tag)
+    interface: str     # Go interface of the oneof (is<Message>_<Oneof>)
+    wrapper: str       # Wrapper type name according to the naming contract
+
+
+def contract_names(schema: SchemaSet) -> List[OneofMember]:
+    """Derives oneof wrapper names for all messages of the schema per the naming contract."""
+    members = []
+    for proto_file in schema.files:
+        for message in proto_file.all_messages():
+            if message.is_map_entry:
+                continue
+            message_go = go_type_name(message.full_name, proto_file.package)
+            nested_types = {go_type_name(m.full_name, proto_file.package) for m in message.messages}
+            nested_types.update(go_type_name(e.full_name, proto_file.package) for e in message.enums)
+
+            field_go_names, oneof_go_names = go_field_names(message)
+            for message_field in message.fields:
+                oneof = message_field.oneof
+                if not oneof:
+                    continue
+                wrapper = f"{message_go}_{field_go_names[message_field.name]}"
+                while wrapper in nested_types:
+                    wrapper += "_"
+                members.append(OneofMember(
+                    full_name=message_field.full_name,
+                    field_name=message_field.name,
+                    interface=f"is{message_go}_{oneof_go_names[oneof]}",
+                    wrapper=wrapper,
+                ))
+    return members
+
+
+_WRAPPER_METHOD = re.compile(r"^func \(\*(\w+)\) (is\w+)\(\) \{\}", re.MULTILINE)
+_TYPE_DECL = re.compile(r"^type (\w+) ", re.MULTILINE)
+_STRUCT = re.compile(r"^type (\w+) struct \{\n\t\w+ [^\n]*`protobuf:\"[^\"]*\bname=(\w+)[^\"]*\"", re.MULTILINE)
+
+
+def generated_wrappers(sources: List[str]) -> Dict[Tuple[str, str], str]:
+    """
+    Extracts oneof wrapper types from generated Go code.
+
+    Returns:
+        Mapping of (oneof interface, proto field name) to wrapper type name
+    """
+    wrappers = {}
+    for source in sources:
+        interfaces = {wrapper: interface for wrapper, interface in _WRAPPER_METHOD.findall(source)}
+        for type_name, field_name in _STRUCT.findall(source):
+            if type_name in interfaces:
+                wrappers[(interfaces[type_name], field_name)] = type_name
+    return wrappers
+
+
+def current_names(members: List[OneofMember], generated: Optional[Dict[Tuple[str, str], str]]) -> Dict[str, str]:
+    """
+    Returns the wrapper name of every oneof member.
+
+    Names are read from the generated code when available, falling back to the
+    naming contract for members that could not be located.
+    """
+    names = {}
+    for member in members:
+        names[member.full_name] = (generated or {}).get((member.interface, member.field_name), member.wrapper)
+    return names
+
+
+def find_drift(baseline: Dict[str, str], current: Dict[str, str]) -> List[Tuple[str, str, str]]:
+    """Returns (field, baseline name, current name) for every wrapper whose name changed."""
+    return [
+        (full_name, expected, current[full_name])
+        for full_name, expected in sorted(baseline.items())
+        if full_name in current and current[full_name] != expected
+    ]
+
+
+def render_aliases(drift: List[Tuple[str, str, str]], package: str, source: str, taken: List[str]) -> str:
+    """Renders type aliases that keep baseline wrapper names compiling."""
+    out = GoFile(package=package, source=source, generator="oneof_wrapper_aliases")
+    for full_name, expected, actual in drift:
+        if expected in taken:
+            raise ValueError(f"cannot alias {expected} to {actual} for {full_name}: {expected} is already declared")
+        out.add(f"""
+// {expected} is the baseline wrapper name of oneof field {full_name}.
+type {expected} = {actual}""")
+    return out.render()
+
+
+def main():
+    """Main entry point for oneof wrapper name checks."""
+    parser = argparse.ArgumentParser(description="Check Go oneof wrapper type names against a baseline")
+    parser.add_argument("--go-package", default="", help="Go import path of the generated package")
+    parser.add_argument("--baseline", help="JSON file mapping oneof field names to wrapper type names")
+    parser.add_argument("--generated", action="append", default=[], help="Generated .pb.go file")
+    parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    parser.add_argument("--names-output", help="Write the current mapping as JSON")
+    parser.add_argument("--aliases-output", help="Write type aliases for drifted names instead of failing")
+    parser.add_argument("files", nargs="+", help="Proto files")
+    args = parser.parse_args()
+
+    try:
+        schema = load_schema_set(args.files, args.dep)
+        members = contract_names(schema)
+        sources = [Path(p).read_text(encoding="utf-8") for p in args.generated]
+        current = current_names(members, generated_wrappers(sources) if sources else None)
+
+        if args.names_output:
+            with open(args.names_output, "w", encoding="utf-8") as f:
+                json.dump(current, f, indent=2, sort_keys=True)
+                f.write("\n")
+
+        baseline = {}
+        if args.baseline:
+            with open(args.baseline, "r", encoding="utf-8") as f:
+                baseline = json.load(f)
+        drift = find_drift(baseline, current)
+
+        if args.aliases_output:
+            package = go_package_name(args.go_package, schema.files[0])
+            taken = [name for source in sources for name in _TYPE_DECL.findall(source)]
+            with open(args.aliases_output, "w", encoding="utf-8") as f:
+                f.write(render_aliases(drift, package, args.files[0], taken))
+        elif drift:
+            for full_name, expected, actual in drift:
+                print(f"ERROR: oneof wrapper for {full_name} is {actual}, baseline expects {expected}", file=sys.stderr)
+            print("Update the baseline from the oneof_wrapper_names sub-target, or set oneof_wrapper_aliases", file=sys.stderr)
+            sys.exit(1)
+    except (ProtoParseError, OSError, ValueError) as e:
+        print(f"ERROR: go_oneof_names: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
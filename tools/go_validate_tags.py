@@ -0,0 +1,212 @@
+#!/usr/bin/env python3
+"""
+ORM validation struct tags for generated Go protobuf code.
+
+Persistence layers such as gorm (via go-playground/validator) and ent read
+validation rules from struct tags. This tool maps a subset of protovalidate
+field rules (`(buf.validate.field)`) to tag values and appends them to the
+field tags of protoc-gen-go output, e.g.
+
+    string email = 1 [(buf.validate.field).string.email = true];
+
+becomes
+
+    Email string `protobuf:"..." json:"email,omitempty" validate:"email"`
+
+Rules are addressed as "<kind>.<rule>" (e.g. "string.max_len") or by their
+top-level name ("required"). A tag template may contain "{}", which is
+replaced by the rule argument. Rules without a mapping are ignored.
+
+Usage:
+    go_validate_tags.py --config config.json --input-dir raw/ \\
+        --output user.pb.go user.proto
+"""
+
+import argparse
+import json
+import re
+import sys
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+try:
+    from go_helper_gen import go_type_name
+    from proto_schema import Field, ProtoParseError, SchemaSet, find_option, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from go_helper_gen import go_type_name
+    from proto_schema import Field, ProtoParseError, SchemaSet, find_option, load_schema_set
+
+
+_NUMERIC_KINDS = [
+    "int32", "int64", "uint32", "uint64", "sint32", "sint64",
+    "fixed32", "fixed64", "sfixed32", "sfixed64", "float", "double",
+]
+
+# protovalidate rule -> go-playground/validator tag
+DEFAULT_TAG_RULES: Dict[str, str] = {
+    "required": "required",
+    "string.email": "email",
+    "string.hostname": "hostname",
+    "string.ip": "ip",
+    "string.ipv4": "ipv4",
+    "string.ipv6": "ipv6",
+    "string.uri": "uri",
+    "string.uuid": "uuid",
+    "string.len": "len={}",
+    "string.min_len": "min={}",
+    "string.max_len": "max={}",
+    "string.prefix": "startswith={}",
+    "string.suffix": "endswith={}",
+    "string.contains": "contains={}",
+    "repeated.min_items": "min={}",
+    "repeated.max_items": "max={}",
+    "map.min_pairs": "min={}",
+    "map.max_pairs": "max={}",
+}
+for _kind in _NUMERIC_KINDS:
+    for _rule in ("gt", "gte", "lt", "lte"):
+        DEFAULT_TAG_RULES[f"{_kind}.{_rule}"] = _rule + "={}"
+
+
+class TagConfigError(Exception):
+    """Raised when the tag configuration is invalid."""
+
+
+def _format_argument(argument: Any) -> str:
+    if isinstance(argument, bool):
+        return "true" if argument else "false"
+    return str(argument)
+
+
+def field_tag(message_field: Field, rules: Dict[str, str]) -> str:
+    """
+    Returns the tag value for a field, or "" if none of its rules are mapped.
+
+    Args:
+        message_field: Field with (buf.validate.field) options
+        rules: Mapping of protovalidate rule to tag template
+    """
+    constraints = find_option(message_field.options, "buf.validate.field")
+    if not isinstance(constraints, dict):
+        return ""
+
+    values = []
+    for kind, value in constraints.items():
+        if isinstance(value, dict):
+            candidates = [(f"{kind}.{rule}", argument) for rule, argument in value.items()]
+        else:
+            candidates = [(kind, value)]
+        for rule, argument in candidates:
+            template = rules.get(rule, "")
+            # Boolean rules such as string.email only apply when set to true
+            if not template or argument is False:
+                continue
+            if "{}" in template:
+                values.append(template.replace("{}", _format_argument(argument)))
+            elif argument is True:
+                values.append(template)
+    return ",".join(values)
+
+
+def struct_tags(schema: SchemaSet, rules: Dict[str, str]) -> Dict[str, Dict[str, str]]:
+    """
+    Computes the tag value of every validated field.
+
+    Returns:
+        Mapping of Go struct name to {proto field name: tag value}
+    """
+    tags: Dict[str, Dict[str, str]] = {}
+    for proto_file in schema.files:
+        for message in proto_file.all_messages():
+            if message.is_map_entry:
+                continue
+            for message_field in message.fields:
+                # Oneof members live in wrapper structs that ORMs do not persist
+                if message_field.oneof:
+                    continue
+                tag = field_tag(message_field, rules)
+                if tag:
+                    struct = go_type_name(message.full_name, proto_file.package)
+                    tags.setdefault(struct, {})[message_field.name] = tag
+    return tags
+
+
+_STRUCT_START = re.compile(r"^type (\w+) struct \{$")
+_FIELD = re.compile(r"^(\t\w+ [^`]+)`([^`]*)`(.*)$")
+_PROTO_NAME = re.compile(r'\bprotobuf:"[^"]*\bname=(\w+)[,"]')
+
+
+def add_tags(content: str, tags: Dict[str, Dict[str, str]], key: str) -> Tuple[str, int]:
+    """
+    Appends `key:"value"` to the tags of generated struct fields.
+
+    Returns:
+        Tuple of (rewritten content, number of tagged fields)
+    """
+    lines = content.split("\n")
+    struct: Optional[str] = None
+    tagged = 0
+    for i, line in enumerate(lines):
+        start = _STRUCT_START.match(line)
+        if start:
+            struct = start.group(1)
+            continue
+        if line == "}":
+            struct = None
+            continue
+        if struct not in tags:
+            continue
+        match = _FIELD.match(line)
+        name = _PROTO_NAME.search(match.group(2)) if match else None
+        if not name or name.group(1) not in tags[struct]:
+            continue
+        if re.search(rf'\b{re.escape(key)}:"', match.group(2)):
+            raise TagConfigError(f"field {struct}.{name.group(1)} already has a {key} tag")
+        lines[i] = f'{match.group(1)}`{match.group(2)} {key}:"{tags[struct][name.group(1)]}"`{match.group(3)}'
+        tagged += 1
+    return "\n".join(lines), tagged
+
+
+def load_config(config: Dict[str, Any]) -> Tuple[str, Dict[str, str]]:
+    """Returns the tag key and the effective rule mapping (defaults overridden by config)."""
+    key = config.get("tag", "validate")
+    if not re.match(r"^[A-Za-z_][A-Za-z0-9_]*$", key or ""):
+        raise TagConfigError(f"'{key}' is not a valid struct tag key")
+    rules = dict(DEFAULT_TAG_RULES)
+    for rule, template in config.get("rules", {}).items():
+        if '"' in template or "`" in template:
+            raise TagConfigError(f"tag template for {rule} must not contain quotes or backticks")
+        rules[rule] = template
+    return key, rules
+
+
+def main():
+    """Main entry point for validation tag post-processing."""
+    parser = argparse.ArgumentParser(description="Add ORM validation struct tags to generated Go code")
+    parser.add_argument("--config", required=True, help="JSON file with the tag key and rule mapping")
+    parser.add_argument("--input-dir", required=True, help="Directory with protoc-gen-go output")
+    parser.add_argument("--output", action="append", default=[], help="Output file; its basename selects the input")
+    parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    parser.add_argument("files", nargs="+", help="Proto files")
+    args = parser.parse_args()
+
+    try:
+        with open(args.config, "r", encoding="utf-8") as f:
+            key, rules = load_config(json.load(f))
+        tags = struct_tags(load_schema_set(args.files, args.dep), rules)
+
+        for output in args.output:
+            name = Path(output).name
+            matches = sorted(Path(args.input_dir).rglob(name))
+            if not matches:
+                raise TagConfigError(f"protoc-gen-go did not produce {name}")
+            content, _ = add_tags(matches[0].read_text(encoding="utf-8"), tags, key)
+            Path(output).write_text(content, encoding="utf-8")
+    except (TagConfigError, ProtoParseError, OSError, ValueError) as e:
+        print(f"ERROR: go_validate_tags: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,115 @@
+#!/usr/bin/env python3
+"""
+Wire-level gRPC service renaming for protobuf Buck2 integration.
+
+During a service rename, servers must keep answering on the old
+`/package.Service/Method` paths while the proto (and the Go types generated
+from it) already carry the new name, or vice versa. This tool rewrites the
+registered service name in protoc-gen-go-grpc output (the ServiceDesc
+ServiceName and every FullMethodName) according to a mapping, leaving Go
+identifiers unchanged.
+
+A prefix can also be prepended to the wire name of every service, after the
+mapping, for multi-tenant routing: with `--prefix tenant_a`, acme.user.v1.UserService
+is served and called as /tenant_a.acme.user.v1.UserService/Method.
+
+Usage:
+    grpc_service_rename.py --mapping mapping.json --input-dir raw/ \\
+        --output user_grpc.pb.go [--prefix tenant_a]
+"""
+
+import argparse
+import json
+import re
+import sys
+from pathlib import Path
+from typing import Dict, List, Tuple
+
+_SERVICE_NAME = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$")
+_SERVICE_DESC_NAME = re.compile(r'ServiceName: "([^"]+)",')
+
+
+class RenameError(Exception):
+    """Raised when the mapping is invalid or does not apply."""
+
+
+def validate_mapping(mapping: Dict[str, str]) -> None:
+    """Checks that every key and override is a valid fully-qualified gRPC service name."""
+    for proto_name, wire_name in mapping.items():
+        for name in (proto_name, wire_name):
+            if not _SERVICE_NAME.match(name):
+                raise RenameError(
+                    f"'{name}' is not a valid gRPC service name (expected package.Service, "
+                    "used as /package.Service/Method)"
+                )
+
+
+def prefixed_mapping(content: str, mapping: Dict[str, str], prefix: str) -> Dict[str, str]:
+    """
+    Returns the mapping applying a prefix to every service of generated code.
+
+    Services listed in the mapping get the prefix in front of their mapped
+    name, all others in front of their proto name.
+    """
+    result = dict(mapping)
+    for proto_name in _SERVICE_DESC_NAME.findall(content):
+        result[proto_name] = f"{prefix}.{mapping.get(proto_name, proto_name)}"
+    validate_mapping(result)
+    return result
+
+
+def rename_services(content: str, mapping: Dict[str, str]) -> Tuple[str, List[str]]:
+    """
+    Rewrites wire-level service names in generated gRPC Go code.
+
+    Returns:
+        Tuple of (rewritten content, proto service names that were found)
+    """
+    found = []
+    for proto_name, wire_name in mapping.items():
+        service_name = f'ServiceName: "{proto_name}",'
+        method_prefix = f'"/{proto_name}/'
+        if service_name not in content and method_prefix not in content:
+            continue
+        found.append(proto_name)
+        content = content.replace(service_name, f'ServiceName: "{wire_name}",')
+        content = content.replace(method_prefix, f'"/{wire_name}/')
+    return content, found
+
+
+def main():
+    """Main entry point for gRPC service renaming."""
+    parser = argparse.ArgumentParser(description="Rename wire-level gRPC service names in generated Go code")
+    parser.add_argument("--mapping", required=True, help="JSON file mapping proto service names to wire names")
+    parser.add_argument("--input-dir", required=True, help="Directory with protoc-gen-go-grpc output")
+    parser.add_argument("--output", action="append", default=[], help="Output file; its basename selects the input")
+    parser.add_argument("--prefix", default="", help="Prefix prepended to the wire name of every service")
+    args = parser.parse_args()
+
+    try:
+        with open(args.mapping, "r", encoding="utf-8") as f:
+            mapping = json.load(f)
+        validate_mapping(mapping)
+
+        found = set()
+        for output in args.output:
+            name = Path(output).name
+            matches = sorted(Path(args.input_dir).rglob(name))
+            if not matches:
+                raise RenameError(f"protoc-gen-go-grpc did not produce {name}")
+            content = matches[0].read_text(encoding="utf-8")
+            file_mapping = prefixed_mapping(content, mapping, args.prefix) if args.prefix else mapping
+            content, renamed = rename_services(content, file_mapping)
+            found.update(renamed)
+            Path(output).write_text(content, encoding="utf-8")
+
+        missing = sorted(set(mapping) - found)
+        if missing:
+            raise RenameError(f"services not found in generated code: {', '.join(missing)}")
+    except (RenameError, OSError, ValueError) as e:
+        print(f"ERROR: grpc_service_rename: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,176 @@
+#!/usr/bin/env python3
+"""
+Offline (air-gapped) resolution of protoc and plugin binaries.
+
+With `[protobuf] offline = true` the download scripts never touch the
+network: every tool must already be in a pre-populated cache directory,
+laid out like the download cache:
+
+    <cache>/<tool>-<version>-<platform>/<binary path>
+    <cache>/<tool>-<version>-<platform>/.sha256   (optional, archive sha256)
+
+A missing tool fails immediately with its name, version, expected path and
+checksum instead of a network timeout in the middle of the build. When the
+cache records the archive digest it must match the pinned checksum.
+
+The cache directory comes from `[protobuf] offline_cache_dir`, the rule's
+`offline_cache_dir` attribute or $BUCK2_PROTOBUF_OFFLINE_CACHE.
+
+The `manifest` command lists every tool and version a build needs, with
+download URLs and checksums, so the cache can be seeded on a connected host:
+
+    offline_tools.py manifest --specs tools.json --lockfile protoc.lock.json \\
+        --cache-dir /opt/protobuf-tools
+"""
+
+import argparse
+import json
+import os
+import sys
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Dict, List, Optional
+
+OFFLINE_CACHE_ENV = "BUCK2_PROTOBUF_OFFLINE_CACHE"
+
+# Marker next to a cached binary recording the sha256 of its release archive
+CHECKSUM_MARKER = ".sha256"
+
+
+class OfflineToolError(RuntimeError):
+    """Raised when a tool cannot be resolved from the offline cache."""
+
+
+@dataclass
+class ToolSpec:
+    """A tool binary a build needs."""
+    tool: str
+    version: str
+    platform: str
+    binary_path: str
+    sha256: Optional[str] = None
+    url: Optional[str] = None
+
+    @property
+    def cache_key(self) -> str:
+        return f"{self.tool}-{self.version}-{self.platform}"
+
+    def cache_path(self, cache_dir: Path) -> Path:
+        return cache_dir / self.cache_key / self.binary_path
+
+
+def offline_cache_dir(explicit: Optional[str] = None) -> Path:
+    """Returns the configured offline cache directory."""
+    cache_dir = explicit or os.environ.get(OFFLINE_CACHE_ENV)
+    if not cache_dir:
+        raise OfflineToolError(
+            "offline mode needs a pre-populated tool cache: set [protobuf] offline_cache_dir "
+            f"or ${OFFLINE_CACHE_ENV}"
+        )
+    return Path(cache_dir)
+
+
+def missing_message(spec: ToolSpec, cache_dir: Path) -> str:
+    """Describes a tool missing from the offline cache and how to provide it."""
+    source = f", release archive {spec.url}" if spec.url else ""
+    return (
+        f"offline mode: {spec.tool} {spec.version} ({spec.platform}) is not in the tool cache {cache_dir}. "
+        f"Expected {spec.cache_path(cache_dir)} (archive sha256 {spec.sha256 or 'not pinned'}{source}). "
+        f"List every tool the build needs with `buck2 build //tools:tool_manifest --out -` and seed the cache; "
+        f"no download was attempted"
+    )
+
+
+def resolve(spec: ToolSpec, cache_dir: Path) -> Path:
+    """
+    Returns the cached binary of a tool without accessing the network.
+
+    Raises:
+        OfflineToolError: If the binary is missing or was extracted from an
+                          archive whose recorded digest differs from the pin
+    """
+    binary = spec.cache_path(cache_dir)
+    if not binary.is_file():
+        raise OfflineToolError(missing_message(spec, cache_dir))
+
+    marker = binary.parent / CHECKSUM_MARKER
+    if spec.sha256 and marker.is_file():
+        recorded = marker.read_text().strip().lower()
+        if recorded != spec.sha256.lower():
+            raise OfflineToolError(
+                f"offline mode: {spec.tool} {spec.version} ({spec.platform}) in {binary.parent} was extracted "
+                f"from an archive with sha256 {recorded}, expected {spec.sha256.lower()}; replace the cached copy"
+            )
+
+    if not os.access(binary, os.X_OK):
+        binary.chmod(0o755)
+    return binary
+
+
+def load_specs(path: str, lockfile: Optional[str] = None) -> List[ToolSpec]:
+    """
+    Loads tool specs written by the tool_manifest rule.
+
+    Pinned protoc checksums in the lockfile win over the built-in ones, as
+    they do for downloads.
+    """
+    with open(path, "r", encoding="utf-8") as f:
+        specs = [ToolSpec(**entry) for entry in json.load(f)]
+    if lockfile:
+        with open(lockfile, "r", encoding="utf-8") as f:
+            pins = json.load(f).get("protoc", {})
+        for spec in specs:
+            pin = pins.get(spec.version, {}).get(spec.platform) if spec.tool == "protoc" else None
+            if pin:
+                spec.sha256 = pin
+    return specs
+
+
+def render_manifest(specs: List[ToolSpec], cache_dir: Optional[Path] = None) -> str:
+    """Renders the manifest as text, with the cache status of each tool if a cache is given."""
+    lines = []
+    for spec in sorted(specs, key=lambda s: (s.tool, s.version, s.platform)):
+        line = f"{spec.tool} {spec.version} {spec.platform} sha256={spec.sha256 or '-'} {spec.url or '-'} -> {spec.cache_key}/{spec.binary_path}"
+        if cache_dir is not None:
+            line += " [present]" if spec.cache_path(cache_dir).is_file() else " [MISSING]"
+        lines.append(line)
+    return "\n".join(lines) + "\n"
+
+
+def main():
+    """Main entry point for the offline tool manifest."""
+    parser = argparse.ArgumentParser(description="Offline tool cache support")
+    subparsers = parser.add_subparsers(dest="command", required=True)
+    manifest = subparsers.add_parser("manifest", help="List the tools a build needs")
+    manifest.add_argument("--specs", required=True, help="JSON list of tool specs (written by tool_manifest)")
+    manifest.add_argument("--lockfile", help="Protoc lockfile with pinned archive checksums")
+    manifest.add_argument("--cache-dir", help="Offline cache to check the tools against")
+    manifest.add_argument("--output", help="Write the manifest to this file instead of stdout")
+    manifest.add_argument("--json", action="store_true", help="Write JSON instead of text")
+    args = parser.parse_args()
+
+    try:
+        specs = load_specs(args.specs, args.lockfile)
+        cache_dir = Path(args.cache_dir) if args.cache_dir else None
+        if args.json:
+            entries: List[Dict] = []
+            for spec in specs:
+                entry = dict(asdict(spec), cache_path=f"{spec.cache_key}/{spec.binary_path}")
+                if cache_dir is not None:
+                    entry["present"] = spec.cache_path(cache_dir).is_file()
+                entries.append(entry)
+            output = json.dumps(entries, indent=2) + "\n"
+        else:
+            output = render_manifest(specs, cache_dir)
+    except (OSError, ValueError, TypeError) as e:
+        print(f"ERROR: offline_tools: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    if args.output:
+        Path(args.output).write_text(output, encoding="utf-8")
+    else:
+        sys.stdout.write(output)
+
+
+if __name__ == "__main__":
+    main()
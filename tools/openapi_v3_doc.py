@@ -0,0 +1,143 @@
+#!/usr/bin/env python3
+"""
+Finalizes OpenAPI v3 documents for protobuf Buck2 integration.
+
+protoc-gen-openapi (gnostic) writes one openapi.yaml merging every service of
+the proto files it is run on, with a components/schemas entry for each
+message the operations reference. This tool turns that output into the
+document a go_proto_library exposes:
+
+- without --merge, the proto files must declare at most one service, so a
+  second service cannot silently end up in the same document
+- --server-url adds a top-level servers entry, which the plugin has no
+  option for
+- every '#/components/schemas/...' reference must resolve to a schema in the
+  document
+
+Usage:
+    openapi_v3_doc.py --input gen/openapi.yaml --output openapi.yaml \\
+        --server-url https://api.example.com --merge user.proto admin.proto
+"""
+
+import argparse
+import re
+import sys
+from pathlib import Path
+from typing import List, Optional
+
+try:
+    from proto_schema import ProtoParseError, parse_proto_file
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import ProtoParseError, parse_proto_file
+
+_TOP_LEVEL_KEY = re.compile(r"^([A-Za-z][\w-]*):")
+_SCHEMA_REF = re.compile(r"""\$ref:\s*['"]?#/components/schemas/([^'"\s]+)""")
+
+
+class OpenApiError(Exception):
+    """Raised when a generated document cannot be finalized."""
+
+
+def service_names(proto_paths: List[str]) -> List[str]:
+    """Returns the fully-qualified names of the services declared in the files."""
+    names = []
+    for path in proto_paths:
+        names.extend(service.full_name for service in parse_proto_file(path).services)
+    return names
+
+
+def check_merge(services: List[str], merge: bool) -> None:
+    """Fails if several services would be merged into one document without --merge."""
+    if len(services) > 1 and not merge:
+        raise OpenApiError(f"{len(services)} services ({', '.join(services)}) would be merged into one "
+                           f"openapi.yaml; set openapi_v3_merge = True or split the proto_library")
+
+
+def _yaml_string(value: str) -> str:
+    return "'" + value.replace("'", "''") + "'"
+
+
+def add_server(document: str, url: str) -> str:
+    """Inserts a top-level servers entry after the info section."""
+    lines = document.splitlines(keepends=True)
+    keys = []
+    for i, line in enumerate(lines):
+        match = _TOP_LEVEL_KEY.match(line)
+        if match:
+            keys.append((i, match.group(1)))
+    if any(key == "servers" for _, key in keys):
+        raise OpenApiError("the document already declares servers (openapi.v3.document annotation); "
+                           "remove openapi_v3_server_url or the annotation")
+    names = [key for _, key in keys]
+    if "info" not in names:
+        raise OpenApiError("not an OpenAPI document: missing info section")
+    following = names.index("info") + 1
+    position = keys[following][0] if following < len(keys) else len(lines)
+    if position == len(lines) and lines and not lines[-1].endswith("\n"):
+        lines[-1] += "\n"
+    lines[position:position] = ["servers:\n", f"    - url: {_yaml_string(url)}\n"]
+    return "".join(lines)
+
+
+def declared_schemas(document: str) -> List[str]:
+    """Returns the names under components/schemas."""
+    names, section, indent = [], None, None
+    for line in document.splitlines():
+        if not line.strip() or line.lstrip().startswith("#"):
+            continue
+        depth = len(line) - len(line.lstrip(" "))
+        if depth == 0:
+            section = line.rstrip() if line.rstrip() == "components:" else None
+            indent = None
+        elif section == "components:":
+            if line.strip() == "schemas:":
+                section, indent = "components.schemas", None
+        elif section == "components.schemas":
+            if indent is None:
+                indent = depth
+            if depth < indent:
+                section = "components:"
+            elif depth == indent and line.rstrip().endswith(":"):
+                names.append(line.strip()[:-1].strip("'\""))
+    return names
+
+
+def check_schema_refs(document: str) -> None:
+    """Fails if a schema reference has no matching components/schemas entry."""
+    declared = set(declared_schemas(document))
+    missing = sorted({name for name in _SCHEMA_REF.findall(document) if name not in declared})
+    if missing:
+        raise OpenApiError(f"schemas referenced but not defined in components/schemas: {', '.join(missing)}")
+
+
+def finalize(document: str, services: List[str], merge: bool, server_url: Optional[str]) -> str:
+    """Applies the merge policy and server URL and checks schema references."""
+    check_merge(services, merge)
+    if server_url:
+        document = add_server(document, server_url)
+    check_schema_refs(document)
+    return document
+
+
+def main():
+    """Main entry point for OpenAPI v3 document finalization."""
+    parser = argparse.ArgumentParser(description="Finalize protoc-gen-openapi output")
+    parser.add_argument("--input", required=True, help="openapi.yaml written by protoc-gen-openapi")
+    parser.add_argument("--output", required=True, help="Finalized openapi.yaml")
+    parser.add_argument("--server-url", help="URL of the server entry to add")
+    parser.add_argument("--merge", action="store_true", help="Allow several services in one document")
+    parser.add_argument("protos", nargs="*", help="Proto files the document was generated from")
+    args = parser.parse_args()
+
+    try:
+        document = Path(args.input).read_text(encoding="utf-8")
+        document = finalize(document, service_names(args.protos), args.merge, args.server_url)
+        Path(args.output).write_text(document, encoding="utf-8")
+    except (OpenApiError, ProtoParseError, OSError) as e:
+        print(f"ERROR: openapi_v3_doc: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
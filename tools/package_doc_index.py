@@ -0,0 +1,284 @@
+#!/usr/bin/env python3
+"""
+Markdown documentation index per proto package.
+
+Reads a FileDescriptorSet compiled with --include_source_info and writes one
+README.md per proto package of the library's files, e.g.
+`acme/user/v1/README.md` for package acme.user.v1. Each index lists the
+package's services, messages and enums with the summary (first sentence) of
+their leading doc comments, followed by a section per type with the full
+comment, its fields or methods and the file it is defined in.
+
+Types link to their sections; field and method types from another indexed
+package link to that package's index. The output only depends on the
+descriptor set, so it is byte-for-byte reproducible.
+
+Usage:
+    package_doc_index.py --descriptor-set user.binpb --output-dir docs \\
+        --target acme/user/v1/user.proto --target acme/user/v1/service.proto
+"""
+
+import argparse
+import os
+import re
+import shutil
+import sys
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Tuple
+
+try:
+    from descriptor_repro import DescriptorError, decode
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from descriptor_repro import DescriptorError, decode
+
+# Field numbers of descriptor.proto elements in source code info paths
+FILE_MESSAGE, FILE_ENUM, FILE_SERVICE = 4, 5, 6
+MESSAGE_FIELD, MESSAGE_NESTED, MESSAGE_ENUM = 2, 3, 4
+ENUM_VALUE = 2
+SERVICE_METHOD = 2
+
+# FieldDescriptorProto.type values of scalar fields, by name
+SCALAR_TYPES = {
+    1: "double", 2: "float", 3: "int64", 4: "uint64", 5: "int32", 6: "fixed64", 7: "fixed32", 8: "bool",
+    9: "string", 12: "bytes", 13: "uint32", 15: "sfixed32", 16: "sfixed64", 17: "sint32", 18: "sint64",
+}
+LABEL_REPEATED = 3
+
+INDEX_FILE = "README.md"
+
+
+class DocIndexError(Exception):
+    """Raised when the index cannot be generated."""
+
+
+@dataclass
+class DocEntry:
+    """A documented type of a package."""
+    kind: str  # "service", "message" or "enum"
+    name: str  # Name relative to the package, e.g. "User.Address"
+    file: str
+    comment: str
+    members: List[Tuple[str, str]] = field(default_factory=list)  # (signature, comment)
+
+
+def _value(message: Dict, key: str, default=None):
+    values = message.get(key)
+    return values[0] if values else default
+
+
+def _comments(file_descriptor: Dict) -> Dict[Tuple[int, ...], str]:
+    """Returns leading (or else trailing) comments by source code info path."""
+    comments = {}
+    for location in _value(file_descriptor, "source_code_info", {}).get("location", []):
+        comment = _value(location, "leading_comments") or _value(location, "trailing_comments")
+        if comment:
+            comments[tuple(location.get("path", []))] = comment
+    return comments
+
+
+def clean_comment(comment: str) -> str:
+    """Strips comment indentation and surrounding blank lines."""
+    lines = [line[1:] if line.startswith(" ") else line for line in comment.split("\n")]
+    return "\n".join(lines).strip()
+
+
+def summarize(comment: str) -> str:
+    """Returns the first sentence of the first paragraph of a comment."""
+    paragraph = " ".join(clean_comment(comment).split("\n\n")[0].split())
+    match = re.match(r"(.+?[.!?])(\s|$)", paragraph)
+    return match.group(1) if match else paragraph
+
+
+def anchor(name: str) -> str:
+    """Returns the GitHub-style heading anchor for a type name."""
+    return re.sub(r"[^a-z0-9_-]", "", name.lower())
+
+
+def package_dir(package: str) -> str:
+    """Returns the index directory of a package, relative to the output root."""
+    return package.replace(".", "/")
+
+
+def is_target(path: str, targets: List[str]) -> bool:
+    """Returns whether a descriptor file name belongs to one of the indexed files."""
+    return any(target == path or target.endswith("/" + path) for target in targets)
+
+
+class Linker:
+    """Renders type references as links to the indexed packages."""
+
+    def __init__(self, types: Dict[str, str]):
+        self.types = types  # fully qualified name (no leading dot) -> package
+
+    def link(self, type_name: str, from_package: str) -> str:
+        full_name = type_name.lstrip(".")
+        package = self.types.get(full_name)
+        if package is None:
+            return f"`{full_name}`"
+        name = full_name[len(package) + 1:] if package else full_name
+        if package == from_package:
+            return f"[`{name}`](#{anchor(name)})"
+        target = os.path.join(package_dir(package), INDEX_FILE)
+        relative = os.path.relpath(target, package_dir(from_package) or ".").replace(os.sep, "/")
+        return f"[`{full_name}`]({relative}#{anchor(name)})"
+
+
+def _field_type(field_descriptor: Dict, linker: Linker, package: str) -> str:
+    type_name = _value(field_descriptor, "type_name")
+    rendered = linker.link(type_name, package) if type_name else f"`{SCALAR_TYPES.get(_value(field_descriptor, 'type'), '?')}`"
+    if _value(field_descriptor, "label") == LABEL_REPEATED:
+        rendered = "repeated " + rendered
+    return rendered
+
+
+def collect_entries(descriptor_set: Dict, targets: List[str]) -> Dict[str, List[DocEntry]]:
+    """
+    Collects the documented types of the target files, grouped by package.
+
+    References to types outside the target files (imports, well-known
+    types) are rendered unlinked, with their fully qualified name.
+    """
+    files = [f for f in descriptor_set.get("file", []) if is_target(_value(f, "name", ""), targets)]
+    types: Dict[str, str] = {}
+    for file_descriptor in files:
+        package = _value(file_descriptor, "package", "")
+        prefix = package + "." if package else ""
+
+        def register(messages: List[Dict], enums: List[Dict], scope: str) -> None:
+            for enum in enums:
+                types[prefix + scope + _value(enum, "name")] = package
+            for message in messages:
+                name = scope + _value(message, "name")
+                types[prefix + name] = package
+                register(message.get("nested_type", []), message.get("enum_type", []), name + ".")
+
+        register(file_descriptor.get("message_type", []), file_descriptor.get("enum_type", []), "")
+        for service in file_descriptor.get("service", []):
+            types[prefix + _value(service, "name")] = package
+
+    linker = Linker(types)
+
+    packages: Dict[str, List[DocEntry]] = {}
+    for file_descriptor in files:
+        path = _value(file_descriptor, "name", "")
+        package = _value(file_descriptor, "package", "")
+        comments = _comments(file_descriptor)
+        entries = packages.setdefault(package, [])
+
+        def add_messages(messages: List[Dict], enums: List[Dict], scope: str, path_prefix: Tuple[int, ...],
+                         message_field: int, enum_field: int) -> None:
+            for i, message in enumerate(messages):
+                name = scope + _value(message, "name")
+                message_path = path_prefix + (message_field, i)
+                entry = DocEntry("message", name, path, comments.get(message_path, ""))
+                for j, field_descriptor in enumerate(message.get("field", [])):
+                    signature = "`{}` {} = {}".format(_value(field_descriptor, "name"),
+                                                      _field_type(field_descriptor, linker, package),
+                                                      _value(field_descriptor, "number"))
+                    entry.members.append((signature, comments.get(message_path + (MESSAGE_FIELD, j), "")))
+                entries.append(entry)
+                add_messages(message.get("nested_type", []), message.get("enum_type", []), name + ".",
+                             message_path, MESSAGE_NESTED, MESSAGE_ENUM)
+            for i, enum in enumerate(enums):
+                enum_path = path_prefix + (enum_field, i)
+                entry = DocEntry("enum", scope + _value(enum, "name"), path, comments.get(enum_path, ""))
+                for j, value in enumerate(enum.get("value", [])):
+                    signature = "`{}` = {}".format(_value(value, "name"), _value(value, "number"))
+                    entry.members.append((signature, comments.get(enum_path + (ENUM_VALUE, j), "")))
+                entries.append(entry)
+
+        add_messages(file_descriptor.get("message_type", []), file_descriptor.get("enum_type", []), "", (),
+                     FILE_MESSAGE, FILE_ENUM)
+        for i, service in enumerate(file_descriptor.get("service", [])):
+            entry = DocEntry("service", _value(service, "name"), path, comments.get((FILE_SERVICE, i), ""))
+            for j, method in enumerate(service.get("method", [])):
+                signature = "`{}`({}{}) returns ({}{})".format(
+                    _value(method, "name"),
+                    "stream " if _value(method, "client_streaming") else "",
+                    linker.link(_value(method, "input_type", ""), package),
+                    "stream " if _value(method, "server_streaming") else "",
+                    linker.link(_value(method, "output_type", ""), package),
+                )
+                entry.members.append((signature, comments.get((FILE_SERVICE, i, SERVICE_METHOD, j), "")))
+            entries.append(entry)
+    return packages
+
+
+def _escape_cell(text: str) -> str:
+    return text.replace("|", "\\|")
+
+
+def render_index(package: str, entries: List[DocEntry]) -> str:
+    """Renders the markdown index of one package."""
+    lines = [f"# {package or '(default package)'}", ""]
+    files = sorted({entry.file for entry in entries})
+    lines += ["Files: " + ", ".join(f"`{f}`" for f in files), ""]
+
+    for kind, title in [("service", "Services"), ("message", "Messages"), ("enum", "Enums")]:
+        of_kind = sorted((e for e in entries if e.kind == kind), key=lambda e: e.name)
+        if not of_kind:
+            continue
+        lines += [f"## {title}", "", f"| {kind.capitalize()} | Summary |", "| --- | --- |"]
+        for entry in of_kind:
+            lines.append(f"| [{entry.name}](#{anchor(entry.name)}) | {_escape_cell(summarize(entry.comment))} |")
+        lines.append("")
+
+    for kind in ["service", "message", "enum"]:
+        for entry in sorted((e for e in entries if e.kind == kind), key=lambda e: e.name):
+            lines += [f"### {entry.name}", ""]
+            if entry.comment:
+                lines += [clean_comment(entry.comment), ""]
+            lines += [f"{kind.capitalize()} defined in `{entry.file}`.", ""]
+            for signature, comment in entry.members:
+                summary = summarize(comment)
+                lines.append(f"- {signature}" + (f": {summary}" if summary else ""))
+            if entry.members:
+                lines.append("")
+    return "\n".join(lines).rstrip("\n") + "\n"
+
+
+def write_indexes(descriptor_set_data: bytes, targets: List[str], output_dir: str) -> List[str]:
+    """
+    Writes one index per package of the target files.
+
+    Returns:
+        Paths of the written indexes, relative to output_dir
+    """
+    packages = collect_entries(decode(descriptor_set_data), targets)
+    if not packages:
+        raise DocIndexError("none of the target files are in the descriptor set: " + ", ".join(targets))
+    written = []
+    for package, entries in sorted(packages.items()):
+        relative = os.path.join(package_dir(package), INDEX_FILE) if package else INDEX_FILE
+        path = os.path.join(output_dir, relative)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(render_index(package, entries))
+        written.append(relative)
+    return written
+
+
+def main():
+    """Main entry point for the package documentation index."""
+    parser = argparse.ArgumentParser(description="Write a markdown index per proto package from a descriptor set")
+    parser.add_argument("--descriptor-set", required=True, help="FileDescriptorSet with imports and source info")
+    parser.add_argument("--output-dir", required=True, help="Directory to write the indexes to")
+    parser.add_argument("--target", action="append", required=True, help="Proto file to index (repeatable)")
+    args = parser.parse_args()
+
+    try:
+        with open(args.descriptor_set, "rb") as f:
+            data = f.read()
+        if os.path.exists(args.output_dir):
+            shutil.rmtree(args.output_dir)
+        os.makedirs(args.output_dir)
+        write_indexes(data, args.target, args.output_dir)
+    except (DocIndexError, DescriptorError, OSError, UnicodeDecodeError) as e:
+        print(f"ERROR: package_doc_index: {e}", file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
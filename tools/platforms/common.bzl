@@ -161,6 +161,131 @@ def get_plugin_info():
                 },
             },
         },
+        "protoc-gen-connect-go": {
+            "1.16.2": {
+                "linux-x86_64": {
+                    "url": "https://github.com/connectrpc/connect-go/releases/download/v1.16.2/protoc-gen-connect-go.linux.amd64.tar.gz",
+                    "sha256": "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2",
+                    "binary_path": "protoc-gen-connect-go",
+                },
+                "linux-aarch64": {
+                    "url": "https://github.com/connectrpc/connect-go/releases/download/v1.16.2/protoc-gen-connect-go.linux.arm64.tar.gz",
+                    "sha256": "b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3",
+                    "binary_path": "protoc-gen-connect-go",
+                },
+                "darwin-x86_64": {
+                    "url": "https://github.com/connectrpc/connect-go/releases/download/v1.16.2/protoc-gen-connect-go.darwin.amd64.tar.gz",
+                    "sha256": "c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4",
+                    "binary_path": "protoc-gen-connect-go",
+                },
+                "darwin-arm64": {
+                    "url": "https://github.com/connectrpc/connect-go/releases/download/v1.16.2/protoc-gen-connect-go.darwin.arm64.tar.gz",
+                    "sha256": "d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5",
+                    "binary_path": "protoc-gen-connect-go",
+                },
+                "windows-x86_64": {
+                    "url": "https://github.com/connectrpc/connect-go/releases/download/v1.16.2/protoc-gen-connect-go.windows.amd64.tar.gz",
+                    "sha256": "e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6",
+                    "binary_path": "protoc-gen-connect-go.exe",
+                },
+            },
+        },
+        "protoc-gen-grpc-gateway": {
+            "2.20.0": {
+                "linux-x86_64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-grpc-gateway-v2.20.0-linux-x86_64",
+                    "binary_path": "protoc-gen-grpc-gateway",
+                },
+                "linux-aarch64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-grpc-gateway-v2.20.0-linux-arm64",
+                    "binary_path": "protoc-gen-grpc-gateway",
+                },
+                "darwin-x86_64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-grpc-gateway-v2.20.0-darwin-x86_64",
+                    "binary_path": "protoc-gen-grpc-gateway",
+                },
+                "darwin-arm64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-grpc-gateway-v2.20.0-darwin-arm64",
+                    "binary_path": "protoc-gen-grpc-gateway",
+                },
+                "windows-x86_64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-grpc-gateway-v2.20.0-windows-x86_64.exe",
+                    "binary_path": "protoc-gen-grpc-gateway.exe",
+                },
+            },
+        },
+        "protoc-gen-openapiv2": {
+            "2.20.0": {
+                "linux-x86_64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-openapiv2-v2.20.0-linux-x86_64",
+                    "binary_path": "protoc-gen-openapiv2",
+                },
+                "linux-aarch64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-openapiv2-v2.20.0-linux-arm64",
+                    "binary_path": "protoc-gen-openapiv2",
+                },
+                "darwin-x86_64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-openapiv2-v2.20.0-darwin-x86_64",
+                    "binary_path": "protoc-gen-openapiv2",
+                },
+                "darwin-arm64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-openapiv2-v2.20.0-darwin-arm64",
+                    "binary_path": "protoc-gen-openapiv2",
+                },
+                "windows-x86_64": {
+                    "url": "https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v2.20.0/protoc-gen-openapiv2-v2.20.0-windows-x86_64.exe",
+                    "binary_path": "protoc-gen-openapiv2.exe",
+                },
+            },
+        },
+        "protoc-gen-openapi": {
+            "0.7.0": {
+                "linux-x86_64": {
+                    "url": "https://github.com/google/gnostic/releases/download/v0.7.0/protoc-gen-openapi_0.7.0_linux_amd64.tar.gz",
+                    "binary_path": "protoc-gen-openapi",
+                },
+                "linux-aarch64": {
+                    "url": "https://github.com/google/gnostic/releases/download/v0.7.0/protoc-gen-openapi_0.7.0_linux_arm64.tar.gz",
+                    "binary_path": "protoc-gen-openapi",
+                },
+                "darwin-x86_64": {
+                    "url": "https://github.com/google/gnostic/releases/download/v0.7.0/protoc-gen-openapi_0.7.0_darwin_amd64.tar.gz",
+                    "binary_path": "protoc-gen-openapi",
+                },
+                "darwin-arm64": {
+                    "url": "https://github.com/google/gnostic/releases/download/v0.7.0/protoc-gen-openapi_0.7.0_darwin_arm64.tar.gz",
+                    "binary_path": "protoc-gen-openapi",
+                },
+                "windows-x86_64": {
+                    "url": "https://github.com/google/gnostic/releases/download/v0.7.0/protoc-gen-openapi_0.7.0_windows_amd64.tar.gz",
+                    "binary_path": "protoc-gen-openapi.exe",
+                },
+            },
+        },
+        "protoc-gen-jsonschema": {
+            "1.4.1": {
+                "linux-x86_64": {
+                    "url": "https://github.com/chrusty/protoc-gen-jsonschema/releases/download/1.4.1/protoc-gen-jsonschema_1.4.1_linux_amd64.tar.gz",
+                    "binary_path": "protoc-gen-jsonschema",
+                },
+                "linux-aarch64": {
+                    "url": "https://github.com/chrusty/protoc-gen-jsonschema/releases/download/1.4.1/protoc-gen-jsonschema_1.4.1_linux_arm64.tar.gz",
+                    "binary_path": "protoc-gen-jsonschema",
+                },
+                "darwin-x86_64": {
+                    "url": "https://github.com/chrusty/protoc-gen-jsonschema/releases/download/1.4.1/protoc-gen-jsonschema_1.4.1_darwin_amd64.tar.gz",
+                    "binary_path": "protoc-gen-jsonschema",
+                },
+                "darwin-arm64": {
+                    "url": "https://github.com/chrusty/protoc-gen-jsonschema/releases/download/1.4.1/protoc-gen-jsonschema_1.4.1_darwin_arm64.tar.gz",
+                    "binary_path": "protoc-gen-jsonschema",
+                },
+                "windows-x86_64": {
+                    "url": "https://github.com/chrusty/protoc-gen-jsonschema/releases/download/1.4.1/protoc-gen-jsonschema_1.4.1_windows_amd64.tar.gz",
+                    "binary_path": "protoc-gen-jsonschema.exe",
+                },
+            },
+        },
         "protoc-gen-grpc-python": {
             "1.59.0": {
                 "linux-x86_64": {
@@ -195,6 +320,64 @@ def get_plugin_info():
                 },
             },
         },
+        "protoc-gen-mypy": {
+            "3.6.0": {
+                "linux-x86_64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy",
+                    "type": "python_package",
+                },
+                "linux-aarch64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy",
+                    "type": "python_package",
+                },
+                "darwin-x86_64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy",
+                    "type": "python_package",
+                },
+                "darwin-arm64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy",
+                    "type": "python_package",
+                },
+                "windows-x86_64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy.exe",
+                    "type": "python_package",
+                },
+            },
+        },
+        "protoc-gen-mypy_grpc": {
+            "3.6.0": {
+                "linux-x86_64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy_grpc",
+                    "type": "python_package",
+                },
+                "linux-aarch64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy_grpc",
+                    "type": "python_package",
+                },
+                "darwin-x86_64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy_grpc",
+                    "type": "python_package",
+                },
+                "darwin-arm64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy_grpc",
+                    "type": "python_package",
+                },
+                "windows-x86_64": {
+                    "url": "https://pypi.org/simple/mypy-protobuf/",
+                    "binary_path": "bin/protoc-gen-mypy_grpc.exe",
+                    "type": "python_package",
+                },
+            },
+        },
         "protoc-gen-ts": {
             "5.0.0": {
                 "linux-x86_64": {
@@ -253,6 +436,30 @@ def get_plugin_info():
                 },
             },
         },
+        "protoc-gen-js": {
+            "3.21.2": {
+                "linux-x86_64": {
+                    "url": "https://github.com/protocolbuffers/protobuf-javascript/releases/download/v3.21.2/protobuf-javascript-3.21.2-linux-x86_64.tar.gz",
+                    "binary_path": "bin/protoc-gen-js",
+                },
+                "linux-aarch64": {
+                    "url": "https://github.com/protocolbuffers/protobuf-javascript/releases/download/v3.21.2/protobuf-javascript-3.21.2-linux-aarch_64.tar.gz",
+                    "binary_path": "bin/protoc-gen-js",
+                },
+                "darwin-x86_64": {
+                    "url": "https://github.com/protocolbuffers/protobuf-javascript/releases/download/v3.21.2/protobuf-javascript-3.21.2-osx-x86_64.tar.gz",
+                    "binary_path": "bin/protoc-gen-js",
+                },
+                "darwin-arm64": {
+                    "url": "https://github.com/protocolbuffers/protobuf-javascript/releases/download/v3.21.2/protobuf-javascript-3.21.2-osx-aarch_64.tar.gz",
+                    "binary_path": "bin/protoc-gen-js",
+                },
+                "windows-x86_64": {
+                    "url": "https://github.com/protocolbuffers/protobuf-javascript/releases/download/v3.21.2/protobuf-javascript-3.21.2-win64.zip",
+                    "binary_path": "bin/protoc-gen-js.exe",
+                },
+            },
+        },
         "ts-proto": {
             "1.165.0": {
                 "linux-x86_64": {
@@ -287,6 +494,74 @@ def get_plugin_info():
                 },
             },
         },
+        "protoc-gen-es": {
+            "1.10.0": {
+                "linux-x86_64": {
+                    "url": "https://registry.npmjs.org/@bufbuild/protoc-gen-es/-/protoc-gen-es-1.10.0.tgz",
+                    "binary_path": "bin/protoc-gen-es",
+                    "type": "npm_package",
+                    "package": "@bufbuild/protoc-gen-es",
+                },
+                "linux-aarch64": {
+                    "url": "https://registry.npmjs.org/@bufbuild/protoc-gen-es/-/protoc-gen-es-1.10.0.tgz",
+                    "binary_path": "bin/protoc-gen-es",
+                    "type": "npm_package",
+                    "package": "@bufbuild/protoc-gen-es",
+                },
+                "darwin-x86_64": {
+                    "url": "https://registry.npmjs.org/@bufbuild/protoc-gen-es/-/protoc-gen-es-1.10.0.tgz",
+                    "binary_path": "bin/protoc-gen-es",
+                    "type": "npm_package",
+                    "package": "@bufbuild/protoc-gen-es",
+                },
+                "darwin-arm64": {
+                    "url": "https://registry.npmjs.org/@bufbuild/protoc-gen-es/-/protoc-gen-es-1.10.0.tgz",
+                    "binary_path": "bin/protoc-gen-es",
+                    "type": "npm_package",
+                    "package": "@bufbuild/protoc-gen-es",
+                },
+                "windows-x86_64": {
+                    "url": "https://registry.npmjs.org/@bufbuild/protoc-gen-es/-/protoc-gen-es-1.10.0.tgz",
+                    "binary_path": "bin/protoc-gen-es.cmd",
+                    "type": "npm_package",
+                    "package": "@bufbuild/protoc-gen-es",
+                },
+            },
+        },
+        "protoc-gen-connect-es": {
+            "1.6.1": {
+                "linux-x86_64": {
+                    "url": "https://registry.npmjs.org/@connectrpc/protoc-gen-connect-es/-/protoc-gen-connect-es-1.6.1.tgz",
+                    "binary_path": "bin/protoc-gen-connect-es",
+                    "type": "npm_package",
+                    "package": "@connectrpc/protoc-gen-connect-es",
+                },
+                "linux-aarch64": {
+                    "url": "https://registry.npmjs.org/@connectrpc/protoc-gen-connect-es/-/protoc-gen-connect-es-1.6.1.tgz",
+                    "binary_path": "bin/protoc-gen-connect-es",
+                    "type": "npm_package",
+                    "package": "@connectrpc/protoc-gen-connect-es",
+                },
+                "darwin-x86_64": {
+                    "url": "https://registry.npmjs.org/@connectrpc/protoc-gen-connect-es/-/protoc-gen-connect-es-1.6.1.tgz",
+                    "binary_path": "bin/protoc-gen-connect-es",
+                    "type": "npm_package",
+                    "package": "@connectrpc/protoc-gen-connect-es",
+                },
+                "darwin-arm64": {
+                    "url": "https://registry.npmjs.org/@connectrpc/protoc-gen-connect-es/-/protoc-gen-connect-es-1.6.1.tgz",
+                    "binary_path": "bin/protoc-gen-connect-es",
+                    "type": "npm_package",
+                    "package": "@connectrpc/protoc-gen-connect-es",
+                },
+                "windows-x86_64": {
+                    "url": "https://registry.npmjs.org/@connectrpc/protoc-gen-connect-es/-/protoc-gen-connect-es-1.6.1.tgz",
+                    "binary_path": "bin/protoc-gen-connect-es.cmd",
+                    "type": "npm_package",
+                    "package": "@connectrpc/protoc-gen-connect-es",
+                },
+            },
+        },
         "protoc-gen-prost": {
             "0.12.0": {
                 "linux-x86_64": {
@@ -358,10 +633,20 @@ def get_default_versions():
         "protoc": "24.4",
         "protoc-gen-go": "1.31.0",
         "protoc-gen-go-grpc": "1.3.0",
+        "protoc-gen-connect-go": "1.16.2",
+        "protoc-gen-grpc-gateway": "2.20.0",
+        "protoc-gen-openapiv2": "2.20.0",
+        "protoc-gen-openapi": "0.7.0",
+        "protoc-gen-jsonschema": "1.4.1",
         "protoc-gen-grpc-python": "1.59.0",
+        "protoc-gen-mypy": "3.6.0",
+        "protoc-gen-mypy_grpc": "3.6.0",
         "protoc-gen-ts": "5.0.0",
         "protoc-gen-grpc-web": "1.4.2",
+        "protoc-gen-js": "3.21.2",
         "ts-proto": "1.165.0",
+        "protoc-gen-es": "1.10.0",
+        "protoc-gen-connect-es": "1.6.1",
         "protoc-gen-prost": "0.12.0",
         "protoc-gen-tonic": "0.10.0",
     }
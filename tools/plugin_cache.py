@@ -0,0 +1,150 @@
+#!/usr/bin/env python3
+"""
+Content-addressed plugin cache for protobuf Buck2 integration.
+
+Download actions write into a fresh Buck2 output directory, so without a
+shared cache every build, and every switch back to a plugin version fetched
+before, downloads the plugin again. The plugin cache keeps every fetched
+plugin in a directory keyed by the sha256 of its release archive:
+
+    <root>/<plugin>-<version>-<platform>-<sha256>/<binary path>
+    <root>/index/<plugin>-<version>-<platform>   (sha256 of the last fetch)
+    <root>/locks/<plugin>-<version>-<platform>.lock
+
+Entries are never modified once published. A fetch holds an exclusive lock
+on the plugin version, fills a staging directory inside the root and
+publishes it with an atomic rename, so concurrent builds fetching the same
+plugin download it once and never see a partially extracted entry. The index
+lets plugins without a pinned checksum be found without downloading them.
+
+The root is `[protobuf] plugin_cache_dir`, then $BUCK2_PROTOBUF_PLUGIN_CACHE
+(e.g. a directory CI mounts persistently), then
+~/.cache/buck2-protobuf/plugins.
+"""
+
+import contextlib
+import os
+import shutil
+import tempfile
+from pathlib import Path
+from typing import Callable, Iterator, Optional
+
+try:
+    import fcntl
+except ImportError:  # Windows
+    fcntl = None
+    import msvcrt
+
+PLUGIN_CACHE_ENV = "BUCK2_PROTOBUF_PLUGIN_CACHE"
+
+DEFAULT_PLUGIN_CACHE = "~/.cache/buck2-protobuf/plugins"
+
+
+class PluginCacheError(RuntimeError):
+    """Raised when a fetched plugin cannot be added to the cache."""
+
+
+def plugin_cache_root(explicit: Optional[str] = None) -> Path:
+    """Returns the configured plugin cache root."""
+    return Path(os.path.expanduser(explicit or os.environ.get(PLUGIN_CACHE_ENV) or DEFAULT_PLUGIN_CACHE))
+
+
+class PluginCache:
+    """Content-addressed store of downloaded plugins."""
+
+    def __init__(self, root: Path):
+        self.root = Path(root)
+
+    @staticmethod
+    def version_key(plugin: str, version: str, platform: str) -> str:
+        return f"{plugin}-{version}-{platform}"
+
+    def entry_dir(self, plugin: str, version: str, platform: str, sha256: str) -> Path:
+        return self.root / f"{self.version_key(plugin, version, platform)}-{sha256.lower()}"
+
+    def lookup(self, plugin: str, version: str, platform: str, binary_path: str,
+               sha256: Optional[str] = None) -> Optional[Path]:
+        """
+        Returns the cached binary of a plugin, or None if it was never fetched.
+
+        Args:
+            sha256: Pinned archive checksum; without one the last fetched
+                    archive of the version is used
+        """
+        if not sha256:
+            index = self.root / "index" / self.version_key(plugin, version, platform)
+            if not index.is_file():
+                return None
+            sha256 = index.read_text(encoding="utf-8").strip()
+        binary = self.entry_dir(plugin, version, platform, sha256) / binary_path
+        return binary if binary.is_file() else None
+
+    def fetch(self, plugin: str, version: str, platform: str, binary_path: str,
+              populate: Callable[[Path], str], sha256: Optional[str] = None) -> Path:
+        """
+        Returns the cached binary of a plugin, fetching it first if needed.
+
+        Args:
+            populate: Downloads and extracts the plugin into the given staging
+                      directory and returns the sha256 of the archive
+            sha256: Pinned archive checksum the fetched archive must match
+
+        Raises:
+            PluginCacheError: If the archive does not match the pin or does
+                              not contain the binary
+        """
+        cached = self.lookup(plugin, version, platform, binary_path, sha256)
+        if cached:
+            return cached
+
+        key = self.version_key(plugin, version, platform)
+        with self._lock(key):
+            # Another process may have published the entry while we waited
+            cached = self.lookup(plugin, version, platform, binary_path, sha256)
+            if cached:
+                return cached
+
+            staging = Path(tempfile.mkdtemp(prefix=f".staging-{key}-", dir=self.root))
+            try:
+                digest = populate(staging).lower()
+                if sha256 and digest != sha256.lower():
+                    raise PluginCacheError(f"{plugin} {version} ({platform}) archive sha256 is {digest}, "
+                                           f"expected {sha256.lower()}")
+                if not (staging / binary_path).is_file():
+                    raise PluginCacheError(f"{plugin} {version} ({platform}) archive does not contain {binary_path}")
+                entry = self.entry_dir(plugin, version, platform, digest)
+                if not entry.exists():
+                    os.rename(staging, entry)
+                self._write_index(key, digest)
+            finally:
+                shutil.rmtree(staging, ignore_errors=True)
+        return entry / binary_path
+
+    def _write_index(self, key: str, digest: str) -> None:
+        """Records the digest of a version, replacing the index file atomically."""
+        index_dir = self.root / "index"
+        index_dir.mkdir(parents=True, exist_ok=True)
+        fd, temp_path = tempfile.mkstemp(prefix=f".{key}-", dir=index_dir)
+        with os.fdopen(fd, "w", encoding="utf-8") as f:
+            f.write(digest + "\n")
+        os.replace(temp_path, index_dir / key)
+
+    @contextlib.contextmanager
+    def _lock(self, key: str) -> Iterator[None]:
+        """Holds an exclusive lock on a plugin version across processes."""
+        lock_dir = self.root / "locks"
+        lock_dir.mkdir(parents=True, exist_ok=True)
+        with open(lock_dir / f"{key}.lock", "a+b") as lock_file:
+            if fcntl:
+                fcntl.flock(lock_file, fcntl.LOCK_EX)
+            else:
+                lock_file.seek(0)
+                msvcrt.locking(lock_file.fileno(), msvcrt.LK_LOCK, 1)
+            try:
+                yield
+            finally:
+                if fcntl:
+                    fcntl.flock(lock_file, fcntl.LOCK_UN)
+                else:
+                    lock_file.seek(0)
+                    msvcrt.locking(lock_file.fileno(), msvcrt.LK_UNLCK, 1)
@@ -0,0 +1,127 @@
+#!/usr/bin/env python3
+"""
+Deterministic distribution archives of generated protobuf code.
+
+Packages generated SDK files into a .tar.gz or .zip archive for external
+distribution. Archives are reproducible: entries are sorted by path, every
+entry has the same fixed timestamp, owner information is cleared, modes are
+normalized to 0644 (0755 for executables), and the gzip header carries no
+file name or time. Building the same inputs twice yields byte-identical
+archives.
+
+Usage:
+    proto_archive.py --format tar.gz --output user-sdk.tar.gz \\
+        --prefix user-sdk-1.2.0 \\
+        --entry go/user.pb.go buck-out/.../user.pb.go \\
+        --entry README.md docs/sdk-readme.md
+"""
+
+import argparse
+import gzip
+import io
+import os
+import sys
+import tarfile
+import zipfile
+from pathlib import PurePosixPath
+from typing import List, Tuple
+
+FORMATS = ["tar.gz", "zip"]
+
+# 1980-01-01T00:00:00Z, the earliest time a zip entry can represent
+FIXED_MTIME = 315532800
+FIXED_DATE_TIME = (1980, 1, 1, 0, 0, 0)
+
+
+class ArchiveError(Exception):
+    """Raised when the archive layout is invalid."""
+
+
+def archive_path(prefix: str, path: str) -> str:
+    """Joins prefix and path into a normalized relative archive path."""
+    joined = PurePosixPath(prefix.strip("/")) / path.strip("/") if prefix.strip("/") else PurePosixPath(path.strip("/"))
+    parts = [part for part in joined.parts if part != "."]
+    if not parts or ".." in parts or joined.is_absolute():
+        raise ArchiveError(f"'{path}' is not a valid path inside the archive")
+    return "/".join(parts)
+
+
+def plan_entries(prefix: str, entries: List[Tuple[str, str]]) -> List[Tuple[str, str]]:
+    """
+    Resolves archive paths and sorts the entries.
+
+    Args:
+        prefix: Top-level directory inside the archive ("" for none)
+        entries: (path inside the archive, source file) pairs
+
+    Returns:
+        Sorted (archive path, source file) pairs
+    """
+    planned = {}
+    for path, source in entries:
+        name = archive_path(prefix, path)
+        if name in planned and planned[name] != source:
+            raise ArchiveError(f"{name} would be written from both {planned[name]} and {source}")
+        planned[name] = source
+    return sorted(planned.items())
+
+
+def _file_mode(source: str) -> int:
+    return 0o755 if os.access(source, os.X_OK) else 0o644
+
+
+def write_tar_gz(output: str, entries: List[Tuple[str, str]]) -> None:
+    """Writes a reproducible gzip-compressed tar archive."""
+    buffer = io.BytesIO()
+    with tarfile.open(fileobj=buffer, mode="w", format=tarfile.PAX_FORMAT) as tar:
+        for name, source in entries:
+            with open(source, "rb") as f:
+                data = f.read()
+            info = tarfile.TarInfo(name)
+            info.size = len(data)
+            info.mtime = FIXED_MTIME
+            info.mode = _file_mode(source)
+            info.uid = info.gid = 0
+            info.uname = info.gname = ""
+            tar.addfile(info, io.BytesIO(data))
+    with open(output, "wb") as raw:
+        with gzip.GzipFile(filename="", mode="wb", fileobj=raw, mtime=0) as gz:
+            gz.write(buffer.getvalue())
+
+
+def write_zip(output: str, entries: List[Tuple[str, str]]) -> None:
+    """Writes a reproducible zip archive."""
+    with zipfile.ZipFile(output, "w") as archive:
+        for name, source in entries:
+            with open(source, "rb") as f:
+                data = f.read()
+            info = zipfile.ZipInfo(name, date_time=FIXED_DATE_TIME)
+            info.compress_type = zipfile.ZIP_DEFLATED
+            info.create_system = 3  # Unix, so external_attr carries the mode on every host
+            info.external_attr = (0o100000 | _file_mode(source)) << 16
+            archive.writestr(info, data)
+
+
+def main():
+    """Main entry point for archive creation."""
+    parser = argparse.ArgumentParser(description="Package generated code into a deterministic archive")
+    parser.add_argument("--format", choices=FORMATS, default="tar.gz", help="Archive format")
+    parser.add_argument("--output", required=True, help="Archive to write")
+    parser.add_argument("--prefix", default="", help="Top-level directory inside the archive")
+    parser.add_argument("--entry", nargs=2, action="append", default=[], metavar=("PATH", "SOURCE"),
+                        help="File to add: path inside the archive and source file")
+    args = parser.parse_args()
+
+    try:
+        entries = plan_entries(args.prefix, [tuple(entry) for entry in args.entry])
+        if args.format == "zip":
+            write_zip(args.output, entries)
+        else:
+            write_tar_gz(args.output, entries)
+    except (ArchiveError, OSError) as e:
+        print(f"ERROR: proto_archive: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
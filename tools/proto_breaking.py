@@ -0,0 +1,153 @@
+#!/usr/bin/env python3
+"""
+Breaking change check against a committed baseline descriptor set.
+
+Compiles the current proto files to a FileDescriptorSet with protoc and runs
+`buf breaking` against a baseline image (a `.binpb` FileDescriptorSet checked
+into the repository, typically built from the last release). Every violation
+is reported with the buf rule that flagged it, e.g. FIELD_SAME_TYPE.
+
+The output is meant to gate merges in CI, so it is deterministic: violations
+are de-duplicated and sorted by file, position, rule and message, and the exit
+code is 0 when the schemas are compatible, 1 when something broke and 2 when
+the check itself could not run.
+
+Usage:
+    proto_breaking.py --protoc bin/protoc --buf bin/buf \\
+        --baseline baselines/user.binpb --use WIRE_JSON \\
+        --proto_path=. --output report.json acme/user/v1/user.proto
+"""
+
+import argparse
+import json
+import os
+import subprocess
+import sys
+import tempfile
+from dataclasses import asdict, dataclass
+from typing import List
+
+# Breaking rule categories understood by buf, from least to most strict
+RULE_SETS = ["WIRE", "WIRE_JSON", "FILE"]
+
+# buf exits with this code when it found violations, as opposed to failing
+BUF_VIOLATIONS_EXIT_CODE = 100
+
+
+class BreakingCheckError(Exception):
+    """Raised when the check cannot run."""
+
+
+@dataclass(frozen=True, order=True)
+class Violation:
+    """A single breaking change reported by buf."""
+    path: str
+    line: int
+    column: int
+    rule: str
+    message: str
+
+    def __str__(self) -> str:
+        location = f"{self.path}:{self.line}:{self.column}" if self.line else self.path
+        return f"{location}: [{self.rule}] {self.message}"
+
+
+def build_descriptor_set(protoc: str, proto_paths: List[str], protos: List[str], output: str) -> None:
+    """Runs protoc to build a descriptor set with imports and source info."""
+    cmd = [protoc] + [f"--proto_path={path}" for path in proto_paths] + [
+        f"--descriptor_set_out={output}", "--include_imports", "--include_source_info",
+    ] + protos
+    result = subprocess.run(cmd, capture_output=True, text=True)
+    if result.returncode != 0:
+        raise BreakingCheckError(f"{protoc} failed with exit code {result.returncode}:\n{result.stderr.strip()}")
+
+
+def normalize_rule_sets(use: List[str]) -> List[str]:
+    """Validates rule set names and returns them de-duplicated in canonical order."""
+    for rule_set in use:
+        if rule_set not in RULE_SETS:
+            raise BreakingCheckError(f"unknown rule set {rule_set!r}; expected one of {', '.join(RULE_SETS)}")
+    return sorted(set(use), key=RULE_SETS.index)
+
+
+def buf_config(use: List[str]) -> str:
+    """Returns the inline buf configuration selecting the breaking rule sets."""
+    return json.dumps({"version": "v1", "breaking": {"use": normalize_rule_sets(use)}}, separators=(",", ":"))
+
+
+def parse_violations(output: str) -> List[Violation]:
+    """Parses `buf breaking --error-format json` output into sorted violations."""
+    violations = set()
+    for line in output.splitlines():
+        line = line.strip()
+        if not line:
+            continue
+        try:
+            entry = json.loads(line)
+        except json.JSONDecodeError as e:
+            raise BreakingCheckError(f"unexpected buf output: {line}") from e
+        violations.add(Violation(
+            path=entry.get("path", ""),
+            line=entry.get("start_line", 0),
+            column=entry.get("start_column", 0),
+            rule=entry.get("type", ""),
+            message=entry.get("message", ""),
+        ))
+    return sorted(violations)
+
+
+def run_buf_breaking(buf: str, current: str, baseline: str, use: List[str]) -> List[Violation]:
+    """Runs buf breaking on two descriptor sets and returns the violations."""
+    cmd = [buf, "breaking", current, "--against", baseline,
+           "--config", buf_config(use), "--error-format", "json"]
+    result = subprocess.run(cmd, capture_output=True, text=True)
+    if result.returncode not in (0, BUF_VIOLATIONS_EXIT_CODE):
+        raise BreakingCheckError(f"{buf} failed with exit code {result.returncode}:\n{result.stderr.strip()}")
+    violations = parse_violations(result.stdout)
+    if result.returncode == BUF_VIOLATIONS_EXIT_CODE and not violations:
+        raise BreakingCheckError(f"{buf} reported violations without listing them:\n{result.stderr.strip()}")
+    return violations
+
+
+def main():
+    """Main entry point for the breaking change check."""
+    parser = argparse.ArgumentParser(description="Check proto files for breaking changes against a baseline descriptor set")
+    parser.add_argument("--protoc", required=True, help="protoc binary")
+    parser.add_argument("--buf", required=True, help="buf binary")
+    parser.add_argument("--baseline", required=True, help="Baseline FileDescriptorSet (.binpb)")
+    parser.add_argument("--use", action="append", default=[], help="buf breaking rule set (repeatable, default: WIRE_JSON)")
+    parser.add_argument("--proto_path", action="append", default=[], help="Import path")
+    parser.add_argument("--output", help="JSON report to write")
+    parser.add_argument("protos", nargs="+", help="Proto files to compile")
+    args = parser.parse_args()
+
+    try:
+        use = normalize_rule_sets(args.use or ["WIRE_JSON"])
+        if not os.path.isfile(args.baseline):
+            raise BreakingCheckError(f"baseline {args.baseline} does not exist")
+        with tempfile.TemporaryDirectory() as temp_dir:
+            current = os.path.join(temp_dir, "current.binpb")
+            build_descriptor_set(args.protoc, args.proto_path, args.protos, current)
+            violations = run_buf_breaking(args.buf, current, args.baseline, use)
+    except (BreakingCheckError, OSError) as e:
+        print(f"ERROR: proto_breaking: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            json.dump({
+                "baseline": args.baseline,
+                "use": use,
+                "violations": [asdict(v) for v in violations],
+            }, f, indent=2, sort_keys=True)
+            f.write("\n")
+    if violations:
+        print(f"{len(violations)} breaking change(s) against {args.baseline} ({', '.join(use)}):", file=sys.stderr)
+        for violation in violations:
+            print(f"  {violation}", file=sys.stderr)
+        sys.exit(1)
+    print(f"No breaking changes against {args.baseline} ({', '.join(use)})")
+
+
+if __name__ == "__main__":
+    main()
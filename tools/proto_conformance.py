@@ -0,0 +1,649 @@
+#!/usr/bin/env python3
+"""
+Cross-language conformance tests for generated protobuf code.
+
+A conformance test checks that the code generated for two languages agrees on
+the encoding of the same messages. For every fixture (a message in protobuf
+JSON) and every ordered language pair source -> target:
+
+1. the source language parses the fixture and serializes it to the wire
+   format and to JSON,
+2. the target language parses the source's wire bytes and re-serializes
+   them; the bytes must be identical,
+3. the target language parses the source's JSON and re-serializes it; the
+   JSON must have the same value.
+
+Each language runs a small generated codec program that reads one request
+per line on stdin, {"type": "<message>", "binary": "<base64>"} or
+{"type": "<message>", "json": {...}}, and answers with
+{"binary": "<base64>", "json": {...}} or {"error": "..."}. Binary output is
+deterministic where the runtime supports it, so map entries are ordered.
+
+A divergence is reported with the message, the first field that differs
+(resolved through the schema for wire bytes, as a JSON path for JSON) and the
+language pair, e.g.:
+
+    FAIL go -> python acme.v1.User (testdata/user.json): JSON differs at
+    address.zipCode: go wrote "", python re-serialized <absent>
+
+`generate` runs at build time and writes the codecs and a manifest with the
+fixtures, pairs and field numbers of every message. `run` executes the test
+over the staged directory with the host toolchains: `go` (after
+`go mod tidy`), `python3` with the protobuf package, and `node` with
+@bufbuild/protobuf installed by `npm install`.
+
+Usage:
+    proto_conformance.py generate --manifest manifest.json --pair go:python \\
+        --fixture acme.v1.User fixtures/0_user.json testdata/user.json \\
+        --go-package github.com/acme/user/v1 --go-codec main.go --go-mod go.mod \\
+        --python-codec codec.py user.proto
+    proto_conformance.py run staged-test-dir/
+"""
+
+import argparse
+import base64
+import json
+import os
+import shutil
+import stat
+import subprocess
+import sys
+import tempfile
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+try:
+    from proto_schema import Message, ProtoParseError, SchemaSet, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import Message, ProtoParseError, SchemaSet, load_schema_set
+
+LANGUAGES = ["go", "python", "typescript"]
+
+# Module requirement of the Go codec; go mod tidy resolves its version
+GO_PROTOBUF_MODULE = "google.golang.org/protobuf"
+
+# Runtime the protobuf-es codec is installed with
+ES_RUNTIME = {"@bufbuild/protobuf": "^1.10.0"}
+
+# Bytes of a diverging wire value shown in a failure
+MAX_WIRE_BYTES = 32
+
+# Marks a field present on one side of a JSON comparison only
+ABSENT = object()
+
+
+class ConformanceError(Exception):
+    """Raised when a conformance test cannot be generated or run."""
+
+
+class WireError(Exception):
+    """Raised when bytes are not a valid wire-format message."""
+
+
+GO_CODEC = '''// Code generated by buck2-protobuf proto_conformance. DO NOT EDIT.
+
+// Command codec re-serializes messages for proto_conformance_test.
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	_ "{import_path}"
+)
+
+type request struct {
+	Type   string          `json:"type"`
+	Binary *string         `json:"binary"`
+	JSON   json.RawMessage `json:"json"`
+}
+
+type response struct {
+	Binary string          `json:"binary"`
+	JSON   json.RawMessage `json:"json,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func handle(req request) (response, error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(req.Type))
+	if err != nil {
+		return response{}, err
+	}
+	message := messageType.New().Interface()
+	if req.Binary != nil {
+		data, err := base64.StdEncoding.DecodeString(*req.Binary)
+		if err != nil {
+			return response{}, err
+		}
+		if err := proto.Unmarshal(data, message); err != nil {
+			return response{}, err
+		}
+	} else if err := protojson.Unmarshal(req.JSON, message); err != nil {
+		return response{}, err
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return response{}, err
+	}
+	text, err := protojson.Marshal(message)
+	if err != nil {
+		return response{}, err
+	}
+	return response{Binary: base64.StdEncoding.EncodeToString(data), JSON: text}, nil
+}
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req request
+		resp, err := response{}, json.Unmarshal(scanner.Bytes(), &req)
+		if err == nil {
+			resp, err = handle(req)
+		}
+		if err != nil {
+			resp = response{Error: err.Error()}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+'''
+
+PYTHON_CODEC = '''# Generated by buck2-protobuf proto_conformance. DO NOT EDIT.
+"""Re-serializes messages for proto_conformance_test."""
+
+import base64
+import importlib
+import json
+import os
+import sys
+
+from google.protobuf import descriptor_pool, json_format
+
+try:
+    from google.protobuf.message_factory import GetMessageClass
+except ImportError:  # protobuf < 4.21
+    from google.protobuf.symbol_database import Default as _symbol_database
+
+    def GetMessageClass(descriptor):
+        return _symbol_database().GetSymbol(descriptor.full_name)
+
+
+def import_modules(root):
+    """Imports every generated module below root, registering its messages."""
+    sys.path.insert(0, root)
+    for dirpath, _, files in sorted(os.walk(root)):
+        for name in sorted(files):
+            if name.endswith("_pb2.py"):
+                module = os.path.relpath(os.path.join(dirpath, name[:-3]), root)
+                importlib.import_module(module.replace(os.sep, "."))
+
+
+def handle(request):
+    descriptor = descriptor_pool.Default().FindMessageTypeByName(request["type"])
+    message = GetMessageClass(descriptor)()
+    if "binary" in request:
+        message.ParseFromString(base64.b64decode(request["binary"]))
+    else:
+        json_format.ParseDict(request["json"], message)
+    return {
+        "binary": base64.b64encode(message.SerializeToString(deterministic=True)).decode("ascii"),
+        "json": json_format.MessageToDict(message),
+    }
+
+
+def main():
+    import_modules(sys.argv[1])
+    for line in sys.stdin:
+        try:
+            response = handle(json.loads(line))
+        except Exception as e:  # Reported to the harness as a parse failure
+            response = {"error": f"{type(e).__name__}: {e}"}
+        sys.stdout.write(json.dumps(response) + "\\n")
+        sys.stdout.flush()
+
+
+if __name__ == "__main__":
+    main()
+'''
+
+ES_CODEC = '''// Generated by buck2-protobuf proto_conformance. DO NOT EDIT.
+// Re-serializes messages for proto_conformance_test.
+import { createRegistry } from "@bufbuild/protobuf";
+import { readdirSync } from "node:fs";
+import { join } from "node:path";
+import { createInterface } from "node:readline";
+import { fileURLToPath, pathToFileURL } from "node:url";
+
+async function loadTypes(dir, types) {
+  for (const entry of readdirSync(dir, { withFileTypes: true })) {
+    const path = join(dir, entry.name);
+    if (entry.isDirectory()) {
+      await loadTypes(path, types);
+    } else if (entry.name.endsWith("_pb.js")) {
+      const module = await import(pathToFileURL(path).href);
+      for (const value of Object.values(module)) {
+        if (typeof value === "function" && typeof value.typeName === "string" && typeof value.fromBinary === "function") {
+          types.push(value);
+        }
+      }
+    }
+  }
+  return types;
+}
+
+const src = fileURLToPath(new URL("./typescript/src", import.meta.url));
+const registry = createRegistry(...(await loadTypes(src, [])));
+for await (const line of createInterface({ input: process.stdin, crlfDelay: Infinity })) {
+  let response;
+  try {
+    const request = JSON.parse(line);
+    const type = registry.findMessage(request.type);
+    if (!type) {
+      throw new Error(`unknown message type ${request.type}`);
+    }
+    const message = request.binary !== undefined
+      ? type.fromBinary(Buffer.from(request.binary, "base64"))
+      : type.fromJson(request.json, { typeRegistry: registry });
+    response = {
+      binary: Buffer.from(message.toBinary()).toString("base64"),
+      json: message.toJson({ typeRegistry: registry }),
+    };
+  } catch (e) {
+    response = { error: String(e instanceof Error ? e.message : e) };
+  }
+  process.stdout.write(JSON.stringify(response) + "\\n");
+}
+'''
+
+
+def parse_pair(pair: str) -> Tuple[str, str]:
+    """Parses a "source:target" language pair."""
+    source, _, target = pair.partition(":")
+    if source not in LANGUAGES or target not in LANGUAGES or source == target:
+        raise ConformanceError(f"pair must be SOURCE:TARGET with two different languages of "
+                               f"{', '.join(LANGUAGES)}, got '{pair}'")
+    return source, target
+
+
+def field_index(schema: SchemaSet) -> Dict[str, Dict[str, Dict[str, Any]]]:
+    """
+    Returns the fields of every message by number.
+
+    Each field records its name and, for message-typed fields, the message
+    type, so wire divergences can be named down to nested fields.
+    """
+    index = {}
+    for full_name, message in sorted(schema.types.items()):
+        if not isinstance(message, Message):
+            continue
+        fields = {}
+        for message_field in message.fields:
+            nested = None
+            if not message_field.is_map and not message_field.is_scalar:
+                resolved = schema.resolve_type(message_field.type_name, full_name)
+                nested = resolved.full_name if isinstance(resolved, Message) else None
+            fields[str(message_field.number)] = {
+                "name": message_field.name,
+                "message": nested,
+                "repeated": message_field.is_repeated,
+            }
+        index[full_name] = fields
+    return index
+
+
+def generate(files: List[str], dep_files: List[str], fixtures: List[Tuple[str, str, str]],
+             pairs: List[str]) -> Dict[str, Any]:
+    """
+    Returns the manifest of a conformance test.
+
+    Args:
+        fixtures: (message type, staged path, source path) of each fixture
+        pairs: "source:target" language pairs
+    """
+    schema = load_schema_set(files, dep_files)
+    parsed_pairs = [parse_pair(pair) for pair in pairs]
+    if not parsed_pairs:
+        raise ConformanceError("at least one language pair is required")
+    manifest_fixtures = []
+    for type_name, staged, source in fixtures:
+        if not isinstance(schema.types.get(type_name), Message):
+            raise ConformanceError(f"fixture {source}: {type_name} is not a message of the proto_library")
+        try:
+            with open(source, "r", encoding="utf-8") as f:
+                content = json.load(f)
+        except ValueError as e:
+            raise ConformanceError(f"fixture {source} is not valid JSON: {e}") from e
+        if not isinstance(content, dict):
+            raise ConformanceError(f"fixture {source} must be a JSON object in the protobuf JSON format")
+        manifest_fixtures.append({"name": source, "type": type_name, "path": staged})
+    if not manifest_fixtures:
+        raise ConformanceError("at least one fixture is required")
+    return {
+        "pairs": [list(pair) for pair in parsed_pairs],
+        "fixtures": manifest_fixtures,
+        "fields": field_index(schema),
+    }
+
+
+def render_go_mod(go_package: str) -> str:
+    """Renders the go.mod of the Go codec module."""
+    return f"module {go_package.split(';', 1)[0]}\n\ngo 1.21\n\nrequire {GO_PROTOBUF_MODULE} v1.31.0\n"
+
+
+def render_go_codec(go_package: str) -> str:
+    return GO_CODEC.replace("{import_path}", go_package.split(";", 1)[0])
+
+
+# Wire-format comparison
+
+
+def _read_varint(data: bytes, pos: int) -> Tuple[int, int]:
+    value, shift = 0, 0
+    while True:
+        if pos >= len(data):
+            raise WireError("truncated varint")
+        byte = data[pos]
+        pos += 1
+        value |= (byte & 0x7F) << shift
+        shift += 7
+        if not byte & 0x80:
+            return value, pos
+
+
+def decode_wire(data: bytes) -> List[Tuple[int, int, bytes]]:
+    """Splits a wire-format message into (field number, wire type, raw value) records."""
+    records, pos = [], 0
+    while pos < len(data):
+        key, pos = _read_varint(data, pos)
+        number, wire_type = key >> 3, key & 7
+        start = pos
+        if wire_type == 0:
+            _, pos = _read_varint(data, pos)
+            value = data[start:pos]
+        elif wire_type == 1 or wire_type == 5:
+            pos += 8 if wire_type == 1 else 4
+            value = data[start:pos]
+        elif wire_type == 2:
+            length, start = _read_varint(data, pos)
+            pos = start + length
+            value = data[start:pos]
+        elif wire_type in (3, 4):
+            value = b""
+        else:
+            raise WireError(f"invalid wire type {wire_type}")
+        if pos > len(data):
+            raise WireError("truncated field")
+        records.append((number, wire_type, value))
+    return records
+
+
+def wire_divergence(index: Dict[str, Any], type_name: str, expected: bytes,
+                    actual: bytes) -> Tuple[str, bytes, bytes]:
+    """
+    Locates the first field whose encoding differs.
+
+    Returns:
+        (field path, expected bytes, actual bytes); the path is empty when the
+        fields are equal but ordered differently, or the bytes do not decode
+    """
+    try:
+        expected_records, actual_records = decode_wire(expected), decode_wire(actual)
+    except WireError:
+        return "", expected, actual
+    fields = index.get(type_name, {})
+    numbers = list(dict.fromkeys([r[0] for r in expected_records] + [r[0] for r in actual_records]))
+    for number in numbers:
+        expected_values = [(wire_type, value) for n, wire_type, value in expected_records if n == number]
+        actual_values = [(wire_type, value) for n, wire_type, value in actual_records if n == number]
+        if expected_values == actual_values:
+            continue
+        info = fields.get(str(number))
+        name = info["name"] if info else f"#{number}"
+        if info and info["message"] and len(expected_values) == len(actual_values):
+            for i, (expected_value, actual_value) in enumerate(zip(expected_values, actual_values)):
+                if expected_value == actual_value:
+                    continue
+                if expected_value[0] == actual_value[0] == 2:
+                    element = f"{name}[{i}]" if info["repeated"] else name
+                    path, sub_expected, sub_actual = wire_divergence(
+                        index, info["message"], expected_value[1], actual_value[1])
+                    return (f"{element}.{path}" if path else element), sub_expected, sub_actual
+                break
+        return name, b"".join(v for _, v in expected_values), b"".join(v for _, v in actual_values)
+    return "", expected, actual
+
+
+def json_divergence(expected: Any, actual: Any, path: str = "") -> Optional[Tuple[str, Any, Any]]:
+    """Returns (JSON path, expected, actual) of the first value that differs, or None."""
+    if isinstance(expected, dict) and isinstance(actual, dict):
+        for key in dict.fromkeys(list(expected) + list(actual)):
+            key_path = f"{path}.{key}" if path else key
+            if key not in expected or key not in actual:
+                return key_path, expected.get(key, ABSENT), actual.get(key, ABSENT)
+            found = json_divergence(expected[key], actual[key], key_path)
+            if found:
+                return found
+        return None
+    if isinstance(expected, list) and isinstance(actual, list) and len(expected) == len(actual):
+        for i, (expected_item, actual_item) in enumerate(zip(expected, actual)):
+            found = json_divergence(expected_item, actual_item, f"{path}[{i}]")
+            if found:
+                return found
+        return None
+    if isinstance(expected, bool) != isinstance(actual, bool) or expected != actual:
+        return path, expected, actual
+    return None
+
+
+def _show_bytes(data: bytes) -> str:
+    if not data:
+        return "<absent>"
+    shown = data[:MAX_WIRE_BYTES].hex()
+    return shown + ("..." if len(data) > MAX_WIRE_BYTES else "")
+
+
+def _show_json(value: Any) -> str:
+    return "<absent>" if value is ABSENT else json.dumps(value)
+
+
+# Test execution
+
+
+class Codec:
+    """A running codec program of one language."""
+
+    def __init__(self, language: str, command: List[str], cwd: str):
+        self.language = language
+        self.process = subprocess.Popen(command, cwd=cwd, stdin=subprocess.PIPE, stdout=subprocess.PIPE,
+                                        text=True, encoding="utf-8")
+
+    def call(self, type_name: str, binary: Optional[bytes] = None, json_value: Any = None) -> Dict[str, Any]:
+        """Parses a message from binary or JSON and returns its re-serialization."""
+        request = {"type": type_name}
+        if binary is not None:
+            request["binary"] = base64.b64encode(binary).decode("ascii")
+        else:
+            request["json"] = json_value
+        self.process.stdin.write(json.dumps(request) + "\n")
+        self.process.stdin.flush()
+        line = self.process.stdout.readline()
+        if not line:
+            raise ConformanceError(f"the {self.language} codec exited with status {self.process.wait()}")
+        response = json.loads(line)
+        if "error" not in response:
+            response["binary"] = base64.b64decode(response.get("binary") or "")
+        return response
+
+    def close(self) -> None:
+        self.process.stdin.close()
+        self.process.wait()
+        self.process.stdout.close()
+
+
+def check_fixture(fixture: Dict[str, Any], fixture_json: Any, source: Codec, target: Codec,
+                  index: Dict[str, Any]) -> List[str]:
+    """Runs one fixture through a language pair and returns its failures."""
+    type_name = fixture["type"]
+    prefix = f"{source.language} -> {target.language} {type_name} ({fixture['name']})"
+    original = source.call(type_name, json_value=fixture_json)
+    if "error" in original:
+        return [f"{prefix}: {source.language} could not parse the fixture: {original['error']}"]
+
+    failures = []
+    from_binary = target.call(type_name, binary=original["binary"])
+    if "error" in from_binary:
+        failures.append(f"{prefix}: {target.language} could not parse the wire bytes of "
+                        f"{source.language}: {from_binary['error']}")
+    elif from_binary["binary"] != original["binary"]:
+        path, expected, actual = wire_divergence(index, type_name, original["binary"], from_binary["binary"])
+        where = f"at field {path}" if path else "in field order"
+        failures.append(f"{prefix}: wire bytes differ {where}: {source.language} wrote {_show_bytes(expected)}, "
+                        f"{target.language} re-serialized {_show_bytes(actual)}")
+
+    from_json = target.call(type_name, json_value=original["json"])
+    if "error" in from_json:
+        failures.append(f"{prefix}: {target.language} could not parse the JSON of {source.language}: "
+                        f"{from_json['error']}")
+    else:
+        found = json_divergence(original["json"], from_json["json"])
+        if found:
+            path, expected, actual = found
+            failures.append(f"{prefix}: JSON differs at {path or 'the top level'}: {source.language} wrote "
+                            f"{_show_json(expected)}, {target.language} re-serialized {_show_json(actual)}")
+    return failures
+
+
+def run_matrix(manifest: Dict[str, Any], codecs: Dict[str, Codec], fixture_dir: str) -> List[str]:
+    """Runs every fixture through every pair, printing results, and returns the failures."""
+    failures = []
+    for fixture in manifest["fixtures"]:
+        with open(os.path.join(fixture_dir, fixture["path"]), "r", encoding="utf-8") as f:
+            fixture_json = json.load(f)
+        for source, target in manifest["pairs"]:
+            case_failures = check_fixture(fixture, fixture_json, codecs[source], codecs[target], manifest["fields"])
+            for failure in case_failures:
+                print(f"FAIL {failure}")
+            if not case_failures:
+                print(f"PASS {source} -> {target} {fixture['type']} ({fixture['name']})")
+            failures.extend(case_failures)
+    return failures
+
+
+def _make_writable(root: str) -> None:
+    for dirpath, _, files in os.walk(root):
+        for name in files:
+            path = os.path.join(dirpath, name)
+            os.chmod(path, os.stat(path).st_mode | stat.S_IWUSR)
+
+
+def _run_tool(command: List[str], cwd: str) -> None:
+    result = subprocess.run(command, cwd=cwd)
+    if result.returncode != 0:
+        raise ConformanceError(f"{' '.join(command)} failed with status {result.returncode}")
+
+
+def start_codecs(work_dir: str, languages: List[str], go: str, node: str, npm: str) -> Dict[str, Codec]:
+    """Builds and starts the codec of each language."""
+    commands = {}
+    if "go" in languages:
+        go_dir = os.path.join(work_dir, "go")
+        _run_tool([go, "mod", "tidy"], go_dir)
+        _run_tool([go, "build", "-o", "codec-bin", "./codec"], go_dir)
+        commands["go"] = [os.path.join(go_dir, "codec-bin")]
+    if "python" in languages:
+        commands["python"] = [sys.executable, os.path.join(work_dir, "codec.py"), os.path.join(work_dir, "python")]
+    if "typescript" in languages:
+        with open(os.path.join(work_dir, "package.json"), "w", encoding="utf-8") as f:
+            json.dump({"private": True, "type": "module", "dependencies": ES_RUNTIME}, f, indent=2)
+        _run_tool([npm, "install", "--no-audit", "--no-fund", "--silent"], work_dir)
+        commands["typescript"] = [node, os.path.join(work_dir, "codec.mjs")]
+    return {language: Codec(language, command, work_dir) for language, command in commands.items()}
+
+
+def run_tests(source_dir: str, go: str = "go", node: str = "node", npm: str = "npm") -> int:
+    """Runs a staged conformance test over a writable copy."""
+    with open(os.path.join(source_dir, "manifest.json"), "r", encoding="utf-8") as f:
+        manifest = json.load(f)
+    languages = sorted({language for pair in manifest["pairs"] for language in pair})
+    with tempfile.TemporaryDirectory() as temp_dir:
+        work_dir = os.path.join(temp_dir, "src")
+        shutil.copytree(source_dir, work_dir)
+        _make_writable(work_dir)
+        codecs = start_codecs(work_dir, languages, go, node, npm)
+        try:
+            failures = run_matrix(manifest, codecs, work_dir)
+        finally:
+            for codec in codecs.values():
+                codec.close()
+    if failures:
+        print(f"{len(failures)} conformance failure(s)", file=sys.stderr)
+        return 1
+    return 0
+
+
+def main():
+    """Main entry point for cross-language conformance tests."""
+    parser = argparse.ArgumentParser(description="Generate and run cross-language conformance tests")
+    subparsers = parser.add_subparsers(dest="command", required=True)
+
+    generate_parser = subparsers.add_parser("generate", help="Generate the codecs and test manifest")
+    generate_parser.add_argument("--manifest", required=True, help="Manifest to write")
+    generate_parser.add_argument("--pair", action="append", default=[], help="SOURCE:TARGET language pair")
+    generate_parser.add_argument("--fixture", action="append", nargs=3, default=[],
+                                 metavar=("TYPE", "STAGED", "SOURCE"),
+                                 help="Message type, staged path and source of a JSON fixture")
+    generate_parser.add_argument("--go-package", help="Go import path of the generated messages")
+    generate_parser.add_argument("--go-codec", help="Go codec main.go to write")
+    generate_parser.add_argument("--go-mod", help="go.mod to write for the Go codec")
+    generate_parser.add_argument("--python-codec", help="Python codec to write")
+    generate_parser.add_argument("--es-codec", help="protobuf-es codec to write")
+    generate_parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    generate_parser.add_argument("files", nargs="+", help="Proto files")
+
+    run_parser = subparsers.add_parser("run", help="Run a staged conformance test")
+    run_parser.add_argument("dir", help="Directory with the manifest, fixtures, codecs and generated code")
+    run_parser.add_argument("--go", default=os.environ.get("GO", "go"), help="Go binary")
+    run_parser.add_argument("--node", default=os.environ.get("NODE", "node"), help="Node.js binary")
+    run_parser.add_argument("--npm", default=os.environ.get("NPM", "npm"), help="npm binary")
+    args = parser.parse_args()
+
+    try:
+        if args.command == "run":
+            sys.exit(run_tests(args.dir, args.go, args.node, args.npm))
+
+        manifest = generate(args.files, args.dep, [tuple(f) for f in args.fixture], args.pair)
+        if (args.go_codec or args.go_mod) and not args.go_package:
+            raise ConformanceError("--go-codec and --go-mod require --go-package")
+        outputs = {args.manifest: json.dumps(manifest, indent=2, sort_keys=True) + "\n"}
+        if args.go_codec:
+            outputs[args.go_codec] = render_go_codec(args.go_package)
+        if args.go_mod:
+            outputs[args.go_mod] = render_go_mod(args.go_package)
+        if args.python_codec:
+            outputs[args.python_codec] = PYTHON_CODEC
+        if args.es_codec:
+            outputs[args.es_codec] = ES_CODEC
+        for path, content in outputs.items():
+            with open(path, "w", encoding="utf-8") as f:
+                f.write(content)
+    except (ConformanceError, ProtoParseError, OSError) as e:
+        print(f"ERROR: proto_conformance: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
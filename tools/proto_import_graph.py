@@ -0,0 +1,92 @@
+#!/usr/bin/env python3
+"""
+Import graph of the proto files of one target.
+
+Code generation runs one protoc action per proto file so that editing one file
+only regenerates that file and the files importing it. Each action must still
+see every file of the target it imports, directly or transitively; this tool
+computes those sets and writes them as JSON for the Buck2 rule:
+
+    {"files": {"acme/user.proto": ["acme/address.proto"], "acme/address.proto": []}}
+
+Imports of files outside the target (dependencies, well-known types) are not
+listed; the rule provides them to every action.
+
+Usage:
+    proto_import_graph.py --output graph.json \\
+        acme/user.proto=path/to/acme/user.proto acme/address.proto=path/to/acme/address.proto
+"""
+
+import argparse
+import json
+import sys
+from pathlib import Path
+from typing import Dict, List
+
+try:
+    from proto_schema import ProtoParseError, parse_proto_file
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import ProtoParseError, parse_proto_file
+
+
+def direct_imports(files: Dict[str, str]) -> Dict[str, List[str]]:
+    """
+    Returns the imports of each file that resolve to another file of the target.
+
+    Args:
+        files: Import path (as written in import statements) to file on disk
+
+    Returns:
+        Import path to the sorted import paths of target files it imports
+    """
+    graph = {}
+    for import_path, path in files.items():
+        proto_file = parse_proto_file(path)
+        graph[import_path] = sorted({i for i in proto_file.imports if i in files and i != import_path})
+    return graph
+
+
+def transitive_imports(graph: Dict[str, List[str]]) -> Dict[str, List[str]]:
+    """Returns the target files each file imports directly or transitively (import cycles are tolerated)."""
+    closure = {}
+    for start in graph:
+        seen = set()
+        pending = list(graph[start])
+        while pending:
+            current = pending.pop()
+            if current in seen or current == start:
+                continue
+            seen.add(current)
+            pending.extend(graph.get(current, []))
+        closure[start] = sorted(seen)
+    return closure
+
+
+def main():
+    """Main entry point for the proto import graph."""
+    parser = argparse.ArgumentParser(description="Compute the transitive imports of each proto file of a target")
+    parser.add_argument("--output", required=True, help="Output JSON file")
+    parser.add_argument("files", nargs="+", help="Proto files as IMPORT_PATH=PATH")
+    args = parser.parse_args()
+
+    files = {}
+    for value in args.files:
+        import_path, sep, path = value.partition("=")
+        if not sep:
+            parser.error(f"invalid file '{value}', expected IMPORT_PATH=PATH")
+        files[import_path] = path
+
+    try:
+        graph = transitive_imports(direct_imports(files))
+    except (OSError, ProtoParseError) as e:
+        print(f"ERROR: proto_import_graph: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    with open(args.output, "w", encoding="utf-8") as f:
+        json.dump({"files": graph}, f, indent=2, sort_keys=True)
+        f.write("\n")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,272 @@
+#!/usr/bin/env python3
+"""
+JSON Schema finalization for protobuf Buck2 integration.
+
+protoc-gen-jsonschema writes one draft-04 schema per message, with the
+messages it references under "definitions". This tool turns that output into
+the schemas a proto_json_schema target exposes:
+
+- one <package>.<Message>.json per top-level message of the target, or only
+  per message reachable from --root messages
+- protovalidate rules are translated into JSON Schema keywords where an
+  equivalent exists (string.min_len -> minLength, int32.gte -> minimum,
+  repeated.max_items -> maxItems, required -> required, ...); other rules
+  are listed in the property's $comment rather than silently dropped
+- the schema is rewritten for --draft "draft-07" or "2020-12" ($schema,
+  and definitions -> $defs for 2020-12)
+
+Usage:
+    proto_json_schema.py --input-dir raw/ --output-dir schemas/ \\
+        --draft 2020-12 --root acme.user.v1.User user.proto
+"""
+
+import argparse
+import json
+import sys
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set
+
+try:
+    from proto_schema import Field, Message, ProtoParseError, SchemaSet, find_option, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import Field, Message, ProtoParseError, SchemaSet, find_option, load_schema_set
+
+
+DRAFTS = {
+    "draft-07": "http://json-schema.org/draft-07/schema#",
+    "2020-12": "https://json-schema.org/draft/2020-12/schema",
+}
+
+_NUMERIC_RULES = {"int32", "int64", "uint32", "uint64", "sint32", "sint64", "fixed32", "fixed64",
+                  "sfixed32", "sfixed64", "float", "double"}
+
+_STRING_FORMATS = {"email": "email", "hostname": "hostname", "ipv4": "ipv4", "ipv6": "ipv6",
+                   "uri": "uri", "uuid": "uuid"}
+
+_NUMERIC_KEYWORDS = {"gt": "exclusiveMinimum", "gte": "minimum", "lt": "exclusiveMaximum", "lte": "maximum"}
+
+
+class JsonSchemaError(Exception):
+    """Raised when the plugin output cannot be finalized."""
+
+
+# Roots
+
+
+def reachable_messages(schema: SchemaSet, roots: List[str]) -> Set[str]:
+    """Returns the messages referenced, directly or transitively, from the roots."""
+    seen: Set[str] = set()
+    pending = list(roots)
+    while pending:
+        full_name = pending.pop()
+        if full_name in seen:
+            continue
+        seen.add(full_name)
+        message = schema.types.get(full_name)
+        if not isinstance(message, Message):
+            continue
+        for message_field in message.fields:
+            type_name = message_field.map_value_type if message_field.is_map else message_field.type_name
+            resolved = schema.resolve_type_name(type_name, message.full_name)
+            if isinstance(schema.types.get(resolved), Message):
+                pending.append(resolved)
+    return seen
+
+
+def selected_messages(schema: SchemaSet, roots: List[str]) -> List[Message]:
+    """Returns the top-level messages of the target files to write schemas for."""
+    messages = [message for proto_file in schema.files for message in proto_file.messages]
+    if not roots:
+        return messages
+    top_level = {message.full_name for message in messages}
+    for root in roots:
+        if root not in top_level:
+            raise JsonSchemaError(f"root {root} is not a top-level message of the target")
+    reachable = reachable_messages(schema, roots)
+    return [message for message in messages if message.full_name in reachable]
+
+
+# protovalidate
+
+
+def _scalar_keywords(kind: str, rule: str, argument: Any) -> Optional[Dict[str, Any]]:
+    if kind == "string":
+        if rule == "min_len":
+            return {"minLength": argument}
+        if rule == "max_len":
+            return {"maxLength": argument}
+        if rule == "len":
+            return {"minLength": argument, "maxLength": argument}
+        if rule == "pattern":
+            return {"pattern": argument}
+        if rule in _STRING_FORMATS and argument is True:
+            return {"format": _STRING_FORMATS[rule]}
+    if kind in _NUMERIC_RULES and rule in _NUMERIC_KEYWORDS:
+        return {_NUMERIC_KEYWORDS[rule]: argument}
+    if kind in _NUMERIC_RULES or kind in ("string", "bool"):
+        if rule == "const":
+            return {"const": argument}
+        if rule == "in":
+            return {"enum": argument if isinstance(argument, list) else [argument]}
+    return None
+
+
+def _apply_rules(property_schema: Dict[str, Any], rules: Dict[str, Any], unmapped: List[str], prefix: str = "") -> None:
+    """Adds the keywords of the field's protovalidate rules to its property schema."""
+    for kind, value in rules.items():
+        if kind == "required" and not prefix:
+            continue
+        if kind == "repeated" and isinstance(value, dict):
+            for rule, argument in value.items():
+                if rule == "min_items":
+                    property_schema["minItems"] = argument
+                elif rule == "max_items":
+                    property_schema["maxItems"] = argument
+                elif rule == "unique":
+                    property_schema["uniqueItems"] = argument
+                elif rule == "items" and isinstance(argument, dict):
+                    _apply_rules(property_schema.setdefault("items", {}), argument, unmapped, "repeated.items.")
+                else:
+                    unmapped.append(f"{prefix}repeated.{rule}")
+            continue
+        if kind == "map" and isinstance(value, dict):
+            for rule, argument in value.items():
+                if rule == "min_pairs":
+                    property_schema["minProperties"] = argument
+                elif rule == "max_pairs":
+                    property_schema["maxProperties"] = argument
+                else:
+                    unmapped.append(f"{prefix}map.{rule}")
+            continue
+        if not isinstance(value, dict):
+            unmapped.append(f"{prefix}{kind}")
+            continue
+        for rule, argument in value.items():
+            keywords = _scalar_keywords(kind, rule, argument)
+            if keywords is None:
+                unmapped.append(f"{prefix}{kind}.{rule}")
+            else:
+                property_schema.update(keywords)
+
+
+def _is_required(message_field: Field) -> bool:
+    if message_field.label == "required":
+        return True
+    rules = find_option(message_field.options, "buf.validate.field")
+    return isinstance(rules, dict) and rules.get("required") is True
+
+
+def apply_message_rules(definition: Dict[str, Any], message: Message) -> None:
+    """Translates the protovalidate rules of a message's fields into its definition."""
+    properties = definition.get("properties", {})
+    required = list(definition.get("required", []))
+    for message_field in message.fields:
+        label = message_field.json_name if message_field.json_name in properties else message_field.name
+        if label not in properties:
+            continue
+        if _is_required(message_field) and label not in required:
+            required.append(label)
+        rules = find_option(message_field.options, "buf.validate.field")
+        if not isinstance(rules, dict):
+            continue
+        unmapped: List[str] = []
+        _apply_rules(properties[label], rules, unmapped)
+        if unmapped:
+            properties[label]["$comment"] = "protovalidate rules without a JSON Schema equivalent: " + ", ".join(unmapped)
+    if required:
+        definition["required"] = required
+
+
+def _definition_message(schema: SchemaSet, key: str) -> Optional[Message]:
+    """Returns the message a definition key names, by full or unique suffix name."""
+    message = schema.types.get(key)
+    if isinstance(message, Message):
+        return message
+    matches = [m for name, m in schema.types.items()
+               if isinstance(m, Message) and (name.endswith("." + key) or name == key)]
+    return matches[0] if len(matches) == 1 else None
+
+
+# Drafts
+
+
+def _rename_definitions(value: Any) -> Any:
+    if isinstance(value, dict):
+        renamed = {}
+        for key, item in value.items():
+            if key == "definitions":
+                key = "$defs"
+            elif key == "$ref" and isinstance(item, str):
+                item = item.replace("#/definitions/", "#/$defs/")
+            renamed[key] = _rename_definitions(item)
+        return renamed
+    if isinstance(value, list):
+        return [_rename_definitions(item) for item in value]
+    return value
+
+
+def convert_draft(document: Dict[str, Any], draft: str) -> Dict[str, Any]:
+    """Rewrites a draft-04 schema for the requested draft."""
+    if draft not in DRAFTS:
+        raise JsonSchemaError(f"draft must be one of {', '.join(DRAFTS)}, got {draft!r}")
+    if "id" in document:
+        document["$id"] = document.pop("id")
+    if draft == "2020-12":
+        document = _rename_definitions(document)
+    return {"$schema": DRAFTS[draft], **{k: v for k, v in document.items() if k != "$schema"}}
+
+
+def finalize(document: Dict[str, Any], message: Message, schema: SchemaSet, draft: str) -> Dict[str, Any]:
+    """Applies protovalidate rules and the draft to the schema of one message."""
+    if "properties" in document:
+        apply_message_rules(document, message)
+    for key, definition in document.get("definitions", {}).items():
+        defined = _definition_message(schema, key)
+        if defined and isinstance(definition, dict):
+            apply_message_rules(definition, defined)
+    return convert_draft(document, draft)
+
+
+def find_plugin_output(input_dir: Path, message: Message, package: str) -> Path:
+    """Returns the file protoc-gen-jsonschema wrote for a top-level message."""
+    candidates = [f"{package}/{message.name}.json", f"{package.replace('.', '/')}/{message.name}.json",
+                  f"{message.name}.json"]
+    for candidate in candidates:
+        if (input_dir / candidate).is_file():
+            return input_dir / candidate
+    matches = sorted(input_dir.rglob(f"{message.name}.json"))
+    if len(matches) == 1:
+        return matches[0]
+    raise JsonSchemaError(f"protoc-gen-jsonschema did not write a schema for {message.full_name}")
+
+
+def main():
+    """Main entry point for JSON Schema finalization."""
+    parser = argparse.ArgumentParser(description="Finalize protoc-gen-jsonschema output")
+    parser.add_argument("--input-dir", required=True, help="Directory with protoc-gen-jsonschema output")
+    parser.add_argument("--output-dir", required=True, help="Directory to write <package>.<Message>.json to")
+    parser.add_argument("--draft", default="2020-12", help="JSON Schema draft: draft-07 or 2020-12")
+    parser.add_argument("--root", action="append", default=[], help="Root message; unreachable messages are skipped")
+    parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    parser.add_argument("files", nargs="+", help="Proto files")
+    args = parser.parse_args()
+
+    try:
+        schema = load_schema_set(args.files, args.dep)
+        packages = {message.full_name: proto_file.package
+                    for proto_file in schema.files for message in proto_file.messages}
+        output_dir = Path(args.output_dir)
+        output_dir.mkdir(parents=True, exist_ok=True)
+        for message in selected_messages(schema, args.root):
+            path = find_plugin_output(Path(args.input_dir), message, packages[message.full_name])
+            document = finalize(json.loads(path.read_text(encoding="utf-8")), message, schema, args.draft)
+            (output_dir / f"{message.full_name}.json").write_text(
+                json.dumps(document, indent=2, sort_keys=False) + "\n", encoding="utf-8")
+    except (JsonSchemaError, ProtoParseError, OSError, ValueError) as e:
+        print(f"ERROR: proto_json_schema: {e}", file=sys.stderr)
+        sys.exit(2)
+
+
+if __name__ == "__main__":
+    main()
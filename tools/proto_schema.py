@@ -0,0 +1,1148 @@
+#!/usr/bin/env python3
+"""
+Lightweight protobuf schema parser for buck2-protobuf analysis tools.
+
+This module parses .proto source files into a simple object model (files,
+messages, fields, enums, services and methods) including options and
+source comments. It is used by the schema lint checks and code generation
+helpers, which need schema structure without requiring a protoc binary at
+analysis time.
+
+The parser covers proto2, proto3 and editions syntax as used in practice.
+It does not perform full semantic validation; that remains protoc's job.
+"""
+
+import argparse
+import json
+import re
+import sys
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Tuple, Union
+
+
+WELL_KNOWN_TYPES = {
+    "google.protobuf.Any",
+    "google.protobuf.Api",
+    "google.protobuf.BoolValue",
+    "google.protobuf.BytesValue",
+    "google.protobuf.DoubleValue",
+    "google.protobuf.Duration",
+    "google.protobuf.Empty",
+    "google.protobuf.Enum",
+    "google.protobuf.EnumValue",
+    "google.protobuf.Field",
+    "google.protobuf.FieldMask",
+    "google.protobuf.FloatValue",
+    "google.protobuf.Int32Value",
+    "google.protobuf.Int64Value",
+    "google.protobuf.ListValue",
+    "google.protobuf.Method",
+    "google.protobuf.Mixin",
+    "google.protobuf.NullValue",
+    "google.protobuf.Option",
+    "google.protobuf.SourceContext",
+    "google.protobuf.StringValue",
+    "google.protobuf.Struct",
+    "google.protobuf.Syntax",
+    "google.protobuf.Timestamp",
+    "google.protobuf.Type",
+    "google.protobuf.UInt32Value",
+    "google.protobuf.UInt64Value",
+    "google.protobuf.Value",
+}
+
+SCALAR_TYPES = {
+    "double", "float", "int32", "int64", "uint32", "uint64", "sint32",
+    "sint64", "fixed32", "fixed64", "sfixed32", "sfixed64", "bool",
+    "string", "bytes",
+}
+
+
+class ProtoParseError(Exception):
+    """Raised when a proto file cannot be parsed."""
+
+    def __init__(self, path: str, line: int, message: str):
+        super().__init__(f"{path}:{line}: {message}")
+        self.path = path
+        self.line = line
+
+
+@dataclass
+class Token:
+    """A lexical token with its attached leading comment."""
+    kind: str  # ident, int, float, string, punct
+    value: str
+    line: int
+    leading_comment: str = ""
+
+
+@dataclass
+class Field:
+    """A message field, map field, group or extension field."""
+    name: str
+    number: int
+    type_name: str
+    label: str = ""  # "", "optional", "required", "repeated"
+    line: int = 0
+    options: Dict[str, Any] = field(default_factory=dict)
+    leading_comment: str = ""
+    trailing_comment: str = ""
+    oneof: Optional[str] = None
+    map_key_type: Optional[str] = None
+    map_value_type: Optional[str] = None
+    is_group: bool = False
+    extendee: Optional[str] = None
+    scope: str = ""  # Fully-qualified name of the enclosing message or package
+    declaration_index: int = 0
+
+    @property
+    def is_map(self) -> bool:
+        return self.map_key_type is not None
+
+    @property
+    def is_repeated(self) -> bool:
+        return self.label == "repeated" or self.is_map
+
+    @property
+    def cardinality(self) -> str:
+        """Returns "map", "repeated" or "singular"."""
+        if self.is_map:
+            return "map"
+        if self.label == "repeated":
+            return "repeated"
+        return "singular"
+
+    @property
+    def is_scalar(self) -> bool:
+        return self.type_name in SCALAR_TYPES
+
+    @property
+    def full_name(self) -> str:
+        return f"{self.scope}.{self.name}" if self.scope else self.name
+
+    @property
+    def json_name(self) -> str:
+        """Effective JSON name: explicit json_name or protoc's lowerCamelCase."""
+        explicit = self.options.get("json_name")
+        if isinstance(explicit, str):
+            return explicit
+        return to_json_name(self.name)
+
+    @property
+    def default(self) -> Optional[Any]:
+        return self.options.get("default")
+
+
+@dataclass
+class Oneof:
+    """A oneof declaration within a message."""
+    name: str
+    line: int = 0
+    options: Dict[str, Any] = field(default_factory=dict)
+    leading_comment: str = ""
+    fields: List[str] = field(default_factory=list)
+    scope: str = ""
+
+    @property
+    def full_name(self) -> str:
+        return f"{self.scope}.{self.name}" if self.scope else self.name
+
+
+@dataclass
+class EnumValue:
+    """A single enum value."""
+    name: str
+    number: int
+    line: int = 0
+    options: Dict[str, Any] = field(default_factory=dict)
+    leading_comment: str = ""
+    trailing_comment: str = ""
+
+
+@dataclass
+class Enum:
+    """An enum declaration."""
+    name: str
+    full_name: str
+    line: int = 0
+    values: List[EnumValue] = field(default_factory=list)
+    options: Dict[str, Any] = field(default_factory=dict)
+    leading_comment: str = ""
+    reserved_numbers: List[Tuple[int, int]] = field(default_factory=list)
+    reserved_names: List[str] = field(default_factory=list)
+    parent: Optional[str] = None
+
+
+@dataclass
+class Message:
+    """A message declaration, including nested declarations."""
+    name: str
+    full_name: str
+    line: int = 0
+    fields: List[Field] = field(default_factory=list)
+    messages: List["Message"] = field(default_factory=list)
+    enums: List[Enum] = field(default_factory=list)
+    oneofs: List[Oneof] = field(default_factory=list)
+    extensions: List[Field] = field(default_factory=list)
+    options: Dict[str, Any] = field(default_factory=dict)
+    leading_comment: str = ""
+    reserved_numbers: List[Tuple[int, int]] = field(default_factory=list)
+    reserved_names: List[str] = field(default_factory=list)
+    extension_ranges: List[Tuple[int, int]] = field(default_factory=list)
+    parent: Optional[str] = None
+    is_map_entry: bool = False
+    is_group: bool = False
+
+    @property
+    def is_top_level(self) -> bool:
+        return self.parent is None
+
+
+@dataclass
+class Method:
+    """An RPC method declaration."""
+    name: str
+    input_type: str
+    output_type: str
+    line: int = 0
+    client_streaming: bool = False
+    server_streaming: bool = False
+    options: Dict[str, Any] = field(default_factory=dict)
+    leading_comment: str = ""
+    service: str = ""
+
+    @property
+    def full_name(self) -> str:
+        return f"{self.service}.{self.name}" if self.service else self.name
+
+    @property
+    def is_streaming(self) -> bool:
+        return self.client_streaming or self.server_streaming
+
+    def http_rules(self) -> List[Tuple[str, str]]:
+        """Returns (verb, path) pairs from google.api.http, including additional bindings."""
+        rule = find_option(self.options, "google.api.http")
+        return _collect_http_rules(rule) if isinstance(rule, dict) else []
+
+
+@dataclass
+class Service:
+    """A service declaration."""
+    name: str
+    full_name: str
+    line: int = 0
+    methods: List[Method] = field(default_factory=list)
+    options: Dict[str, Any] = field(default_factory=dict)
+    leading_comment: str = ""
+
+    @property
+    def has_streaming(self) -> bool:
+        """Returns whether any method streams requests or responses."""
+        return any(method.is_streaming for method in self.methods)
+
+
+@dataclass
+class ProtoFile:
+    """A parsed .proto file."""
+    path: str
+    syntax: str = "proto2"
+    edition: Optional[str] = None
+    package: str = ""
+    imports: List[str] = field(default_factory=list)
+    public_imports: List[str] = field(default_factory=list)
+    import_lines: Dict[str, int] = field(default_factory=dict)
+    options: Dict[str, Any] = field(default_factory=dict)
+    messages: List[Message] = field(default_factory=list)
+    enums: List[Enum] = field(default_factory=list)
+    services: List[Service] = field(default_factory=list)
+    extensions: List[Field] = field(default_factory=list)
+    line_count: int = 0
+    package_line: int = 0
+
+    def all_messages(self) -> Iterator[Message]:
+        """Yields every message in the file, depth first."""
+        stack = list(reversed(self.messages))
+        while stack:
+            message = stack.pop()
+            yield message
+            stack.extend(reversed(message.messages))
+
+    def all_enums(self) -> Iterator[Enum]:
+        """Yields every enum in the file, including nested enums."""
+        yield from self.enums
+        for message in self.all_messages():
+            yield from message.enums
+
+    def all_extensions(self) -> Iterator[Field]:
+        """Yields every extension field declared in the file."""
+        yield from self.extensions
+        for message in self.all_messages():
+            yield from message.extensions
+
+    def top_level_definition_count(self) -> int:
+        return len(self.messages) + len(self.enums) + len(self.services) + len(self.extensions)
+
+
+class SchemaSet:
+    """A set of parsed proto files with type resolution across files."""
+
+    def __init__(self, files: List[ProtoFile], dep_files: Optional[List[ProtoFile]] = None):
+        self.files = files
+        self.dep_files = dep_files or []
+        self.types: Dict[str, Union[Message, Enum]] = {}
+        self.type_files: Dict[str, ProtoFile] = {}
+        for proto_file in self.files + self.dep_files:
+            for message in proto_file.all_messages():
+                self.types.setdefault(message.full_name, message)
+                self.type_files.setdefault(message.full_name, proto_file)
+            for enum in proto_file.all_enums():
+                self.types.setdefault(enum.full_name, enum)
+                self.type_files.setdefault(enum.full_name, proto_file)
+
+    @property
+    def all_files(self) -> List[ProtoFile]:
+        return self.files + self.dep_files
+
+    def resolve_type(self, type_name: str, scope: str) -> Optional[Union[Message, Enum]]:
+        """
+        Resolves a type reference using protobuf scoping rules.
+
+        Args:
+            type_name: Type name as written in the source (e.g. "Foo.Bar" or ".pkg.Foo")
+            scope: Fully-qualified scope the reference appears in
+
+        Returns:
+            The resolved Message or Enum, or None if the type is unknown
+        """
+        full_name = self.resolve_type_name(type_name, scope)
+        return self.types.get(full_name) if full_name else None
+
+    def resolve_type_name(self, type_name: str, scope: str) -> Optional[str]:
+        """Resolves a type reference to its fully-qualified name (without leading dot)."""
+        if type_name in SCALAR_TYPES:
+            return None
+        if type_name.startswith("."):
+            return type_name[1:]
+        parts = scope.split(".") if scope else []
+        while True:
+            candidate = ".".join(parts + [type_name])
+            if candidate in self.types:
+                return candidate
+            if not parts:
+                break
+            parts.pop()
+        if type_name in WELL_KNOWN_TYPES:
+            return type_name
+        return None
+
+    def file_for_type(self, full_name: str) -> Optional[ProtoFile]:
+        return self.type_files.get(full_name)
+
+    def find_import(self, import_path: str) -> Optional[ProtoFile]:
+        """Finds the parsed file satisfying an import statement, matching by path suffix."""
+        for proto_file in self.all_files:
+            if proto_file.path == import_path or proto_file.path.endswith("/" + import_path):
+                return proto_file
+        return None
+
+
+def to_json_name(name: str) -> str:
+    """Computes protoc's default JSON name for a field name."""
+    result = []
+    capitalize_next = False
+    for char in name:
+        if char == "_":
+            capitalize_next = True
+        elif capitalize_next:
+            result.append(char.upper())
+            capitalize_next = False
+        else:
+            result.append(char)
+    return "".join(result)
+
+
+def option_name_matches(option_name: str, wanted: str) -> bool:
+    """
+    Checks whether a written option name refers to the wanted option.
+
+    Custom option names are compared without parentheses and leading dots, and
+    a suffix match is accepted so that package-relative references resolve.
+    """
+    normalized = option_name.replace("(", "").replace(")", "").lstrip(".")
+    wanted = wanted.replace("(", "").replace(")", "").lstrip(".")
+    return normalized == wanted or normalized.endswith("." + wanted) or wanted.endswith("." + normalized)
+
+
+def find_option(options: Dict[str, Any], wanted: str) -> Optional[Any]:
+    """Returns the value of an option by (possibly unqualified) name, or None."""
+    if wanted in options:
+        return options[wanted]
+    for name, value in options.items():
+        if option_name_matches(name, wanted):
+            return value
+    return None
+
+
+def _collect_http_rules(rule: Dict[str, Any]) -> List[Tuple[str, str]]:
+    rules = []
+    for verb in ("get", "put", "post", "delete", "patch"):
+        if verb in rule and isinstance(rule[verb], str):
+            rules.append((verb.upper(), rule[verb]))
+    custom = rule.get("custom")
+    if isinstance(custom, dict) and isinstance(custom.get("path"), str):
+        rules.append((str(custom.get("kind", "")).upper(), custom["path"]))
+    bindings = rule.get("additional_bindings", [])
+    if isinstance(bindings, dict):
+        bindings = [bindings]
+    for binding in bindings:
+        if isinstance(binding, dict):
+            rules.extend(_collect_http_rules(binding))
+    return rules
+
+
+_PUNCTUATION = set("{}[]()<>;=,:-+/.")
+
+
+def tokenize(content: str, path: str = "<input>") -> Tuple[List[Token], Dict[int, str]]:
+    """
+    Splits proto source into tokens.
+
+    Returns:
+        Tuple of (tokens, trailing comments keyed by line number)
+    """
+    tokens: List[Token] = []
+    trailing: Dict[int, str] = {}
+    pending_comments: List[str] = []
+    pending_end_line = 0
+    i = 0
+    line = 1
+    length = len(content)
+
+    def last_token_line() -> int:
+        return tokens[-1].line if tokens else 0
+
+    while i < length:
+        char = content[i]
+        if char == "\n":
+            line += 1
+            i += 1
+            continue
+        if char in " \t\r\f\v":
+            i += 1
+            continue
+        if content.startswith("//", i):
+            end = content.find("\n", i)
+            end = length if end == -1 else end
+            text = content[i + 2:end].strip()
+            if tokens and last_token_line() == line and not pending_comments:
+                trailing[line] = (trailing.get(line, "") + " " + text).strip()
+            else:
+                if pending_comments and pending_end_line < line - 1:
+                    pending_comments = []
+                pending_comments.append(text)
+                pending_end_line = line
+            i = end
+            continue
+        if content.startswith("/*", i):
+            end = content.find("*/", i + 2)
+            if end == -1:
+                raise ProtoParseError(path, line, "unterminated block comment")
+            raw = content[i + 2:end]
+            start_line = line
+            line += raw.count("\n")
+            text_lines = [l.strip().lstrip("*").strip() for l in raw.split("\n")]
+            text = "\n".join(l for l in text_lines if l)
+            if tokens and last_token_line() == start_line and not pending_comments:
+                trailing[start_line] = (trailing.get(start_line, "") + " " + text).strip()
+            else:
+                if pending_comments and pending_end_line < start_line - 1:
+                    pending_comments = []
+                pending_comments.append(text)
+                pending_end_line = line
+            i = end + 2
+            continue
+
+        leading = ""
+        if pending_comments:
+            if pending_end_line >= line - 1:
+                leading = "\n".join(pending_comments)
+            pending_comments = []
+
+        if char in "\"'":
+            quote = char
+            j = i + 1
+            value_chars = []
+            while j < length and content[j] != quote:
+                if content[j] == "\n":
+                    raise ProtoParseError(path, line, "unterminated string literal")
+                if content[j] == "\\" and j + 1 < length:
+                    escaped, j = _unescape(content, j + 1)
+                    value_chars.append(escaped)
+                    continue
+                value_chars.append(content[j])
+                j += 1
+            if j >= length:
+                raise ProtoParseError(path, line, "unterminated string literal")
+            tokens.append(Token("string", "".join(value_chars), line, leading))
+            i = j + 1
+            continue
+        if char.isdigit() or (char == "." and i + 1 < length and content[i + 1].isdigit()):
+            match = re.match(r"0[xX][0-9a-fA-F]+|\d*\.?\d+(?:[eE][+-]?\d+)?|\d+\.", content[i:])
+            text = match.group(0)
+            kind = "float" if any(c in text for c in ".eE") and not text.lower().startswith("0x") else "int"
+            tokens.append(Token(kind, text, line, leading))
+            i += len(text)
+            continue
+        if char.isalpha() or char == "_" or (char == "." and i + 1 < length and (content[i + 1].isalpha() or content[i + 1] == "_")):
+            match = re.match(r"\.?[^\W\d]\w*(?:\.[^\W\d]\w*)*", content[i:])
+            text = match.group(0)
+            tokens.append(Token("ident", text, line, leading))
+            i += len(text)
+            continue
+        if char in _PUNCTUATION:
+            tokens.append(Token("punct", char, line, leading))
+            i += 1
+            continue
+        raise ProtoParseError(path, line, f"unexpected character {char!r}")
+
+    return tokens, trailing
+
+
+def _parse_int(text: str) -> int:
+    if text.lower().startswith("0x"):
+        return int(text, 16)
+    if len(text) > 1 and text.startswith("0"):
+        return int(text, 8)
+    return int(text)
+
+
+_SIMPLE_ESCAPES = {"a": "\a", "b": "\b", "f": "\f", "n": "\n", "r": "\r", "t": "\t", "v": "\v"}
+
+
+def _unescape(content: str, i: int) -> Tuple[str, int]:
+    """
+    Decodes the escape sequence following a backslash at content[i].
+
+    Returns the decoded character and the index just past the sequence.
+    """
+    char = content[i]
+    if char in "xX":
+        match = re.match(r"[0-9a-fA-F]{1,2}", content[i + 1:])
+        if match:
+            return chr(int(match.group(0), 16)), i + 1 + len(match.group(0))
+    elif char in "01234567":
+        match = re.match(r"[0-7]{1,3}", content[i:])
+        return chr(int(match.group(0), 8) & 0xFF), i + len(match.group(0))
+    elif char in "uU":
+        digits = 4 if char == "u" else 8
+        match = re.match(r"[0-9a-fA-F]{%d}" % digits, content[i + 1:])
+        if match:
+            return chr(int(match.group(0), 16)), i + 1 + digits
+    return _SIMPLE_ESCAPES.get(char, char), i + 1
+
+
+class _Parser:
+    """Recursive-descent parser over the token stream."""
+
+    def __init__(self, path: str, content: str):
+        self.path = path
+        self.tokens, self.trailing = tokenize(content, path)
+        self.pos = 0
+        self.file = ProtoFile(path=path, line_count=content.count("\n") + (0 if content.endswith("\n") or not content else 1))
+
+    # Token helpers
+
+    def peek(self, offset: int = 0) -> Optional[Token]:
+        index = self.pos + offset
+        return self.tokens[index] if index < len(self.tokens) else None
+
+    def next(self) -> Token:
+        token = self.peek()
+        if token is None:
+            last_line = self.tokens[-1].line if self.tokens else 1
+            raise ProtoParseError(self.path, last_line, "unexpected end of file")
+        self.pos += 1
+        return token
+
+    def at(self, value: str) -> bool:
+        token = self.peek()
+        return token is not None and token.value == value and token.kind in ("punct", "ident")
+
+    def accept(self, value: str) -> bool:
+        if self.at(value):
+            self.pos += 1
+            return True
+        return False
+
+    def expect(self, value: str) -> Token:
+        token = self.next()
+        if token.value != value or token.kind not in ("punct", "ident"):
+            raise ProtoParseError(self.path, token.line, f"expected {value!r}, found {token.value!r}")
+        return token
+
+    def expect_ident(self) -> Token:
+        token = self.next()
+        if token.kind != "ident":
+            raise ProtoParseError(self.path, token.line, f"expected identifier, found {token.value!r}")
+        return token
+
+    def expect_int(self) -> int:
+        negative = self.accept("-")
+        token = self.next()
+        if token.kind != "int":
+            if token.kind == "ident" and token.value == "max":
+                return 536870911
+            raise ProtoParseError(self.path, token.line, f"expected integer, found {token.value!r}")
+        value = _parse_int(token.value)
+        return -value if negative else value
+
+    def expect_string(self) -> str:
+        token = self.next()
+        if token.kind != "string":
+            raise ProtoParseError(self.path, token.line, f"expected string, found {token.value!r}")
+        value = token.value
+        while self.peek() is not None and self.peek().kind == "string":
+            value += self.next().value
+        return value
+
+    def trailing_comment(self, line: int) -> str:
+        return self.trailing.get(line, "")
+
+    # Grammar
+
+    def parse(self) -> ProtoFile:
+        while self.peek() is not None:
+            self.parse_top_level()
+        return self.file
+
+    def parse_top_level(self) -> None:
+        token = self.peek()
+        if self.accept(";"):
+            return
+        keyword = token.value
+        if keyword == "syntax":
+            self.next()
+            self.expect("=")
+            self.file.syntax = self.expect_string()
+            self.expect(";")
+        elif keyword == "edition":
+            self.next()
+            self.expect("=")
+            self.file.edition = self.expect_string()
+            self.file.syntax = "editions"
+            self.expect(";")
+        elif keyword == "package":
+            self.next()
+            self.file.package = self.expect_ident().value
+            self.file.package_line = token.line
+            self.expect(";")
+        elif keyword == "import":
+            self.next()
+            public = False
+            if self.at("public") or self.at("weak"):
+                public = self.next().value == "public"
+            path = self.expect_string()
+            self.file.imports.append(path)
+            self.file.import_lines[path] = token.line
+            if public:
+                self.file.public_imports.append(path)
+            self.expect(";")
+        elif keyword == "option":
+            self.parse_option_statement(self.file.options)
+        elif keyword == "message":
+            self.file.messages.append(self.parse_message(self.file.package, None))
+        elif keyword == "enum":
+            self.file.enums.append(self.parse_enum(self.file.package, None))
+        elif keyword == "service":
+            self.file.services.append(self.parse_service())
+        elif keyword == "extend":
+            self.file.extensions.extend(self.parse_extend(self.file.package, None))
+        else:
+            raise ProtoParseError(self.path, token.line, f"unexpected token {keyword!r}")
+
+    def qualify(self, scope: str, name: str) -> str:
+        return f"{scope}.{name}" if scope else name
+
+    def parse_option_name(self) -> str:
+        parts = []
+        while True:
+            if self.accept("("):
+                parts.append("(" + self.expect_ident().value + ")")
+                self.expect(")")
+            else:
+                parts.append(self.expect_ident().value)
+            token = self.peek()
+            if token is not None and token.kind == "ident" and token.value.startswith("."):
+                # "(ext).foo.bar" continuation is lexed as a single identifier
+                self.next()
+                parts.append(token.value[1:])
+            if not self.accept("."):
+                break
+        return ".".join(parts)
+
+    def parse_option_statement(self, options: Dict[str, Any]) -> None:
+        self.expect("option")
+        name = self.parse_option_name()
+        self.expect("=")
+        _store_option(options, name, self.parse_option_value())
+        self.expect(";")
+
+    def parse_option_value(self) -> Any:
+        if self.at("{"):
+            return self.parse_aggregate()
+        if self.at("["):
+            self.next()
+            values = []
+            while not self.accept("]"):
+                values.append(self.parse_option_value())
+                self.accept(",")
+            return values
+        negative = self.accept("-")
+        token = self.peek()
+        if token is not None and token.kind == "string":
+            return self.expect_string()
+        token = self.next()
+        if token.kind == "int":
+            value = _parse_int(token.value)
+            return -value if negative else value
+        if token.kind == "float":
+            return -float(token.value) if negative else float(token.value)
+        if token.kind == "ident":
+            if token.value == "true":
+                return True
+            if token.value == "false":
+                return False
+            if token.value in ("inf", "nan"):
+                return float("-" + token.value if negative else token.value)
+            return token.value
+        raise ProtoParseError(self.path, token.line, f"invalid option value {token.value!r}")
+
+    def parse_aggregate(self) -> Dict[str, Any]:
+        self.expect("{")
+        result: Dict[str, Any] = {}
+        while not self.accept("}"):
+            if self.accept("["):
+                key = "[" + self.expect_ident().value + "]"
+                self.expect("]")
+            else:
+                key = self.expect_ident().value
+            if self.accept(":"):
+                value = self.parse_option_value()
+            else:
+                value = self.parse_aggregate()
+            _store_aggregate(result, key, value)
+            if not self.accept(","):
+                self.accept(";")
+        return result
+
+    def parse_field_options(self) -> Dict[str, Any]:
+        options: Dict[str, Any] = {}
+        if not self.accept("["):
+            return options
+        while True:
+            name = self.parse_option_name()
+            self.expect("=")
+            _store_option(options, name, self.parse_option_value())
+            if self.accept("]"):
+                break
+            self.expect(",")
+        return options
+
+    def parse_reserved(self, numbers: List[Tuple[int, int]], names: List[str]) -> None:
+        self.expect("reserved")
+        while True:
+            token = self.peek()
+            if token.kind == "string":
+                names.append(self.expect_string())
+            elif token.kind == "ident" and not token.value in ("max",):
+                names.append(self.next().value)
+            else:
+                start = self.expect_int()
+                end = start
+                if self.accept("to"):
+                    end = self.expect_int()
+                numbers.append((start, end))
+            if not self.accept(","):
+                break
+        self.expect(";")
+
+    def parse_message(self, scope: str, parent: Optional[str], leading: Optional[str] = None) -> Message:
+        start = self.expect("message")
+        name = self.expect_ident()
+        full_name = self.qualify(scope, name.value)
+        message = Message(
+            name=name.value,
+            full_name=full_name,
+            line=start.line,
+            leading_comment=start.leading_comment if leading is None else leading,
+            parent=parent,
+        )
+        self.expect("{")
+        self.parse_message_body(message)
+        return message
+
+    def parse_message_body(self, message: Message) -> None:
+        full_name = message.full_name
+        while not self.accept("}"):
+            token = self.peek()
+            if token is None:
+                raise ProtoParseError(self.path, message.line, f"unterminated message {message.name}")
+            keyword = token.value
+            if self.accept(";"):
+                continue
+            if keyword == "option":
+                self.parse_option_statement(message.options)
+            elif keyword == "message":
+                message.messages.append(self.parse_message(full_name, full_name))
+            elif keyword == "enum":
+                message.enums.append(self.parse_enum(full_name, full_name))
+            elif keyword == "oneof":
+                self.parse_oneof(message)
+            elif keyword == "reserved":
+                self.parse_reserved(message.reserved_numbers, message.reserved_names)
+            elif keyword == "extensions":
+                self.next()
+                while True:
+                    start_num = self.expect_int()
+                    end_num = start_num
+                    if self.accept("to"):
+                        end_num = self.expect_int()
+                    message.extension_ranges.append((start_num, end_num))
+                    if not self.accept(","):
+                        break
+                self.parse_field_options()
+                self.expect(";")
+            elif keyword == "extend":
+                message.extensions.extend(self.parse_extend(full_name, message))
+            elif keyword == "map" and self.peek(1) is not None and self.peek(1).value == "<":
+                self.add_field(message, self.parse_map_field(full_name))
+            else:
+                self.add_field(message, self.parse_field(full_name, message))
+
+    def add_field(self, message: Message, new_field: Field, oneof: Optional[Oneof] = None) -> None:
+        new_field.declaration_index = len(message.fields)
+        if oneof is not None:
+            new_field.oneof = oneof.name
+            oneof.fields.append(new_field.name)
+        message.fields.append(new_field)
+
+    def parse_field(self, scope: str, message: Optional[Message]) -> Field:
+        first = self.peek()
+        label = ""
+        if first.value in ("optional", "required", "repeated") and first.kind == "ident":
+            # "optional" may also be a type name; a label is followed by a type and a name,
+            # whereas a type is followed by the name and then "="
+            following = self.peek(2)
+            if following is not None and following.kind == "ident":
+                label = self.next().value
+        type_token = self.expect_ident()
+        if type_token.value == "group":
+            return self.parse_group(scope, message, label, first)
+        name = self.expect_ident()
+        self.expect("=")
+        number = self.expect_int()
+        options = self.parse_field_options()
+        end = self.expect(";")
+        return Field(
+            name=name.value,
+            number=number,
+            type_name=type_token.value,
+            label=label,
+            line=first.line,
+            options=options,
+            leading_comment=first.leading_comment,
+            trailing_comment=self.trailing_comment(end.line),
+            scope=scope,
+        )
+
+    def parse_group(self, scope: str, message: Optional[Message], label: str, first: Token) -> Field:
+        name = self.expect_ident()
+        self.expect("=")
+        number = self.expect_int()
+        options = self.parse_field_options()
+        full_name = self.qualify(scope, name.value)
+        group_message = Message(
+            name=name.value,
+            full_name=full_name,
+            line=first.line,
+            leading_comment=first.leading_comment,
+            parent=scope if message is not None else None,
+            is_group=True,
+        )
+        self.expect("{")
+        self.parse_message_body(group_message)
+        if message is not None:
+            message.messages.append(group_message)
+        else:
+            self.file.messages.append(group_message)
+        return Field(
+            name=name.value.lower(),
+            number=number,
+            type_name=name.value,
+            label=label,
+            line=first.line,
+            options=options,
+            leading_comment=first.leading_comment,
+            is_group=True,
+            scope=scope,
+        )
+
+    def parse_map_field(self, scope: str) -> Field:
+        first = self.expect("map")
+        self.expect("<")
+        key_type = self.expect_ident().value
+        self.expect(",")
+        value_type = self.expect_ident().value
+        self.expect(">")
+        name = self.expect_ident()
+        self.expect("=")
+        number = self.expect_int()
+        options = self.parse_field_options()
+        end = self.expect(";")
+        return Field(
+            name=name.value,
+            number=number,
+            type_name=value_type,
+            label="repeated",
+            line=first.line,
+            options=options,
+            leading_comment=first.leading_comment,
+            trailing_comment=self.trailing_comment(end.line),
+            map_key_type=key_type,
+            map_value_type=value_type,
+            scope=scope,
+        )
+
+    def parse_oneof(self, message: Message) -> None:
+        start = self.expect("oneof")
+        name = self.expect_ident()
+        oneof = Oneof(name=name.value, line=start.line, leading_comment=start.leading_comment, scope=message.full_name)
+        self.expect("{")
+        while not self.accept("}"):
+            if self.accept(";"):
+                continue
+            if self.at("option"):
+                self.parse_option_statement(oneof.options)
+                continue
+            self.add_field(message, self.parse_field(message.full_name, message), oneof)
+        message.oneofs.append(oneof)
+
+    def parse_extend(self, scope: str, message: Optional[Message]) -> List[Field]:
+        self.expect("extend")
+        extendee = self.expect_ident().value
+        self.expect("{")
+        fields = []
+        while not self.accept("}"):
+            if self.accept(";"):
+                continue
+            ext = self.parse_field(scope, message)
+            ext.extendee = extendee
+            fields.append(ext)
+        return fields
+
+    def parse_enum(self, scope: str, parent: Optional[str]) -> Enum:
+        start = self.expect("enum")
+        name = self.expect_ident()
+        enum = Enum(
+            name=name.value,
+            full_name=self.qualify(scope, name.value),
+            line=start.line,
+            leading_comment=start.leading_comment,
+            parent=parent,
+        )
+        self.expect("{")
+        while not self.accept("}"):
+            token = self.peek()
+            if token is None:
+                raise ProtoParseError(self.path, enum.line, f"unterminated enum {enum.name}")
+            if self.accept(";"):
+                continue
+            if token.value == "option":
+                self.parse_option_statement(enum.options)
+            elif token.value == "reserved":
+                self.parse_reserved(enum.reserved_numbers, enum.reserved_names)
+            else:
+                value_name = self.expect_ident()
+                self.expect("=")
+                number = self.expect_int()
+                options = self.parse_field_options()
+                end = self.expect(";")
+                enum.values.append(EnumValue(
+                    name=value_name.value,
+                    number=number,
+                    line=value_name.line,
+                    options=options,
+                    leading_comment=value_name.leading_comment,
+                    trailing_comment=self.trailing_comment(end.line),
+                ))
+        return enum
+
+    def parse_service(self) -> Service:
+        start = self.expect("service")
+        name = self.expect_ident()
+        service = Service(
+            name=name.value,
+            full_name=self.qualify(self.file.package, name.value),
+            line=start.line,
+            leading_comment=start.leading_comment,
+        )
+        self.expect("{")
+        while not self.accept("}"):
+            token = self.peek()
+            if token is None:
+                raise ProtoParseError(self.path, service.line, f"unterminated service {service.name}")
+            if self.accept(";"):
+                continue
+            if token.value == "option":
+                self.parse_option_statement(service.options)
+            elif token.value == "rpc":
+                service.methods.append(self.parse_method(service))
+            else:
+                raise ProtoParseError(self.path, token.line, f"unexpected token {token.value!r} in service")
+        return service
+
+    def parse_method(self, service: Service) -> Method:
+        start = self.expect("rpc")
+        name = self.expect_ident()
+        self.expect("(")
+        client_streaming = self.at("stream") and self.peek(1) is not None and self.peek(1).kind == "ident"
+        if client_streaming:
+            self.next()
+        input_type = self.expect_ident().value
+        self.expect(")")
+        self.expect("returns")
+        self.expect("(")
+        server_streaming = self.at("stream") and self.peek(1) is not None and self.peek(1).kind == "ident"
+        if server_streaming:
+            self.next()
+        output_type = self.expect_ident().value
+        self.expect(")")
+        method = Method(
+            name=name.value,
+            input_type=input_type,
+            output_type=output_type,
+            line=start.line,
+            client_streaming=client_streaming,
+            server_streaming=server_streaming,
+            leading_comment=start.leading_comment,
+            service=service.full_name,
+        )
+        if self.accept("{"):
+            while not self.accept("}"):
+                if self.accept(";"):
+                    continue
+                self.parse_option_statement(method.options)
+        else:
+            self.expect(";")
+        return method
+
+
+def _store_option(options: Dict[str, Any], name: str, value: Any) -> None:
+    """Stores an option, merging sub-field assignments like (a).b.c = v."""
+    if name.startswith("(") and ")." in name:
+        base, _, path = name.partition(").")
+        base += ")"
+        target = options.setdefault(base, {})
+        if not isinstance(target, dict):
+            options[name] = value
+            return
+        keys = path.split(".")
+        for key in keys[:-1]:
+            target = target.setdefault(key, {})
+            if not isinstance(target, dict):
+                options[name] = value
+                return
+        existing = target.get(keys[-1])
+        if isinstance(value, dict) and (isinstance(existing, list) or isinstance(existing, dict) and existing):
+            # Repeated message options, e.g. two (buf.validate.message).cel entries
+            _store_aggregate(target, keys[-1], value)
+            return
+        target[keys[-1]] = value
+        return
+    if name in options and isinstance(options[name], dict) and isinstance(value, dict):
+        options[name].update(value)
+        return
+    options[name] = value
+
+
+def _store_aggregate(result: Dict[str, Any], key: str, value: Any) -> None:
+    if key in result:
+        existing = result[key]
+        if isinstance(existing, list):
+            existing.append(value)
+        else:
+            result[key] = [existing, value]
+    else:
+        result[key] = value
+
+
+def parse_proto(content: str, path: str = "<input>") -> ProtoFile:
+    """
+    Parses proto source text.
+
+    Args:
+        content: Source text of the .proto file
+        path: Path used for diagnostics and import matching
+
+    Returns:
+        Parsed ProtoFile
+    """
+    return _Parser(path, content).parse()
+
+
+def parse_proto_file(path: Union[str, Path]) -> ProtoFile:
+    """Parses a .proto file from disk."""
+    with open(path, "r", encoding="utf-8") as f:
+        return parse_proto(f.read(), str(path))
+
+
+def load_schema_set(paths: List[str], dep_paths: Optional[List[str]] = None) -> SchemaSet:
+    """
+    Parses target files and their dependencies into a SchemaSet.
+
+    Dependency files that are also targets are only parsed once.
+    """
+    files = [parse_proto_file(p) for p in paths]
+    seen = set(paths)
+    dep_files = []
+    for dep_path in dep_paths or []:
+        if dep_path in seen:
+            continue
+        seen.add(dep_path)
+        dep_files.append(parse_proto_file(dep_path))
+    return SchemaSet(files, dep_files)
+
+
+def _summarize(proto_file: ProtoFile) -> Dict[str, Any]:
+    return {
+        "path": proto_file.path,
+        "syntax": proto_file.syntax,
+        "package": proto_file.package,
+        "imports": proto_file.imports,
+        "options": proto_file.options,
+        "messages": [m.full_name for m in proto_file.all_messages()],
+        "enums": [e.full_name for e in proto_file.all_enums()],
+        "services": {
+            s.full_name: [m.name for m in s.methods] for s in proto_file.services
+        },
+    }
+
+
+def main():
+    """Main entry point: prints a JSON summary of the given proto files."""
+    parser = argparse.ArgumentParser(description="Parse proto files and print a JSON summary")
+    parser.add_argument("files", nargs="+", help="Proto files to parse")
+    args = parser.parse_args()
+
+    try:
+        summaries = [_summarize(parse_proto_file(path)) for path in args.files]
+    except (OSError, ProtoParseError) as e:
+        print(f"ERROR: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    print(json.dumps(summaries, indent=2))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,360 @@
+#!/usr/bin/env python3
+"""
+CUE schema generation from protobuf messages.
+
+This tool translates proto messages and enums into CUE definitions so that
+configuration written in JSON or YAML can be validated with `cue vet` against
+the same schema as the protobuf API. A subset of protovalidate constraints is
+translated into CUE constraints; everything else is documented in the output
+as a comment rather than silently dropped.
+
+Mapping summary:
+    message          -> #Definition (nested messages become nested definitions)
+    enum             -> disjunction of value names ("A" | "B")
+    repeated T       -> [...T]
+    map<K, V>        -> {[string]: V}
+    proto2 required  -> required field; every other field is optional (field?:)
+
+Unmappable constructs (emitted as comments):
+    oneof exclusivity, extensions, types outside the translated files, and
+    protovalidate rules without a CUE equivalent (e.g. string.email, cel).
+
+Usage:
+    proto_to_cue.py --package user --output user.cue user.proto
+"""
+
+import argparse
+import json
+import re
+import sys
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+try:
+    from proto_schema import Enum, Field, Message, ProtoParseError, SchemaSet, find_option, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import Enum, Field, Message, ProtoParseError, SchemaSet, find_option, load_schema_set
+
+
+SCALAR_CUE_TYPES = {
+    "double": "float64",
+    "float": "float32",
+    "int32": "int32",
+    "sint32": "int32",
+    "sfixed32": "int32",
+    "int64": "int64",
+    "sint64": "int64",
+    "sfixed64": "int64",
+    "uint32": "uint32",
+    "fixed32": "uint32",
+    "uint64": "uint64",
+    "fixed64": "uint64",
+    "bool": "bool",
+    "string": "string",
+    "bytes": "bytes",
+}
+
+# Well-known types use their proto3 JSON representation
+WELL_KNOWN_CUE_TYPES = {
+    "google.protobuf.Timestamp": "time.Time",
+    "google.protobuf.Duration": 'string & =~"^-?[0-9]+(\\\\.[0-9]+)?s$"',
+    "google.protobuf.Struct": "{...}",
+    "google.protobuf.Value": "_",
+    "google.protobuf.ListValue": "[..._]",
+    "google.protobuf.Empty": "{}",
+    "google.protobuf.FieldMask": "string",
+    "google.protobuf.Any": '{"@type": string, ...}',
+    "google.protobuf.DoubleValue": "float64 | null",
+    "google.protobuf.FloatValue": "float32 | null",
+    "google.protobuf.Int32Value": "int32 | null",
+    "google.protobuf.Int64Value": "int64 | null",
+    "google.protobuf.UInt32Value": "uint32 | null",
+    "google.protobuf.UInt64Value": "uint64 | null",
+    "google.protobuf.BoolValue": "bool | null",
+    "google.protobuf.StringValue": "string | null",
+    "google.protobuf.BytesValue": "bytes | null",
+}
+
+_NUMERIC_RULES = {"int32", "int64", "uint32", "uint64", "sint32", "sint64", "fixed32", "fixed64",
+                  "sfixed32", "sfixed64", "float", "double"}
+
+
+class CueTranslationError(Exception):
+    """Raised when a schema cannot be translated."""
+
+
+def cue_string(value: str) -> str:
+    """Renders a CUE string literal."""
+    return json.dumps(value)
+
+
+def cue_label(name: str) -> str:
+    """Renders a field label, quoting it when it is not a valid identifier."""
+    return name if re.fullmatch(r"[A-Za-z_$][A-Za-z0-9_$]*", name) and not name.startswith("#") else cue_string(name)
+
+
+class CueTranslator:
+    """Translates a parsed schema into a single CUE file."""
+
+    def __init__(self, schema: SchemaSet, package: str, field_names: str = "json"):
+        if field_names not in ("json", "proto"):
+            raise CueTranslationError(f"field_names must be 'json' or 'proto', got '{field_names}'")
+        self.schema = schema
+        self.package = package
+        self.field_names = field_names
+        self.imports: Set[str] = set()
+        self.unmappable: List[str] = []
+        self.local_types: Dict[str, str] = {}
+        for proto_file in schema.files:
+            for message in proto_file.all_messages():
+                self.local_types[message.full_name] = self._definition_path(message.full_name, proto_file.package)
+            for enum in proto_file.all_enums():
+                self.local_types[enum.full_name] = self._definition_path(enum.full_name, proto_file.package)
+        self._check_collisions()
+
+    def _definition_path(self, full_name: str, package: str) -> str:
+        relative = full_name[len(package) + 1:] if package and full_name.startswith(package + ".") else full_name
+        return ".".join("#" + part for part in relative.split("."))
+
+    def _check_collisions(self) -> None:
+        seen: Dict[str, str] = {}
+        for full_name, path in self.local_types.items():
+            if path in seen:
+                raise CueTranslationError(f"{full_name} and {seen[path]} both translate to {path}")
+            seen[path] = full_name
+
+    def note(self, element: str, reason: str) -> str:
+        self.unmappable.append(f"{element}: {reason}")
+        return f"// unmappable: {reason}"
+
+    # Types
+
+    def type_expr(self, type_name: str, scope: str, element: str) -> Tuple[str, Optional[str]]:
+        """Returns (CUE type expression, optional unmappable comment)."""
+        if type_name in SCALAR_CUE_TYPES:
+            return SCALAR_CUE_TYPES[type_name], None
+        full_name = self.schema.resolve_type_name(type_name, scope)
+        if full_name in self.local_types:
+            return self.local_types[full_name], None
+        if full_name in WELL_KNOWN_CUE_TYPES:
+            if full_name == "google.protobuf.Timestamp":
+                self.imports.add("time")
+            return WELL_KNOWN_CUE_TYPES[full_name], None
+        return "_", self.note(element, f"type {full_name or type_name} is not part of the translated files")
+
+    def field_expr(self, message_field: Field, scope: str) -> Tuple[str, List[str]]:
+        comments = []
+        value_type = message_field.map_value_type if message_field.is_map else message_field.type_name
+        base, note = self.type_expr(value_type, scope, message_field.full_name)
+        if note:
+            comments.append(note)
+
+        rules = find_option(message_field.options, "buf.validate.field")
+        constraints, rule_notes = self.constraints(message_field, rules if isinstance(rules, dict) else {})
+        comments.extend(rule_notes)
+
+        element_expr = " & ".join([base] + constraints["element"]) if constraints["element"] else base
+        if message_field.is_map:
+            expr = f"{{[string]: {element_expr}}}"
+        elif message_field.label == "repeated":
+            expr = f"[...{element_expr}]"
+        else:
+            expr = element_expr
+        if constraints["container"]:
+            expr = " & ".join([expr] + constraints["container"])
+        return expr, comments
+
+    # protovalidate
+
+    def constraints(self, message_field: Field, rules: Dict[str, Any]) -> Tuple[Dict[str, List[str]], List[str]]:
+        """Translates supported protovalidate rules into CUE constraints."""
+        result: Dict[str, List[str]] = {"element": [], "container": []}
+        notes = []
+        for kind, value in rules.items():
+            if kind == "required":
+                continue
+            if kind == "repeated" and isinstance(value, dict):
+                for rule, argument in value.items():
+                    if rule == "min_items":
+                        self.imports.add("list")
+                        result["container"].append(f"list.MinItems({argument})")
+                    elif rule == "max_items":
+                        self.imports.add("list")
+                        result["container"].append(f"list.MaxItems({argument})")
+                    elif rule == "items" and isinstance(argument, dict):
+                        element, element_notes = self.constraints(message_field, argument)
+                        result["element"].extend(element["element"])
+                        notes.extend(element_notes)
+                    else:
+                        notes.append(self.note(message_field.full_name, f"repeated.{rule}"))
+                continue
+            if not isinstance(value, dict):
+                notes.append(self.note(message_field.full_name, kind))
+                continue
+            for rule, argument in value.items():
+                expr = self._scalar_constraint(kind, rule, argument)
+                if expr is None:
+                    notes.append(self.note(message_field.full_name, f"{kind}.{rule}"))
+                else:
+                    result["element"].append(expr)
+        return result, notes
+
+    def _scalar_constraint(self, kind: str, rule: str, argument: Any) -> Optional[str]:
+        if kind == "string":
+            if rule == "min_len":
+                self.imports.add("strings")
+                return f"strings.MinRunes({argument})"
+            if rule == "max_len":
+                self.imports.add("strings")
+                return f"strings.MaxRunes({argument})"
+            if rule == "pattern":
+                return f"=~{cue_string(argument)}"
+            if rule == "prefix":
+                self.imports.add("strings")
+                return f"strings.HasPrefix({cue_string(argument)})"
+            if rule == "suffix":
+                self.imports.add("strings")
+                return f"strings.HasSuffix({cue_string(argument)})"
+            if rule == "contains":
+                self.imports.add("strings")
+                return f"strings.Contains({cue_string(argument)})"
+            if rule == "const":
+                return cue_string(argument)
+            if rule == "in":
+                values = argument if isinstance(argument, list) else [argument]
+                return "(" + " | ".join(cue_string(v) for v in values) + ")"
+        if kind in _NUMERIC_RULES:
+            operators = {"gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+            if rule in operators:
+                return f"{operators[rule]}{argument}"
+            if rule == "const":
+                return str(argument)
+            if rule == "in":
+                values = argument if isinstance(argument, list) else [argument]
+                return "(" + " | ".join(str(v) for v in values) + ")"
+        return None
+
+    # Definitions
+
+    def label(self, message_field: Field) -> str:
+        return message_field.json_name if self.field_names == "json" else message_field.name
+
+    def is_required(self, message_field: Field) -> bool:
+        if message_field.label == "required":
+            return True
+        rules = find_option(message_field.options, "buf.validate.field")
+        return isinstance(rules, dict) and rules.get("required") is True
+
+    def enum_lines(self, enum: Enum, indent: str) -> List[str]:
+        lines = []
+        if enum.leading_comment:
+            lines.extend(f"{indent}// {line}".rstrip() for line in enum.leading_comment.split("\n"))
+        values = " | ".join(cue_string(value.name) for value in enum.values) or "_|_"
+        lines.append(f"{indent}#{enum.name}: {values}")
+        return lines
+
+    def message_lines(self, message: Message, indent: str) -> List[str]:
+        lines = []
+        if message.leading_comment:
+            lines.extend(f"{indent}// {line}".rstrip() for line in message.leading_comment.split("\n"))
+        lines.append(f"{indent}#{message.name}: {{")
+        inner = indent + "\t"
+        oneof_fields = {name for oneof in message.oneofs for name in oneof.fields}
+
+        for message_field in message.fields:
+            expr, comments = self.field_expr(message_field, message.full_name)
+            if message_field.leading_comment:
+                lines.extend(f"{inner}// {line}".rstrip() for line in message_field.leading_comment.split("\n"))
+            for comment in comments:
+                lines.append(f"{inner}{comment}")
+            marker = "" if self.is_required(message_field) and message_field.name not in oneof_fields else "?"
+            lines.append(f"{inner}{cue_label(self.label(message_field))}{marker}: {expr}")
+
+        for oneof in message.oneofs:
+            lines.append(f"{inner}{self.note(oneof.full_name, 'oneof exclusivity is not enforced; members are optional')}")
+        if message.extensions or message.extension_ranges:
+            lines.append(f"{inner}{self.note(message.full_name, 'extensions are not translated')}")
+
+        for enum in message.enums:
+            lines.extend(self.enum_lines(enum, inner))
+        for nested in message.messages:
+            if not nested.is_map_entry:
+                lines.extend(self.message_lines(nested, inner))
+        lines.append(f"{indent}}}")
+        return lines
+
+    def translate(self) -> str:
+        body = []
+        for proto_file in self.schema.files:
+            body.append(f"// Source: {proto_file.path}")
+            for enum in proto_file.enums:
+                body.extend(self.enum_lines(enum, ""))
+                body.append("")
+            for message in proto_file.messages:
+                body.extend(self.message_lines(message, ""))
+                body.append("")
+            if proto_file.extensions:
+                body.append(self.note(proto_file.path, "top-level extensions are not translated"))
+                body.append("")
+
+        lines = ["// Code generated by buck2-protobuf proto_to_cue. DO NOT EDIT.", "", f"package {self.package}", ""]
+        if self.imports:
+            lines.append("import (")
+            lines.extend(f"\t{cue_string(i)}" for i in sorted(self.imports))
+            lines.extend([")", ""])
+        lines.extend(body)
+        return "\n".join(lines).rstrip() + "\n"
+
+
+def cue_package_name(schema: SchemaSet, override: str = "") -> str:
+    """Derives a CUE package name from the override or the first proto package."""
+    name = override
+    if not name:
+        packages = [f.package for f in schema.files if f.package]
+        name = packages[0].split(".")[-1] if packages else "proto"
+    name = re.sub(r"[^A-Za-z0-9_]", "_", name)
+    return "_" + name if name[0].isdigit() else name
+
+
+def translate_files(files: List[str], package: str = "", field_names: str = "json",
+                    dep_files: Optional[List[str]] = None) -> Tuple[str, List[str]]:
+    """
+    Translates proto files into one CUE file.
+
+    Returns:
+        Tuple of (CUE source, list of unmappable construct notes)
+    """
+    schema = load_schema_set(files, dep_files)
+    translator = CueTranslator(schema, cue_package_name(schema, package), field_names)
+    return translator.translate(), translator.unmappable
+
+
+def main():
+    """Main entry point for CUE schema generation."""
+    parser = argparse.ArgumentParser(description="Generate a CUE schema from proto files")
+    parser.add_argument("--package", default="", help="CUE package name (default: last proto package component)")
+    parser.add_argument("--field-names", default="json", choices=["json", "proto"], help="Field label style")
+    parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    parser.add_argument("--output", help="Output .cue file")
+    parser.add_argument("files", nargs="+", help="Proto files to translate")
+    args = parser.parse_args()
+
+    try:
+        content, unmappable = translate_files(args.files, args.package, args.field_names, args.dep)
+    except (CueTranslationError, ProtoParseError, OSError) as e:
+        print(f"ERROR: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(content)
+    else:
+        sys.stdout.write(content)
+
+    for note in unmappable:
+        print(f"note: {note}", file=sys.stderr)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,368 @@
+#!/usr/bin/env python3
+"""
+Sequential protoc plugin pipeline for protobuf Buck2 integration.
+
+protoc runs every plugin of one invocation on the same parsed request, so a
+plugin can never see files written by another one. Plugins that post-process
+generated code (e.g. a struct tag injector rewriting protoc-gen-go output)
+need that code on disk first. This tool runs one protoc invocation per plugin,
+in the declared order, all writing into the same staging directory: each
+stage sees, and may rewrite, everything the earlier stages produced.
+
+Arguments after "--" are the arguments shared by every invocation (import
+paths and proto files), followed by one "--stage" group per plugin. The
+placeholder {out_dir} in any argument is replaced by the staging directory.
+
+With --plugin-memory-limit (or BUCK2_PROTOBUF_PLUGIN_MEMORY_LIMIT), e.g.
+"512M" or "2G", every plugin passed with --plugin= runs under a launcher that
+caps its memory with an rlimit (RLIMIT_DATA on Linux, RLIMIT_AS elsewhere)
+before starting it, so a runaway plugin fails on its own instead of
+exhausting the host; protoc itself is not capped. A stage whose plugin ran
+out of memory fails with an error naming the plugin and the limit; a plugin
+killed by another signal is reported as a crash. Platforms
+without rlimits (Windows) run the plugins uncapped, with a warning.
+
+When BUCK2_PROTOBUF_PROFILE_DIR is set, the duration of every stage and of
+copying the outputs is written to <dir>/<profile name>.trace.json in Chrome
+trace format (chrome://tracing, Perfetto, speedscope). The profile is written
+outside the declared outputs, which stay byte-identical.
+
+Plugins see the absolute staging directory through {out_dir}, and some echo
+it (or the sandbox they ran in) into generated headers and comments. Copied
+outputs have the staging directory and the working directory prefixes
+removed, so the same inputs produce the same bytes on every machine.
+
+Usage:
+    protoc_pipeline.py --protoc bin/protoc --out-dir staged/ \\
+        --output user.pb.go -- --proto_path=. user.proto \\
+        --stage go --plugin=protoc-gen-go=bin/protoc-gen-go --go_out={out_dir} \\
+        --stage gotag --plugin=protoc-gen-gotag=bin/gotag --gotag_out=outdir={out_dir}:{out_dir}
+"""
+
+import argparse
+import json
+import os
+import re
+import shutil
+import signal
+import subprocess
+import sys
+import tempfile
+import time
+from contextlib import contextmanager
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Iterator, List, Optional, Tuple
+
+STAGE_MARKER = "--stage"
+OUT_DIR_PLACEHOLDER = "{out_dir}"
+PROFILE_DIR_ENV = "BUCK2_PROTOBUF_PROFILE_DIR"
+MEMORY_LIMIT_ENV = "BUCK2_PROTOBUF_PLUGIN_MEMORY_LIMIT"
+
+MEMORY_UNITS = {"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+# Exit signal and stderr fragments of a process that failed to allocate memory.
+# Other signals are crashes unless stderr reports a failed allocation, e.g. an
+# abort on std::bad_alloc.
+OOM_SIGNAL = signal.SIGKILL
+OOM_MESSAGES = (
+    "out of memory",
+    "cannot allocate memory",
+    "memoryerror",
+    "std::bad_alloc",
+    "memory allocation of",
+    "failed to reserve",
+)
+
+try:
+    import resource
+except ImportError:  # Windows
+    resource = None
+
+
+class PipelineError(Exception):
+    """Raised when the pipeline is malformed or a stage fails."""
+
+
+@dataclass
+class Stage:
+    """One protoc invocation running a single plugin."""
+    plugin: str
+    args: List[str]
+
+
+@dataclass
+class Profile:
+    """Durations of the pipeline phases of one target."""
+    name: str
+    events: List[Dict] = field(default_factory=list)
+    start: float = field(default_factory=time.perf_counter)
+
+    @contextmanager
+    def phase(self, name: str, category: str, **args) -> Iterator[None]:
+        """Records the duration of the enclosed block as a complete trace event."""
+        begin = time.perf_counter()
+        try:
+            yield
+        finally:
+            self.events.append({
+                "name": name,
+                "cat": category,
+                "ph": "X",
+                "ts": round((begin - self.start) * 1e6),
+                "dur": round((time.perf_counter() - begin) * 1e6),
+                "pid": 1,
+                "tid": 1,
+                "args": args,
+            })
+
+    def to_trace(self) -> Dict:
+        """Returns the profile in Chrome trace event format."""
+        return {
+            "traceEvents": [{"name": "process_name", "ph": "M", "pid": 1, "tid": 1, "args": {"name": self.name}}] + self.events,
+            "displayTimeUnit": "ms",
+        }
+
+    def write(self, profile_dir: str) -> str:
+        """Writes the trace to <profile_dir>/<name>.trace.json and returns its path."""
+        file_name = re.sub(r"[^A-Za-z0-9_.-]+", "_", self.name).strip("_") or "pipeline"
+        path = Path(profile_dir) / f"{file_name}.trace.json"
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text(json.dumps(self.to_trace(), indent=2), encoding="utf-8")
+        return str(path)
+
+
+@contextmanager
+def _phase(profile: Optional[Profile], name: str, category: str, **args) -> Iterator[None]:
+    if profile is None:
+        yield
+    else:
+        with profile.phase(name, category, **args):
+            yield
+
+
+def parse_stages(argv: List[str]) -> Tuple[List[str], List[Stage]]:
+    """
+    Splits the arguments after "--" into shared arguments and stages.
+
+    Each stage starts with "--stage <plugin name>".
+
+    Returns:
+        Tuple of (shared protoc arguments, stages in execution order)
+    """
+    common: List[str] = []
+    stages: List[Stage] = []
+    i = 0
+    while i < len(argv):
+        if argv[i] == STAGE_MARKER:
+            if i + 1 >= len(argv):
+                raise PipelineError(f"{STAGE_MARKER} requires a plugin name")
+            stages.append(Stage(plugin=argv[i + 1], args=[]))
+            i += 2
+            continue
+        (stages[-1].args if stages else common).append(argv[i])
+        i += 1
+    if not stages:
+        raise PipelineError("no stages given")
+    return common, stages
+
+
+def stage_command(protoc: str, common: List[str], stage: Stage, out_dir: str) -> List[str]:
+    """Returns the protoc command line of a stage."""
+    return [arg.replace(OUT_DIR_PLACEHOLDER, out_dir) for arg in [protoc] + common + stage.args]
+
+
+def check_plugins(stages: List[Stage]) -> None:
+    """
+    Verifies that every --plugin=protoc-gen-<name>=<path> given to a stage is
+    an executable file, so a wrong plugin prefix fails before protoc runs with
+    the path that was tried.
+    """
+    for stage in stages:
+        for arg in stage.args:
+            if not arg.startswith("--plugin=") or "=" not in arg[len("--plugin="):]:
+                continue
+            name, path = arg[len("--plugin="):].split("=", 1)
+            if not os.path.isfile(path):
+                raise PipelineError(f"{name} not found at {path}")
+            if not os.access(path, os.X_OK):
+                raise PipelineError(f"{name} at {path} is not executable")
+
+
+def parse_memory_limit(value: str) -> int:
+    """Parses a memory size such as "512M", "2G", "1.5GiB" or "1048576" to bytes."""
+    match = re.fullmatch(r"\s*(\d+(?:\.\d+)?)\s*([KMGT]?)(?:I?B)?\s*", value.upper())
+    if not match or float(match.group(1)) <= 0:
+        raise PipelineError(f"invalid plugin memory limit '{value}', expected e.g. 512M or 2G")
+    return int(float(match.group(1)) * MEMORY_UNITS[match.group(2)])
+
+
+def format_memory(limit: int) -> str:
+    for unit in ("T", "G", "M", "K"):
+        if limit >= MEMORY_UNITS[unit] and limit % MEMORY_UNITS[unit] == 0:
+            return f"{limit // MEMORY_UNITS[unit]} {unit}iB"
+    return f"{limit} bytes"
+
+
+def limit_plugins(stages: List[Stage], limit: int, launcher_dir: str) -> Dict[str, str]:
+    """
+    Replaces every --plugin=protoc-gen-<name>=<path> by a launcher that runs
+    the plugin under the memory limit.
+
+    Returns:
+        Out-of-memory marker file of each launched plugin, by plugin name
+    """
+    markers = {}
+    for stage in stages:
+        for i, arg in enumerate(stage.args):
+            if not arg.startswith("--plugin=") or "=" not in arg[len("--plugin="):]:
+                continue
+            name, path = arg[len("--plugin="):].split("=", 1)
+            launcher = Path(launcher_dir) / name
+            markers[name] = str(launcher) + ".oom"
+            launcher.write_text(
+                "#!/bin/sh\n"
+                f"exec '{sys.executable}' '{Path(__file__).resolve()}' --run-plugin '{os.path.abspath(path)}' "
+                f"--plugin-memory-limit {limit} --oom-marker '{markers[name]}'\n",
+                encoding="utf-8",
+            )
+            launcher.chmod(0o755)
+            stage.args[i] = f"--plugin={name}={launcher}"
+    return markers
+
+
+def _set_memory_limit(limit: int) -> None:
+    kind = resource.RLIMIT_DATA if sys.platform.startswith("linux") else resource.RLIMIT_AS
+    resource.setrlimit(kind, (limit, limit))
+
+
+def ran_out_of_memory(returncode: int, stderr: str) -> bool:
+    """Returns whether a failed plugin looks like it hit its memory limit."""
+    if returncode == -OOM_SIGNAL:
+        return True
+    lowered = stderr.lower()
+    return returncode != 0 and any(message in lowered for message in OOM_MESSAGES)
+
+
+def run_limited_plugin(plugin: str, limit: int, oom_marker: str) -> int:
+    """
+    Runs a plugin under a memory limit, forwarding the protoc request and response.
+
+    Writes oom_marker when the plugin fails because it ran out of memory, and
+    reports other deaths by signal as crashes.
+    """
+    result = subprocess.run(
+        [plugin], stdin=sys.stdin.buffer, stdout=sys.stdout.buffer, stderr=subprocess.PIPE,
+        preexec_fn=lambda: _set_memory_limit(limit),
+    )
+    stderr = result.stderr.decode("utf-8", "replace")
+    sys.stderr.write(stderr)
+    if ran_out_of_memory(result.returncode, stderr):
+        Path(oom_marker).write_text(f"{result.returncode}\n", encoding="utf-8")
+    elif result.returncode < 0:
+        sys.stderr.write(f"{Path(plugin).name} crashed with {signal.Signals(-result.returncode).name}\n")
+    return result.returncode if result.returncode >= 0 else 128 - result.returncode
+
+
+def run_pipeline(protoc: str, common: List[str], stages: List[Stage], out_dir: str, profile: Optional[Profile] = None,
+                 memory_limit: Optional[int] = None) -> None:
+    """Runs the stages in order, stopping at the first failing one."""
+    check_plugins(stages)
+    Path(out_dir).mkdir(parents=True, exist_ok=True)
+    with tempfile.TemporaryDirectory(prefix="protoc_pipeline_") as launcher_dir:
+        markers = {}
+        if memory_limit and resource is None:
+            print("WARNING: protoc_pipeline: plugin memory limits need rlimit support; running plugins uncapped",
+                  file=sys.stderr)
+        elif memory_limit:
+            markers = limit_plugins(stages, memory_limit, launcher_dir)
+        for index, stage in enumerate(stages, 1):
+            with _phase(profile, f"protoc-gen-{stage.plugin}", "plugin", stage=index):
+                result = subprocess.run(stage_command(protoc, common, stage, out_dir), capture_output=True, text=True)
+            if result.returncode != 0:
+                exhausted = [name for name, marker in sorted(markers.items()) if os.path.exists(marker)]
+                if exhausted:
+                    raise PipelineError(
+                        f"stage {index}/{len(stages)} ({stage.plugin}): {', '.join(exhausted)} ran out of memory "
+                        f"(plugin memory limit {format_memory(memory_limit)}, set by --plugin-memory-limit or "
+                        f"{MEMORY_LIMIT_ENV}):\n{result.stderr.strip()}"
+                    )
+                raise PipelineError(
+                    f"stage {index}/{len(stages)} ({stage.plugin}) failed with exit code {result.returncode}:\n"
+                    f"{result.stderr.strip()}"
+                )
+
+
+def strip_sandbox_paths(content: bytes, prefixes: List[str]) -> bytes:
+    """
+    Removes absolute directory prefixes from generated content, turning e.g.
+    "// source: /sandbox/123/acme/v1/user.proto" into "// source: acme/v1/user.proto".
+    """
+    # Longest first: the staging directory usually lives in the working directory
+    for prefix in sorted({p.rstrip(os.sep) for p in prefixes if p}, key=len, reverse=True):
+        content = content.replace((prefix + os.sep).encode("utf-8"), b"")
+    return content
+
+
+def copy_outputs(out_dir: str, outputs: List[str], strip_prefixes: Optional[List[str]] = None) -> None:
+    """
+    Copies declared outputs from the staging directory, selecting them by
+    basename, with strip_prefixes removed from their content.
+    """
+    for output in outputs:
+        name = Path(output).name
+        matches = sorted(Path(out_dir).rglob(name))
+        if not matches:
+            raise PipelineError(f"no stage produced {name}")
+        if strip_prefixes:
+            Path(output).write_bytes(strip_sandbox_paths(matches[0].read_bytes(), strip_prefixes))
+        else:
+            shutil.copyfile(matches[0], output)
+
+
+def main():
+    """Main entry point for the protoc plugin pipeline."""
+    parser = argparse.ArgumentParser(description="Run protoc plugins sequentially with intermediate staging")
+    parser.add_argument("--protoc", required=True, help="protoc executable")
+    parser.add_argument("--out-dir", required=True, help="Staging directory shared by all stages")
+    parser.add_argument("--output", action="append", default=[], help="Declared output file; its basename selects the staged file")
+    parser.add_argument("--profile-name", help=f"Name of the profile written when {PROFILE_DIR_ENV} is set (default: the staging directory name)")
+    parser.add_argument("--plugin-memory-limit", help=f"Memory cap of each plugin, e.g. 512M (default: ${MEMORY_LIMIT_ENV})")
+    parser.add_argument("pipeline", nargs=argparse.REMAINDER, help="-- shared arguments, then --stage <plugin> groups")
+
+    # Internal: the launcher of a memory-limited plugin
+    if sys.argv[1:2] == ["--run-plugin"]:
+        launcher = argparse.ArgumentParser()
+        launcher.add_argument("--run-plugin", required=True)
+        launcher.add_argument("--plugin-memory-limit", type=int, required=True)
+        launcher.add_argument("--oom-marker", required=True)
+        launched = launcher.parse_args()
+        sys.exit(run_limited_plugin(launched.run_plugin, launched.plugin_memory_limit, launched.oom_marker))
+
+    args = parser.parse_args()
+
+    profile_dir = os.environ.get(PROFILE_DIR_ENV)
+    profile = Profile(args.profile_name or Path(args.out_dir).name) if profile_dir else None
+    try:
+        pipeline = args.pipeline[1:] if args.pipeline[:1] == ["--"] else args.pipeline
+        common, stages = parse_stages(pipeline)
+        out_dir = str(Path(args.out_dir).resolve())
+        limit = args.plugin_memory_limit or os.environ.get(MEMORY_LIMIT_ENV)
+        memory_limit = parse_memory_limit(limit) if limit else None
+        with _phase(profile, "pipeline", "pipeline", stages=len(stages)):
+            run_pipeline(args.protoc, common, stages, out_dir, profile, memory_limit=memory_limit)
+            with _phase(profile, "copy_outputs", "io", outputs=len(args.output)):
+                copy_outputs(out_dir, args.output, strip_prefixes=[out_dir, os.getcwd()])
+    except (PipelineError, OSError) as e:
+        print(f"ERROR: protoc_pipeline: {e}", file=sys.stderr)
+        sys.exit(2)
+    finally:
+        if profile is not None:
+            try:
+                profile.write(profile_dir)
+            except OSError as e:
+                print(f"WARNING: protoc_pipeline: cannot write profile: {e}", file=sys.stderr)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,87 @@
+"""Checksum-pinned protoc toolchain for Buck2.
+
+This module provides a protoc toolchain whose release archive is verified
+against a SHA256 pin before it is used. Pins come from a committed lockfile
+(//tools:protoc.lock.json by default); per-platform overrides cover archives
+served by air-gapped mirrors.
+"""
+
+load("//rules:tools.bzl", "get_protoc_binary", "get_target_platform")
+load("//rules/private:providers.bzl", "ProtocToolchainInfo")
+
+def _is_sha256(value):
+    return len(value) == 64 and all([c in "0123456789abcdef" for c in value.lower().elems()])
+
+def _protoc_toolchain_impl(ctx):
+    """
+    Implementation for protoc_toolchain rule.
+    
+    Downloads protoc for the target platform and verifies the archive against
+    its pinned SHA256, failing the build on a mismatch.
+    
+    Args:
+        ctx: Buck2 rule context
+        
+    Returns:
+        List of providers including ProtocToolchainInfo
+    """
+    for platform, checksum in ctx.attrs.sha256.items():
+        if not _is_sha256(checksum):
+            fail("sha256 for {} must be 64 hex characters, got '{}'".format(platform, checksum))
+
+    platform = ctx.attrs.platform or get_target_platform(ctx)
+    sha256 = ctx.attrs.sha256.get(platform, "")
+    protoc = get_protoc_binary(
+        ctx,
+        version = ctx.attrs.version,
+        platform = platform,
+        sha256 = sha256,
+        url = ctx.attrs.urls.get(platform, ""),
+        lockfile = ctx.attrs.lockfile,
+    )
+
+    return [
+        DefaultInfo(default_outputs = [protoc]),
+        RunInfo(args = cmd_args(protoc)),
+        ProtocToolchainInfo(
+            protoc = protoc,
+            version = ctx.attrs.version,
+            platform = platform,
+            sha256 = sha256,
+        ),
+    ]
+
+# Protoc toolchain rule definition
+protoc_toolchain = rule(
+    impl = _protoc_toolchain_impl,
+    attrs = {
+        "version": attrs.string(
+            doc = "Protoc version to download, e.g. \"25.1\"",
+        ),
+        "platform": attrs.option(
+            attrs.string(),
+            default = None,
+            doc = "Target platform (auto-detected if not specified)",
+        ),
+        "lockfile": attrs.source(
+            default = "//tools:protoc.lock.json",
+            doc = "JSON lockfile with pinned archive checksums per version and platform",
+        ),
+        "sha256": attrs.dict(
+            attrs.string(),
+            attrs.string(),
+            default = {},
+            doc = "Platform to archive SHA256, overriding the lockfile (e.g. for mirrors)",
+        ),
+        "urls": attrs.dict(
+            attrs.string(),
+            attrs.string(),
+            default = {},
+            doc = "Platform to download URL, e.g. an air-gapped mirror",
+        ),
+        "_download_protoc_script": attrs.source(
+            default = "//tools:download_protoc.py",
+            doc = "Python script for downloading protoc binaries",
+        ),
+    },
+)
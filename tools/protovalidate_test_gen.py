@@ -0,0 +1,1171 @@
+#!/usr/bin/env python3
+"""
+Go test scaffolding for protovalidate constraints.
+
+Generates a Go test file that exercises the `(buf.validate.field)` rules of
+every message: a valid fixture must pass validation, and for each constrained
+field a copy of the fixture mutated to break one of its rules must fail with
+an error that mentions the field. All tests share one protovalidate validator.
+
+Fixtures are synthesized from the rules. Fields without rules are left unset,
+as are message and explicit-presence fields that are not required. A message
+whose rules cannot be satisfied by construction (regular expressions,
+message-typed rules on required fields, ...) gets a test that is skipped with
+the reason, so that the gap stays visible.
+
+Custom CEL constraints (`cel` of `(buf.validate.message)` and
+`(buf.validate.field)`) get a subtest named after the constraint ID, whose
+violation must mention that ID. Expressions that are conjunctions of simple
+conditions on fields, such as `this.age >= 18 && size(this.name) > 0` or
+`this.startsWith('usr_')`, are translated into standard rules: the fixture
+satisfies them and the subtest breaks the first condition. Any other
+expression gets a skipped subtest with a TODO naming the constraint ID, or
+skips the whole message when the fixture cannot be shown to satisfy it.
+
+With --validatejson the generator also writes a `validatejson` package that
+serializes a protovalidate.ValidationError as a JSON document, and the tests
+report every unexpected validation failure through it (see
+VALIDATEJSON_REPORT_DIR).
+
+Usage:
+    protovalidate_test_gen.py generate --go-package github.com/acme/user/v1 \\
+        --output user_validate_test.go --go-mod go.mod \\
+        --validatejson validatejson/validatejson.go user.proto
+    protovalidate_test_gen.py run staged-test-dir/
+"""
+
+import argparse
+import ast
+import json
+import os
+import re
+import shutil
+import stat
+import subprocess
+import sys
+import tempfile
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+try:
+    from go_helper_gen import go_field_names, go_package_name, go_type_name
+    from go_oneof_names import contract_names
+    from proto_schema import Enum, Message, ProtoFile, ProtoParseError, SchemaSet, find_option, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from go_helper_gen import go_field_names, go_package_name, go_type_name
+    from go_oneof_names import contract_names
+    from proto_schema import Enum, Message, ProtoFile, ProtoParseError, SchemaSet, find_option, load_schema_set
+
+
+DEFAULT_VALIDATOR_MODULE = "github.com/bufbuild/protovalidate-go"
+
+# Package of the JSON report helper, relative to the module of the test
+VALIDATEJSON_PACKAGE = "validatejson"
+
+# Environment variable naming the directory JSON reports are written to
+VALIDATEJSON_REPORT_DIR = "PROTOVALIDATE_REPORT_DIR"
+
+FIELD_RULES = "buf.validate.field"
+ONEOF_RULES = "buf.validate.oneof"
+MESSAGE_RULES = "buf.validate.message"
+
+GO_SCALAR_TYPES = {
+    "double": "float64", "float": "float32",
+    "int32": "int32", "sint32": "int32", "sfixed32": "int32",
+    "int64": "int64", "sint64": "int64", "sfixed64": "int64",
+    "uint32": "uint32", "fixed32": "uint32", "uint64": "uint64", "fixed64": "uint64",
+    "bool": "bool", "string": "string", "bytes": "[]byte",
+}
+UNSIGNED_KINDS = {"uint32", "fixed32", "uint64", "fixed64"}
+FLOAT_KINDS = {"float", "double"}
+
+# Values of `ignore` under which a zero value is not validated
+IGNORE_EMPTY = {"IGNORE_IF_UNPOPULATED", "IGNORE_IF_DEFAULT_VALUE", "IGNORE_IF_ZERO_VALUE"}
+
+# Valid and invalid examples of the string formats protovalidate checks
+STRING_FORMATS = {
+    "email": ("user@example.com", "not an email"),
+    "hostname": ("example.com", "-invalid-"),
+    "address": ("example.com", "-invalid-"),
+    "ip": ("192.0.2.1", "not an ip"),
+    "ipv4": ("192.0.2.1", "2001:db8::1"),
+    "ipv6": ("2001:db8::1", "192.0.2.1"),
+    "uri": ("https://example.com/path", "not a uri"),
+    "uuid": ("123e4567-e89b-12d3-a456-426614174000", "not-a-uuid"),
+}
+STRING_RULES = {
+    "const", "in", "not_in", "len", "min_len", "max_len", "len_bytes", "min_bytes", "max_bytes",
+    "prefix", "suffix", "contains", "not_contains",
+} | set(STRING_FORMATS)
+NUMERIC_RULES = {"const", "in", "not_in", "gt", "gte", "lt", "lte", "finite"}
+ENUM_RULES = {"const", "in", "not_in", "defined_only"}
+
+# Keys of custom CEL constraints in field and message rules
+CEL_KEYS = ("cel", "cel_expression")
+
+# The subset of CEL that is translated into standard rules: `&&` of
+# comparisons between a field (or its size) and a literal, has() and string
+# methods. `this` is the field of a field constraint or the message.
+CEL_LITERAL = r"'(?:[^'\\]|\\.)*'|\"(?:[^\"\\]|\\.)*\"|-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?u?|true|false"
+CEL_PATH = r"this(?:\.\w+)?"
+CEL_OPERAND = rf"size\(\s*{CEL_PATH}\s*\)|{CEL_PATH}(?:\.size\(\))?|{CEL_LITERAL}"
+CEL_COMPARISON = re.compile(rf"({CEL_OPERAND})\s*(<=|>=|==|!=|<|>)\s*({CEL_OPERAND})")
+CEL_METHOD = re.compile(rf"({CEL_PATH})\.(startsWith|endsWith|contains)\(\s*({CEL_LITERAL})\s*\)")
+CEL_HAS = re.compile(r"has\(\s*(this\.\w+)\s*\)")
+CEL_SIZE = re.compile(rf"size\(\s*({CEL_PATH})\s*\)|({CEL_PATH})\.size\(\)")
+CEL_METHOD_RULES = {"startsWith": "prefix", "endsWith": "suffix", "contains": "contains"}
+CEL_FLIPPED = {"<": ">", "<=": ">=", ">": "<", ">=": "<=", "==": "==", "!=": "!="}
+CEL_VALUE_RULES = {"<": "lt", "<=": "lte", ">": "gt", ">=": "gte", "==": "const"}
+
+# Bounds that are combined by keeping the tighter one when rules are merged
+LOWER_BOUNDS = {"gte", "min_len", "min_items", "min_pairs"}
+UPPER_BOUNDS = {"lte", "max_len", "max_items", "max_pairs"}
+
+# Go types of the well-known types a required field can be set to
+WELL_KNOWN_GO_TYPES = {
+    "google.protobuf.Timestamp": ("google.golang.org/protobuf/types/known/timestamppb", "Timestamp"),
+    "google.protobuf.Duration": ("google.golang.org/protobuf/types/known/durationpb", "Duration"),
+    "google.protobuf.Empty": ("google.golang.org/protobuf/types/known/emptypb", "Empty"),
+    "google.protobuf.FieldMask": ("google.golang.org/protobuf/types/known/fieldmaskpb", "FieldMask"),
+    "google.protobuf.Struct": ("google.golang.org/protobuf/types/known/structpb", "Struct"),
+    "google.protobuf.Any": ("google.golang.org/protobuf/types/known/anypb", "Any"),
+}
+for _wrapper in ["Double", "Float", "Int64", "UInt64", "Int32", "UInt32", "Bool", "String", "Bytes"]:
+    WELL_KNOWN_GO_TYPES[f"google.protobuf.{_wrapper}Value"] = ("google.golang.org/protobuf/types/known/wrapperspb", f"{_wrapper}Value")
+
+
+class Unsupported(Exception):
+    """Raised when no fixture can be synthesized for a rule."""
+
+
+class GenerateError(Exception):
+    """Raised when the inputs are invalid."""
+
+
+@dataclass
+class Mutation:
+    """A subtest that breaks one field of the valid fixture."""
+    name: str             # Subtest name and the field or CEL constraint ID the error must mention
+    statement: str = ""   # Go statement mutating msg; empty when no invalid value is known
+    skip_reason: str = ""
+
+
+@dataclass
+class CelConstraint:
+    """A custom CEL constraint of a message or one of its fields."""
+    id: str
+    expression: str
+    field: Optional[str] = None  # Field of a field constraint; None for a message constraint
+
+
+@dataclass
+class CelCondition:
+    """One condition of a CEL conjunction."""
+    field: str
+    measure: str    # "value", "size", "has", "prefix", "suffix" or "contains"
+    op: str = ""    # Comparison operator of "value" and "size"
+    value: Any = None
+
+
+@dataclass
+class MessageTest:
+    """Fixture and mutations of one message."""
+    message: Message
+    go_name: str
+    assignments: List[str] = field(default_factory=list)
+    mutations: List[Mutation] = field(default_factory=list)
+    skip_reason: str = ""
+
+
+def field_rules(options: Dict[str, Any]) -> Dict[str, Any]:
+    rules = find_option(options, FIELD_RULES)
+    return rules if isinstance(rules, dict) else {}
+
+
+def standard_rules(rules: Dict[str, Any]) -> Dict[str, Any]:
+    return {key: value for key, value in rules.items() if key not in CEL_KEYS}
+
+
+def cel_constraints(rules: Dict[str, Any], field_name: Optional[str] = None) -> List[CelConstraint]:
+    """Returns the CEL constraints of message rules, or of the rules of a field."""
+    constraints = []
+    for entry in as_list(rules.get("cel", [])):
+        if isinstance(entry, dict):
+            expression = str(entry.get("expression", ""))
+            constraints.append(CelConstraint(str(entry.get("id") or expression), expression, field_name))
+    # The ID of a cel_expression constraint is the expression itself
+    for expression in as_list(rules.get("cel_expression", [])):
+        constraints.append(CelConstraint(str(expression), str(expression), field_name))
+    return constraints
+
+
+def as_list(value: Any) -> List[Any]:
+    return value if isinstance(value, list) else [value]
+
+
+def go_string(value: str) -> str:
+    return json.dumps(value)
+
+
+def is_zero(value: Any) -> bool:
+    return value in (0, "", False) and not (isinstance(value, float) and value != 0)
+
+
+def _check_keys(kind: str, rules: Dict[str, Any], supported: set) -> None:
+    for key in rules:
+        if key not in supported:
+            raise Unsupported(f"{kind}.{key} rules are not supported")
+
+
+def numeric_values(kind: str, rules: Dict[str, Any], nonzero: bool) -> Tuple[Any, List[Any]]:
+    """Returns a value satisfying numeric rules and values that each break one of them."""
+    _check_keys(kind, rules, NUMERIC_RULES)
+    not_in = as_list(rules.get("not_in", []))
+    gt, gte, lt, lte = (rules.get(k) for k in ("gt", "gte", "lt", "lte"))
+    step = 1
+    if "const" in rules:
+        valid = rules["const"]
+    elif "in" in rules:
+        candidates = [v for v in as_list(rules["in"]) if v not in not_in and not (nonzero and v == 0)]
+        if not candidates:
+            raise Unsupported(f"{kind}.in leaves no valid value")
+        valid = candidates[0]
+    else:
+        lo = gte if gte is not None else (gt + step if gt is not None else None)
+        hi = lte if lte is not None else (lt - step if lt is not None else None)
+        lower = gt if gt is not None else gte
+        upper = lt if lt is not None else lte
+        exclusive = lower is not None and upper is not None and lower > upper
+        if kind in FLOAT_KINDS and lower is not None and upper is not None and not exclusive:
+            valid = (lower + upper) / 2  # Also fits narrow open ranges, e.g. gt: 0, lt: 1
+            hi = None
+        elif lo is not None:
+            # gt/gte above lt/lte is an exclusive range; lo is on its upper side
+            valid = lo
+        elif hi is not None:
+            valid = hi
+        else:
+            valid = step if nonzero else 0
+        while valid in not_in or (nonzero and valid == 0):
+            valid += step
+        if hi is not None and not exclusive and valid > hi:
+            raise Unsupported(f"{kind} range leaves no valid value")
+    if kind in UNSIGNED_KINDS and valid < 0:
+        raise Unsupported(f"{kind} rules leave no valid value")
+
+    invalid = []
+    if "const" in rules:
+        invalid.append(rules["const"] + step)
+    if "in" in rules:
+        invalid.append(max(as_list(rules["in"])) + step)
+    if gt is not None:
+        invalid.append(gt)
+    if gte is not None:
+        invalid.append(gte - step)
+    if lt is not None:
+        invalid.append(lt)
+    if lte is not None:
+        invalid.append(lte + step)
+    if not_in:
+        invalid.append(not_in[0])
+    if kind in UNSIGNED_KINDS:
+        invalid = [v for v in invalid if v >= 0]
+    return valid, invalid
+
+
+def string_values(kind: str, rules: Dict[str, Any], nonzero: bool) -> Tuple[str, List[str]]:
+    """Returns a value satisfying string or bytes rules and values that each break one of them."""
+    _check_keys(kind, rules, STRING_RULES if kind == "string" else STRING_RULES - set(STRING_FORMATS) | {"ip", "ipv4", "ipv6"})
+    not_in = as_list(rules.get("not_in", []))
+    formats = [name for name in STRING_FORMATS if rules.get(name) is True]
+    prefix, suffix, contains = rules.get("prefix", ""), rules.get("suffix", ""), rules.get("contains", "")
+    min_length = max(rules.get("min_len", 0), rules.get("min_bytes", 0), rules.get("len", 0), rules.get("len_bytes", 0))
+    max_lengths = [rules[k] for k in ("max_len", "max_bytes", "len", "len_bytes") if k in rules]
+
+    if "const" in rules:
+        valid = rules["const"]
+    elif "in" in rules:
+        candidates = [v for v in as_list(rules["in"]) if v not in not_in and not (nonzero and v == "")]
+        if not candidates:
+            raise Unsupported(f"{kind}.in leaves no valid value")
+        valid = candidates[0]
+    elif formats:
+        if len(formats) > 1 or prefix or suffix or contains:
+            raise Unsupported(f"{kind}.{formats[0]} combined with other rules is not supported")
+        valid = STRING_FORMATS[formats[0]][0]
+        if len(valid) < min_length:
+            raise Unsupported(f"{kind}.{formats[0]} combined with a minimum length is not supported")
+    else:
+        body = "" if contains in prefix + suffix else contains
+        padding = max(min_length - len(prefix + body + suffix), 1 if nonzero and not prefix + body + suffix else 0)
+        valid = prefix + body + "a" * padding + suffix
+    if any(len(valid) > limit for limit in max_lengths):
+        raise Unsupported(f"{kind} length rules leave no valid value")
+    if valid in not_in or ("not_contains" in rules and rules["not_contains"] in valid):
+        raise Unsupported(f"{kind}.not_in/not_contains rules leave no valid value")
+
+    def other_char(text: str) -> str:
+        return "%" if text.startswith("#") or text.endswith("#") else "#"
+
+    invalid = []
+    if "const" in rules:
+        invalid.append(rules["const"] + "x")
+    if "in" in rules:
+        invalid.append(max(as_list(rules["in"]), key=len) + "x")
+    for name in formats:
+        invalid.append(STRING_FORMATS[name][1])
+    for key in ("min_len", "min_bytes"):
+        if rules.get(key, 0) > 0:
+            invalid.append("a" * (rules[key] - 1))
+    for key in ("max_len", "max_bytes"):
+        if key in rules:
+            invalid.append(valid + "a" * (rules[key] - len(valid) + 1))
+    for key in ("len", "len_bytes"):
+        if key in rules:
+            invalid.append(valid + "a")
+    if prefix:
+        invalid.append(other_char(prefix))
+    if suffix:
+        invalid.append(other_char(suffix))
+    if contains:
+        invalid.append("" if len(contains) > 1 or contains != "#" else "%")
+    if "not_contains" in rules:
+        invalid.append(rules["not_contains"])
+    if not_in:
+        invalid.append(not_in[0])
+    return valid, invalid
+
+
+def enum_values(enum: Enum, rules: Dict[str, Any], nonzero: bool) -> Tuple[int, List[int]]:
+    """Returns a value satisfying enum rules and values that each break one of them."""
+    _check_keys("enum", rules, ENUM_RULES)
+    not_in = as_list(rules.get("not_in", []))
+    defined = [value.number for value in enum.values]
+    if "const" in rules:
+        valid = rules["const"]
+    else:
+        candidates = as_list(rules["in"]) if "in" in rules else defined
+        candidates = [v for v in candidates if v not in not_in and not (nonzero and v == 0)]
+        if not candidates:
+            raise Unsupported(f"enum rules leave no valid value of {enum.full_name}")
+        valid = candidates[0]
+
+    invalid = []
+    if "const" in rules:
+        invalid.append(rules["const"] + 1)
+    if "in" in rules:
+        invalid.append(max(as_list(rules["in"])) + 1)
+    if rules.get("defined_only") is True and defined:
+        invalid.append(max(defined) + 1)
+    if not_in:
+        invalid.append(not_in[0])
+    return valid, invalid
+
+
+def split_conjunction(expression: str) -> List[str]:
+    """Splits a CEL expression at the top-level `&&` operators."""
+    parts, depth, quote, start, i = [], 0, "", 0, 0
+    while i < len(expression):
+        char = expression[i]
+        if quote:
+            if char == "\\":
+                i += 1
+            elif char == quote:
+                quote = ""
+        elif char in "'\"":
+            quote = char
+        elif char == "(":
+            depth += 1
+        elif char == ")":
+            depth -= 1
+        elif depth == 0 and expression.startswith("&&", i):
+            parts.append(expression[start:i])
+            start = i + 2
+            i += 1
+        i += 1
+    parts.append(expression[start:])
+    return [part.strip() for part in parts]
+
+
+def cel_literal(text: str) -> Any:
+    if text in ("true", "false"):
+        return text == "true"
+    if text[0] in "'\"":
+        return ast.literal_eval(text)
+    number = text.rstrip("u")
+    return float(number) if any(c in number for c in ".eE") else int(number)
+
+
+def cel_field(path: str, this_field: Optional[str]) -> str:
+    """Returns the field a `this` path of a constraint refers to."""
+    if path == "this":
+        if this_field is None:
+            raise Unsupported("conditions on the whole message are not supported")
+        return this_field
+    if this_field is not None:
+        raise Unsupported(f"conditions on fields of {this_field} are not supported")
+    return path[len("this."):]
+
+
+def parse_cel(expression: str, this_field: Optional[str] = None) -> List[CelCondition]:
+    """
+    Parses a CEL expression of the supported subset into its conditions.
+
+    Args:
+        expression: The CEL expression
+        this_field: Field of a field constraint; None for a message constraint
+
+    Raises:
+        Unsupported: If the expression is outside the supported subset
+    """
+    conditions = []
+    for part in split_conjunction(expression):
+        if match := CEL_HAS.fullmatch(part):
+            conditions.append(CelCondition(cel_field(match.group(1), this_field), "has"))
+        elif match := CEL_METHOD.fullmatch(part):
+            field_name = cel_field(match.group(1), this_field)
+            conditions.append(CelCondition(field_name, CEL_METHOD_RULES[match.group(2)], value=cel_literal(match.group(3))))
+        elif match := CEL_COMPARISON.fullmatch(part):
+            left, op, right = match.groups()
+            if re.fullmatch(CEL_LITERAL, left):
+                left, op, right = right, CEL_FLIPPED[op], left
+            if re.fullmatch(CEL_LITERAL, left) or not re.fullmatch(CEL_LITERAL, right):
+                raise Unsupported(f"'{part}' does not compare a field with a literal")
+            if size := CEL_SIZE.fullmatch(left):
+                conditions.append(CelCondition(cel_field(size.group(1) or size.group(2), this_field), "size", op, cel_literal(right)))
+            else:
+                conditions.append(CelCondition(cel_field(left, this_field), "value", op, cel_literal(right)))
+        else:
+            raise Unsupported(f"'{part}' is not a supported CEL condition")
+    return conditions
+
+
+def size_rules(unit: str, op: str, value: int) -> Dict[str, int]:
+    """Returns min_/max_ rules (e.g. min_len) equivalent to a size comparison."""
+    if op == "!=" or not isinstance(value, int):
+        raise Unsupported(f"size {op} {value} is not supported")
+    bounds = {">=": {"min": value}, ">": {"min": value + 1}, "<=": {"max": value}, "<": {"max": value - 1},
+              "==": {"min": value, "max": value}}[op]
+    return {f"{bound}_{unit}": limit for bound, limit in bounds.items()}
+
+
+def merge_rules(base: Dict[str, Any], extra: Dict[str, Any]) -> Dict[str, Any]:
+    """Returns rules satisfied only by values satisfying both base and extra."""
+    merged = dict(base)
+    for key, value in extra.items():
+        if key not in merged:
+            merged[key] = value
+        elif isinstance(merged[key], dict) and isinstance(value, dict):
+            merged[key] = merge_rules(merged[key], value)
+        elif key == "not_in":
+            merged[key] = as_list(merged[key]) + as_list(value)
+        elif key in LOWER_BOUNDS:
+            merged[key] = max(merged[key], value)
+        elif key in UPPER_BOUNDS:
+            merged[key] = min(merged[key], value)
+        elif merged[key] != value:
+            raise Unsupported(f"{key} = {value!r} conflicts with {key} = {merged[key]!r}")
+    return merged
+
+
+class TestGenerator:
+    """Synthesizes fixtures and mutations for the messages of one Go package."""
+
+    def __init__(self, schema: SchemaSet, go_package: str, validator_module: str, json_report: bool = False):
+        self.schema = schema
+        self.go_package = go_package
+        self.validator_module = validator_module
+        self.json_report = json_report
+        self.package_files = {id(proto_file) for proto_file in schema.files}
+        self.wrappers = {member.full_name: member.wrapper for member in contract_names(schema)}
+        self.imports: Dict[str, str] = {}
+        self.tests: Dict[str, MessageTest] = {}
+        self.building: List[str] = []
+
+    # Types
+
+    def _package_of(self, full_name: str) -> Optional[ProtoFile]:
+        for proto_file in self.schema.files:
+            if any(t.full_name == full_name for t in list(proto_file.all_messages()) + list(proto_file.all_enums())):
+                return proto_file
+        return None
+
+    def go_type(self, type_name: str, scope: str) -> Tuple[str, str, Any]:
+        """Returns (Go type, kind, resolved type) where kind is a scalar type, "enum" or "message"."""
+        if type_name in GO_SCALAR_TYPES:
+            return GO_SCALAR_TYPES[type_name], type_name, None
+        full_name = self.schema.resolve_type_name(type_name, scope)
+        if full_name in WELL_KNOWN_GO_TYPES:
+            import_path, name = WELL_KNOWN_GO_TYPES[full_name]
+            alias = import_path.rsplit("/", 1)[-1]
+            self.imports[import_path] = alias
+            return f"*{alias}.{name}", "message", full_name
+        resolved = self.schema.resolve_type(type_name, scope)
+        proto_file = self._package_of(full_name) if full_name else None
+        if resolved is None or proto_file is None:
+            raise Unsupported(f"type {type_name} is not defined in this Go package")
+        go_name = "pb." + go_type_name(full_name, proto_file.package)
+        if isinstance(resolved, Enum):
+            return go_name, "enum", resolved
+        return "*" + go_name, "message", resolved
+
+    def message_value(self, resolved: Any) -> str:
+        """Returns a Go expression for a valid, non-nil message."""
+        if isinstance(resolved, str):
+            import_path, name = WELL_KNOWN_GO_TYPES[resolved]
+            return f"&{import_path.rsplit('/', 1)[-1]}.{name}{{}}"
+        test = self.message_test(resolved)
+        if test.skip_reason:
+            raise Unsupported(f"no valid {resolved.full_name}: {test.skip_reason}")
+        return f"valid{test.go_name}()"
+
+    def literal(self, go_type: str, kind: str, value: Any) -> str:
+        if kind == "string":
+            return go_string(value)
+        if kind == "bytes":
+            return f"[]byte({go_string(value)})"
+        if kind == "bool":
+            return "true" if value else "false"
+        if kind == "enum":
+            return f"{go_type}({value})"
+        if kind in FLOAT_KINDS:
+            return repr(float(value))
+        return str(int(value))
+
+    # Values
+
+    def scalar_values(self, kind: str, rules: Dict[str, Any], resolved: Any, nonzero: bool) -> Tuple[Any, List[Any]]:
+        kind_rules = rules.get("enum" if kind == "enum" else kind, {})
+        if not isinstance(kind_rules, dict):
+            raise Unsupported(f"{kind} rules must be a message")
+        if kind == "enum":
+            return enum_values(resolved, kind_rules, nonzero)
+        if kind in ("string", "bytes"):
+            return string_values(kind, kind_rules, nonzero)
+        if kind == "bool":
+            if "const" in kind_rules:
+                if nonzero and kind_rules["const"] is False:
+                    raise Unsupported("bool.const = false contradicts required")
+                return kind_rules["const"], [not kind_rules["const"]]
+            return nonzero, []
+        return numeric_values(kind, kind_rules, nonzero)
+
+    def element_values(self, type_name: str, scope: str, rules: Dict[str, Any]) -> Tuple[str, Optional[str], str]:
+        """Returns (valid, invalid or None, Go type) for a repeated item, map key or map value."""
+        if "cel" in rules:
+            raise Unsupported("CEL rules are not supported")
+        go_type, kind, resolved = self.go_type(type_name, scope)
+        if kind == "message":
+            if any(key not in ("required", "ignore") for key in rules):
+                raise Unsupported(f"{next(iter(rules))} rules on messages are not supported")
+            return self.message_value(resolved), None, go_type
+        valid, invalid = self.scalar_values(kind, rules, resolved, False)
+        invalid_literal = self.literal(go_type, kind, invalid[0]) if invalid else None
+        return self.literal(go_type, kind, valid), invalid_literal, go_type
+
+    def distinct_values(self, type_name: str, scope: str, rules: Dict[str, Any], count: int) -> List[str]:
+        """Returns count distinct valid values of an unconstrained scalar type."""
+        go_type, kind, _ = self.go_type(type_name, scope)
+        if rules or kind not in GO_SCALAR_TYPES or kind == "bool":
+            if count > 1:
+                raise Unsupported(f"{count} distinct {type_name} values with rules are not supported")
+        if kind in ("string", "bytes"):
+            return [self.literal(go_type, kind, f"k{i}") for i in range(count)]
+        return [self.literal(go_type, kind, i + 1) for i in range(count)]
+
+    # Fields
+
+    def field_case(self, proto_file: ProtoFile, message: Message, message_field, rules: Dict[str, Any]) -> Tuple[Optional[str], List[str]]:
+        """
+        Returns a field's value in the valid fixture (None to leave it unset)
+        and Go expressions that each make the field invalid.
+        """
+        required = rules.get("required") is True
+        avoid_zero = rules.get("ignore") in IGNORE_EMPTY or rules.get("ignore_empty") is True
+        scope = message.full_name
+
+        if message_field.is_map:
+            return self.map_case(message_field, rules.get("map", {}), scope, required, avoid_zero)
+        if message_field.label == "repeated":
+            return self.repeated_case(message_field, rules.get("repeated", {}), scope, required, avoid_zero)
+
+        go_type, kind, resolved = self.go_type(message_field.type_name, scope)
+        if kind == "message":
+            type_rules = [key for key in rules if key not in ("required", "ignore", "ignore_empty")]
+            if required:
+                if type_rules:
+                    raise Unsupported(f"{type_rules[0]} rules on required field {message_field.name} are not supported")
+                return self.message_value(resolved), ["nil"]
+            # Rules of unset message fields are not evaluated
+            return None, []
+
+        presence = self.has_presence(proto_file, message_field) and kind != "bytes"
+        valid, invalid = self.scalar_values(kind, rules, resolved, required and not presence)
+        zero = {"string": "", "bytes": "", "bool": False}.get(kind, 0)
+        if required and not presence:
+            invalid = [zero] + invalid
+        if avoid_zero:
+            invalid = [value for value in invalid if not is_zero(value)]
+
+        def wrap(value: Any) -> str:
+            literal = self.literal(go_type, kind, value)
+            return f"ptr[{go_type}]({literal})" if presence else literal
+
+        invalid_exprs = [wrap(value) for value in invalid]
+        if presence:
+            if required:
+                return wrap(valid), ["nil"]
+            return None, invalid_exprs
+        return (None if is_zero(valid) else wrap(valid)), invalid_exprs
+
+    def repeated_case(self, message_field, rules: Dict[str, Any], scope: str, required: bool, avoid_zero: bool) -> Tuple[Optional[str], List[str]]:
+        _check_keys("repeated", rules, {"min_items", "max_items", "unique", "items"})
+        valid_item, invalid_item, item_type = self.element_values(message_field.type_name, scope, rules.get("items", {}))
+        min_items = max(rules.get("min_items", 0), 1 if required else 0)
+
+        def items(values: List[str]) -> str:
+            return f"[]{item_type}{{{', '.join(values)}}}"
+
+        if rules.get("unique") is True and min_items > 1:
+            valid_items = self.distinct_values(message_field.type_name, scope, rules.get("items", {}), min_items)
+        else:
+            valid_items = [valid_item] * min_items
+        invalid = []
+        if min_items and not avoid_zero:
+            invalid.append("nil")
+        if "max_items" in rules:
+            invalid.append(items([valid_item] * (rules["max_items"] + 1)))
+        if invalid_item:
+            invalid.append(items([invalid_item] + [valid_item] * (max(min_items, 1) - 1)))
+        if rules.get("unique") is True and rules.get("max_items", 2) >= 2:
+            invalid.append(items([valid_item, valid_item]))
+        return (items(valid_items) if valid_items else None), invalid
+
+    def map_case(self, message_field, rules: Dict[str, Any], scope: str, required: bool, avoid_zero: bool) -> Tuple[Optional[str], List[str]]:
+        _check_keys("map", rules, {"min_pairs", "max_pairs", "keys", "values"})
+        key_rules, value_rules = rules.get("keys", {}), rules.get("values", {})
+        valid_key, invalid_key, key_type = self.element_values(message_field.map_key_type, scope, key_rules)
+        valid_value, invalid_value, value_type = self.element_values(message_field.map_value_type, scope, value_rules)
+        min_pairs = max(rules.get("min_pairs", 0), 1 if required else 0)
+
+        def pairs(entries: List[Tuple[str, str]]) -> str:
+            return f"map[{key_type}]{value_type}{{{', '.join(f'{k}: {v}' for k, v in entries)}}}"
+
+        keys = self.distinct_values(message_field.map_key_type, scope, key_rules, min_pairs) if min_pairs > 1 else [valid_key] * min_pairs
+        invalid = []
+        if min_pairs and not avoid_zero:
+            invalid.append("nil")
+        if "max_pairs" in rules:
+            try:
+                extra = self.distinct_values(message_field.map_key_type, scope, key_rules, rules["max_pairs"] + 1)
+                invalid.append(pairs([(key, valid_value) for key in extra]))
+            except Unsupported:
+                pass
+        if invalid_key:
+            invalid.append(pairs([(invalid_key, valid_value)]))
+        if invalid_value:
+            invalid.append(pairs([(valid_key, invalid_value)]))
+        return (pairs([(key, valid_value) for key in keys]) if keys else None), invalid
+
+    def may_be_unset(self, proto_file: ProtoFile, message: Message, message_field, rules: Dict[str, Any]) -> bool:
+        """Returns whether the rules of an unset field are not evaluated."""
+        if rules.get("required") is True:
+            return False
+        if rules.get("ignore") in IGNORE_EMPTY or rules.get("ignore_empty") is True:
+            return True
+        if message_field.is_map or message_field.label == "repeated":
+            return False
+        try:
+            _, kind, _ = self.go_type(message_field.type_name, message.full_name)
+        except Unsupported:
+            return False
+        return kind == "message" or (kind != "bytes" and self.has_presence(proto_file, message_field))
+
+    @staticmethod
+    def has_presence(proto_file: ProtoFile, message_field) -> bool:
+        """Returns whether protoc-gen-go generates a pointer for a singular scalar field."""
+        if proto_file.syntax == "proto2":
+            return True
+        if proto_file.syntax == "proto3":
+            return message_field.label == "optional"
+        presence = find_option(message_field.options, "features.field_presence")
+        presence = presence or find_option(proto_file.options, "features.field_presence")
+        return presence != "IMPLICIT"
+
+    # CEL constraints
+
+    def cel_rules(self, condition: CelCondition, message_field, scope: str) -> Dict[str, Any]:
+        """Returns the standard rules equivalent to one condition of a CEL expression."""
+        if condition.measure == "has":
+            return {"required": True}
+        if message_field.is_map or message_field.label == "repeated":
+            if condition.measure != "size":
+                raise Unsupported(f"conditions on the elements of {message_field.name} are not supported")
+            if message_field.is_map:
+                return {"map": size_rules("pairs", condition.op, condition.value)}
+            return {"repeated": size_rules("items", condition.op, condition.value)}
+        _, kind, _ = self.go_type(message_field.type_name, scope)
+        if kind == "message":
+            raise Unsupported(f"conditions on message field {message_field.name} are not supported")
+        if condition.measure == "size" or condition.measure in CEL_METHOD_RULES.values():
+            if kind not in ("string", "bytes"):
+                raise Unsupported(f"{condition.measure} of {kind} field {message_field.name} is not supported")
+            if condition.measure == "size":
+                return {kind: size_rules("len", condition.op, condition.value)}
+            return {kind: {condition.measure: condition.value}}
+        if condition.op == "!=":
+            return {kind: {"not_in": [condition.value]}}
+        if condition.op != "==" and (kind in ("string", "bytes", "bool", "enum") or isinstance(condition.value, str)):
+            raise Unsupported(f"{condition.op} on {kind} field {message_field.name} is not supported")
+        if condition.op in ("<", ">") and kind not in FLOAT_KINDS:
+            # Integer bounds are inclusive so that they combine with other rules
+            if condition.op == ">":
+                return {kind: {"gte": condition.value + 1}}
+            return {kind: {"lte": condition.value - 1}}
+        return {kind: {CEL_VALUE_RULES[condition.op]: condition.value}}
+
+    def cel_cases(self, proto_file: ProtoFile, message: Message, constraints: List[CelConstraint],
+                  field_go_names: Dict[str, str]) -> Tuple[Dict[str, Dict[str, Any]], List[Mutation]]:
+        """
+        Returns the rules the CEL constraints of a message add to the valid
+        value of each field, and one mutation per constraint that breaks it.
+        """
+        fields = {message_field.name: message_field for message_field in message.fields}
+        extra: Dict[str, Dict[str, Any]] = {}
+        mutations = []
+        for constraint in constraints:
+            try:
+                conditions = []
+                for condition in parse_cel(constraint.expression, constraint.field):
+                    message_field = fields.get(condition.field)
+                    if message_field is None:
+                        raise Unsupported(f"{condition.field} is not a field of {message.full_name}")
+                    if message_field.oneof:
+                        raise Unsupported(f"conditions on oneof member {condition.field} are not supported")
+                    conditions.append((message_field, self.cel_rules(condition, message_field, message.full_name)))
+                merged = dict(extra)
+                for message_field, rules in conditions:
+                    if constraint.field is None and not message_field.is_map and message_field.label != "repeated":
+                        # Message constraints also read unset fields, so the fixture sets them
+                        rules = {**rules, "required": True}
+                    merged[message_field.name] = merge_rules(merged.get(message_field.name, {}), rules)
+                for name in {message_field.name for message_field, _ in conditions}:
+                    rules = standard_rules(field_rules(fields[name].options))
+                    self.field_case(proto_file, message, fields[name], merge_rules(rules, merged[name]))
+                message_field, rules = conditions[0]
+                if constraint.field is not None:
+                    # Ignored zero values are not validated, so they cannot break the constraint
+                    own = field_rules(message_field.options)
+                    rules = {**rules, **{key: own[key] for key in ("ignore", "ignore_empty") if key in own}}
+                _, invalid = self.field_case(proto_file, message, message_field, rules)
+            except Unsupported as e:
+                reason = f"TODO: test CEL constraint {constraint.id} by hand: {e}"
+                # The fixture may break a constraint of a field it leaves unset only
+                if constraint.field is None or not self.may_be_unset(
+                        proto_file, message, fields[constraint.field], field_rules(fields[constraint.field].options)):
+                    raise Unsupported(reason)
+                mutations.append(Mutation(constraint.id, skip_reason=reason))
+                continue
+            extra = merged
+            statement = f"msg.{field_go_names[message_field.name]} = {invalid[0]}" if invalid else ""
+            mutations.append(Mutation(constraint.id, statement))
+        return extra, mutations
+
+    # Messages
+
+    def message_test(self, message: Message) -> MessageTest:
+        """Returns the fixture and mutations of a message, computing them once."""
+        if message.full_name in self.tests:
+            return self.tests[message.full_name]
+        proto_file = self._package_of(message.full_name)
+        test = MessageTest(message=message, go_name=go_type_name(message.full_name, proto_file.package))
+        if message.full_name in self.building:
+            raise Unsupported(f"required fields of {message.full_name} are recursive")
+        self.building.append(message.full_name)
+        try:
+            self._build(proto_file, message, test)
+        except Unsupported as e:
+            test.assignments, test.mutations, test.skip_reason = [], [], str(e)
+        finally:
+            self.building.pop()
+        self.tests[message.full_name] = test
+        return test
+
+    def _build(self, proto_file: ProtoFile, message: Message, test: MessageTest) -> None:
+        message_rules = find_option(message.options, MESSAGE_RULES)
+        constraints = []
+        if isinstance(message_rules, dict):
+            if message_rules.get("disabled") is True:
+                return
+            unsupported = sorted(key for key in message_rules if key != "cel")
+            if unsupported:
+                raise Unsupported(f"message rules ({', '.join(unsupported)}) are not supported")
+            constraints = cel_constraints(message_rules)
+        for message_field in message.fields:
+            rules = field_rules(message_field.options)
+            if rules.get("ignore") != "IGNORE_ALWAYS":
+                constraints += cel_constraints(rules, message_field.name)
+
+        field_go_names, oneof_go_names = go_field_names(message)
+        cel_rules, cel_mutations = self.cel_cases(proto_file, message, constraints, field_go_names)
+        chosen_members = {}
+        for oneof in message.oneofs:
+            oneof_rules = find_option(oneof.options, ONEOF_RULES)
+            if isinstance(oneof_rules, dict) and oneof_rules.get("required") is True:
+                chosen_members[oneof.name] = oneof.fields[0]
+                test.mutations.append(Mutation(oneof.name, f"msg.{oneof_go_names[oneof.name]} = nil"))
+
+        for message_field in message.fields:
+            rules = standard_rules(field_rules(message_field.options))
+            if rules.get("ignore") == "IGNORE_ALWAYS":
+                continue
+            go_field = field_go_names[message_field.name]
+            if message_field.oneof:
+                target = oneof_go_names[message_field.oneof]
+                wrapper = "pb." + self.wrappers[message_field.full_name]
+                chosen = chosen_members.get(message_field.oneof) == message_field.name
+                if not rules and not chosen:
+                    continue
+                valid, invalid, _ = self.element_values(message_field.type_name, message.full_name, rules)
+                if chosen:
+                    test.assignments.append(f"msg.{target} = &{wrapper}{{{go_field}: {valid}}}")
+                if rules:
+                    statement = f"msg.{target} = &{wrapper}{{{go_field}: {invalid}}}" if invalid else ""
+                    test.mutations.append(Mutation(message_field.name, statement))
+                continue
+            extra = cel_rules.get(message_field.name, {})
+            if not rules and not extra:
+                continue
+            try:
+                valid, invalid = self.field_case(proto_file, message, message_field, merge_rules(rules, extra))
+                if extra and rules:
+                    _, invalid = self.field_case(proto_file, message, message_field, rules)
+            except Unsupported as e:
+                # A field that may stay unset does not invalidate the fixture
+                if not self.may_be_unset(proto_file, message, message_field, rules):
+                    raise
+                test.mutations.append(Mutation(message_field.name, skip_reason=str(e)))
+                continue
+            if valid is not None:
+                test.assignments.append(f"msg.{go_field} = {valid}")
+            if rules:
+                statement = f"msg.{go_field} = {invalid[0]}" if invalid else ""
+                test.mutations.append(Mutation(message_field.name, statement))
+        test.mutations.extend(cel_mutations)
+
+    # Rendering
+
+    def render(self, package: str, sources: List[str]) -> str:
+        tests = [self.message_test(m) for f in self.schema.files for m in f.all_messages() if not m.is_map_entry]
+
+        std_imports = ["strings", "testing"]
+        imports = {
+            "google.golang.org/protobuf/proto": "",
+            self.validator_module: "protovalidate",
+            self.go_package: "pb",
+        }
+        imports.update(self.imports)
+        if self.json_report:
+            std_imports = ["os", "path/filepath"] + std_imports
+            imports[f"{self.go_package}/{VALIDATEJSON_PACKAGE}"] = ""
+        lines = [
+            "// Code generated by buck2-protobuf protovalidate_test_gen. DO NOT EDIT.",
+            f"// source: {', '.join(sources)}",
+            "",
+            f"package {package}_test",
+            "",
+            "import (",
+        ]
+        lines += [f"\t{go_string(path)}" for path in std_imports]
+        lines.append("")
+        for path in sorted(imports):
+            alias = imports[path]
+            default_alias = path.rstrip("/").rsplit("/", 1)[-1]
+            lines.append(f"\t{alias} {go_string(path)}" if alias and alias != default_alias else f"\t{go_string(path)}")
+        lines.append(")")
+        report = "\n\t\treportViolations(t, msg, err)" if self.json_report else ""
+        lines.append(f"""
+// validator is shared by all tests; it caches the compiled rules of every
+// message type it has seen.
+var validator, validatorErr = protovalidate.New()
+
+func ptr[T any](v T) *T {{ return &v }}
+
+func requireValid(t *testing.T, msg proto.Message) {{
+\tt.Helper()
+\tif validatorErr != nil {{
+\t\tt.Fatalf("protovalidate.New: %v", validatorErr)
+\t}}
+\tif err := validator.Validate(msg); err != nil {{{report}
+\t\tt.Fatalf("valid fixture failed validation: %v", err)
+\t}}
+}}
+
+func requireViolation(t *testing.T, msg proto.Message, field string) {{
+\tt.Helper()
+\tif validatorErr != nil {{
+\t\tt.Fatalf("protovalidate.New: %v", validatorErr)
+\t}}
+\terr := validator.Validate(msg)
+\tif err == nil {{
+\t\tt.Fatalf("expected a violation of %s, got none", field)
+\t}}
+\tif !strings.Contains(err.Error(), field) {{{report}
+\t\tt.Fatalf("violation does not mention %s: %v", field, err)
+\t}}
+}}""".rstrip())
+        if self.json_report:
+            lines.append(f"""
+// reportViolations logs the JSON report of err and, when ${VALIDATEJSON_REPORT_DIR}
+// is set, writes it to <test name>.json in that directory.
+func reportViolations(t *testing.T, msg proto.Message, err error) {{
+\tt.Helper()
+\treport, reportErr := validatejson.Marshal(msg, err)
+\tif reportErr != nil {{
+\t\tt.Logf("no JSON report: %v", reportErr)
+\t\treturn
+\t}}
+\tt.Logf("validation report:\\n%s", report)
+\tif dir := os.Getenv({go_string(VALIDATEJSON_REPORT_DIR)}); dir != "" {{
+\t\tname := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()) + ".json"
+\t\tif writeErr := os.WriteFile(filepath.Join(dir, name), report, 0o644); writeErr != nil {{
+\t\t\tt.Errorf("writing validation report: %v", writeErr)
+\t\t}}
+\t}}
+}}""".rstrip())
+
+        for test in tests:
+            lines.append("")
+            lines.extend(self._render_message(test))
+        return "\n".join(lines) + "\n"
+
+    def _render_message(self, test: MessageTest) -> List[str]:
+        full_name, go_name = test.message.full_name, test.go_name
+        lines = []
+        if not test.skip_reason:
+            lines.append(f"// valid{go_name} returns a fixture satisfying the rules of {full_name}.")
+            lines.append(f"func valid{go_name}() *pb.{go_name} {{")
+            if test.assignments:
+                lines.append(f"\tmsg := &pb.{go_name}{{}}")
+                lines.extend(f"\t{assignment}" for assignment in test.assignments)
+                lines.append("\treturn msg")
+            else:
+                lines.append(f"\treturn &pb.{go_name}{{}}")
+            lines.append("}")
+            if not test.mutations:
+                return lines
+            lines.append("")
+        elif not find_option(test.message.options, MESSAGE_RULES) and not any(field_rules(f.options) for f in test.message.fields):
+            return lines
+
+        lines.append(f"func Test{go_name}(t *testing.T) {{")
+        if test.skip_reason:
+            lines.append(f"\tt.Skip({go_string(f'cannot generate a valid {full_name}: {test.skip_reason}')})")
+            lines.append("}")
+            return lines
+        lines.append('\tt.Run("valid", func(t *testing.T) {')
+        lines.append(f"\t\trequireValid(t, valid{go_name}())")
+        lines.append("\t})")
+        for mutation in test.mutations:
+            lines.append(f"\tt.Run({go_string(mutation.name)}, func(t *testing.T) {{")
+            if mutation.skip_reason:
+                lines.append(f"\t\tt.Skip({go_string(mutation.skip_reason)})")
+            elif mutation.statement:
+                lines.append(f"\t\tmsg := valid{go_name}()")
+                lines.append(f"\t\t{mutation.statement}")
+                lines.append(f"\t\trequireViolation(t, msg, {go_string(mutation.name)})")
+            else:
+                lines.append(f"\t\tt.Skip({go_string(f'no invalid value is generated for the rules of {mutation.name}')})")
+            lines.append("\t})")
+        lines.append("}")
+        return lines
+
+
+def generate(files: List[str], go_package: str, validator_module: str = DEFAULT_VALIDATOR_MODULE,
+             dep_files: Optional[List[str]] = None, json_report: bool = False) -> str:
+    """Returns the Go test file for the messages of the given proto files."""
+    if not files:
+        raise GenerateError("no proto files given")
+    schema = load_schema_set(files, dep_files)
+    import_path = go_package.split(";", 1)[0]
+    if not import_path:
+        raise GenerateError("a Go import path is required")
+    package = go_package_name(go_package, schema.files[0])
+    generator = TestGenerator(schema, import_path, validator_module, json_report)
+    return generator.render(package, [f.path for f in schema.files])
+
+
+def render_validatejson(validator_module: str = DEFAULT_VALIDATOR_MODULE) -> str:
+    """
+    Renders the validatejson package.
+
+    Reports list the violations in the order protovalidate returned them and
+    encode with a fixed key order, so the same failure always produces the
+    same bytes and a report decodes back to an equal Report.
+    """
+    return f"""// Code generated by buck2-protobuf protovalidate_test_gen. DO NOT EDIT.
+
+// Package validatejson serializes protovalidate validation errors as JSON, so
+// that tools such as CI bots can parse validation failures.
+package validatejson
+
+import (
+\t"bytes"
+\t"encoding/json"
+\t"errors"
+\t"fmt"
+
+\tprotovalidate {go_string(validator_module)}
+\t"google.golang.org/protobuf/proto"
+)
+
+// Report lists the violations of one validated message.
+type Report struct {{
+\t// Message is the fully-qualified type of the validated message.
+\tMessage string `json:"message"`
+\t// Violations are in the order protovalidate reported them.
+\tViolations []Violation `json:"violations"`
+}}
+
+// Violation is one failed constraint.
+type Violation struct {{
+\t// FieldPath is the path of the violating field, such as "tags[0]"; it is
+\t// empty for message-level constraints.
+\tFieldPath string `json:"field_path"`
+\t// ConstraintID identifies the failed rule, such as "string.min_len".
+\tConstraintID string `json:"constraint_id"`
+\t// Message is the human-readable description of the violation.
+\tMessage string `json:"message"`
+\t// ForKey is set when the violation concerns a map key rather than its value.
+\tForKey bool `json:"for_key,omitempty"`
+}}
+
+// NewReport returns the report of err, which must be a (possibly wrapped)
+// *protovalidate.ValidationError returned when validating msg.
+func NewReport(msg proto.Message, err error) (*Report, error) {{
+\tvar validationErr *protovalidate.ValidationError
+\tif !errors.As(err, &validationErr) {{
+\t\treturn nil, fmt.Errorf("validatejson: not a validation error: %w", err)
+\t}}
+\treport := &Report{{
+\t\tMessage:    string(msg.ProtoReflect().Descriptor().FullName()),
+\t\tViolations: make([]Violation, 0, len(validationErr.Violations)),
+\t}}
+\tfor _, violation := range validationErr.Violations {{
+\t\treport.Violations = append(report.Violations, Violation{{
+\t\t\tFieldPath:    protovalidate.FieldPathString(violation.Proto.GetField()),
+\t\t\tConstraintID: violation.Proto.GetConstraintId(),
+\t\t\tMessage:      violation.Proto.GetMessage(),
+\t\t\tForKey:       violation.Proto.GetForKey(),
+\t\t}})
+\t}}
+\treturn report, nil
+}}
+
+// Marshal returns the JSON report of err. The output is indented, uses a
+// fixed key order and ends in a newline, so it can be compared with golden
+// files.
+func Marshal(msg proto.Message, err error) ([]byte, error) {{
+\treport, err := NewReport(msg, err)
+\tif err != nil {{
+\t\treturn nil, err
+\t}}
+\tvar buf bytes.Buffer
+\tencoder := json.NewEncoder(&buf)
+\tencoder.SetEscapeHTML(false)
+\tencoder.SetIndent("", "  ")
+\tif err := encoder.Encode(report); err != nil {{
+\t\treturn nil, fmt.Errorf("validatejson: %w", err)
+\t}}
+\treturn buf.Bytes(), nil
+}}
+
+// Unmarshal parses a report written by Marshal.
+func Unmarshal(data []byte) (*Report, error) {{
+\tvar report Report
+\tdecoder := json.NewDecoder(bytes.NewReader(data))
+\tdecoder.DisallowUnknownFields()
+\tif err := decoder.Decode(&report); err != nil {{
+\t\treturn nil, fmt.Errorf("validatejson: %w", err)
+\t}}
+\treturn &report, nil
+}}
+"""
+
+
+def render_go_mod(go_package: str, requires: List[str]) -> str:
+    """Renders the go.mod of the staged test package."""
+    lines = [f"module {go_package.split(';', 1)[0]}", "", "go 1.21"]
+    if requires:
+        lines += ["", "require ("]
+        for require in sorted(requires):
+            module, _, version = require.partition("@")
+            if not module or not version:
+                raise GenerateError(f"requirement must be MODULE@VERSION, got '{require}'")
+            lines.append(f"\t{module} {version}")
+        lines.append(")")
+    return "\n".join(lines) + "\n"
+
+
+def run_tests(source_dir: str, go: str = "go") -> int:
+    """Runs go test over a writable copy of a staged test package."""
+    with tempfile.TemporaryDirectory() as work_dir:
+        package_dir = os.path.join(work_dir, "src")
+        shutil.copytree(source_dir, package_dir)
+        for root, _, files in os.walk(package_dir):
+            for name in files:
+                path = os.path.join(root, name)
+                os.chmod(path, os.stat(path).st_mode | stat.S_IWUSR)
+        for command in ([go, "mod", "tidy"], [go, "test", "-count=1", "."]):
+            result = subprocess.run(command, cwd=package_dir)
+            if result.returncode != 0:
+                return result.returncode
+    return 0
+
+
+def main():
+    """Main entry point for protovalidate test generation."""
+    parser = argparse.ArgumentParser(description="Generate and run protovalidate Go tests")
+    subparsers = parser.add_subparsers(dest="command", required=True)
+
+    generate_parser = subparsers.add_parser("generate", help="Generate the Go test file")
+    generate_parser.add_argument("--go-package", required=True, help="Go import path of the generated messages")
+    generate_parser.add_argument("--validator-module", default=DEFAULT_VALIDATOR_MODULE, help="protovalidate Go module")
+    generate_parser.add_argument("--output", required=True, help="Go test file to write")
+    generate_parser.add_argument("--go-mod", help="go.mod to write for the test package")
+    generate_parser.add_argument("--require", action="append", default=[], metavar="MODULE@VERSION",
+                                 help="Module requirement pinned in the go.mod")
+    generate_parser.add_argument("--validatejson", help="Write the validatejson package to this file and report "
+                                 "validation failures of the tests as JSON")
+    generate_parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    generate_parser.add_argument("files", nargs="+", help="Proto files")
+
+    run_parser = subparsers.add_parser("run", help="Run go test over a staged test package")
+    run_parser.add_argument("dir", help="Directory with the generated Go files, the test and go.mod")
+    run_parser.add_argument("--go", default=os.environ.get("GO", "go"), help="Go binary")
+    args = parser.parse_args()
+
+    if args.command == "run":
+        sys.exit(run_tests(args.dir, args.go))
+
+    try:
+        content = generate(args.files, args.go_package, args.validator_module, args.dep,
+                           json_report=bool(args.validatejson))
+        go_mod = render_go_mod(args.go_package, args.require) if args.go_mod else None
+    except (GenerateError, ProtoParseError, OSError) as e:
+        print(f"ERROR: protovalidate_test_gen: {e}", file=sys.stderr)
+        sys.exit(2)
+    with open(args.output, "w", encoding="utf-8") as f:
+        f.write(content)
+    if go_mod is not None:
+        with open(args.go_mod, "w", encoding="utf-8") as f:
+            f.write(go_mod)
+    if args.validatejson:
+        with open(args.validatejson, "w", encoding="utf-8") as f:
+            f.write(render_validatejson(args.validator_module))
+
+
+if __name__ == "__main__":
+    main()
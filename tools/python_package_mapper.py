@@ -0,0 +1,429 @@
+#!/usr/bin/env python3
+"""
+Python package mapping for generated protobuf code.
+
+protoc places generated Python modules according to the .proto file path and
+writes absolute imports that mirror that layout (`from acme.user.v1 import
+user_pb2`). When the source tree uses a different package structure those
+imports break. This tool relocates generated modules according to a mapping
+from proto directories to Python packages, rewrites imports in `_pb2.py`,
+`_pb2_grpc.py` and `.pyi` files to match, and verifies that the new layout
+does not introduce import cycles.
+
+Package cycles (packages importing each other) introduced by the mapping are
+rejected by default. With the "deferred" cycle strategy every package cycle is
+allowed instead, and each package
+on a cycle gets an `__init__.py` that imports its generated modules lazily on
+first attribute access (PEP 562), so importing a package never runs generated
+code and the cycle cannot fail at import time.
+
+Usage:
+    python_package_mapper.py --input-dir gen --output-dir out \\
+        --map acme/user=myapp.protos.user --relative-imports \\
+        --cycle-strategy deferred
+"""
+
+import argparse
+import os
+import re
+import shutil
+import sys
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Optional, Set, Tuple
+
+
+GENERATED_SUFFIXES = (".py", ".pyi")
+
+# Module name suffixes of the plugins, longest first (user.proto -> user_pb2, user_pb2_grpc)
+PROTO_MODULE_SUFFIXES = ("_pb2_grpc", "_pb2")
+
+# How package import cycles are handled: rejected, or broken with lazy package imports
+CYCLE_STRATEGIES = ["fail", "deferred"]
+
+DEFAULT_INIT = '"""Generated Python protobuf package."""\n'
+
+PY_TYPED = "# PEP 561 stub package marker\n"
+
+# pkgutil-style namespace package: portions of the package generated by other
+# libraries (e.g. acme.user and acme.billing) are merged into one package
+EXTEND_PATH_INIT = DEFAULT_INIT + '\n__path__ = __import__("pkgutil").extend_path(__path__, __name__)\n'
+
+DEFERRED_INIT = '''"""Generated Python protobuf package.
+
+Generated modules are imported on first access, so importing this package
+never runs generated code and package import cycles cannot fail.
+"""
+
+import importlib
+
+__all__ = [{modules}]
+
+
+def __getattr__(name):
+    if name in __all__:
+        return importlib.import_module(f"{{__name__}}.{{name}}")
+    raise AttributeError(f"module {{__name__!r}} has no attribute {{name!r}}")
+'''
+
+_FROM_IMPORT = re.compile(r"^(\s*)from\s+([\w.]+)\s+import\s+(\w+)(\s+as\s+\w+)?\s*$")
+_PLAIN_IMPORT = re.compile(r"^(\s*)import\s+([\w.]+)(\s+as\s+\w+)?\s*$")
+
+
+class PackageMappingError(Exception):
+    """Raised when a mapping is invalid or produces a broken layout."""
+
+
+@dataclass
+class MappingResult:
+    """Outcome of mapping a generated tree."""
+    modules: Dict[str, str] = field(default_factory=dict)  # original module -> mapped module
+    imports: Dict[str, Set[str]] = field(default_factory=dict)  # mapped module -> mapped generated imports
+    packages: Set[str] = field(default_factory=set)
+    cycles: List[List[str]] = field(default_factory=list)  # package cycles broken by the cycle strategy
+
+
+def parse_mapping(entries: List[str]) -> Dict[str, str]:
+    """Parses PROTO_DIR=PYTHON_PACKAGE entries into a dictionary."""
+    mapping = {}
+    for entry in entries:
+        proto_dir, sep, package = entry.partition("=")
+        if not sep:
+            raise PackageMappingError(f"invalid mapping '{entry}', expected PROTO_DIR=PYTHON_PACKAGE")
+        proto_dir = proto_dir.strip("/")
+        if package and not re.fullmatch(r"[A-Za-z_]\w*(\.[A-Za-z_]\w*)*", package):
+            raise PackageMappingError(f"invalid Python package '{package}' for '{proto_dir}'")
+        mapping[proto_dir] = package
+    return mapping
+
+
+def map_module(module: str, mapping: Dict[str, str]) -> str:
+    """
+    Maps a dotted module name using the longest matching proto directory prefix.
+
+    A key naming the module's .proto file (e.g. "acme/user/v1/user.proto")
+    takes precedence over directories, so proto files sharing a directory can
+    be mapped to different packages.
+
+    Args:
+        module: Module name as generated by protoc (e.g. "acme.user.v1.user_pb2")
+        mapping: Proto directory (or file) to Python package mapping
+
+    Returns:
+        The mapped module name (unchanged if no prefix matches)
+    """
+    parts = module.split(".")
+    for suffix in PROTO_MODULE_SUFFIXES:
+        if parts[-1].endswith(suffix):
+            proto_file = "/".join(parts[:-1] + [parts[-1][:-len(suffix)] + ".proto"])
+            if proto_file in mapping:
+                prefix = mapping[proto_file].split(".") if mapping[proto_file] else []
+                return ".".join(prefix + parts[-1:])
+            break
+    for length in range(len(parts) - 1, -1, -1):
+        key = "/".join(parts[:length])
+        if key in mapping:
+            prefix = mapping[key].split(".") if mapping[key] else []
+            return ".".join(prefix + parts[length:])
+    return module
+
+
+def relative_import(from_module: str, target_package: str) -> str:
+    """Returns the relative package reference (e.g. "..common") from a module to a package."""
+    current = from_module.split(".")[:-1]
+    target = target_package.split(".") if target_package else []
+    common = 0
+    while common < len(current) and common < len(target) and current[common] == target[common]:
+        common += 1
+    dots = "." * (len(current) - common + 1)
+    return dots + ".".join(target[common:])
+
+
+def rewrite_imports(
+    content: str,
+    module: str,
+    mapping: Dict[str, str],
+    generated: Set[str],
+    relative: bool,
+) -> Tuple[str, Set[str]]:
+    """
+    Rewrites import statements of one generated file.
+
+    Args:
+        content: File content
+        module: Mapped module name of the file
+        mapping: Proto directory to Python package mapping
+        generated: Original module names produced in this tree
+        relative: Use relative imports for modules within the tree
+
+    Returns:
+        Tuple of (new content, mapped generated modules imported by the file)
+    """
+    imported = set()
+    renames: Dict[str, str] = {}
+    lines = []
+    for line in content.split("\n"):
+        match = _FROM_IMPORT.match(line)
+        plain = None if match else _PLAIN_IMPORT.match(line)
+        if match:
+            indent, package, name, alias = match.group(1), match.group(2), match.group(3), match.group(4) or ""
+            original = f"{package}.{name}"
+        elif plain and plain.group(3):
+            # protoc aliases plain imports of top-level modules ("import foo_pb2 as foo__pb2")
+            indent, original, alias = plain.group(1), plain.group(2), plain.group(3)
+        elif plain:
+            # Type stubs import modules unaliased and reference them by full name
+            original = plain.group(2)
+            mapped = map_module(original, mapping)
+            if original in generated:
+                imported.add(mapped)
+            if mapped != original:
+                renames[original] = mapped
+                line = f"{plain.group(1)}import {mapped}"
+            lines.append(line)
+            continue
+        else:
+            lines.append(line)
+            continue
+
+        mapped = map_module(original, mapping)
+        if mapped == original and original not in generated:
+            lines.append(line)
+            continue
+
+        mapped_package, _, name = mapped.rpartition(".")
+        if original in generated:
+            imported.add(mapped)
+            if relative:
+                mapped_package = relative_import(module, mapped_package)
+        if mapped_package:
+            lines.append(f"{indent}from {mapped_package} import {name}{alias}")
+        else:
+            lines.append(f"{indent}import {name}{alias}")
+
+    content = "\n".join(lines)
+    for original, mapped in renames.items():
+        content = re.sub(r"(?<![\w.]){}(?=\.)".format(re.escape(original)), mapped, content)
+    return content, imported
+
+
+def find_cycle(graph: Dict[str, Set[str]]) -> Optional[List[str]]:
+    """Returns one import cycle in the graph, or None if it is acyclic."""
+    visiting: List[str] = []
+    done: Set[str] = set()
+
+    def visit(node: str) -> Optional[List[str]]:
+        if node in visiting:
+            return visiting[visiting.index(node):] + [node]
+        if node in done:
+            return None
+        visiting.append(node)
+        for neighbour in sorted(graph.get(node, ())):
+            cycle = visit(neighbour)
+            if cycle:
+                return cycle
+        visiting.pop()
+        done.add(node)
+        return None
+
+    for node in sorted(graph):
+        cycle = visit(node)
+        if cycle:
+            return cycle
+    return None
+
+
+def package_graph(module_imports: Dict[str, Set[str]]) -> Dict[str, Set[str]]:
+    """Collapses a module import graph into a graph between packages."""
+    graph: Dict[str, Set[str]] = {}
+    for module, targets in module_imports.items():
+        package = module.rpartition(".")[0]
+        for target in targets:
+            target_package = target.rpartition(".")[0]
+            if target_package != package:
+                graph.setdefault(package, set()).add(target_package)
+    return graph
+
+
+def cyclic_packages(graph: Dict[str, Set[str]]) -> List[Set[str]]:
+    """Returns the strongly connected components of the graph that contain a cycle."""
+    index: Dict[str, int] = {}
+    lowlink: Dict[str, int] = {}
+    stack: List[str] = []
+    components: List[Set[str]] = []
+
+    def connect(node: str) -> None:
+        index[node] = lowlink[node] = len(index)
+        stack.append(node)
+        for neighbour in sorted(graph.get(node, ())):
+            if neighbour not in index:
+                connect(neighbour)
+                lowlink[node] = min(lowlink[node], lowlink[neighbour])
+            elif neighbour in stack:
+                lowlink[node] = min(lowlink[node], index[neighbour])
+        if lowlink[node] == index[node]:
+            component = set()
+            while True:
+                member = stack.pop()
+                component.add(member)
+                if member == node:
+                    break
+            if len(component) > 1 or node in graph.get(node, ()):
+                components.append(component)
+
+    for node in sorted(graph):
+        if node not in index:
+            connect(node)
+    return components
+
+
+def deferred_init(modules: List[str]) -> str:
+    """Returns an __init__.py that imports the given submodules lazily."""
+    return DEFERRED_INIT.format(modules=", ".join(f'"{module}"' for module in sorted(modules)))
+
+
+def _module_name(relative_path: str) -> str:
+    for suffix in GENERATED_SUFFIXES:
+        if relative_path.endswith(suffix):
+            relative_path = relative_path[:-len(suffix)]
+    return relative_path.replace(os.sep, ".").replace("/", ".")
+
+
+def map_tree(
+    input_dir: str,
+    output_dir: str,
+    mapping: Dict[str, str],
+    relative: bool = False,
+    py_typed: bool = False,
+    cycle_strategy: str = "fail",
+    extend_path: bool = False,
+) -> MappingResult:
+    """
+    Relocates a protoc-generated Python tree according to a package mapping.
+
+    Args:
+        input_dir: Directory written by protoc --python_out/--grpc_python_out
+        output_dir: Directory to write the mapped tree to
+        mapping: Proto directory to Python package mapping
+        relative: Use relative imports between modules of the tree
+        py_typed: Write a PEP 561 py.typed marker into each top-level package
+        cycle_strategy: "fail" to reject introduced package cycles, "deferred" to
+                        break every package cycle with lazily importing __init__.py files
+        extend_path: Write pkgutil-style __init__.py files, so packages shared with
+                     trees generated by other libraries are merged on sys.path
+
+    Returns:
+        MappingResult describing the mapped modules and their imports
+    """
+    sources: Dict[str, List[str]] = {}
+    for root, _, files in os.walk(input_dir):
+        for filename in sorted(files):
+            if filename.endswith(GENERATED_SUFFIXES) and filename != "__init__.py":
+                relative_path = os.path.relpath(os.path.join(root, filename), input_dir)
+                sources.setdefault(_module_name(relative_path), []).append(relative_path)
+
+    generated = set(sources)
+    result = MappingResult()
+    destinations: Dict[str, str] = {}
+    for module in sorted(generated):
+        mapped = map_module(module, mapping)
+        if mapped in destinations:
+            raise PackageMappingError(f"modules {destinations[mapped]} and {module} both map to {mapped}")
+        destinations[mapped] = module
+        result.modules[module] = mapped
+
+    original_imports: Dict[str, Set[str]] = {}
+    for module, paths in sorted(sources.items()):
+        mapped = result.modules[module]
+        for relative_path in paths:
+            with open(os.path.join(input_dir, relative_path), "r", encoding="utf-8") as f:
+                content = f.read()
+            new_content, imported = rewrite_imports(content, mapped, mapping, generated, relative)
+            # The builder registers messages under the module name (used for __module__)
+            new_content = new_content.replace(f"'{module}'", f"'{mapped}'")
+            result.imports.setdefault(mapped, set()).update(imported)
+            _, original = rewrite_imports(content, module, {}, generated, False)
+            original_imports.setdefault(module, set()).update(original)
+
+            extension = ".pyi" if relative_path.endswith(".pyi") else ".py"
+            destination = os.path.join(output_dir, *mapped.split(".")) + extension
+            os.makedirs(os.path.dirname(destination), exist_ok=True)
+            with open(destination, "w", encoding="utf-8") as f:
+                f.write(new_content)
+
+    cycle = find_cycle(result.imports)
+    if cycle:
+        raise PackageMappingError("package mapping introduces an import cycle: " + " -> ".join(cycle))
+    if cycle_strategy not in CYCLE_STRATEGIES:
+        raise PackageMappingError(f"unknown cycle strategy '{cycle_strategy}', expected one of {CYCLE_STRATEGIES}")
+    graph = package_graph(result.imports)
+    cycle = find_cycle(graph)
+    deferred: Set[str] = set()
+    if cycle and cycle_strategy == "deferred":
+        for component in cyclic_packages(graph):
+            deferred.update(component)
+            result.cycles.append(find_cycle({p: graph[p] & component for p in component}))
+    elif cycle and not find_cycle(package_graph(original_imports)):
+        raise PackageMappingError("package mapping introduces a package import cycle: " + " -> ".join(cycle))
+
+    for mapped in result.modules.values():
+        parts = mapped.split(".")[:-1]
+        for i in range(1, len(parts) + 1):
+            result.packages.add(".".join(parts[:i]))
+    for package in sorted(result.packages):
+        init_file = os.path.join(output_dir, *package.split("."), "__init__.py")
+        if not os.path.exists(init_file):
+            if package in deferred:
+                modules = [m.rpartition(".")[2] for m in result.modules.values() if m.rpartition(".")[0] == package]
+                content = deferred_init(modules)
+            else:
+                content = EXTEND_PATH_INIT if extend_path else DEFAULT_INIT
+            with open(init_file, "w", encoding="utf-8") as f:
+                f.write(content)
+    if py_typed:
+        for package in sorted(p for p in result.packages if "." not in p):
+            Path(output_dir, package, "py.typed").write_text(PY_TYPED, encoding="utf-8")
+
+    return result
+
+
+def main():
+    """Main entry point for Python package mapping."""
+    parser = argparse.ArgumentParser(description="Relocate generated Python protobuf code into mapped packages")
+    parser.add_argument("--input-dir", required=True, help="Directory containing protoc output")
+    parser.add_argument("--output-dir", required=True, help="Directory to write the mapped tree to")
+    parser.add_argument("--map", action="append", default=[], help="Mapping as PROTO_DIR=PYTHON_PACKAGE")
+    parser.add_argument("--relative-imports", action="store_true", help="Use relative imports within the tree")
+    parser.add_argument("--py-typed", action="store_true", help="Write PEP 561 py.typed markers")
+    parser.add_argument("--cycle-strategy", choices=CYCLE_STRATEGIES, default="fail",
+                        help="How package import cycles are handled")
+    parser.add_argument("--extend-path", action="store_true",
+                        help="Merge packages shared with other generated trees (pkgutil namespace packages)")
+    args = parser.parse_args()
+
+    try:
+        if os.path.exists(args.output_dir):
+            shutil.rmtree(args.output_dir)
+        os.makedirs(args.output_dir)
+        result = map_tree(
+            args.input_dir,
+            args.output_dir,
+            parse_mapping(args.map),
+            relative=args.relative_imports,
+            py_typed=args.py_typed,
+            cycle_strategy=args.cycle_strategy,
+            extend_path=args.extend_path,
+        )
+    except (PackageMappingError, OSError) as e:
+        print(f"ERROR: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    for original, mapped in sorted(result.modules.items()):
+        if original != mapped:
+            print(f"{original} -> {mapped}")
+    for cycle in result.cycles:
+        print("deferred package imports to break cycle: " + " -> ".join(cycle))
+
+
+if __name__ == "__main__":
+    main()
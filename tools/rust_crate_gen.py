@@ -0,0 +1,151 @@
+#!/usr/bin/env python3
+"""
+Assembles the source tree of a crate from protoc-gen-prost/tonic output.
+
+protoc-gen-prost writes one file per proto package (`acme.user.v1.rs`) and
+protoc-gen-tonic one per package with services (`acme.user.v1.tonic.rs`).
+Neither is a module tree; this tool copies them into the crate's src/
+directory and writes:
+
+    src/mod.rs   nested `pub mod` blocks following the package hierarchy,
+                 each including the package's prost and tonic files
+    src/lib.rs   the crate root, including mod.rs
+
+so `acme.user.v1.User` is `crate::acme::user::v1::User`. Code generated for a
+package refers to other packages with `super::` paths, which resolve because
+every package of the crate sits in the same tree. Well-known types are not
+generated; prost refers to them as `::prost_types::...`.
+
+Usage:
+    rust_crate_gen.py --input-dir rust_raw --output-dir rust/src --crate-name user_proto
+"""
+
+import argparse
+import shutil
+import sys
+from pathlib import Path
+from typing import Dict, List
+
+# Rust keywords a package segment may collide with; prost emits them as raw identifiers
+RUST_KEYWORDS = {
+    "as", "async", "await", "break", "const", "continue", "crate", "dyn", "else", "enum", "extern",
+    "false", "fn", "for", "if", "impl", "in", "let", "loop", "match", "mod", "move", "mut", "pub",
+    "ref", "return", "self", "Self", "static", "struct", "super", "trait", "true", "type", "unsafe",
+    "use", "where", "while", "abstract", "become", "box", "do", "final", "macro", "override", "priv",
+    "typeof", "unsized", "virtual", "yield", "try",
+}
+
+TONIC_SUFFIX = ".tonic.rs"
+
+# File prost writes for proto files without a package statement
+NO_PACKAGE_FILE = "_.rs"
+
+
+class CrateGenError(RuntimeError):
+    """Raised when the generated files cannot be assembled into a crate."""
+
+
+def module_name(segment: str) -> str:
+    """Returns the Rust module name of a package segment."""
+    if segment in ("self", "super", "crate", "Self"):
+        raise CrateGenError(f"package segment '{segment}' cannot be a Rust module name")
+    return f"r#{segment}" if segment in RUST_KEYWORDS else segment
+
+
+def package_files(files: List[str]) -> Dict[str, List[str]]:
+    """
+    Groups generated file names by proto package.
+
+    Returns:
+        Package ("" for files without a package) to its files, prost file first
+    """
+    packages: Dict[str, List[str]] = {}
+    for name in sorted(files):
+        if name == NO_PACKAGE_FILE or name == "_" + TONIC_SUFFIX:
+            package = ""
+        elif name.endswith(TONIC_SUFFIX):
+            package = name[:-len(TONIC_SUFFIX)]
+        elif name.endswith(".rs"):
+            package = name[:-len(".rs")]
+        else:
+            continue
+        packages.setdefault(package, []).append(name)
+    for names in packages.values():
+        names.sort(key=lambda name: name.endswith(TONIC_SUFFIX))
+    return packages
+
+
+def render_mod_rs(packages: Dict[str, List[str]]) -> str:
+    """Renders mod.rs with one nested module per package segment."""
+    # Tree of segment -> subtree, with the files of a package under the "" key
+    tree: Dict = {}
+    for package, files in packages.items():
+        node = tree
+        for segment in package.split(".") if package else []:
+            node = node.setdefault(segment, {})
+        node[""] = files
+
+    lines = ["// Generated by buck2-protobuf rust_proto_library. DO NOT EDIT.", ""]
+
+    def emit(node: Dict, depth: int) -> None:
+        indent = "    " * depth
+        for name in node.get("", []):
+            lines.append(f'{indent}include!("{name}");')
+        for segment in sorted(key for key in node if key):
+            lines.append(f"{indent}pub mod {module_name(segment)} {{")
+            emit(node[segment], depth + 1)
+            lines.append(f"{indent}}}")
+
+    emit(tree, 0)
+    return "\n".join(lines) + "\n"
+
+
+def render_lib_rs(crate_name: str) -> str:
+    """Renders the crate root."""
+    return (
+        f"//! Generated protobuf crate {crate_name}.\n"
+        "//!\n"
+        "//! Generated by buck2-protobuf rust_proto_library. DO NOT EDIT.\n"
+        "\n"
+        '#![allow(clippy::all, non_camel_case_types, unused_imports)]\n'
+        "\n"
+        'include!("mod.rs");\n'
+    )
+
+
+def assemble_crate(input_dir: Path, output_dir: Path, crate_name: str) -> Dict[str, List[str]]:
+    """Copies the generated files into output_dir and writes mod.rs and lib.rs."""
+    files = [path.name for path in input_dir.iterdir() if path.is_file()]
+    packages = package_files(files)
+    if not packages:
+        raise CrateGenError(f"protoc generated no Rust files in {input_dir}")
+    for reserved in ("mod.rs", "lib.rs"):
+        if reserved in files:
+            raise CrateGenError(f"proto package '{reserved[:-3]}' clashes with the generated src/{reserved}")
+
+    output_dir.mkdir(parents=True, exist_ok=True)
+    for names in packages.values():
+        for name in names:
+            shutil.copyfile(input_dir / name, output_dir / name)
+    (output_dir / "mod.rs").write_text(render_mod_rs(packages), encoding="utf-8")
+    (output_dir / "lib.rs").write_text(render_lib_rs(crate_name), encoding="utf-8")
+    return packages
+
+
+def main():
+    """Main entry point for the Rust crate generator."""
+    parser = argparse.ArgumentParser(description="Assemble prost/tonic output into a crate source tree")
+    parser.add_argument("--input-dir", required=True, help="protoc output directory")
+    parser.add_argument("--output-dir", required=True, help="Crate src/ directory to write")
+    parser.add_argument("--crate-name", required=True, help="Crate name, for the crate documentation")
+    args = parser.parse_args()
+
+    try:
+        assemble_crate(Path(args.input_dir), Path(args.output_dir), args.crate_name)
+    except (CrateGenError, OSError) as e:
+        print(f"ERROR: rust_crate_gen: {e}", file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
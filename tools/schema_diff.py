@@ -0,0 +1,280 @@
+#!/usr/bin/env python3
+"""
+Schema diff summaries for protobuf Buck2 integration.
+
+This tool compares a set of .proto files against a baseline (typically the
+base branch of a pull request) and writes a compact markdown summary for PR
+bots: counts of added, removed and changed elements, followed by the list of
+breaking changes. The exit code reflects breaking status when requested.
+
+Elements are matched by fully-qualified name; fields and enum values are
+matched by number, so renames show up as changes rather than as an addition
+plus a removal.
+
+Usage:
+    schema_diff.py --baseline old/user.proto --output summary.md \\
+        --exit-code user.proto
+"""
+
+import argparse
+import json
+import sys
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+try:
+    from proto_schema import Message, ProtoParseError, SchemaSet, load_schema_set
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import Message, ProtoParseError, SchemaSet, load_schema_set
+
+
+@dataclass
+class SchemaChange:
+    """A single difference between the baseline and the current schema."""
+    kind: str      # "message", "field", "enum", "enum value", "service", "rpc", "file"
+    action: str    # "added", "removed", "changed"
+    element: str
+    detail: str
+    breaking: bool
+    file: str = ""
+    line: int = 0
+
+
+def _is_reserved(number: int, reserved: List[Tuple[int, int]]) -> bool:
+    return any(start <= number <= end for start, end in reserved)
+
+
+def _field_type(schema: SchemaSet, message: Message, message_field) -> str:
+    if message_field.is_map:
+        key = message_field.map_key_type
+        value = schema.resolve_type_name(message_field.map_value_type, message.full_name) or message_field.map_value_type
+        return f"map<{key}, {value}>"
+    return schema.resolve_type_name(message_field.type_name, message.full_name) or message_field.type_name
+
+
+def _index(schema: SchemaSet) -> Dict[str, Dict[str, Any]]:
+    """Indexes messages, enums and services by full name, with their files."""
+    index = {"message": {}, "enum": {}, "service": {}}
+    for proto_file in schema.files:
+        for message in proto_file.all_messages():
+            if not message.is_map_entry:
+                index["message"][message.full_name] = (message, proto_file)
+        for enum in proto_file.all_enums():
+            index["enum"][enum.full_name] = (enum, proto_file)
+        for service in proto_file.services:
+            index["service"][service.full_name] = (service, proto_file)
+    return index
+
+
+def diff_schemas(baseline: SchemaSet, current: SchemaSet) -> List[SchemaChange]:
+    """
+    Computes the changes from baseline to current.
+
+    Args:
+        baseline: Schema of the base revision
+        current: Schema of the new revision
+
+    Returns:
+        List of changes, in declaration order of the current schema
+    """
+    changes: List[SchemaChange] = []
+    old, new = _index(baseline), _index(current)
+
+    def add(kind, action, element, detail, breaking, proto_file=None, line=0):
+        changes.append(SchemaChange(kind, action, element, detail, breaking,
+                                    proto_file.path if proto_file else "", line))
+
+    # Messages and fields
+    for name, (message, proto_file) in new["message"].items():
+        if name not in old["message"]:
+            add("message", "added", name, "message added", False, proto_file, message.line)
+            continue
+        old_message, _ = old["message"][name]
+        old_fields = {f.number: f for f in old_message.fields}
+        new_fields = {f.number: f for f in message.fields}
+        for number, new_field in new_fields.items():
+            element = new_field.full_name
+            if number not in old_fields:
+                add("field", "added", element, f"field {new_field.name} = {number} added", False, proto_file, new_field.line)
+                continue
+            old_field = old_fields[number]
+            if old_field.name != new_field.name:
+                add("field", "changed", element, f"field {number} renamed from {old_field.name} to {new_field.name}",
+                    True, proto_file, new_field.line)
+            old_type = _field_type(baseline, old_message, old_field)
+            new_type = _field_type(current, message, new_field)
+            if old_type != new_type:
+                add("field", "changed", element, f"type changed from {old_type} to {new_type}", True, proto_file, new_field.line)
+            if old_field.cardinality != new_field.cardinality:
+                add("field", "changed", element, f"cardinality changed from {old_field.cardinality} to {new_field.cardinality}",
+                    True, proto_file, new_field.line)
+            if (old_field.oneof or "") != (new_field.oneof or ""):
+                add("field", "changed", element, "moved into or out of a oneof", True, proto_file, new_field.line)
+        for number, old_field in old_fields.items():
+            if number not in new_fields:
+                reserved = _is_reserved(number, message.reserved_numbers)
+                detail = f"field {old_field.name} = {number} removed" + ("" if reserved else " without reserving its number")
+                add("field", "removed", old_field.full_name, detail, not reserved, proto_file, message.line)
+    for name, (message, proto_file) in old["message"].items():
+        if name not in new["message"]:
+            add("message", "removed", name, "message removed", True)
+
+    # Enums and values
+    for name, (enum, proto_file) in new["enum"].items():
+        if name not in old["enum"]:
+            add("enum", "added", name, "enum added", False, proto_file, enum.line)
+            continue
+        old_enum, _ = old["enum"][name]
+        old_values = {v.name: v.number for v in old_enum.values}
+        new_values = {v.name: v for v in enum.values}
+        for value in enum.values:
+            element = f"{name}.{value.name}"
+            if value.name not in old_values:
+                add("enum value", "added", element, f"value {value.name} = {value.number} added", False, proto_file, value.line)
+            elif old_values[value.name] != value.number:
+                add("enum value", "changed", element, f"number changed from {old_values[value.name]} to {value.number}",
+                    True, proto_file, value.line)
+        for value_name, number in old_values.items():
+            if value_name not in new_values:
+                reserved = _is_reserved(number, enum.reserved_numbers)
+                detail = f"value {value_name} = {number} removed" + ("" if reserved else " without reserving its number")
+                add("enum value", "removed", f"{name}.{value_name}", detail, not reserved, proto_file, enum.line)
+    for name in old["enum"]:
+        if name not in new["enum"]:
+            add("enum", "removed", name, "enum removed", True)
+
+    # Services and methods
+    for name, (service, proto_file) in new["service"].items():
+        if name not in old["service"]:
+            add("service", "added", name, "service added", False, proto_file, service.line)
+            continue
+        old_service, _ = old["service"][name]
+        old_methods = {m.name: m for m in old_service.methods}
+        for method in service.methods:
+            if method.name not in old_methods:
+                add("rpc", "added", method.full_name, "rpc added", False, proto_file, method.line)
+                continue
+            old_method = old_methods[method.name]
+            old_signature = (baseline.resolve_type_name(old_method.input_type, name) or old_method.input_type,
+                             baseline.resolve_type_name(old_method.output_type, name) or old_method.output_type,
+                             old_method.client_streaming, old_method.server_streaming)
+            new_signature = (current.resolve_type_name(method.input_type, name) or method.input_type,
+                             current.resolve_type_name(method.output_type, name) or method.output_type,
+                             method.client_streaming, method.server_streaming)
+            if old_signature != new_signature:
+                add("rpc", "changed", method.full_name, "request, response or streaming changed", True, proto_file, method.line)
+        new_method_names = {m.name for m in service.methods}
+        for method_name in old_methods:
+            if method_name not in new_method_names:
+                add("rpc", "removed", f"{name}.{method_name}", "rpc removed", True, proto_file, service.line)
+    for name in old["service"]:
+        if name not in new["service"]:
+            add("service", "removed", name, "service removed", True)
+
+    return changes
+
+
+_KIND_PLURALS = {
+    "message": "messages",
+    "field": "fields",
+    "enum": "enums",
+    "enum value": "enum values",
+    "service": "services",
+    "rpc": "RPCs",
+}
+
+
+def summary_counts(changes: List[SchemaChange]) -> List[Tuple[str, int]]:
+    """Returns ("N added fields"-style label, count) pairs in a stable order."""
+    counts: Dict[Tuple[str, str], int] = {}
+    for change in changes:
+        counts[(change.action, change.kind)] = counts.get((change.action, change.kind), 0) + 1
+    ordered = []
+    for action in ("added", "removed", "changed"):
+        for kind, plural in _KIND_PLURALS.items():
+            count = counts.get((action, kind), 0)
+            if count:
+                ordered.append((f"{action} {plural if count != 1 else kind}", count))
+    return ordered
+
+
+def render_markdown(changes: List[SchemaChange], title: str = "Schema changes") -> str:
+    """Renders a compact markdown summary suitable for a PR comment."""
+    breaking = [c for c in changes if c.breaking]
+    lines = [f"### {title}", ""]
+    if not changes:
+        lines.append("No schema changes.")
+        return "\n".join(lines) + "\n"
+
+    status = f"⚠️ **{len(breaking)} breaking change{'s' if len(breaking) != 1 else ''}**" if breaking else "✅ **No breaking changes**"
+    counts = " · ".join(f"{count} {label}" for label, count in summary_counts(changes))
+    lines.extend([f"{status} · {counts}", ""])
+
+    if breaking:
+        lines.extend(["<details open>", f"<summary>Breaking changes ({len(breaking)})</summary>", ""])
+        for change in breaking:
+            location = f" ({change.file}:{change.line})" if change.file else ""
+            lines.append(f"- `{change.element}`{location}: {change.detail}")
+        lines.extend(["", "</details>", ""])
+
+    other = [c for c in changes if not c.breaking]
+    if other:
+        lines.extend(["<details>", f"<summary>Other changes ({len(other)})</summary>", ""])
+        for change in other:
+            lines.append(f"- `{change.element}`: {change.detail}")
+        lines.extend(["", "</details>", ""])
+
+    return "\n".join(lines).rstrip() + "\n"
+
+
+def run_diff(
+    files: List[str],
+    baseline_files: List[str],
+    dep_files: Optional[List[str]] = None,
+) -> List[SchemaChange]:
+    """Parses both schema versions and returns their differences."""
+    current = load_schema_set(files, dep_files)
+    baseline = load_schema_set(baseline_files, dep_files)
+    return diff_schemas(baseline, current)
+
+
+def main():
+    """Main entry point for schema diff summaries."""
+    parser = argparse.ArgumentParser(description="Summarize protobuf schema changes as markdown")
+    parser.add_argument("--baseline", action="append", default=[], help="Baseline proto file (repeatable)")
+    parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    parser.add_argument("--output", help="Markdown output file (default: stdout)")
+    parser.add_argument("--json-output", help="Also write the changes as JSON")
+    parser.add_argument("--title", default="Schema changes", help="Heading of the summary")
+    parser.add_argument("--exit-code", action="store_true", help="Exit with status 1 when breaking changes are found")
+    parser.add_argument("files", nargs="+", help="Current proto files")
+    args = parser.parse_args()
+
+    try:
+        changes = run_diff(args.files, args.baseline, args.dep)
+    except (ProtoParseError, OSError) as e:
+        print(f"ERROR: schema_diff: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    markdown = render_markdown(changes, args.title)
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(markdown)
+    else:
+        sys.stdout.write(markdown)
+
+    if args.json_output:
+        with open(args.json_output, "w", encoding="utf-8") as f:
+            json.dump({
+                "breaking": sum(1 for c in changes if c.breaking),
+                "changes": [asdict(c) for c in changes],
+            }, f, indent=2)
+
+    if args.exit_code and any(c.breaking for c in changes):
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
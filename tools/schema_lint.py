@@ -0,0 +1,1715 @@
+#!/usr/bin/env python3
+"""
+Schema lint checks for protobuf Buck2 integration.
+
+This tool runs a single named check over a set of .proto files and writes a
+JSON report in the same format as custom validation rule scripts. Checks are
+registered with @register_check and receive the parsed schema, the check
+configuration and any named input files supplied by the Buck2 rule.
+
+Usage:
+    schema_lint.py --check api_allowlist --config config.json \\
+        --input allowlist=api_allowlist.txt --output report.json foo.proto
+"""
+
+import argparse
+import fnmatch
+import json
+import re
+import sys
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Any, Callable, Dict, Iterator, List, Optional, Set, Tuple
+
+try:
+    from proto_schema import Enum, Field, Message, ProtoFile, ProtoParseError, SchemaSet, find_option, load_schema_set
+    from proto_schema import WELL_KNOWN_TYPES
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import Enum, Field, Message, ProtoFile, ProtoParseError, SchemaSet, find_option, load_schema_set
+    from proto_schema import WELL_KNOWN_TYPES
+
+
+@dataclass
+class Violation:
+    """A single check violation."""
+    file: str
+    line: int
+    element: str
+    message: str
+    severity: str = "error"
+
+
+@dataclass
+class CheckContext:
+    """Everything a check needs to run."""
+    schema: SchemaSet
+    config: Dict[str, Any]
+    inputs: Dict[str, str]
+    baseline: Optional[SchemaSet] = None
+
+    def input_path(self, key: str) -> str:
+        """Returns the path of a named input, failing clearly if it is missing."""
+        if key not in self.inputs:
+            raise CheckConfigError(f"missing required input '{key}'")
+        return self.inputs[key]
+
+    def require_baseline(self) -> SchemaSet:
+        if self.baseline is None:
+            raise CheckConfigError("this check requires a baseline")
+        return self.baseline
+
+
+class CheckConfigError(Exception):
+    """Raised when a check is misconfigured."""
+
+
+CheckFunction = Callable[[CheckContext], List[Violation]]
+
+CHECKS: Dict[str, CheckFunction] = {}
+CHECK_DESCRIPTIONS: Dict[str, str] = {}
+
+
+def register_check(name: str, description: str) -> Callable[[CheckFunction], CheckFunction]:
+    """Registers a check function under the given name."""
+    def decorator(func: CheckFunction) -> CheckFunction:
+        CHECKS[name] = func
+        CHECK_DESCRIPTIONS[name] = description
+        return func
+    return decorator
+
+
+def is_exempt(element: str, exemptions: Any) -> bool:
+    """
+    Checks whether an element is exempted.
+
+    Exemptions may be a list of names/glob patterns, or a dict mapping names to
+    the documented reason for the exemption.
+    """
+    if not exemptions:
+        return False
+    patterns = exemptions.keys() if isinstance(exemptions, dict) else exemptions
+    return any(element == pattern or fnmatch.fnmatchcase(element, pattern) for pattern in patterns)
+
+
+def load_name_list(path: str) -> List[str]:
+    """Loads a newline-separated list of names, ignoring blank lines and # comments."""
+    names = []
+    with open(path, "r", encoding="utf-8") as f:
+        for line in f:
+            line = line.split("#", 1)[0].strip()
+            if line:
+                names.append(line)
+    return names
+
+
+def iter_option_owners(proto_file) -> Iterator[Tuple[str, str, int, Dict[str, Any]]]:
+    """
+    Yields every element of a file that can carry options.
+
+    Yields:
+        Tuples of (kind, fully-qualified name, line, options)
+    """
+    yield "file", proto_file.package or proto_file.path, proto_file.package_line, proto_file.options
+    for message in proto_file.all_messages():
+        yield "message", message.full_name, message.line, message.options
+        for message_field in message.fields:
+            yield "field", message_field.full_name, message_field.line, message_field.options
+        for oneof in message.oneofs:
+            yield "oneof", oneof.full_name, oneof.line, oneof.options
+    for extension in proto_file.all_extensions():
+        yield "extension", extension.full_name, extension.line, extension.options
+    for enum in proto_file.all_enums():
+        yield "enum", enum.full_name, enum.line, enum.options
+        for value in enum.values:
+            yield "enum value", f"{enum.full_name}.{value.name}", value.line, value.options
+    for service in proto_file.services:
+        yield "service", service.full_name, service.line, service.options
+        for method in service.methods:
+            yield "method", method.full_name, method.line, method.options
+
+
+def iter_commented_elements(proto_file) -> Iterator[Tuple[str, str, int, str]]:
+    """
+    Yields every documented element of a file with its combined comments.
+
+    Yields:
+        Tuples of (kind, fully-qualified name, line, comment text)
+    """
+    def comments(element) -> str:
+        return "\n".join(c for c in (element.leading_comment, getattr(element, "trailing_comment", "")) if c)
+
+    for message in proto_file.all_messages():
+        yield "message", message.full_name, message.line, comments(message)
+        for message_field in message.fields:
+            yield "field", message_field.full_name, message_field.line, comments(message_field)
+        for oneof in message.oneofs:
+            yield "oneof", oneof.full_name, oneof.line, comments(oneof)
+    for enum in proto_file.all_enums():
+        yield "enum", enum.full_name, enum.line, comments(enum)
+        for value in enum.values:
+            yield "enum value", f"{enum.full_name}.{value.name}", value.line, comments(value)
+    for service in proto_file.services:
+        yield "service", service.full_name, service.line, comments(service)
+        for method in service.methods:
+            yield "rpc", method.full_name, method.line, comments(method)
+
+
+# Checks
+
+
+@register_check("api_allowlist", "Public messages must match the API allowlist in both directions")
+def check_api_allowlist(ctx: CheckContext) -> List[Violation]:
+    allowlist_path = ctx.input_path("allowlist")
+    allowlist = load_name_list(allowlist_path)
+    public_option = ctx.config.get("public_option", "")
+    public_patterns = [re.compile(p) for p in ctx.config.get("public_name_patterns", [])]
+
+    if not public_option and not public_patterns:
+        raise CheckConfigError("api_allowlist requires public_option or public_name_patterns")
+
+    violations = []
+    defined = set()
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            defined.add(message.full_name)
+            marked = bool(public_option) and find_option(message.options, public_option) is True
+            named = any(p.search(message.full_name) for p in public_patterns)
+            if (marked or named) and message.full_name not in allowlist:
+                reason = "marked public via {}".format(public_option) if marked else "matches a public naming pattern"
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=message.line,
+                    element=message.full_name,
+                    message=f"message {message.full_name} is {reason} but is not in the API allowlist",
+                ))
+
+    for name in allowlist:
+        if name not in defined:
+            violations.append(Violation(
+                file=allowlist_path,
+                line=0,
+                element=name,
+                message=f"allowlisted message {name} no longer exists",
+            ))
+
+    return violations
+
+
+def option_value_size(value: Any, is_bytes: bool = False) -> int:
+    """
+    Returns the approximate encoded size in bytes of an option value.
+
+    Bytes literals are decoded to code points 0-255, so for bytes-typed values
+    each character is measured as a single byte rather than UTF-8 encoded.
+    """
+    if isinstance(value, dict):
+        return sum(len(key) + option_value_size(v) for key, v in value.items())
+    if isinstance(value, list):
+        return sum(option_value_size(v, is_bytes) for v in value)
+    if isinstance(value, str):
+        return len(value.encode("latin-1" if is_bytes else "utf-8"))
+    return len(str(value))
+
+
+@register_check("large_option_values", "Option values and field defaults must not exceed a byte threshold")
+def check_large_option_values(ctx: CheckContext) -> List[Violation]:
+    max_bytes = ctx.config.get("max_bytes", 1024)
+    if not isinstance(max_bytes, int) or max_bytes <= 0:
+        raise CheckConfigError("large_option_values requires a positive integer max_bytes")
+
+    bytes_fields = set()
+    extensions = {}
+    for proto_file in ctx.schema.all_files:
+        for extension in proto_file.all_extensions():
+            extensions[extension.full_name] = extension
+        for message in proto_file.all_messages():
+            bytes_fields.update(f.full_name for f in message.fields if f.type_name == "bytes")
+    bytes_fields.update(name for name, extension in extensions.items() if extension.type_name == "bytes")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for kind, name, line, options in iter_option_owners(proto_file):
+            scope = proto_file.package if kind == "file" else name
+            for option_name, value in options.items():
+                if option_name == "default":
+                    is_bytes = kind in ("field", "extension") and name in bytes_fields
+                else:
+                    match = re.match(r"^\((\.?[\w.]+)\)$", option_name)
+                    resolved = _resolve_extension(match.group(1), scope, extensions) if match else None
+                    is_bytes = resolved in bytes_fields
+                size = option_value_size(value, is_bytes)
+                if size <= max_bytes:
+                    continue
+                what = "default value" if option_name == "default" else f"option {option_name}"
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=line,
+                    element=name,
+                    message=f"{what} on {kind} {name} is {size} bytes (limit {max_bytes})",
+                ))
+    return violations
+
+
+@register_check("comment_markers", "Doc comments must not contain release-blocking markers such as TODO or FIXME")
+def check_comment_markers(ctx: CheckContext) -> List[Violation]:
+    markers = ctx.config.get("markers", ["TODO", "FIXME", "XXX"])
+    if not markers:
+        raise CheckConfigError("comment_markers requires at least one marker")
+    pattern = re.compile(r"\b(" + "|".join(re.escape(m) for m in markers) + r")\b")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for kind, name, line, comment in iter_commented_elements(proto_file):
+            found = sorted(set(pattern.findall(comment)))
+            if found:
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=line,
+                    element=name,
+                    message=f"comment on {kind} {name} contains {', '.join(found)}",
+                ))
+    return violations
+
+
+_PATH_VARIABLE = re.compile(r"\{([^}=]+)(?:=([^}]*))?\}")
+
+
+def normalize_http_path(path: str) -> str:
+    """Normalizes an HTTP path template so that equivalent routes compare equal."""
+    normalized = _PATH_VARIABLE.sub(lambda m: m.group(2) or "*", path)
+    return normalized.rstrip("/") or "/"
+
+
+@register_check("http_route_conflicts", "No two RPCs may share an HTTP verb and path template")
+def check_http_route_conflicts(ctx: CheckContext) -> List[Violation]:
+    routes: Dict[Tuple[str, str], Tuple[str, str, int]] = {}
+    violations = []
+    for proto_file in ctx.schema.files:
+        for service in proto_file.services:
+            for method in service.methods:
+                for verb, path in method.http_rules():
+                    key = (verb, normalize_http_path(path))
+                    if key not in routes:
+                        routes[key] = (method.full_name, proto_file.path, method.line)
+                        continue
+                    other, other_file, other_line = routes[key]
+                    if other == method.full_name:
+                        continue
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=method.line,
+                        element=method.full_name,
+                        message=f"rpc {method.full_name} and rpc {other} ({other_file}:{other_line}) both map to {verb} {path}",
+                    ))
+    return violations
+
+
+@register_check("service_exposure", "Services must expose HTTP routes or be marked internal")
+def check_service_exposure(ctx: CheckContext) -> List[Violation]:
+    option = ctx.config.get("internal_option", "buck2protobuf.api.v1.internal")
+    if not option:
+        raise CheckConfigError("service_exposure requires an internal_option name")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for service in proto_file.services:
+            if find_option(service.options, option) is True:
+                continue
+            if any(method.http_rules() for method in service.methods):
+                continue
+            violations.append(Violation(
+                file=proto_file.path,
+                line=service.line,
+                element=service.full_name,
+                message=f"service {service.full_name} has no google.api.http routes and is not marked ({option}) = true",
+            ))
+    return violations
+
+
+@register_check("negative_enum_values", "Enum values must not be negative")
+def check_negative_enum_values(ctx: CheckContext) -> List[Violation]:
+    min_value = ctx.config.get("min_value", 0)
+    if not isinstance(min_value, int) or min_value < 0:
+        raise CheckConfigError(f"negative_enum_values min_value must be a non-negative integer, got {min_value!r}")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for enum in proto_file.all_enums():
+            # Whole enums may be exempted, e.g. for sentinel values such as -1
+            if is_exempt(enum.full_name, ctx.config.get("exemptions")):
+                continue
+            for value in enum.values:
+                if value.number < min_value:
+                    element = f"{enum.full_name}.{value.name}"
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=value.line,
+                        element=element,
+                        message=f"enum value {element} = {value.number} is below {min_value}",
+                    ))
+    return violations
+
+
+# Largest field number protobuf allows, written "max" in reserved ranges
+MAX_FIELD_NUMBER = 536870911
+
+
+def _parse_number_ranges(check: str, ranges: Any) -> List[Tuple[int, int]]:
+    """Validates a list of inclusive [start, end] field number ranges."""
+    if not isinstance(ranges, list) or not ranges:
+        raise CheckConfigError(f"{check} ranges must be a non-empty list of [start, end] pairs, got {ranges!r}")
+    parsed = []
+    for entry in ranges:
+        if (not isinstance(entry, (list, tuple)) or len(entry) != 2
+                or not all(isinstance(n, int) and not isinstance(n, bool) for n in entry)
+                or not 1 <= entry[0] <= entry[1] <= MAX_FIELD_NUMBER):
+            raise CheckConfigError(f"{check} range {entry!r} must be [start, end] with 1 <= start <= end <= {MAX_FIELD_NUMBER}")
+        parsed.append((entry[0], entry[1]))
+    return parsed
+
+
+def _format_number_range(start: int, end: int) -> str:
+    return f"{start} to {'max' if end == MAX_FIELD_NUMBER else end}"
+
+
+@register_check("reserved_field_numbers", "Non-extension fields must not use numbers in the reserved internal ranges")
+def check_reserved_field_numbers(ctx: CheckContext) -> List[Violation]:
+    ranges = _parse_number_ranges("reserved_field_numbers", ctx.config.get("ranges", [[50000, MAX_FIELD_NUMBER]]))
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            # Extensions live in message.extensions and are allowed in the range
+            for message_field in message.fields:
+                for start, end in ranges:
+                    if start <= message_field.number <= end:
+                        violations.append(Violation(
+                            file=proto_file.path,
+                            line=message_field.line,
+                            element=message_field.full_name,
+                            message=f"field {message_field.full_name} = {message_field.number} is in the reserved "
+                                    f"internal range {_format_number_range(start, end)}",
+                        ))
+                        break
+    return violations
+
+
+@register_check("enum_number_stability", "Enum value numbers must not change relative to the baseline")
+def check_enum_number_stability(ctx: CheckContext) -> List[Violation]:
+    baseline = ctx.require_baseline()
+    previous = {}
+    for proto_file in baseline.files:
+        for enum in proto_file.all_enums():
+            previous[enum.full_name] = {value.name: value.number for value in enum.values}
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for enum in proto_file.all_enums():
+            if enum.full_name not in previous:
+                continue
+            old_numbers = previous[enum.full_name]
+            old_names = {number: name for name, number in old_numbers.items()}
+            for value in enum.values:
+                element = f"{enum.full_name}.{value.name}"
+                if value.name in old_numbers and old_numbers[value.name] != value.number:
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=value.line,
+                        element=element,
+                        message=f"enum value {element} changed number from {old_numbers[value.name]} to {value.number}",
+                    ))
+                elif value.name not in old_numbers and value.number in old_names:
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=value.line,
+                        element=element,
+                        message=f"enum value {element} reuses number {value.number} previously assigned to {old_names[value.number]}",
+                    ))
+    return violations
+
+
+@register_check("field_cardinality_stability", "Fields must not change between singular, repeated and map relative to the baseline")
+def check_field_cardinality_stability(ctx: CheckContext) -> List[Violation]:
+    baseline = ctx.require_baseline()
+    previous = {}
+    for proto_file in baseline.files:
+        for message in proto_file.all_messages():
+            for message_field in message.fields:
+                previous[(message.full_name, message_field.number)] = (message_field.name, message_field.cardinality)
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            if message.is_map_entry:
+                continue
+            for message_field in message.fields:
+                key = (message.full_name, message_field.number)
+                if key not in previous:
+                    continue
+                old_name, old_cardinality = previous[key]
+                cardinality = message_field.cardinality
+                if cardinality != old_cardinality:
+                    renamed = f" (was {old_name})" if old_name != message_field.name else ""
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=message_field.line,
+                        element=message_field.full_name,
+                        message=f"field {message_field.full_name} = {message_field.number}{renamed} changed from {old_cardinality} to {cardinality}",
+                    ))
+    return violations
+
+
+def _field_layout(message: Message) -> List[Tuple[int, str]]:
+    """Returns (number, name) of the message's fields in declaration order."""
+    ordered = sorted(message.fields, key=lambda f: f.declaration_index)
+    return [(message_field.number, message_field.name) for message_field in ordered]
+
+
+def _format_layout(layout: List[Tuple[int, str]]) -> str:
+    return ", ".join(f"{name} = {number}" for number, name in layout)
+
+
+@register_check("field_layout_stability", "Fields must keep their declaration order relative to the baseline")
+def check_field_layout_stability(ctx: CheckContext) -> List[Violation]:
+    allow_inserted = ctx.config.get("allow_inserted_fields", False)
+    if not isinstance(allow_inserted, bool):
+        raise CheckConfigError("field_layout_stability requires a boolean allow_inserted_fields")
+    baseline = ctx.require_baseline()
+    previous = {}
+    for proto_file in baseline.files:
+        for message in proto_file.all_messages():
+            previous[message.full_name] = _field_layout(message)
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            if message.is_map_entry or message.full_name not in previous:
+                continue
+            old_layout = previous[message.full_name]
+            layout = _field_layout(message)
+            old_numbers = {number for number, _ in old_layout}
+            numbers = {number for number, _ in layout}
+            # Fields are matched by number; removed fields are left to breaking change checks
+            old_kept = [entry for entry in old_layout if entry[0] in numbers]
+            kept = [entry for entry in layout if entry[0] in old_numbers]
+            if [n for n, _ in kept] != [n for n, _ in old_kept]:
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=message.line,
+                    element=message.full_name,
+                    message=f"fields of {message.full_name} were reordered: baseline declares "
+                            f"{_format_layout(old_kept)}, now {_format_layout(kept)}",
+                ))
+                continue
+            if allow_inserted or not kept:
+                continue
+            last_kept = max(i for i, entry in enumerate(layout) if entry[0] in old_numbers)
+            inserted = [entry for entry in layout[:last_kept] if entry[0] not in old_numbers]
+            if inserted:
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=message.line,
+                    element=message.full_name,
+                    message=f"fields of {message.full_name} were inserted before existing fields: "
+                            f"{_format_layout(inserted)}; declare new fields after {layout[last_kept][1]}",
+                ))
+    return violations
+
+
+# Default unit suffix rules: category -> field name pattern and allowed suffixes
+DEFAULT_UNIT_RULES = {
+    "time": {
+        "pattern": r"(^|_)(timeout|delay|duration|interval|ttl|latency|elapsed|period|age)(_|$)",
+        "suffixes": ["_seconds", "_millis", "_micros", "_nanos"],
+    },
+    "size": {
+        "pattern": r"(^|_)(?<!page_)(size|capacity|quota)(_|$)",
+        "suffixes": ["_bytes"],
+    },
+    "money": {
+        "pattern": r"(^|_)(price|cost|amount|fee|balance|budget)(_|$)",
+        "suffixes": ["_micros", "_cents"],
+    },
+}
+
+_NON_NUMERIC_SCALARS = {"string", "bytes", "bool"}
+
+
+@register_check("unit_suffixes", "Numeric fields whose names denote a time, size or amount must end in a unit suffix")
+def check_unit_suffixes(ctx: CheckContext) -> List[Violation]:
+    unit_rules = ctx.config.get("unit_rules") or DEFAULT_UNIT_RULES
+    compiled = []
+    for category, rule in sorted(unit_rules.items()):
+        if not rule.get("pattern") or not rule.get("suffixes"):
+            raise CheckConfigError(f"unit rule '{category}' requires a pattern and at least one suffix")
+        try:
+            compiled.append((category, re.compile(rule["pattern"]), rule["suffixes"]))
+        except re.error as e:
+            raise CheckConfigError(f"unit rule '{category}' has an invalid pattern: {e}")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            for message_field in message.fields:
+                # Message-typed fields (Duration, Money, ...) carry their own units
+                if not message_field.is_scalar or message_field.type_name in _NON_NUMERIC_SCALARS:
+                    continue
+                for category, pattern, suffixes in compiled:
+                    if not pattern.search(message_field.name):
+                        continue
+                    if not any(message_field.name.endswith(suffix) for suffix in suffixes):
+                        violations.append(Violation(
+                            file=proto_file.path,
+                            line=message_field.line,
+                            element=message_field.full_name,
+                            message=f"{category} field {message_field.full_name} must end in one of {', '.join(suffixes)}",
+                        ))
+                    break
+    return violations
+
+
+FIELD_PRESENCE_STYLES = ["explicit", "implicit"]
+
+
+@register_check("field_presence_consistency", "proto3 messages must not mix implicit-presence and optional scalar fields")
+def check_field_presence_consistency(ctx: CheckContext) -> List[Violation]:
+    prefer = ctx.config.get("prefer", "")
+    if prefer and prefer not in FIELD_PRESENCE_STYLES:
+        raise CheckConfigError(f"prefer must be one of {', '.join(FIELD_PRESENCE_STYLES)}, got '{prefer}'")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        # proto2 and editions define presence differently; only proto3 has both styles
+        if proto_file.syntax != "proto3":
+            continue
+        for message in proto_file.all_messages():
+            explicit, implicit = [], []
+            for message_field in message.fields:
+                if not message_field.is_scalar or message_field.is_repeated or message_field.oneof:
+                    continue
+                (explicit if message_field.label == "optional" else implicit).append(message_field.name)
+            if not explicit or not implicit:
+                continue
+            if prefer == "explicit":
+                detail = f"fields without optional: {', '.join(implicit)}"
+            elif prefer == "implicit":
+                detail = f"optional fields: {', '.join(explicit)}"
+            else:
+                detail = f"optional: {', '.join(explicit)}; implicit presence: {', '.join(implicit)}"
+            violations.append(Violation(
+                file=proto_file.path,
+                line=message.line,
+                element=message.full_name,
+                message=f"message {message.full_name} mixes optional and implicit-presence scalars ({detail})",
+            ))
+    return violations
+
+
+_PASCAL_CASE = re.compile(r"^[A-Z][A-Za-z0-9]*$")
+
+
+@register_check("service_pascal_case", "Service and RPC names must be PascalCase")
+def check_service_pascal_case(ctx: CheckContext) -> List[Violation]:
+    violations = []
+    for proto_file in ctx.schema.files:
+        for service in proto_file.services:
+            # An exempted legacy service also exempts its methods
+            if is_exempt(service.full_name, ctx.config.get("exemptions")):
+                continue
+            elements = [("service", service)] + [("rpc", method) for method in service.methods]
+            for kind, element in elements:
+                if not _PASCAL_CASE.match(element.name):
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=element.line,
+                        element=element.full_name,
+                        message=f"{kind} name {element.name} is not PascalCase",
+                    ))
+    return violations
+
+
+DEFAULT_MUTATION_PREFIXES = ["Create", "Delete", "Update"]
+SAFE_TO_RETRY_LEVELS = ["IDEMPOTENT", "NO_SIDE_EFFECTS"]
+
+
+def _is_mutation(name: str, prefixes: List[str]) -> Optional[str]:
+    """Returns the mutation prefix a method name starts with as a whole word, if any."""
+    for prefix in prefixes:
+        if name == prefix or (name.startswith(prefix) and not name[len(prefix)].islower()):
+            return prefix
+    return None
+
+
+@register_check("idempotent_mutations", "Mutating RPCs must not be marked idempotent without a justification")
+def check_idempotent_mutations(ctx: CheckContext) -> List[Violation]:
+    prefixes = ctx.config.get("mutation_prefixes", DEFAULT_MUTATION_PREFIXES)
+    if not isinstance(prefixes, list) or not prefixes or not all(isinstance(p, str) and p for p in prefixes):
+        raise CheckConfigError("idempotent_mutations requires a non-empty list of mutation_prefixes")
+    marker = ctx.config.get("justification", "Idempotent:")
+    if not isinstance(marker, str):
+        raise CheckConfigError("idempotent_mutations requires a string justification marker")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for service in proto_file.services:
+            for method in service.methods:
+                level = find_option(method.options, "idempotency_level")
+                if level not in SAFE_TO_RETRY_LEVELS:
+                    continue
+                prefix = _is_mutation(method.name, prefixes)
+                if prefix is None:
+                    continue
+                if marker and marker.lower() in method.leading_comment.lower():
+                    continue
+                hint = f" or document why retries are safe in a comment containing '{marker}'" if marker else ""
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=method.line,
+                    element=method.full_name,
+                    message=f"rpc {method.full_name} looks like a mutation ({prefix}) but is marked "
+                            f"idempotency_level = {level}; remove the option{hint}",
+                ))
+    return violations
+
+
+@register_check("rpc_top_level_messages", "RPC request and response types must be top-level messages")
+def check_rpc_top_level_messages(ctx: CheckContext) -> List[Violation]:
+    violations = []
+    for proto_file in ctx.schema.files:
+        for service in proto_file.services:
+            for method in service.methods:
+                for kind, type_name in (("request", method.input_type), ("response", method.output_type)):
+                    message = ctx.schema.resolve_type(type_name, service.full_name)
+                    # Unresolved types are reported by protoc; nested types may be exempted by name
+                    if not isinstance(message, Message) or message.is_top_level:
+                        continue
+                    if is_exempt(message.full_name, ctx.config.get("exemptions")):
+                        continue
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=method.line,
+                        element=method.full_name,
+                        message=f"{kind} type {message.full_name} of rpc {method.name} is a nested message; "
+                                f"move it to the top level",
+                    ))
+    return violations
+
+
+@register_check("group_fields", "Messages and extensions must not use proto2 group syntax")
+def check_group_fields(ctx: CheckContext) -> List[Violation]:
+    violations = []
+    for proto_file in ctx.schema.files:
+        fields = [f for message in proto_file.all_messages() for f in message.fields]
+        fields.extend(proto_file.all_extensions())
+        for group in fields:
+            if not group.is_group:
+                continue
+            group_type = f"{group.scope}.{group.type_name}" if group.scope else group.type_name
+            kind = f"extension of {group.extendee}" if group.extendee else "field"
+            violations.append(Violation(
+                file=proto_file.path,
+                line=group.line,
+                element=group.full_name,
+                message=f"group {group.type_name} ({kind} {group.full_name} = {group.number}) uses deprecated "
+                        f"group syntax; declare message {group_type} and a message field instead",
+            ))
+    return violations
+
+
+@register_check("file_size", "Proto files must not exceed a maximum number of lines or top-level definitions")
+def check_file_size(ctx: CheckContext) -> List[Violation]:
+    # 0 means unlimited
+    limits = {}
+    for key in ("max_lines", "max_definitions"):
+        limit = ctx.config.get(key, 0)
+        if not isinstance(limit, int) or limit < 0:
+            raise CheckConfigError(f"file_size requires {key} to be a non-negative integer")
+        limits[key] = limit
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        counts = [
+            ("lines", proto_file.line_count, limits["max_lines"]),
+            ("top-level definitions", proto_file.top_level_definition_count(), limits["max_definitions"]),
+        ]
+        for what, count, limit in counts:
+            if limit and count > limit:
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=1,
+                    element=proto_file.path,
+                    message=f"file has {count} {what} (limit {limit}); consider splitting it into smaller files",
+                ))
+    return violations
+
+
+LINE_ENDINGS = {"lf": b"\n", "crlf": b"\r\n"}
+
+
+def _line_ending_config(config: Dict[str, Any]) -> Tuple[str, bool]:
+    style = config.get("line_ending", "lf")
+    if style not in ("lf", "crlf", "consistent"):
+        raise CheckConfigError(f"line_endings requires line_ending to be 'lf', 'crlf' or 'consistent', got {style!r}")
+    final_newline = config.get("final_newline", True)
+    if not isinstance(final_newline, bool):
+        raise CheckConfigError("line_endings requires final_newline to be a boolean")
+    return style, final_newline
+
+
+def _split_line_endings(data: bytes) -> List[Tuple[int, bytes]]:
+    """Returns the (1-based line, terminator) of every line break in data."""
+    return [(line, match.group()) for line, match in enumerate(re.finditer(rb"\r\n|\r|\n", data), 1)]
+
+
+def _majority_ending(endings: List[Tuple[int, bytes]]) -> bytes:
+    # LF wins ties, and files without line breaks
+    crlf = sum(1 for _, ending in endings if ending == b"\r\n")
+    return b"\r\n" if crlf > len(endings) - crlf else b"\n"
+
+
+@register_check("line_endings", "Proto files must end with a newline and use consistent line endings")
+def check_line_endings(ctx: CheckContext) -> List[Violation]:
+    # The parser reads files with universal newlines, so the raw bytes are checked here
+    style, final_newline = _line_ending_config(ctx.config)
+    names = {b"\n": "LF", b"\r\n": "CRLF", b"\r": "CR"}
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        data = Path(proto_file.path).read_bytes()
+        endings = _split_line_endings(data)
+        expected = LINE_ENDINGS.get(style) or _majority_ending(endings)
+        wrong = [(line, ending) for line, ending in endings if ending != expected]
+        if wrong:
+            line, ending = wrong[0]
+            violations.append(Violation(
+                file=proto_file.path,
+                line=line,
+                element=proto_file.path,
+                message=f"{len(wrong)} line(s) end with {names[ending]} instead of {names[expected]}, "
+                        f"first at line {line}",
+            ))
+        if final_newline and data and not data.endswith((b"\n", b"\r")):
+            violations.append(Violation(
+                file=proto_file.path,
+                line=len(endings) + 1,
+                element=proto_file.path,
+                message="file does not end with a newline",
+            ))
+    return violations
+
+
+def fix_line_endings(data: bytes, config: Dict[str, Any]) -> bytes:
+    """Returns data rewritten to satisfy the line_endings check with the given config."""
+    style, final_newline = _line_ending_config(config)
+    endings = _split_line_endings(data)
+    expected = LINE_ENDINGS.get(style) or _majority_ending(endings)
+    fixed = re.sub(rb"\r\n|\r|\n", expected, data)
+    if final_newline and fixed and not fixed.endswith(expected):
+        fixed += expected
+    return fixed
+
+
+# Checks that can rewrite the offending files themselves, used by --fix
+FIXERS: Dict[str, Callable[[bytes, Dict[str, Any]], bytes]] = {
+    "line_endings": fix_line_endings,
+}
+
+
+@register_check("case_insensitive_type_names", "Type names in a package must not differ only in case")
+def check_case_insensitive_type_names(ctx: CheckContext) -> List[Violation]:
+    target_files = {proto_file.path for proto_file in ctx.schema.files}
+    seen: Dict[str, Tuple[str, str, int]] = {}
+    violations = []
+    # Dependency files come last, so collisions are reported in the target's own files
+    for proto_file in ctx.schema.files + ctx.schema.dep_files:
+        types = [("message", m) for m in proto_file.all_messages() if not m.is_map_entry]
+        types += [("enum", e) for e in proto_file.all_enums()]
+        for kind, element in sorted(types, key=lambda t: t[1].line):
+            key = element.full_name.lower()
+            if key not in seen:
+                seen[key] = (element.full_name, proto_file.path, element.line)
+                continue
+            other_name, other_file, other_line = seen[key]
+            if other_name == element.full_name:
+                continue  # Redefinitions are reported by protoc
+            if proto_file.path in target_files:
+                file, line, name, other = proto_file.path, element.line, element.full_name, f"{other_name} ({other_file}:{other_line})"
+            elif other_file in target_files:
+                file, line, name, other = other_file, other_line, other_name, f"{element.full_name} ({proto_file.path}:{element.line})"
+            else:
+                continue
+            violations.append(Violation(
+                file=file,
+                line=line,
+                element=name,
+                message=f"{kind} {name} differs only in case from {other}",
+            ))
+    return violations
+
+
+@register_check("json_name_collisions", "Fields of a message must not share a JSON name")
+def check_json_name_collisions(ctx: CheckContext) -> List[Violation]:
+    proto_names = ctx.config.get("proto_names", True)
+    if not isinstance(proto_names, bool):
+        raise CheckConfigError("json_name_collisions requires a boolean proto_names")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            if message.is_map_entry:
+                continue
+            seen: Dict[str, Field] = {}
+            for message_field in message.fields:
+                # JSON parsers also accept the original field name, so it may not
+                # match the JSON name of another field either
+                keys = [message_field.json_name]
+                if proto_names and message_field.name != message_field.json_name:
+                    keys.append(message_field.name)
+                for key in keys:
+                    other = seen.get(key)
+                    if other is None or other is message_field:
+                        continue
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=message_field.line,
+                        element=message_field.full_name,
+                        message=f"field {message_field.name} (JSON name \"{message_field.json_name}\") and field "
+                                f"{other.name} (JSON name \"{other.json_name}\", line {other.line}) "
+                                f"of {message.full_name} both map to JSON key \"{key}\"",
+                    ))
+                    break
+                for key in keys:
+                    seen.setdefault(key, message_field)
+    return violations
+
+
+@register_check("package_depth", "Packages must have at least a minimum number of components")
+def check_package_depth(ctx: CheckContext) -> List[Violation]:
+    min_depth = ctx.config.get("min_depth", 3)
+    if not isinstance(min_depth, int) or min_depth <= 0:
+        raise CheckConfigError("package_depth requires a positive integer min_depth")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        depth = len(proto_file.package.split(".")) if proto_file.package else 0
+        if depth >= min_depth:
+            continue
+        description = f"package {proto_file.package}" if proto_file.package else "file has no package and"
+        violations.append(Violation(
+            file=proto_file.path,
+            line=proto_file.package_line,
+            element=proto_file.package or proto_file.path,
+            message=f"{description} has {depth} component{'s' if depth != 1 else ''} "
+                    f"(minimum {min_depth}, e.g. org.domain.v1)",
+        ))
+    return violations
+
+
+@register_check("repeated_cardinality", "Repeated message and map fields must declare an expected maximum count")
+def check_repeated_cardinality(ctx: CheckContext) -> List[Violation]:
+    option = ctx.config.get("option", "buck2protobuf.sizing.v1.max_count")
+    include_scalars = ctx.config.get("include_scalars", False)
+    if not option:
+        raise CheckConfigError("repeated_cardinality requires an option name")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            if message.is_map_entry:
+                continue
+            for message_field in message.fields:
+                if not message_field.is_repeated:
+                    continue
+                is_message = isinstance(ctx.schema.resolve_type(message_field.type_name, message.full_name), Message)
+                if not (message_field.is_map or is_message or include_scalars):
+                    continue
+                value = find_option(message_field.options, option)
+                if value is None:
+                    problem = f"has no ({option}) annotation"
+                elif not isinstance(value, int) or isinstance(value, bool) or value <= 0:
+                    problem = f"has ({option}) = {value}, expected a positive count"
+                else:
+                    continue
+                kind = "map" if message_field.is_map else "repeated"
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=message_field.line,
+                    element=message_field.full_name,
+                    message=f"{kind} field {message_field.full_name} {problem}",
+                ))
+    return violations
+
+
+# Phrases that describe what a map key is, e.g. "keyed by user ID" or "map from SKU to item"
+DEFAULT_MAP_KEY_PATTERN = r"\bkey(s|ed)?\b|\b(indexed|identified) by\b|\b(maps?|mapping) (from|of)\b"
+
+
+@register_check("map_key_docs", "Map fields must have a leading comment that describes the key")
+def check_map_key_docs(ctx: CheckContext) -> List[Violation]:
+    try:
+        pattern = re.compile(ctx.config.get("key_pattern") or DEFAULT_MAP_KEY_PATTERN, re.IGNORECASE)
+    except re.error as e:
+        raise CheckConfigError(f"map_key_docs key_pattern is invalid: {e}")
+    message_values_only = ctx.config.get("message_values_only", False)
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            for message_field in message.fields:
+                if not message_field.is_map:
+                    continue
+                if message_values_only and not isinstance(
+                        ctx.schema.resolve_type(message_field.map_value_type, message.full_name), Message):
+                    continue
+                comment = message_field.leading_comment.strip()
+                if not comment:
+                    problem = "has no leading comment describing its key"
+                elif not pattern.search(comment):
+                    problem = "has a leading comment that does not describe its key"
+                else:
+                    continue
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=message_field.line,
+                    element=message_field.full_name,
+                    message=f"map field {message_field.full_name} {problem}",
+                ))
+    return violations
+
+
+# Words telling readers that at most one member of a oneof is set
+DEFAULT_ONEOF_KEYWORDS = ["exclusive", "only one", "at most one", "exactly one", "one of"]
+
+
+@register_check("oneof_exclusivity_docs", "Oneofs must have a leading comment stating that their members are mutually exclusive")
+def check_oneof_exclusivity_docs(ctx: CheckContext) -> List[Violation]:
+    keywords = ctx.config.get("keywords") or DEFAULT_ONEOF_KEYWORDS
+    if not isinstance(keywords, list) or not all(isinstance(k, str) and k.strip() for k in keywords):
+        raise CheckConfigError(f"oneof_exclusivity_docs keywords must be a list of non-empty strings, got {keywords!r}")
+    pattern = re.compile("|".join(rf"\b{re.escape(k.strip())}" for k in keywords), re.IGNORECASE)
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            for oneof in message.oneofs:
+                comment = oneof.leading_comment.strip()
+                if not comment:
+                    problem = "has no leading comment stating that its members are mutually exclusive"
+                elif not pattern.search(comment):
+                    problem = "has a leading comment that does not mention exclusivity"
+                else:
+                    continue
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=oneof.line,
+                    element=oneof.full_name,
+                    message=f"oneof {oneof.full_name} {problem} (expected one of: {', '.join(keywords)})",
+                ))
+    return violations
+
+
+# Options message extended by custom options on each kind of element
+OPTION_EXTENDEES = {
+    "file": "FileOptions",
+    "message": "MessageOptions",
+    "field": "FieldOptions",
+    "extension": "FieldOptions",
+    "oneof": "OneofOptions",
+    "enum": "EnumOptions",
+    "enum value": "EnumValueOptions",
+    "service": "ServiceOptions",
+    "method": "MethodOptions",
+}
+
+
+def _resolve_extension(name: str, scope: str, extensions: Dict[str, Any]) -> Optional[str]:
+    """Resolves an option extension name using protobuf scoping rules."""
+    if name.startswith("."):
+        return name[1:] if name[1:] in extensions else None
+    parts = scope.split(".") if scope else []
+    while True:
+        candidate = ".".join(parts + [name])
+        if candidate in extensions:
+            return candidate
+        if not parts:
+            return None
+        parts.pop()
+
+
+def _required_field(schema: SchemaSet, message: Message, seen: Set[str]) -> Optional[Field]:
+    """Returns a required field of a message, declared directly or in a message it contains."""
+    if message.full_name in seen:
+        return None
+    seen.add(message.full_name)
+    for message_field in message.fields:
+        if message_field.label == "required":
+            return message_field
+    for message_field in message.fields:
+        type_name = message_field.map_value_type if message_field.is_map else message_field.type_name
+        nested = schema.resolve_type(type_name, message.full_name)
+        if isinstance(nested, Message):
+            required = _required_field(schema, nested, seen)
+            if required is not None:
+                return required
+    return None
+
+
+def _proto2_message_usages(
+    ctx: CheckContext,
+    describe: Callable[[Message], Optional[str]],
+) -> List[Violation]:
+    """
+    Reports proto3 fields, map values and RPC types that use a proto2 message.
+
+    Args:
+        ctx: Check context
+        describe: Returns the hazard of a proto2 message (completing "proto2
+                  message X (import ...), ..."), or None if it is safe to use
+
+    Returns:
+        One violation per using field or RPC
+    """
+    def proto2_usage(proto_file: ProtoFile, type_name: str, scope: str) -> Optional[str]:
+        """Describes the hazard of using a type from a proto3 file, or returns None."""
+        used = ctx.schema.resolve_type(type_name, scope)
+        if not isinstance(used, Message):
+            return None
+        defining_file = ctx.schema.file_for_type(used.full_name)
+        if defining_file is None or defining_file.syntax != "proto2":
+            return None
+        hazard = describe(used)
+        if hazard is None:
+            return None
+        imports = [i for i in proto_file.imports if ctx.schema.find_import(i) is defining_file]
+        if imports:
+            line = proto_file.import_lines.get(imports[0], 0)
+            source = f"import \"{imports[0]}\"" + (f" at line {line}" if line else "")
+        else:
+            source = f"proto2 file {defining_file.path}"
+        return f"proto2 message {used.full_name} ({source}), {hazard}"
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        if proto_file.syntax != "proto3":
+            continue
+        for message in proto_file.all_messages():
+            for message_field in message.fields:
+                type_name = message_field.map_value_type if message_field.is_map else message_field.type_name
+                usage = proto2_usage(proto_file, type_name, message.full_name)
+                if usage:
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=message_field.line,
+                        element=message_field.full_name,
+                        message=f"proto3 field {message_field.full_name} uses {usage}",
+                    ))
+        for service in proto_file.services:
+            for method in service.methods:
+                for kind, type_name in [("request", method.input_type), ("response", method.output_type)]:
+                    usage = proto2_usage(proto_file, type_name, service.full_name)
+                    if usage:
+                        violations.append(Violation(
+                            file=proto_file.path,
+                            line=method.line,
+                            element=method.full_name,
+                            message=f"proto3 RPC {method.full_name} {kind} is {usage}",
+                        ))
+    return violations
+
+
+@register_check("proto2_required_in_proto3", "proto3 files must not use proto2 messages that have required fields")
+def check_proto2_required_in_proto3(ctx: CheckContext) -> List[Violation]:
+    def describe(message: Message) -> Optional[str]:
+        required = _required_field(ctx.schema, message, set())
+        return f"which has required field {required.full_name}" if required else None
+
+    return _proto2_message_usages(ctx, describe)
+
+
+def _format_default(message_field: Field) -> str:
+    """Renders an explicit default as written in the .proto file."""
+    default = message_field.default
+    if isinstance(default, bool):
+        return "true" if default else "false"
+    if message_field.type_name in ("string", "bytes"):
+        return json.dumps(str(default))
+    return str(default)
+
+
+def _defaulted_fields(schema: SchemaSet, message: Message, seen: Set[str]) -> List[Field]:
+    """Returns fields with explicit defaults of a proto2 message and the proto2 messages it contains."""
+    if message.full_name in seen:
+        return []
+    seen.add(message.full_name)
+    defaulted = [f for f in message.fields if f.default is not None]
+    for message_field in message.fields:
+        type_name = message_field.map_value_type if message_field.is_map else message_field.type_name
+        nested = schema.resolve_type(type_name, message.full_name)
+        if isinstance(nested, Message):
+            defining_file = schema.file_for_type(nested.full_name)
+            if defining_file is not None and defining_file.syntax == "proto2":
+                defaulted.extend(_defaulted_fields(schema, nested, seen))
+    return defaulted
+
+
+@register_check("proto2_defaults_in_proto3", "proto3 files must not use proto2 messages whose fields have explicit defaults")
+def check_proto2_defaults_in_proto3(ctx: CheckContext) -> List[Violation]:
+    def describe(message: Message) -> Optional[str]:
+        defaulted = _defaulted_fields(ctx.schema, message, set())
+        if not defaulted:
+            return None
+        defaults = ", ".join(f"{f.full_name} = {_format_default(f)}" for f in defaulted)
+        return f"whose explicit defaults proto3 does not have: {defaults}"
+
+    return _proto2_message_usages(ctx, describe)
+
+
+# Message types that make a field nullable on the wire and in generated code
+NULLABLE_TYPES = {
+    "google.protobuf.DoubleValue", "google.protobuf.FloatValue", "google.protobuf.Int64Value",
+    "google.protobuf.UInt64Value", "google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+    "google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue",
+    "google.protobuf.Value", "google.protobuf.NullValue",
+}
+
+
+@register_check("crud_required_annotations", "Fields must not be required on both create and update, or required on create and nullable")
+def check_crud_required_annotations(ctx: CheckContext) -> List[Violation]:
+    create_option = ctx.config.get("create_option", "buck2protobuf.crud.v1.required_on_create")
+    update_option = ctx.config.get("update_option", "buck2protobuf.crud.v1.required_on_update")
+    if not create_option or not update_option:
+        raise CheckConfigError("crud_required_annotations requires create_option and update_option")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for message in proto_file.all_messages():
+            for message_field in message.fields:
+                on_create = find_option(message_field.options, create_option) is True
+                on_update = find_option(message_field.options, update_option) is True
+                problem = None
+                if on_create and on_update:
+                    problem = f"is marked both ({create_option}) and ({update_option})"
+                elif on_create:
+                    if message_field.label == "optional" and proto_file.syntax == "proto3":
+                        problem = f"is marked ({create_option}) but is nullable (proto3 optional)"
+                    else:
+                        resolved = ctx.schema.resolve_type_name(message_field.type_name, message.full_name)
+                        if resolved in NULLABLE_TYPES:
+                            problem = f"is marked ({create_option}) but is nullable ({resolved})"
+                if problem:
+                    violations.append(Violation(
+                        file=proto_file.path,
+                        line=message_field.line,
+                        element=message_field.full_name,
+                        message=f"field {message_field.full_name} {problem}",
+                    ))
+    return violations
+
+
+@register_check("registered_options", "Custom options must be defined by an extension in the target or its dependencies")
+def check_registered_options(ctx: CheckContext) -> List[Violation]:
+    extensions = {}
+    for proto_file in ctx.schema.all_files:
+        for extension in proto_file.all_extensions():
+            extensions[extension.full_name] = extension
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for kind, name, line, options in iter_option_owners(proto_file):
+            scope = proto_file.package if kind == "file" else name
+            extendee = OPTION_EXTENDEES[kind]
+            for option_name in options:
+                match = re.match(r"^\((\.?[\w.]+)\)", option_name)
+                if not match:
+                    continue
+                extension_name = match.group(1)
+                resolved = _resolve_extension(extension_name, scope, extensions)
+                if resolved is None:
+                    message = (f"custom option {option_name} on {kind} {name} is not defined: no extension "
+                               f"{extension_name.lstrip('.')} of google.protobuf.{extendee} in the target or its "
+                               f"dependencies (missing dependency?)")
+                elif (extensions[resolved].extendee or "").split(".")[-1] != extendee:
+                    message = (f"custom option {option_name} on {kind} {name} extends "
+                               f"{extensions[resolved].extendee}, not google.protobuf.{extendee}")
+                else:
+                    continue
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=line,
+                    element=name,
+                    message=message,
+                ))
+    return violations
+
+
+# Import path prefixes of commonly used external modules
+KNOWN_MODULE_PREFIXES = {
+    "buf.build/protocolbuffers/wellknowntypes": ["google/protobuf/"],
+    "buf.build/googleapis/googleapis": ["google/api/", "google/rpc/", "google/type/", "google/longrunning/"],
+    "buf.build/bufbuild/protovalidate": ["buf/validate/"],
+    "buf.build/envoyproxy/protoc-gen-validate": ["validate/"],
+    "buf.build/grpc-ecosystem/grpc-gateway": ["protoc-gen-openapiv2/"],
+}
+
+
+def import_module(import_path: str, module_prefixes: Dict[str, List[str]]) -> str:
+    """
+    Returns the module an import path belongs to.
+
+    The longest matching prefix in module_prefixes wins; paths outside every
+    known module belong to the module named after their first directory.
+    """
+    best, best_length = "", -1
+    for module, prefixes in module_prefixes.items():
+        for prefix in prefixes:
+            if import_path.startswith(prefix) and len(prefix) > best_length:
+                best, best_length = module, len(prefix)
+    if best:
+        return best
+    return import_path.split("/", 1)[0] if "/" in import_path else import_path
+
+
+@register_check("import_allowlist", "Imports must resolve to files in the target or in an allowlisted module")
+def check_import_allowlist(ctx: CheckContext) -> List[Violation]:
+    allowed = set(ctx.config.get("allowed_modules", []))
+    module_prefixes = dict(KNOWN_MODULE_PREFIXES)
+    module_prefixes.update(ctx.config.get("module_prefixes", {}))
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for import_path in proto_file.imports:
+            # Imports between the checked target's own files are always allowed
+            resolved = ctx.schema.find_import(import_path)
+            if resolved is not None and resolved in ctx.schema.files:
+                continue
+            module = import_module(import_path, module_prefixes)
+            if module in allowed:
+                continue
+            violations.append(Violation(
+                file=proto_file.path,
+                line=proto_file.import_lines.get(import_path, 0),
+                element=import_path,
+                message=f"import \"{import_path}\" from module {module} is not in the dependency allowlist",
+            ))
+    return violations
+
+
+# Well-known types bundled with protoc, by the name used in proto_library(well_known_types = [...])
+WELL_KNOWN_TYPE_FILES = {
+    name: f"google/protobuf/{name}.proto"
+    for name in (
+        "any", "api", "compiler/plugin", "descriptor", "duration", "empty", "field_mask",
+        "source_context", "struct", "timestamp", "type", "wrappers",
+    )
+}
+
+
+@register_check("well_known_type_subset", "Imports of well-known types must be in the subset declared by the library")
+def check_well_known_type_subset(ctx: CheckContext) -> List[Violation]:
+    declared = ctx.config.get("allowed", [])
+    if not isinstance(declared, list) or any(name not in WELL_KNOWN_TYPE_FILES for name in declared):
+        raise CheckConfigError(f"well_known_type_subset allowed must list names from {sorted(WELL_KNOWN_TYPE_FILES)}, "
+                               f"got {declared!r}")
+    allowed = {WELL_KNOWN_TYPE_FILES[name] for name in declared}
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for import_path in proto_file.imports:
+            if not import_path.startswith("google/protobuf/") or import_path in allowed:
+                continue
+            name = next((n for n, path in WELL_KNOWN_TYPE_FILES.items() if path == import_path), None)
+            hint = f"add \"{name}\" to well_known_types" if name else "it is not a well-known type bundled with protoc"
+            violations.append(Violation(
+                file=proto_file.path,
+                line=proto_file.import_lines.get(import_path, 0),
+                element=import_path,
+                message=f"import \"{import_path}\" is not in the declared well-known types "
+                        f"[{', '.join(declared)}]; {hint}",
+            ))
+    return violations
+
+
+@register_check("bare_wkt_responses", "RPC responses must be dedicated messages rather than bare well-known types")
+def check_bare_wkt_responses(ctx: CheckContext) -> List[Violation]:
+    allowed = ctx.config.get("allowed", ["google.protobuf.Empty"])
+    if not isinstance(allowed, list) or any(name not in WELL_KNOWN_TYPES for name in allowed):
+        raise CheckConfigError(f"bare_wkt_responses allowed must list well-known type names such as "
+                               f"google.protobuf.Empty, got {allowed!r}")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for service in proto_file.services:
+            for method in service.methods:
+                full_name = ctx.schema.resolve_type_name(method.output_type, service.full_name)
+                if full_name not in WELL_KNOWN_TYPES or full_name in allowed:
+                    continue
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=method.line,
+                    element=method.full_name,
+                    message=f"rpc {method.full_name} returns the bare well-known type {full_name}; "
+                            f"return a dedicated {method.name}Response message so fields can be added later",
+                ))
+    return violations
+
+
+# [TypeName] references in doc comments: optional package components, then
+# PascalCase type components; markdown links such as [text](url) are skipped
+DEFAULT_TYPE_REFERENCE_PATTERN = r"\[(\.?(?:[a-z]\w*\.)*[A-Z](?=\w*[a-z])\w*(?:\.[A-Z]\w*)*)\](?![(\[])"
+
+
+def _resolve_reference(reference: str, scope: str, known: Set[str]) -> Optional[str]:
+    """Resolves a comment type reference like a type name written in the scope."""
+    if reference.startswith("."):
+        return reference[1:] if reference[1:] in known else None
+    parts = scope.split(".") if scope else []
+    while True:
+        candidate = ".".join(parts + [reference])
+        if candidate in known:
+            return candidate
+        if not parts:
+            return None
+        parts.pop()
+
+
+def _comment_line(source_lines: List[str], element_line: int, text: str) -> int:
+    """
+    Returns the line of the comment on an element that contains text.
+
+    Checks the element's own line (trailing comment), then the comment lines
+    directly above it, falling back to the element line.
+    """
+    if element_line <= len(source_lines) and text in source_lines[element_line - 1]:
+        return element_line
+    line = element_line - 1
+    while line >= 1:
+        stripped = source_lines[line - 1].strip()
+        if not (stripped.startswith(("//", "/*", "*")) or stripped.endswith("*/")):
+            break
+        if text in stripped:
+            return line
+        line -= 1
+    return element_line
+
+
+@register_check("comment_type_references", "[TypeName] references in doc comments must resolve to types in the target or its dependencies")
+def check_comment_type_references(ctx: CheckContext) -> List[Violation]:
+    try:
+        pattern = re.compile(ctx.config.get("pattern", DEFAULT_TYPE_REFERENCE_PATTERN))
+    except re.error as e:
+        raise CheckConfigError(f"comment_type_references pattern is not a valid regular expression: {e}")
+    if pattern.groups != 1:
+        raise CheckConfigError("comment_type_references pattern must have exactly one group capturing the type name")
+
+    known = set(ctx.schema.types) | WELL_KNOWN_TYPES
+    for proto_file in ctx.schema.all_files:
+        known.update(service.full_name for service in proto_file.services)
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        source_lines = None
+        for kind, name, line, comment in iter_commented_elements(proto_file):
+            for match in pattern.finditer(comment):
+                reference = match.group(1)
+                if _resolve_reference(reference, name, known):
+                    continue
+                if source_lines is None:
+                    source_lines = Path(proto_file.path).read_text(encoding="utf-8").splitlines()
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=_comment_line(source_lines, line, match.group(0)),
+                    element=name,
+                    message=f"comment on {kind} {name} references [{reference}], which is not a type "
+                            f"in the target or its dependencies",
+                ))
+    return violations
+
+
+ENUM_OPENNESS = ["closed", "open"]
+
+
+def _enum_type_feature(options: Dict[str, Any]) -> Optional[str]:
+    """Returns the features.enum_type set in options, in either option syntax."""
+    value = find_option(options, "features.enum_type")
+    if value is None:
+        features = find_option(options, "features")
+        value = features.get("enum_type") if isinstance(features, dict) else None
+    return value.lower() if isinstance(value, str) else None
+
+
+def _enum_openness(schema: SchemaSet, proto_file: ProtoFile, enum: Enum) -> Tuple[str, str]:
+    """Returns whether an enum is open or closed, and what decided it."""
+    if proto_file.syntax == "proto2":
+        return "closed", "proto2"
+    if proto_file.syntax == "proto3":
+        return "open", "proto3"
+    # Editions resolve features from the innermost scope that sets them
+    openness = _enum_type_feature(enum.options)
+    if openness:
+        return openness, "features.enum_type on the enum"
+    scope = enum.parent
+    while scope:
+        message = schema.types.get(scope)
+        if not isinstance(message, Message):
+            break
+        openness = _enum_type_feature(message.options)
+        if openness:
+            return openness, f"features.enum_type on {scope}"
+        scope = message.parent
+    openness = _enum_type_feature(proto_file.options)
+    if openness:
+        return openness, "file features.enum_type"
+    return "open", f"edition {proto_file.edition} default"
+
+
+@register_check("enum_openness", "Enums must be open or closed as required by the policy")
+def check_enum_openness(ctx: CheckContext) -> List[Violation]:
+    policy = ctx.config.get("policy", "closed")
+    if policy not in ENUM_OPENNESS:
+        raise CheckConfigError(f"enum_openness policy must be one of {', '.join(ENUM_OPENNESS)}, got {policy!r}")
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        for enum in proto_file.all_enums():
+            if is_exempt(enum.full_name, ctx.config.get("exemptions")):
+                continue
+            openness, source = _enum_openness(ctx.schema, proto_file, enum)
+            if openness != policy:
+                violations.append(Violation(
+                    file=proto_file.path,
+                    line=enum.line,
+                    element=enum.full_name,
+                    message=f"enum {enum.full_name} is {openness} ({source}), but the policy requires {policy} enums",
+                ))
+    return violations
+
+
+# Well-known types with generic names, by the file defining them. By default
+# they are only reserved in files importing that file.
+GENERIC_WELL_KNOWN_TYPES = {
+    "google.protobuf.Api": "google/protobuf/api.proto",
+    "google.protobuf.Method": "google/protobuf/api.proto",
+    "google.protobuf.Mixin": "google/protobuf/api.proto",
+    "google.protobuf.Value": "google/protobuf/struct.proto",
+    "google.protobuf.Enum": "google/protobuf/type.proto",
+    "google.protobuf.EnumValue": "google/protobuf/type.proto",
+    "google.protobuf.Field": "google/protobuf/type.proto",
+    "google.protobuf.Option": "google/protobuf/type.proto",
+    "google.protobuf.Syntax": "google/protobuf/type.proto",
+    "google.protobuf.Type": "google/protobuf/type.proto",
+}
+
+
+@register_check("wkt_name_shadowing", "Message and enum names must not shadow well-known type names")
+def check_wkt_name_shadowing(ctx: CheckContext) -> List[Violation]:
+    names = ctx.config.get("names")
+    if names is not None and (not isinstance(names, list)
+                              or any(name not in WELL_KNOWN_TYPES for name in names)):
+        raise CheckConfigError(f"wkt_name_shadowing names must list well-known type names such as "
+                               f"google.protobuf.Timestamp, got {names!r}")
+    # Listed names are always reserved; by default generic names only where their file is imported
+    conditional = {} if names is not None else GENERIC_WELL_KNOWN_TYPES
+    shadowed = {name.rsplit(".", 1)[-1]: name for name in (names if names is not None else WELL_KNOWN_TYPES)}
+
+    violations = []
+    for proto_file in ctx.schema.files:
+        # The well-known types themselves
+        if proto_file.package == "google.protobuf":
+            continue
+        local_types = [("message", m) for m in proto_file.all_messages()]
+        local_types += [("enum", e) for e in proto_file.all_enums()]
+        for kind, local_type in local_types:
+            if local_type.name not in shadowed:
+                continue
+            defining_file = conditional.get(shadowed[local_type.name])
+            if defining_file and defining_file not in proto_file.imports:
+                continue
+            violations.append(Violation(
+                file=proto_file.path,
+                line=local_type.line,
+                element=local_type.full_name,
+                message=f"{kind} {local_type.full_name} shadows the well-known type {shadowed[local_type.name]}; "
+                        f"rename it or use the well-known type",
+            ))
+    return violations
+
+
+@register_check("package_owners", "Every file must declare an owning team, and files of a package must agree")
+def check_package_owners(ctx: CheckContext) -> List[Violation]:
+    option = ctx.config.get("owner_option", "buck2protobuf.owner.v1.owner")
+    consistent = ctx.config.get("require_consistent", True)
+    if not option:
+        raise CheckConfigError("package_owners requires an owner_option name")
+    if not isinstance(consistent, bool):
+        raise CheckConfigError(f"package_owners require_consistent must be a bool, got {consistent!r}")
+
+    violations = []
+    # package -> owner -> files declaring it
+    owners: Dict[str, Dict[str, List[ProtoFile]]] = {}
+    for proto_file in sorted(ctx.schema.files, key=lambda f: f.path):
+        owner = find_option(proto_file.options, option)
+        if not isinstance(owner, str) or not owner.strip():
+            violations.append(Violation(
+                file=proto_file.path,
+                line=proto_file.package_line,
+                element=proto_file.path,
+                message=f"file {proto_file.path} does not declare an owner; add option ({option}) = \"<team>\"",
+            ))
+            continue
+        owners.setdefault(proto_file.package, {}).setdefault(owner, []).append(proto_file)
+
+    if consistent:
+        for package, by_owner in sorted(owners.items()):
+            if len(by_owner) < 2:
+                continue
+            summary = ", ".join(f"{owner!r} ({', '.join(f.path for f in files)})"
+                                for owner, files in sorted(by_owner.items()))
+            first = min((f for files in by_owner.values() for f in files), key=lambda f: f.path)
+            violations.append(Violation(
+                file=first.path,
+                line=first.package_line,
+                element=package or first.path,
+                message=f"package {package or '(none)'} has files with different owners: {summary}",
+            ))
+    return violations
+
+
+# Runner
+
+
+def run_check(
+    check: str,
+    files: List[str],
+    config: Dict[str, Any],
+    inputs: Optional[Dict[str, str]] = None,
+    dep_files: Optional[List[str]] = None,
+    baseline_files: Optional[List[str]] = None,
+) -> Dict[str, Any]:
+    """
+    Runs a registered check and returns the report dictionary.
+
+    Args:
+        check: Registered check name
+        files: Proto files being checked
+        config: Check configuration
+        inputs: Named input files (e.g. allowlists)
+        dep_files: Transitive dependency proto files used for resolution only
+        baseline_files: Baseline proto files for checks that compare versions
+
+    Returns:
+        Report dictionary with rule_name, files_checked, totals and violations
+    """
+    if check not in CHECKS:
+        raise CheckConfigError(f"unknown check '{check}' (available: {', '.join(sorted(CHECKS))})")
+
+    schema = load_schema_set(files, dep_files)
+    baseline = load_schema_set(baseline_files) if baseline_files else None
+    ctx = CheckContext(schema=schema, config=config, inputs=inputs or {}, baseline=baseline)
+
+    severity = config.get("severity", "error")
+    exemptions = config.get("exemptions", [])
+    violations = []
+    for violation in CHECKS[check](ctx):
+        if is_exempt(violation.element, exemptions):
+            continue
+        if violation.severity == "error":
+            violation.severity = severity
+        violations.append(violation)
+
+    violations.sort(key=lambda v: (v.file, v.line, v.element, v.message))
+    return {
+        "rule_name": check,
+        "description": CHECK_DESCRIPTIONS[check],
+        "files_checked": files,
+        "total_errors": sum(1 for v in violations if v.severity == "error"),
+        "total_warnings": sum(1 for v in violations if v.severity == "warning"),
+        "violations": [asdict(v) for v in violations],
+    }
+
+
+def fix_files(check: str, files: List[str], config: Dict[str, Any]) -> List[str]:
+    """Rewrites files in place with the fixer of a check and returns the changed ones."""
+    if check not in FIXERS:
+        raise CheckConfigError(f"check '{check}' has no fixer (available: {', '.join(sorted(FIXERS))})")
+    changed = []
+    for path in files:
+        if is_exempt(path, config.get("exemptions")):
+            continue
+        data = Path(path).read_bytes()
+        fixed = FIXERS[check](data, config)
+        if fixed != data:
+            Path(path).write_bytes(fixed)
+            changed.append(path)
+            print(f"fixed {path}", file=sys.stderr)
+    return changed
+
+
+def _parse_inputs(values: List[str]) -> Dict[str, str]:
+    inputs = {}
+    for value in values:
+        key, sep, path = value.partition("=")
+        if not sep:
+            raise CheckConfigError(f"invalid --input '{value}', expected KEY=PATH")
+        inputs[key] = path
+    return inputs
+
+
+def main():
+    """Main entry point for schema lint checks."""
+    parser = argparse.ArgumentParser(description="Run a schema lint check over proto files")
+    parser.add_argument("--check", help="Name of the check to run")
+    parser.add_argument("--config", help="JSON file with check configuration")
+    parser.add_argument("--input", action="append", default=[], help="Named input file as KEY=PATH")
+    parser.add_argument("--dep", action="append", default=[], help="Dependency proto file used for resolution")
+    parser.add_argument("--baseline", action="append", default=[], help="Baseline proto file")
+    parser.add_argument("--output", help="Output JSON report file")
+    parser.add_argument("--list-checks", action="store_true", help="List available checks and exit")
+    parser.add_argument("--fix", action="store_true", help="Rewrite the files in place instead of reporting (checks with a fixer only)")
+    parser.add_argument("files", nargs="*", help="Proto files to check")
+    args = parser.parse_args()
+
+    if args.list_checks:
+        for name in sorted(CHECKS):
+            print(f"{name}: {CHECK_DESCRIPTIONS[name]}")
+        return
+    if not args.check:
+        parser.error("--check is required")
+
+    try:
+        config = {}
+        if args.config:
+            with open(args.config, "r", encoding="utf-8") as f:
+                config = json.load(f)
+        if args.fix:
+            fix_files(args.check, args.files, config)
+            return
+        report = run_check(
+            args.check,
+            args.files,
+            config,
+            inputs=_parse_inputs(args.input),
+            dep_files=args.dep,
+            baseline_files=args.baseline,
+        )
+    except (CheckConfigError, ProtoParseError, OSError, ValueError) as e:
+        print(f"ERROR: {args.check}: {e}", file=sys.stderr)
+        sys.exit(2)
+
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            json.dump(report, f, indent=2)
+            f.write("\n")
+    else:
+        print(json.dumps(report, indent=2))
+
+    for violation in report["violations"]:
+        location = f"{violation['file']}:{violation['line']}" if violation["line"] else violation["file"]
+        print(f"{location}: {violation['severity']}: {violation['message']}", file=sys.stderr)
+
+    if report["total_errors"] > 0:
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
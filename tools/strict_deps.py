@@ -0,0 +1,128 @@
+#!/usr/bin/env python3
+"""
+Strict dependency check for the proto files of one target.
+
+protoc resolves an import against every file on its include path, so a file
+can import something that is only reachable through a dependency of a
+dependency. The target then compiles until that intermediate dependency drops
+the import. This check fails when an import is a file of a target that is not
+a direct `deps` entry, and names that target:
+
+    acme/order.proto:4: imports "acme/money.proto", which is only available
+    transitively (provided by //acme:money_proto); add //acme:money_proto to deps
+
+The JSON report lists every violation:
+
+    {"violations": [{"file": "acme/order.proto", "line": 4,
+                     "import": "acme/money.proto", "provider": "//acme:money_proto"}]}
+
+Imports of files no proto_library declares (bsr_module descriptor sets,
+missing files) are left to protoc.
+
+Usage:
+    strict_deps.py --target //acme:order_proto --output report.json \\
+        --direct acme/user.proto --transitive acme/money.proto=//acme:money_proto \\
+        acme/order.proto=path/to/acme/order.proto
+"""
+
+import argparse
+import json
+import sys
+from pathlib import Path
+from typing import Dict, List
+
+try:
+    from proto_schema import ProtoParseError, parse_proto_file
+except ImportError:
+    sys.path.insert(0, str(Path(__file__).parent))
+    from proto_schema import ProtoParseError, parse_proto_file
+
+# Shipped with protoc, always available without a dep
+WELL_KNOWN_PREFIX = "google/protobuf/"
+
+
+def check_imports(
+    files: Dict[str, str],
+    direct: List[str],
+    transitive: Dict[str, str],
+) -> List[Dict]:
+    """
+    Returns the imports of the target's files that only a transitive dependency provides.
+
+    Args:
+        files: Import path of each file of the target to its file on disk
+        direct: Import paths of the files of the direct dependencies
+        transitive: Import path of each other reachable file to the label of its target
+
+    Returns:
+        Violations as dicts with file, line, import and provider
+    """
+    allowed = set(files) | set(direct)
+    violations = []
+    for import_path in sorted(files):
+        proto_file = parse_proto_file(files[import_path])
+        for imported in proto_file.imports:
+            if imported in allowed or imported.startswith(WELL_KNOWN_PREFIX) or imported not in transitive:
+                continue
+            violations.append({
+                "file": import_path,
+                "line": proto_file.import_lines.get(imported, 0),
+                "import": imported,
+                "provider": transitive.get(imported),
+            })
+    return violations
+
+
+def format_violation(violation: Dict) -> str:
+    """Formats a violation as a file:line diagnostic suggesting the missing dep."""
+    provider = violation["provider"]
+    return (
+        f"{violation['file']}:{violation['line']}: imports \"{violation['import']}\", which is only available "
+        f"transitively (provided by {provider}); add {provider} to deps"
+    )
+
+
+def main():
+    """Main entry point for the strict dependency check."""
+    parser = argparse.ArgumentParser(description="Fail on imports not provided by a direct dependency")
+    parser.add_argument("--target", required=True, help="Label of the checked target")
+    parser.add_argument("--output", required=True, help="Output JSON report")
+    parser.add_argument("--direct", action="append", default=[], help="Import path of a direct dependency file")
+    parser.add_argument("--transitive", action="append", default=[],
+                        help="IMPORT_PATH=LABEL of a file only reachable transitively")
+    parser.add_argument("files", nargs="+", help="Proto files of the target as IMPORT_PATH=PATH")
+    args = parser.parse_args()
+
+    files = {}
+    for value in args.files:
+        import_path, sep, path = value.partition("=")
+        if not sep:
+            parser.error(f"invalid file '{value}', expected IMPORT_PATH=PATH")
+        files[import_path] = path
+    transitive = {}
+    for value in args.transitive:
+        import_path, sep, label = value.partition("=")
+        if not sep:
+            parser.error(f"invalid transitive file '{value}', expected IMPORT_PATH=LABEL")
+        transitive[import_path] = label
+
+    try:
+        violations = check_imports(files, args.direct, transitive)
+    except (OSError, ProtoParseError) as e:
+        print(f"ERROR: strict_deps: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    with open(args.output, "w", encoding="utf-8") as f:
+        json.dump({"target": args.target, "violations": violations}, f, indent=2, sort_keys=True)
+        f.write("\n")
+
+    if violations:
+        print(f"ERROR: strict_deps: {args.target} imports files that are not provided by a direct dependency:",
+              file=sys.stderr)
+        for violation in violations:
+            print(f"  {format_violation(violation)}", file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,147 @@
+#!/usr/bin/env python3
+"""
+Test suite for BSR module resolution (bsr_module).
+"""
+
+import json
+import shutil
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from bsr_module import BSRModuleError, BufExporter, LockedModule, ModuleResolver, load_lockfile, module_digest
+except ImportError:
+    sys.path.append(str(Path(__file__).parent))
+    from bsr_module import BSRModuleError, BufExporter, LockedModule, ModuleResolver, load_lockfile, module_digest
+
+TOOLS_DIR = Path(__file__).parent
+
+MODULES = {
+    "buf.build/acme/payments": {
+        "acme/payments/v1/payment.proto": '''syntax = "proto3";
+package acme.payments.v1;
+import "acme/money/v1/money.proto";
+import "google/protobuf/timestamp.proto";
+message Payment { acme.money.v1.Money amount = 1; }
+''',
+    },
+    "buf.build/acme/money": {
+        "acme/money/v1/money.proto": '''syntax = "proto3";
+package acme.money.v1;
+import "acme/currency/v1/currency.proto";
+message Money { acme.currency.v1.Currency currency = 1; int64 units = 2; }
+''',
+    },
+    "buf.build/acme/currency": {
+        "acme/currency/v1/currency.proto": '''syntax = "proto3";
+package acme.currency.v1;
+enum Currency { CURRENCY_UNSPECIFIED = 0; }
+''',
+    },
+    "buf.build/acme/unused": {
+        "acme/unused/v1/unused.proto": 'syntax = "proto3";\npackage acme.unused.v1;\n',
+    },
+}
+
+DEPS = {
+    "buf.build/acme/payments": ["buf.build/acme/money"],
+    "buf.build/acme/money": ["buf.build/acme/currency"],
+    "buf.build/acme/currency": [],
+    "buf.build/acme/unused": [],
+}
+
+
+class TestBSRModule(unittest.TestCase):
+    """Test lockfile pins, digest verification and recursive resolution."""
+
+    def setUp(self):
+        self.temp_dir = Path(tempfile.mkdtemp())
+        self.cache = self.temp_dir / "cache"
+        self.fetched = []
+        self.lock = {}
+        for name in ["buf.build/acme/currency", "buf.build/acme/money", "buf.build/acme/payments", "buf.build/acme/unused"]:
+            commit = name.rsplit("/", 1)[1] + "0" * 8
+            directory = self.export(name, commit)
+            files = sorted(MODULES[name])
+            digest = module_digest("b5", directory, files, [self.lock[dep].digest for dep in DEPS[name]])
+            self.lock[name] = LockedModule(name, commit, digest)
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def export(self, name: str, commit: str) -> Path:
+        directory = self.cache / name / commit
+        for path, content in MODULES[name].items():
+            (directory / path).parent.mkdir(parents=True, exist_ok=True)
+            (directory / path).write_text(content, encoding="utf-8")
+        (directory / ".complete").write_text("")
+        return directory
+
+    def fetch(self, module: LockedModule) -> Path:
+        self.fetched.append(module.name)
+        return self.cache / module.name / module.commit
+
+    def test_transitive_modules_are_resolved(self):
+        modules = ModuleResolver(self.lock, self.fetch).resolve("buf.build/acme/payments")
+        self.assertEqual([m.name for m in modules],
+                         ["buf.build/acme/payments", "buf.build/acme/money", "buf.build/acme/currency"])
+        self.assertEqual(modules[0].deps, ["buf.build/acme/money"])
+        self.assertEqual(modules[2].files, ["acme/currency/v1/currency.proto"])
+        self.assertNotIn("buf.build/acme/unused", self.fetched)
+
+    def test_modified_file_fails_digest_check(self):
+        money = self.cache / "buf.build/acme/money" / self.lock["buf.build/acme/money"].commit
+        (money / "acme/money/v1/money.proto").write_text("// changed\n" + MODULES["buf.build/acme/money"]["acme/money/v1/money.proto"])
+        with self.assertRaisesRegex(BSRModuleError, "buf.build/acme/money at commit money00000000 has digest"):
+            ModuleResolver(self.lock, self.fetch).resolve("buf.build/acme/payments")
+
+    def test_import_missing_from_lockfile(self):
+        del self.lock["buf.build/acme/currency"]
+        with self.assertRaisesRegex(BSRModuleError, 'buf.build/acme/money imports "acme/currency/v1/currency.proto", '
+                                                    "which no module pinned in buf.lock provides"):
+            ModuleResolver(self.lock, self.fetch).resolve("buf.build/acme/payments")
+
+    def test_commit_must_match_pin(self):
+        with self.assertRaisesRegex(BSRModuleError, "does not match the buf.lock pin payments00000000"):
+            ModuleResolver(self.lock, self.fetch).resolve("buf.build/acme/payments", "deadbeef")
+        with self.assertRaisesRegex(BSRModuleError, "buf.build/acme/billing is not pinned in buf.lock"):
+            ModuleResolver(self.lock, self.fetch).resolve("buf.build/acme/billing")
+
+    def test_lockfile_versions(self):
+        v1 = self.temp_dir / "v1.lock"
+        v1.write_text("version: v1\ndeps:\n  - remote: buf.build\n    owner: acme\n    repository: money\n"
+                      "    commit: abc\n    digest: shake256:00\n")
+        v2 = self.temp_dir / "v2.lock"
+        v2.write_text("version: v2\ndeps:\n  - name: buf.build/acme/money\n    commit: abc\n    digest: b5:00\n")
+        self.assertEqual(load_lockfile(str(v1)), {"buf.build/acme/money": LockedModule("buf.build/acme/money", "abc", "shake256:00")})
+        self.assertEqual(load_lockfile(str(v2))["buf.build/acme/money"].digest, "b5:00")
+
+    def test_offline_cache_miss_does_not_download(self):
+        exporter = BufExporter("/nonexistent/buf", self.temp_dir / "empty", offline=True)
+        with self.assertRaisesRegex(BSRModuleError, "no download was attempted"):
+            exporter(self.lock["buf.build/acme/payments"])
+
+    def test_script_writes_files_and_manifest(self):
+        lockfile = self.temp_dir / "buf.lock"
+        lockfile.write_text("version: v2\ndeps:\n" + "".join(
+            f"  - name: {m.name}\n    commit: {m.commit}\n    digest: {m.digest}\n" for m in self.lock.values()))
+        output_dir = self.temp_dir / "out"
+        manifest = self.temp_dir / "modules.json"
+        result = subprocess.run(
+            [sys.executable, str(TOOLS_DIR / "bsr_module.py"), "--module", "buf.build/acme/money",
+             "--lockfile", str(lockfile), "--output-dir", str(output_dir), "--manifest", str(manifest),
+             "--cache-dir", str(self.cache), "--buf", "/nonexistent/buf"],
+            capture_output=True, text=True, timeout=60,
+        )
+        self.assertEqual(result.returncode, 0, result.stderr)
+        self.assertTrue((output_dir / "acme/currency/v1/currency.proto").is_file())
+        self.assertFalse((output_dir / "acme/payments").exists())
+        modules = json.loads(manifest.read_text())["modules"]
+        self.assertEqual([m["name"] for m in modules], ["buf.build/acme/money", "buf.build/acme/currency"])
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,101 @@
+#!/usr/bin/env python3
+"""
+Test suite for the build-time buf format check.
+"""
+
+import os
+import shutil
+import stat
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from buf_format_check import FormatCheckError, check_files, file_diff, is_excluded, is_generated
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from buf_format_check import FormatCheckError, check_files, file_diff, is_excluded, is_generated
+
+
+UNFORMATTED = 'syntax = "proto3";\npackage acme.v1;\nmessage User {\n  string id=1;\n}\n'
+FORMATTED = 'syntax = "proto3";\n\npackage acme.v1;\n\nmessage User {\n  string id = 1;\n}\n'
+
+
+class TestSelection(unittest.TestCase):
+    """Test which files are checked."""
+
+    def test_generated_files(self):
+        self.assertTrue(is_generated("// Code generated by protoc-gen-proto. DO NOT EDIT.\n" + FORMATTED))
+        self.assertTrue(is_generated("// Copyright Acme\n\n// Code generated by tool. DO NOT EDIT.\n"))
+        self.assertFalse(is_generated(FORMATTED + "// Code generated by tool. DO NOT EDIT.\n"))
+
+    def test_exclude_patterns(self):
+        self.assertTrue(is_excluded("proto/acme/legacy/v1/user.proto", ["acme/legacy"]))
+        self.assertTrue(is_excluded("proto/acme/legacy/v1/user.proto", ["acme/legacy/"]))
+        self.assertTrue(is_excluded("proto/acme/v1/user_test.proto", ["*_test.proto"]))
+        self.assertTrue(is_excluded("acme/v1/user.proto", ["acme/v1/user.proto"]))
+        self.assertFalse(is_excluded("proto/acme/legacy_v2/user.proto", ["acme/legacy"]))
+
+
+class TestCheckFiles(unittest.TestCase):
+    """Test running buf against a fake binary."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.cwd = os.getcwd()
+        os.chdir(self.temp_dir)
+
+    def tearDown(self):
+        os.chdir(self.cwd)
+        shutil.rmtree(self.temp_dir)
+
+    def fake_buf(self, script: str) -> str:
+        path = os.path.join(self.temp_dir, "buf")
+        with open(path, "w", encoding="utf-8") as f:
+            f.write("#!/usr/bin/env python3\nimport sys\n" + script)
+        os.chmod(path, os.stat(path).st_mode | stat.S_IEXEC)
+        return path
+
+    def write(self, path: str, content: str) -> str:
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        Path(path).write_text(content, encoding="utf-8")
+        return path
+
+    def test_stable_diff_of_unformatted_files(self):
+        buf = self.fake_buf(f'assert sys.argv[1] == "format"\nprint({FORMATTED!r}, end="")\n')
+        files = [
+            self.write("acme/v1/user.proto", UNFORMATTED),
+            self.write("acme/v1/clean.proto", FORMATTED),
+            self.write("acme/v1/gen.proto", "// Code generated by tool. DO NOT EDIT.\n" + UNFORMATTED),
+            self.write("acme/legacy/old.proto", UNFORMATTED),
+        ]
+        unformatted = check_files(buf, files, ["acme/legacy"])
+        self.assertEqual([path for path, _, _ in unformatted], ["acme/v1/user.proto"])
+        self.assertEqual(file_diff(*unformatted[0]), (
+            "--- a/acme/v1/user.proto\n"
+            "+++ b/acme/v1/user.proto\n"
+            "@@ -1,5 +1,7 @@\n"
+            ' syntax = "proto3";\n'
+            "+\n"
+            " package acme.v1;\n"
+            "+\n"
+            " message User {\n"
+            "-  string id=1;\n"
+            "+  string id = 1;\n"
+            " }\n"
+        ))
+
+    def test_missing_trailing_newline(self):
+        diff = file_diff("a.proto", "message A {}", "message A {}\n")
+        self.assertIn("-message A {}\n\\ No newline at end of file\n+message A {}\n", diff)
+
+    def test_buf_failure(self):
+        buf = self.fake_buf('sys.exit("acme/v1/user.proto:4:13: syntax error: unexpected \'=\'")\n')
+        path = self.write("acme/v1/user.proto", UNFORMATTED)
+        with self.assertRaisesRegex(FormatCheckError, "syntax error"):
+            check_files(buf, [path], [])
+
+
+if __name__ == "__main__":
+    unittest.main()
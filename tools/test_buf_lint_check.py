@@ -0,0 +1,86 @@
+#!/usr/bin/env python3
+"""
+Test suite for the build-time buf lint check.
+"""
+
+import json
+import os
+import shutil
+import stat
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from buf_lint_check import LintCheckError, lint_config, parse_ignore_only, render_report, run_buf_lint
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from buf_lint_check import LintCheckError, lint_config, parse_ignore_only, render_report, run_buf_lint
+
+
+BUF_OUTPUT = "\n".join(json.dumps(entry) for entry in [
+    {"path": "acme/user.proto", "start_line": 9, "start_column": 3, "type": "FIELD_LOWER_SNAKE_CASE",
+     "message": 'Field name "userName" should be lower_snake_case, such as "user_name".'},
+    {"path": "acme/user.proto", "start_line": 3, "start_column": 1, "type": "PACKAGE_VERSION_SUFFIX",
+     "message": 'Package name "acme" should be suffixed with a correctly formed version, such as "acme.v1".'},
+    {"path": "google/protobuf/timestamp.proto", "start_line": 1, "start_column": 1, "type": "PACKAGE_DIRECTORY_MATCH",
+     "message": "Files with package \"google.protobuf\" must be within a directory \"google/protobuf\"."},
+])
+
+
+class TestLintConfig(unittest.TestCase):
+    """Test the inline buf configuration."""
+
+    def test_stable_config(self):
+        config = lint_config(["DEFAULT"], ["ENUM_ZERO_VALUE_SUFFIX"], ["acme/legacy"],
+                             parse_ignore_only(["FIELD_LOWER_SNAKE_CASE=b.proto", "FIELD_LOWER_SNAKE_CASE=a.proto"]))
+        self.assertEqual(json.loads(config), {"version": "v1", "lint": {
+            "use": ["DEFAULT"],
+            "except": ["ENUM_ZERO_VALUE_SUFFIX"],
+            "ignore": ["acme/legacy"],
+            "ignore_only": {"FIELD_LOWER_SNAKE_CASE": ["a.proto", "b.proto"]},
+        }})
+        self.assertEqual(json.loads(lint_config([], [], [], {}))["lint"], {"use": ["DEFAULT"]})
+
+    def test_invalid_input(self):
+        with self.assertRaisesRegex(LintCheckError, "invalid lint rule or category 'default'"):
+            lint_config(["default"], [], [], {})
+        with self.assertRaisesRegex(LintCheckError, "RULE=PATH"):
+            parse_ignore_only(["FIELD_LOWER_SNAKE_CASE"])
+
+
+class TestRunBufLint(unittest.TestCase):
+    """Test running buf against fake binaries."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def fake_buf(self, script: str) -> str:
+        path = os.path.join(self.temp_dir, "buf")
+        with open(path, "w", encoding="utf-8") as f:
+            f.write("#!/usr/bin/env python3\nimport sys\n" + script)
+        os.chmod(path, os.stat(path).st_mode | stat.S_IEXEC)
+        return path
+
+    def test_sorted_violations_in_target_files(self):
+        buf = self.fake_buf(f'assert sys.argv[1:3] == ["lint", "user.binpb"]\nprint({BUF_OUTPUT!r})\nsys.exit(100)\n')
+        violations = run_buf_lint(buf, "user.binpb", lint_config([], [], [], {}), ["proto/acme/user.proto"])
+        self.assertEqual(render_report(violations), (
+            'acme/user.proto:3:1: [PACKAGE_VERSION_SUFFIX] Package name "acme" should be suffixed with a '
+            'correctly formed version, such as "acme.v1".\n'
+            'acme/user.proto:9:3: [FIELD_LOWER_SNAKE_CASE] Field name "userName" should be lower_snake_case, '
+            'such as "user_name".\n'
+        ))
+
+    def test_buf_failure(self):
+        buf = self.fake_buf('sys.exit("Failure: decode user.binpb: unexpected EOF")\n')
+        with self.assertRaisesRegex(LintCheckError, "unexpected EOF"):
+            run_buf_lint(buf, "user.binpb", lint_config([], [], [], {}), ["acme/user.proto"])
+
+
+if __name__ == "__main__":
+    unittest.main()
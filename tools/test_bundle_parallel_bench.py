@@ -0,0 +1,54 @@
+#!/usr/bin/env python3
+"""
+Test suite for the parallel proto_bundle benchmark schema.
+"""
+
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from bundle_parallel_bench import BenchError, schema_path, write_schema
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from bundle_parallel_bench import BenchError, schema_path, write_schema
+
+
+class TestBenchSchema(unittest.TestCase):
+    """Test the generated schema and BUCK file."""
+
+    def setUp(self):
+        self.temp_dir = Path(tempfile.mkdtemp())
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def test_schema_chains_imports_and_bundles_languages(self):
+        write_schema(self.temp_dir, 50, ["go", "python", "cpp", "typescript"], "first")
+        buck = (self.temp_dir / "BUCK").read_text(encoding="utf-8")
+        self.assertEqual(buck.count(".proto\","), 50)
+        for language in ["go", "python", "cpp", "typescript"]:
+            self.assertIn(f'"{language}": {{', buck)
+        self.assertNotIn('"rust"', buck)
+
+        last = (self.temp_dir / schema_path(49)).read_text(encoding="utf-8")
+        self.assertIn('import "bench/v1/entity_048.proto";', last)
+        self.assertIn("Entity48 parent = 21;", last)
+        self.assertNotIn("import", (self.temp_dir / schema_path(0)).read_text(encoding="utf-8"))
+
+    def test_resalting_changes_every_file(self):
+        write_schema(self.temp_dir, 3, ["go"], "first")
+        before = [(self.temp_dir / schema_path(i)).read_text(encoding="utf-8") for i in range(3)]
+        write_schema(self.temp_dir, 3, ["go"], "second")
+        after = [(self.temp_dir / schema_path(i)).read_text(encoding="utf-8") for i in range(3)]
+        self.assertTrue(all(b != a for b, a in zip(before, after)))
+
+    def test_unknown_language(self):
+        with self.assertRaises(BenchError):
+            write_schema(self.temp_dir, 1, ["java"], "salt")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,76 @@
+#!/usr/bin/env python3
+"""
+Test suite for the generated code size budget check.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from codegen_budget import BudgetError, format_bytes, measure, parse_language_budgets, render_report
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from codegen_budget import BudgetError, format_bytes, measure, parse_language_budgets, render_report
+
+
+class TestCodegenBudget(unittest.TestCase):
+    """Test size accounting and budgets."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, relative: str, size: int) -> str:
+        path = os.path.join(self.temp_dir, relative)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "wb") as f:
+            f.write(b"x" * size)
+        return path
+
+    def test_totals_per_language(self):
+        user = self.write("go/user.pb.go", 300)
+        grpc = self.write("go/user_grpc.pb.go", 100)
+        py = self.write("py/user_pb2.py", 50)
+        usage = measure([("go", "user.pb.go", user), ("go", "user_grpc.pb.go", grpc),
+                         ("python", "user_pb2.py", py), ("go", "user.pb.go", user)])
+        self.assertEqual([(u.language, u.total_bytes) for u in usage], [("go", 400), ("python", 50)])
+        self.assertFalse(any(u.over_budget for u in usage))
+
+    def test_budgets_and_largest_files(self):
+        files = [("go", f"f{i}.pb.go", self.write(f"go/f{i}.pb.go", size)) for i, size in enumerate([10, 500, 200])]
+        files.append(("python", "user_pb2.py", self.write("py/user_pb2.py", 900)))
+        usage = measure(files, budget_bytes=600, language_budgets={"python": 0})
+        go, python = usage
+        self.assertTrue(go.over_budget)
+        self.assertFalse(python.over_budget)
+        report = render_report(usage, top=2)
+        self.assertEqual(report["languages"][0]["largest"], [
+            {"file": "f1.pb.go", "bytes": 500},
+            {"file": "f2.pb.go", "bytes": 200},
+        ])
+
+    def test_directories_are_walked(self):
+        self.write("py/acme/__init__.py", 5)
+        self.write("py/acme/user_pb2.py", 70)
+        usage = measure([("python", "acme", os.path.join(self.temp_dir, "py", "acme"))])
+        self.assertEqual(usage[0].files, [("acme/__init__.py", 5), ("acme/user_pb2.py", 70)])
+
+    def test_parse_language_budgets(self):
+        self.assertEqual(parse_language_budgets(["go=1000", "cpp=0"]), {"go": 1000, "cpp": 0})
+        with self.assertRaises(BudgetError):
+            parse_language_budgets(["go=1MB"])
+
+    def test_format_bytes(self):
+        self.assertEqual(format_bytes(512), "512 B")
+        self.assertEqual(format_bytes(1536), "1.5 KiB")
+        self.assertEqual(format_bytes(3 << 20), "3.0 MiB")
+
+
+if __name__ == "__main__":
+    unittest.main()
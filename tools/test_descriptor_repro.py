@@ -0,0 +1,139 @@
+#!/usr/bin/env python3
+"""
+Test suite for the descriptor set reproducibility check.
+"""
+
+import os
+import shutil
+import stat
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from descriptor_repro import DescriptorError, build_descriptor_set, compare_descriptor_sets, decode, default_json_name
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from descriptor_repro import DescriptorError, build_descriptor_set, compare_descriptor_sets, decode, default_json_name
+
+
+def varint(value: int) -> bytes:
+    out = bytearray()
+    while True:
+        byte = value & 0x7F
+        value >>= 7
+        if value:
+            out.append(byte | 0x80)
+        else:
+            out.append(byte)
+            return bytes(out)
+
+
+def tag(number: int, value) -> bytes:
+    """Encodes an int as a varint field and str/bytes as a length-delimited field."""
+    if isinstance(value, int):
+        return varint(number << 3) + varint(value)
+    data = value.encode("utf-8") if isinstance(value, str) else value
+    return varint(number << 3 | 2) + varint(len(data)) + data
+
+
+def field(name: str, number: int, *extra: bytes) -> bytes:
+    return tag(1, name) + tag(3, number) + tag(4, 1) + tag(5, 9) + b"".join(extra)
+
+
+def descriptor_set(*files: bytes) -> bytes:
+    return b"".join(tag(1, f) for f in files)
+
+
+def user_file(*fields: bytes, extra: bytes = b"") -> bytes:
+    message = tag(1, "User") + b"".join(tag(2, f) for f in fields)
+    return tag(1, "acme/user.proto") + tag(2, "acme.v1") + tag(4, message) + tag(12, "proto3") + extra
+
+
+class TestDecode(unittest.TestCase):
+    """Test wire format decoding."""
+
+    def test_named_and_unknown_fields(self):
+        decoded = decode(descriptor_set(user_file(field("user_id", 1, tag(8, tag(3, 1))))))
+        user_field = decoded["file"][0]["message_type"][0]["field"][0]
+        self.assertEqual(user_field["name"], ["user_id"])
+        self.assertEqual(user_field["number"], [1])
+        self.assertEqual(user_field["options"], [{"#3": [1]}])
+
+    def test_truncated(self):
+        with self.assertRaises(DescriptorError):
+            decode(tag(1, b"\x0a\x05ab"))
+
+    def test_default_json_name(self):
+        self.assertEqual(default_json_name("user_id"), "userId")
+        self.assertEqual(default_json_name("http2_url"), "http2Url")
+
+
+class TestCompare(unittest.TestCase):
+    """Test normalization and comparison."""
+
+    def test_version_specific_metadata_is_ignored(self):
+        baseline = descriptor_set(user_file(field("user_id", 1)))
+        features = tag(50, tag(1, 1))
+        candidate = descriptor_set(
+            user_file(field("user_id", 1, tag(10, "userId"), tag(8, features)), extra=tag(9, b"\x0a\x00") + tag(14, 999)),
+            tag(1, "google/protobuf/descriptor.proto") + tag(2, "google.protobuf"),
+        )
+        self.assertEqual(compare_descriptor_sets(baseline, candidate), [])
+
+    def test_field_order_within_message_is_ignored(self):
+        baseline = descriptor_set(tag(1, "a.proto") + tag(2, "acme"))
+        candidate = descriptor_set(tag(2, "acme") + tag(1, "a.proto"))
+        self.assertEqual(compare_descriptor_sets(baseline, candidate), [])
+
+    def test_reports_semantic_differences(self):
+        baseline = descriptor_set(user_file(field("user_id", 1), field("email", 2, tag(10, "email"))))
+        candidate = descriptor_set(user_file(field("user_id", 1, tag(8, tag(3, 1))), field("email", 2, tag(10, "eMail")),
+                                             field("phone", 3)))
+        self.assertEqual(compare_descriptor_sets(baseline, candidate), [
+            'acme/user.proto > message User > field email: json_name: (unset) -> "eMail"',
+            'acme/user.proto > message User > field phone: only in candidate',
+            'acme/user.proto > message User > field user_id: options: (unset) -> {"#3": [1]}',
+        ])
+
+    def test_well_known_types_opt_in(self):
+        baseline = descriptor_set(tag(1, "google/protobuf/timestamp.proto") + tag(2, "google.protobuf"))
+        candidate = descriptor_set(tag(1, "google/protobuf/timestamp.proto") + tag(2, "google.protobuf")
+                                   + tag(8, tag(11, "google.golang.org/protobuf/types/known/timestamppb")))
+        self.assertEqual(compare_descriptor_sets(baseline, candidate), [])
+        self.assertEqual(len(compare_descriptor_sets(baseline, candidate, include_well_known_types=True)), 1)
+
+
+class TestBuildDescriptorSet(unittest.TestCase):
+    """Test running protoc."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def fake_protoc(self, script: str) -> str:
+        path = os.path.join(self.temp_dir, "protoc")
+        with open(path, "w", encoding="utf-8") as f:
+            f.write("#!/usr/bin/env python3\nimport sys\n" + script)
+        os.chmod(path, os.stat(path).st_mode | stat.S_IEXEC)
+        return path
+
+    def test_writes_and_reads_descriptor_set(self):
+        protoc = self.fake_protoc(
+            'out = [a for a in sys.argv if a.startswith("--descriptor_set_out=")][0].split("=", 1)[1]\n'
+            'open(out, "wb").write(b"\\x0a\\x00")\n'
+        )
+        data = build_descriptor_set(protoc, ["."], ["user.proto"], os.path.join(self.temp_dir, "out.pb"))
+        self.assertEqual(data, b"\x0a\x00")
+
+    def test_protoc_failure(self):
+        protoc = self.fake_protoc('sys.exit("user.proto:3:1: syntax error")\n')
+        with self.assertRaisesRegex(DescriptorError, "syntax error"):
+            build_descriptor_set(protoc, ["."], ["user.proto"], os.path.join(self.temp_dir, "out.pb"))
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,127 @@
+#!/usr/bin/env python3
+"""
+Test suite for protoc checksum pinning.
+"""
+
+import hashlib
+import io
+import json
+import shutil
+import tempfile
+import unittest
+import zipfile
+from contextlib import redirect_stderr
+from pathlib import Path
+from unittest.mock import patch
+
+try:
+    from download_protoc import ChecksumMismatchError, ProtocDownloader
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from download_protoc import ChecksumMismatchError, ProtocDownloader
+
+
+def make_archive() -> bytes:
+    buffer = io.BytesIO()
+    with zipfile.ZipFile(buffer, "w") as archive:
+        archive.writestr("bin/protoc", "#!/bin/sh\necho libprotoc\n")
+    return buffer.getvalue()
+
+
+ARCHIVE = make_archive()
+ARCHIVE_SHA256 = hashlib.sha256(ARCHIVE).hexdigest()
+
+
+class TestProtocChecksumPinning(unittest.TestCase):
+    """Test checksum resolution and verification of protoc archives."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.cache_dir = Path(self.temp_dir) / "cache"
+        self.lockfile = Path(self.temp_dir) / "protoc.lock.json"
+        self.downloads = []
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir, ignore_errors=True)
+
+    def write_lockfile(self, pins):
+        self.lockfile.write_text(json.dumps({"protoc": pins}))
+
+    def fake_download(self, url, output_path, max_retries=3):
+        self.downloads.append(url)
+        output_path.write_bytes(ARCHIVE)
+        return True
+
+    def download(self, downloader, **kwargs):
+        with patch.object(downloader, "download_with_retry", self.fake_download):
+            return downloader.download_protoc("25.1", "linux-x86_64", **kwargs)
+
+    def test_lockfile_pin_is_verified(self):
+        self.write_lockfile({"25.1": {"linux-x86_64": ARCHIVE_SHA256}})
+        downloader = ProtocDownloader(str(self.cache_dir), lockfile=str(self.lockfile))
+        binary = self.download(downloader)
+        self.assertTrue(binary.endswith("bin/protoc"))
+        self.assertEqual((Path(binary).parent.parent / ".sha256").read_text().strip(), ARCHIVE_SHA256)
+
+    def test_mismatch_fails_and_installs_nothing(self):
+        self.write_lockfile({"25.1": {"linux-x86_64": "0" * 64}})
+        downloader = ProtocDownloader(str(self.cache_dir), lockfile=str(self.lockfile))
+        with self.assertRaises(ChecksumMismatchError) as cm:
+            self.download(downloader)
+        self.assertIn(f"expected {'0' * 64}, got {ARCHIVE_SHA256}", str(cm.exception))
+        self.assertFalse((self.cache_dir / "protoc-25.1-linux-x86_64").exists())
+        self.assertFalse((self.cache_dir / "protoc-25.1-linux-x86_64.zip").exists())
+
+    def test_override_wins_over_lockfile(self):
+        self.write_lockfile({"25.1": {"linux-x86_64": "0" * 64}})
+        downloader = ProtocDownloader(str(self.cache_dir), lockfile=str(self.lockfile))
+        self.download(downloader, checksum=ARCHIVE_SHA256.upper(), url="https://mirror.example.com/protoc.zip")
+        self.assertEqual(self.downloads, ["https://mirror.example.com/protoc.zip"])
+
+    def test_url_override_uses_cache(self):
+        self.write_lockfile({"25.1": {"linux-x86_64": ARCHIVE_SHA256}})
+        downloader = ProtocDownloader(str(self.cache_dir), lockfile=str(self.lockfile))
+        first = self.download(downloader, url="https://mirror.example.com/protoc.zip")
+        second = self.download(downloader, url="https://mirror.example.com/protoc.zip")
+        self.assertEqual(first, second)
+        self.assertEqual(len(self.downloads), 1)
+
+        # A version only available from the mirror is cached too
+        with patch.object(downloader, "download_with_retry", self.fake_download):
+            for _ in range(2):
+                downloader.download_protoc("99.0", "linux-x86_64", checksum=ARCHIVE_SHA256,
+                                           url="https://mirror.example.com/protoc-99.zip")
+        self.assertEqual(len(self.downloads), 2)
+
+    def test_missing_pin_warns_with_digest(self):
+        self.write_lockfile({})
+        downloader = ProtocDownloader(str(self.cache_dir), lockfile=str(self.lockfile))
+        stderr = io.StringIO()
+        with redirect_stderr(stderr):
+            self.download(downloader)
+        self.assertIn("no pinned sha256 for protoc 25.1 on linux-x86_64", stderr.getvalue())
+        self.assertIn(ARCHIVE_SHA256, stderr.getvalue())
+
+    def test_cache_is_reverified_against_pin(self):
+        self.write_lockfile({"25.1": {"linux-x86_64": ARCHIVE_SHA256}})
+        downloader = ProtocDownloader(str(self.cache_dir), lockfile=str(self.lockfile))
+        self.download(downloader)
+        self.download(downloader)
+        self.assertEqual(len(self.downloads), 1)
+
+        self.write_lockfile({"25.1": {"linux-x86_64": "0" * 64}})
+        downloader = ProtocDownloader(str(self.cache_dir), lockfile=str(self.lockfile))
+        with self.assertRaises(ChecksumMismatchError):
+            self.download(downloader)
+        self.assertEqual(len(self.downloads), 2)
+
+    def test_committed_lockfile_is_well_formed(self):
+        lock = json.loads((Path(__file__).parent / "protoc.lock.json").read_text())
+        for version, platforms in lock["protoc"].items():
+            for platform, checksum in platforms.items():
+                self.assertRegex(checksum, r"^[0-9a-f]{64}$", f"{version} {platform}")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,56 @@
+#!/usr/bin/env python3
+"""
+Test suite for coverage exclusion markers.
+"""
+
+import unittest
+from pathlib import Path
+
+try:
+    from go_coverage_marker import MarkerError, add_marker
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from go_coverage_marker import MarkerError, add_marker
+
+
+GENERATED = '''// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: acme/user/v1/user.proto
+
+package userv1
+'''
+
+ARENA = '''//go:build goexperiment.arenas
+
+// Code generated by buck2-protobuf. DO NOT EDIT.
+
+package userv1
+'''
+
+
+class TestAddMarker(unittest.TestCase):
+    """Test inserting markers into generated files."""
+
+    def test_comment_marker(self):
+        marked = add_marker(GENERATED, "//coverage:ignore file")
+        self.assertEqual(marked, "//coverage:ignore file\n\n" + GENERATED)
+        self.assertEqual(add_marker(marked, "//coverage:ignore file"), marked)
+
+    def test_build_constraint_marker(self):
+        self.assertEqual(add_marker(GENERATED, "//go:build !coverage\n"), "//go:build !coverage\n\n" + GENERATED)
+
+    def test_build_constraint_is_combined(self):
+        marked = add_marker(ARENA, "//go:build !coverage")
+        self.assertEqual(marked, ARENA.replace("goexperiment.arenas", "(goexperiment.arenas) && (!coverage)"))
+        marked = add_marker(ARENA, "//go:build !coverage\n//nolint:all")
+        self.assertTrue(marked.startswith("//nolint:all\n\n//go:build (goexperiment.arenas) && (!coverage)\n"))
+
+    def test_invalid_markers(self):
+        with self.assertRaisesRegex(MarkerError, "line comments"):
+            add_marker(GENERATED, "/* coverage:ignore */")
+        with self.assertRaisesRegex(MarkerError, "more than one"):
+            add_marker(GENERATED, "//go:build a\n//go:build b")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,107 @@
+#!/usr/bin/env python3
+"""
+Test suite for per-service splitting of generated gRPC Go code.
+"""
+
+import unittest
+from pathlib import Path
+
+try:
+    from go_grpc_split import SplitError, service_file_name, split_services
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from go_grpc_split import SplitError, service_file_name, split_services
+
+
+GENERATED = '''// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// source: acme/user/v1/user.proto
+
+package userv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	UserService_GetUser_FullMethodName = "/acme.user.v1.UserService/GetUser"
+)
+
+type UserServiceClient interface {
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+}
+
+func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+
+var UserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "acme.user.v1.UserService",
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetUser",
+		},
+	},
+}
+
+const (
+	HTTPAdmin_Purge_FullMethodName = "/acme.user.v1.HTTPAdmin/Purge"
+)
+
+type HTTPAdminClient interface {
+	Purge(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+var HTTPAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "acme.user.v1.HTTPAdmin",
+}
+'''
+
+
+class TestSplitServices(unittest.TestCase):
+    """Test splitting generated gRPC code by service."""
+
+    def test_one_file_per_service(self):
+        files = split_services(GENERATED)
+        self.assertEqual(list(files), ["UserService", "HTTPAdmin"])
+        user, admin = files["UserService"], files["HTTPAdmin"]
+        for content in (user, admin):
+            self.assertTrue(content.startswith("// Code generated by protoc-gen-go-grpc. DO NOT EDIT."))
+            self.assertIn("package userv1\n", content)
+            self.assertIn("const _ = grpc.SupportPackageIsVersion7\n", content)
+        self.assertIn("UserService_GetUser_FullMethodName", user)
+        self.assertNotIn("HTTPAdmin", user)
+        self.assertIn("HTTPAdmin_Purge_FullMethodName", admin)
+        self.assertNotIn("UserService", admin)
+        self.assertTrue(admin.endswith('\tServiceName: "acme.user.v1.HTTPAdmin",\n}\n'))
+
+    def test_imports_are_pruned(self):
+        files = split_services(GENERATED)
+        self.assertIn('import (\n\tcontext "context"\n\tgrpc "google.golang.org/grpc"\n'
+                      '\tcodes "google.golang.org/grpc/codes"\n\tstatus "google.golang.org/grpc/status"\n)\n',
+                      files["UserService"])
+        self.assertIn('import (\n\tcontext "context"\n\tgrpc "google.golang.org/grpc"\n'
+                      '\temptypb "google.golang.org/protobuf/types/known/emptypb"\n)\n',
+                      files["HTTPAdmin"])
+
+    def test_rejects_other_files(self):
+        with self.assertRaises(SplitError):
+            split_services("package userv1\n")
+        with self.assertRaises(SplitError):
+            split_services(GENERATED + "\nfunc Extra() {}\n")
+
+    def test_service_file_name(self):
+        self.assertEqual(service_file_name("acme.user.v1.UserService"), "user_service_grpc.pb.go")
+        self.assertEqual(service_file_name("HTTPAdmin"), "http_admin_grpc.pb.go")
+
+
+if __name__ == "__main__":
+    unittest.main()
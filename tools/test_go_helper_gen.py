@@ -0,0 +1,934 @@
+#!/usr/bin/env python3
+"""
+Test suite for Go helper generation.
+"""
+
+import gzip
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from go_helper_gen import GeneratorConfigError, generate, go_camel_case, go_type_name, parse_descriptor_set, parse_stamp
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from go_helper_gen import GeneratorConfigError, generate, go_camel_case, go_type_name, parse_descriptor_set, parse_stamp
+
+
+class GoHelperTestCase(unittest.TestCase):
+    """Base class providing temporary proto files."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, name: str, content: str) -> str:
+        path = os.path.join(self.temp_dir, name)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+        return path
+
+    def generate_one(self, generator: str, path: str, config=None, go_package: str = "") -> str:
+        return generate(generator, [path], config or {}, go_package)[path]
+
+
+class TestNaming(unittest.TestCase):
+    """Test Go identifier derivation."""
+
+    def test_go_camel_case(self):
+        self.assertEqual(go_camel_case("display_name"), "DisplayName")
+        self.assertEqual(go_camel_case("_internal"), "XInternal")
+        self.assertEqual(go_camel_case("http2_port"), "Http2Port")
+
+    def test_nested_type_name(self):
+        self.assertEqual(go_type_name("acme.v1.User.Address", "acme.v1"), "User_Address")
+        self.assertEqual(go_type_name("acme.v1.user_info", "acme.v1"), "UserInfo")
+
+
+class TestJsonCasing(GoHelperTestCase):
+    """Test the json_casing generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            option go_package = "github.com/acme/user/v1;userv1";
+            message User {
+              string display_name = 1;
+              message Address { string zip_code = 1; }
+            }
+        ''')
+
+    def test_methods_for_every_message(self):
+        code = self.generate_one("json_casing", self.proto, {"casing": "snake"})
+        self.assertIn("package userv1", code)
+        self.assertIn("func (x *User) MarshalJSON() ([]byte, error)", code)
+        self.assertIn("func (x *User_Address) UnmarshalJSON(data []byte) error", code)
+        self.assertIn('return strings.Join(words, "_")', code)
+
+    def test_proto_casing_uses_protojson_directly(self):
+        code = self.generate_one("json_casing", self.proto, {"casing": "proto"})
+        self.assertIn("protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)", code)
+        self.assertNotIn("RenameJSON", code)
+
+    def test_header_documents_divergence(self):
+        code = self.generate_one("json_casing", self.proto, {"casing": "kebab"})
+        self.assertIn("diverges from canonical protojson", code)
+
+    def test_proto_casing_header(self):
+        code = self.generate_one("json_casing", self.proto, {"casing": "proto"})
+        self.assertIn("decoding accepts proto field names and JSON names alike", code)
+        self.assertNotIn("sorted order", code)
+        self.assertNotIn("diverges from canonical protojson", code)
+
+    def test_go_package_override(self):
+        code = self.generate_one("json_casing", self.proto, {"casing": "camel"}, "example.com/api/users")
+        self.assertIn("package users", code)
+
+    def test_rejects_unknown_casing(self):
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("json_casing", self.proto, {"casing": "title"})
+
+    def test_no_messages_generates_nothing(self):
+        path = self.write("empty.proto", 'syntax = "proto3";\npackage acme.v1;\nenum Kind { KIND_UNSPECIFIED = 0; }\n')
+        self.assertIsNone(self.generate_one("json_casing", path, {"casing": "snake"}))
+
+
+class TestGrpcObservability(GoHelperTestCase):
+    """Test the grpc_observability generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("svc.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message Req {}
+            service UserService { rpc Get(Req) returns (Req); }
+        ''')
+
+    def test_prometheus_constructor(self):
+        code = self.generate_one("grpc_observability", self.proto, {"metrics": "prometheus"}, "example.com/user/v1")
+        self.assertIn("func NewUserServiceObservableServer(srv UserServiceServer, registry prometheus.Registerer", code)
+        self.assertIn('grpcprom "github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus"', code)
+        self.assertIn("channelzsvc.RegisterChannelzServiceToServer(server)", code)
+        self.assertIn("RegisterUserServiceServer(server, srv)", code)
+
+    def test_otel_constructor(self):
+        code = self.generate_one("grpc_observability", self.proto, {"metrics": "otel"})
+        self.assertIn("provider metric.MeterProvider", code)
+        self.assertIn("otelgrpc.NewServerHandler(otelgrpc.WithMeterProvider(provider))", code)
+
+    def test_files_without_services_generate_nothing(self):
+        path = self.write("types.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage Req {}\n')
+        self.assertIsNone(self.generate_one("grpc_observability", path, {"metrics": "otel"}))
+
+    def test_stream_interceptor_only_for_streaming_services(self):
+        path = self.write("mixed.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Req {}
+            service UnaryService { rpc Get(Req) returns (Req); }
+            service MixedService {
+              rpc Get(Req) returns (Req);
+              rpc Watch(Req) returns (stream Req);
+            }
+        ''')
+        code = self.generate_one("grpc_observability", path, {"metrics": "prometheus"})
+        unary = code[code.index("func NewUnaryServiceObservableServer"):code.index("func NewMixedServiceObservableServer")]
+        mixed = code[code.index("func NewMixedServiceObservableServer"):]
+        self.assertIn("grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor()),\n\t)", unary)
+        self.assertNotIn("StreamServerInterceptor", unary)
+        self.assertIn("grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor()),", mixed)
+
+    def test_rejects_unknown_backend(self):
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("grpc_observability", self.proto, {"metrics": "statsd"})
+
+
+class TestBuildInfo(GoHelperTestCase):
+    """Test the build_info generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.first = self.write("a.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage A {}\n')
+        self.second = self.write("b.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage B {}\n')
+
+    def test_unstamped_build_is_empty(self):
+        code = self.generate_one("build_info", self.first)
+        self.assertIn('Commit:    "",', code)
+        self.assertIn('BuildTime: "",', code)
+        self.assertNotIn("Stamped build", code)
+
+    def test_stamped_commit_and_time(self):
+        stamp = parse_stamp("STABLE_GIT_COMMIT 4f2c9a1e\nBUILD_TIMESTAMP 1700000000\n")
+        code = self.generate_one("build_info", self.first, {"stamp": stamp})
+        self.assertIn('Commit:    "4f2c9a1e",', code)
+        self.assertIn('BuildTime: "2023-11-14T22:13:20Z",', code)
+        self.assertIn("Stamped build", code)
+
+    def test_fixed_build_time_overrides_stamp(self):
+        config = {"stamp": {"BUILD_SCM_REVISION": "abc", "BUILD_TIMESTAMP": "1"}, "build_time": "2024-01-01T00:00:00Z"}
+        code = self.generate_one("build_info", self.first, config)
+        self.assertIn('Commit:    "abc",', code)
+        self.assertIn('BuildTime: "2024-01-01T00:00:00Z",', code)
+
+    def test_emitted_once_per_package(self):
+        results = generate("build_info", [self.first, self.second], {})
+        self.assertIn("var BuildInfo", results[self.first])
+        self.assertIsNone(results[self.second])
+
+
+class TestInitHook(GoHelperTestCase):
+    """Test the init_hook generator."""
+
+    def test_calls_hook_from_another_package(self):
+        path = self.write("user.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage User {}\n')
+        code = self.generate_one("init_hook", path, {"hook": "github.com/acme/plugins/registry/v2.Register"})
+        self.assertIn('registry "github.com/acme/plugins/registry/v2"', code)
+        self.assertIn("registry.Register((&User{}).ProtoReflect().Descriptor().ParentFile())", code)
+
+    def test_local_hook_and_enum_only_file(self):
+        path = self.write("status.proto", 'syntax = "proto3";\npackage acme.v1;\nenum Status { STATUS_UNSPECIFIED = 0; }\n')
+        code = self.generate_one("init_hook", path, {"hook": "registerFile"})
+        self.assertIn("registerFile(Status(0).Descriptor().ParentFile())", code)
+        self.assertNotIn("import", code)
+
+    def test_file_without_go_types_is_skipped(self):
+        path = self.write("svc.proto", 'syntax = "proto3";\npackage acme.v1;\nimport "user.proto";\n'
+                          'service Users { rpc Get(User) returns (User); }\n')
+        self.write("user.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage User {}\n')
+        results = generate("init_hook", [path], {"hook": "registerFile"})
+        self.assertIsNone(results[path])
+
+    def test_invalid_hook(self):
+        path = self.write("user.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage User {}\n')
+        for hook in ["", "github.com/acme/registry.register", "github.com/acme/registry.", "a b.C"]:
+            with self.assertRaises(GeneratorConfigError, msg=hook):
+                self.generate_one("init_hook", path, {"hook": hook})
+
+
+class TestRedaction(GoHelperTestCase):
+    """Test the redaction generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.first = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            import "buck2protobuf/redact/v1/redact.proto";
+            message User {
+              string email = 1 [(buck2protobuf.redact.v1.pii) = REDACTION_MASK];
+              message Address { string street = 1 [(buck2protobuf.redact.v1.pii) = REDACTION_CLEAR]; }
+            }
+        ''')
+        self.second = self.write("page.proto", 'syntax = "proto3";\npackage acme.user.v1;\nmessage Page {}\n')
+
+    def test_redact_method_per_message(self):
+        code = generate("redaction", [self.first], {}, "example.com/user/v1")[self.first]
+        self.assertIn("func (x *User) Redact() *User {", code)
+        self.assertIn("func (x *User_Address) Redact() *User_Address {", code)
+        self.assertIn("c := proto.Clone(x).(*User)", code)
+
+    def test_walker_emitted_once_per_package(self):
+        results = generate("redaction", [self.first, self.second], {})
+        self.assertIn("func redactPIIMessage(m protoreflect.Message) {", results[self.first])
+        self.assertIn('redactv1 "github.com/birbparty/buck2-protobuf/proto/buck2protobuf/redact/v1"', results[self.first])
+        self.assertIn("func (x *Page) Redact() *Page {", results[self.second])
+        self.assertNotIn("redactPIIMessage(m protoreflect.Message)", results[self.second])
+        self.assertNotIn("protoreflect\"", results[self.second])
+
+
+class TestRecursionGuard(GoHelperTestCase):
+    """Test the recursion_guard generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("tree.proto", '''
+            syntax = "proto3";
+            package acme.tree.v1;
+            message Node { repeated Node children = 1; Leaf leaf = 2; }
+            message Leaf { string value = 1; }
+            message Expr { map<string, Operand> operands = 1; }
+            message Operand { Expr expr = 1; }
+        ''')
+
+    def test_only_recursive_messages(self):
+        code = self.generate_one("recursion_guard", self.proto, {"max_depth": 32})
+        self.assertIn("func (x *Node) UnmarshalSafe(b []byte) error {", code)
+        self.assertIn("func (x *Expr) UnmarshalSafe(b []byte) error {", code)
+        self.assertIn("func (x *Operand) UnmarshalSafe(b []byte) error {", code)
+        self.assertNotIn("*Leaf", code)
+        self.assertIn("proto.UnmarshalOptions{RecursionLimit: 32}.Unmarshal(b, x)", code)
+
+    def test_no_recursive_messages_generates_nothing(self):
+        path = self.write("flat.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage A { B b = 1; }\nmessage B {}\n')
+        self.assertIsNone(self.generate_one("recursion_guard", path, {"max_depth": 32}))
+
+    def test_rejects_invalid_depth(self):
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("recursion_guard", self.proto, {"max_depth": 0})
+
+
+class TestWktJson(GoHelperTestCase):
+    """Test the wkt_json generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("event.proto", '''
+            syntax = "proto3";
+            package acme.event.v1;
+            import "google/protobuf/timestamp.proto";
+            message Event { google.protobuf.Timestamp created_at = 1; }
+        ''')
+
+    def test_timestamp_epoch_millis(self):
+        code = self.generate_one("wkt_json", self.proto, {"formats": {"timestamp": "epoch_millis"}})
+        self.assertIn("//   google.protobuf.Timestamp: epoch_millis", code)
+        self.assertIn("\t\treturn seconds*1000 + nanos/1000000", code)
+        self.assertIn("time.UnixMilli(v).UTC().Format(time.RFC3339Nano)", code)
+        self.assertNotIn("google.protobuf.Duration", code)
+        self.assertNotIn('"strconv"', code)
+        self.assertIn("func (x *Event) MarshalJSON() ([]byte, error) {", code)
+
+    def test_duration_formats(self):
+        code = self.generate_one("wkt_json", self.proto, {"formats": {"timestamp": "rfc3339", "duration": "millis"}})
+        self.assertIn('case "google.protobuf.Duration":', code)
+        self.assertNotIn('case "google.protobuf.Timestamp":', code)
+        self.assertIn('strconv.FormatFloat(float64(v)/1000, \'f\', -1, 64) + "s"', code)
+        self.assertNotIn('"time"', code)
+
+    def test_rejects_invalid_formats(self):
+        for formats in ({}, {"timestamp": "rfc3339"}, {"timestamp": "unix"}, {"any": "string"}):
+            with self.assertRaises(GeneratorConfigError):
+                self.generate_one("wkt_json", self.proto, {"formats": formats})
+
+
+class TestGrpcMessageLimits(GoHelperTestCase):
+    """Test the grpc_message_limits generator."""
+
+    def test_server_options_from_annotation(self):
+        path = self.write("upload.proto", '''
+            syntax = "proto3";
+            package acme.upload.v1;
+            import "buck2protobuf/grpc/v1/limits.proto";
+            message Chunk {}
+            service UploadService {
+              option (buck2protobuf.grpc.v1.message_limits) = { max_recv_bytes: 16777216 max_send_bytes: 1024 };
+              rpc Upload(Chunk) returns (Chunk);
+            }
+            service StatusService {
+              option (buck2protobuf.grpc.v1.message_limits).max_send_bytes = 4096;
+              rpc Get(Chunk) returns (Chunk);
+            }
+            service PlainService { rpc Get(Chunk) returns (Chunk); }
+        ''')
+        code = self.generate_one("grpc_message_limits", path)
+        self.assertIn("const UploadServiceMaxRecvMsgSize = 16777216", code)
+        self.assertIn("const UploadServiceMaxSendMsgSize = 1024", code)
+        self.assertIn("func UploadServiceServerOptions() []grpc.ServerOption {\n\treturn []grpc.ServerOption{\n"
+                      "\t\tgrpc.MaxRecvMsgSize(UploadServiceMaxRecvMsgSize),\n"
+                      "\t\tgrpc.MaxSendMsgSize(UploadServiceMaxSendMsgSize),\n\t}\n}", code)
+        self.assertIn("\t\tgrpc.MaxSendMsgSize(StatusServiceMaxSendMsgSize),\n\t}", code)
+        self.assertNotIn("StatusServiceMaxRecvMsgSize", code)
+        self.assertNotIn("PlainService", code)
+
+    def test_no_annotations_generates_nothing(self):
+        path = self.write("plain.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage M {}\nservice S { rpc Get(M) returns (M); }\n')
+        self.assertIsNone(self.generate_one("grpc_message_limits", path))
+
+    def test_rejects_negative_limit(self):
+        path = self.write("bad.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message M {}
+            service S {
+              option (buck2protobuf.grpc.v1.message_limits).max_recv_bytes = -1;
+              rpc Get(M) returns (M);
+            }
+        ''')
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("grpc_message_limits", path)
+
+
+class TestGrpcClientTimeouts(GoHelperTestCase):
+    """Test the grpc_client_timeouts generator."""
+
+    USER_PROTO = '''
+        syntax = "proto3";
+        package acme.user.v1;
+        import "buck2protobuf/deadline/v1/deadline.proto";
+        message User {}
+        service UserService {
+          rpc GetUser(User) returns (User) { option (buck2protobuf.deadline.v1.timeout_ms) = 2500; }
+          rpc WatchUsers(User) returns (stream User) { option (buck2protobuf.deadline.v1.timeout_ms) = 60000; }
+          rpc DeleteUser(User) returns (User) { option (buck2protobuf.deadline.v1.timeout_ms) = 0; }
+          rpc ListUsers(User) returns (User);
+        }
+        service PlainService { rpc Get(User) returns (User); }
+    '''
+
+    def test_timeouts_and_client_wrapper(self):
+        path = self.write("user.proto", self.USER_PROTO)
+        code = self.generate_one("grpc_client_timeouts", path)
+        self.assertIn("var UserServiceMethodTimeouts = map[string]time.Duration{\n"
+                      "\tUserService_GetUser_FullMethodName:    2500 * time.Millisecond,\n"
+                      "\tUserService_WatchUsers_FullMethodName: 60 * time.Second,\n}", code)
+        self.assertIn("func NewUserServiceTimeoutClient(cc grpc.ClientConnInterface) UserServiceClient {\n"
+                      "\treturn NewUserServiceClient(timeoutClientConn{cc: cc, timeouts: UserServiceMethodTimeouts})\n}", code)
+        self.assertIn("type timeoutClientConn struct", code)
+        self.assertIn("if _, ok := ctx.Deadline(); ok {", code)
+        self.assertNotIn("DeleteUser", code)
+        self.assertNotIn("PlainService", code)
+
+    def test_connection_wrapper_emitted_once(self):
+        plain = self.write("a_plain.proto", 'syntax = "proto3";\npackage acme.user.v1;\nmessage M {}\nservice S { rpc Get(M) returns (M); }\n')
+        first = self.write("b_user.proto", self.USER_PROTO)
+        second = self.write("c_admin.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message Empty {}
+            service AdminService {
+              rpc Purge(Empty) returns (Empty) { option (buck2protobuf.deadline.v1.timeout_ms) = 30000; }
+            }
+        ''')
+        outputs = generate("grpc_client_timeouts", [plain, first, second], {}, "")
+        self.assertIsNone(outputs[plain])
+        self.assertIn("type timeoutClientConn struct", outputs[first])
+        self.assertNotIn("type timeoutClientConn struct", outputs[second])
+        self.assertNotIn("\t\"context\"", outputs[second])
+        self.assertIn("func NewAdminServiceTimeoutClient(", outputs[second])
+
+    def test_rejects_negative_timeout(self):
+        path = self.write("bad.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message M {}
+            service S { rpc Get(M) returns (M) { option (buck2protobuf.deadline.v1.timeout_ms) = -5; } }
+        ''')
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("grpc_client_timeouts", path)
+
+
+class TestGrpcKeepalive(GoHelperTestCase):
+    """Test the grpc_keepalive generator."""
+
+    def keepalive_proto(self, settings: str) -> str:
+        return self.write("stream.proto", f'''
+            syntax = "proto3";
+            package acme.stream.v1;
+            import "buck2protobuf/keepalive/v1/keepalive.proto";
+            message Req {{}}
+            service StreamService {{
+              option (buck2protobuf.keepalive.v1.keepalive) = {{ {settings} }};
+              rpc Watch(Req) returns (stream Req);
+            }}
+            service PlainService {{ rpc Get(Req) returns (Req); }}
+        ''')
+
+    def test_server_and_dial_options(self):
+        path = self.keepalive_proto("time_ms: 30000 timeout_ms: 2500 permit_without_stream: true max_connection_age_ms: 1800000")
+        code = self.generate_one("grpc_keepalive", path)
+        self.assertIn("\t\tgrpc.KeepaliveParams(keepalive.ServerParameters{\n"
+                      "\t\t\tMaxConnectionAge: 1800 * time.Second,\n"
+                      "\t\t\tTime:             30 * time.Second,\n"
+                      "\t\t\tTimeout:          2500 * time.Millisecond,\n"
+                      "\t\t}),", code)
+        # The server lets clients ping as often as the annotation asks them to
+        self.assertIn("\t\tgrpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{\n"
+                      "\t\t\tMinTime:             30 * time.Second,\n"
+                      "\t\t\tPermitWithoutStream: true,\n"
+                      "\t\t}),", code)
+        self.assertIn("func StreamServiceKeepaliveDialOptions() []grpc.DialOption {", code)
+        self.assertIn("\t\tgrpc.WithKeepaliveParams(keepalive.ClientParameters{\n"
+                      "\t\t\tTime:                30 * time.Second,\n"
+                      "\t\t\tTimeout:             2500 * time.Millisecond,\n"
+                      "\t\t\tPermitWithoutStream: true,\n", code)
+        self.assertNotIn("PlainService", code)
+
+    def test_server_only_settings(self):
+        path = self.keepalive_proto("max_connection_idle_ms: 300000 min_time_ms: 10000")
+        code = self.generate_one("grpc_keepalive", path)
+        self.assertIn("MaxConnectionIdle: 300 * time.Second,", code)
+        self.assertIn("MinTime: 10 * time.Second,", code)
+        self.assertNotIn("DialOptions", code)
+
+    def test_unannotated_file(self):
+        path = self.write("plain.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage M {}\nservice S { rpc Get(M) returns (M); }\n')
+        self.assertIsNone(self.generate_one("grpc_keepalive", path))
+
+    def test_rejects_invalid_settings(self):
+        for settings in ("time_ms: -1", "permit_without_stream: 1", "ping_ms: 5"):
+            with self.subTest(settings=settings):
+                with self.assertRaises(GeneratorConfigError):
+                    self.generate_one("grpc_keepalive", self.keepalive_proto(settings))
+
+
+class TestGrpcStatusCodes(GoHelperTestCase):
+    """Test the grpc_status_codes generator."""
+
+    ERRORS_PROTO = '''
+        syntax = "proto3";
+        package acme.user.v1;
+        import "buck2protobuf/errors/v1/errors.proto";
+        message UserNotFound {
+          option (buck2protobuf.errors.v1.grpc_code) = "NOT_FOUND";
+          string user_id = 1;
+        }
+        message QuotaExceeded { option (buck2protobuf.errors.v1.grpc_code) = "RESOURCE_EXHAUSTED"; }
+        message User {}
+    '''
+
+    def test_error_methods_and_status_from_err(self):
+        path = self.write("errors.proto", self.ERRORS_PROTO)
+        code = self.generate_one("grpc_status_codes", path)
+        self.assertIn("func (x *UserNotFound) Error() string {\n"
+                      "\treturn \"acme.user.v1.UserNotFound: \" + x.String()\n}", code)
+        self.assertIn("st := status.New(codes.NotFound, x.Error())", code)
+        self.assertIn("st := status.New(codes.ResourceExhausted, x.Error())", code)
+        self.assertIn("func StatusFromErr(err error) *status.Status {", code)
+        self.assertNotIn("*User)", code)
+
+    def test_status_from_err_emitted_once(self):
+        plain = self.write("a_user.proto", 'syntax = "proto3";\npackage acme.user.v1;\nmessage User {}\n')
+        first = self.write("b_errors.proto", self.ERRORS_PROTO.replace("message User {}", ""))
+        second = self.write("c_errors.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message Conflict { option (buck2protobuf.errors.v1.grpc_code) = "ALREADY_EXISTS"; }
+        ''')
+        outputs = generate("grpc_status_codes", [plain, first, second], {}, "")
+        self.assertIsNone(outputs[plain])
+        self.assertIn("func StatusFromErr(", outputs[first])
+        self.assertNotIn("func StatusFromErr(", outputs[second])
+        self.assertIn("codes.AlreadyExists", outputs[second])
+
+    def test_custom_option(self):
+        path = self.write("errors.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Denied { option (acme.errors.v1.code) = "PERMISSION_DENIED"; }
+        ''')
+        code = self.generate_one("grpc_status_codes", path, {"option": "(acme.errors.v1.code)"})
+        self.assertIn("codes.PermissionDenied", code)
+        self.assertIn("(acme.errors.v1.code)", code)
+
+    def test_rejects_invalid_code_and_clashing_field(self):
+        bad_code = self.write("bad_code.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Fine { option (buck2protobuf.errors.v1.grpc_code) = "OK"; }
+        ''')
+        clash = self.write("clash.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Failed { option (buck2protobuf.errors.v1.grpc_code) = "INTERNAL"; string error = 1; }
+        ''')
+        for path in [bad_code, clash]:
+            with self.assertRaises(GeneratorConfigError):
+                self.generate_one("grpc_status_codes", path)
+
+
+
+class TestGrpcRetryCodes(GoHelperTestCase):
+    """Test the grpc_retry_codes generator."""
+
+    USER_PROTO = '''
+        syntax = "proto3";
+        package acme.user.v1;
+        import "buck2protobuf/retry/v1/retry.proto";
+        message User {}
+        service UserService {
+          rpc GetUser(User) returns (User) {
+            option (buck2protobuf.retry.v1.retry) = { retryable_codes: ["UNAVAILABLE", "RESOURCE_EXHAUSTED"] };
+          }
+          rpc UpdateUser(User) returns (User) {
+            option (buck2protobuf.retry.v1.retry) = { retryable_codes: "ABORTED" };
+          }
+          rpc DeleteUser(User) returns (User) { option (buck2protobuf.retry.v1.retry) = {}; }
+          rpc ListUsers(User) returns (User);
+        }
+        service PlainService { rpc Get(User) returns (User); }
+    '''
+
+    def test_codes_and_classifier(self):
+        path = self.write("user.proto", self.USER_PROTO)
+        code = self.generate_one("grpc_retry_codes", path)
+        self.assertIn("var UserServiceRetryableCodes = map[string][]codes.Code{\n"
+                      "\tUserService_GetUser_FullMethodName:    {codes.Unavailable, codes.ResourceExhausted},\n"
+                      "\tUserService_UpdateUser_FullMethodName: {codes.Aborted},\n}", code)
+        self.assertIn("var retryableCodes = []map[string][]codes.Code{\n\tUserServiceRetryableCodes,\n}", code)
+        self.assertIn("func IsRetryable(method string, err error) bool {", code)
+        self.assertIn("code := status.Code(err)", code)
+        self.assertNotIn("DeleteUser", code)
+        self.assertNotIn("PlainService", code)
+
+    def test_classifier_emitted_once(self):
+        plain = self.write("a_plain.proto", 'syntax = "proto3";\npackage acme.user.v1;\nmessage M {}\nservice S { rpc Get(M) returns (M); }\n')
+        first = self.write("b_user.proto", self.USER_PROTO)
+        second = self.write("c_admin.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message Empty {}
+            service AdminService {
+              rpc Purge(Empty) returns (Empty) {
+                option (buck2protobuf.retry.v1.retry) = { retryable_codes: "UNAVAILABLE" };
+              }
+            }
+        ''')
+        outputs = generate("grpc_retry_codes", [plain, first, second], {}, "")
+        self.assertIsNone(outputs[plain])
+        self.assertIn("\tUserServiceRetryableCodes,\n\tAdminServiceRetryableCodes,\n}", outputs[first])
+        self.assertNotIn("func IsRetryable(", outputs[second])
+        self.assertNotIn("grpc/status", outputs[second])
+        self.assertIn("AdminService_Purge_FullMethodName: {codes.Unavailable},", outputs[second])
+
+    def test_rejects_invalid_codes(self):
+        for codes in ('"OK"', '["UNAVAILABLE", "UNAVAILABLE"]', '"unavailable"', "14"):
+            path = self.write("bad.proto", f'''
+                syntax = "proto3";
+                package acme.v1;
+                message M {{}}
+                service S {{ rpc Get(M) returns (M) {{ option (buck2protobuf.retry.v1.retry) = {{ retryable_codes: {codes} }}; }} }}
+            ''')
+            with self.subTest(codes=codes), self.assertRaises(GeneratorConfigError):
+                self.generate_one("grpc_retry_codes", path)
+
+
+class TestSpanAttributes(GoHelperTestCase):
+    """Test the span_attributes generator."""
+
+    USER_PROTO = '''
+        syntax = "proto3";
+        package acme.user.v1;
+        import "buck2protobuf/otel/v1/otel.proto";
+        enum Tier { TIER_UNSPECIFIED = 0; }
+        message GetUserRequest {
+          string user_id = 1 [(buck2protobuf.otel.v1.span_attribute) = "app.user.id"];
+          optional int32 shard = 2 [(buck2protobuf.otel.v1.span_attribute) = "app.shard"];
+          Tier tier = 3 [(buck2protobuf.otel.v1.span_attribute) = "app.tier"];
+          repeated string tags = 4 [(buck2protobuf.otel.v1.span_attribute) = "app.tags"];
+          uint64 quota = 5 [(buck2protobuf.otel.v1.span_attribute) = "app.quota"];
+          oneof lookup {
+            string email = 6 [(buck2protobuf.otel.v1.span_attribute) = "app.user.email"];
+            int64 legacy_id = 7;
+          }
+          string password = 8;
+        }
+        message Plain { string name = 1; }
+    '''
+
+    def test_attributes(self):
+        path = self.write("user.proto", self.USER_PROTO)
+        code = self.generate_one("span_attributes", path)
+        self.assertIn("func SpanAttributes(m proto.Message) []attribute.KeyValue {", code)
+        self.assertIn("func (x *GetUserRequest) SpanAttributes() []attribute.KeyValue {", code)
+        self.assertIn("\tattrs := make([]attribute.KeyValue, 0, 6)\n"
+                      "\tattrs = append(attrs, attribute.String(\"app.user.id\", x.GetUserId()))\n"
+                      "\tif x.Shard != nil {\n\t\tattrs = append(attrs, attribute.Int64(\"app.shard\", int64(x.GetShard())))\n\t}\n"
+                      "\tattrs = append(attrs, attribute.String(\"app.tier\", x.GetTier().String()))\n"
+                      "\tif len(x.GetTags()) > 0 {\n\t\tattrs = append(attrs, attribute.StringSlice(\"app.tags\", x.GetTags()))\n\t}\n"
+                      "\tattrs = append(attrs, attribute.String(\"app.quota\", strconv.FormatUint(x.GetQuota(), 10)))\n"
+                      "\tif _, ok := x.Lookup.(*GetUserRequest_Email); ok {", code)
+        self.assertIn('\t"strconv"\n', code)
+        self.assertNotIn("password", code.lower())
+        self.assertNotIn("Plain", code)
+
+    def test_accessor_emitted_once(self):
+        plain = self.write("a_plain.proto", 'syntax = "proto3";\npackage acme.user.v1;\nmessage M {}\n')
+        first = self.write("b_user.proto", self.USER_PROTO)
+        second = self.write("c_audit.proto", '''
+            syntax = "proto2";
+            package acme.user.v1;
+            message Audit { optional string actor = 1 [(buck2protobuf.otel.v1.span_attribute) = "app.actor"]; }
+        ''')
+        outputs = generate("span_attributes", [plain, first, second], {}, "")
+        self.assertIsNone(outputs[plain])
+        self.assertIn("func SpanAttributes(", outputs[first])
+        self.assertNotIn("func SpanAttributes(", outputs[second])
+        self.assertNotIn("protobuf/proto", outputs[second])
+        self.assertIn("\tif x.Actor != nil {", outputs[second])
+
+    def test_rejects_invalid_annotations(self):
+        for declaration in ('string a = 1 [(buck2protobuf.otel.v1.span_attribute) = ""];',
+                            'string a = 1 [(buck2protobuf.otel.v1.span_attribute) = "app id"];',
+                            'bytes a = 1 [(buck2protobuf.otel.v1.span_attribute) = "app.a"];',
+                            'M a = 1 [(buck2protobuf.otel.v1.span_attribute) = "app.a"];',
+                            'repeated int32 a = 1 [(buck2protobuf.otel.v1.span_attribute) = "app.a"];',
+                            'string a = 1 [(buck2protobuf.otel.v1.span_attribute) = "app.a"];\n'
+                            'string b = 2 [(buck2protobuf.otel.v1.span_attribute) = "app.a"];'):
+            path = self.write("bad.proto", f'''
+                syntax = "proto3";
+                package acme.v1;
+                message M {{ {declaration} }}
+            ''')
+            with self.subTest(declaration=declaration), self.assertRaises(GeneratorConfigError):
+                self.generate_one("span_attributes", path)
+
+
+class TestEnumStrings(GoHelperTestCase):
+    """Test the enum_strings generator."""
+
+    CARRIER_PROTO = '''
+        syntax = "proto3";
+        package acme.ship.v1;
+        import "buck2protobuf/enums/v1/enums.proto";
+        enum Carrier {
+          option allow_alias = true;
+          CARRIER_UNSPECIFIED = 0;
+          CARRIER_UPS = 1 [(buck2protobuf.enums.v1.string_value) = "ups"];
+          CARRIER_DHL_EXPRESS = 2 [(buck2protobuf.enums.v1.string_value) = "dhl-express"];
+          CARRIER_DHL = 2 [(buck2protobuf.enums.v1.string_value) = "dhl"];
+        }
+        message Shipment {
+          enum Speed {
+            SPEED_UNSPECIFIED = 0;
+            SPEED_OVERNIGHT = 1 [(buck2protobuf.enums.v1.string_value) = "next-day"];
+          }
+          Speed speed = 1;
+        }
+        enum Plain { PLAIN_UNSPECIFIED = 0; }
+    '''
+
+    def test_text_methods(self):
+        path = self.write("ship.proto", self.CARRIER_PROTO)
+        code = self.generate_one("enum_strings", path)
+        self.assertIn("var CarrierExternalStrings = map[Carrier]string{\n"
+                      "\tCarrier_CARRIER_UPS:         \"ups\",\n"
+                      "\tCarrier_CARRIER_DHL_EXPRESS: \"dhl-express\",\n}", code)
+        self.assertIn("var CarrierExternalValues = map[string]Carrier{\n"
+                      "\t\"ups\":         Carrier_CARRIER_UPS,\n"
+                      "\t\"dhl-express\": Carrier_CARRIER_DHL_EXPRESS,\n"
+                      "\t\"dhl\":         Carrier_CARRIER_DHL,\n}", code)
+        self.assertIn("func (x Carrier) MarshalText() ([]byte, error) {", code)
+        self.assertIn("func (x *Carrier) UnmarshalText(text []byte) error {", code)
+        # Nested enum values are prefixed with the enclosing message
+        self.assertIn("\"next-day\": Shipment_SPEED_OVERNIGHT,", code)
+        self.assertIn("func (x *Shipment_Speed) UnmarshalText(", code)
+        self.assertNotIn("Plain", code)
+
+    def test_unannotated_file_and_custom_option(self):
+        plain = self.write("plain.proto", 'syntax = "proto3";\npackage acme.v1;\nenum Plain { PLAIN_UNSPECIFIED = 0; }\n')
+        self.assertIsNone(self.generate_one("enum_strings", plain))
+        custom = self.write("custom.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            enum Tier { TIER_UNSPECIFIED = 0; TIER_GOLD = 1 [(acme.ext.v1.wire) = "GOLD"]; }
+        ''')
+        code = self.generate_one("enum_strings", custom, {"option": "(acme.ext.v1.wire)"})
+        self.assertIn("\"GOLD\": Tier_TIER_GOLD,", code)
+
+    def test_rejects_duplicate_and_empty_strings(self):
+        duplicate = self.write("duplicate.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            enum Tier {
+              TIER_UNSPECIFIED = 0;
+              TIER_GOLD = 1 [(buck2protobuf.enums.v1.string_value) = "gold"];
+              TIER_GOLDEN = 2 [(buck2protobuf.enums.v1.string_value) = "gold"];
+            }
+        ''')
+        empty = self.write("empty.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            enum Tier { TIER_UNSPECIFIED = 0 [(buck2protobuf.enums.v1.string_value) = ""]; }
+        ''')
+        for path in [duplicate, empty]:
+            with self.assertRaises(GeneratorConfigError):
+                self.generate_one("enum_strings", path)
+
+
+class TestArena(GoHelperTestCase):
+    """Test the arena generator."""
+
+    def test_constructors_behind_build_constraint(self):
+        path = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message User { message Address {} map<string, string> labels = 1; }
+        ''')
+        code = self.generate_one("arena", path, go_package="github.com/acme/user/v1;userv1")
+        self.assertTrue(code.startswith("//go:build goexperiment.arenas\n\n// Code generated"))
+        self.assertIn('import (\n\t"arena"\n)', code)
+        self.assertIn("func NewUserArena(a *arena.Arena) *User {\n\treturn arena.New[User](a)\n}", code)
+        self.assertIn("func NewUser_AddressArena(a *arena.Arena) *User_Address {", code)
+        self.assertNotIn("LabelsEntry", code)
+
+    def test_no_messages_generates_nothing(self):
+        path = self.write("enums.proto", 'syntax = "proto3";\npackage acme.v1;\nenum E { E_UNSPECIFIED = 0; }\n')
+        self.assertIsNone(self.generate_one("arena", path))
+
+
+class TestGrpcServerInterceptors(GoHelperTestCase):
+    """Test the grpc_server_interceptors generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("svc.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message Req {}
+            service UserService { rpc Get(Req) returns (Req); }
+        ''')
+
+    def test_all_interceptors_in_order(self):
+        code = self.generate_one("grpc_server_interceptors", self.proto)
+        self.assertIn("func DefaultServerOptions(registry prometheus.Registerer, logger *slog.Logger) ([]grpc.ServerOption, error) {", code)
+        self.assertIn("grpc.ChainUnaryInterceptor(\n"
+                      "\t\t\tmetrics.UnaryServerInterceptor(),\n"
+                      "\t\t\tlogging.UnaryServerInterceptor(log),\n"
+                      "\t\t\trecovery.UnaryServerInterceptor(),\n"
+                      "\t\t\tprotovalidatemw.UnaryServerInterceptor(validator),\n\t\t),", code)
+        self.assertIn("validator, err := protovalidate.New()", code)
+        self.assertNotIn("ChainStreamInterceptor", code)
+
+    def test_stream_interceptors_when_a_service_streams(self):
+        path = self.write("stream.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message Req {}
+            service UploadService { rpc Upload(stream Req) returns (Req); }
+        ''')
+        results = generate("grpc_server_interceptors", [self.proto, path], {"interceptors": ["recovery"]})
+        self.assertIn("grpc.ChainStreamInterceptor(\n\t\t\trecovery.StreamServerInterceptor(),\n\t\t),", results[self.proto])
+
+    def test_selected_interceptors_only(self):
+        code = self.generate_one("grpc_server_interceptors", self.proto, {"interceptors": ["recovery", "logging"]})
+        self.assertIn("func DefaultServerOptions(logger *slog.Logger) ([]grpc.ServerOption, error) {", code)
+        self.assertIn("outermost first: logging, recovery.", code)
+        self.assertNotIn("prometheus", code)
+        self.assertNotIn("protovalidate", code)
+
+    def test_emitted_once_per_package(self):
+        types = self.write("types.proto", 'syntax = "proto3";\npackage acme.user.v1;\nmessage Other {}\n')
+        other = self.write("other.proto", 'syntax = "proto3";\npackage acme.user.v1;\nmessage O {}\nservice OtherService { rpc Get(O) returns (O); }\n')
+        results = generate("grpc_server_interceptors", [types, self.proto, other], {"interceptors": ["recovery"]})
+        self.assertIsNone(results[types])
+        self.assertIn("func DefaultServerOptions()", results[self.proto])
+        self.assertIsNone(results[other])
+
+    def test_rejects_unknown_interceptor(self):
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("grpc_server_interceptors", self.proto, {"interceptors": ["auth"]})
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("grpc_server_interceptors", self.proto, {"interceptors": []})
+
+
+class TestFieldIndex(GoHelperTestCase):
+    """Test the field_index generator."""
+
+    def test_wire_types_and_offsets(self):
+        path = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.user.v1;
+            message Empty {}
+            message User {
+              enum Kind { KIND_UNSPECIFIED = 0; }
+              string name = 1;
+              repeated int32 codes = 2;
+              repeated int32 loose = 3 [packed = false];
+              Kind kind = 4;
+              map<string, string> labels = 5;
+              oneof contact { string email = 6; Empty none = 7; }
+              double score = 8;
+              string get_name = 9;
+            }
+        ''')
+        code = self.generate_one("field_index", path)
+        self.assertIn("type FieldIndex struct {", code)
+        self.assertIn("var EmptyFieldIndex = map[string]FieldIndex{}", code)
+        self.assertIn('\t"codes": {\n\t\tNumber: 2, WireType: protowire.BytesType, Offset: unsafe.Offsetof(User{}.Codes),\n\t},', code)
+        self.assertIn("Number: 3, WireType: protowire.VarintType,", code)
+        self.assertIn("Number: 4, WireType: protowire.VarintType,", code)
+        self.assertIn("Number: 5, WireType: protowire.BytesType,", code)
+        self.assertIn('Number: 7, WireType: protowire.BytesType, Offset: unsafe.Offsetof(User{}.Contact), Oneof: "contact",', code)
+        self.assertIn("Number: 8, WireType: protowire.Fixed64Type,", code)
+        self.assertIn("Offset: unsafe.Offsetof(User{}.GetName_)", code)
+        self.assertNotIn("LabelsEntry", code)
+
+    def test_proto2_repeated_scalars_are_unpacked(self):
+        path = self.write("legacy.proto", '''
+            syntax = "proto2";
+            package acme.v1;
+            message M { repeated int32 a = 1; repeated sint64 b = 2 [packed = true]; }
+        ''')
+        code = self.generate_one("field_index", path)
+        self.assertIn("Number: 1, WireType: protowire.VarintType,", code)
+        self.assertIn("Number: 2, WireType: protowire.BytesType,", code)
+
+    def test_entry_type_emitted_once(self):
+        first = self.write("a.proto", 'syntax = "proto3";\npackage acme.v1;\nenum E { E_UNSPECIFIED = 0; }\n')
+        second = self.write("b.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage M { int32 a = 1; }\n')
+        results = generate("field_index", [first, second], {})
+        self.assertIn("type FieldIndex struct", results[first])
+        self.assertNotIn("unsafe", results[first])
+        self.assertNotIn("type FieldIndex struct", results[second])
+        self.assertIn("var MFieldIndex", results[second])
+
+
+
+def _field(number: int, payload: bytes) -> bytes:
+    # Single-byte tags and lengths suffice for the test descriptors
+    return bytes([number << 3 | 2, len(payload)]) + payload
+
+
+def _file_descriptor(name: str, dependencies=(), source_info: bool = False) -> bytes:
+    data = _field(1, name.encode()) + _field(2, b"acme.v1")
+    for dependency in dependencies:
+        data += _field(3, dependency.encode())
+    if source_info:
+        data += _field(9, b"\x0a\x00")
+    return data
+
+
+class TestDescriptorEmbed(GoHelperTestCase):
+    """Test the descriptor_embed generator."""
+
+    def setUp(self):
+        super().setUp()
+        self.path = self.write("acme/v1/user.proto", 'syntax = "proto3";\npackage acme.v1;\nimport "acme/v1/common.proto";\nmessage User {}\n')
+        common = _file_descriptor("acme/v1/common.proto")
+        user = _file_descriptor("acme/v1/user.proto", ["acme/v1/common.proto"], source_info=True)
+        self.descriptors = parse_descriptor_set(_field(1, common) + _field(1, user))
+
+    def test_strips_source_info(self):
+        user = self.descriptors["acme/v1/user.proto"]
+        self.assertEqual(user.dependencies, ["acme/v1/common.proto"])
+        self.assertEqual(user.data, _file_descriptor("acme/v1/user.proto", ["acme/v1/common.proto"]))
+
+    def test_raw_format(self):
+        code = self.generate_one("descriptor_embed", self.path, {"format": "raw", "descriptors": self.descriptors})
+        expected = _file_descriptor("acme/v1/user.proto", ["acme/v1/common.proto"])
+        self.assertIn("var userCompactDesc = []byte{\n\t0x0a, 0x12, 0x61,", code)
+        self.assertIn(f"// UserProtoCompactDescriptor returns the descriptor of acme/v1/user.proto ({len(expected)} bytes):\n"
+                      "// a FileDescriptorProto, without source code info.", code)
+        self.assertIn("func UserProtoCompactDescriptor() []byte {\n\treturn userCompactDesc\n}", code)
+
+    def test_gzip_with_imports(self):
+        code = self.generate_one("descriptor_embed", self.path, {
+            "format": "gzip", "include_imports": True, "descriptors": self.descriptors,
+        })
+        literal = code[code.index("[]byte{") + len("[]byte{"):code.index("}")]
+        data = bytes(int(b, 16) for b in literal.replace(",", " ").split())
+        common = _file_descriptor("acme/v1/common.proto")
+        user = _file_descriptor("acme/v1/user.proto", ["acme/v1/common.proto"])
+        self.assertEqual(gzip.decompress(data), _field(1, common) + _field(1, user))
+        self.assertIn("// a gzip-compressed FileDescriptorSet of the file and its transitive imports,\n// dependencies first", code)
+
+    def test_invalid_config(self):
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("descriptor_embed", self.path, {"format": "zstd", "descriptors": self.descriptors})
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("descriptor_embed", self.path, {"format": "raw"})
+        with self.assertRaises(GeneratorConfigError):
+            self.generate_one("descriptor_embed", self.path, {"format": "raw", "descriptors": {}})
+
+
+if __name__ == "__main__":
+    unittest.main()
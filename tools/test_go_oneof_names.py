@@ -0,0 +1,132 @@
+#!/usr/bin/env python3
+"""
+Test suite for Go oneof wrapper name stability.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from go_oneof_names import contract_names, current_names, find_drift, generated_wrappers, render_aliases
+    from proto_schema import load_schema_set
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from go_oneof_names import contract_names, current_names, find_drift, generated_wrappers, render_aliases
+    from proto_schema import load_schema_set
+
+
+PROTO = '''
+syntax = "proto3";
+package acme.v1;
+
+message Event {
+  message Created { string id = 1; }
+  enum Kind { KIND_UNSPECIFIED = 0; }
+  string get_name = 1;
+  oneof payload {
+    Created created = 2;
+    string name = 3;
+    string kind = 4;
+  }
+  map<string, string> labels = 5;
+}
+'''
+
+GENERATED = '''
+type isEvent_Payload interface {
+	isEvent_Payload()
+}
+
+type Event_Created_ struct {
+	Created *Event_Created `protobuf:"bytes,2,opt,name=created,proto3,oneof"`
+}
+
+type Event_Name struct {
+	Name string `protobuf:"bytes,3,opt,name=name,proto3,oneof"`
+}
+
+type Event_Kind_ struct {
+	Kind string `protobuf:"bytes,4,opt,name=kind,proto3,oneof"`
+}
+
+func (*Event_Created_) isEvent_Payload() {}
+
+func (*Event_Name) isEvent_Payload() {}
+
+func (*Event_Kind_) isEvent_Payload() {}
+'''
+
+
+class OneofNamesTestCase(unittest.TestCase):
+    """Base class providing a temporary proto file."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.path = os.path.join(self.temp_dir, "event.proto")
+        with open(self.path, "w", encoding="utf-8") as f:
+            f.write(PROTO)
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def members(self):
+        return {m.full_name: m for m in contract_names(load_schema_set([self.path]))}
+
+
+class TestContract(OneofNamesTestCase):
+    """Test the naming contract."""
+
+    def test_nested_type_conflicts_get_suffix(self):
+        members = self.members()
+        self.assertEqual(members["acme.v1.Event.created"].wrapper, "Event_Created_")
+        self.assertEqual(members["acme.v1.Event.kind"].wrapper, "Event_Kind_")
+
+    def test_getter_conflict_renames_field(self):
+        # GetName is taken by the getter of get_name, so name becomes Name_
+        self.assertEqual(self.members()["acme.v1.Event.name"].wrapper, "Event_Name_")
+
+    def test_interface_name(self):
+        self.assertEqual(self.members()["acme.v1.Event.created"].interface, "isEvent_Payload")
+
+    def test_only_oneof_members(self):
+        self.assertEqual(set(self.members()), {"acme.v1.Event.created", "acme.v1.Event.name", "acme.v1.Event.kind"})
+
+
+class TestGeneratedCode(OneofNamesTestCase):
+    """Test reading wrapper names from generated code."""
+
+    def test_generated_names_win(self):
+        members = list(self.members().values())
+        names = current_names(members, generated_wrappers([GENERATED]))
+        self.assertEqual(names["acme.v1.Event.name"], "Event_Name")
+        self.assertEqual(names["acme.v1.Event.created"], "Event_Created_")
+
+    def test_falls_back_to_contract(self):
+        names = current_names(list(self.members().values()), None)
+        self.assertEqual(names["acme.v1.Event.name"], "Event_Name_")
+
+
+class TestDrift(OneofNamesTestCase):
+    """Test baseline comparison and aliases."""
+
+    def test_drift_detected(self):
+        current = {"acme.v1.Event.name": "Event_Name_", "acme.v1.Event.kind": "Event_Kind_"}
+        baseline = {"acme.v1.Event.name": "Event_Name", "acme.v1.Event.kind": "Event_Kind_", "acme.v1.Gone.x": "Gone_X"}
+        self.assertEqual(find_drift(baseline, current), [("acme.v1.Event.name", "Event_Name", "Event_Name_")])
+
+    def test_aliases(self):
+        code = render_aliases([("acme.v1.Event.name", "Event_Name", "Event_Name_")], "eventv1", "event.proto", [])
+        self.assertIn("package eventv1", code)
+        self.assertIn("type Event_Name = Event_Name_", code)
+
+    def test_alias_conflict(self):
+        with self.assertRaises(ValueError):
+            render_aliases([("acme.v1.Event.name", "Event_Name", "Event_Name_")], "eventv1", "event.proto", ["Event_Name"])
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,109 @@
+#!/usr/bin/env python3
+"""
+Test suite for ORM validation struct tags.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from go_validate_tags import DEFAULT_TAG_RULES, TagConfigError, add_tags, load_config, struct_tags
+    from proto_schema import load_schema_set
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from go_validate_tags import DEFAULT_TAG_RULES, TagConfigError, add_tags, load_config, struct_tags
+    from proto_schema import load_schema_set
+
+
+PROTO = '''
+syntax = "proto3";
+package acme.user.v1;
+import "buf/validate/validate.proto";
+
+message User {
+  string email = 1 [(buf.validate.field).string.email = true, (buf.validate.field).string.max_len = 254];
+  int32 age = 2 [(buf.validate.field).int32 = {gte: 0, lte: 150}];
+  string id = 3 [(buf.validate.field).required = true];
+  string note = 4 [(buf.validate.field).string.pattern = "^[a-z]+$"];
+  message Address {
+    string country = 1 [(buf.validate.field).string.len = 2];
+  }
+  oneof contact {
+    string phone = 5 [(buf.validate.field).string.min_len = 5];
+  }
+}
+'''
+
+GENERATED = '''type User struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Email string `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Age   int32  `protobuf:"varint,2,opt,name=age,proto3" json:"age,omitempty"`
+	Id    string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	Note  string `protobuf:"bytes,4,opt,name=note,proto3" json:"note,omitempty"`
+	// Types that are assignable to Contact:
+	//
+	//	*User_Phone
+	Contact isUser_Contact `protobuf_oneof:"contact"`
+}
+
+type User_Address struct {
+	Country string `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+}
+'''
+
+
+class TestValidateTags(unittest.TestCase):
+    """Test mapping protovalidate rules to struct tags."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.path = os.path.join(self.temp_dir, "user.proto")
+        with open(self.path, "w", encoding="utf-8") as f:
+            f.write(PROTO)
+        self.schema = load_schema_set([self.path])
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def test_default_mapping(self):
+        tags = struct_tags(self.schema, DEFAULT_TAG_RULES)
+        self.assertEqual(tags, {
+            "User": {"email": "email,max=254", "age": "gte=0,lte=150", "id": "required"},
+            "User_Address": {"country": "len=2"},
+        })
+
+    def test_custom_mapping(self):
+        key, rules = load_config({"tag": "binding", "rules": {"string.email": "", "string.pattern": "regexp={}"}})
+        tags = struct_tags(self.schema, rules)
+        self.assertEqual(key, "binding")
+        self.assertEqual(tags["User"]["email"], "max=254")
+        self.assertEqual(tags["User"]["note"], "regexp=^[a-z]+$")
+
+    def test_rewrites_generated_code(self):
+        content, tagged = add_tags(GENERATED, struct_tags(self.schema, DEFAULT_TAG_RULES), "validate")
+        self.assertEqual(tagged, 4)
+        self.assertIn('json:"email,omitempty" validate:"email,max=254"`', content)
+        self.assertIn('json:"country,omitempty" validate:"len=2"`', content)
+        self.assertIn('Note  string `protobuf:"bytes,4,opt,name=note,proto3" json:"note,omitempty"`\n', content)
+        self.assertIn('Contact isUser_Contact `protobuf_oneof:"contact"`\n', content)
+
+    def test_existing_tag_conflicts(self):
+        with self.assertRaises(TagConfigError):
+            add_tags(GENERATED, struct_tags(self.schema, DEFAULT_TAG_RULES), "json")
+
+    def test_rejects_invalid_config(self):
+        with self.assertRaises(TagConfigError):
+            load_config({"tag": "val idate"})
+        with self.assertRaises(TagConfigError):
+            load_config({"rules": {"string.email": 'email"'}})
+
+
+if __name__ == "__main__":
+    unittest.main()
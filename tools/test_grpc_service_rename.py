@@ -0,0 +1,79 @@
+#!/usr/bin/env python3
+"""
+Test suite for wire-level gRPC service renaming.
+"""
+
+import unittest
+from pathlib import Path
+
+try:
+    from grpc_service_rename import RenameError, prefixed_mapping, rename_services, validate_mapping
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from grpc_service_rename import RenameError, prefixed_mapping, rename_services, validate_mapping
+
+
+GENERATED = '''
+const (
+	UserService_GetUser_FullMethodName = "/acme.user.v2.UserService/GetUser"
+)
+
+func (c *userServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	err := c.cc.Invoke(ctx, UserService_GetUser_FullMethodName, in, out, opts...)
+}
+
+var UserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "acme.user.v2.UserService",
+	HandlerType: (*UserServiceServer)(nil),
+}
+'''
+
+
+class TestRenameServices(unittest.TestCase):
+    """Test rewriting generated gRPC code."""
+
+    def test_rewrites_wire_names_only(self):
+        content, found = rename_services(GENERATED, {"acme.user.v2.UserService": "acme.user.v1.UserService"})
+        self.assertEqual(found, ["acme.user.v2.UserService"])
+        self.assertIn('"/acme.user.v1.UserService/GetUser"', content)
+        self.assertIn('ServiceName: "acme.user.v1.UserService",', content)
+        self.assertIn("UserService_ServiceDesc = grpc.ServiceDesc{", content)
+        self.assertIn("HandlerType: (*UserServiceServer)(nil)", content)
+        self.assertNotIn("acme.user.v2", content)
+
+    def test_unrelated_services_untouched(self):
+        content, found = rename_services(GENERATED, {"acme.other.v1.Other": "acme.other.v2.Other"})
+        self.assertEqual(found, [])
+        self.assertEqual(content, GENERATED)
+
+    def test_validates_names(self):
+        validate_mapping({"acme.user.v2.UserService": "UserService"})
+        for invalid in ["acme/user.UserService", "acme..UserService", "acme.1User", ""]:
+            with self.assertRaises(RenameError):
+                validate_mapping({"acme.user.v2.UserService": invalid})
+
+
+class TestServicePrefix(unittest.TestCase):
+    """Test prefixing every service for tenant-scoped routing."""
+
+    def test_prefix_applies_to_all_services(self):
+        mapping = prefixed_mapping(GENERATED, {}, "tenant_a")
+        content, found = rename_services(GENERATED, mapping)
+        self.assertEqual(found, ["acme.user.v2.UserService"])
+        self.assertIn('"/tenant_a.acme.user.v2.UserService/GetUser"', content)
+        self.assertIn('ServiceName: "tenant_a.acme.user.v2.UserService",', content)
+        self.assertIn("UserService_GetUser_FullMethodName, in, out", content)
+
+    def test_prefix_follows_rename(self):
+        mapping = prefixed_mapping(GENERATED, {"acme.user.v2.UserService": "acme.user.v1.UserService"}, "tenant_a.eu")
+        self.assertEqual(mapping, {"acme.user.v2.UserService": "tenant_a.eu.acme.user.v1.UserService"})
+
+    def test_invalid_prefix(self):
+        for prefix in ["tenant-a", "1tenant", "tenant/a"]:
+            with self.assertRaises(RenameError):
+                prefixed_mapping(GENERATED, {}, prefix)
+
+
+if __name__ == "__main__":
+    unittest.main()
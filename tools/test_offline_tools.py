@@ -0,0 +1,122 @@
+#!/usr/bin/env python3
+"""
+Test suite for offline (air-gapped) tool resolution.
+"""
+
+import json
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+from unittest.mock import patch
+
+try:
+    from download_plugins import PluginDownloader
+    from download_protoc import ProtocDownloader
+    from offline_tools import OFFLINE_CACHE_ENV, OfflineToolError, ToolSpec, load_specs, offline_cache_dir, render_manifest
+except ImportError:
+    sys.path.append(str(Path(__file__).parent))
+    from download_plugins import PluginDownloader
+    from download_protoc import ProtocDownloader
+    from offline_tools import OFFLINE_CACHE_ENV, OfflineToolError, ToolSpec, load_specs, offline_cache_dir, render_manifest
+
+TOOLS_DIR = Path(__file__).parent
+PIN = "5871398dfd6ac954a6adebf41f1ae3a4de915a36a6ab2fd3e8f2c00d45b50dec"
+
+
+class TestOfflineResolution(unittest.TestCase):
+    """Test resolution from a pre-populated cache."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.offline_cache = Path(self.temp_dir) / "offline"
+        self.offline_cache.mkdir()
+        self.downloader = ProtocDownloader(os.path.join(self.temp_dir, "download"), lockfile=str(TOOLS_DIR / "protoc.lock.json"))
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def seed(self, relative_path: str, checksum: str = "") -> Path:
+        binary = self.offline_cache / relative_path
+        binary.parent.mkdir(parents=True, exist_ok=True)
+        binary.write_text("#!/bin/sh\n")
+        if checksum:
+            (binary.parent / ".sha256").write_text(checksum + "\n")
+        return binary
+
+    def test_missing_binary_names_tool_and_checksum(self):
+        with patch.object(ProtocDownloader, "download_with_retry", side_effect=AssertionError("network access")):
+            with self.assertRaises(OfflineToolError) as raised:
+                self.downloader.resolve_offline("24.4", "linux-x86_64", self.offline_cache)
+        message = str(raised.exception)
+        self.assertIn("protoc 24.4 (linux-x86_64)", message)
+        self.assertIn(PIN, message)
+        self.assertIn(str(self.offline_cache / "protoc-24.4-linux-x86_64" / "bin" / "protoc"), message)
+        self.assertIn("no download was attempted", message)
+
+    def test_cached_binary_is_used(self):
+        binary = self.seed("protoc-24.4-linux-x86_64/bin/protoc", PIN)
+        self.assertEqual(self.downloader.resolve_offline("24.4", "linux-x86_64", self.offline_cache), str(binary))
+        self.assertTrue(os.access(binary, os.X_OK))
+
+    def test_recorded_digest_must_match_pin(self):
+        self.seed("protoc-24.4-linux-x86_64/bin/protoc", "0" * 64)
+        with self.assertRaisesRegex(OfflineToolError, "expected " + PIN):
+            self.downloader.resolve_offline("24.4", "linux-x86_64", self.offline_cache)
+
+    def test_missing_plugin_uses_rule_binary_path(self):
+        downloader = PluginDownloader(os.path.join(self.temp_dir, "download"))
+        with self.assertRaisesRegex(OfflineToolError, "protoc-gen-mypy-3.6.0-linux-x86_64/bin/protoc-gen-mypy"):
+            downloader.resolve_offline("protoc-gen-mypy", "3.6.0", "linux-x86_64", self.offline_cache,
+                                       binary_path="bin/protoc-gen-mypy")
+
+    def test_cache_dir_from_environment(self):
+        with patch.dict(os.environ, {OFFLINE_CACHE_ENV: str(self.offline_cache)}):
+            self.assertEqual(offline_cache_dir(), self.offline_cache)
+        with patch.dict(os.environ, {}, clear=True):
+            with self.assertRaisesRegex(OfflineToolError, OFFLINE_CACHE_ENV):
+                offline_cache_dir()
+
+    def test_download_script_fails_fast(self):
+        result = subprocess.run(
+            [sys.executable, str(TOOLS_DIR / "download_protoc.py"), "--version", "24.4", "--platform", "linux-x86_64",
+             "--cache-dir", os.path.join(self.temp_dir, "download"), "--offline",
+             "--offline-cache-dir", str(self.offline_cache)],
+            capture_output=True, text=True, timeout=60,
+        )
+        self.assertEqual(result.returncode, 1)
+        self.assertIn("ERROR: offline mode: protoc 24.4 (linux-x86_64) is not in the tool cache", result.stderr)
+
+
+class TestManifest(unittest.TestCase):
+    """Test the manifest of tools to pre-seed."""
+
+    def test_manifest_marks_missing_tools_and_applies_lockfile(self):
+        temp_dir = Path(tempfile.mkdtemp())
+        self.addCleanup(shutil.rmtree, temp_dir)
+        specs_file = temp_dir / "specs.json"
+        specs_file.write_text(json.dumps([
+            {"tool": "protoc-gen-go", "version": "1.31.0", "platform": "linux-x86_64",
+             "binary_path": "protoc-gen-go", "sha256": "ab" * 32, "url": "https://example.com/go.tar.gz"},
+            {"tool": "protoc", "version": "24.4", "platform": "linux-x86_64",
+             "binary_path": "bin/protoc", "sha256": "cd" * 32, "url": None},
+        ]))
+        (temp_dir / "protoc-gen-go-1.31.0-linux-x86_64").mkdir()
+        (temp_dir / "protoc-gen-go-1.31.0-linux-x86_64" / "protoc-gen-go").write_text("")
+
+        specs = load_specs(str(specs_file), str(TOOLS_DIR / "protoc.lock.json"))
+        lines = render_manifest(specs, temp_dir).splitlines()
+        self.assertEqual(lines[0], f"protoc 24.4 linux-x86_64 sha256={PIN} - -> protoc-24.4-linux-x86_64/bin/protoc [MISSING]")
+        self.assertTrue(lines[1].startswith("protoc-gen-go 1.31.0 linux-x86_64"))
+        self.assertTrue(lines[1].endswith("[present]"))
+
+    def test_cache_key(self):
+        spec = ToolSpec("protoc-gen-go", "1.31.0", "darwin-arm64", "protoc-gen-go")
+        self.assertEqual(spec.cache_key, "protoc-gen-go-1.31.0-darwin-arm64")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,103 @@
+#!/usr/bin/env python3
+"""
+Test suite for OpenAPI v3 document finalization.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from openapi_v3_doc import OpenApiError, add_server, declared_schemas, finalize, service_names
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from openapi_v3_doc import OpenApiError, add_server, declared_schemas, finalize, service_names
+
+
+GENERATED = '''# Generated with protoc-gen-openapi
+# https://github.com/google/gnostic/tree/master/cmd/protoc-gen-openapi
+
+openapi: 3.0.3
+info:
+    title: UserService API
+    version: 1.2.0
+paths:
+    /v1/users/{user_id}:
+        get:
+            tags:
+                - UserService
+            operationId: UserService_GetUser
+            responses:
+                "200":
+                    content:
+                        application/json:
+                            schema:
+                                $ref: '#/components/schemas/User'
+components:
+    schemas:
+        Address:
+            type: object
+            properties:
+                city:
+                    type: string
+        User:
+            type: object
+            properties:
+                address:
+                    $ref: '#/components/schemas/Address'
+tags:
+    - name: UserService
+'''
+
+
+class TestFinalize(unittest.TestCase):
+    """Test merge policy, servers and schema checks."""
+
+    def test_server_is_added_after_info(self):
+        document = finalize(GENERATED, ["acme.user.v1.UserService"], False, "https://api.example.com/o'brien")
+        self.assertIn("    version: 1.2.0\nservers:\n    - url: 'https://api.example.com/o''brien'\npaths:\n",
+                      document)
+        self.assertEqual(document.replace("servers:\n    - url: 'https://api.example.com/o''brien'\n", ""),
+                         GENERATED)
+
+    def test_existing_servers_conflict(self):
+        with self.assertRaises(OpenApiError):
+            add_server(add_server(GENERATED, "https://a.example.com"), "https://b.example.com")
+
+    def test_multiple_services_require_merge(self):
+        services = ["acme.user.v1.UserService", "acme.user.v1.AdminService"]
+        with self.assertRaisesRegex(OpenApiError, "AdminService"):
+            finalize(GENERATED, services, False, None)
+        self.assertEqual(finalize(GENERATED, services, True, None), GENERATED)
+
+    def test_missing_schemas_are_reported(self):
+        self.assertEqual(declared_schemas(GENERATED), ["Address", "User"])
+        broken = GENERATED.replace("        Address:\n", "        Location:\n")
+        with self.assertRaisesRegex(OpenApiError, "Address"):
+            finalize(broken, [], False, None)
+
+
+class TestServiceNames(unittest.TestCase):
+    """Test counting services in proto files."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def test_services_of_all_files(self):
+        paths = []
+        for name, services in (("user.proto", "service UserService {}\nservice AdminService {}\n"),
+                               ("types.proto", "message User {}\n")):
+            path = os.path.join(self.temp_dir, name)
+            Path(path).write_text(f'syntax = "proto3";\npackage acme.v1;\n{services}', encoding="utf-8")
+            paths.append(path)
+        self.assertEqual(service_names(paths), ["acme.v1.UserService", "acme.v1.AdminService"])
+
+
+if __name__ == "__main__":
+    unittest.main()
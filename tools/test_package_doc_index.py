@@ -0,0 +1,118 @@
+#!/usr/bin/env python3
+"""
+Test suite for the per-package documentation index.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from package_doc_index import DocIndexError, summarize, write_indexes
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from package_doc_index import DocIndexError, summarize, write_indexes
+
+
+def varint(value: int) -> bytes:
+    out = bytearray()
+    while True:
+        byte = value & 0x7F
+        value >>= 7
+        if value:
+            out.append(byte | 0x80)
+        else:
+            out.append(byte)
+            return bytes(out)
+
+
+def tag(number: int, value) -> bytes:
+    """Encodes an int as a varint field and str/bytes as a length-delimited field."""
+    if isinstance(value, int):
+        return varint(number << 3) + varint(value)
+    data = value.encode("utf-8") if isinstance(value, str) else value
+    return varint(number << 3 | 2) + varint(len(data)) + data
+
+
+def location(path, comment: str) -> bytes:
+    return tag(1, b"".join(varint(p) for p in path)) + tag(3, comment)
+
+
+def source_info(*locations: bytes) -> bytes:
+    return tag(9, b"".join(tag(1, loc) for loc in locations))
+
+
+ADDRESS_FILE = (
+    tag(1, "acme/common/v1/address.proto") + tag(2, "acme.common.v1")
+    + tag(4, tag(1, "Address") + tag(2, tag(1, "city") + tag(3, 1) + tag(4, 1) + tag(5, 9)))
+    + source_info(location([4, 0], " A postal address. Used for billing.\n"))
+)
+
+USER_FILE = (
+    tag(1, "acme/user/v1/user.proto") + tag(2, "acme.user.v1")
+    + tag(4, tag(1, "User")
+          + tag(2, tag(1, "address") + tag(3, 1) + tag(4, 1) + tag(5, 11) + tag(6, ".acme.common.v1.Address"))
+          + tag(2, tag(1, "created") + tag(3, 2) + tag(4, 1) + tag(5, 11) + tag(6, ".google.protobuf.Timestamp"))
+          + tag(2, tag(1, "tags") + tag(3, 3) + tag(4, 3) + tag(5, 9))
+          + tag(4, tag(1, "Status") + tag(2, tag(1, "STATUS_UNSPECIFIED") + tag(2, 0))))
+    + tag(6, tag(1, "UserService") + tag(2, tag(1, "GetUser") + tag(2, ".acme.user.v1.User")
+                                           + tag(3, ".acme.user.v1.User") + tag(6, 1)))
+    + source_info(
+        location([4, 0], " A registered user | member.\n\n Users are created at sign-up.\n"),
+        location([4, 0, 2, 2], " Free-form labels.\n"),
+        location([4, 0, 4, 0], " Lifecycle state of a user.\n"),
+        location([6, 0], " Manages users.\n"),
+    )
+)
+
+TIMESTAMP_FILE = tag(1, "google/protobuf/timestamp.proto") + tag(2, "google.protobuf") + tag(4, tag(1, "Timestamp"))
+
+
+def descriptor_set(*files: bytes) -> bytes:
+    return b"".join(tag(1, f) for f in files)
+
+
+class TestPackageDocIndex(unittest.TestCase):
+    """Test index generation from descriptor sets."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def read(self, relative_path: str) -> str:
+        with open(os.path.join(self.temp_dir, relative_path), "r", encoding="utf-8") as f:
+            return f.read()
+
+    def test_one_index_per_target_package(self):
+        data = descriptor_set(TIMESTAMP_FILE, ADDRESS_FILE, USER_FILE)
+        written = write_indexes(data, ["proto/acme/user/v1/user.proto", "proto/acme/common/v1/address.proto"],
+                                self.temp_dir)
+        self.assertEqual(written, ["acme/common/v1/README.md", "acme/user/v1/README.md"])
+
+        index = self.read("acme/user/v1/README.md")
+        self.assertIn("| [UserService](#userservice) | Manages users. |", index)
+        self.assertIn("| [User](#user) | A registered user \\| member. |", index)
+        self.assertIn("| [User.Status](#userstatus) | Lifecycle state of a user. |", index)
+        self.assertIn("A registered user | member.\n\nUsers are created at sign-up.", index)
+        self.assertIn("- `GetUser`([`User`](#user)) returns (stream [`User`](#user))", index)
+        self.assertIn("- `address` [`acme.common.v1.Address`](../../common/v1/README.md#address) = 1", index)
+        self.assertIn("- `created` `google.protobuf.Timestamp` = 2", index)
+        self.assertIn("- `tags` repeated `string` = 3: Free-form labels.", index)
+        self.assertIn("| [Address](#address) | A postal address. |", self.read("acme/common/v1/README.md"))
+
+    def test_unknown_targets(self):
+        with self.assertRaisesRegex(DocIndexError, "acme/missing.proto"):
+            write_indexes(descriptor_set(USER_FILE), ["acme/missing.proto"], self.temp_dir)
+
+    def test_summarize(self):
+        self.assertEqual(summarize(" Returns the user.\n Fails if it does not exist.\n"), "Returns the user.")
+        self.assertEqual(summarize(" Deprecated: use v2\n\n Details.\n"), "Deprecated: use v2")
+
+
+if __name__ == "__main__":
+    unittest.main()
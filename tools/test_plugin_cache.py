@@ -0,0 +1,158 @@
+#!/usr/bin/env python3
+"""
+Test suite for the content-addressed plugin cache.
+"""
+
+import hashlib
+import io
+import multiprocessing
+import os
+import shutil
+import sys
+import tarfile
+import tempfile
+import time
+import unittest
+from pathlib import Path
+from unittest.mock import patch
+
+try:
+    from download_plugins import PluginDownloader
+    from plugin_cache import PLUGIN_CACHE_ENV, PluginCache, PluginCacheError, plugin_cache_root
+except ImportError:
+    sys.path.append(str(Path(__file__).parent))
+    from download_plugins import PluginDownloader
+    from plugin_cache import PLUGIN_CACHE_ENV, PluginCache, PluginCacheError, plugin_cache_root
+
+# Large enough that a reader racing a non-atomic write would see a short file
+BINARY = b"\x7fELF" + b"\xab" * (4 * 1024 * 1024)
+DIGEST = hashlib.sha256(BINARY).hexdigest()
+
+
+def slow_populate(staging_dir: Path, log_path: Path) -> str:
+    """Writes the plugin in small chunks, recording each download."""
+    with open(log_path, "a", encoding="utf-8") as log:
+        log.write(f"{os.getpid()}\n")
+    binary = staging_dir / "bin" / "protoc-gen-test"
+    binary.parent.mkdir(parents=True)
+    with open(binary, "wb") as f:
+        for start in range(0, len(BINARY), 256 * 1024):
+            f.write(BINARY[start:start + 256 * 1024])
+            f.flush()
+            time.sleep(0.01)
+    return DIGEST
+
+
+def fetch_in_process(root: str, log_path: str, start, results) -> None:
+    start.wait()
+    cache = PluginCache(Path(root))
+    binary = cache.fetch("protoc-gen-test", "1.0.0", "linux-x86_64", "bin/protoc-gen-test",
+                         lambda staging: slow_populate(staging, Path(log_path)), DIGEST)
+    results.put(hashlib.sha256(binary.read_bytes()).hexdigest())
+
+
+class TestPluginCache(unittest.TestCase):
+    """Test fetching and looking up cache entries."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.root = Path(self.temp_dir) / "plugins"
+        self.cache = PluginCache(self.root)
+        self.log = Path(self.temp_dir) / "downloads.log"
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def populate(self, staging_dir: Path) -> str:
+        return slow_populate(staging_dir, self.log)
+
+    def downloads(self) -> int:
+        return len(self.log.read_text().splitlines()) if self.log.exists() else 0
+
+    def test_entry_is_keyed_by_content(self):
+        binary = self.cache.fetch("protoc-gen-test", "1.0.0", "linux-x86_64", "bin/protoc-gen-test",
+                                  self.populate, DIGEST.upper())
+        self.assertEqual(binary, self.root / f"protoc-gen-test-1.0.0-linux-x86_64-{DIGEST}" / "bin" / "protoc-gen-test")
+        self.assertEqual(binary.read_bytes(), BINARY)
+        self.assertEqual([p.name for p in self.root.iterdir() if p.name.startswith(".staging-")], [])
+
+    def test_cached_versions_are_not_downloaded_again(self):
+        for version in ("1.0.0", "2.0.0", "1.0.0", "2.0.0"):
+            self.cache.fetch("protoc-gen-test", version, "linux-x86_64", "bin/protoc-gen-test", self.populate)
+        self.assertEqual(self.downloads(), 2)
+        self.assertIsNotNone(self.cache.lookup("protoc-gen-test", "1.0.0", "linux-x86_64", "bin/protoc-gen-test"))
+        self.assertIsNone(self.cache.lookup("protoc-gen-test", "3.0.0", "linux-x86_64", "bin/protoc-gen-test"))
+
+    def test_checksum_mismatch_publishes_nothing(self):
+        with self.assertRaises(PluginCacheError):
+            self.cache.fetch("protoc-gen-test", "1.0.0", "linux-x86_64", "bin/protoc-gen-test",
+                             self.populate, "0" * 64)
+        self.assertEqual(sorted(p.name for p in self.root.iterdir()), ["locks"])
+
+    def test_concurrent_fetches_download_once(self):
+        context = multiprocessing.get_context("fork")
+        start, results = context.Event(), context.Queue()
+        workers = [context.Process(target=fetch_in_process, args=(str(self.root), str(self.log), start, results))
+                   for _ in range(2)]
+        for worker in workers:
+            worker.start()
+        start.set()
+        for worker in workers:
+            worker.join(timeout=60)
+            self.assertEqual(worker.exitcode, 0)
+
+        self.assertEqual([results.get(timeout=5), results.get(timeout=5)], [DIGEST, DIGEST])
+        self.assertEqual(self.downloads(), 1)
+        entries = sorted(p.name for p in self.root.iterdir() if p.name not in ("index", "locks"))
+        self.assertEqual(entries, [f"protoc-gen-test-1.0.0-linux-x86_64-{DIGEST}"])
+
+    def test_root_from_environment(self):
+        with patch.dict(os.environ, {PLUGIN_CACHE_ENV: "/ci/plugins"}):
+            self.assertEqual(plugin_cache_root(), Path("/ci/plugins"))
+            self.assertEqual(plugin_cache_root("/explicit"), Path("/explicit"))
+        with patch.dict(os.environ, {}, clear=True):
+            self.assertEqual(plugin_cache_root(), Path(os.path.expanduser("~/.cache/buck2-protobuf/plugins")))
+
+
+class TestDownloaderUsesPluginCache(unittest.TestCase):
+    """Test that HTTP plugin downloads go through the plugin cache."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        archive = io.BytesIO()
+        with tarfile.open(fileobj=archive, mode="w:gz") as tar:
+            info = tarfile.TarInfo("protoc-gen-test")
+            info.size = len(BINARY)
+            tar.addfile(info, io.BytesIO(BINARY))
+        self.archive = archive.getvalue()
+        self.checksum = hashlib.sha256(self.archive).hexdigest()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def downloader(self, action_dir: str) -> PluginDownloader:
+        downloader = PluginDownloader(os.path.join(self.temp_dir, action_dir),
+                                      plugin_cache_dir=os.path.join(self.temp_dir, "plugins"))
+        downloader.plugin_config = {"protoc-gen-test": {"1.0.0": {"linux-x86_64": {
+            "url": "https://example.com/protoc-gen-test.tar.gz",
+            "sha256": self.checksum,
+            "binary_path": "protoc-gen-test",
+        }}}}
+        return downloader
+
+    def test_second_action_reuses_download(self):
+        def fake_download(url, output_path, max_retries=3):
+            output_path.write_bytes(self.archive)
+            return True
+
+        with patch.object(PluginDownloader, "download_with_retry", side_effect=fake_download) as download:
+            first = self.downloader("action1").download_plugin("protoc-gen-test", "1.0.0", "linux-x86_64")
+            second = self.downloader("action2").download_plugin("protoc-gen-test", "1.0.0", "linux-x86_64")
+        self.assertEqual(download.call_count, 1)
+        self.assertEqual(first, second)
+        self.assertEqual(Path(first).read_bytes(), BINARY)
+        self.assertIn(self.checksum, first)
+
+
+if __name__ == "__main__":
+    unittest.main()
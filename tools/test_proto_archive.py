@@ -0,0 +1,102 @@
+#!/usr/bin/env python3
+"""
+Test suite for deterministic distribution archives.
+"""
+
+import os
+import shutil
+import tarfile
+import tempfile
+import time
+import unittest
+import zipfile
+from pathlib import Path
+
+try:
+    from proto_archive import FIXED_MTIME, ArchiveError, archive_path, plan_entries, write_tar_gz, write_zip
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from proto_archive import FIXED_MTIME, ArchiveError, archive_path, plan_entries, write_tar_gz, write_zip
+
+
+class ArchiveTestCase(unittest.TestCase):
+    """Base class providing temporary source files."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, name: str, content: str) -> str:
+        path = os.path.join(self.temp_dir, name)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+        return path
+
+    def entries(self):
+        return plan_entries("user-sdk-1.2.0", [
+            ("python/user_pb2.py", self.write("py/user_pb2.py", "# python\n")),
+            ("go/user.pb.go", self.write("go/user.pb.go", "package userv1\n")),
+            ("README.md", self.write("README.md", "# User SDK\n")),
+        ])
+
+
+class TestLayout(ArchiveTestCase):
+    """Test archive path planning."""
+
+    def test_prefix_and_sorting(self):
+        self.assertEqual([name for name, _ in self.entries()], [
+            "user-sdk-1.2.0/README.md",
+            "user-sdk-1.2.0/go/user.pb.go",
+            "user-sdk-1.2.0/python/user_pb2.py",
+        ])
+
+    def test_paths_are_normalized(self):
+        self.assertEqual(archive_path("", "./go/user.pb.go"), "go/user.pb.go")
+        self.assertEqual(archive_path("/sdk/", "/go/user.pb.go"), "sdk/go/user.pb.go")
+        with self.assertRaises(ArchiveError):
+            archive_path("sdk", "../escape.txt")
+
+    def test_collisions(self):
+        first = self.write("a/user.pb.go", "a")
+        second = self.write("b/user.pb.go", "b")
+        with self.assertRaises(ArchiveError):
+            plan_entries("", [("go/user.pb.go", first), ("go/user.pb.go", second)])
+
+
+class TestDeterminism(ArchiveTestCase):
+    """Test that archives are reproducible."""
+
+    def build_twice(self, writer, suffix):
+        entries = self.entries()
+        first = os.path.join(self.temp_dir, "first" + suffix)
+        writer(first, entries)
+        # Source timestamps must not leak into the archive
+        for _, source in entries:
+            os.utime(source, (time.time() + 3600, time.time() + 3600))
+        second = os.path.join(self.temp_dir, "second" + suffix)
+        writer(second, entries)
+        self.assertEqual(Path(first).read_bytes(), Path(second).read_bytes())
+        return first
+
+    def test_tar_gz(self):
+        archive = self.build_twice(write_tar_gz, ".tar.gz")
+        with tarfile.open(archive, "r:gz") as tar:
+            members = tar.getmembers()
+            self.assertEqual([m.name for m in members][0], "user-sdk-1.2.0/README.md")
+            self.assertTrue(all(m.mtime == FIXED_MTIME and m.uid == 0 and m.mode == 0o644 for m in members))
+            self.assertEqual(tar.extractfile("user-sdk-1.2.0/go/user.pb.go").read(), b"package userv1\n")
+
+    def test_zip(self):
+        archive = self.build_twice(write_zip, ".zip")
+        with zipfile.ZipFile(archive) as zf:
+            self.assertEqual(zf.namelist()[1], "user-sdk-1.2.0/go/user.pb.go")
+            self.assertEqual(zf.getinfo("user-sdk-1.2.0/README.md").date_time, (1980, 1, 1, 0, 0, 0))
+            self.assertEqual(zf.read("user-sdk-1.2.0/python/user_pb2.py"), b"# python\n")
+
+
+if __name__ == "__main__":
+    unittest.main()
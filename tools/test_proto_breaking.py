@@ -0,0 +1,100 @@
+#!/usr/bin/env python3
+"""
+Test suite for the baseline breaking change check.
+"""
+
+import json
+import os
+import shutil
+import stat
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from proto_breaking import BreakingCheckError, Violation, buf_config, parse_violations, run_buf_breaking
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from proto_breaking import BreakingCheckError, Violation, buf_config, parse_violations, run_buf_breaking
+
+
+TYPE_CHANGE = {
+    "path": "acme/user.proto", "start_line": 7, "start_column": 3, "end_line": 7, "end_column": 20,
+    "type": "FIELD_SAME_TYPE",
+    "message": 'Field "2" with name "email" on message "User" changed type from "string" to "int32".',
+}
+DELETED_FIELD = {
+    "path": "acme/user.proto", "start_line": 5, "start_column": 1, "end_line": 9, "end_column": 2,
+    "type": "FIELD_NO_DELETE",
+    "message": 'Previously present field "3" with name "age" on message "User" was deleted.',
+}
+
+
+class TestParseViolations(unittest.TestCase):
+    """Test parsing buf's JSON output."""
+
+    def test_sorted_and_deduplicated(self):
+        output = "\n".join(json.dumps(entry) for entry in [TYPE_CHANGE, DELETED_FIELD, TYPE_CHANGE]) + "\n"
+        violations = parse_violations(output)
+        self.assertEqual([v.rule for v in violations], ["FIELD_NO_DELETE", "FIELD_SAME_TYPE"])
+        self.assertEqual(
+            str(violations[1]),
+            'acme/user.proto:7:3: [FIELD_SAME_TYPE] Field "2" with name "email" on message "User" '
+            'changed type from "string" to "int32".',
+        )
+
+    def test_file_level_violation(self):
+        violation = parse_violations(json.dumps({"path": "acme/old.proto", "type": "FILE_NO_DELETE",
+                                                 "message": 'Previously present file "acme/old.proto" was deleted.'}))[0]
+        self.assertEqual(violation, Violation("acme/old.proto", 0, 0, "FILE_NO_DELETE", violation.message))
+        self.assertTrue(str(violation).startswith("acme/old.proto: [FILE_NO_DELETE]"))
+
+    def test_unexpected_output(self):
+        with self.assertRaisesRegex(BreakingCheckError, "unexpected buf output"):
+            parse_violations("Failure: something went wrong")
+
+    def test_buf_config(self):
+        self.assertEqual(json.loads(buf_config(["FILE", "WIRE", "FILE"])),
+                         {"version": "v1", "breaking": {"use": ["WIRE", "FILE"]}})
+        with self.assertRaisesRegex(BreakingCheckError, "unknown rule set 'PACKAGE'"):
+            buf_config(["PACKAGE"])
+
+
+class TestRunBufBreaking(unittest.TestCase):
+    """Test running buf against fake binaries."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def fake_buf(self, script: str) -> str:
+        path = os.path.join(self.temp_dir, "buf")
+        with open(path, "w", encoding="utf-8") as f:
+            f.write("#!/usr/bin/env python3\nimport sys\n" + script)
+        os.chmod(path, os.stat(path).st_mode | stat.S_IEXEC)
+        return path
+
+    def test_violations(self):
+        buf = self.fake_buf(
+            'assert sys.argv[1:5] == ["breaking", "current.binpb", "--against", "baseline.binpb"]\n'
+            f'print({json.dumps(json.dumps(TYPE_CHANGE))})\n'
+            'sys.exit(100)\n'
+        )
+        violations = run_buf_breaking(buf, "current.binpb", "baseline.binpb", ["WIRE"])
+        self.assertEqual([(v.path, v.line, v.rule) for v in violations], [("acme/user.proto", 7, "FIELD_SAME_TYPE")])
+
+    def test_compatible(self):
+        buf = self.fake_buf("sys.exit(0)\n")
+        self.assertEqual(run_buf_breaking(buf, "current.binpb", "baseline.binpb", ["WIRE"]), [])
+
+    def test_buf_failure(self):
+        buf = self.fake_buf('sys.exit("Failure: baseline.binpb: invalid FileDescriptorSet")\n')
+        with self.assertRaisesRegex(BreakingCheckError, "invalid FileDescriptorSet"):
+            run_buf_breaking(buf, "current.binpb", "baseline.binpb", ["WIRE"])
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,177 @@
+#!/usr/bin/env python3
+"""
+Test suite for cross-language conformance tests.
+"""
+
+import json
+import os
+import shutil
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from proto_conformance import (Codec, ConformanceError, check_fixture, decode_wire, generate,
+                                   json_divergence, wire_divergence)
+except ImportError:
+    sys.path.append(str(Path(__file__).parent))
+    from proto_conformance import (Codec, ConformanceError, check_fixture, decode_wire, generate,
+                                   json_divergence, wire_divergence)
+
+
+USER_PROTO = '''
+syntax = "proto3";
+package acme.v1;
+message Address {
+  string city = 1;
+  string zip_code = 2;
+}
+message User {
+  string name = 1;
+  Address address = 2;
+  repeated Address previous = 3;
+  map<string, int32> scores = 4;
+}
+'''
+
+# Fake codec: re-serializes binary unchanged, derives JSON from the request,
+# and in "drop-empty" mode omits empty strings as a runtime with different
+# default-value handling would
+FAKE_CODEC = '''
+import base64, json, sys
+mode = sys.argv[1]
+for line in sys.stdin:
+    request = json.loads(line)
+    if "json" in request:
+        value = request["json"]
+        if mode == "drop-empty":
+            value = {k: v for k, v in value.items() if v != ""}
+        binary = base64.b64encode(json.dumps(request["json"], sort_keys=True).encode()).decode()
+    else:
+        binary = request["binary"]
+        value = json.loads(base64.b64decode(binary))
+        if mode == "drop-empty":
+            value = {k: v for k, v in value.items() if v != ""}
+    sys.stdout.write(json.dumps({"binary": binary, "json": value}) + "\\n")
+    sys.stdout.flush()
+'''
+
+# User{name: "ada", address: {city: "Oslo", zip_code: "0150"}}
+ADDRESS = b"\x0a\x04Oslo\x12\x040150"
+USER = b"\x0a\x03ada\x12" + bytes([len(ADDRESS)]) + ADDRESS
+
+
+class ConformanceTestCase(unittest.TestCase):
+    """Base class providing temporary files."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, name: str, content: str) -> str:
+        path = os.path.join(self.temp_dir, name)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+        return path
+
+
+class TestGenerate(ConformanceTestCase):
+    """Test manifest generation."""
+
+    def test_manifest(self):
+        proto = self.write("user.proto", USER_PROTO)
+        fixture = self.write("user.json", '{"name": "ada"}')
+        manifest = generate([proto], [], [("acme.v1.User", "fixtures/0_user.json", fixture)],
+                            ["go:python", "python:typescript"])
+        self.assertEqual(manifest["pairs"], [["go", "python"], ["python", "typescript"]])
+        self.assertEqual(manifest["fixtures"], [{"name": fixture, "type": "acme.v1.User", "path": "fixtures/0_user.json"}])
+        self.assertEqual(manifest["fields"]["acme.v1.User"]["2"], {"name": "address", "message": "acme.v1.Address", "repeated": False})
+        self.assertEqual(manifest["fields"]["acme.v1.User"]["4"]["message"], None)
+
+    def test_invalid_inputs(self):
+        proto = self.write("user.proto", USER_PROTO)
+        fixture = self.write("user.json", '{"name": "ada"}')
+        with self.assertRaisesRegex(ConformanceError, "acme.v1.Missing"):
+            generate([proto], [], [("acme.v1.Missing", "fixtures/0_user.json", fixture)], ["go:python"])
+        with self.assertRaisesRegex(ConformanceError, "go:go"):
+            generate([proto], [], [("acme.v1.User", "fixtures/0_user.json", fixture)], ["go:go"])
+        broken = self.write("broken.json", "[1, 2]")
+        with self.assertRaisesRegex(ConformanceError, "JSON object"):
+            generate([proto], [], [("acme.v1.User", "fixtures/0_broken.json", broken)], ["go:python"])
+
+
+class TestDivergence(unittest.TestCase):
+    """Test locating the field that diverged."""
+
+    def setUp(self):
+        self.index = {
+            "acme.v1.User": {
+                "1": {"name": "name", "message": None, "repeated": False},
+                "2": {"name": "address", "message": "acme.v1.Address", "repeated": False},
+                "3": {"name": "previous", "message": "acme.v1.Address", "repeated": True},
+            },
+            "acme.v1.Address": {
+                "1": {"name": "city", "message": None, "repeated": False},
+                "2": {"name": "zip_code", "message": None, "repeated": False},
+            },
+        }
+
+    def test_decode_wire(self):
+        self.assertEqual(decode_wire(USER), [(1, 2, b"ada"), (2, 2, ADDRESS)])
+
+    def test_nested_field(self):
+        changed = USER.replace(b"\x12\x040150", b"")
+        changed = changed.replace(bytes([len(ADDRESS)]), bytes([len(ADDRESS) - 6]))
+        path, expected, actual = wire_divergence(self.index, "acme.v1.User", USER, changed)
+        self.assertEqual((path, expected, actual), ("address.zip_code", b"0150", b""))
+
+    def test_repeated_and_unknown_fields(self):
+        first = b"\x1a\x02\x0a\x00" + b"\x1a\x03\x0a\x01A"
+        second = b"\x1a\x02\x0a\x00" + b"\x1a\x03\x0a\x01B"
+        self.assertEqual(wire_divergence(self.index, "acme.v1.User", first, second)[0], "previous[1].city")
+        self.assertEqual(wire_divergence(self.index, "acme.v1.User", b"", b"\x48\x01")[0], "#9")
+
+    def test_field_order(self):
+        reordered = b"\x12" + bytes([len(ADDRESS)]) + ADDRESS + b"\x0a\x03ada"
+        self.assertEqual(wire_divergence(self.index, "acme.v1.User", USER, reordered)[0], "")
+
+    def test_json(self):
+        self.assertIsNone(json_divergence({"a": [1, {"b": 2}]}, {"a": [1.0, {"b": 2}]}))
+        self.assertEqual(json_divergence({"a": [1, {"b": 2}]}, {"a": [1, {"b": "2"}]}), ("a[1].b", 2, "2"))
+        self.assertEqual(json_divergence({"a": True}, {"a": 1}), ("a", True, 1))
+        path, expected, _ = json_divergence({"a": 1, "zip": ""}, {"a": 1})
+        self.assertEqual((path, expected), ("zip", ""))
+
+
+class TestCheckFixture(ConformanceTestCase):
+    """Test running fixtures through fake codecs."""
+
+    def setUp(self):
+        super().setUp()
+        script = self.write("fake_codec.py", FAKE_CODEC)
+        self.codecs = []
+        for language, mode in (("go", "keep"), ("python", "drop-empty")):
+            self.codecs.append(Codec(language, [sys.executable, script, mode], self.temp_dir))
+
+    def tearDown(self):
+        for codec in self.codecs:
+            codec.close()
+        super().tearDown()
+
+    def test_default_value_mismatch_names_message_field_and_pair(self):
+        go, python = self.codecs
+        fixture = {"name": "user.json", "type": "acme.v1.User"}
+        failures = check_fixture(fixture, {"name": "ada", "zipCode": ""}, go, python, {})
+        self.assertEqual(failures, [
+            'go -> python acme.v1.User (user.json): JSON differs at zipCode: go wrote "", '
+            'python re-serialized <absent>',
+        ])
+        self.assertEqual(check_fixture(fixture, {"name": "ada"}, go, python, {}), [])
+        self.assertEqual(check_fixture(fixture, {"name": "ada", "zipCode": ""}, python, go, {}), [])
+
+
+if __name__ == "__main__":
+    unittest.main()
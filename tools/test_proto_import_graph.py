@@ -0,0 +1,94 @@
+#!/usr/bin/env python3
+"""
+Test suite for the per-file codegen import graph and its benchmark.
+"""
+
+import json
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from codegen_cache_bench import count_protoc_runs
+    from proto_import_graph import direct_imports, transitive_imports
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from codegen_cache_bench import count_protoc_runs
+    from proto_import_graph import direct_imports, transitive_imports
+
+
+class TestProtoImportGraph(unittest.TestCase):
+    """Test the imports computed for each file of a target."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, name: str, content: str) -> str:
+        path = os.path.join(self.temp_dir, name.replace("/", "_"))
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+        return path
+
+    def test_transitive_target_imports(self):
+        files = {
+            "shop/item.proto": self.write("shop/item.proto", '''syntax = "proto3";
+package shop;
+import "shop/price.proto";
+import "google/protobuf/timestamp.proto";
+message Item { Price price = 1; }
+'''),
+            "shop/price.proto": self.write("shop/price.proto", '''syntax = "proto3";
+package shop;
+import "shop/currency.proto";
+message Price { Currency currency = 1; }
+'''),
+            "shop/currency.proto": self.write("shop/currency.proto", '''syntax = "proto3";
+package shop;
+enum Currency { CURRENCY_UNSPECIFIED = 0; }
+'''),
+            "shop/stock.proto": self.write("shop/stock.proto", '''syntax = "proto3";
+package shop;
+message Stock {}
+'''),
+        }
+        graph = direct_imports(files)
+        self.assertEqual(graph["shop/item.proto"], ["shop/price.proto"])
+        self.assertEqual(transitive_imports(graph), {
+            "shop/item.proto": ["shop/currency.proto", "shop/price.proto"],
+            "shop/price.proto": ["shop/currency.proto"],
+            "shop/currency.proto": [],
+            "shop/stock.proto": [],
+        })
+
+    def test_cycles_terminate(self):
+        self.assertEqual(transitive_imports({"a.proto": ["b.proto"], "b.proto": ["a.proto"]}), {
+            "a.proto": ["b.proto"],
+            "b.proto": ["a.proto"],
+        })
+
+
+class TestCodegenCacheBench(unittest.TestCase):
+    """Test counting protoc invocations in buck2 what-ran output."""
+
+    def test_counts_executed_protoc_actions(self):
+        lines = [
+            json.dumps({"reason": "build", "identity": "root//shop:shop_go (go_protoc shop/item.proto)", "executor": "Local"}),
+            json.dumps({"reason": "build", "identity": "root//shop:shop_go (go_protoc shop/price.proto)", "executor": "Cache"}),
+            json.dumps({"reason": "build", "identity": "root//shop:shop_go (go_import_graph shop_go)", "executor": "Local"}),
+            json.dumps({"reason": "build", "identity": "root//shop:other_go (go_protoc_pipeline other_go_go_generation)", "executor": "Remote"}),
+            "",
+        ]
+        self.assertEqual(count_protoc_runs(lines), [
+            "root//shop:shop_go (go_protoc shop/item.proto)",
+            "root//shop:other_go (go_protoc_pipeline other_go_go_generation)",
+        ])
+
+
+if __name__ == "__main__":
+    unittest.main()
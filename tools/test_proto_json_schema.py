@@ -0,0 +1,131 @@
+#!/usr/bin/env python3
+"""
+Test suite for JSON Schema finalization.
+"""
+
+import json
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from proto_json_schema import JsonSchemaError, convert_draft, finalize, find_plugin_output, selected_messages
+    from proto_schema import load_schema_set
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from proto_json_schema import JsonSchemaError, convert_draft, finalize, find_plugin_output, selected_messages
+    from proto_schema import load_schema_set
+
+
+PROTO = '''
+syntax = "proto3";
+package acme.user.v1;
+import "buf/validate/validate.proto";
+
+message User {
+  string email = 1 [(buf.validate.field).string.email = true, (buf.validate.field).required = true];
+  int32 age = 2 [(buf.validate.field).int32 = {gte: 0, lt: 150}];
+  string handle = 3 [(buf.validate.field).string = {pattern: "^[a-z]+$", max_len: 20, prefix: "@"}];
+  repeated string tags = 4 [(buf.validate.field).repeated = {max_items: 5, items: {string: {min_len: 1}}}];
+  Address address = 5;
+}
+message Address {
+  string city = 1 [(buf.validate.field).string.min_len = 1];
+}
+message AuditEvent {
+  string actor = 1;
+}
+'''
+
+# protoc-gen-jsonschema output for User (json_fieldnames)
+PLUGIN_OUTPUT = {
+    "$schema": "http://json-schema.org/draft-04/schema#",
+    "$ref": "#/definitions/User",
+    "definitions": {
+        "User": {
+            "properties": {
+                "email": {"type": "string"},
+                "age": {"type": "integer"},
+                "handle": {"type": "string"},
+                "tags": {"items": {"type": "string"}, "type": "array"},
+                "address": {"$ref": "#/definitions/acme.user.v1.Address"},
+            },
+            "additionalProperties": True,
+            "type": "object",
+            "title": "User",
+        },
+        "acme.user.v1.Address": {
+            "properties": {"city": {"type": "string"}},
+            "type": "object",
+            "title": "Address",
+        },
+    },
+}
+
+
+class JsonSchemaTestCase(unittest.TestCase):
+    """Base class parsing the test schema."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        path = os.path.join(self.temp_dir, "user.proto")
+        Path(path).write_text(PROTO, encoding="utf-8")
+        self.schema = load_schema_set([path])
+        self.user = self.schema.types["acme.user.v1.User"]
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+
+class TestFinalize(JsonSchemaTestCase):
+    """Test protovalidate translation and drafts."""
+
+    def test_protovalidate_keywords(self):
+        document = finalize(json.loads(json.dumps(PLUGIN_OUTPUT)), self.user, self.schema, "draft-07")
+        self.assertEqual(document["$schema"], "http://json-schema.org/draft-07/schema#")
+        user = document["definitions"]["User"]
+        self.assertEqual(user["required"], ["email"])
+        self.assertEqual(user["properties"]["email"], {"type": "string", "format": "email"})
+        self.assertEqual(user["properties"]["age"], {"type": "integer", "minimum": 0, "exclusiveMaximum": 150})
+        self.assertEqual(user["properties"]["handle"]["pattern"], "^[a-z]+$")
+        self.assertEqual(user["properties"]["handle"]["maxLength"], 20)
+        self.assertIn("string.prefix", user["properties"]["handle"]["$comment"])
+        self.assertEqual(user["properties"]["tags"], {"items": {"type": "string", "minLength": 1},
+                                                      "type": "array", "maxItems": 5})
+        self.assertEqual(document["definitions"]["acme.user.v1.Address"]["properties"]["city"]["minLength"], 1)
+
+    def test_2020_12_uses_defs(self):
+        document = finalize(json.loads(json.dumps(PLUGIN_OUTPUT)), self.user, self.schema, "2020-12")
+        self.assertEqual(document["$schema"], "https://json-schema.org/draft/2020-12/schema")
+        self.assertEqual(document["$ref"], "#/$defs/User")
+        self.assertNotIn("definitions", document)
+        self.assertEqual(document["$defs"]["User"]["properties"]["address"]["$ref"], "#/$defs/acme.user.v1.Address")
+        with self.assertRaisesRegex(JsonSchemaError, "draft-04"):
+            convert_draft({}, "draft-04")
+
+
+class TestRoots(JsonSchemaTestCase):
+    """Test selecting messages and locating plugin output."""
+
+    def test_roots_exclude_unreachable_messages(self):
+        names = [m.full_name for m in selected_messages(self.schema, [])]
+        self.assertEqual(names, ["acme.user.v1.User", "acme.user.v1.Address", "acme.user.v1.AuditEvent"])
+        names = [m.full_name for m in selected_messages(self.schema, ["acme.user.v1.User"])]
+        self.assertEqual(names, ["acme.user.v1.User", "acme.user.v1.Address"])
+        with self.assertRaisesRegex(JsonSchemaError, "acme.user.v1.Missing"):
+            selected_messages(self.schema, ["acme.user.v1.Missing"])
+
+    def test_plugin_output_lookup(self):
+        raw = Path(self.temp_dir, "raw", "acme.user.v1")
+        raw.mkdir(parents=True)
+        (raw / "User.json").write_text("{}", encoding="utf-8")
+        self.assertEqual(find_plugin_output(raw.parent, self.user, "acme.user.v1"), raw / "User.json")
+        with self.assertRaisesRegex(JsonSchemaError, "AuditEvent"):
+            find_plugin_output(raw.parent, self.schema.types["acme.user.v1.AuditEvent"], "acme.user.v1")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,179 @@
+#!/usr/bin/env python3
+"""
+Test suite for the lightweight proto schema parser.
+"""
+
+import unittest
+from pathlib import Path
+
+try:
+    from proto_schema import (
+        ProtoParseError, SchemaSet, find_option, parse_proto, to_json_name,
+    )
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from proto_schema import (
+        ProtoParseError, SchemaSet, find_option, parse_proto, to_json_name,
+    )
+
+
+SAMPLE = '''
+syntax = "proto3";
+
+package acme.user.v1;
+
+import "google/api/annotations.proto";
+import public "acme/common.proto";
+
+option go_package = "github.com/acme/user/v1";
+
+// User is a person.
+message User {
+  option (acme.api.public) = true;
+
+  // Unique identifier.
+  string id = 1;
+  optional string nickname = 2 [json_name = "nick"];
+  repeated string tags = 3;  // Free-form tags
+  map<string, Address> addresses = 4;
+
+  // Exactly one contact method is set.
+  oneof contact {
+    string email = 5;
+    string phone = 6;
+  }
+
+  message Address {
+    string city = 1;
+  }
+
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+    KIND_ADMIN = 1 [deprecated = true];
+  }
+}
+
+service UserService {
+  // Fetches a user.
+  rpc GetUser(GetUserRequest) returns (User) {
+    option (google.api.http) = {
+      get: "/v1/users/{id}"
+      additional_bindings { post: "/v1/users:get" body: "*" }
+    };
+  }
+  rpc Watch(stream GetUserRequest) returns (stream User);
+}
+
+message GetUserRequest {
+  string id = 1 [(buf.validate.field).string.min_len = 1, (buf.validate.field).string.max_len = 64];
+}
+'''
+
+
+class TestParser(unittest.TestCase):
+    """Test parsing of declarations."""
+
+    def setUp(self):
+        self.file = parse_proto(SAMPLE, "acme/user/v1/user.proto")
+
+    def test_file_level(self):
+        self.assertEqual(self.file.syntax, "proto3")
+        self.assertEqual(self.file.package, "acme.user.v1")
+        self.assertEqual(self.file.imports, ["google/api/annotations.proto", "acme/common.proto"])
+        self.assertEqual(self.file.public_imports, ["acme/common.proto"])
+        self.assertEqual(self.file.options["go_package"], "github.com/acme/user/v1")
+
+    def test_messages_and_fields(self):
+        names = [m.full_name for m in self.file.all_messages()]
+        self.assertEqual(names, ["acme.user.v1.User", "acme.user.v1.User.Address", "acme.user.v1.GetUserRequest"])
+
+        user = self.file.messages[0]
+        self.assertEqual(user.leading_comment, "User is a person.")
+        self.assertIs(find_option(user.options, "acme.api.public"), True)
+
+        fields = {f.name: f for f in user.fields}
+        self.assertEqual(fields["id"].leading_comment, "Unique identifier.")
+        self.assertEqual(fields["nickname"].label, "optional")
+        self.assertEqual(fields["nickname"].json_name, "nick")
+        self.assertEqual(fields["tags"].cardinality, "repeated")
+        self.assertEqual(fields["tags"].trailing_comment, "Free-form tags")
+        self.assertEqual(fields["addresses"].cardinality, "map")
+        self.assertEqual(fields["addresses"].map_value_type, "Address")
+        self.assertEqual(fields["email"].oneof, "contact")
+        self.assertEqual(user.oneofs[0].leading_comment, "Exactly one contact method is set.")
+        self.assertEqual(user.oneofs[0].fields, ["email", "phone"])
+
+    def test_nested_enum(self):
+        enums = list(self.file.all_enums())
+        self.assertEqual(enums[0].full_name, "acme.user.v1.User.Kind")
+        self.assertEqual([v.number for v in enums[0].values], [0, 1])
+        self.assertIs(enums[0].values[1].options["deprecated"], True)
+
+    def test_aggregate_field_options(self):
+        request = self.file.messages[1]
+        rules = find_option(request.fields[0].options, "buf.validate.field")
+        self.assertEqual(rules, {"string": {"min_len": 1, "max_len": 64}})
+
+    def test_services(self):
+        service = self.file.services[0]
+        self.assertEqual(service.full_name, "acme.user.v1.UserService")
+        get_user, watch = service.methods
+        self.assertEqual(get_user.leading_comment, "Fetches a user.")
+        self.assertEqual(get_user.http_rules(), [("GET", "/v1/users/{id}"), ("POST", "/v1/users:get")])
+        self.assertTrue(watch.client_streaming)
+        self.assertTrue(watch.server_streaming)
+
+    def test_type_resolution(self):
+        schema = SchemaSet([self.file])
+        self.assertEqual(schema.resolve_type_name("Address", "acme.user.v1.User"), "acme.user.v1.User.Address")
+        self.assertEqual(schema.resolve_type_name("User", "acme.user.v1.UserService"), "acme.user.v1.User")
+        self.assertIsNone(schema.resolve_type_name("Missing", "acme.user.v1"))
+
+    def test_type_named_like_label(self):
+        proto = parse_proto('''
+            syntax = "proto3";
+            message optional { string id = 1; }
+            message Holder {
+              optional value = 1;
+              repeated optional history = 2;
+            }
+        ''')
+        value, history = proto.messages[1].fields
+        self.assertEqual((value.label, value.type_name, value.name), ("", "optional", "value"))
+        self.assertEqual((history.label, history.type_name, history.name), ("repeated", "optional", "history"))
+
+    def test_proto2_groups(self):
+        proto = parse_proto('''
+            syntax = "proto2";
+            message Outer {
+              optional group Result = 1 {
+                required string url = 2 [default = "none"];
+              }
+            }
+        ''')
+        outer = proto.messages[0]
+        self.assertTrue(outer.fields[0].is_group)
+        self.assertEqual(outer.messages[0].name, "Result")
+        self.assertEqual(outer.messages[0].fields[0].label, "required")
+        self.assertEqual(outer.messages[0].fields[0].default, "none")
+
+    def test_parse_error_reports_line(self):
+        with self.assertRaises(ProtoParseError) as cm:
+            parse_proto('syntax = "proto3";\nmessage Broken {\n  string = 1;\n}\n', "broken.proto")
+        self.assertEqual(cm.exception.line, 3)
+
+    def test_string_escapes(self):
+        proto = parse_proto(r'''
+            syntax = "proto2";
+            message M { optional bytes b = 1 [default = "\x41\101\n\u00e9"]; }
+        ''')
+        self.assertEqual(proto.messages[0].fields[0].default, "AA\n\u00e9")
+
+    def test_json_name(self):
+        self.assertEqual(to_json_name("display_name"), "displayName")
+        self.assertEqual(to_json_name("x_y_z"), "xYZ")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,95 @@
+#!/usr/bin/env python3
+"""
+Test suite for CUE schema generation.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from proto_to_cue import CueTranslationError, translate_files
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from proto_to_cue import CueTranslationError, translate_files
+
+
+CONFIG_PROTO = '''
+syntax = "proto3";
+package acme.config.v1;
+import "google/protobuf/timestamp.proto";
+
+// Server configuration.
+message Server {
+  string host = 1 [(buf.validate.field).string.min_len = 1];
+  uint32 port = 2 [(buf.validate.field).uint32 = {gt: 0, lte: 65535}];
+  repeated string tags = 3 [(buf.validate.field).repeated.max_items = 5];
+  map<string, Limits> limits = 4;
+  google.protobuf.Timestamp created_at = 5;
+  Mode mode = 6 [(buf.validate.field).required = true];
+  oneof auth {
+    string token = 7;
+    string email = 8 [(buf.validate.field).string.email = true];
+  }
+
+  message Limits {
+    int32 max = 1;
+  }
+}
+
+enum Mode {
+  MODE_UNSPECIFIED = 0;
+  MODE_FAST = 1;
+}
+'''
+
+
+class TestProtoToCue(unittest.TestCase):
+    """Test translation of proto files into CUE."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.proto = os.path.join(self.temp_dir, "config.proto")
+        with open(self.proto, "w", encoding="utf-8") as f:
+            f.write(CONFIG_PROTO)
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def test_definitions(self):
+        cue, _ = translate_files([self.proto], package="config")
+        self.assertIn("package config", cue)
+        self.assertIn('#Mode: "MODE_UNSPECIFIED" | "MODE_FAST"', cue)
+        self.assertIn("// Server configuration.\n#Server: {", cue)
+        self.assertIn("\tlimits?: {[string]: #Server.#Limits}", cue)
+        self.assertIn("\t#Limits: {\n\t\tmax?: int32\n\t}", cue)
+        self.assertIn("\tcreatedAt?: time.Time", cue)
+
+    def test_protovalidate_constraints(self):
+        cue, _ = translate_files([self.proto])
+        self.assertIn("\thost?: string & strings.MinRunes(1)", cue)
+        self.assertIn("\tport?: uint32 & >0 & <=65535", cue)
+        self.assertIn("\ttags?: [...string] & list.MaxItems(5)", cue)
+        self.assertIn("\tmode: #Mode", cue)
+        self.assertIn('import (\n\t"list"\n\t"strings"\n\t"time"\n)', cue)
+
+    def test_unmappable_constructs_are_reported(self):
+        cue, unmappable = translate_files([self.proto])
+        self.assertIn("\t// unmappable: string.email\n\temail?: string", cue)
+        self.assertEqual(unmappable, [
+            "acme.config.v1.Server.email: string.email",
+            "acme.config.v1.Server.auth: oneof exclusivity is not enforced; members are optional",
+        ])
+
+    def test_proto_field_names(self):
+        cue, _ = translate_files([self.proto], field_names="proto")
+        self.assertIn("\tcreated_at?: time.Time", cue)
+        with self.assertRaises(CueTranslationError):
+            translate_files([self.proto], field_names="kebab")
+
+
+if __name__ == "__main__":
+    unittest.main()
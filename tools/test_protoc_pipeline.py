@@ -0,0 +1,214 @@
+#!/usr/bin/env python3
+"""
+Test suite for the sequential protoc plugin pipeline.
+"""
+
+import json
+import os
+import shutil
+import stat
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from protoc_pipeline import (
+        PipelineError, Profile, Stage, copy_outputs, parse_memory_limit, parse_stages, ran_out_of_memory, run_pipeline,
+        stage_command,
+    )
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from protoc_pipeline import (
+        PipelineError, Profile, Stage, copy_outputs, parse_memory_limit, parse_stages, ran_out_of_memory, run_pipeline,
+        stage_command,
+    )
+
+
+# Stands in for protoc: "--gen_out=DIR" writes user.pb.go, "--tag_out=DIR"
+# appends a tag to it (failing if it does not exist yet), "--src_out=DIR"
+# writes it with a header embedding absolute paths, "--fail_out" fails and
+# "--plugin=NAME=PATH" runs the plugin.
+FAKE_PROTOC = '''#!/usr/bin/env python3
+import os
+import subprocess
+import sys
+from pathlib import Path
+for arg in sys.argv[1:]:
+    if arg.startswith("--plugin="):
+        name, path = arg[len("--plugin="):].split("=", 1)
+        if subprocess.run([path], input=b"").returncode != 0:
+            sys.exit(name + ": Plugin failed")
+    elif arg.startswith("--gen_out="):
+        out = Path(arg.split("=", 1)[1]) / "acme" / "v1"
+        out.mkdir(parents=True, exist_ok=True)
+        (out / "user.pb.go").write_text("type User struct{}\\n")
+    elif arg.startswith("--tag_out="):
+        path = Path(arg.split("=", 1)[1]) / "acme" / "v1" / "user.pb.go"
+        if not path.exists():
+            sys.exit("user.pb.go not generated yet")
+        path.write_text(path.read_text() + "// tagged\\n")
+    elif arg.startswith("--src_out="):
+        out = Path(arg.split("=", 1)[1]) / "acme" / "v1"
+        out.mkdir(parents=True, exist_ok=True)
+        (out / "user.pb.go").write_text(f"// source: {os.getcwd()}/acme/v1/user.proto\\n// output: {out}/user.pb.go\\n")
+    elif arg == "--fail_out":
+        sys.exit("plugin crashed")
+'''
+
+
+class TestParseStages(unittest.TestCase):
+    """Test splitting pipeline arguments into stages."""
+
+    def test_common_args_and_stages(self):
+        common, stages = parse_stages([
+            "--proto_path=.", "user.proto",
+            "--stage", "go", "--go_out={out_dir}",
+            "--stage", "gotag", "--gotag_out=outdir={out_dir}:{out_dir}",
+        ])
+        self.assertEqual(common, ["--proto_path=.", "user.proto"])
+        self.assertEqual([s.plugin for s in stages], ["go", "gotag"])
+        self.assertEqual(stages[1].args, ["--gotag_out=outdir={out_dir}:{out_dir}"])
+
+    def test_requires_stages(self):
+        with self.assertRaises(PipelineError):
+            parse_stages(["user.proto"])
+        with self.assertRaises(PipelineError):
+            parse_stages(["user.proto", "--stage"])
+
+    def test_out_dir_placeholder(self):
+        command = stage_command("protoc", ["a.proto"], Stage("gotag", ["--gotag_out=outdir={out_dir}:{out_dir}"]), "/tmp/x")
+        self.assertEqual(command, ["protoc", "a.proto", "--gotag_out=outdir=/tmp/x:/tmp/x"])
+
+
+class TestRunPipeline(unittest.TestCase):
+    """Test running stages against a fake protoc."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.protoc = os.path.join(self.temp_dir, "protoc")
+        with open(self.protoc, "w", encoding="utf-8") as f:
+            f.write(FAKE_PROTOC)
+        os.chmod(self.protoc, os.stat(self.protoc).st_mode | stat.S_IEXEC)
+        self.out_dir = os.path.join(self.temp_dir, "staged")
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def test_later_stages_see_earlier_outputs(self):
+        stages = [Stage("gen", ["--gen_out={out_dir}"]), Stage("tag", ["--tag_out={out_dir}"])]
+        run_pipeline(self.protoc, [], stages, self.out_dir)
+        output = os.path.join(self.temp_dir, "user.pb.go")
+        copy_outputs(self.out_dir, [output])
+        self.assertEqual(Path(output).read_text(), "type User struct{}\n// tagged\n")
+
+    def test_outputs_are_identical_across_sandboxes(self):
+        outputs = []
+        for sandbox in ("first", "second"):
+            out_dir = os.path.join(self.temp_dir, sandbox, "staged")
+            run_pipeline(self.protoc, [], [Stage("src", ["--src_out={out_dir}"])], out_dir)
+            output = os.path.join(self.temp_dir, sandbox, "user.pb.go")
+            copy_outputs(out_dir, [output], strip_prefixes=[out_dir, os.getcwd()])
+            outputs.append(Path(output).read_bytes())
+        self.assertEqual(outputs[0], outputs[1])
+        self.assertEqual(outputs[0], b"// source: acme/v1/user.proto\n// output: acme/v1/user.pb.go\n")
+
+    def test_order_matters(self):
+        stages = [Stage("tag", ["--tag_out={out_dir}"]), Stage("gen", ["--gen_out={out_dir}"])]
+        with self.assertRaisesRegex(PipelineError, r"stage 1/2 \(tag\) failed"):
+            run_pipeline(self.protoc, [], stages, self.out_dir)
+
+    def test_failing_stage_stops_pipeline(self):
+        stages = [Stage("broken", ["--fail_out"]), Stage("gen", ["--gen_out={out_dir}"])]
+        with self.assertRaisesRegex(PipelineError, "plugin crashed"):
+            run_pipeline(self.protoc, [], stages, self.out_dir)
+        self.assertFalse(os.path.exists(os.path.join(self.out_dir, "acme")))
+
+    def test_profile_records_stages(self):
+        profile = Profile("//acme:user_go")
+        stages = [Stage("gen", ["--gen_out={out_dir}"]), Stage("tag", ["--tag_out={out_dir}"])]
+        run_pipeline(self.protoc, [], stages, self.out_dir, profile)
+        self.assertEqual([(e["name"], e["args"]) for e in profile.events],
+                         [("protoc-gen-gen", {"stage": 1}), ("protoc-gen-tag", {"stage": 2})])
+        self.assertTrue(all(e["ph"] == "X" and e["dur"] >= 0 for e in profile.events))
+        path = profile.write(os.path.join(self.temp_dir, "profiles"))
+        self.assertEqual(Path(path).name, "acme_user_go.trace.json")
+        self.assertEqual(json.loads(Path(path).read_text())["traceEvents"][0]["args"], {"name": "//acme:user_go"})
+
+    def test_plugin_paths_are_checked(self):
+        plugin = os.path.join(self.temp_dir, "protoc-gen-gen")
+        with open(plugin, "w", encoding="utf-8") as f:
+            f.write("#!/bin/sh\n")
+        stage = Stage("gen", [f"--plugin=protoc-gen-gen={plugin}", "--gen_out={out_dir}"])
+        with self.assertRaisesRegex(PipelineError, "protoc-gen-gen at .* is not executable"):
+            run_pipeline(self.protoc, [], [stage], self.out_dir)
+        os.chmod(plugin, os.stat(plugin).st_mode | stat.S_IEXEC)
+        run_pipeline(self.protoc, [], [stage], self.out_dir)
+
+        missing = Stage("gen", [f"--plugin=protoc-gen-gen={self.temp_dir}/bin/protoc-gen-gen", "--gen_out={out_dir}"])
+        with self.assertRaisesRegex(PipelineError, "protoc-gen-gen not found at .*/bin/protoc-gen-gen"):
+            run_pipeline(self.protoc, [], [missing], self.out_dir)
+
+    def write_plugin(self, name: str, body: str) -> str:
+        plugin = os.path.join(self.temp_dir, name)
+        with open(plugin, "w", encoding="utf-8") as f:
+            f.write(f"#!{sys.executable}\n{body}\n")
+        os.chmod(plugin, os.stat(plugin).st_mode | stat.S_IEXEC)
+        return plugin
+
+    @unittest.skipUnless(hasattr(os, "fork"), "plugin memory limits need rlimits")
+    def test_plugin_exceeding_memory_limit_is_named(self):
+        hog = self.write_plugin("protoc-gen-hog", "data = bytearray(1 << 30)")
+        stages = [Stage("hog", [f"--plugin=protoc-gen-hog={hog}", "--gen_out={out_dir}"])]
+        with self.assertRaisesRegex(PipelineError, r"protoc-gen-hog ran out of memory \(plugin memory limit 128 MiB"):
+            run_pipeline(self.protoc, [], stages, self.out_dir, memory_limit=parse_memory_limit("128M"))
+
+    @unittest.skipUnless(hasattr(os, "fork"), "plugin memory limits need rlimits")
+    def test_plugin_within_memory_limit_runs(self):
+        small = self.write_plugin("protoc-gen-small", "data = bytearray(1 << 20)")
+        stages = [Stage("small", [f"--plugin=protoc-gen-small={small}", "--gen_out={out_dir}"])]
+        run_pipeline(self.protoc, [], stages, self.out_dir, memory_limit=parse_memory_limit("256M"))
+        self.assertTrue(os.path.exists(os.path.join(self.out_dir, "acme", "v1", "user.pb.go")))
+
+    def test_other_failures_are_not_attributed_to_memory(self):
+        broken = self.write_plugin("protoc-gen-broken", "raise SystemExit('bad option')")
+        stages = [Stage("broken", [f"--plugin=protoc-gen-broken={broken}"])]
+        with self.assertRaises(PipelineError) as raised:
+            run_pipeline(self.protoc, [], stages, self.out_dir, memory_limit=parse_memory_limit("256M"))
+        self.assertNotIn("ran out of memory", str(raised.exception))
+        self.assertIn("bad option", str(raised.exception))
+
+    @unittest.skipUnless(hasattr(os, "fork"), "plugin memory limits need rlimits")
+    def test_plugin_crash_is_not_attributed_to_memory(self):
+        crash = self.write_plugin("protoc-gen-crash", "import os, signal\nos.kill(os.getpid(), signal.SIGSEGV)")
+        stages = [Stage("crash", [f"--plugin=protoc-gen-crash={crash}"])]
+        with self.assertRaises(PipelineError) as raised:
+            run_pipeline(self.protoc, [], stages, self.out_dir, memory_limit=parse_memory_limit("256M"))
+        self.assertNotIn("ran out of memory", str(raised.exception))
+        self.assertIn("protoc-gen-crash crashed with SIGSEGV", str(raised.exception))
+
+    def test_ran_out_of_memory(self):
+        self.assertTrue(ran_out_of_memory(-9, ""))
+        self.assertTrue(ran_out_of_memory(-6, "terminate called after throwing an instance of 'std::bad_alloc'"))
+        self.assertTrue(ran_out_of_memory(1, "MemoryError"))
+        self.assertFalse(ran_out_of_memory(-11, ""))
+        self.assertFalse(ran_out_of_memory(-6, "assertion failed"))
+        self.assertFalse(ran_out_of_memory(0, "out of memory"))
+
+    def test_parse_memory_limit(self):
+        self.assertEqual(parse_memory_limit("512M"), 512 << 20)
+        self.assertEqual(parse_memory_limit("2GiB"), 2 << 30)
+        self.assertEqual(parse_memory_limit("1.5g"), 3 << 29)
+        self.assertEqual(parse_memory_limit("4096"), 4096)
+        with self.assertRaises(PipelineError):
+            parse_memory_limit("lots")
+
+    def test_missing_output(self):
+        run_pipeline(self.protoc, [], [Stage("gen", ["--gen_out={out_dir}"])], self.out_dir)
+        with self.assertRaises(PipelineError):
+            copy_outputs(self.out_dir, [os.path.join(self.temp_dir, "user_grpc.pb.go")])
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,180 @@
+#!/usr/bin/env python3
+"""
+Test suite for Python package mapping of generated protobuf code.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from python_package_mapper import (
+        PackageMappingError, cyclic_packages, map_module, map_tree, parse_mapping, relative_import,
+    )
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from python_package_mapper import (
+        PackageMappingError, cyclic_packages, map_module, map_tree, parse_mapping, relative_import,
+    )
+
+
+USER_PB2 = '''from google.protobuf import descriptor as _descriptor
+from google.protobuf.internal import builder as _builder
+from acme.common import types_pb2 as acme_dot_common_dot_types__pb2
+
+_builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'acme.user.v1.user_pb2', _globals)
+'''
+
+TYPES_PB2 = '''from google.protobuf import descriptor as _descriptor
+'''
+
+
+class TestMappingFunctions(unittest.TestCase):
+    """Test mapping primitives."""
+
+    def test_longest_prefix_wins(self):
+        mapping = {"acme": "gen.acme", "acme/user": "myapp.users"}
+        self.assertEqual(map_module("acme.user.v1.user_pb2", mapping), "myapp.users.v1.user_pb2")
+        self.assertEqual(map_module("acme.common.types_pb2", mapping), "gen.acme.common.types_pb2")
+        self.assertEqual(map_module("google.protobuf.empty_pb2", mapping), "google.protobuf.empty_pb2")
+
+    def test_proto_file_key_wins(self):
+        mapping = {"acme/user": "myapp.users", "acme/user/legacy.proto": "myapp.legacy"}
+        self.assertEqual(map_module("acme.user.legacy_pb2", mapping), "myapp.legacy.legacy_pb2")
+        self.assertEqual(map_module("acme.user.legacy_pb2_grpc", mapping), "myapp.legacy.legacy_pb2_grpc")
+        self.assertEqual(map_module("acme.user.user_pb2", mapping), "myapp.users.user_pb2")
+
+    def test_relative_import(self):
+        self.assertEqual(relative_import("a.b.c_pb2", "a.b"), ".")
+        self.assertEqual(relative_import("a.b.c_pb2", "a.d"), "..d")
+        self.assertEqual(relative_import("a.b.c_pb2", "a.b.e"), ".e")
+
+    def test_cyclic_packages(self):
+        graph = {"a": {"b"}, "b": {"a", "c"}, "c": {"d"}, "d": {"d"}}
+        self.assertEqual(cyclic_packages(graph), [{"d"}, {"a", "b"}])
+
+    def test_parse_mapping_rejects_invalid_package(self):
+        with self.assertRaises(PackageMappingError):
+            parse_mapping(["acme=my-app.protos"])
+        with self.assertRaises(PackageMappingError):
+            parse_mapping(["acme"])
+
+
+class TestMapTree(unittest.TestCase):
+    """Test relocation of a generated tree."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.input_dir = os.path.join(self.temp_dir, "in")
+        self.output_dir = os.path.join(self.temp_dir, "out")
+        self.write("acme/user/v1/user_pb2.py", USER_PB2)
+        self.write("acme/common/types_pb2.py", TYPES_PB2)
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, relative_path: str, content: str) -> None:
+        path = os.path.join(self.input_dir, relative_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+
+    def read(self, relative_path: str) -> str:
+        with open(os.path.join(self.output_dir, relative_path), "r", encoding="utf-8") as f:
+            return f.read()
+
+    def test_absolute_imports_are_rewritten(self):
+        map_tree(self.input_dir, self.output_dir, {"acme": "myapp.protos"})
+        content = self.read("myapp/protos/user/v1/user_pb2.py")
+        self.assertIn("from myapp.protos.common import types_pb2 as acme_dot_common_dot_types__pb2", content)
+        self.assertIn("from google.protobuf import descriptor as _descriptor", content)
+        self.assertIn("'myapp.protos.user.v1.user_pb2'", content)
+        self.assertTrue(os.path.exists(os.path.join(self.output_dir, "myapp", "protos", "common", "__init__.py")))
+
+    def test_relative_imports(self):
+        map_tree(self.input_dir, self.output_dir, {"acme": "myapp.protos"}, relative=True, py_typed=True)
+        content = self.read("myapp/protos/user/v1/user_pb2.py")
+        self.assertIn("from ...common import types_pb2 as acme_dot_common_dot_types__pb2", content)
+        self.assertTrue(os.path.exists(os.path.join(self.output_dir, "myapp", "py.typed")))
+
+    def test_stub_references_are_renamed(self):
+        self.write("acme/user/v1/user_pb2.pyi", "import acme.common.types_pb2\n\nx: acme.common.types_pb2.Kind\n")
+        map_tree(self.input_dir, self.output_dir, {"acme": "gen"})
+        self.assertEqual(self.read("gen/user/v1/user_pb2.pyi"), "import gen.common.types_pb2\n\nx: gen.common.types_pb2.Kind\n")
+
+    def test_colliding_destinations_fail(self):
+        self.write("legacy/types_pb2.py", TYPES_PB2)
+        with self.assertRaises(PackageMappingError):
+            map_tree(self.input_dir, self.output_dir, {"acme/common": "shared", "legacy": "shared"})
+
+    def test_introduced_package_cycle_fails(self):
+        # user/v1 -> common -> user/v2 is acyclic until v1 and v2 share a package
+        self.write("acme/common/types_pb2.py", "from acme.user.v2 import extra_pb2 as extra__pb2\n")
+        self.write("acme/user/v2/extra_pb2.py", TYPES_PB2)
+        with self.assertRaises(PackageMappingError):
+            map_tree(self.input_dir, self.output_dir, {"acme/user/v2": "myapp.user", "acme/user/v1": "myapp.user"})
+
+
+    def test_deferred_strategy_breaks_package_cycle(self):
+        self.write("acme/common/types_pb2.py", "from acme.user.v2 import extra_pb2 as extra__pb2\n")
+        self.write("acme/user/v2/extra_pb2.py", TYPES_PB2)
+        mapping = {"acme/user/v2": "myapp.user", "acme/user/v1": "myapp.user", "acme/common": "myapp.common"}
+        result = map_tree(self.input_dir, self.output_dir, mapping, cycle_strategy="deferred")
+        self.assertEqual(result.cycles, [["myapp.common", "myapp.user", "myapp.common"]])
+        init = self.read("myapp/user/__init__.py")
+        self.assertIn('__all__ = ["extra_pb2", "user_pb2"]', init)
+        self.assertIn("def __getattr__(name):", init)
+        self.assertEqual(self.read("myapp/__init__.py"), '"""Generated Python protobuf package."""\n')
+
+    def test_deferred_init_imports_lazily(self):
+        self.write("acme/a/one_pb2.py", "from acme.b import two_pb2\nVALUE = 1\n")
+        self.write("acme/b/two_pb2.py", "from acme.a import three_pb2\n")
+        self.write("acme/a/three_pb2.py", "")
+        os.remove(os.path.join(self.input_dir, "acme/user/v1/user_pb2.py"))
+        os.remove(os.path.join(self.input_dir, "acme/common/types_pb2.py"))
+        map_tree(self.input_dir, self.output_dir, {"acme": "lazyacme"}, cycle_strategy="deferred")
+        import importlib
+        import sys
+        sys.path.insert(0, self.output_dir)
+        try:
+            package = importlib.import_module("lazyacme.a")
+            self.assertNotIn("lazyacme.a.one_pb2", sys.modules)
+            self.assertEqual(package.one_pb2.VALUE, 1)
+            with self.assertRaises(AttributeError):
+                package.missing_pb2
+        finally:
+            sys.path.remove(self.output_dir)
+            for name in [m for m in sys.modules if m.startswith("lazyacme")]:
+                del sys.modules[name]
+
+    def test_extend_path_merges_packages_across_trees(self):
+        other_dir = os.path.join(self.temp_dir, "other")
+        self.write("acme/user/v1/user_pb2.py", "VALUE = 1\n")
+        map_tree(self.input_dir, self.output_dir, {"acme": "splitacme"}, extend_path=True)
+        os.makedirs(os.path.join(other_dir, "acme", "billing"))
+        with open(os.path.join(other_dir, "acme", "billing", "invoice_pb2.py"), "w", encoding="utf-8") as f:
+            f.write("VALUE = 2\n")
+        map_tree(other_dir, os.path.join(self.temp_dir, "other_out"), {"acme": "splitacme"}, extend_path=True)
+        import importlib
+        import sys
+        roots = [self.output_dir, os.path.join(self.temp_dir, "other_out")]
+        sys.path[:0] = roots
+        try:
+            self.assertEqual(importlib.import_module("splitacme.user.v1.user_pb2").VALUE, 1)
+            self.assertEqual(importlib.import_module("splitacme.billing.invoice_pb2").VALUE, 2)
+        finally:
+            for root in roots:
+                sys.path.remove(root)
+            for name in [m for m in sys.modules if m.startswith("splitacme")]:
+                del sys.modules[name]
+
+    def test_unknown_cycle_strategy(self):
+        with self.assertRaises(PackageMappingError):
+            map_tree(self.input_dir, self.output_dir, {}, cycle_strategy="split")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,66 @@
+#!/usr/bin/env python3
+"""
+Test suite for the Rust crate source tree generator.
+"""
+
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from rust_crate_gen import CrateGenError, assemble_crate, package_files, render_mod_rs
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from rust_crate_gen import CrateGenError, assemble_crate, package_files, render_mod_rs
+
+
+class TestRustCrateGen(unittest.TestCase):
+    """Test mod.rs generation from per-package prost/tonic output."""
+
+    def setUp(self):
+        self.temp_dir = Path(tempfile.mkdtemp())
+        self.raw = self.temp_dir / "raw"
+        self.raw.mkdir()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def test_package_hierarchy(self):
+        packages = package_files(["acme.user.v1.tonic.rs", "acme.user.v1.rs", "acme.common.rs", "_.rs"])
+        self.assertEqual(packages["acme.user.v1"], ["acme.user.v1.rs", "acme.user.v1.tonic.rs"])
+        self.assertEqual(render_mod_rs(packages), "\n".join([
+            "// Generated by buck2-protobuf rust_proto_library. DO NOT EDIT.",
+            "",
+            'include!("_.rs");',
+            "pub mod acme {",
+            "    pub mod common {",
+            '        include!("acme.common.rs");',
+            "    }",
+            "    pub mod user {",
+            "        pub mod v1 {",
+            '            include!("acme.user.v1.rs");',
+            '            include!("acme.user.v1.tonic.rs");',
+            "        }",
+            "    }",
+            "}",
+        ]) + "\n")
+
+    def test_keyword_segments_are_raw_identifiers(self):
+        self.assertIn("pub mod r#type {", render_mod_rs(package_files(["acme.type.rs"])))
+
+    def test_assemble_crate(self):
+        (self.raw / "acme.v1.rs").write_text("pub struct User {}\n")
+        src = self.temp_dir / "src"
+        assemble_crate(self.raw, src, "acme_proto")
+        self.assertEqual(sorted(p.name for p in src.iterdir()), ["acme.v1.rs", "lib.rs", "mod.rs"])
+        self.assertIn('include!("mod.rs");', (src / "lib.rs").read_text())
+
+    def test_empty_output_fails(self):
+        with self.assertRaisesRegex(CrateGenError, "generated no Rust files"):
+            assemble_crate(self.raw, self.temp_dir / "src", "acme_proto")
+
+
+if __name__ == "__main__":
+    unittest.main()
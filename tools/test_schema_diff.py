@@ -0,0 +1,90 @@
+#!/usr/bin/env python3
+"""
+Test suite for schema diff summaries.
+"""
+
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from schema_diff import render_markdown, run_diff
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from schema_diff import render_markdown, run_diff
+
+
+BASE = '''
+syntax = "proto3";
+package acme.v1;
+message User { string id = 1; string email = 2; int32 age = 3; string legacy = 6; }
+enum Status { STATUS_UNSPECIFIED = 0; STATUS_ACTIVE = 1; }
+service Users { rpc Get(User) returns (User); rpc Delete(User) returns (User); }
+'''
+
+HEAD = '''
+syntax = "proto3";
+package acme.v1;
+message User { string id = 1; int64 age = 3; string nickname = 4; string old_legacy = 6; reserved 2; }
+message Page {}
+enum Status { STATUS_UNSPECIFIED = 0; STATUS_ACTIVE = 1; STATUS_BANNED = 2; }
+service Users { rpc Get(User) returns (User); rpc List(Page) returns (Page); }
+'''
+
+
+class TestSchemaDiff(unittest.TestCase):
+    """Test diffing and markdown rendering."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, name: str, content: str) -> str:
+        path = os.path.join(self.temp_dir, name)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+        return path
+
+    def diff(self, base: str, head: str):
+        return run_diff([self.write("head/user.proto", head)], [self.write("base/user.proto", base)])
+
+    def test_classifies_changes(self):
+        changes = {(c.action, c.element): c for c in self.diff(BASE, HEAD)}
+        self.assertTrue(changes[("changed", "acme.v1.User.age")].breaking)
+        self.assertIn("renamed from legacy to old_legacy", changes[("changed", "acme.v1.User.old_legacy")].detail)
+        self.assertFalse(changes[("removed", "acme.v1.User.email")].breaking)  # number reserved
+        self.assertTrue(changes[("removed", "acme.v1.Users.Delete")].breaking)
+        self.assertFalse(changes[("added", "acme.v1.User.nickname")].breaking)
+        self.assertFalse(changes[("added", "acme.v1.Status.STATUS_BANNED")].breaking)
+        self.assertFalse(changes[("added", "acme.v1.Page")].breaking)
+
+    def test_markdown_summary(self):
+        markdown = render_markdown(self.diff(BASE, HEAD))
+        self.assertIn("⚠️ **3 breaking changes** · 1 added message · 1 added field", markdown)
+        self.assertIn("1 removed rpc", markdown)
+        self.assertIn("- `acme.v1.User.age`", markdown)
+
+    def test_unchanged_schema(self):
+        self.assertEqual(self.diff(BASE, BASE), [])
+        self.assertIn("No schema changes.", render_markdown([]))
+
+    def test_enum_value_number_change(self):
+        base = 'syntax = "proto3";\npackage acme.v1;\nenum E { E_UNSPECIFIED = 0; E_A = 1; }\n'
+        head = 'syntax = "proto3";\npackage acme.v1;\nenum E { E_UNSPECIFIED = 0; E_A = 2; }\n'
+        changes = self.diff(base, head)
+        self.assertEqual([(c.element, c.breaking) for c in changes], [("acme.v1.E.E_A", True)])
+
+    def test_additions_only(self):
+        head = BASE.replace("string legacy = 6;", "string legacy = 6; bool admin = 7;")
+        markdown = render_markdown(self.diff(BASE, head))
+        self.assertIn("✅ **No breaking changes** · 1 added field", markdown)
+        self.assertNotIn("Breaking changes (", markdown)
+
+if __name__ == "__main__":
+    unittest.main()
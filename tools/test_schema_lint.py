@@ -0,0 +1,1525 @@
+#!/usr/bin/env python3
+"""
+Test suite for schema lint checks.
+"""
+
+import json
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from schema_lint import CheckConfigError, fix_files, run_check
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from schema_lint import CheckConfigError, fix_files, run_check
+
+
+class SchemaLintTestCase(unittest.TestCase):
+    """Base class providing temporary proto files."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, name: str, content: str) -> str:
+        path = os.path.join(self.temp_dir, name)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+        return path
+
+    def messages(self, report):
+        return [v["message"] for v in report["violations"]]
+
+
+class TestRunner(SchemaLintTestCase):
+    """Test behavior shared by all checks."""
+
+    def test_unknown_check(self):
+        with self.assertRaises(CheckConfigError):
+            run_check("does_not_exist", [], {})
+
+
+class TestApiAllowlist(SchemaLintTestCase):
+    """Test the api_allowlist check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("api.proto", '''
+            syntax = "proto3";
+            package acme.api.v1;
+            message User { option (acme.api.v1.public) = true; string id = 1; }
+            message Order { option (acme.api.v1.public) = true; string id = 1; }
+            message Internal { string id = 1; }
+        ''')
+
+    def test_reports_both_directions(self):
+        allowlist = self.write("allowlist.txt", "# Public API\nacme.api.v1.User\nacme.api.v1.Removed\n")
+        report = run_check(
+            "api_allowlist",
+            [self.proto],
+            {"public_option": "(acme.api.v1.public)"},
+            inputs={"allowlist": allowlist},
+        )
+        self.assertEqual(report["total_errors"], 2)
+        elements = sorted(v["element"] for v in report["violations"])
+        self.assertEqual(elements, ["acme.api.v1.Order", "acme.api.v1.Removed"])
+
+    def test_naming_patterns_and_exemptions(self):
+        allowlist = self.write("allowlist.txt", "acme.api.v1.User\nacme.api.v1.Order\n")
+        report = run_check(
+            "api_allowlist",
+            [self.proto],
+            {"public_name_patterns": [r"\.v1\."], "exemptions": ["acme.api.v1.Intern*"]},
+            inputs={"allowlist": allowlist},
+        )
+        self.assertEqual(report["violations"], [])
+
+    def test_warning_severity_does_not_count_as_error(self):
+        allowlist = self.write("allowlist.txt", "")
+        report = run_check(
+            "api_allowlist",
+            [self.proto],
+            {"public_option": "(acme.api.v1.public)", "severity": "warning"},
+            inputs={"allowlist": allowlist},
+        )
+        self.assertEqual(report["total_errors"], 0)
+        self.assertEqual(report["total_warnings"], 2)
+
+    def test_requires_allowlist_input(self):
+        with self.assertRaises(CheckConfigError):
+            run_check("api_allowlist", [self.proto], {"public_option": "(acme.api.v1.public)"})
+
+
+class TestLargeOptionValues(SchemaLintTestCase):
+    """Test the large_option_values check."""
+
+    def setUp(self):
+        super().setUp()
+        blob = "\\x00" * 40
+        self.proto = self.write("blob.proto", f'''
+            syntax = "proto2";
+            package acme.v1;
+            message Config {{
+              optional bytes seed = 1 [default = "{blob}"];
+              optional string label = 2 [default = "ok"];
+            }}
+            service Store {{
+              rpc Get(Config) returns (Config) {{
+                option (acme.v1.fixture) = {{ payload: "{"x" * 50}" }};
+              }}
+            }}
+        ''')
+
+    def test_reports_element_and_size(self):
+        report = run_check("large_option_values", [self.proto], {"max_bytes": 32})
+        self.assertEqual(self.messages(report), [
+            "default value on field acme.v1.Config.seed is 40 bytes (limit 32)",
+            "option (acme.v1.fixture) on method acme.v1.Store.Get is 57 bytes (limit 32)",
+        ])
+
+    def test_bytes_values_measure_raw_bytes(self):
+        high = "\\xff" * 600
+        proto = self.write("high.proto", f'''
+            syntax = "proto2";
+            package acme.v1;
+            import "google/protobuf/descriptor.proto";
+            extend google.protobuf.MessageOptions {{
+              optional bytes magic = 50001;
+            }}
+            message Blob {{
+              option (magic) = "{high}";
+              optional bytes data = 1 [default = "{high}"];
+              optional string text = 2 [default = "{high}"];
+            }}
+        ''')
+        report = run_check("large_option_values", [proto], {"max_bytes": 1000})
+        self.assertEqual(self.messages(report), [
+            "default value on field acme.v1.Blob.text is 1200 bytes (limit 1000)",
+        ])
+
+    def test_threshold_is_configurable(self):
+        report = run_check("large_option_values", [self.proto], {"max_bytes": 100})
+        self.assertEqual(report["violations"], [])
+
+    def test_rejects_invalid_threshold(self):
+        with self.assertRaises(CheckConfigError):
+            run_check("large_option_values", [self.proto], {"max_bytes": 0})
+
+
+class TestCommentMarkers(SchemaLintTestCase):
+    """Test the comment_markers check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("svc.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            // A user. TODO: split into profile
+            message User {
+              string id = 1;  // FIXME validate format
+              // Mentions TODOS in prose, which is fine.
+              string name = 2;
+            }
+            service Users {
+              // XXX remove before GA
+              rpc Get(User) returns (User);
+            }
+        ''')
+
+    def test_reports_each_element(self):
+        report = run_check("comment_markers", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "comment on message acme.v1.User contains TODO",
+            "comment on field acme.v1.User.id contains FIXME",
+            "comment on rpc acme.v1.Users.Get contains XXX",
+        ])
+
+    def test_custom_markers(self):
+        report = run_check("comment_markers", [self.proto], {"markers": ["HACK"]})
+        self.assertEqual(report["violations"], [])
+
+
+class TestHttpRouteConflicts(SchemaLintTestCase):
+    """Test the http_route_conflicts check."""
+
+    def test_conflicts_across_files(self):
+        users = self.write("users.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Req { string id = 1; }
+            service Users {
+              rpc GetUser(Req) returns (Req) { option (google.api.http) = { get: "/v1/users/{id}" }; }
+              rpc ListUsers(Req) returns (Req) { option (google.api.http) = { get: "/v1/users" }; }
+            }
+        ''')
+        admin = self.write("admin.proto", '''
+            syntax = "proto3";
+            package acme.admin.v1;
+            import "users.proto";
+            service Admin {
+              rpc FetchUser(acme.v1.Req) returns (acme.v1.Req) {
+                option (google.api.http) = {
+                  post: "/v1/admin/users"
+                  additional_bindings { get: "/v1/users/{name}" }
+                };
+              }
+              rpc ListAll(acme.v1.Req) returns (acme.v1.Req) { option (google.api.http) = { post: "/v1/users" }; }
+            }
+        ''')
+        report = run_check("http_route_conflicts", [users, admin], {})
+        self.assertEqual(report["total_errors"], 1)
+        self.assertIn("rpc acme.admin.v1.Admin.FetchUser and rpc acme.v1.Users.GetUser", report["violations"][0]["message"])
+
+
+class TestServiceExposure(SchemaLintTestCase):
+    """Test the service_exposure check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("svc.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "google/api/annotations.proto";
+            import "buck2protobuf/api/v1/exposure.proto";
+            message M {}
+            service PublicService {
+              rpc Get(M) returns (M) { option (google.api.http) = { get: "/v1/things" }; }
+              rpc Sync(M) returns (M);
+            }
+            service ReplicationService {
+              option (buck2protobuf.api.v1.internal) = true;
+              rpc Sync(M) returns (M);
+            }
+            service ForgottenService { rpc Get(M) returns (M); }
+            service NotInternalService {
+              option (buck2protobuf.api.v1.internal) = false;
+              rpc Get(M) returns (M);
+            }
+        ''')
+
+    def test_reports_unexposed_services(self):
+        report = run_check("service_exposure", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "service acme.v1.ForgottenService has no google.api.http routes and is not marked (buck2protobuf.api.v1.internal) = true",
+            "service acme.v1.NotInternalService has no google.api.http routes and is not marked (buck2protobuf.api.v1.internal) = true",
+        ])
+
+    def test_custom_marker_and_exemptions(self):
+        config = {"internal_option": "acme.internal", "exemptions": ["acme.v1.ForgottenService"]}
+        report = run_check("service_exposure", [self.proto], config)
+        self.assertEqual([v["element"] for v in report["violations"]], [
+            "acme.v1.ReplicationService",
+            "acme.v1.NotInternalService",
+        ])
+
+
+class TestNegativeEnumValues(SchemaLintTestCase):
+    """Test the negative_enum_values check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("status.proto", '''
+            syntax = "proto2";
+            package acme.v1;
+            enum Status { STATUS_UNKNOWN = -1; STATUS_ACTIVE = 1; }
+            message Job {
+              enum Priority { PRIORITY_LOW = -2; PRIORITY_NONE = 0; }
+            }
+        ''')
+
+    def test_reports_negative_values(self):
+        report = run_check("negative_enum_values", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "enum value acme.v1.Status.STATUS_UNKNOWN = -1 is below 0",
+            "enum value acme.v1.Job.Priority.PRIORITY_LOW = -2 is below 0",
+        ])
+
+    def test_exempt_whole_enum(self):
+        report = run_check("negative_enum_values", [self.proto], {"exemptions": {"acme.v1.Status": "sentinel for legacy clients"}})
+        self.assertEqual([v["element"] for v in report["violations"]], ["acme.v1.Job.Priority.PRIORITY_LOW"])
+
+    def test_min_value(self):
+        report = run_check("negative_enum_values", [self.proto], {"min_value": 1})
+        self.assertEqual(len(report["violations"]), 3)
+        with self.assertRaises(CheckConfigError):
+            run_check("negative_enum_values", [self.proto], {"min_value": -1})
+
+
+class TestOneofExclusivityDocs(SchemaLintTestCase):
+    """Test the oneof_exclusivity_docs check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("payment.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Payment {
+              // Payment method; exactly one is set.
+              oneof method { string card = 1; string iban = 2; }
+              // How the payment was initiated. Mutually exclusive.
+              oneof source { string web = 3; string pos = 4; }
+              oneof undocumented { string a = 5; string b = 6; }
+              message Refund {
+                // The reason.
+                oneof reason { string fraud = 7; string other = 8; }
+              }
+              optional string note = 9;
+            }
+        ''')
+
+    def test_reports_undocumented_oneofs(self):
+        report = run_check("oneof_exclusivity_docs", [self.proto], {})
+        self.assertEqual([v["element"] for v in report["violations"]], [
+            "acme.v1.Payment.undocumented",
+            "acme.v1.Payment.Refund.reason",
+        ])
+        self.assertIn("has no leading comment", report["violations"][0]["message"])
+        self.assertIn("does not mention exclusivity", report["violations"][1]["message"])
+
+    def test_custom_keywords(self):
+        report = run_check("oneof_exclusivity_docs", [self.proto], {"keywords": ["mutually exclusive"]})
+        self.assertEqual([v["element"] for v in report["violations"]], [
+            "acme.v1.Payment.method",
+            "acme.v1.Payment.undocumented",
+            "acme.v1.Payment.Refund.reason",
+        ])
+        with self.assertRaises(CheckConfigError):
+            run_check("oneof_exclusivity_docs", [self.proto], {"keywords": [""]})
+
+
+class TestProto2RequiredInProto3(SchemaLintTestCase):
+    """Test the proto2_required_in_proto3 check."""
+
+    def setUp(self):
+        super().setUp()
+        self.legacy = self.write("legacy/user.proto", '''
+            syntax = "proto2";
+            package acme.legacy;
+            message User { required string id = 1; optional string name = 2; }
+            message Profile { optional User owner = 1; }
+            message Tag { optional string value = 1; }
+        ''')
+        self.proto = self.write("order.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "legacy/user.proto";
+            message Order {
+              acme.legacy.User buyer = 1;
+              map<string, acme.legacy.Profile> profiles = 2;
+              repeated acme.legacy.Tag tags = 3;
+            }
+            service Orders { rpc Lookup(acme.legacy.Tag) returns (acme.legacy.User); }
+        ''')
+
+    def test_reports_fields_and_rpcs(self):
+        report = run_check("proto2_required_in_proto3", [self.proto], {}, dep_files=[self.legacy])
+        self.assertEqual([v["element"] for v in report["violations"]], [
+            "acme.v1.Order.buyer",
+            "acme.v1.Order.profiles",
+            "acme.v1.Orders.Lookup",
+        ])
+        self.assertIn('import "legacy/user.proto" at line 4', report["violations"][0]["message"])
+        self.assertIn("required field acme.legacy.User.id", report["violations"][1]["message"])
+        self.assertIn("response", report["violations"][2]["message"])
+
+    def test_proto2_consumers_are_not_checked(self):
+        proto = self.write("legacy_order.proto", '''
+            syntax = "proto2";
+            package acme.v1;
+            import "legacy/user.proto";
+            message Order { optional acme.legacy.User buyer = 1; }
+        ''')
+        report = run_check("proto2_required_in_proto3", [proto], {}, dep_files=[self.legacy])
+        self.assertEqual(report["violations"], [])
+
+
+class TestProto2DefaultsInProto3(SchemaLintTestCase):
+    """Test the proto2_defaults_in_proto3 check."""
+
+    def setUp(self):
+        super().setUp()
+        self.legacy = self.write("legacy/settings.proto", '''
+            syntax = "proto2";
+            package acme.legacy;
+            enum Mode { MODE_SLOW = 0; MODE_FAST = 1; }
+            message Retry { optional int32 attempts = 1 [default = 3]; }
+            message Settings {
+              optional string region = 1 [default = "eu"];
+              optional Mode mode = 2 [default = MODE_FAST];
+              optional Retry retry = 3;
+            }
+            message Plain { optional int32 count = 1; }
+        ''')
+
+    def test_reports_fields_and_defaults(self):
+        proto = self.write("service.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "legacy/settings.proto";
+            message Job {
+              acme.legacy.Settings settings = 1;
+              acme.legacy.Plain plain = 2;
+            }
+            service Jobs { rpc Configure(acme.legacy.Retry) returns (Job); }
+        ''')
+        report = run_check("proto2_defaults_in_proto3", [proto], {}, dep_files=[self.legacy])
+        self.assertEqual(self.messages(report), [
+            'proto3 field acme.v1.Job.settings uses proto2 message acme.legacy.Settings '
+            '(import "legacy/settings.proto" at line 4), whose explicit defaults proto3 does not have: '
+            'acme.legacy.Settings.region = "eu", acme.legacy.Settings.mode = MODE_FAST, '
+            'acme.legacy.Retry.attempts = 3',
+            'proto3 RPC acme.v1.Jobs.Configure request is proto2 message acme.legacy.Retry '
+            '(import "legacy/settings.proto" at line 4), whose explicit defaults proto3 does not have: '
+            'acme.legacy.Retry.attempts = 3',
+        ])
+
+    def test_proto2_consumers_are_not_checked(self):
+        proto = self.write("legacy_job.proto", '''
+            syntax = "proto2";
+            package acme.v1;
+            import "legacy/settings.proto";
+            message Job { optional acme.legacy.Settings settings = 1; }
+        ''')
+        report = run_check("proto2_defaults_in_proto3", [proto], {}, dep_files=[self.legacy])
+        self.assertEqual(report["violations"], [])
+
+
+class TestCrudRequiredAnnotations(SchemaLintTestCase):
+    """Test the crud_required_annotations check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "google/protobuf/wrappers.proto";
+            message User {
+              string email = 1 [(buck2protobuf.crud.v1.required_on_create) = true];
+              string etag = 2 [(buck2protobuf.crud.v1.required_on_update) = true];
+              string id = 3 [(buck2protobuf.crud.v1.required_on_create) = true,
+                             (buck2protobuf.crud.v1.required_on_update) = true];
+              optional string nickname = 4 [(buck2protobuf.crud.v1.required_on_create) = true];
+              google.protobuf.StringValue phone = 5 [(buck2protobuf.crud.v1.required_on_create) = true];
+              google.protobuf.StringValue fax = 6 [(buck2protobuf.crud.v1.required_on_update) = true];
+            }
+        ''')
+
+    def test_reports_conflicts(self):
+        report = run_check("crud_required_annotations", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "field acme.v1.User.id is marked both (buck2protobuf.crud.v1.required_on_create) "
+            "and (buck2protobuf.crud.v1.required_on_update)",
+            "field acme.v1.User.nickname is marked (buck2protobuf.crud.v1.required_on_create) "
+            "but is nullable (proto3 optional)",
+            "field acme.v1.User.phone is marked (buck2protobuf.crud.v1.required_on_create) "
+            "but is nullable (google.protobuf.StringValue)",
+        ])
+
+    def test_custom_option_names(self):
+        proto = self.write("order.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Order { string id = 1 [(acme.api.create) = true, (acme.api.update) = true]; }
+        ''')
+        config = {"create_option": "acme.api.create", "update_option": "acme.api.update"}
+        report = run_check("crud_required_annotations", [proto], config)
+        self.assertEqual([v["element"] for v in report["violations"]], ["acme.v1.Order.id"])
+        with self.assertRaises(CheckConfigError):
+            run_check("crud_required_annotations", [proto], {"create_option": ""})
+
+
+class TestReservedFieldNumbers(SchemaLintTestCase):
+    """Test the reserved_field_numbers check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("user.proto", '''
+            syntax = "proto2";
+            package acme.v1;
+            import "google/protobuf/descriptor.proto";
+            message User {
+              optional string name = 1;
+              optional string debug_token = 50001;
+              oneof contact { string email = 60000; string phone = 3; }
+              extensions 1000 to max;
+            }
+            extend google.protobuf.FieldOptions { optional bool sensitive = 50010; }
+            extend User { optional string audit = 50020; }
+        ''')
+
+    def test_reports_fields_but_not_extensions(self):
+        report = run_check("reserved_field_numbers", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "field acme.v1.User.debug_token = 50001 is in the reserved internal range 50000 to max",
+            "field acme.v1.User.email = 60000 is in the reserved internal range 50000 to max",
+        ])
+
+    def test_configured_ranges(self):
+        report = run_check("reserved_field_numbers", [self.proto], {"ranges": [[1, 2], [59000, 59999]]})
+        self.assertEqual(self.messages(report), [
+            "field acme.v1.User.name = 1 is in the reserved internal range 1 to 2",
+        ])
+
+    def test_invalid_ranges(self):
+        for ranges in [[], [[5, 1]], [[1]], [[0, 10]], [[1, 536870912]]]:
+            with self.assertRaises(CheckConfigError):
+                run_check("reserved_field_numbers", [self.proto], {"ranges": ranges})
+
+
+class TestEnumNumberStability(SchemaLintTestCase):
+    """Test the enum_number_stability check."""
+
+    def test_reports_changed_and_reused_numbers(self):
+        baseline = self.write("old/status.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            enum Status { STATUS_UNSPECIFIED = 0; STATUS_ACTIVE = 1; STATUS_DELETED = 2; STATUS_BANNED = 3; }
+        ''')
+        current = self.write("new/status.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            enum Status { STATUS_UNSPECIFIED = 0; STATUS_ACTIVE = 1; STATUS_DELETED = 4; STATUS_SUSPENDED = 3; }
+        ''')
+        report = run_check("enum_number_stability", [current], {}, baseline_files=[baseline])
+        self.assertEqual(self.messages(report), [
+            "enum value acme.v1.Status.STATUS_DELETED changed number from 2 to 4",
+            "enum value acme.v1.Status.STATUS_SUSPENDED reuses number 3 previously assigned to STATUS_BANNED",
+        ])
+
+    def test_requires_baseline(self):
+        current = self.write("status.proto", 'syntax = "proto3";\nenum S { S_UNSPECIFIED = 0; }\n')
+        with self.assertRaises(CheckConfigError):
+            run_check("enum_number_stability", [current], {})
+
+
+class TestFieldCardinalityStability(SchemaLintTestCase):
+    """Test the field_cardinality_stability check."""
+
+    def test_reports_cardinality_changes(self):
+        baseline = self.write("old/order.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Item {}
+            message Order {
+              Item item = 1;
+              repeated string tags = 2;
+              map<string, string> labels = 3;
+              repeated int32 codes = 4;
+              string note = 5;
+            }
+        ''')
+        current = self.write("new/order.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Item {}
+            message Order {
+              repeated Item items = 1;
+              string tags = 2;
+              repeated string labels = 3;
+              repeated int32 codes = 4;
+              optional string note = 5;
+              repeated string extra = 6;
+            }
+        ''')
+        report = run_check("field_cardinality_stability", [current], {}, baseline_files=[baseline])
+        self.assertEqual(self.messages(report), [
+            "field acme.v1.Order.items = 1 (was item) changed from singular to repeated",
+            "field acme.v1.Order.tags = 2 changed from repeated to singular",
+            "field acme.v1.Order.labels = 3 changed from map to repeated",
+        ])
+
+    def test_requires_baseline(self):
+        current = self.write("order.proto", 'syntax = "proto3";\nmessage M { string a = 1; }\n')
+        with self.assertRaises(CheckConfigError):
+            run_check("field_cardinality_stability", [current], {})
+
+
+class TestFieldLayoutStability(SchemaLintTestCase):
+    """Test the field_layout_stability check."""
+
+    def setUp(self):
+        super().setUp()
+        self.baseline = self.write("old/frame.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Frame { int64 id = 1; int32 size = 2; bytes payload = 3; }
+            message Header { string name = 1; string value = 2; }
+        ''')
+
+    def test_reports_reordered_fields(self):
+        current = self.write("new/frame.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Frame { int32 size = 2; int64 id = 1; bytes payload = 3; }
+            message Header { string name = 1; string value = 2; }
+        ''')
+        report = run_check("field_layout_stability", [current], {}, baseline_files=[self.baseline])
+        self.assertEqual(self.messages(report), [
+            "fields of acme.v1.Frame were reordered: baseline declares id = 1, size = 2, payload = 3, "
+            "now size = 2, id = 1, payload = 3",
+        ])
+
+    def test_inserted_and_removed_fields(self):
+        current = self.write("new/frame.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Frame { int64 id = 1; string kind = 4; int32 size = 2; bytes payload = 3; }
+            message Header { string name = 1; string comment = 3; }
+        ''')
+        report = run_check("field_layout_stability", [current], {}, baseline_files=[self.baseline])
+        self.assertEqual(self.messages(report), [
+            "fields of acme.v1.Frame were inserted before existing fields: kind = 4; declare new fields after payload",
+        ])
+        report = run_check("field_layout_stability", [current], {"allow_inserted_fields": True},
+                           baseline_files=[self.baseline])
+        self.assertEqual(self.messages(report), [])
+
+    def test_requires_baseline(self):
+        current = self.write("frame.proto", 'syntax = "proto3";\nmessage M { string a = 1; }\n')
+        with self.assertRaises(CheckConfigError):
+            run_check("field_layout_stability", [current], {})
+
+
+class TestUnitSuffixes(SchemaLintTestCase):
+    """Test the unit_suffixes check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("job.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "google/protobuf/duration.proto";
+            message Job {
+              int64 timeout_seconds = 1;
+              int64 retry_delay = 2;
+              google.protobuf.Duration max_age = 3;
+              uint64 payload_size = 4;
+              int32 page_size = 5;
+              int64 price = 6;
+              string price_currency = 7;
+            }
+        ''')
+
+    def test_default_rules(self):
+        report = run_check("unit_suffixes", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "time field acme.v1.Job.retry_delay must end in one of _seconds, _millis, _micros, _nanos",
+            "size field acme.v1.Job.payload_size must end in one of _bytes",
+            "money field acme.v1.Job.price must end in one of _micros, _cents",
+        ])
+
+    def test_custom_rules(self):
+        config = {"unit_rules": {"time": {"pattern": "delay", "suffixes": ["_ms"]}}}
+        report = run_check("unit_suffixes", [self.proto], config)
+        self.assertEqual(self.messages(report), ["time field acme.v1.Job.retry_delay must end in one of _ms"])
+
+    def test_rejects_rule_without_suffixes(self):
+        with self.assertRaises(CheckConfigError):
+            run_check("unit_suffixes", [self.proto], {"unit_rules": {"time": {"pattern": "delay", "suffixes": []}}})
+
+
+class TestFieldPresenceConsistency(SchemaLintTestCase):
+    """Test the field_presence_consistency check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message User {
+              string id = 1;
+              optional string nickname = 2;
+              int32 age = 3;
+              repeated string tags = 4;
+              oneof contact { string email = 5; }
+            }
+            message Page {
+              optional int32 size = 1;
+              optional string token = 2;
+              User owner = 3;
+            }
+        ''')
+
+    def test_reports_mixed_messages(self):
+        report = run_check("field_presence_consistency", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "message acme.v1.User mixes optional and implicit-presence scalars (optional: nickname; implicit presence: id, age)",
+        ])
+
+    def test_preferred_style_lists_fields_to_change(self):
+        report = run_check("field_presence_consistency", [self.proto], {"prefer": "explicit"})
+        self.assertIn("fields without optional: id, age", self.messages(report)[0])
+
+    def test_documented_exemptions(self):
+        config = {"exemptions": {"acme.v1.User": "id is always set by the server"}}
+        report = run_check("field_presence_consistency", [self.proto], config)
+        self.assertEqual(report["total_errors"], 0)
+
+    def test_proto2_is_ignored(self):
+        path = self.write("legacy.proto", 'syntax = "proto2";\nmessage M { optional string a = 1; required string b = 2; }\n')
+        self.assertEqual(run_check("field_presence_consistency", [path], {})["violations"], [])
+
+
+class TestServicePascalCase(SchemaLintTestCase):
+    """Test the service_pascal_case check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("svc.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Req {}
+            service user_service {
+              rpc GetUser(Req) returns (Req);
+              rpc list_users(Req) returns (Req);
+            }
+            service LegacyAPI { rpc get_all(Req) returns (Req); }
+        ''')
+
+    def test_reports_services_and_methods(self):
+        report = run_check("service_pascal_case", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "service name user_service is not PascalCase",
+            "rpc name list_users is not PascalCase",
+            "rpc name get_all is not PascalCase",
+        ])
+
+    def test_exempted_service_covers_methods(self):
+        report = run_check("service_pascal_case", [self.proto], {"exemptions": ["acme.v1.LegacyAPI", "acme.v1.user_service"]})
+        self.assertEqual(report["violations"], [])
+
+
+class TestIdempotentMutations(SchemaLintTestCase):
+    """Test the idempotent_mutations check."""
+
+    PROTO = '''syntax = "proto3";
+package acme.user.v1;
+message Req {}
+service UserService {
+  rpc CreateUser(Req) returns (Req) { option idempotency_level = IDEMPOTENT; }
+  // Idempotent: keyed by the client-supplied request_id.
+  rpc UpdateUser(Req) returns (Req) { option idempotency_level = IDEMPOTENT; }
+  rpc DeleteUser(Req) returns (Req) { option idempotency_level = NO_SIDE_EFFECTS; }
+  rpc Deleted(Req) returns (Req) { option idempotency_level = IDEMPOTENT; }
+  rpc Createur(Req) returns (Req) { option idempotency_level = IDEMPOTENT; }
+  rpc GetUser(Req) returns (Req) { option idempotency_level = NO_SIDE_EFFECTS; }
+  rpc ArchiveUser(Req) returns (Req) { option idempotency_level = IDEMPOTENT; }
+  rpc CreateGroup(Req) returns (Req);
+}
+'''
+
+    def test_flags_unjustified_mutations(self):
+        proto = self.write("user.proto", self.PROTO)
+        report = run_check("idempotent_mutations", [proto], {})
+        self.assertEqual(self.messages(report), [
+            "rpc acme.user.v1.UserService.CreateUser looks like a mutation (Create) but is marked "
+            "idempotency_level = IDEMPOTENT; remove the option or document why retries are safe "
+            "in a comment containing 'Idempotent:'",
+            "rpc acme.user.v1.UserService.DeleteUser looks like a mutation (Delete) but is marked "
+            "idempotency_level = NO_SIDE_EFFECTS; remove the option or document why retries are safe "
+            "in a comment containing 'Idempotent:'",
+        ])
+        self.assertEqual(report["violations"][0]["line"], 5)
+
+    def test_custom_prefixes_and_exemptions(self):
+        proto = self.write("user.proto", self.PROTO)
+        config = {"mutation_prefixes": ["Archive"], "justification": ""}
+        report = run_check("idempotent_mutations", [proto], config)
+        self.assertEqual([v["element"] for v in report["violations"]], ["acme.user.v1.UserService.ArchiveUser"])
+        config["exemptions"] = ["acme.user.v1.UserService.ArchiveUser"]
+        self.assertEqual(run_check("idempotent_mutations", [proto], config)["violations"], [])
+
+    def test_invalid_config(self):
+        proto = self.write("user.proto", self.PROTO)
+        with self.assertRaises(CheckConfigError):
+            run_check("idempotent_mutations", [proto], {"mutation_prefixes": []})
+
+
+class TestImportAllowlist(SchemaLintTestCase):
+    """Test the import_allowlist check."""
+
+    def setUp(self):
+        super().setUp()
+        self.types = self.write("acme/v1/types.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage T {}\n')
+        self.proto = self.write("acme/v1/svc.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "acme/v1/types.proto";
+            import "google/protobuf/timestamp.proto";
+            import "google/api/annotations.proto";
+            import "thirdparty/money/money.proto";
+            message Req {}
+        ''')
+
+    def test_reports_disallowed_modules(self):
+        config = {"allowed_modules": ["buf.build/protocolbuffers/wellknowntypes"]}
+        report = run_check("import_allowlist", [self.proto, self.types], config)
+        self.assertEqual(self.messages(report), [
+            'import "google/api/annotations.proto" from module buf.build/googleapis/googleapis is not in the dependency allowlist',
+            'import "thirdparty/money/money.proto" from module thirdparty is not in the dependency allowlist',
+        ])
+
+    def test_custom_module_prefixes(self):
+        config = {
+            "allowed_modules": ["buf.build/protocolbuffers/wellknowntypes", "buf.build/googleapis/googleapis", "//third_party:money"],
+            "module_prefixes": {"//third_party:money": ["thirdparty/money/"]},
+        }
+        report = run_check("import_allowlist", [self.proto, self.types], config)
+        self.assertEqual(report["violations"], [])
+
+
+class TestRpcTopLevelMessages(SchemaLintTestCase):
+    """Test the rpc_top_level_messages check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("svc.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message GetUserRequest {}
+            message UserService { message Reply {} }
+            message Legacy { message Req {} }
+            service Users {
+              rpc GetUser(GetUserRequest) returns (UserService.Reply);
+              rpc Old(Legacy.Req) returns (GetUserRequest);
+              rpc Ok(GetUserRequest) returns (GetUserRequest);
+            }
+        ''')
+
+    def test_reports_nested_types(self):
+        report = run_check("rpc_top_level_messages", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "response type acme.v1.UserService.Reply of rpc GetUser is a nested message; move it to the top level",
+            "request type acme.v1.Legacy.Req of rpc Old is a nested message; move it to the top level",
+        ])
+        self.assertEqual(report["violations"][0]["element"], "acme.v1.Users.GetUser")
+
+    def test_exempt_method_or_type(self):
+        report = run_check("rpc_top_level_messages", [self.proto], {"exemptions": ["acme.v1.Users.GetUser", "acme.v1.Legacy.*"]})
+        self.assertEqual(report["violations"], [])
+
+
+class TestGroupFields(SchemaLintTestCase):
+    """Test the group_fields check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("search.proto", '''
+            syntax = "proto2";
+            package acme.v1;
+            message SearchResponse {
+              repeated group Result = 1 {
+                optional string url = 2;
+              }
+              optional int32 total = 3;
+            }
+            message Base { extensions 100 to 199; }
+            extend Base {
+              optional group Tracking = 100 { optional string id = 1; }
+            }
+        ''')
+
+    def test_reports_groups_and_extension_groups(self):
+        report = run_check("group_fields", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "group Result (field acme.v1.SearchResponse.result = 1) uses deprecated group syntax; "
+            "declare message acme.v1.SearchResponse.Result and a message field instead",
+            "group Tracking (extension of Base acme.v1.tracking = 100) uses deprecated group syntax; "
+            "declare message acme.v1.Tracking and a message field instead",
+        ])
+        self.assertEqual(report["violations"][0]["line"], 5)
+
+    def test_exempt_group(self):
+        report = run_check("group_fields", [self.proto], {"exemptions": ["acme.v1.SearchResponse.*"]})
+        self.assertEqual(report["total_errors"], 1)
+
+
+class TestWellKnownTypeSubset(SchemaLintTestCase):
+    """Test the well_known_type_subset check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("event.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "google/protobuf/timestamp.proto";
+            import "google/protobuf/duration.proto";
+            import "google/protobuf/unittest.proto";
+            message Event { google.protobuf.Timestamp at = 1; }
+        ''')
+
+    def test_reports_undeclared_imports(self):
+        report = run_check("well_known_type_subset", [self.proto], {"allowed": ["timestamp"]})
+        self.assertEqual(self.messages(report), [
+            'import "google/protobuf/duration.proto" is not in the declared well-known types [timestamp]; '
+            'add "duration" to well_known_types',
+            'import "google/protobuf/unittest.proto" is not in the declared well-known types [timestamp]; '
+            'it is not a well-known type bundled with protoc',
+        ])
+        self.assertEqual(report["violations"][0]["line"], 5)
+
+    def test_declared_subset_passes(self):
+        proto = self.write("clean.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "google/protobuf/timestamp.proto";
+            import "common/types.proto";
+        ''')
+        report = run_check("well_known_type_subset", [proto], {"allowed": ["timestamp", "empty"]})
+        self.assertEqual(report["violations"], [])
+
+    def test_rejects_unknown_names(self):
+        with self.assertRaises(CheckConfigError):
+            run_check("well_known_type_subset", [self.proto], {"allowed": ["timestamps"]})
+
+
+class TestBareWktResponses(SchemaLintTestCase):
+    """Test the bare_wkt_responses check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "google/protobuf/empty.proto";
+            import "google/protobuf/wrappers.proto";
+            import "google/protobuf/timestamp.proto";
+            message GetUserRequest {}
+            message GetUserResponse {}
+            service UserService {
+              rpc GetUser(GetUserRequest) returns (GetUserResponse);
+              rpc DeleteUser(GetUserRequest) returns (google.protobuf.Empty);
+              rpc GetName(GetUserRequest) returns (google.protobuf.StringValue);
+              rpc LastSeen(google.protobuf.StringValue) returns (.google.protobuf.Timestamp);
+            }
+        ''')
+
+    def test_reports_bare_responses(self):
+        report = run_check("bare_wkt_responses", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "rpc acme.v1.UserService.GetName returns the bare well-known type google.protobuf.StringValue; "
+            "return a dedicated GetNameResponse message so fields can be added later",
+            "rpc acme.v1.UserService.LastSeen returns the bare well-known type google.protobuf.Timestamp; "
+            "return a dedicated LastSeenResponse message so fields can be added later",
+        ])
+        self.assertEqual(report["violations"][0]["line"], 12)
+
+    def test_allowed_types_and_exemptions(self):
+        report = run_check("bare_wkt_responses", [self.proto], {
+            "allowed": ["google.protobuf.Empty", "google.protobuf.Timestamp"],
+            "exemptions": ["acme.v1.UserService.GetName"],
+        })
+        self.assertEqual(report["violations"], [])
+
+    def test_rejects_unknown_names(self):
+        with self.assertRaises(CheckConfigError):
+            run_check("bare_wkt_responses", [self.proto], {"allowed": ["Empty"]})
+
+
+class TestCommentTypeReferences(SchemaLintTestCase):
+    """Test the comment_type_references check."""
+
+    def setUp(self):
+        super().setUp()
+        self.common = self.write("common.proto", '''
+            syntax = "proto3";
+            package acme.common.v1;
+            message Money { int64 units = 1; }
+        ''')
+        self.proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "common.proto";
+            // A user; see [Profile] and [acme.common.v1.Money].
+            // Managed by [UserService], formerly [LegacyUser].
+            message User {
+              // Nested settings, see [Settings.Theme] and [Setting].
+              Settings settings = 1;
+              int64 id = 2;  // Not a [Identifier], see [the docs](https://example.com).
+              message Settings { enum Theme { THEME_UNSPECIFIED = 0; } }
+            }
+            message Profile {}
+            service UserService {
+              /*
+               * Returns a [User] or a [google.protobuf.Empty] or a [Money].
+               */
+              rpc Get(Profile) returns (User);
+            }
+        ''')
+
+    def test_reports_unresolved_references(self):
+        report = run_check("comment_type_references", [self.proto], {}, dep_files=[self.common])
+        self.assertEqual(
+            [(v["line"], v["message"]) for v in report["violations"]],
+            [
+                (6, "comment on message acme.v1.User references [LegacyUser], "
+                    "which is not a type in the target or its dependencies"),
+                (8, "comment on field acme.v1.User.settings references [Setting], "
+                    "which is not a type in the target or its dependencies"),
+                (10, "comment on field acme.v1.User.id references [Identifier], "
+                     "which is not a type in the target or its dependencies"),
+                (16, "comment on rpc acme.v1.UserService.Get references [Money], "
+                     "which is not a type in the target or its dependencies"),
+            ],
+        )
+
+    def test_dependencies_resolve(self):
+        report = run_check("comment_type_references", [self.proto], {})
+        self.assertIn("comment on message acme.v1.User references [acme.common.v1.Money], "
+                      "which is not a type in the target or its dependencies", self.messages(report))
+
+    def test_custom_pattern(self):
+        report = run_check("comment_type_references", [self.proto], {"pattern": r"`(\w+)`"},
+                           dep_files=[self.common])
+        self.assertEqual(report["violations"], [])
+        with self.assertRaises(CheckConfigError):
+            run_check("comment_type_references", [self.proto], {"pattern": r"\[\w+\]"})
+
+
+class TestEnumOpenness(SchemaLintTestCase):
+    """Test the enum_openness check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("user.proto", '''
+            edition = "2023";
+            package acme.v1;
+            option features.enum_type = CLOSED;
+            enum Status { STATUS_UNSPECIFIED = 0; }
+            enum Color {
+              option features.enum_type = OPEN;
+              COLOR_UNSPECIFIED = 0;
+            }
+            message User {
+              option features = { enum_type: OPEN };
+              enum Role { ROLE_UNSPECIFIED = 0; }
+              enum Tier { option features.enum_type = CLOSED; TIER_UNSPECIFIED = 0; }
+            }
+        ''')
+
+    def test_closed_policy(self):
+        report = run_check("enum_openness", [self.proto], {})
+        self.assertEqual([(v["line"], v["message"]) for v in report["violations"]], [
+            (6, "enum acme.v1.Color is open (features.enum_type on the enum), but the policy requires closed enums"),
+            (12, "enum acme.v1.User.Role is open (features.enum_type on acme.v1.User), "
+                 "but the policy requires closed enums"),
+        ])
+
+    def test_open_policy(self):
+        report = run_check("enum_openness", [self.proto], {"policy": "open"})
+        self.assertEqual([v["element"] for v in report["violations"]], ["acme.v1.Status", "acme.v1.User.Tier"])
+        self.assertIn("(file features.enum_type)", self.messages(report)[0])
+
+    def test_syntax_defaults(self):
+        proto3 = self.write("proto3.proto", 'syntax = "proto3";\npackage a;\nenum E { E_UNSPECIFIED = 0; }\n')
+        proto2 = self.write("proto2.proto", 'syntax = "proto2";\npackage b;\nenum E { E_UNSPECIFIED = 0; }\n')
+        editions = self.write("editions.proto", 'edition = "2023";\npackage c;\nenum E { E_UNSPECIFIED = 0; }\n')
+        report = run_check("enum_openness", [proto3, proto2, editions], {})
+        self.assertEqual(self.messages(report), [
+            "enum c.E is open (edition 2023 default), but the policy requires closed enums",
+            "enum a.E is open (proto3), but the policy requires closed enums",
+        ])
+
+    def test_marked_enums_are_exempt(self):
+        config = {"exemptions": {"acme.v1.Color": "values are added by clients", "acme.v1.User.*": "legacy"}}
+        self.assertEqual(run_check("enum_openness", [self.proto], config)["violations"], [])
+        with self.assertRaises(CheckConfigError):
+            run_check("enum_openness", [self.proto], {"policy": "ajar"})
+
+
+class TestWktNameShadowing(SchemaLintTestCase):
+    """Test the wkt_name_shadowing check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("user.proto", '''syntax = "proto3";
+package acme.v1;
+message Timestamp { int64 millis = 1; }
+message User {
+  message Duration {}
+  enum Syntax { SYNTAX_UNSPECIFIED = 0; }
+  Timestamp created = 1;
+}
+message Timestamps {}
+''')
+
+    def test_shadowing_types_are_reported(self):
+        report = run_check("wkt_name_shadowing", [self.proto], {})
+        self.assertEqual([(v["line"], v["element"]) for v in report["violations"]], [
+            (3, "acme.v1.Timestamp"), (5, "acme.v1.User.Duration"),
+        ])
+        self.assertEqual(self.messages(report)[0], "message acme.v1.Timestamp shadows the well-known type "
+                                                   "google.protobuf.Timestamp; rename it or use the well-known type")
+
+    def test_generic_names_need_their_file_imported(self):
+        catalog = self.write("catalog.proto", '''syntax = "proto3";
+package acme.v1;
+message Field { string name = 1; }
+message Value { string text = 1; }
+enum Type { TYPE_UNSPECIFIED = 0; }
+''')
+        report = run_check("wkt_name_shadowing", [catalog], {})
+        self.assertEqual(report["violations"], [])
+
+        reflective = self.write("reflective.proto", '''syntax = "proto3";
+package acme.v1;
+import "google/protobuf/type.proto";
+message Field { google.protobuf.Field descriptor = 1; }
+message Value { string text = 1; }
+''')
+        report = run_check("wkt_name_shadowing", [reflective], {})
+        self.assertEqual([v["element"] for v in report["violations"]], ["acme.v1.Field"])
+        self.assertTrue(self.messages(report)[0].startswith("message acme.v1.Field shadows the well-known type "
+                                                            "google.protobuf.Field"))
+
+        # Listed names are reserved whether or not their file is imported
+        report = run_check("wkt_name_shadowing", [catalog], {"names": ["google.protobuf.Value"]})
+        self.assertEqual([v["element"] for v in report["violations"]], ["acme.v1.Value"])
+
+    def test_names_and_exemptions(self):
+        config = {"names": ["google.protobuf.Timestamp", "google.protobuf.Duration"],
+                  "exemptions": {"acme.v1.Timestamp": "wire format predates the WKT"}}
+        report = run_check("wkt_name_shadowing", [self.proto], config)
+        self.assertEqual([v["element"] for v in report["violations"]], ["acme.v1.User.Duration"])
+        with self.assertRaises(CheckConfigError):
+            run_check("wkt_name_shadowing", [self.proto], {"names": ["Timestamp"]})
+
+
+class TestPackageOwners(SchemaLintTestCase):
+    """Test the package_owners check."""
+
+    def owned(self, name: str, package: str, owner: str = "") -> str:
+        option = f'option (buck2protobuf.owner.v1.owner) = "{owner}";\n' if owner else ""
+        return self.write(name, f'syntax = "proto3";\npackage {package};\n'
+                                f'import "buck2protobuf/owner/v1/owner.proto";\n{option}')
+
+    def test_missing_and_conflicting_owners(self):
+        user = self.owned("user.proto", "acme.user.v1", "team-identity")
+        admin = self.owned("admin.proto", "acme.user.v1", "team-admin")
+        orphan = self.owned("orphan.proto", "acme.misc.v1")
+        report = run_check("package_owners", [user, admin, orphan], {})
+        self.assertEqual([(v["file"], v["line"], v["element"]) for v in report["violations"]], [
+            (admin, 2, "acme.user.v1"), (orphan, 2, orphan),
+        ])
+        self.assertEqual(self.messages(report), [
+            f"package acme.user.v1 has files with different owners: 'team-admin' ({admin}), 'team-identity' ({user})",
+            f"file {orphan} does not declare an owner; add option (buck2protobuf.owner.v1.owner) = \"<team>\"",
+        ])
+
+    def test_consistency_is_optional(self):
+        user = self.owned("user.proto", "acme.user.v1", "team-identity")
+        admin = self.owned("admin.proto", "acme.user.v1", "team-admin")
+        report = run_check("package_owners", [user, admin], {"require_consistent": False})
+        self.assertEqual(report["violations"], [])
+        with self.assertRaises(CheckConfigError):
+            run_check("package_owners", [user], {"owner_option": ""})
+
+    def test_custom_option_and_path_exemptions(self):
+        owned = self.write("a.proto", 'syntax = "proto3";\npackage acme.v1;\noption (acme.owner) = "team-a";\n')
+        legacy = self.owned("legacy/b.proto", "acme.v1")
+        config = {"owner_option": "acme.owner", "exemptions": ["*/legacy/*"]}
+        self.assertEqual(run_check("package_owners", [owned, legacy], config)["violations"], [])
+
+
+class TestFileSize(SchemaLintTestCase):
+    """Test the file_size check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("big.proto", '''syntax = "proto3";
+package acme.v1;
+message A { message Nested {} }
+message B {}
+enum C { C_UNSPECIFIED = 0; }
+service D {}
+''')
+
+    def test_unlimited_by_default(self):
+        report = run_check("file_size", [self.proto], {})
+        self.assertEqual(report["violations"], [])
+
+    def test_reports_lines_and_definitions(self):
+        report = run_check("file_size", [self.proto], {"max_lines": 5, "max_definitions": 3})
+        self.assertEqual(self.messages(report), [
+            "file has 4 top-level definitions (limit 3); consider splitting it into smaller files",
+            "file has 6 lines (limit 5); consider splitting it into smaller files",
+        ])
+
+    def test_within_limits(self):
+        report = run_check("file_size", [self.proto], {"max_lines": 6, "max_definitions": 4})
+        self.assertEqual(report["violations"], [])
+
+    def test_rejects_negative_limit(self):
+        with self.assertRaises(CheckConfigError):
+            run_check("file_size", [self.proto], {"max_lines": -1})
+
+
+class TestLineEndings(SchemaLintTestCase):
+    """Test the line_endings check and its fixer."""
+
+    def test_lf_with_final_newline_passes(self):
+        proto = self.write("ok.proto", 'syntax = "proto3";\npackage acme.v1;\n')
+        report = run_check("line_endings", [proto], {})
+        self.assertEqual(report["violations"], [])
+
+    def test_reports_crlf_and_missing_final_newline(self):
+        proto = self.write("bad.proto", 'syntax = "proto3";\r\npackage acme.v1;\r\nmessage A {}')
+        report = run_check("line_endings", [proto], {})
+        self.assertEqual(self.messages(report), [
+            "2 line(s) end with CRLF instead of LF, first at line 1",
+            "file does not end with a newline",
+        ])
+        self.assertEqual([v["line"] for v in report["violations"]], [1, 3])
+
+    def test_crlf_style(self):
+        proto = self.write("win.proto", 'syntax = "proto3";\r\npackage acme.v1;\n')
+        report = run_check("line_endings", [proto], {"line_ending": "crlf"})
+        self.assertEqual(self.messages(report), ["1 line(s) end with LF instead of CRLF, first at line 2"])
+
+    def test_consistent_style_uses_majority(self):
+        proto = self.write("mixed.proto", 'syntax = "proto3";\r\npackage acme.v1;\r\nmessage A {}\n')
+        report = run_check("line_endings", [proto], {"line_ending": "consistent"})
+        self.assertEqual(self.messages(report), ["1 line(s) end with LF instead of CRLF, first at line 3"])
+
+    def test_final_newline_optional(self):
+        proto = self.write("short.proto", 'syntax = "proto3";')
+        report = run_check("line_endings", [proto], {"final_newline": False})
+        self.assertEqual(report["violations"], [])
+
+    def test_fix_rewrites_files(self):
+        bad = self.write("bad.proto", 'syntax = "proto3";\r\npackage acme.v1;')
+        good = self.write("good.proto", 'syntax = "proto3";\n')
+        self.assertEqual(fix_files("line_endings", [bad, good], {}), [bad])
+        with open(bad, "rb") as f:
+            self.assertEqual(f.read(), b'syntax = "proto3";\npackage acme.v1;\n')
+        self.assertEqual(run_check("line_endings", [bad], {})["violations"], [])
+
+    def test_rejects_unknown_style(self):
+        proto = self.write("ok.proto", 'syntax = "proto3";\n')
+        with self.assertRaises(CheckConfigError):
+            run_check("line_endings", [proto], {"line_ending": "cr"})
+        with self.assertRaises(CheckConfigError):
+            fix_files("file_size", [proto], {})
+
+
+class TestJsonNameCollisions(SchemaLintTestCase):
+    """Test the json_name_collisions check."""
+
+    def test_default_and_explicit_json_names(self):
+        proto = self.write("a.proto", '''syntax = "proto3";
+package acme.v1;
+message User {
+  string user_id = 1;
+  string userId = 2;
+  string email = 3;
+  string contact = 4 [json_name = "email"];
+  oneof owner { string team_name = 5 [json_name = "team"]; }
+  string team = 6;
+}
+''')
+        report = run_check("json_name_collisions", [proto], {})
+        self.assertEqual(self.messages(report), [
+            'field userId (JSON name "userId") and field user_id (JSON name "userId", line 4) '
+            'of acme.v1.User both map to JSON key "userId"',
+            'field contact (JSON name "email") and field email (JSON name "email", line 6) '
+            'of acme.v1.User both map to JSON key "email"',
+            'field team (JSON name "team") and field team_name (JSON name "team", line 8) '
+            'of acme.v1.User both map to JSON key "team"',
+        ])
+        self.assertEqual(report["violations"][0]["element"], "acme.v1.User.userId")
+
+    def test_proto_name_matching_json_name(self):
+        proto = self.write("b.proto", '''syntax = "proto3";
+package acme.v1;
+message Order {
+  string order_id = 1 [json_name = "id"];
+  string order_id_v2 = 2 [json_name = "order_id"];
+  message Line { string sku = 1; }
+  map<string, Line> lines = 3;
+}
+''')
+        report = run_check("json_name_collisions", [proto], {})
+        self.assertEqual(self.messages(report), [
+            'field order_id_v2 (JSON name "order_id") and field order_id (JSON name "id", line 4) '
+            'of acme.v1.Order both map to JSON key "order_id"',
+        ])
+        report = run_check("json_name_collisions", [proto], {"proto_names": False})
+        self.assertEqual(report["violations"], [])
+
+    def test_invalid_config(self):
+        proto = self.write("c.proto", 'syntax = "proto3";\npackage acme.v1;\n')
+        with self.assertRaises(CheckConfigError):
+            run_check("json_name_collisions", [proto], {"proto_names": "yes"})
+
+
+class TestRegisteredOptions(SchemaLintTestCase):
+    """Test the registered_options check."""
+
+    def setUp(self):
+        super().setUp()
+        self.options = self.write("acme/options.proto", '''
+            syntax = "proto3";
+            package acme.options;
+            import "google/protobuf/descriptor.proto";
+            extend google.protobuf.FieldOptions { bool sensitive = 50001; }
+            extend google.protobuf.MessageOptions { string table = 50002; }
+        ''')
+
+    def test_defined_options_pass(self):
+        proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "acme/options.proto";
+            message User {
+              option (acme.options.table) = "users";
+              string email = 1 [(acme.options.sensitive) = true, json_name = "mail"];
+            }
+        ''')
+        report = run_check("registered_options", [proto], {}, dep_files=[self.options])
+        self.assertEqual(report["violations"], [])
+
+    def test_missing_extension(self):
+        proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message User {
+              string email = 1 [(acme.options.sensitive) = true, (acme.options.missing) = 1];
+            }
+        ''')
+        report = run_check("registered_options", [proto], {}, dep_files=[self.options])
+        self.assertEqual(self.messages(report), [
+            "custom option (acme.options.missing) on field acme.v1.User.email is not defined: no extension "
+            "acme.options.missing of google.protobuf.FieldOptions in the target or its dependencies (missing dependency?)",
+        ])
+
+    def test_missing_dependency(self):
+        proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            option (acme.options.sensitive) = true;
+        ''')
+        report = run_check("registered_options", [proto], {})
+        self.assertEqual(len(report["violations"]), 1)
+        self.assertIn("no extension acme.options.sensitive of google.protobuf.FileOptions", self.messages(report)[0])
+
+    def test_wrong_options_message(self):
+        proto = self.write("user.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message User { option (acme.options.sensitive) = true; }
+        ''')
+        report = run_check("registered_options", [proto], {}, dep_files=[self.options])
+        self.assertEqual(self.messages(report), [
+            "custom option (acme.options.sensitive) on message acme.v1.User extends "
+            "google.protobuf.FieldOptions, not google.protobuf.MessageOptions",
+        ])
+
+    def test_package_relative_name(self):
+        proto = self.write("acme/options/use.proto", '''
+            syntax = "proto3";
+            package acme.options.v1;
+            message User { string email = 1 [(options.sensitive) = true]; }
+        ''')
+        report = run_check("registered_options", [proto], {}, dep_files=[self.options])
+        self.assertEqual(report["violations"], [])
+
+
+class TestCaseInsensitiveTypeNames(SchemaLintTestCase):
+    """Test the case_insensitive_type_names check."""
+
+    def test_reports_both_types(self):
+        proto = self.write("a.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message FooBar {}
+            enum Foobar { FOOBAR_UNSPECIFIED = 0; }
+            message Outer { message Item {} message ITEM {} }
+            message Item {}
+        ''')
+        report = run_check("case_insensitive_type_names", [proto], {})
+        self.assertEqual(self.messages(report), [
+            f"enum acme.v1.Foobar differs only in case from acme.v1.FooBar ({proto}:4)",
+            f"message acme.v1.Outer.ITEM differs only in case from acme.v1.Outer.Item ({proto}:6)",
+        ])
+
+    def test_across_files_and_dependencies(self):
+        dep = self.write("dep.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage UserID {}\n')
+        proto = self.write("b.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage UserId {}\nmessage Other {}\n')
+        report = run_check("case_insensitive_type_names", [proto], {}, dep_files=[dep])
+        self.assertEqual(self.messages(report), [
+            f"message acme.v1.UserId differs only in case from acme.v1.UserID ({dep}:3)",
+        ])
+
+    def test_other_packages_ignored(self):
+        proto = self.write("c.proto", 'syntax = "proto3";\npackage acme.v1;\nmessage User {}\n')
+        other = self.write("d.proto", 'syntax = "proto3";\npackage acme.v2;\nmessage USER {}\n')
+        report = run_check("case_insensitive_type_names", [proto, other], {})
+        self.assertEqual(report["violations"], [])
+
+
+class TestPackageDepth(SchemaLintTestCase):
+    """Test the package_depth check."""
+
+    def test_reports_shallow_packages(self):
+        deep = self.write("deep.proto", 'syntax = "proto3";\npackage acme.billing.v1;\n')
+        shallow = self.write("shallow.proto", 'syntax = "proto3";\npackage acme.v1;\n')
+        none = self.write("none.proto", 'syntax = "proto3";\nmessage M {}\n')
+        report = run_check("package_depth", [deep, shallow, none], {"min_depth": 3})
+        self.assertEqual(self.messages(report), [
+            "file has no package and has 0 components (minimum 3, e.g. org.domain.v1)",
+            "package acme.v1 has 2 components (minimum 3, e.g. org.domain.v1)",
+        ])
+        self.assertEqual(report["violations"][1]["line"], 2)
+
+    def test_exempt_shared_root(self):
+        shared = self.write("shared.proto", 'syntax = "proto3";\npackage acme;\n')
+        report = run_check("package_depth", [shared], {"min_depth": 3, "exemptions": ["acme"]})
+        self.assertEqual(report["violations"], [])
+
+    def test_rejects_invalid_depth(self):
+        proto = self.write("a.proto", 'syntax = "proto3";\npackage acme.v1;\n')
+        with self.assertRaises(CheckConfigError):
+            run_check("package_depth", [proto], {"min_depth": 0})
+
+
+class TestRepeatedCardinality(SchemaLintTestCase):
+    """Test the repeated_cardinality check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("order.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            import "buck2protobuf/sizing/v1/sizing.proto";
+            message Item {}
+            message Order {
+              repeated Item items = 1 [(buck2protobuf.sizing.v1.max_count) = 500];
+              repeated Item gifts = 2;
+              map<string, Item> by_sku = 3;
+              repeated string tags = 4;
+              repeated Item bad = 5 [(buck2protobuf.sizing.v1.max_count) = 0];
+            }
+        ''')
+
+    def test_reports_unannotated_message_fields(self):
+        report = run_check("repeated_cardinality", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "repeated field acme.v1.Order.gifts has no (buck2protobuf.sizing.v1.max_count) annotation",
+            "map field acme.v1.Order.by_sku has no (buck2protobuf.sizing.v1.max_count) annotation",
+            "repeated field acme.v1.Order.bad has (buck2protobuf.sizing.v1.max_count) = 0, expected a positive count",
+        ])
+
+    def test_include_scalars_and_exemptions(self):
+        config = {"include_scalars": True, "exemptions": ["acme.v1.Order.gifts", "acme.v1.Order.by_sku", "acme.v1.Order.bad"]}
+        report = run_check("repeated_cardinality", [self.proto], config)
+        self.assertEqual(self.messages(report), [
+            "repeated field acme.v1.Order.tags has no (buck2protobuf.sizing.v1.max_count) annotation",
+        ])
+
+    def test_custom_option(self):
+        proto = self.write("custom.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Item {}
+            message Batch { repeated Item items = 1 [(acme.sizing.max_items) = 10]; }
+        ''')
+        report = run_check("repeated_cardinality", [proto], {"option": "acme.sizing.max_items"})
+        self.assertEqual(report["violations"], [])
+
+
+class TestMapKeyDocs(SchemaLintTestCase):
+    """Test the map_key_docs check."""
+
+    def setUp(self):
+        super().setUp()
+        self.proto = self.write("catalog.proto", '''
+            syntax = "proto3";
+            package acme.v1;
+            message Item {}
+            message Catalog {
+              // Items keyed by SKU.
+              map<string, Item> items = 1;
+              // Map from region code to the item on display there.
+              map<string, Item> featured = 2;
+              map<string, Item> undocumented = 3;
+              // The items.
+              map<string, Item> vague = 4;
+              map<string, string> labels = 5;
+            }
+        ''')
+
+    def test_reports_undocumented_keys(self):
+        report = run_check("map_key_docs", [self.proto], {})
+        self.assertEqual(self.messages(report), [
+            "map field acme.v1.Catalog.undocumented has no leading comment describing its key",
+            "map field acme.v1.Catalog.vague has a leading comment that does not describe its key",
+            "map field acme.v1.Catalog.labels has no leading comment describing its key",
+        ])
+
+    def test_message_values_only(self):
+        report = run_check("map_key_docs", [self.proto], {"message_values_only": True})
+        self.assertEqual([v["element"] for v in report["violations"]], [
+            "acme.v1.Catalog.undocumented",
+            "acme.v1.Catalog.vague",
+        ])
+
+    def test_custom_pattern(self):
+        report = run_check("map_key_docs", [self.proto], {"key_pattern": r"\bthe items\b", "message_values_only": True})
+        self.assertEqual([v["element"] for v in report["violations"]], [
+            "acme.v1.Catalog.items",
+            "acme.v1.Catalog.featured",
+            "acme.v1.Catalog.undocumented",
+        ])
+        with self.assertRaises(CheckConfigError):
+            run_check("map_key_docs", [self.proto], {"key_pattern": "("})
+
+
+if __name__ == "__main__":
+    unittest.main()
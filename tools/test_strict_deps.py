@@ -0,0 +1,94 @@
+#!/usr/bin/env python3
+"""
+Test suite for the strict dependency check.
+"""
+
+import json
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from strict_deps import check_imports, format_violation
+except ImportError:
+    sys.path.append(str(Path(__file__).parent))
+    from strict_deps import check_imports, format_violation
+
+TOOLS_DIR = Path(__file__).parent
+
+ORDER_PROTO = '''syntax = "proto3";
+package acme;
+
+import "acme/user.proto";
+import "acme/money.proto";
+import "google/protobuf/timestamp.proto";
+import "acme/line.proto";
+
+message Order {}
+'''
+
+
+class TestStrictDeps(unittest.TestCase):
+    """Test imports checked against the direct deps."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.order = os.path.join(self.temp_dir, "order.proto")
+        with open(self.order, "w", encoding="utf-8") as f:
+            f.write(ORDER_PROTO)
+        self.line = os.path.join(self.temp_dir, "line.proto")
+        with open(self.line, "w", encoding="utf-8") as f:
+            f.write('syntax = "proto3";\npackage acme;\nmessage Line {}\n')
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def test_transitive_import_names_provider(self):
+        violations = check_imports(
+            {"acme/order.proto": self.order, "acme/line.proto": self.line},
+            ["acme/user.proto"],
+            {"acme/money.proto": "//acme:money_proto"},
+        )
+        self.assertEqual(violations, [{
+            "file": "acme/order.proto", "line": 5, "import": "acme/money.proto", "provider": "//acme:money_proto",
+        }])
+        self.assertEqual(
+            format_violation(violations[0]),
+            'acme/order.proto:5: imports "acme/money.proto", which is only available transitively '
+            "(provided by //acme:money_proto); add //acme:money_proto to deps",
+        )
+
+    def test_all_direct(self):
+        violations = check_imports(
+            {"acme/order.proto": self.order, "acme/line.proto": self.line},
+            ["acme/user.proto", "acme/money.proto"],
+            {},
+        )
+        self.assertEqual(violations, [])
+
+    def test_script_fails_with_report(self):
+        report = os.path.join(self.temp_dir, "report.json")
+        result = subprocess.run(
+            [sys.executable, str(TOOLS_DIR / "strict_deps.py"), "--target", "//acme:order_proto",
+             "--output", report, f"acme/order.proto={self.order}",
+             "--direct", "acme/money.proto", "--transitive", "acme/user.proto=//acme:user_proto"],
+            capture_output=True, text=True, timeout=60,
+        )
+        self.assertEqual(result.returncode, 1)
+        self.assertIn("//acme:order_proto imports files that are not provided by a direct dependency", result.stderr)
+        self.assertIn("add //acme:user_proto to deps", result.stderr)
+        with open(report, encoding="utf-8") as f:
+            self.assertEqual([v["import"] for v in json.load(f)["violations"]], ["acme/user.proto"])
+
+    def test_undeclared_file_is_left_to_protoc(self):
+        # acme/line.proto is not a file of any proto_library, e.g. it comes from a bsr_module
+        violations = check_imports({"acme/order.proto": self.order}, ["acme/user.proto", "acme/money.proto"], {})
+        self.assertEqual(violations, [])
+
+
+if __name__ == "__main__":
+    unittest.main()
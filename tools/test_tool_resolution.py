@@ -0,0 +1,135 @@
+#!/usr/bin/env python3
+"""
+Test suite for dry-run tool resolution.
+"""
+
+import json
+import os
+import shutil
+import tempfile
+import unittest
+from pathlib import Path
+
+try:
+    from tool_resolution import ToolDatabase, ToolReference, check_resolution, collect_references
+except ImportError:
+    import sys
+    sys.path.append(str(Path(__file__).parent))
+    from tool_resolution import ToolDatabase, ToolReference, check_resolution, collect_references
+
+
+CHECKSUM = "a" * 64
+
+
+def make_database() -> ToolDatabase:
+    return ToolDatabase(
+        protoc={
+            "24.4": {"linux-x86_64": {"url": "https://example.com/protoc.zip", "sha256": CHECKSUM}},
+        },
+        plugins={
+            "protoc-gen-go": {
+                "1.31.0": {
+                    "linux-x86_64": {"url": "https://example.com/go.tar.gz", "sha256": CHECKSUM},
+                    "darwin-arm64": {"url": "https://example.com/go-mac.tar.gz", "sha256": ""},
+                },
+            },
+            "protoc-gen-grpc-python": {
+                "1.59.0": {"linux-x86_64": {"type": "python_package", "package": "grpcio-tools"}},
+            },
+        },
+        defaults={"protoc": "24.4", "protoc-gen-go": "1.31.0"},
+        language_tools={"go": {"protoc-gen-go": "1.31.0"}},
+    )
+
+
+class TestCollectReferences(unittest.TestCase):
+    """Test scanning BUCK files and lockfiles."""
+
+    def setUp(self):
+        self.temp_dir = tempfile.mkdtemp()
+        self.database = make_database()
+
+    def tearDown(self):
+        shutil.rmtree(self.temp_dir)
+
+    def write(self, name: str, content: str) -> Path:
+        path = Path(self.temp_dir) / name
+        os.makedirs(path.parent, exist_ok=True)
+        path.write_text(content, encoding="utf-8")
+        return path
+
+    def test_rules_imply_tools(self):
+        self.write("api/BUCK", '''
+load("@protobuf//rules:proto.bzl", "proto_library")
+proto_library(name = "api_proto", srcs = ["api.proto"], protoc_version = "23.0")
+go_proto_library(name = "api_go", proto = ":api_proto")
+''')
+        references = collect_references([Path(self.temp_dir)], self.database)
+        found = {(r.tool, r.version) for r in references}
+        self.assertEqual(found, {("protoc", "23.0"), ("protoc", "24.4"), ("protoc-gen-go", "1.31.0")})
+        go = next(r for r in references if r.tool == "protoc-gen-go")
+        self.assertTrue(go.sources[0].endswith("api/BUCK:4 (api_go)"))
+
+    def test_lockfile(self):
+        lockfile = self.write("tools.lock.json", json.dumps({"tools": {"protoc-gen-go": "1.30.0"}}))
+        references = collect_references([], self.database, lockfile)
+        self.assertEqual([(r.tool, r.version) for r in references], [("protoc-gen-go", "1.30.0")])
+
+    def test_unparseable_build_file_is_skipped(self):
+        self.write("BUCK", "this is not starlark (")
+        self.assertEqual(collect_references([Path(self.temp_dir)], self.database), [])
+
+    def test_repository_database_loads(self):
+        database = ToolDatabase.load()
+        self.assertIn(database.defaults["protoc"], database.protoc)
+        self.assertIn("protoc-gen-go", database.tools_for_rule("go_proto_library"))
+
+
+class TestCheckResolution(unittest.TestCase):
+    """Test resolution checks."""
+
+    def setUp(self):
+        self.database = make_database()
+
+    def reasons(self, references, platforms=("linux-x86_64",), **kwargs):
+        problems = check_resolution(references, self.database, list(platforms), **kwargs)
+        return [(p.tool, p.platform, p.reason) for p in problems]
+
+    def test_resolvable_tools(self):
+        references = [ToolReference("protoc", "24.4"), ToolReference("protoc-gen-grpc-python", "1.59.0")]
+        self.assertEqual(self.reasons(references, network=False), [])
+
+    def test_unknown_tool_and_version(self):
+        references = [ToolReference("protoc-gen-foo", "1.0"), ToolReference("protoc", "99.0")]
+        reasons = self.reasons(references, network=False)
+        self.assertEqual(reasons[0], ("protoc-gen-foo", "*", "unknown tool"))
+        self.assertIn("unknown version (known: 24.4)", reasons[1][2])
+
+    def test_missing_platform_and_checksum(self):
+        references = [ToolReference("protoc", "24.4"), ToolReference("protoc-gen-go", "1.31.0")]
+        reasons = self.reasons(references, platforms=("darwin-arm64",), network=False)
+        self.assertIn(("protoc", "darwin-arm64", "no download entry for platform"), reasons)
+        self.assertIn(("protoc-gen-go", "darwin-arm64", "SHA256 checksum missing or malformed"), reasons)
+
+    def test_head_requests(self):
+        requested = []
+
+        def head(url):
+            requested.append(url)
+            return "HTTP 404" if "protoc" in url else None
+
+        references = [ToolReference("protoc", "24.4"), ToolReference("protoc-gen-go", "1.31.0")]
+        reasons = self.reasons(references, head=head)
+        self.assertEqual(reasons, [("protoc", "linux-x86_64",
+                                    "URL not reachable (HTTP 404): https://example.com/protoc.zip")])
+        self.assertEqual(len(requested), 2)
+
+    def test_no_network_skips_head_requests(self):
+        def head(url):
+            self.fail("HEAD request sent with network disabled")
+
+        self.reasons([ToolReference("protoc", "24.4")], network=False, head=head)
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,269 @@
+#!/usr/bin/env python3
+"""
+Dry-run resolution of protoc and plugin tools for protobuf Buck2 integration.
+
+This module verifies, without downloading anything, that every tool referenced
+by the build resolves: the version is known to the tool database, a download
+entry exists for each requested platform, a SHA256 checksum is recorded, and
+(optionally) the download URL answers a HEAD request.
+
+Tool references are collected from BUCK files (proto_library protoc_version
+and the tools each language rule requires) and from an optional lockfile.
+The tool database and per-language requirements are read from
+tools/platforms/common.bzl and rules/tools.bzl, so there is a single source
+of truth shared with the Buck2 rules.
+
+Lockfile format (JSON):
+    {"tools": {"protoc": "24.4", "protoc-gen-go": "1.31.0"}}
+"""
+
+import ast
+import json
+import re
+import urllib.error
+import urllib.request
+from dataclasses import asdict, dataclass, field
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Tuple
+
+
+REPO_ROOT = Path(__file__).resolve().parent.parent
+TOOL_DATABASE = REPO_ROOT / "tools" / "platforms" / "common.bzl"
+TOOL_RULES = REPO_ROOT / "rules" / "tools.bzl"
+
+# Rule name prefixes and the language whose tool requirements they use
+RULE_LANGUAGES = {
+    "go_": "go",
+    "python_": "python",
+    "cpp_": "cpp",
+    "typescript_": "typescript",
+    "rust_": "rust",
+}
+
+_SHA256 = re.compile(r"^[0-9a-f]{64}$")
+
+
+@dataclass
+class ToolReference:
+    """A tool version required by the build."""
+    tool: str
+    version: str
+    sources: List[str] = field(default_factory=list)
+
+
+@dataclass
+class ResolutionProblem:
+    """A tool that cannot be resolved."""
+    tool: str
+    version: str
+    platform: str
+    reason: str
+    sources: List[str] = field(default_factory=list)
+
+
+def _function_return_literal(tree: ast.Module, function: str) -> Any:
+    for node in tree.body:
+        if isinstance(node, ast.FunctionDef) and node.name == function:
+            for statement in node.body:
+                if isinstance(statement, ast.Return) and statement.value is not None:
+                    return ast.literal_eval(statement.value)
+    raise ValueError(f"could not find a literal return value in {function}()")
+
+
+def _function_assignment_literal(tree: ast.Module, function: str, variable: str) -> Any:
+    for node in tree.body:
+        if isinstance(node, ast.FunctionDef) and node.name == function:
+            for statement in ast.walk(node):
+                if isinstance(statement, ast.Assign) and any(
+                    isinstance(t, ast.Name) and t.id == variable for t in statement.targets
+                ):
+                    return ast.literal_eval(statement.value)
+    raise ValueError(f"could not find {variable} in {function}()")
+
+
+@dataclass
+class ToolDatabase:
+    """Known tool versions, per-platform download entries and defaults."""
+    protoc: Dict[str, Dict[str, Dict[str, Any]]]
+    plugins: Dict[str, Dict[str, Dict[str, Dict[str, Any]]]]
+    defaults: Dict[str, str]
+    language_tools: Dict[str, Dict[str, str]]
+
+    @classmethod
+    def load(cls, database_path: Path = TOOL_DATABASE, rules_path: Path = TOOL_RULES) -> "ToolDatabase":
+        database = ast.parse(database_path.read_text(encoding="utf-8"))
+        rules = ast.parse(rules_path.read_text(encoding="utf-8"))
+        return cls(
+            protoc=_function_return_literal(database, "get_protoc_info"),
+            plugins=_function_return_literal(database, "get_plugin_info"),
+            defaults=_function_return_literal(database, "get_default_versions"),
+            language_tools=_function_assignment_literal(rules, "get_tool_requirements", "language_tools"),
+        )
+
+    def versions(self, tool: str) -> Optional[Dict[str, Dict[str, Any]]]:
+        if tool == "protoc":
+            return self.protoc
+        return self.plugins.get(tool)
+
+    def tools_for_rule(self, rule_name: str) -> List[str]:
+        """Returns the tools a rule requires (protoc plus its language plugins)."""
+        if rule_name == "proto_library":
+            return ["protoc"]
+        for prefix, language in RULE_LANGUAGES.items():
+            if rule_name.startswith(prefix):
+                return ["protoc"] + sorted(self.language_tools.get(language, {}))
+        return []
+
+
+def _literal(node: ast.AST) -> Any:
+    try:
+        return ast.literal_eval(node)
+    except (ValueError, SyntaxError):
+        return None
+
+
+def scan_build_file(path: Path, database: ToolDatabase) -> Iterator[Tuple[str, str, str]]:
+    """
+    Yields tool references from a BUCK file.
+
+    Yields:
+        Tuples of (tool, version or "" for the default, source label)
+    """
+    try:
+        tree = ast.parse(path.read_text(encoding="utf-8"), filename=str(path))
+    except (SyntaxError, UnicodeDecodeError):
+        return
+    for node in ast.walk(tree):
+        if not isinstance(node, ast.Call) or not isinstance(node.func, ast.Name):
+            continue
+        kwargs = {kw.arg: kw.value for kw in node.keywords if kw.arg}
+        target = _literal(kwargs["name"]) if "name" in kwargs else None
+        source = f"{path}:{node.lineno}" + (f" ({target})" if isinstance(target, str) else "")
+        for tool in database.tools_for_rule(node.func.id):
+            version = ""
+            if tool == "protoc" and "protoc_version" in kwargs:
+                version = _literal(kwargs["protoc_version"]) or ""
+            yield tool, version, source
+
+
+def collect_references(
+    roots: List[Path],
+    database: ToolDatabase,
+    lockfile: Optional[Path] = None,
+) -> List[ToolReference]:
+    """
+    Collects every (tool, version) pair referenced by BUCK files and the lockfile.
+
+    Empty versions resolve to the database defaults.
+    """
+    references: Dict[Tuple[str, str], ToolReference] = {}
+
+    def add(tool: str, version: str, source: str) -> None:
+        version = version or database.defaults.get(tool, "")
+        reference = references.setdefault((tool, version), ToolReference(tool, version))
+        reference.sources.append(source)
+
+    for root in roots:
+        build_files = [root] if root.is_file() else sorted(root.rglob("BUCK"))
+        for build_file in build_files:
+            for tool, version, source in scan_build_file(build_file, database):
+                add(tool, version, source)
+
+    if lockfile:
+        with open(lockfile, "r", encoding="utf-8") as f:
+            locked = json.load(f).get("tools", {})
+        for tool, version in sorted(locked.items()):
+            add(tool, str(version), str(lockfile))
+
+    return sorted(references.values(), key=lambda r: (r.tool, r.version))
+
+
+def head_request(url: str, timeout: float = 10.0) -> Optional[str]:
+    """Sends a HEAD request and returns an error description, or None on success."""
+    request = urllib.request.Request(url, method="HEAD")
+    try:
+        with urllib.request.urlopen(request, timeout=timeout) as response:
+            if response.status >= 400:
+                return f"HTTP {response.status}"
+    except urllib.error.HTTPError as e:
+        return f"HTTP {e.code}"
+    except (urllib.error.URLError, OSError) as e:
+        return str(getattr(e, "reason", e))
+    return None
+
+
+def check_resolution(
+    references: List[ToolReference],
+    database: ToolDatabase,
+    platforms: List[str],
+    network: bool = True,
+    head=head_request,
+) -> List[ResolutionProblem]:
+    """
+    Checks that every referenced tool resolves on every requested platform.
+
+    Args:
+        references: Tool references to check
+        database: Tool database
+        platforms: Platform strings (e.g. "linux-x86_64")
+        network: Whether to send HEAD requests to download URLs
+        head: HEAD request function (injectable for tests)
+
+    Returns:
+        List of resolution problems (empty when everything resolves)
+    """
+    problems = []
+    checked_urls: Dict[str, Optional[str]] = {}
+    for reference in references:
+        def problem(platform: str, reason: str) -> None:
+            problems.append(ResolutionProblem(reference.tool, reference.version, platform, reason, reference.sources))
+
+        versions = database.versions(reference.tool)
+        if versions is None:
+            problem("*", "unknown tool")
+            continue
+        if not reference.version:
+            problem("*", "no version specified and no default version")
+            continue
+        if reference.version not in versions:
+            problem("*", f"unknown version (known: {', '.join(sorted(versions))})")
+            continue
+
+        for platform in platforms:
+            entry = versions[reference.version].get(platform)
+            if entry is None:
+                problem(platform, "no download entry for platform")
+                continue
+            if entry.get("type") == "python_package":
+                # Installed with pip; resolved by package name and version
+                if not entry.get("package"):
+                    problem(platform, "python package name missing")
+                continue
+            if not entry.get("url"):
+                problem(platform, "download URL missing")
+                continue
+            if not _SHA256.match(entry.get("sha256", "")):
+                problem(platform, "SHA256 checksum missing or malformed")
+            if network:
+                url = entry["url"]
+                if url not in checked_urls:
+                    checked_urls[url] = head(url)
+                if checked_urls[url]:
+                    problem(platform, f"URL not reachable ({checked_urls[url]}): {url}")
+    return problems
+
+
+def format_problems(problems: List[ResolutionProblem]) -> str:
+    lines = []
+    for p in problems:
+        lines.append(f"{p.tool} {p.version or '<none>'} [{p.platform}]: {p.reason}")
+        for source in p.sources:
+            lines.append(f"    referenced by {source}")
+    return "\n".join(lines)
+
+
+def problems_to_json(problems: List[ResolutionProblem], references: List[ToolReference]) -> Dict[str, Any]:
+    return {
+        "tools_checked": len(references),
+        "problems": [asdict(p) for p in problems],
+    }